@@ -0,0 +1,244 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	crds "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/crossplane/apis/apiextensions"
+
+	"github.com/crossplane/agent/pkg/stats"
+	"github.com/crossplane/agent/pkg/warmup"
+)
+
+const (
+	timeout = 5 * time.Minute
+
+	// configMapPrefix is the prefix of the names of the ConfigMaps the agent
+	// is configured with and publishes its reports in.
+	configMapPrefix = "crossplane-agent"
+)
+
+// A Claim and the statistics of its syncs.
+type Claim struct {
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace"`
+	Name      string       `json:"name"`
+	Stats     *stats.Stats `json:"stats,omitempty"`
+}
+
+// Command collects the sync statistics of the claims, the logs of the agent
+// and its configuration into a gzipped tarball that can be attached to a
+// troubleshooting escalation. Secrets are never collected.
+type Command struct {
+	ClusterConfig *rest.Config
+
+	// Namespace is the namespace the agent runs in.
+	Namespace string
+
+	// PodSelector is the label selector of the pods of the agent.
+	PodSelector string
+
+	// LogLines is how many of the last lines of the logs of each container
+	// are collected. All of them are if it's zero.
+	LogLines int64
+
+	// Passthrough are the kinds that are synced like claims even though
+	// they're not claims.
+	Passthrough []schema.GroupVersionKind
+}
+
+// Run writes the bundle to out, printing what couldn't be collected to
+// report. Only an error writing the bundle itself is returned, so that what
+// can be collected is.
+func (c *Command) Run(out, report io.Writer) error {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		return errors.Wrap(err, "cannot add Kubernetes API to scheme")
+	}
+	if err := crds.AddToScheme(s); err != nil {
+		return errors.Wrap(err, "cannot add CustomResourceDefinition API to scheme")
+	}
+	if err := apiextensions.AddToScheme(s); err != nil {
+		return errors.Wrap(err, "cannot add Crossplane apiextensions API to scheme")
+	}
+	kube, err := client.New(c.ClusterConfig, client.Options{Scheme: s})
+	if err != nil {
+		return errors.Wrap(err, "cannot create cluster client")
+	}
+	cs, err := kubernetes.NewForConfig(c.ClusterConfig)
+	if err != nil {
+		return errors.Wrap(err, "cannot create clientset")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		h := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(h); err != nil {
+			return errors.Wrapf(err, "cannot write %s", name)
+		}
+		_, err := tw.Write(data)
+		return errors.Wrapf(err, "cannot write %s", name)
+	}
+	skip := func(what string, err error) {
+		fmt.Fprintf(report, "skipping %s: %s\n", what, err) // nolint:errcheck
+	}
+
+	claims, err := c.claims(ctx, kube, report)
+	if err != nil {
+		skip("claim statistics", err)
+	}
+	b, err := json.MarshalIndent(claims, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal claim statistics")
+	}
+	if err := add("claims.json", b); err != nil {
+		return err
+	}
+
+	cms := &corev1.ConfigMapList{}
+	if err := kube.List(ctx, cms, client.InNamespace(c.Namespace)); err != nil {
+		skip("configuration", err)
+	}
+	for i := range cms.Items {
+		cm := cms.Items[i]
+		if !strings.HasPrefix(cm.GetName(), configMapPrefix) {
+			continue
+		}
+		cm.SetManagedFields(nil)
+		b, err := yaml.Marshal(cm)
+		if err != nil {
+			return errors.Wrapf(err, "cannot marshal ConfigMap %s", cm.GetName())
+		}
+		if err := add(path.Join("config", cm.GetName()+".yaml"), b); err != nil {
+			return err
+		}
+	}
+
+	pods := &corev1.PodList{}
+	sel, err := labelSelector(c.PodSelector)
+	if err != nil {
+		return err
+	}
+	if err := kube.List(ctx, pods, client.InNamespace(c.Namespace), sel); err != nil {
+		skip("agent pods", err)
+	}
+	for i := range pods.Items {
+		p := pods.Items[i]
+		p.SetManagedFields(nil)
+		b, err := yaml.Marshal(p)
+		if err != nil {
+			return errors.Wrapf(err, "cannot marshal pod %s", p.GetName())
+		}
+		if err := add(path.Join("pods", p.GetName()+".yaml"), b); err != nil {
+			return err
+		}
+		for _, ct := range p.Spec.Containers {
+			o := &corev1.PodLogOptions{Container: ct.Name}
+			if c.LogLines > 0 {
+				o.TailLines = &c.LogLines
+			}
+			logs, err := readLogs(ctx, cs, p, o)
+			if err != nil {
+				skip(fmt.Sprintf("logs of container %s of pod %s", ct.Name, p.GetName()), err)
+				continue
+			}
+			if err := add(path.Join("logs", p.GetName(), ct.Name+".log"), logs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "cannot write bundle")
+	}
+	return errors.Wrap(gz.Close(), "cannot write bundle")
+}
+
+// claims returns the statistics of every claim, including the ones that have
+// none recorded.
+func (c *Command) claims(ctx context.Context, kube client.Client, report io.Writer) ([]Claim, error) {
+	kinds, err := warmup.ClaimKinds(ctx, kube, c.Passthrough)
+	if err != nil {
+		return nil, err
+	}
+	claims := []Claim{}
+	for _, gvk := range kinds {
+		l := &kunstructured.UnstructuredList{}
+		l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := kube.List(ctx, l); err != nil {
+			fmt.Fprintf(report, "skipping claims of kind %s: %s\n", gvk.Kind, err) // nolint:errcheck
+			continue
+		}
+		for i := range l.Items {
+			cl := &l.Items[i]
+			out := Claim{Kind: gvk.Kind, Namespace: cl.GetNamespace(), Name: cl.GetName()}
+			s, recorded, err := stats.Get(cl)
+			if err != nil {
+				fmt.Fprintf(report, "skipping statistics of %s %s/%s: %s\n", gvk.Kind, cl.GetNamespace(), cl.GetName(), err) // nolint:errcheck
+			}
+			if recorded && err == nil {
+				out.Stats = &s
+			}
+			claims = append(claims, out)
+		}
+	}
+	return claims, nil
+}
+
+func labelSelector(s string) (client.MatchingLabelsSelector, error) {
+	sel, err := labels.Parse(s)
+	if err != nil {
+		return client.MatchingLabelsSelector{}, errors.Wrapf(err, "cannot parse pod selector %q", s)
+	}
+	return client.MatchingLabelsSelector{Selector: sel}, nil
+}
+
+func readLogs(ctx context.Context, cs kubernetes.Interface, p corev1.Pod, o *corev1.PodLogOptions) ([]byte, error) {
+	rc, err := cs.CoreV1().Pods(p.GetNamespace()).GetLogs(p.GetName(), o).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close() // nolint:errcheck
+	return ioutil.ReadAll(rc)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	crds "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane/apis/apiextensions"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/controllers/xrd"
+	"github.com/crossplane/agent/pkg/typegen"
+)
+
+const (
+	timeout = 1 * time.Minute
+
+	// FileName is the name of the file the Go source of each group version is
+	// written to.
+	FileName = "zz_generated.claims.go"
+)
+
+// Command generates Go types, typed clients and informers for the claim kinds
+// that are offered in the local cluster by the CompositeResourceDefinitions
+// the agent synced, so that other tooling in the local cluster can use them.
+type Command struct {
+	ClusterConfig *rest.Config
+
+	// OutputDir is the directory the packages are written to. The claims of
+	// each group version are written to a <group>/<version> package under it.
+	OutputDir string
+}
+
+// Run generates a package for each group version of the claim kinds, printing
+// what's generated to out.
+func (c *Command) Run(out io.Writer) error {
+	s := runtime.NewScheme()
+	if err := crds.AddToScheme(s); err != nil {
+		return errors.Wrap(err, "cannot add CustomResourceDefinition API to scheme")
+	}
+	if err := apiextensions.AddToScheme(s); err != nil {
+		return errors.Wrap(err, "cannot add Crossplane apiextensions API to scheme")
+	}
+	kube, err := client.New(c.ClusterConfig, client.Options{Scheme: s})
+	if err != nil {
+		return errors.Wrap(err, "cannot create cluster client")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	l := &v1alpha1.CompositeResourceDefinitionList{}
+	if err := kube.List(ctx, l); err != nil {
+		return errors.Wrap(err, "cannot list composite resource definitions")
+	}
+	kinds := map[schema.GroupVersion][]typegen.Kind{}
+	for _, d := range l.Items {
+		if d.Spec.ClaimNames == nil {
+			continue
+		}
+		crd := &crds.CustomResourceDefinition{}
+		err := kube.Get(ctx, xrd.GetClaimCRDName(d), crd)
+		if runtimeresource.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "cannot get claim CRD of %s", d.GetName())
+		}
+		if err != nil {
+			fmt.Fprintf(out, "# Skipping %s, its claims are not offered in this cluster yet\n", d.GetName()) // nolint:errcheck
+			continue
+		}
+		k, err := typegen.KindOf(*crd)
+		if err != nil {
+			return err
+		}
+		gv := k.GroupVersionKind.GroupVersion()
+		kinds[gv] = append(kinds[gv], k)
+	}
+	if len(kinds) == 0 {
+		return errors.New("no claim kinds are offered in this cluster")
+	}
+
+	gvs := make([]schema.GroupVersion, 0, len(kinds))
+	for gv := range kinds {
+		gvs = append(gvs, gv)
+	}
+	sort.Slice(gvs, func(i, j int) bool { return gvs[i].String() < gvs[j].String() })
+	for _, gv := range gvs {
+		src, err := typegen.Generate(gv.Version, kinds[gv])
+		if err != nil {
+			return errors.Wrapf(err, "cannot generate claims of %s", gv)
+		}
+		dir := filepath.Join(c.OutputDir, gv.Group, gv.Version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrapf(err, "cannot create directory %s", dir)
+		}
+		path := filepath.Join(dir, FileName)
+		if err := ioutil.WriteFile(path, src, 0644); err != nil { // nolint:gosec
+			return errors.Wrapf(err, "cannot write %s", path)
+		}
+		fmt.Fprintf(out, "Generated %d claim kinds of %s in %s\n", len(kinds[gv]), gv, path) // nolint:errcheck
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/agent/pkg/registration"
+)
+
+// Agent configures & starts the manager that aggregates the snapshots agents
+// publish into the status of the fleet. It runs in the remote cluster.
+type Agent struct {
+	// RegistrationNamespace is the namespace where agents publish their
+	// snapshots and where the status of the fleet is published.
+	RegistrationNamespace string
+
+	// HeartbeatTimeout is how old the last snapshot of an agent may get before
+	// the agent is considered unhealthy.
+	HeartbeatTimeout time.Duration
+}
+
+// Run adds the aggregator and starts the manager.
+func (a *Agent) Run(log logging.Logger, period time.Duration) error {
+	log.Debug("Starting", "sync-period", period.String())
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{SyncPeriod: &period, MetricsBindAddress: "127.0.0.1:8082"})
+	if err != nil {
+		return errors.Wrap(err, "cannot start hub cluster manager")
+	}
+
+	agg := registration.NewAggregator(mgr.GetAPIReader(), runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), a.RegistrationNamespace, a.HeartbeatTimeout, 30*time.Second, log)
+	if err := mgr.Add(agg); err != nil {
+		return errors.Wrap(err, "cannot add fleet aggregator")
+	}
+
+	return errors.Wrap(mgr.Start(ctrl.SetupSignalHandler()), "cannot start controller manager")
+}
@@ -17,39 +17,427 @@ limitations under the License.
 package local
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	crds "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane/apis/apiextensions"
 
+	"github.com/crossplane/agent/pkg/backpressure"
+	"github.com/crossplane/agent/pkg/connection"
+	"github.com/crossplane/agent/pkg/controllers"
+	"github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/controllers/decommission"
 	"github.com/crossplane/agent/pkg/controllers/xrd"
+	"github.com/crossplane/agent/pkg/conversion"
+	"github.com/crossplane/agent/pkg/dryrun"
+	"github.com/crossplane/agent/pkg/fault"
+	"github.com/crossplane/agent/pkg/hubconfig"
+	"github.com/crossplane/agent/pkg/idle"
+	"github.com/crossplane/agent/pkg/lifecycle"
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/onboarding"
+	"github.com/crossplane/agent/pkg/platform"
+	"github.com/crossplane/agent/pkg/registration"
+	"github.com/crossplane/agent/pkg/remote"
+	"github.com/crossplane/agent/pkg/rollout"
+	"github.com/crossplane/agent/pkg/staleness"
+	"github.com/crossplane/agent/pkg/stats"
+	"github.com/crossplane/agent/pkg/status"
+	"github.com/crossplane/agent/pkg/suspend"
+	"github.com/crossplane/agent/pkg/trim"
+	"github.com/crossplane/agent/pkg/version"
+	"github.com/crossplane/agent/pkg/warmup"
+	"github.com/crossplane/agent/pkg/warning"
 )
 
 // Agent configures & starts the manager that will watch the local cluster.
 type Agent struct {
 	ClusterConfig *rest.Config
 	DefaultConfig *rest.Config
+
+	// RemoteTransport tunes the connections to the remote cluster, which are
+	// checked once in every HealthCheckPeriod. Connections that may be dead
+	// are dropped when a check fails.
+	RemoteTransport   remote.TransportOptions
+	HealthCheckPeriod time.Duration
+
+	// MaxClaimSize is the largest serialized claim, in bytes, that will be
+	// pushed to the remote cluster.
+	MaxClaimSize int
+
+	// CanaryNamespace is the namespace in the remote cluster where claims are
+	// validated with a server-side dry-run before they are pushed. Canary
+	// validation is disabled if it's empty.
+	CanaryNamespace string
+
+	// Namespace is the namespace in the local cluster where Agent keeps its
+	// bookkeeping objects.
+	Namespace string
+
+	// ErrorBudget is the fraction of claim syncs that may fail within
+	// ErrorBudgetWindow before the rollout of definition updates is paused.
+	// The error budget is disabled if it's zero.
+	ErrorBudget       float64
+	ErrorBudgetWindow time.Duration
+
+	// BackpressureThreshold is the fraction of claim syncs that may fail
+	// before all claim controllers slow down and run fewer syncs at once.
+	// Backpressure is disabled if it's zero.
+	BackpressureThreshold float64
+
+	// MaxConcurrentSyncs is the number of claim syncs that may run at the same
+	// time across all claim types while backpressure is not applied.
+	MaxConcurrentSyncs int
+
+	// StatusUpdateQPS is how many status writes to local claims are let
+	// through per second on average, across all claim types, and
+	// StatusUpdateBurst is how many of them may be written at once. Status
+	// writes are not staggered if StatusUpdateQPS is zero.
+	StatusUpdateQPS   float64
+	StatusUpdateBurst int
+
+	// SyncQPS is how many claims start syncing per second on average across
+	// all claim Reconcilers, and SyncBurst how many of them may start at
+	// once. Syncs are not staggered if SyncQPS is zero.
+	SyncQPS   float64
+	SyncBurst int
+
+	// SyncInterval is how often claims, and the definitions they're of, are
+	// synced when nothing about them changed.
+	SyncInterval time.Duration
+
+	// ErrorRetryInterval is how long to wait before retrying a claim or a
+	// definition that failed to sync. The wait doubles with each consecutive
+	// failure, up to MaxErrorRetryInterval.
+	ErrorRetryInterval    time.Duration
+	MaxErrorRetryInterval time.Duration
+
+	// DisconnectedAfter is how long the remote cluster may be unreachable
+	// before claims stop being synced and report that they're disconnected
+	// until it's reachable again, at which point all claims are verified.
+	// Claims are never disconnected if it's zero.
+	DisconnectedAfter time.Duration
+
+	// DryRun reports the writes the agent would make to either cluster, and
+	// the diffs they'd make, rather than making them.
+	DryRun bool
+
+	// Suspend stops the agent from propagating or pruning anything while it
+	// keeps reporting status. The agent can be suspended without a restart by
+	// annotating the suspend.ConfigMapName ConfigMap in Namespace too.
+	Suspend bool
+
+	// LeaderElection makes only one of the replicas of the agent sync at a
+	// time, while the others stand by to take over. The leader holds a lock in
+	// LeaderElectionNamespace of the local cluster, or in Namespace if it's
+	// empty, that it has to renew within LeaseDuration. The replicas that
+	// stand by keep their caches warm and probe the remote cluster, so that
+	// they start syncing as soon as they're elected.
+	LeaderElection          bool
+	LeaderElectionNamespace string
+	LeaseDuration           time.Duration
+
+	// HubConfig restarts the agent when the settings it was started with are
+	// changed in the remote cluster, and reports its effective configuration.
+	HubConfig *hubconfig.Watcher
+
+	// RemoteKubeconfig restarts the agent when the kubeconfig of the remote
+	// cluster it discovered when it started changes.
+	RemoteKubeconfig *remote.KubeconfigWatcher
+
+	// RemoteUIDPolicy determines what happens when a remote claim is deleted
+	// and created again out-of-band.
+	RemoteUIDPolicy claim.UIDPolicy
+
+	// ClusterName identifies this cluster among the ones that sync to the same
+	// remote cluster. If it's set, claims are synced to a remote namespace
+	// only while this agent holds the lock of that namespace.
+	ClusterName string
+
+	// RemoteNamespacePolicy determines what happens when the remote namespace
+	// a claim is synced to doesn't exist.
+	RemoteNamespacePolicy claim.NamespacePolicy
+
+	// SyncInputs enables syncing the Secrets and ConfigMaps that claims
+	// reference in their input annotations to the remote cluster.
+	SyncInputs bool
+
+	// RequireApproval holds claims until they're approved with an annotation
+	// before they're pushed to the remote cluster for the first time.
+	RequireApproval bool
+
+	// RemoteFinalizer holds the deletion of remote claims by anyone but the
+	// agent until it's acknowledged on their local claims.
+	RemoteFinalizer bool
+
+	// RecordLastPushedSpec records the spec that was last pushed to the remote
+	// cluster on local claims.
+	RecordLastPushedSpec bool
+
+	// ValidateSchema validates claims against the schema of their CRD in the
+	// remote cluster before they're pushed, reporting the fields that don't
+	// conform to it on the claims.
+	ValidateSchema bool
+
+	// CheckQuota checks the quotas of the remote namespace of claims before
+	// they're created there, reporting the quotas their creation would
+	// exceed on the claims.
+	CheckQuota bool
+
+	// AdmissionWebhook is the URL claims are reviewed by before they're
+	// pushed, waiting up to AdmissionWebhookTimeout for its verdict. Claims
+	// are not reviewed if it's empty.
+	AdmissionWebhook        string
+	AdmissionWebhookTimeout time.Duration
+
+	// DetectDrift reports remote claims whose spec was changed out-of-band
+	// before pushing the desired spec again.
+	DetectDrift bool
+
+	// IdleThreshold is how long a claim may be ready but untouched before it's
+	// reported as idle in Namespace. Reporting is disabled if it's zero.
+	IdleThreshold time.Duration
+
+	// NamespaceMapping maps local namespaces to the remote namespaces their
+	// claims are synced to. Claims are relocated when it changes.
+	NamespaceMapping map[string]string
+
+	// ConversionPolicy is how the local copies of claim CRDs that are converted
+	// by a webhook in the remote cluster are converted unless their CRD or XRD
+	// chooses otherwise.
+	ConversionPolicy conversion.Policy
+
+	// ConversionProxyService is the local Service, in namespace/name form, that
+	// exposes the conversion proxy, which serves with the tls.crt, tls.key and
+	// ca.crt files in ConversionProxyCertDir. The proxy is not run if it's
+	// empty.
+	ConversionProxyService string
+	ConversionProxyCertDir string
+
+	// SnapshotPeriod is how often a snapshot of the state of the agent is
+	// published into its registration object in RegistrationNamespace of the
+	// remote cluster. Snapshots are disabled if it's zero.
+	SnapshotPeriod        time.Duration
+	RegistrationNamespace string
+
+	// PlatformHealthPeriod is how often the health of the definitions in the
+	// remote cluster that local claims depend on is published in Namespace.
+	// It's not published if it's zero.
+	PlatformHealthPeriod time.Duration
+
+	// ConnectionProbePeriod is how often the connection to the remote cluster
+	// is probed stage by stage, and the stage that fails, if any, is published
+	// in Namespace. It's not probed if it's zero.
+	ConnectionProbePeriod time.Duration
+
+	// StatusPeriod is how often the overall sync state of the agent is
+	// published in Namespace. It's not published if it's zero.
+	StatusPeriod time.Duration
+
+	// SyncStatsPeriod is how often the statistics of the syncs of each claim
+	// are written into an annotation of the claim. They're not recorded if
+	// it's zero.
+	SyncStatsPeriod time.Duration
+
+	// CloudEventsSink is the URL lifecycle events of claims are posted to as
+	// CloudEvents. Events are not emitted if it's empty.
+	CloudEventsSink string
+
+	// VersionTable tells the versions claims are read and written at in the
+	// remote cluster when they're different than the ones served locally.
+	VersionTable claim.VersionTable
+
+	// StatusPolicies tell which status fields of remote claims are pulled
+	// onto local claims, by kind. The whole status is pulled for the kinds
+	// that are not in it.
+	StatusPolicies claim.StatusPolicyTable
+
+	// ConditionMapping tells the types the conditions of remote claims are
+	// pulled onto local claims with. The claim.DefaultConditionMapping is
+	// used if it's nil.
+	ConditionMapping claim.ConditionMapping
+
+	// PassthroughKinds are the kinds of namespaced custom resources that are
+	// synced to the remote cluster like claims even though no
+	// CompositeResourceDefinition offers them.
+	PassthroughKinds []schema.GroupVersionKind
+
+	// SecretEnvelope encrypts the data of input secrets before they're pushed
+	// to the remote cluster and decrypts the connection secrets that were
+	// sealed in it. Secrets are propagated as they are if it's nil.
+	SecretEnvelope claim.SecretEnvelope
+
+	// SyncRecorder records the claims every sync reads and pushes so that
+	// they can be replayed offline. Syncs are not recorded if it's nil.
+	SyncRecorder claim.SyncRecorder
+
+	// MaxDefinitionStaleness is how long definitions may go without being
+	// refreshed from the remote cluster by the agent running in remote mode.
+	// New claims are held while they're stale if HoldOnStaleDefinitions is
+	// set.
+	MaxDefinitionStaleness time.Duration
+	HoldOnStaleDefinitions bool
+
+	// FanOutConfigs are the remote clusters, keyed by their names, that claims
+	// may be fanned out to in addition to the one they're synced with.
+	FanOutConfigs map[string]*rest.Config
+
+	// RemoteConfigs are the remote clusters, keyed by their names, that claims
+	// labelled with their name are synced to rather than the one in
+	// ClusterConfig. They share the local cache with it, and are configured
+	// like it. Namespaces are only decommissioned in the remote cluster in
+	// ClusterConfig.
+	RemoteConfigs map[string]*rest.Config
+
+	// RemoteOwnerLabels are added to the claims and inputs that are pushed to
+	// the remote cluster, and restrict the claims and inputs that are listed
+	// there, for remote clusters where the agent is only granted access to
+	// the objects that carry them.
+	RemoteOwnerLabels map[string]string
+
+	// LabelPolicy determines the labels that claims must have, or are given,
+	// when they're pushed to the remote cluster.
+	LabelPolicy claim.LabelPolicy
+
+	// OnboardingBatchSize is how many of the claims that existed before the
+	// agent was started are synced for the first time per OnboardingInterval,
+	// and how far their onboarding got is reported in Namespace. They're all
+	// synced right away if it's zero.
+	OnboardingBatchSize int
+	OnboardingInterval  time.Duration
+
+	// DecommissionNamespaces tears down the synced claims of local namespaces
+	// that are deleted in a defined order, holding the namespaces until it's
+	// done and reporting it in Namespace.
+	DecommissionNamespaces bool
+
+	// ClaimSelector restricts the local claims that are synced to the remote
+	// cluster. All claims are synced if it's nil.
+	ClaimSelector *claim.ClaimSelector
+
+	// HealthProbeAddress is the address the liveness and readiness endpoints
+	// are served at. They're not served if it's empty.
+	HealthProbeAddress string
+
+	// UnreadyAfter is how long the remote cluster may be unreachable before
+	// the agent reports itself not ready. The agent stays ready while the
+	// remote cluster is unreachable if it's zero.
+	UnreadyAfter time.Duration
+
+	// MetricsAddress is the address the /metrics endpoint is served at. It's
+	// served at 127.0.0.1:8080 if it's empty.
+	MetricsAddress string
+
+	// CacheWarmupTimeout is how long the caches of the synced kinds may take
+	// to fill up after a start before the agent reports itself ready anyway.
+	// The caches of the remote claims are warmed up as well if WatchRemote is
+	// set. The agent is ready right away if it's zero.
+	CacheWarmupTimeout time.Duration
+
+	// CacheTrim is what's removed from the objects that are cached, so that
+	// they take up less memory.
+	CacheTrim trim.Options
+
+	// WatchRemote watches the remote claims so that their changes are pulled
+	// within seconds rather than on the next poll.
+	WatchRemote bool
+
+	// LocalFaults and RemoteFaults are injected into the requests made to
+	// the local and the remote cluster respectively by end-to-end tests.
+	LocalFaults  fault.Spec
+	RemoteFaults fault.Spec
 }
 
 // Run adds all controllers and starts the manager that will watch the local cluster.
 func (a *Agent) Run(log logging.Logger, period time.Duration) error {
 	log.Debug("Starting", "sync-period", period.String())
 
+	// Warnings returned by either api-server are logged and surfaced on the
+	// claims whose sync caused them.
+	transport := remote.ConfigureTransport(a.ClusterConfig, a.RemoteTransport)
+	wrap := warning.NewTransportWrapper(log)
+	a.ClusterConfig.Wrap(wrap)
+	localConfig := ctrl.GetConfigOrDie()
+	localConfig.Wrap(wrap)
+	if !a.RemoteFaults.Empty() {
+		a.ClusterConfig.Wrap(fault.NewTransportWrapper(a.RemoteFaults, log))
+	}
+	if !a.LocalFaults.Empty() {
+		localConfig.Wrap(fault.NewTransportWrapper(a.LocalFaults, log))
+	}
+	api, err := metrics.NewRemoteAPI(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create remote API metrics")
+	}
+	a.ClusterConfig.Wrap(metrics.NewTransportWrapper(api))
+
 	clusterRemoteClient, err := client.New(a.ClusterConfig, client.Options{})
 	if err != nil {
 		return errors.Wrap(err, "cannot create cluster remote client")
 	}
+	if a.DryRun {
+		clusterRemoteClient = dryrun.NewClient(clusterRemoteClient, "remote", log)
+	}
+	// Claims and their inputs are read through a client that only lists the
+	// objects the agent owns, while definitions are read in full.
+	claimsRemoteClient := client.Client(clusterRemoteClient)
+	if len(a.RemoteOwnerLabels) > 0 {
+		claimsRemoteClient = remote.NewScopedClient(clusterRemoteClient, labels.SelectorFromSet(a.RemoteOwnerLabels))
+	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{SyncPeriod: &period, MetricsBindAddress: "127.0.0.1:8080"})
+	// Only the objects that are listed and watched into the cache are trimmed,
+	// the ones that are written are sent as they are.
+	newCache := func(cfg *rest.Config, o cache.Options) (cache.Cache, error) {
+		if !a.CacheTrim.Empty() {
+			cfg = rest.CopyConfig(cfg)
+			cfg.Wrap(trim.NewTransportWrapper(a.CacheTrim))
+		}
+		return cache.New(cfg, o)
+	}
+	metricsAddress := a.MetricsAddress
+	if metricsAddress == "" {
+		metricsAddress = "127.0.0.1:8080"
+	}
+	newClient := manager.DefaultNewClient
+	if a.DryRun {
+		newClient = dryrun.NewClientFunc("local", log)
+	}
+	o := ctrl.Options{SyncPeriod: &period, MetricsBindAddress: metricsAddress, HealthProbeBindAddress: a.HealthProbeAddress, NewCache: newCache, NewClient: newClient}
+	a.leaderElection(&o)
+	mgr, err := ctrl.NewManager(localConfig, o)
 	if err != nil {
 		return errors.Wrap(err, "cannot start local cluster manager")
 	}
+	if a.LeaderElection {
+		log.Info("Standing by until elected leader", "lock", leaderElectionID)
+		if err := mgr.Add(manager.RunnableFunc(func(_ <-chan struct{}) error {
+			log.Info("Elected leader, starting to sync")
+			return nil
+		})); err != nil {
+			return errors.Wrap(err, "cannot add leader election notice")
+		}
+	}
 
 	if err := crds.AddToScheme(mgr.GetScheme()); err != nil {
 		return errors.Wrap(err, "Cannot add CustomResourceDefinition API to scheme")
@@ -58,10 +446,478 @@ func (a *Agent) Run(log logging.Logger, period time.Duration) error {
 	if err := apiextensions.AddToScheme(mgr.GetScheme()); err != nil {
 		return errors.Wrap(err, "Cannot add Crossplane apiextensions API to scheme")
 	}
+	// A restore of the remote cluster is detected through individual claims
+	// but all claims need to be verified against it.
+	resync := claim.NewResyncTrigger()
+
+	// The switch is read bypassing the cache so that the agent can be
+	// suspended even while its informers are struggling.
+	sw := suspend.NewConfigMapSwitch(mgr.GetAPIReader(), types.NamespacedName{Namespace: a.Namespace, Name: suspend.ConfigMapName}, a.Suspend)
+	co := []claim.ReconcilerOption{
+		claim.WithMaxObjectSize(a.MaxClaimSize),
+		claim.WithResyncTrigger(resync),
+		claim.WithRemoteUIDPolicy(a.RemoteUIDPolicy),
+		claim.WithNamespaceMapper(claim.NamespaceMap(a.NamespaceMapping)),
+		claim.WithNamespaceEnsurer(claim.NewAPINamespaceEnsurer(claimsRemoteClient, a.RemoteNamespacePolicy)),
+		claim.WithVersionTable(a.VersionTable),
+		claim.WithStatusPolicies(a.StatusPolicies),
+		claim.WithConditionMapping(a.ConditionMapping),
+		claim.WithResyncRequest(claim.NewNamespaceResyncRequest(mgr.GetClient())),
+		claim.WithClusterName(a.ClusterName),
+		claim.WithOwnerLabels(a.RemoteOwnerLabels),
+		claim.WithClaimSelector(a.ClaimSelector),
+		claim.WithSuspendSwitch(sw),
+	}
+	// The fencing token is acquired once the agent becomes the leader, if
+	// leader election is enabled, so that a replica that takes over gets a
+	// greater token than the one it took over from.
+	fencing := claim.NewLeaseFencingToken(clusterRemoteClient, types.NamespacedName{Namespace: a.RegistrationNamespace, Name: claim.FencingLeaseNameOf(a.ClusterName)}, log)
+	if err := mgr.Add(fencing); err != nil {
+		return errors.Wrap(err, "cannot add fencing token")
+	}
+	co = append(co, claim.WithFencingToken(fencing))
+	io := []claim.InputSyncerOption{claim.WithInputOwnerLabels(a.RemoteOwnerLabels)}
+	if a.SecretEnvelope != nil {
+		co = append(co, claim.WithSecretEnvelope(a.SecretEnvelope))
+		io = append(io, claim.WithInputSecretEnvelope(a.SecretEnvelope))
+	}
+	if a.SyncRecorder != nil {
+		co = append(co, claim.WithSyncRecorder(a.SyncRecorder))
+	}
+	deps := claim.DependencyResolverChain{claim.NewAPIDependencyResolver(claimsRemoteClient)}
+	if a.SyncInputs {
+		deps = append(claim.DependencyResolverChain{claim.NewInputSyncer(mgr.GetClient(), claimsRemoteClient, io...)}, deps...)
+	}
+	co = append(co, claim.WithDependencyResolver(deps))
+	if a.RequireApproval {
+		co = append(co, claim.WithApprovalRequired())
+	}
+	if a.RemoteFinalizer {
+		co = append(co, claim.WithRemoteFinalizer())
+	}
+	if a.RecordLastPushedSpec {
+		co = append(co, claim.WithLastPushedSpec(claim.DefaultMaxPushedSpecSize))
+	}
+	if a.LabelPolicy.Enabled() {
+		co = append(co, claim.WithLabelPolicy(a.LabelPolicy))
+	}
+	if a.DetectDrift {
+		co = append(co, claim.WithDriftDetection())
+	}
+	if a.ValidateSchema {
+		co = append(co, claim.WithSchemaValidator(claim.NewAPISchemaValidator(mgr.GetClient(), clusterRemoteClient)))
+	}
+	if a.CheckQuota {
+		co = append(co, claim.WithQuotaChecker(claim.NewAPIQuotaChecker(mgr.GetClient(), clusterRemoteClient)))
+	}
+	if a.AdmissionWebhook != "" {
+		co = append(co, claim.WithAdmissionGate(claim.NewWebhookAdmissionGate(a.AdmissionWebhook, a.AdmissionWebhookTimeout)))
+	}
+	if len(a.FanOutConfigs) > 0 {
+		remotes := make(map[string]client.Client, len(a.FanOutConfigs))
+		for name, cfg := range a.FanOutConfigs {
+			cfg.Wrap(wrap)
+			c, err := client.New(cfg, client.Options{})
+			if err != nil {
+				return errors.Wrapf(err, "cannot create fan-out remote client %s", name)
+			}
+			if a.DryRun {
+				c = dryrun.NewClient(c, name, log)
+			}
+			remotes[name] = c
+		}
+		co = append(co, claim.WithFanOut(claim.NewFanOut(remotes, claim.NamespaceMap(a.NamespaceMapping))))
+	}
+	if a.MaxDefinitionStaleness > 0 && a.HoldOnStaleDefinitions {
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: staleness.ConfigMapName}
+		co = append(co, claim.WithDefinitionsGate(staleness.NewGate(mgr.GetAPIReader(), nn, a.MaxDefinitionStaleness)))
+	}
+	locker := claim.NamespaceLocker(claim.NewNopNamespaceLocker())
+	if a.ClusterName != "" {
+		locker = claim.NewLeaseLocker(clusterRemoteClient, a.ClusterName, claim.LeaseDuration(a.SyncInterval))
+		co = append(co, claim.WithNamespaceLocker(locker))
+	}
+	if a.CanaryNamespace != "" {
+		co = append(co, claim.WithCanaryValidator(claim.NewDryRunCanaryValidator(clusterRemoteClient, a.CanaryNamespace)))
+	}
+	if a.ErrorBudget > 0 {
+		budget := rollout.NewErrorBudget(a.ErrorBudget, a.ErrorBudgetWindow)
+		co = append(co, claim.WithSyncObserver(a.ClaimSelector.ObserveSelected(budget)))
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: rollout.ConfigMapName}
+		p := rollout.NewPublisher(budget, runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), nn, 30*time.Second, log)
+		if err := mgr.Add(p); err != nil {
+			return errors.Wrap(err, "cannot add error budget publisher")
+		}
+	}
+	// The snapshot and the status of the agent are published from the same
+	// record of the claims, which is kept only if either is published.
+	var claimTracker *registration.ClaimTracker
+	trackClaims := func() *registration.ClaimTracker {
+		if claimTracker == nil {
+			claimTracker = registration.NewClaimTracker(claim.ObservationExpiry(a.SyncInterval))
+			co = append(co, claim.WithSyncObserver(claimTracker))
+		}
+		return claimTracker
+	}
+	if a.SnapshotPeriod > 0 {
+		if a.ClusterName == "" {
+			return errors.New("cluster name is required to publish snapshots")
+		}
+		nn := types.NamespacedName{Namespace: a.RegistrationNamespace, Name: registration.Name(a.ClusterName)}
+		p := registration.NewPublisher(trackClaims(), runtimeresource.NewAPIPatchingApplicator(clusterRemoteClient), nn, a.SnapshotPeriod, log)
+		p.Set(registration.KeyCluster, a.ClusterName)
+		p.Set(registration.KeyAgentVersion, version.Version)
+		dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			return errors.Wrap(err, "cannot create discovery client")
+		}
+		if v, err := dc.ServerVersion(); err == nil {
+			p.Set(registration.KeyKubernetesVersion, v.GitVersion)
+		}
+		if err := mgr.Add(p); err != nil {
+			return errors.Wrap(err, "cannot add snapshot publisher")
+		}
+	}
+	if a.OnboardingBatchSize > 0 {
+		throttle := onboarding.NewThrottle(time.Now(), a.OnboardingBatchSize, a.OnboardingInterval)
+		co = append(co, claim.WithOnboardingThrottle(throttle))
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: onboarding.ConfigMapName}
+		if err := mgr.Add(onboarding.NewReporter(throttle, runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), nn, a.OnboardingInterval, log)); err != nil {
+			return errors.Wrap(err, "cannot add onboarding progress reporter")
+		}
+	}
+	if a.IdleThreshold > 0 {
+		tracker := idle.NewTracker(a.IdleThreshold, 10*time.Minute)
+		co = append(co, claim.WithSyncObserver(tracker))
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: idle.ConfigMapName}
+		if err := mgr.Add(idle.NewReporter(tracker, runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), nn, time.Hour, log)); err != nil {
+			return errors.Wrap(err, "cannot add idle claim reporter")
+		}
+	}
+	if a.SyncStatsPeriod > 0 {
+		rec := stats.NewRecorder(mgr.GetClient(), a.SyncStatsPeriod, log)
+		co = append(co, claim.WithSyncObserver(rec))
+		if err := mgr.Add(rec); err != nil {
+			return errors.Wrap(err, "cannot add sync statistics recorder")
+		}
+	}
+	if a.PlatformHealthPeriod > 0 {
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: platform.ConfigMapName}
+		r := platform.NewReporter(platform.NewChecker(mgr.GetClient(), clusterRemoteClient), runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), nn, a.PlatformHealthPeriod, log)
+		if err := mgr.Add(r); err != nil {
+			return errors.Wrap(err, "cannot add platform health reporter")
+		}
+	}
+	if a.ConnectionProbePeriod > 0 {
+		// Permissions are reviewed by creating objects, which a dry-run client
+		// would only log.
+		rc, err := client.New(a.ClusterConfig, client.Options{})
+		if err != nil {
+			return errors.Wrap(err, "cannot create connection probe client")
+		}
+		p, err := connection.NewProber(a.ClusterConfig, rc)
+		if err != nil {
+			return errors.Wrap(err, "cannot create connection prober")
+		}
+		cp, err := metrics.NewConnectionProbe(ctrlmetrics.Registry)
+		if err != nil {
+			return errors.Wrap(err, "cannot create connection probe metrics")
+		}
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: connection.ConfigMapName}
+		if err := mgr.Add(connection.NewReporter(p, runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), nn, a.ConnectionProbePeriod, cp, log)); err != nil {
+			return errors.Wrap(err, "cannot add connection status reporter")
+		}
+	}
+	if a.CloudEventsSink != "" {
+		source := "crossplane-agent"
+		if a.ClusterName != "" {
+			source += "/" + a.ClusterName
+		}
+		em := lifecycle.NewEmitter(lifecycle.NewHTTPSink(a.CloudEventsSink, 10*time.Second), source, lifecycle.WithLogger(log))
+		co = append(co, claim.WithSyncObserver(em))
+		if err := mgr.Add(em); err != nil {
+			return errors.Wrap(err, "cannot add lifecycle event emitter")
+		}
+	}
+	prop, err := metrics.NewPropagation(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create propagation metrics")
+	}
+	co = append(co, claim.WithPropagationMetrics(prop))
+	cond, err := metrics.NewConditions(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create condition metrics")
+	}
+	rec := claim.NewConditionRecorder(cond, claim.ObservationExpiry(a.SyncInterval))
+	if err := mgr.Add(rec); err != nil {
+		return errors.Wrap(err, "cannot add condition recorder")
+	}
+	co = append(co, claim.WithSyncObserver(rec))
+	sm, err := metrics.NewSync(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create sync metrics")
+	}
+	co = append(co, claim.WithSyncObserver(claim.NewSyncCounter(sm)))
+	if a.CacheWarmupTimeout > 0 {
+		wm, err := metrics.NewWarmup(ctrlmetrics.Registry)
+		if err != nil {
+			return errors.Wrap(err, "cannot create cache warm-up metrics")
+		}
+		kinds := func(ctx context.Context) ([]schema.GroupVersionKind, error) {
+			return warmup.ClaimKinds(ctx, mgr.GetAPIReader(), a.PassthroughKinds)
+		}
+		w := warmup.NewWarmer(mgr.GetCache(), kinds, a.CacheWarmupTimeout, wm, log)
+		if err := mgr.Add(w); err != nil {
+			return errors.Wrap(err, "cannot add cache warmer")
+		}
+		if err := mgr.AddReadyzCheck("cache-warmup", w.Ready); err != nil {
+			return errors.Wrap(err, "cannot add cache warm-up readiness check")
+		}
+	} else if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
+		return errors.Wrap(err, "cannot add readiness check")
+	}
+	conn, err := metrics.NewConnectivity(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create connectivity metrics")
+	}
+	monitor, err := remote.NewMonitor(a.ClusterConfig, a.HealthCheckPeriod, remote.WithLogger(log), remote.WithMetrics(conn), remote.WithProbeFailureHandler(transport.CloseIdleConnections), remote.WithDisconnectedAfter(a.DisconnectedAfter))
+	if err != nil {
+		return errors.Wrap(err, "cannot create remote cluster monitor")
+	}
+	if err := mgr.Add(monitor); err != nil {
+		return errors.Wrap(err, "cannot add remote cluster monitor")
+	}
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return errors.Wrap(err, "cannot add liveness check")
+	}
+	if err := mgr.AddReadyzCheck("remote-connectivity", monitor.ReadyCheck(a.UnreadyAfter)); err != nil {
+		return errors.Wrap(err, "cannot add remote connectivity readiness check")
+	}
+	if a.HubConfig != nil {
+		if err := mgr.Add(a.HubConfig); err != nil {
+			return errors.Wrap(err, "cannot add hub configuration watcher")
+		}
+	}
+	if a.RemoteKubeconfig != nil {
+		if err := mgr.Add(a.RemoteKubeconfig); err != nil {
+			return errors.Wrap(err, "cannot add remote kubeconfig watcher")
+		}
+	}
+	if a.DisconnectedAfter > 0 {
+		co = append(co, claim.WithConnectivity(monitor))
+
+		// Anything may have changed in the remote cluster while the agent
+		// was disconnected from it, so all claims are verified.
+		monitor.OnRecover(resync.Trigger)
+	}
+	if a.StatusPeriod > 0 {
+		kinds := func(ctx context.Context) ([]schema.GroupVersionKind, error) {
+			return warmup.ClaimKinds(ctx, mgr.GetAPIReader(), a.PassthroughKinds)
+		}
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: status.ConfigMapName}
+		if err := mgr.Add(status.NewReporter(trackClaims(), kinds, monitor, runtimeresource.NewAPIPatchingApplicator(mgr.GetClient()), nn, a.StatusPeriod, log)); err != nil {
+			return errors.Wrap(err, "cannot add agent status reporter")
+		}
+	}
+
+	xo := []xrd.ReconcilerOption{}
+	if a.SyncInterval > 0 {
+		co = append(co, claim.WithSyncInterval(a.SyncInterval))
+		xo = append(xo, xrd.WithSyncInterval(a.SyncInterval))
+	}
+	if a.ErrorRetryInterval > 0 {
+		co = append(co, claim.WithErrorRetryInterval(a.ErrorRetryInterval, a.MaxErrorRetryInterval))
+		xo = append(xo, xrd.WithErrorRetryInterval(a.ErrorRetryInterval, a.MaxErrorRetryInterval))
+	}
+	if a.WatchRemote {
+		rc, err := cache.New(a.ClusterConfig, cache.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			return errors.Wrap(err, "cannot create remote cluster cache")
+		}
+		if err := mgr.Add(rc); err != nil {
+			return errors.Wrap(err, "cannot add remote cluster cache")
+		}
+		xo = append(xo, xrd.WithRemoteInformers(rc))
+
+		// The remote claims are watched only once the controllers of their
+		// kinds are started by the leader, so their caches are warmed up
+		// beforehand to let a replica that takes over start syncing right
+		// away.
+		if a.CacheWarmupTimeout > 0 {
+			kinds := func(ctx context.Context) ([]schema.GroupVersionKind, error) {
+				return warmup.ClaimKinds(ctx, mgr.GetAPIReader(), a.PassthroughKinds)
+			}
+			w := warmup.NewWarmer(rc, kinds, a.CacheWarmupTimeout, nil, log.WithValues("cluster", "remote"), warmup.WithoutSecrets())
+			if err := mgr.Add(w); err != nil {
+				return errors.Wrap(err, "cannot add remote cluster cache warmer")
+			}
+			if err := mgr.AddReadyzCheck("remote-cache-warmup", w.Ready); err != nil {
+				return errors.Wrap(err, "cannot add remote cache warm-up readiness check")
+			}
+		}
+	}
+	var proxy *conversion.Proxy
+	var pc *conversion.ProxyConfig
+	if a.ConversionProxyService != "" {
+		parts := strings.SplitN(a.ConversionProxyService, "/", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("conversion proxy service %q is not in namespace/name form", a.ConversionProxyService)
+		}
+		ca, err := ioutil.ReadFile(filepath.Join(a.ConversionProxyCertDir, "ca.crt"))
+		if err != nil {
+			return errors.Wrap(err, "cannot read the CA bundle of the conversion proxy")
+		}
+		cs, err := kubernetes.NewForConfig(a.ClusterConfig)
+		if err != nil {
+			return errors.Wrap(err, "cannot create remote clientset")
+		}
+		proxy = conversion.NewProxy(cs.CoreV1().RESTClient(), ":9443", filepath.Join(a.ConversionProxyCertDir, "tls.crt"), filepath.Join(a.ConversionProxyCertDir, "tls.key"), log)
+		if err := mgr.Add(proxy); err != nil {
+			return errors.Wrap(err, "cannot add conversion proxy")
+		}
+		pc = &conversion.ProxyConfig{Service: crds.ServiceReference{Namespace: parts[0], Name: parts[1]}, CABundle: ca}
+	}
+	xo = append(xo, xrd.WithConversionConfigurator(conversion.NewConfigurator(a.ConversionPolicy, proxy, pc)))
+	if a.StatusUpdateQPS > 0 {
+		co = append(co, claim.WithStatusScheduler(claim.NewStatusScheduler(a.StatusUpdateQPS, a.StatusUpdateBurst)))
+	}
+	if a.SyncQPS > 0 {
+		co = append(co, claim.WithSyncThrottle(claim.NewStatusScheduler(a.SyncQPS, a.SyncBurst)))
+	}
+	if a.BackpressureThreshold > 0 {
+		reg := backpressure.NewRegulator(a.BackpressureThreshold, backpressure.WithMaxConcurrency(a.MaxConcurrentSyncs))
+		co = append(co, claim.WithSyncObserver(reg))
+		xo = append(xo, xrd.WithRegulator(reg))
+
+		// Claims that failed during an outage of the remote cluster would
+		// otherwise keep being retried slowly after it's over.
+		monitor.OnReconnect(reg.Reset)
+	}
+
+	remotes := make([]claim.Remote, 0, len(a.RemoteConfigs))
+	for name, rcfg := range a.RemoteConfigs {
+		rm, err := a.newRemote(name, rcfg, mgr, resync, wrap, api, log)
+		if err != nil {
+			return err
+		}
+		remotes = append(remotes, rm)
+	}
+
 	// TODO(muvaf): Need to pass in the default config.
-	if err := xrd.Setup(mgr, clusterRemoteClient, log); err != nil {
+	cfg := controllers.Config{Log: log, Claims: &controllers.ClaimsConfig{Options: co, XRDOptions: xo, Passthrough: a.PassthroughKinds, Remotes: remotes}}
+	if err := controllers.SetupWithManager(mgr, claimsRemoteClient, cfg); err != nil {
 		return errors.Wrap(err, "cannot setup CompositeResourceDefinition reconciler")
 	}
+	if a.DecommissionNamespaces {
+		kinds := func(ctx context.Context) ([]schema.GroupVersionKind, error) {
+			return warmup.ClaimKinds(ctx, mgr.GetAPIReader(), a.PassthroughKinds)
+		}
+		do := []decommission.ReconcilerOption{
+			decommission.WithNamespaceMapper(claim.NamespaceMap(a.NamespaceMapping)),
+			decommission.WithVersionTable(a.VersionTable),
+			decommission.WithReportNamespace(a.Namespace),
+			decommission.WithSuspendSwitch(sw),
+			decommission.WithClusterName(a.ClusterName),
+			decommission.WithNamespaceLocker(locker),
+			decommission.WithFencingToken(fencing),
+		}
+		if err := decommission.Setup(mgr, claimsRemoteClient, log, kinds, do...); err != nil {
+			return errors.Wrap(err, "cannot setup namespace decommissioning controller")
+		}
+	}
 
 	return errors.Wrap(mgr.Start(ctrl.SetupSignalHandler()), "cannot start controller manager")
 }
+
+// newRemote returns the additional remote cluster with the supplied name and
+// config, whose claim Reconcilers write to it in place of the default remote
+// cluster, and adds its monitor to the supplied manager.
+func (a *Agent) newRemote(name string, cfg *rest.Config, mgr manager.Manager, resync *claim.ResyncTrigger, wrap func(http.RoundTripper) http.RoundTripper, api *metrics.RemoteAPI, log logging.Logger) (claim.Remote, error) {
+	log = log.WithValues("remote", name)
+	transport := remote.ConfigureTransport(cfg, a.RemoteTransport)
+	cfg.Wrap(wrap)
+	if !a.RemoteFaults.Empty() {
+		cfg.Wrap(fault.NewTransportWrapper(a.RemoteFaults, log))
+	}
+	cfg.Wrap(metrics.NewTransportWrapper(api))
+
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return claim.Remote{}, errors.Wrapf(err, "cannot create remote client %s", name)
+	}
+	if a.DryRun {
+		c = dryrun.NewClient(c, name, log)
+	}
+	cc := client.Client(c)
+	if len(a.RemoteOwnerLabels) > 0 {
+		cc = remote.NewScopedClient(c, labels.SelectorFromSet(a.RemoteOwnerLabels))
+	}
+
+	deps := claim.DependencyResolverChain{claim.NewAPIDependencyResolver(cc)}
+	if a.SyncInputs {
+		io := []claim.InputSyncerOption{claim.WithInputOwnerLabels(a.RemoteOwnerLabels)}
+		if a.SecretEnvelope != nil {
+			io = append(io, claim.WithInputSecretEnvelope(a.SecretEnvelope))
+		}
+		deps = append(claim.DependencyResolverChain{claim.NewInputSyncer(mgr.GetClient(), cc, io...)}, deps...)
+	}
+	// Each remote cluster counts the fencing tokens of the agents that write
+	// to it.
+	fencing := claim.NewLeaseFencingToken(c, types.NamespacedName{Namespace: a.RegistrationNamespace, Name: claim.FencingLeaseNameOf(a.ClusterName)}, log)
+	if err := mgr.Add(fencing); err != nil {
+		return claim.Remote{}, errors.Wrapf(err, "cannot add fencing token %s", name)
+	}
+	co := []claim.ReconcilerOption{
+		claim.WithNamespaceEnsurer(claim.NewAPINamespaceEnsurer(cc, a.RemoteNamespacePolicy)),
+		claim.WithDependencyResolver(deps),
+		claim.WithFencingToken(fencing),
+	}
+	if a.ClusterName != "" {
+		co = append(co, claim.WithNamespaceLocker(claim.NewLeaseLocker(c, a.ClusterName, claim.LeaseDuration(a.SyncInterval))))
+	}
+	if a.CanaryNamespace != "" {
+		co = append(co, claim.WithCanaryValidator(claim.NewDryRunCanaryValidator(c, a.CanaryNamespace)))
+	}
+	if a.ValidateSchema {
+		co = append(co, claim.WithSchemaValidator(claim.NewAPISchemaValidator(mgr.GetClient(), c)))
+	}
+	if a.CheckQuota {
+		co = append(co, claim.WithQuotaChecker(claim.NewAPIQuotaChecker(mgr.GetClient(), c)))
+	}
+
+	monitor, err := remote.NewMonitor(cfg, a.HealthCheckPeriod, remote.WithLogger(log), remote.WithProbeFailureHandler(transport.CloseIdleConnections), remote.WithDisconnectedAfter(a.DisconnectedAfter))
+	if err != nil {
+		return claim.Remote{}, errors.Wrapf(err, "cannot create remote cluster monitor %s", name)
+	}
+	if err := mgr.Add(monitor); err != nil {
+		return claim.Remote{}, errors.Wrapf(err, "cannot add remote cluster monitor %s", name)
+	}
+	if a.DisconnectedAfter > 0 {
+		co = append(co, claim.WithConnectivity(monitor))
+		monitor.OnRecover(resync.Trigger)
+	}
+	return claim.Remote{Name: name, Client: cc, Options: co}, nil
+}
+
+// leaderElectionID is the name of the lock the replicas of the agent running
+// in local mode elect their leader with.
+const leaderElectionID = "crossplane-agent-local"
+
+// leaderElection configures the supplied manager options to elect a leader
+// among the replicas of the agent if it's enabled. The renew deadline and the
+// retry period keep the ratio of their defaults to the lease duration.
+func (a *Agent) leaderElection(o *ctrl.Options) {
+	if !a.LeaderElection {
+		return
+	}
+	o.LeaderElection = true
+	o.LeaderElectionID = leaderElectionID
+	o.LeaderElectionNamespace = a.LeaderElectionNamespace
+	if o.LeaderElectionNamespace == "" {
+		o.LeaderElectionNamespace = a.Namespace
+	}
+	if a.LeaseDuration > 0 {
+		renew, retry := a.LeaseDuration*2/3, a.LeaseDuration*2/15
+		o.LeaseDuration, o.RenewDeadline, o.RetryPeriod = &a.LeaseDuration, &renew, &retry
+	}
+}
@@ -17,19 +17,54 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/alecthomas/kingpin.v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
 
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
+	"github.com/crossplane/agent/cmd/agent/bundle"
+	"github.com/crossplane/agent/cmd/agent/generate"
+	"github.com/crossplane/agent/cmd/agent/hub"
 	"github.com/crossplane/agent/cmd/agent/local"
+	"github.com/crossplane/agent/cmd/agent/pushed"
 	"github.com/crossplane/agent/cmd/agent/remote"
+	"github.com/crossplane/agent/cmd/agent/validate"
+	"github.com/crossplane/agent/pkg/alerts"
+	"github.com/crossplane/agent/pkg/connection"
+	"github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/conversion"
+	"github.com/crossplane/agent/pkg/dryrun"
+	"github.com/crossplane/agent/pkg/envelope"
+	"github.com/crossplane/agent/pkg/fault"
+	"github.com/crossplane/agent/pkg/hubconfig"
+	"github.com/crossplane/agent/pkg/preflight"
+	agentremote "github.com/crossplane/agent/pkg/remote"
+	"github.com/crossplane/agent/pkg/replay"
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/status"
+	"github.com/crossplane/agent/pkg/suspend"
+	"github.com/crossplane/agent/pkg/trim"
 )
 
 func main() {
@@ -41,9 +76,248 @@ func main() {
 	s := app.Command("sync", "Start syncing to Crossplane.").Default()
 	csa := s.Flag("cluster-kubeconfig", "File path of the kubeconfig of ServiceAccount to be used to get cluster-scoped resources like CRDs.").Envar("CLUSTER_KUBECONFIG").String()
 	dsa := s.Flag("default-kubeconfig", "File path of the  kubeconfig of ServiceAccount to be used for all namespaces that do not have override annotations.").Envar("DEFAULT_KUBECONFIG").String()
-	mode := s.Flag("mode", "The mode of operation to decide whether you would like to run the controllers that watch the local cluster or the remote cluster.").Enum("local", "remote")
+	strict := s.Flag("strict", "Check the configuration, and whether both clusters are reachable with the given credentials and serve the configured kinds, before starting, and exit with every problem that is found rather than fail at runtime.").Bool()
+	mode := s.Flag("mode", "The mode of operation to decide whether you would like to run the controllers that watch the local cluster or the remote cluster, or the aggregation of agent snapshots in the remote cluster.").Enum("local", "remote", "hub")
+	maxClaimSize := s.Flag("max-claim-size", "The largest serialized claim, in bytes, that will be pushed to the remote cluster. Set to 0 to disable the check.").Default(strconv.Itoa(claim.DefaultMaxObjectSize)).Int()
+	canaryNamespace := s.Flag("canary-namespace", "The namespace in the remote cluster where claims are validated with a server-side dry-run before being pushed to their actual namespace.").String()
+	namespace := s.Flag("namespace", "The namespace in the local cluster where Agent keeps its bookkeeping objects.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
+	errorBudget := s.Flag("error-budget", "The fraction of claim syncs that may fail before the rollout of CRD and Composition updates is paused. Set to 0 to disable.").Default("0").Float64()
+	errorBudgetWindow := s.Flag("error-budget-window", "The period over which the error budget is calculated.").Default("10m").Duration()
+	backpressureThreshold := s.Flag("backpressure-threshold", "The fraction of claim syncs that may fail before all claim controllers requeue less often and run fewer syncs at once. Set to 0 to disable.").Default("0.5").Float64()
+	remoteUIDPolicy := s.Flag("remote-uid-policy", "What to do when a remote claim is deleted and created again out-of-band. Either adopt it, alarm and stop syncing, or recreate it from the local claim.").Default(string(claim.UIDPolicyAlarm)).Enum(string(claim.UIDPolicyAdopt), string(claim.UIDPolicyAlarm), string(claim.UIDPolicyRecreate))
+	remoteNamespacePolicy := s.Flag("remote-namespace-policy", "What to do when the remote namespace a claim is synced to doesn't exist. Either fail until it's created or recreate it.").Default(string(claim.NamespacePolicyFail)).Enum(string(claim.NamespacePolicyFail), string(claim.NamespacePolicyRecreate))
+	syncInputs := s.Flag("sync-inputs", "Sync the Secrets and ConfigMaps that claims reference in their input annotations to the remote namespace before the claims.").Bool()
+	requireApproval := s.Flag("require-approval", "Hold claims until they have the "+resource.AnnotationKeyApproved+": \"true\" annotation before pushing them to the remote cluster for the first time.").Bool()
+	remoteFinalizer := s.Flag("remote-finalizer", "Add the "+claim.RemoteFinalizer+" finalizer to remote claims so that their deletion by anyone but the agent is acknowledged on the local claim before they're let go and created again.").Bool()
+	recordLastPushedSpec := s.Flag("record-last-pushed-spec", "Record the spec that was last pushed to the remote cluster in the "+resource.AnnotationKeyLastPushedSpec+" annotation of local claims. Specs larger than "+strconv.Itoa(claim.DefaultMaxPushedSpecSize)+" bytes are recorded as their digest.").Bool()
+	detectDrift := s.Flag("detect-drift", "Report remote claims whose spec was changed out-of-band with the "+string(resource.ReasonAgentSyncDrifted)+" reason and push the desired spec again. Implies --record-last-pushed-spec.").Bool()
+	admissionWebhook := s.Flag("admission-webhook", "The URL claims, without their cluster specific metadata, are posted to as {\"object\": ...} before they're pushed. It has to respond with {\"allowed\": true} for them to be pushed, otherwise they're reported with the "+string(resource.ReasonAgentSyncRejected)+" reason and its message. Claims are not reviewed if it's empty.").String()
+	admissionWebhookTimeout := s.Flag("admission-webhook-timeout", "How long to wait for the admission webhook to respond.").Default("10s").Duration()
+	checkQuota := s.Flag("check-quota", "Check the object count quotas of the remote namespace of claims before they're created there, and report the quotas their creation would exceed on the claims with the "+string(resource.ReasonAgentSyncQuotaExceeded)+" reason.").Bool()
+	validateSchema := s.Flag("validate-schema", "Validate claims against the schema of their CRD in the remote cluster before they're pushed, and report the fields that don't conform to it on the claims with the "+string(resource.ReasonAgentSyncSchemaInvalid)+" reason.").Bool()
+	idleThreshold := s.Flag("idle-claim-threshold", "How long a claim may be ready without its Ready condition changing or its "+resource.AnnotationKeyLastTouched+" annotation being updated before it's reported as idle. Set to 0 to disable.").Default("0").Duration()
+	namespaceMapping := s.Flag("namespace-mapping", "Sync the claims in a local namespace to a remote namespace with a different name, given as local=remote. Claims that were synced to another remote namespace before are relocated.").StringMap()
+	conversionPolicy := s.Flag("conversion-policy", "How claim CRDs that are converted by a webhook in the remote cluster are converted locally, unless their CRD or XRD has the "+conversion.AnnotationKeyPolicy+" annotation. Either strip the webhook or proxy to it.").Default(string(conversion.PolicyNone)).Enum(string(conversion.PolicyNone), string(conversion.PolicyProxy))
+	conversionProxyService := s.Flag("conversion-proxy-service", "The local Service, in namespace/name form, that exposes the conversion proxy on port 443. The proxy is not run if it's empty.").String()
+	conversionProxyCertDir := s.Flag("conversion-proxy-cert-dir", "The directory with the tls.crt, tls.key and ca.crt files the conversion proxy serves with.").Default("/tmp/conversion/certs").String()
+	clusterName := s.Flag("cluster-name", "The name that identifies this cluster among the ones syncing to the same remote cluster. If set, a Lease is held in every remote namespace claims are synced to so that two agents never sync to the same one.").Envar("CLUSTER_NAME").String()
+	snapshotPeriod := s.Flag("snapshot-period", "How often a snapshot of claim counts, errors and versions is published into the registration ConfigMap of this cluster in the remote cluster. Set to 0 to disable.").Default("0").Duration()
+	registrationNamespace := s.Flag("registration-namespace", "The namespace in the remote cluster where the registration ConfigMap of this cluster is kept.").Default("crossplane-system").String()
+	hubConfig := s.Flag("hub-config", "Read the settings that are managed centrally from the "+hubconfig.ConfigMapName+" ConfigMap, and the "+hubconfig.ClusterConfigMapPrefix+"<cluster-name> one, in the registration namespace of the remote cluster. Their keys are the names of the flags that can be set from the hub, e.g. claim-selector or watch-remote, and flags that are set locally take precedence. The agent restarts when they change, and reports its effective configuration in the "+hubconfig.EffectiveConfigMapName+" ConfigMap.").Bool()
+	hubConfigPeriod := s.Flag("hub-config-period", "How often the settings in the remote cluster are checked for changes with --hub-config.").Default("1m").Duration()
+	heartbeatTimeout := s.Flag("heartbeat-timeout", "How old the last snapshot of an agent may get before the hub considers it unhealthy.").Default("5m").Duration()
+	passthroughKinds := s.Flag("passthrough-kind", "The kind, in Kind.version.group form, of namespaced custom resources that are synced to the remote cluster like claims even though they're not claims. Its CRD has to exist in both clusters and the agent needs access to it. Can be repeated.").Strings()
+	platformHealthPeriod := s.Flag("platform-health-period", "How often the health of the CompositeResourceDefinitions and Compositions in the remote cluster that local claims depend on is published into a ConfigMap in the agent namespace. Set to 0 to disable.").Default("1m").Duration()
+	connectionProbePeriod := s.Flag("connection-probe-period", "How often the connection to the remote cluster is probed in local mode, resolving its host, completing the TLS handshake and reviewing the permissions of the agent, and the stage that fails is published in the "+connection.ConfigMapName+" ConfigMap. Set to 0 to disable.").Default("1m").Duration()
+	syncStatsPeriod := s.Flag("sync-stats-period", "How often the statistics of the syncs of each claim, i.e. how many times it was pushed and failed, and its last error and success, are written into its "+resource.AnnotationKeySyncStats+" annotation. Set to 0 to disable.").Default("1m").Duration()
+	statusPeriod := s.Flag("status-period", "How often the overall sync state of the agent, i.e. the number of claim kinds it watches, when claims of each kind were last synced, whether the remote cluster is reachable and the claims that failed to sync, is published in the "+status.ConfigMapName+" ConfigMap in its namespace. Set to 0 to disable.").Default("1m").Duration()
+	cloudEventsSink := s.Flag("cloudevents-sink", "The URL lifecycle events of claims, i.e. when they're propagated, become ready, drift or are deleted, are posted to as CloudEvents. Events are not emitted if it's empty.").String()
+	versionTable := s.Flag("version-conversion-table", "File path of a JSON list of conversions, each with the group, kind, localVersion and remoteVersion of a claim type and the fields that moved between the versions, for claim types that are served at a different version in the remote cluster during a platform upgrade. A remoteGroup and remoteKind may be given for claim types that are relocated to another group in the remote cluster.").String()
+	statusPolicies := s.Flag("status-policy", "Which status fields of remote claims of a kind, given as Kind.group=policy, are pulled onto local claims. The policy is either Conditions, ConnectionDetails or Full. The whole status is pulled for the kinds that are not given. Can be repeated.").StringMap()
+	conditionMapping := s.Flag("condition-mapping", "The type, given as remote=local, that conditions of remote claims of the remote type are pulled onto local claims with, e.g. Provisioned=Ready. Conditions whose type is mapped to nothing, e.g. Synced=, are not pulled. Ready and Synced are pulled as they are unless they're mapped, as are the types that are not given. Can be repeated.").StringMap()
+	envelopeKeyFile := s.Flag("secret-envelope-key-file", "File path of a 32 byte AES key that the data keys of input secrets are encrypted with before they're pushed to the remote cluster. Connection secrets are not sealed by the agent, but the ones that were sealed in the remote cluster with the same key are opened with it.").String()
+	envelopeWrapCommand := s.Flag("secret-envelope-wrap-command", "The command, e.g. of age or the CLI of a KMS, that encrypts the data key of an input secret read from its stdin and writes it to its stdout. Used instead of a key file.").String()
+	envelopeUnwrapCommand := s.Flag("secret-envelope-unwrap-command", "The command, e.g. of age or the CLI of a KMS, that decrypts the data key of a connection secret that was sealed in the remote cluster read from its stdin and writes it to its stdout. Used instead of a key file.").String()
+	envelopeRequireSealed := s.Flag("secret-envelope-require-sealed", "Refuse to propagate connection secrets that were not sealed in the remote cluster rather than propagate them as they are. The agent does not seal connection secrets, so whatever writes them in the remote cluster has to.").Bool()
+	maxDefinitionStaleness := s.Flag("max-definition-staleness", "How long definitions may go without being refreshed from the remote cluster before they're reported as degraded in a ConfigMap in the agent namespace. Set to 0 to disable.").Default("0").Duration()
+	blockOnStaleDefinitions := s.Flag("block-on-stale-definitions", "Hold new claims instead of pushing them to the remote cluster while definitions are older than the maximum staleness.").Bool()
+	remoteKubeconfigs := s.Flag("remote-kubeconfig", "The name and the kubeconfig file path, given as name=path, of a remote cluster that claims with the "+resource.LabelKeyRemote+" label set to its name are synced to rather than the default one, sharing the local cache with it. Can be repeated.").StringMap()
+	fanOutKubeconfigs := s.Flag("fan-out-kubeconfig", "The name and the kubeconfig file path, given as name=path, of a remote cluster that claims with the "+resource.AnnotationKeyFanOut+" annotation are propagated to in addition to the one they're synced with. Can be repeated.").StringMap()
+	onboardingBatchSize := s.Flag("onboarding-batch-size", "How many of the claims that existed before the agent was started are synced for the first time per onboarding interval, so that they don't flood the remote cluster with creates. Set to 0 to sync them all right away.").Default("0").Int()
+	onboardingInterval := s.Flag("onboarding-interval", "How often a batch of claims that existed before the agent was started is synced for the first time, and how often the progress of their onboarding is reported.").Default("1m").Duration()
+	compositionSelector := s.Flag("composition-selector", "A label selector, e.g. environment=staging, that restricts the remote Compositions that are synced to the local cluster in remote mode. Local Compositions that don't match it are left untouched.").String()
+	serverSideApply := s.Flag("server-side-apply", "Apply the definitions synced to the local cluster in remote mode with server-side apply, keeping the fields other tooling manages on them, like annotations, rather than replacing them.").Bool()
+	fieldManager := s.Flag("field-manager", "The field manager the definitions are applied as with --server-side-apply. Agents that sync to the same cluster should use different ones.").Default(resource.DefaultFieldManager).String()
+	decommissionNamespaces := s.Flag("decommission-namespaces", "Tear down the synced claims of a deleted local namespace in a defined order, holding the namespace until their remote claims are cleaned up, and report it in a ConfigMap.").Bool()
+	claimSelector := s.Flag("claim-selector", "A label selector, e.g. team in (a,b), that restricts the local claims that are synced to the remote cluster. Claims that were synced before they stopped matching keep being synced until they're deleted.").String()
+	claimNamespaces := s.Flag("claim-namespace", "A local namespace whose claims are synced to the remote cluster. Claims in all namespaces are synced if none is given. Can be repeated.").Strings()
+	remoteOwnerLabels := s.Flag("remote-owner-label", "A label, given as key=value, that is added to the claims and inputs pushed to the remote cluster and that restricts the ones listed there, for remote clusters where the agent is only granted access to the objects it labels. Can be repeated.").StringMap()
+	requiredLabels := s.Flag("required-label", "The key of a label that claims must have to be pushed to the remote cluster, e.g. to satisfy its admission policies. Claims without it are reported with the "+string(resource.ReasonAgentSyncMissingLabels)+" reason. Can be repeated.").Strings()
+	defaultLabels := s.Flag("default-label", "A label, given as key=value, that is added to the claims pushed to the remote cluster unless they have it. The value may refer to the cluster name as "+claim.LabelVariableCluster+" and to the namespace of the claim as "+claim.LabelVariableNamespace+". Can be repeated.").StringMap()
+	metricsAddress := s.Flag("metrics-bind-address", "The address the /metrics endpoint is served at. Defaults to 127.0.0.1:8080 in local mode and 127.0.0.1:8081 in remote mode.").String()
+	healthProbeAddress := s.Flag("health-probe-bind-address", "The address the /healthz and /readyz endpoints are served at. Defaults to :8082 in local mode and :8083 in remote mode.").String()
+	unreadyAfter := s.Flag("unready-after", "How long the remote cluster may be unreachable before the agent reports itself not ready on /readyz. Set to 0 to stay ready.").Default("5m").Duration()
+	suspendAll := s.Flag("suspend", "Suspend the agent, so that nothing is propagated to or pruned from either cluster while the status of claims, the reports and the probes are kept up to date. The agent can also be suspended without a restart by setting the "+suspend.AnnotationKeySuspended+" annotation of the "+suspend.ConfigMapName+" ConfigMap in its namespace to true.").Bool()
+	cacheWarmupTimeout := s.Flag("cache-warmup-timeout", "How long the caches of all synced kinds may take to fill up after a start before the agent reports itself ready anyway. Set to 0 to be ready right away.").Default("2m").Duration()
+	cacheTrimManagedFields := s.Flag("cache-trim-managed-fields", "Remove the managed fields of objects before they're cached in local mode to save memory.").Default("true").Bool()
+	cacheTrimLastApplied := s.Flag("cache-trim-last-applied", "Remove the last-applied-configuration annotation of kubectl from objects before they're cached in local mode to save memory. The annotation is lost on local claims the agent updates.").Bool()
+	propagateSecrets := s.Flag("propagate-connection-secrets", "Watch the connection secrets of claims in the remote cluster in remote mode and propagate them to the local namespaces of their claims as soon as they change. Requires permission to list and watch Secrets in the remote cluster.").Bool()
+	recordSyncs := s.Flag("record-syncs", "File path that the sanitized claims every sync reads and pushes are appended to in local mode, so that they can be replayed against another version of the agent with the replay command. Syncs are not recorded if it's empty.").String()
+	watchRemote := s.Flag("watch-remote", "Watch the claims in the remote cluster so that their changes are pulled within seconds rather than on the next poll. Requires permission to list and watch them.").Bool()
+	syncInterval := s.Flag("sync-interval", "How often claims, and the CompositeResourceDefinitions they're of, are synced when nothing about them changed. In remote mode, how often CompositeResourceDefinitions and Compositions are synced.").Default("1m").Duration()
+	errorRetryInterval := s.Flag("error-retry-interval", "How long to wait before retrying a claim or definition that failed to sync. The wait doubles, with jitter, with each consecutive failure.").Default("30s").Duration()
+	maxErrorRetryInterval := s.Flag("max-error-retry-interval", "The longest wait before retrying a claim or definition that keeps failing to sync.").Default("5m").Duration()
+	leaderElect := s.Flag("leader-elect", "Elect a leader among the replicas of the agent so that only one of them syncs at a time while the others stand by to take over.").Bool()
+	leaderElectionNamespace := s.Flag("leader-election-namespace", "The namespace in the local cluster where the leader election lock is kept. Defaults to the namespace of the agent.").String()
+	leaseDuration := s.Flag("leader-election-lease-duration", "How long the replicas that aren't the leader wait before trying to take over from a leader that stopped renewing its lock.").Default("15s").Duration()
+	dryRun := s.Flag("dry-run", "Log the writes the agent would make to either cluster, and the diffs they would make, rather than making them.").Bool()
+	disconnectedAfter := s.Flag("disconnected-after", "How long the remote cluster may be unreachable before claims stop being synced and report that they're disconnected until it's reachable again, at which point all claims are verified. Set to 0 to disable.").Default("2m").Duration()
+	maxConcurrentSyncs := s.Flag("max-concurrent-syncs", "The number of claim syncs that may run at the same time across all claim types while no backpressure is applied.").Default("50").Int()
+	statusUpdateQPS := s.Flag("status-update-qps", "How many status updates of local claims are written per second on average across all claim types when many of them change at once. Set to 0 to disable.").Default("20").Float64()
+	statusUpdateBurst := s.Flag("status-update-burst", "How many status updates of local claims may be written at once before they're staggered.").Default("50").Int()
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	remoteDiscovery := s.Flag("remote-discovery", "Where the kubeconfig of the remote cluster is read from in local mode. Either the given kubeconfig files, the hub kubeconfig Secret of an Open Cluster Management klusterlet, or the kubeconfig Secret of a Cluster API Cluster.").Default(string(agentremote.KubeconfigSourceFile)).Enum(string(agentremote.KubeconfigSourceFile), string(agentremote.KubeconfigSourceOCM), string(agentremote.KubeconfigSourceCAPI))
+	remoteDiscoveryPeriod := s.Flag("remote-discovery-period", "How often the kubeconfig of the remote cluster is discovered again when it's not read from a file. The agent restarts when it changes, e.g. when the klusterlet rotates its client certificate.").Default("1m").Duration()
+	remoteDiscoveryRef := s.Flag("remote-discovery-ref", "The klusterlet Secret, or the Cluster API Cluster, in namespace/name form, that the kubeconfig of the remote cluster is discovered from. Defaults to the Secret the klusterlet uses.").Default(agentremote.DefaultOCMHubKubeconfigSecret.String()).String()
+
+	remoteDialTimeout := s.Flag("remote-dial-timeout", "How long establishing a connection to the remote cluster may take.").Default("30s").Duration()
+	remoteKeepAlive := s.Flag("remote-keep-alive", "How often TCP keep-alive probes are sent on idle connections to the remote cluster.").Default("30s").Duration()
+	remoteMaxIdleConns := s.Flag("remote-max-idle-conns", "How many idle connections to the remote cluster are kept open.").Default("25").Int()
+	remoteIdleConnTimeout := s.Flag("remote-idle-conn-timeout", "How long an idle connection to the remote cluster is kept open.").Default("90s").Duration()
+	remoteCABundle := s.Flag("remote-ca-bundle", "File path of a PEM encoded CA bundle that the certificate of the remote api-server has to be signed by, regardless of the CA in the kubeconfig.").String()
+	remoteSPKIPins := s.Flag("remote-spki-pin", "The base64 encoded SHA-256 hash of the SubjectPublicKeyInfo of a certificate, optionally prefixed with sha256/, that has to be in a verified chain of the certificate the remote api-server presents. Can be repeated.").Strings()
+	localFaults := s.Flag("fault-injection-local", "Faults to inject into requests to the local cluster, e.g. latency=200ms,errors=0.1,conflicts=0.05. For resilience tests only.").Hidden().String()
+	remoteFaults := s.Flag("fault-injection-remote", "Faults to inject into requests to the remote cluster, e.g. latency=200ms,errors=0.1,conflicts=0.05. For resilience tests only.").Hidden().String()
+	remoteQPS := s.Flag("remote-qps", "How many requests per second each client of a remote cluster sends to it on average. Every client built from its kubeconfig, e.g. the one claims are synced with, has its own limit. See --sync-qps for a limit across the agent. Set to 0 to keep the client-go default.").Default("0").Float32()
+	remoteBurst := s.Flag("remote-burst", "How many requests each client of a remote cluster may send to it at once. Set to 0 to keep the client-go default.").Default("0").Int()
+	syncQPS := s.Flag("sync-qps", "How many claims start syncing per second on average across all claim controllers, so that the remote clusters aren't hammered when all of them are synced at once, e.g. after a restart. Set to 0 to disable.").Default("0").Float64()
+	syncBurst := s.Flag("sync-burst", "How many claims may start syncing at once before they're staggered.").Default("100").Int()
+	healthCheckPeriod := s.Flag("remote-health-check-period", "How often the connection to the remote cluster is checked. Connections that may be dead are dropped when a check fails so that the next requests dial new ones.").Default("15s").Duration()
+
+	v := app.Command("validate", "Run claims through the same steps the agent would before pushing them, including a server-side dry-run in the remote cluster, and print the resulting remote claims.")
+	vcsa := v.Flag("cluster-kubeconfig", "File path of the kubeconfig to be used to dry-run the claims in the remote cluster.").Envar("CLUSTER_KUBECONFIG").String()
+	vFile := v.Flag("filename", "File path of the claims to validate, or - for stdin.").Short('f').Required().String()
+	vNamespaceMapping := v.Flag("namespace-mapping", "Validate the claims in a local namespace in a remote namespace with a different name, given as local=remote.").StringMap()
+	vCanaryNamespace := v.Flag("canary-namespace", "The namespace in the remote cluster where the claims are validated instead of their actual namespace.").String()
+	vMaxClaimSize := v.Flag("max-claim-size", "The largest serialized claim, in bytes, that will be pushed to the remote cluster. Set to 0 to disable the check.").Default(strconv.Itoa(claim.DefaultMaxObjectSize)).Int()
+
+	al := app.Command("alerts", "Print Prometheus Operator alerting rules for the conditions and metrics of the agent.")
+	alName := al.Flag("name", "The name of the PrometheusRule.").Default("crossplane-agent").String()
+	alNamespace := al.Flag("namespace", "The namespace of the PrometheusRule.").String()
+	alLabels := al.Flag("label", "A label of the PrometheusRule, given as key=value, e.g. to have it picked up by a Prometheus. Can be repeated.").StringMap()
+	alBlockedFor := al.Flag("blocked-for", "How long claims may be blocked or failing to sync before it's alerted on.").Default("30m").Duration()
+	alMaxLag := al.Flag("max-propagation-lag", "The propagation lag of changes to claims that is alerted on.").Default("5m").Duration()
+
+	rp := app.Command("replay", "Render the remote claims of recorded syncs again, without touching either cluster, and print the ones this version of the agent would push differently.")
+	rpFile := rp.Flag("filename", "File path of the recorded syncs, or - for stdin.").Short('f').Required().String()
+	rpNamespaceMapping := rp.Flag("namespace-mapping", "Replay the syncs of the claims in a local namespace to a remote namespace with a different name, given as local=remote.").StringMap()
+	rpVersionTable := rp.Flag("version-conversion-table", "File path of the JSON list of version conversions the syncs are replayed with.").String()
+	rpClusterName := rp.Flag("cluster-name", "The name of the cluster the syncs were recorded in.").String()
+	rpOwnerLabels := rp.Flag("remote-owner-label", "A label, given as key=value, that is added to the remote claims. Can be repeated.").StringMap()
+	rpRemoteFinalizer := rp.Flag("remote-finalizer", "Replay the syncs with the "+claim.RemoteFinalizer+" finalizer added to the remote claims.").Bool()
+
+	lp := app.Command("last-pushed", "Print the spec the agent last pushed to the remote cluster for a claim.")
+	lpKubeconfig := lp.Flag("kubeconfig", "File path of the kubeconfig of the local cluster.").Envar("KUBECONFIG").String()
+	lpNamespace := lp.Flag("namespace", "The namespace of the claim.").Short('n').Default("default").String()
+	lpKind := lp.Arg("kind", "The kind of the claim, given as Kind.version.group, e.g. MySQLInstance.v1alpha1.database.example.org.").Required().String()
+	lpName := lp.Arg("name", "The name of the claim.").Required().String()
+
+	gt := app.Command("generate-types", "Generate Go types, typed clients and informers for the claim kinds offered in the local cluster.")
+	gtKubeconfig := gt.Flag("kubeconfig", "File path of the kubeconfig of the local cluster.").Envar("KUBECONFIG").String()
+	gtOutputDir := gt.Flag("output-dir", "The directory the claims of each group version are written to, as a <group>/<version> package.").Short('o').Default(".").String()
+
+	sb := app.Command("support-bundle", "Collect the sync statistics of the claims, the logs of the agent and its configuration into a gzipped tarball for troubleshooting. Secrets are never collected.")
+	sbKubeconfig := sb.Flag("kubeconfig", "File path of the kubeconfig of the local cluster.").Envar("KUBECONFIG").String()
+	sbNamespace := sb.Flag("namespace", "The namespace the agent runs in.").Short('n').Default("crossplane-system").String()
+	sbSelector := sb.Flag("selector", "The label selector of the pods of the agent.").Default("app=crossplane-agent").String()
+	sbLogLines := sb.Flag("log-lines", "How many of the last lines of the logs of each container of the agent are collected. Set to 0 to collect all of them.").Default("10000").Int64()
+	sbPassthroughKinds := sb.Flag("passthrough-kind", "The kind, in Kind.version.group form, of custom resources that the agent syncs like claims. Can be repeated.").Strings()
+	sbOutput := sb.Flag("output", "File path the bundle is written to.").Short('o').Default("agent-support-bundle.tar.gz").String()
+
+	cmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+	if cmd == sb.FullCommand() {
+		cfg, err := clientcmd.BuildConfigFromFlags("", *sbKubeconfig)
+		if err != nil {
+			kingpin.FatalUsage("could not parse kubeconfig %s", *sbKubeconfig)
+		}
+		passthrough := make([]schema.GroupVersionKind, len(*sbPassthroughKinds))
+		for i, k := range *sbPassthroughKinds {
+			gvk, _ := schema.ParseKindArg(k)
+			if gvk == nil {
+				kingpin.FatalUsage("passthrough kind %s is not in Kind.version.group form", k)
+			}
+			passthrough[i] = *gvk
+		}
+		f, err := os.OpenFile(*sbOutput, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		kingpin.FatalIfError(err, "cannot create support bundle")
+		defer f.Close() // nolint:errcheck
+		c := &bundle.Command{
+			ClusterConfig: cfg,
+			Namespace:     *sbNamespace,
+			PodSelector:   *sbSelector,
+			LogLines:      *sbLogLines,
+			Passthrough:   passthrough,
+		}
+		kingpin.FatalIfError(c.Run(f, os.Stderr), "cannot collect support bundle")
+		return
+	}
+	if cmd == lp.FullCommand() {
+		gvk, _ := schema.ParseKindArg(*lpKind)
+		if gvk == nil {
+			kingpin.FatalUsage("kind %s is not given as Kind.version.group", *lpKind)
+		}
+		cfg, err := clientcmd.BuildConfigFromFlags("", *lpKubeconfig)
+		if err != nil {
+			kingpin.FatalUsage("could not parse kubeconfig %s", *lpKubeconfig)
+		}
+		c := &pushed.Command{
+			ClusterConfig:    cfg,
+			GroupVersionKind: *gvk,
+			Claim:            types.NamespacedName{Namespace: *lpNamespace, Name: *lpName},
+		}
+		kingpin.FatalIfError(c.Run(os.Stdout), "cannot print last pushed spec")
+		return
+	}
+	if cmd == gt.FullCommand() {
+		cfg, err := clientcmd.BuildConfigFromFlags("", *gtKubeconfig)
+		if err != nil {
+			kingpin.FatalUsage("could not parse kubeconfig %s", *gtKubeconfig)
+		}
+		c := &generate.Command{ClusterConfig: cfg, OutputDir: *gtOutputDir}
+		kingpin.FatalIfError(c.Run(os.Stdout), "cannot generate claim types")
+		return
+	}
+	if cmd == rp.FullCommand() {
+		in := os.Stdin
+		if *rpFile != "-" {
+			f, err := os.Open(*rpFile)
+			kingpin.FatalIfError(err, "cannot open recorded syncs")
+			defer f.Close() // nolint:errcheck
+			in = f
+		}
+		r := claim.SyncRenderer{
+			Configurator:    claim.NewDefaultConfigurator(),
+			Mapper:          claim.NamespaceMap(*rpNamespaceMapping),
+			Versions:        readVersionTable(*rpVersionTable),
+			ClusterName:     *rpClusterName,
+			OwnerLabels:     *rpOwnerLabels,
+			RemoteFinalizer: *rpRemoteFinalizer,
+		}
+		summary, err := replay.Replay(context.Background(), in, r, os.Stdout)
+		kingpin.FatalIfError(err, "cannot replay syncs")
+		if summary.Changed > 0 {
+			kingpin.Fatalf("%d of %d replayed syncs would be pushed differently", summary.Changed, summary.Replayed)
+		}
+		return
+	}
+	if cmd == al.FullCommand() {
+		b, err := yaml.Marshal(alerts.Rules(alerts.Options{
+			Name:              *alName,
+			Namespace:         *alNamespace,
+			Labels:            *alLabels,
+			BlockedFor:        *alBlockedFor,
+			MaxPropagationLag: *alMaxLag,
+		}))
+		kingpin.FatalIfError(err, "cannot render alerting rules")
+		_, err = os.Stdout.Write(b)
+		kingpin.FatalIfError(err, "cannot print alerting rules")
+		return
+	}
+	if cmd == v.FullCommand() {
+		cfg, err := clientcmd.BuildConfigFromFlags("", *vcsa)
+		if err != nil {
+			kingpin.FatalUsage("could not parse cluster kubeconfig %s", *vcsa)
+		}
+		c := &validate.Command{
+			ClusterConfig:    cfg,
+			File:             *vFile,
+			NamespaceMapping: *vNamespaceMapping,
+			CanaryNamespace:  *vCanaryNamespace,
+			MaxClaimSize:     *vMaxClaimSize,
+		}
+		kingpin.FatalIfError(c.Run(os.Stdout), "claims are not valid")
+		return
+	}
 	zl := zap.New(zap.UseDevMode(*debug))
 	if *debug {
 		// The controller-runtime runs with a no-op logger by default. It is
@@ -59,18 +333,445 @@ func main() {
 	if err != nil {
 		kingpin.FatalUsage("could not parse cluster kubeconfig %s", *csa)
 	}
+	// The kubeconfig is discovered again periodically once the logger is set
+	// up, so that the agent restarts with the new one when it changes.
+	var discovered func(logging.Logger) *agentremote.KubeconfigWatcher
+	if src := agentremote.KubeconfigSource(*remoteDiscovery); *mode == "local" && src != agentremote.KubeconfigSourceFile {
+		parts := strings.SplitN(*remoteDiscoveryRef, "/", 2)
+		if len(parts) != 2 {
+			kingpin.FatalUsage("remote discovery reference %s is not in namespace/name form", *remoteDiscoveryRef)
+		}
+		lc, err := client.New(ctrl.GetConfigOrDie(), client.Options{})
+		kingpin.FatalIfError(err, "cannot create local client")
+		nn := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+		cfg, err := agentremote.DiscoverConfig(context.Background(), lc, src, nn)
+		kingpin.FatalIfError(err, "cannot discover the kubeconfig of the remote cluster")
+		discovered = func(log logging.Logger) *agentremote.KubeconfigWatcher {
+			return agentremote.NewKubeconfigWatcher(lc, src, nn, rest.CopyConfig(cfg), *remoteDiscoveryPeriod, log)
+		}
+
+		// The discovered identity is used for every namespace.
+		defaultConfig, clusterConfig = cfg, rest.CopyConfig(cfg)
+	}
+	transport := agentremote.TransportOptions{
+		DialTimeout:     *remoteDialTimeout,
+		KeepAlive:       *remoteKeepAlive,
+		MaxIdleConns:    *remoteMaxIdleConns,
+		IdleConnTimeout: *remoteIdleConnTimeout,
+		Pins:            agentremote.TLSPins{SPKIHashes: *remoteSPKIPins},
+		QPS:             *remoteQPS,
+		Burst:           *remoteBurst,
+	}
+	if *remoteCABundle != "" {
+		b, err := ioutil.ReadFile(*remoteCABundle)
+		if err != nil {
+			kingpin.FatalUsage("could not read remote CA bundle %s", *remoteCABundle)
+		}
+		transport.Pins.CABundle = b
+	}
+	kingpin.FatalIfError(transport.Pins.Validate(), "invalid remote TLS pins")
+	// Settings from the hub are applied before any flag is read.
+	var (
+		hubReader   client.Reader
+		hubSettings hubconfig.Effective
+		hubUnknown  []string
+		hubErr      error
+	)
+	if *hubConfig && (*mode == "local" || *mode == "remote") {
+		rc := rest.CopyConfig(clusterConfig)
+		agentremote.ConfigureTransport(rc, transport)
+		hc, err := client.New(rc, client.Options{})
+		kingpin.FatalIfError(err, "cannot create hub configuration client")
+		hubReader = hc
+		hubSettings, hubUnknown, hubErr = applyHubConfig(app, s, hc, *registrationNamespace, *clusterName)
+	}
+	passthrough := make([]schema.GroupVersionKind, len(*passthroughKinds))
+	for i, k := range *passthroughKinds {
+		gvk, _ := schema.ParseKindArg(k)
+		if gvk == nil {
+			kingpin.FatalUsage("passthrough kind %s is not in Kind.version.group form", k)
+		}
+		passthrough[i] = *gvk
+	}
+	versions := readVersionTable(*versionTable)
+	var syncRecorder claim.SyncRecorder
+	if *recordSyncs != "" {
+		f, err := os.OpenFile(*recordSyncs, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			kingpin.FatalUsage("could not open sync record file %s", *recordSyncs)
+		}
+		syncRecorder = replay.NewRecorder(f)
+	}
+	policies := make(claim.StatusPolicyTable, len(*statusPolicies))
+	for k, p := range *statusPolicies {
+		switch claim.StatusPolicy(p) {
+		case claim.StatusPolicyConditions, claim.StatusPolicyConnectionDetails, claim.StatusPolicyFull:
+		default:
+			kingpin.FatalUsage("unknown status policy %s of %s", p, k)
+		}
+		policies[schema.ParseGroupKind(k)] = claim.StatusPolicy(p)
+	}
+	var conditions claim.ConditionMapping
+	if len(*conditionMapping) > 0 {
+		conditions = make(claim.ConditionMapping, len(claim.DefaultConditionMapping)+len(*conditionMapping))
+		for remote, local := range claim.DefaultConditionMapping {
+			conditions[remote] = local
+		}
+		for remote, local := range *conditionMapping {
+			if local == string(resource.TypeAgentSync) {
+				kingpin.FatalUsage("conditions of type %s cannot be mapped to %s, which is set by the agent", remote, local)
+			}
+			conditions[v1alpha1.ConditionType(remote)] = v1alpha1.ConditionType(local)
+		}
+	}
+	var selector *claim.ClaimSelector
+	if *claimSelector != "" || len(*claimNamespaces) > 0 {
+		var ls labels.Selector
+		if *claimSelector != "" {
+			ls, err = labels.Parse(*claimSelector)
+			kingpin.FatalIfError(err, "invalid claim selector")
+		}
+		selector = claim.NewClaimSelector(ls, *claimNamespaces...)
+	}
+	var compositions labels.Selector
+	if *compositionSelector != "" {
+		compositions, err = labels.Parse(*compositionSelector)
+		kingpin.FatalIfError(err, "invalid composition selector")
+	}
+	lf, err := fault.ParseSpec(*localFaults)
+	kingpin.FatalIfError(err, "invalid local fault injection")
+	rf, err := fault.ParseSpec(*remoteFaults)
+	kingpin.FatalIfError(err, "invalid remote fault injection")
+	fanOut := make(map[string]*rest.Config, len(*fanOutKubeconfigs))
+	for name, path := range *fanOutKubeconfigs {
+		cfg, err := clientcmd.BuildConfigFromFlags("", path)
+		if err != nil {
+			kingpin.FatalUsage("could not parse fan-out kubeconfig %s", path)
+		}
+		fanOut[name] = cfg
+	}
+	remotes := make(map[string]*rest.Config, len(*remoteKubeconfigs))
+	for name, path := range *remoteKubeconfigs {
+		cfg, err := clientcmd.BuildConfigFromFlags("", path)
+		if err != nil {
+			kingpin.FatalUsage("could not parse remote kubeconfig %s", path)
+		}
+		remotes[name] = cfg
+	}
+	var secretEnvelope claim.SecretEnvelope
+	var eo []envelope.Option
+	if *envelopeRequireSealed {
+		eo = append(eo, envelope.WithRequireSealed())
+	}
+	switch {
+	case *envelopeKeyFile != "":
+		key, err := ioutil.ReadFile(*envelopeKeyFile)
+		if err != nil {
+			kingpin.FatalUsage("could not read secret envelope key %s", *envelopeKeyFile)
+		}
+		w, err := envelope.NewAESKeyWrapper(key)
+		kingpin.FatalIfError(err, "invalid secret envelope key %s", *envelopeKeyFile)
+		secretEnvelope = envelope.New(w, eo...)
+	case *envelopeWrapCommand != "" || *envelopeUnwrapCommand != "":
+		secretEnvelope = envelope.New(&envelope.CommandKeyWrapper{Wrap: strings.Fields(*envelopeWrapCommand), Unwrap: strings.Fields(*envelopeUnwrapCommand)}, eo...)
+	}
+	if *envelopeRequireSealed && secretEnvelope == nil {
+		kingpin.FatalUsage("secret envelope cannot require sealed secrets without a key file or an unwrap command")
+	}
+	if *strict {
+		var localKinds, remoteKinds []schema.GroupVersionKind
+		if *mode == "local" {
+			localKinds = append(localKinds, passthrough...)
+			remoteKinds = append(remoteKinds, passthrough...)
+			for _, c := range versions {
+				gvk := schema.GroupVersionKind{Group: c.Group, Version: c.Local, Kind: c.Kind}
+				localKinds = append(localKinds, gvk)
+				remoteKinds = append(remoteKinds, c.RemoteGroupVersionKind(gvk))
+			}
+		}
+		rc := rest.CopyConfig(clusterConfig)
+		agentremote.ConfigureTransport(rc, transport)
+		problems := preflight.Check(context.Background(), preflight.Config{
+			Local:            preflightCluster(ctrl.GetConfigOrDie(), localKinds),
+			Remote:           preflightCluster(rc, remoteKinds),
+			Namespace:        *namespace,
+			ClusterName:      *clusterName,
+			NamespaceMapping: *namespaceMapping,
+			OwnerLabels:      *remoteOwnerLabels,
+		})
+		if len(problems) > 0 {
+			kingpin.Fatalf("configuration is not valid:\n%s", strings.Join(problems, "\n"))
+		}
+	}
 	duration, _ := time.ParseDuration("1h")
+	// Secrets and kubeconfigs can find their way into log values, so every
+	// value is redacted before it's written out at any verbosity level.
+	log := resource.NewRedactingLogger(logging.NewLogrLogger(zl.WithName("crossplane-agent")))
+	var hubWatcher *hubconfig.Watcher
+	if hubReader != nil {
+		if hubErr != nil {
+			log.Info("Cannot read hub configuration, starting with local settings", "error", hubErr)
+		}
+		if len(hubUnknown) > 0 {
+			log.Info("Ignoring hub settings that cannot be set from the hub", "settings", hubUnknown)
+		}
+		lc, err := client.New(ctrl.GetConfigOrDie(), client.Options{})
+		kingpin.FatalIfError(err, "cannot create local client")
+		if *dryRun {
+			lc = dryrun.NewClient(lc, "local", log)
+		}
+		nn := types.NamespacedName{Namespace: *namespace, Name: hubconfig.EffectiveConfigMapName}
+		hubWatcher = hubconfig.NewWatcher(hubReader, lc, *registrationNamespace, *clusterName, hubSettings, nn, *hubConfigPeriod, log)
+	}
+	var kubeconfigWatcher *agentremote.KubeconfigWatcher
+	if discovered != nil {
+		kubeconfigWatcher = discovered(log)
+	}
 	switch *mode {
 	case "local":
 		agent := &local.Agent{
-			ClusterConfig: clusterConfig,
-			DefaultConfig: defaultConfig,
+			ClusterConfig:           clusterConfig,
+			DefaultConfig:           defaultConfig,
+			RemoteTransport:         transport,
+			HealthCheckPeriod:       *healthCheckPeriod,
+			MaxClaimSize:            *maxClaimSize,
+			CanaryNamespace:         *canaryNamespace,
+			Namespace:               *namespace,
+			ErrorBudget:             *errorBudget,
+			ErrorBudgetWindow:       *errorBudgetWindow,
+			BackpressureThreshold:   *backpressureThreshold,
+			MaxConcurrentSyncs:      *maxConcurrentSyncs,
+			StatusUpdateQPS:         *statusUpdateQPS,
+			StatusUpdateBurst:       *statusUpdateBurst,
+			SyncQPS:                 *syncQPS,
+			SyncBurst:               *syncBurst,
+			SyncInterval:            *syncInterval,
+			ErrorRetryInterval:      *errorRetryInterval,
+			MaxErrorRetryInterval:   *maxErrorRetryInterval,
+			DisconnectedAfter:       *disconnectedAfter,
+			RemoteUIDPolicy:         claim.UIDPolicy(*remoteUIDPolicy),
+			ClusterName:             *clusterName,
+			RemoteNamespacePolicy:   claim.NamespacePolicy(*remoteNamespacePolicy),
+			SyncInputs:              *syncInputs,
+			RequireApproval:         *requireApproval,
+			RemoteFinalizer:         *remoteFinalizer,
+			RecordLastPushedSpec:    *recordLastPushedSpec,
+			DetectDrift:             *detectDrift,
+			ValidateSchema:          *validateSchema,
+			CheckQuota:              *checkQuota,
+			AdmissionWebhook:        *admissionWebhook,
+			AdmissionWebhookTimeout: *admissionWebhookTimeout,
+			IdleThreshold:           *idleThreshold,
+			NamespaceMapping:        *namespaceMapping,
+			ConversionPolicy:        conversion.Policy(*conversionPolicy),
+			ConversionProxyService:  *conversionProxyService,
+			ConversionProxyCertDir:  *conversionProxyCertDir,
+			SnapshotPeriod:          *snapshotPeriod,
+			RegistrationNamespace:   *registrationNamespace,
+			PlatformHealthPeriod:    *platformHealthPeriod,
+			ConnectionProbePeriod:   *connectionProbePeriod,
+			StatusPeriod:            *statusPeriod,
+			SyncStatsPeriod:         *syncStatsPeriod,
+			VersionTable:            versions,
+			StatusPolicies:          policies,
+			ConditionMapping:        conditions,
+			CloudEventsSink:         *cloudEventsSink,
+			PassthroughKinds:        passthrough,
+			SecretEnvelope:          secretEnvelope,
+			SyncRecorder:            syncRecorder,
+			MaxDefinitionStaleness:  *maxDefinitionStaleness,
+			HoldOnStaleDefinitions:  *blockOnStaleDefinitions,
+			FanOutConfigs:           fanOut,
+			RemoteConfigs:           remotes,
+			RemoteOwnerLabels:       *remoteOwnerLabels,
+			LabelPolicy:             claim.LabelPolicy{Required: *requiredLabels, Defaults: *defaultLabels},
+			ClaimSelector:           selector,
+			DecommissionNamespaces:  *decommissionNamespaces,
+			OnboardingBatchSize:     *onboardingBatchSize,
+			OnboardingInterval:      *onboardingInterval,
+			HealthProbeAddress:      probeAddress(*healthProbeAddress, ":8082"),
+			UnreadyAfter:            *unreadyAfter,
+			MetricsAddress:          *metricsAddress,
+			CacheWarmupTimeout:      *cacheWarmupTimeout,
+			WatchRemote:             *watchRemote,
+			CacheTrim:               trim.Options{ManagedFields: *cacheTrimManagedFields, LastApplied: *cacheTrimLastApplied},
+			LocalFaults:             lf,
+			RemoteFaults:            rf,
+			DryRun:                  *dryRun,
+			Suspend:                 *suspendAll,
+			LeaderElection:          *leaderElect,
+			LeaderElectionNamespace: *leaderElectionNamespace,
+			LeaseDuration:           *leaseDuration,
+			HubConfig:               hubWatcher,
+			RemoteKubeconfig:        kubeconfigWatcher,
 		}
-		kingpin.FatalIfError(agent.Run(logging.NewLogrLogger(zl.WithName("crossplane-agent")), duration), "cannot run agent in local mode")
+		kingpin.FatalIfError(agent.Run(log, duration), "cannot run agent in local mode")
 	case "remote":
 		agent := &remote.Agent{
-			ClusterConfig: clusterConfig,
+			ClusterConfig:           clusterConfig,
+			RemoteTransport:         transport,
+			HealthCheckPeriod:       *healthCheckPeriod,
+			Namespace:               *namespace,
+			SyncInterval:            *syncInterval,
+			ErrorRetryInterval:      *errorRetryInterval,
+			MaxErrorRetryInterval:   *maxErrorRetryInterval,
+			MaxDefinitionStaleness:  *maxDefinitionStaleness,
+			PropagateSecrets:        *propagateSecrets,
+			SecretEnvelope:          secretEnvelope,
+			LocalFaults:             lf,
+			RemoteFaults:            rf,
+			MetricsAddress:          *metricsAddress,
+			DryRun:                  *dryRun,
+			Suspend:                 *suspendAll,
+			CompositionSelector:     compositions,
+			ServerSideApply:         *serverSideApply,
+			FieldManager:            *fieldManager,
+			HealthProbeAddress:      probeAddress(*healthProbeAddress, ":8083"),
+			UnreadyAfter:            *unreadyAfter,
+			LeaderElection:          *leaderElect,
+			LeaderElectionNamespace: *leaderElectionNamespace,
+			LeaseDuration:           *leaseDuration,
+			HubConfig:               hubWatcher,
+		}
+		kingpin.FatalIfError(agent.Run(log, duration), "cannot run agent in remote mode")
+	case "hub":
+		agent := &hub.Agent{
+			RegistrationNamespace: *registrationNamespace,
+			HeartbeatTimeout:      *heartbeatTimeout,
+		}
+		kingpin.FatalIfError(agent.Run(log, duration), "cannot run agent in hub mode")
+	}
+}
+
+// probeAddress returns the supplied address the health probes are served at,
+// or the supplied default of the mode the agent runs in if it's empty.
+func probeAddress(addr, def string) string {
+	if addr == "" {
+		return def
+	}
+	return addr
+}
+
+// hubSettable are the flags of the sync command that can be set from the hub.
+var hubSettable = []string{
+	"claim-selector",
+	"claim-namespace",
+	"composition-selector",
+	"required-label",
+	"default-label",
+	"condition-mapping",
+	"sync-interval",
+	"error-retry-interval",
+	"max-error-retry-interval",
+	"disconnected-after",
+	"onboarding-batch-size",
+	"onboarding-interval",
+	"snapshot-period",
+	"platform-health-period",
+	"connection-probe-period",
+	"status-period",
+	"sync-stats-period",
+	"watch-remote",
+	"detect-drift",
+	"validate-schema",
+	"check-quota",
+	"record-last-pushed-spec",
+	"sync-inputs",
+	"require-approval",
+	"remote-finalizer",
+	"decommission-namespaces",
+	"server-side-apply",
+}
+
+// applyHubConfig sets the flags of the sync command that can be set from the
+// hub, and that were not set locally on the command line or in the environment,
+// to their values in the hub. Values of repeatable flags are comma-separated.
+// An error reading the hub is returned rather than fatal, so that the agent
+// can start with its local settings while the remote cluster is unreachable.
+func applyHubConfig(app *kingpin.Application, s *kingpin.CmdClause, r client.Reader, namespace, cluster string) (hubconfig.Effective, []string, error) {
+	hub, err := hubconfig.Fetch(context.Background(), r, namespace, cluster)
+	pc, perr := app.ParseContext(os.Args[1:])
+	kingpin.FatalIfError(perr, "cannot parse command line")
+	local := map[string]bool{}
+	for _, e := range pc.Elements {
+		if f, ok := e.Clause.(*kingpin.FlagClause); ok {
+			local[f.Model().Name] = true
+		}
+	}
+	current := make(map[string]string, len(hubSettable))
+	for _, name := range hubSettable {
+		m := s.GetFlag(name).Model()
+		current[name] = flagValue(m.Value)
+		if _, ok := os.LookupEnv(m.Envar); ok && m.Envar != "" {
+			local[name] = true
 		}
-		kingpin.FatalIfError(agent.Run(logging.NewLogrLogger(zl.WithName("crossplane-agent")), duration), "cannot run agent in remote mode")
 	}
+	e, unknown := hubconfig.Resolve(current, local, hub)
+	for name, setting := range e {
+		if setting.Source != hubconfig.SourceHub {
+			continue
+		}
+		v := s.GetFlag(name).Model().Value
+		values := []string{setting.Value}
+		if c, ok := v.(interface{ IsCumulative() bool }); ok && c.IsCumulative() {
+			values = strings.Split(setting.Value, ",")
+		}
+		for _, value := range values {
+			kingpin.FatalIfError(v.Set(strings.TrimSpace(value)), "invalid value of %s in the hub configuration", name)
+		}
+	}
+	return e, unknown, err
+}
+
+// flagValue returns the value of a flag as it would be given on the command
+// line, with the values of repeatable flags comma-separated.
+func flagValue(v kingpin.Value) string {
+	g, ok := v.(kingpin.Getter)
+	if !ok {
+		return v.String()
+	}
+	switch value := g.Get().(type) {
+	case []string:
+		return strings.Join(value, ",")
+	case map[string]string:
+		kv := make([]string, 0, len(value))
+		for k, v := range value {
+			kv = append(kv, k+"="+v)
+		}
+		sort.Strings(kv)
+		return strings.Join(kv, ",")
+	default:
+		return v.String()
+	}
+}
+
+// readVersionTable reads the version conversion table in the supplied file.
+// It's empty if the path is.
+func readVersionTable(path string) claim.VersionTable {
+	var versions claim.VersionTable
+	if path == "" {
+		return versions
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		kingpin.FatalUsage("could not read version conversion table %s", path)
+	}
+	if err := json.Unmarshal(b, &versions); err != nil {
+		kingpin.FatalUsage("could not parse version conversion table %s: %s", path, err)
+	}
+	return versions
+}
+
+// preflightCluster returns the cluster with the supplied config that is checked
+// in strict mode.
+func preflightCluster(cfg *rest.Config, kinds []schema.GroupVersionKind) preflight.Cluster {
+	d, err := discovery.NewDiscoveryClientForConfig(cfg)
+	kingpin.FatalIfError(err, "cannot create discovery client")
+	// Mappings are discovered lazily so that a cluster that can't be reached
+	// is reported by the checks rather than here.
+	m, err := apiutil.NewDynamicRESTMapper(cfg, apiutil.WithLazyDiscovery)
+	kingpin.FatalIfError(err, "cannot create REST mapper")
+	c, err := client.New(cfg, client.Options{Mapper: m})
+	kingpin.FatalIfError(err, "cannot create client")
+	return preflight.Cluster{Discovery: d, Client: c, Kinds: kinds}
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pushed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const timeout = 1 * time.Minute
+
+// Command prints the spec the agent last pushed to the remote cluster for a
+// local claim, so that it can be compared with the remote claim without
+// running it through the agent again.
+type Command struct {
+	ClusterConfig *rest.Config
+
+	// GroupVersionKind is the kind of the claim.
+	GroupVersionKind schema.GroupVersionKind
+
+	// Claim is the namespace and the name of the claim.
+	Claim types.NamespacedName
+}
+
+// Run prints the last pushed spec of the claim to out, together with where it
+// was pushed to. An error is returned if none was recorded.
+func (c *Command) Run(out io.Writer) error {
+	kube, err := client.New(c.ClusterConfig, client.Options{})
+	if err != nil {
+		return errors.Wrap(err, "cannot create cluster client")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cl := claim.New(claim.WithGroupVersionKind(c.GroupVersionKind))
+	if err := kube.Get(ctx, c.Claim, cl.GetUnstructured()); err != nil {
+		return errors.Wrap(err, "cannot get claim")
+	}
+	spec, found, err := agentclaim.LastPushedSpec(cl)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("no pushed spec is recorded on the claim, the agent may not record them or may not have pushed it yet")
+	}
+	b, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot print spec")
+	}
+	a := cl.GetAnnotations()
+	fmt.Fprintf(out, "# %s %s was last pushed to namespace %s with resource version %s as:\n%s\n", // nolint:errcheck
+		c.GroupVersionKind.Kind, c.Claim, a[resource.AnnotationKeyRemoteNamespace], a[resource.AnnotationKeyLastRemoteResourceVersion], b)
+	return nil
+}
@@ -21,33 +21,162 @@ import (
 
 	"github.com/pkg/errors"
 	crds "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	capiextensions "github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
 
+	"github.com/crossplane/agent/pkg/controllers"
 	"github.com/crossplane/agent/pkg/controllers/apiextensions"
+	"github.com/crossplane/agent/pkg/controllers/claim"
 	"github.com/crossplane/agent/pkg/controllers/crd"
+	"github.com/crossplane/agent/pkg/controllers/secret"
+	"github.com/crossplane/agent/pkg/dryrun"
+	"github.com/crossplane/agent/pkg/fault"
+	"github.com/crossplane/agent/pkg/hubconfig"
+	"github.com/crossplane/agent/pkg/metrics"
+	agentremote "github.com/crossplane/agent/pkg/remote"
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/rollout"
+	"github.com/crossplane/agent/pkg/staleness"
+	"github.com/crossplane/agent/pkg/suspend"
+	"github.com/crossplane/agent/pkg/warning"
 )
 
 // Agent configures & starts the manager that is watching the remote cluster.
 type Agent struct {
 	ClusterConfig *rest.Config
+
+	// RemoteTransport tunes the connections to the remote cluster, which are
+	// checked once in every HealthCheckPeriod. Connections that may be dead
+	// are dropped when a check fails.
+	RemoteTransport   agentremote.TransportOptions
+	HealthCheckPeriod time.Duration
+
+	// Namespace is the namespace in the local cluster where Agent keeps its
+	// bookkeeping objects.
+	Namespace string
+
+	// SyncInterval is how often definitions are synced when nothing about
+	// them changed.
+	SyncInterval time.Duration
+
+	// ErrorRetryInterval is how long to wait before retrying a definition that
+	// failed to sync. The wait doubles, with jitter, with each consecutive
+	// failure, up to MaxErrorRetryInterval.
+	ErrorRetryInterval    time.Duration
+	MaxErrorRetryInterval time.Duration
+
+	// MaxDefinitionStaleness is how long definitions may go without being
+	// refreshed from the remote cluster before they're reported as degraded.
+	// The time they were last refreshed is not recorded if it's zero.
+	MaxDefinitionStaleness time.Duration
+
+	// PropagateSecrets propagates the connection secrets of remote claims to
+	// the local cluster as soon as they change, decrypting them with
+	// SecretEnvelope if it's set, rather than when their claims are polled.
+	PropagateSecrets bool
+	SecretEnvelope   claim.SecretEnvelope
+
+	// LocalFaults and RemoteFaults are injected into the requests made to
+	// the local and the remote cluster respectively by end-to-end tests.
+	LocalFaults  fault.Spec
+	RemoteFaults fault.Spec
+
+	// MetricsAddress is the address the /metrics endpoint is served at. It's
+	// served at 127.0.0.1:8081 if it's empty.
+	MetricsAddress string
+
+	// CompositionSelector restricts the remote Compositions that are synced to
+	// the local cluster to the ones whose labels match it. All of them are
+	// synced if it's nil.
+	CompositionSelector labels.Selector
+
+	// ServerSideApply applies the definitions that are synced to the local
+	// cluster with server-side apply as FieldManager, which keeps the fields
+	// that other tooling manages on them rather than replacing them.
+	ServerSideApply bool
+	FieldManager    string
+
+	// DryRun reports the writes the agent would make to either cluster, and
+	// the diffs they'd make, rather than making them.
+	DryRun bool
+
+	// Suspend stops the agent from propagating or pruning anything while it
+	// keeps reporting status. The agent can be suspended without a restart by
+	// annotating the suspend.ConfigMapName ConfigMap in Namespace too.
+	Suspend bool
+
+	// HealthProbeAddress is the address the liveness and readiness endpoints
+	// are served at. They're not served if it's empty.
+	HealthProbeAddress string
+
+	// UnreadyAfter is how long the remote cluster may be unreachable before
+	// the agent reports itself not ready. The agent stays ready while the
+	// remote cluster is unreachable if it's zero.
+	UnreadyAfter time.Duration
+
+	// LeaderElection makes only one of the replicas of the agent sync at a
+	// time, while the others stand by to take over. The leader holds a lock in
+	// LeaderElectionNamespace of the local cluster, or in Namespace if it's
+	// empty, that it has to renew within LeaseDuration.
+	LeaderElection          bool
+	LeaderElectionNamespace string
+	LeaseDuration           time.Duration
+
+	// HubConfig restarts the agent when the settings it was started with are
+	// changed in the remote cluster, and reports its effective configuration.
+	HubConfig *hubconfig.Watcher
 }
 
 // Run adds all controllers and starts the manager that watches the remote cluster.
 func (a *Agent) Run(log logging.Logger, period time.Duration) error {
 	log.Debug("Starting", "sync-period", period.String())
 
-	localClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{})
+	// Warnings returned by either api-server are logged.
+	transport := agentremote.ConfigureTransport(a.ClusterConfig, a.RemoteTransport)
+	wrap := warning.NewTransportWrapper(log)
+	a.ClusterConfig.Wrap(wrap)
+	localConfig := ctrl.GetConfigOrDie()
+	localConfig.Wrap(wrap)
+	if !a.RemoteFaults.Empty() {
+		a.ClusterConfig.Wrap(fault.NewTransportWrapper(a.RemoteFaults, log))
+	}
+	if !a.LocalFaults.Empty() {
+		localConfig.Wrap(fault.NewTransportWrapper(a.LocalFaults, log))
+	}
+	api, err := metrics.NewRemoteAPI(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create remote API metrics")
+	}
+	a.ClusterConfig.Wrap(metrics.NewTransportWrapper(api))
+
+	localClient, err := client.New(localConfig, client.Options{})
 	if err != nil {
 		return errors.Wrap(err, "cannot create local client")
 	}
+	if a.DryRun {
+		localClient = dryrun.NewClient(localClient, "local", log)
+	}
 
-	mgr, err := ctrl.NewManager(a.ClusterConfig, ctrl.Options{SyncPeriod: &period, MetricsBindAddress: "127.0.0.1:8081"})
+	metricsAddress := a.MetricsAddress
+	if metricsAddress == "" {
+		metricsAddress = "127.0.0.1:8081"
+	}
+	newClient := manager.DefaultNewClient
+	if a.DryRun {
+		newClient = dryrun.NewClientFunc("remote", log)
+	}
+	o := ctrl.Options{SyncPeriod: &period, MetricsBindAddress: metricsAddress, HealthProbeBindAddress: a.HealthProbeAddress, NewClient: newClient}
+	a.leaderElection(&o, localConfig)
+	mgr, err := ctrl.NewManager(a.ClusterConfig, o)
 	if err != nil {
 		return errors.Wrap(err, "cannot start remote cluster manager")
 	}
@@ -60,15 +189,104 @@ func (a *Agent) Run(log logging.Logger, period time.Duration) error {
 		return errors.Wrap(err, "Cannot add Crossplane apiextensions API to scheme")
 	}
 
-	for _, setup := range []func(mgr manager.Manager, localClient client.Client, logger logging.Logger) error{
-		crd.Setup,
-		apiextensions.SetupXRDSync,
-		apiextensions.SetupCompositionSync,
-	} {
-		if err := setup(mgr, localClient, log); err != nil {
-			return errors.Wrap(err, "cannot setup the controller")
+	// The error budget is published by the agent running in local mode. It's
+	// ignored if it hasn't been updated for a while so that a stopped local
+	// agent doesn't hold the updates forever.
+	gate := rollout.NewConfigMapGate(localClient, types.NamespacedName{Namespace: a.Namespace, Name: rollout.ConfigMapName}, 5*time.Minute)
+	sw := suspend.NewConfigMapSwitch(localClient, types.NamespacedName{Namespace: a.Namespace, Name: suspend.ConfigMapName}, a.Suspend)
+
+	// Informers recover from a lost connection on their own but everything
+	// that failed in the meantime is reconciled again once the remote cluster
+	// is reachable.
+	conn, err := metrics.NewConnectivity(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create connectivity metrics")
+	}
+	monitor, err := agentremote.NewMonitor(a.ClusterConfig, a.HealthCheckPeriod, agentremote.WithLogger(log), agentremote.WithMetrics(conn), agentremote.WithProbeFailureHandler(transport.CloseIdleConnections))
+	if err != nil {
+		return errors.Wrap(err, "cannot create remote cluster monitor")
+	}
+	if err := mgr.Add(monitor); err != nil {
+		return errors.Wrap(err, "cannot add remote cluster monitor")
+	}
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return errors.Wrap(err, "cannot add liveness check")
+	}
+	if err := mgr.AddReadyzCheck("remote-connectivity", monitor.ReadyCheck(a.UnreadyAfter)); err != nil {
+		return errors.Wrap(err, "cannot add remote connectivity readiness check")
+	}
+	if a.HubConfig != nil {
+		if err := mgr.Add(a.HubConfig); err != nil {
+			return errors.Wrap(err, "cannot add hub configuration watcher")
+		}
+	}
+
+	if a.MaxDefinitionStaleness > 0 {
+		nn := types.NamespacedName{Namespace: a.Namespace, Name: staleness.ConfigMapName}
+		if err := mgr.Add(staleness.NewRecorder(mgr.GetAPIReader(), localClient, nn, time.Minute, a.MaxDefinitionStaleness, log)); err != nil {
+			return errors.Wrap(err, "cannot add definition staleness recorder")
+		}
+	}
+
+	sm, err := metrics.NewSync(ctrlmetrics.Registry)
+	if err != nil {
+		return errors.Wrap(err, "cannot create sync metrics")
+	}
+	cfg := controllers.DefinitionsConfig{
+		CRDOptions: []crd.ReconcilerOption{crd.WithRolloutGate(gate), crd.WithReconnectMonitor(monitor)},
+		Options:    []apiextensions.ReconcilerOption{apiextensions.WithRolloutGate(gate), apiextensions.WithSuspendSwitch(sw), apiextensions.WithReconnectMonitor(monitor), apiextensions.WithSyncMetrics(sm)},
+	}
+	if a.ServerSideApply {
+		cfg.Options = append(cfg.Options, apiextensions.WithApplicator(resource.NewServerSideApplicator(localClient, mgr.GetScheme(), a.FieldManager)))
+	}
+	if a.SyncInterval > 0 {
+		cfg.Options = append(cfg.Options, apiextensions.WithSyncInterval(a.SyncInterval))
+	}
+	if a.ErrorRetryInterval > 0 {
+		cfg.Options = append(cfg.Options, apiextensions.WithErrorRetryInterval(a.ErrorRetryInterval, a.MaxErrorRetryInterval))
+	}
+	if a.CompositionSelector != nil {
+		cfg.CompositionOptions = append(cfg.CompositionOptions, apiextensions.WithSelector(a.CompositionSelector))
+	}
+	if err := controllers.SetupDefinitions(mgr, localClient, log, cfg); err != nil {
+		return errors.Wrap(err, "cannot setup the controller")
+	}
+	if a.PropagateSecrets {
+		var so []secret.ReconcilerOption
+		if a.SecretEnvelope != nil {
+			so = append(so, secret.WithSecretEnvelope(a.SecretEnvelope))
+		}
+		if err := secret.Setup(mgr, localClient, log, so...); err != nil {
+			return errors.Wrap(err, "cannot setup connection secret controller")
 		}
 	}
 
 	return errors.Wrap(mgr.Start(ctrl.SetupSignalHandler()), "cannot start controller manager")
 }
+
+// leaderElectionID is the name of the lock the replicas of the agent running
+// in remote mode elect their leader with.
+const leaderElectionID = "crossplane-agent-remote"
+
+// leaderElection configures the supplied manager options to elect a leader
+// among the replicas of the agent if it's enabled. The renew deadline and the
+// retry period keep the ratio of their defaults to the lease duration.
+//
+// The lock is kept in the supplied local cluster rather than the remote cluster
+// the manager watches.
+func (a *Agent) leaderElection(o *ctrl.Options, lock *rest.Config) {
+	if !a.LeaderElection {
+		return
+	}
+	o.LeaderElection = true
+	o.LeaderElectionID = leaderElectionID
+	o.LeaderElectionNamespace = a.LeaderElectionNamespace
+	if o.LeaderElectionNamespace == "" {
+		o.LeaderElectionNamespace = a.Namespace
+	}
+	o.LeaderElectionConfig = lock
+	if a.LeaseDuration > 0 {
+		renew, retry := a.LeaseDuration*2/3, a.LeaseDuration*2/15
+		o.LeaseDuration, o.RenewDeadline, o.RetryPeriod = &a.LeaseDuration, &renew, &retry
+	}
+}
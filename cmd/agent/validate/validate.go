@@ -0,0 +1,151 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+)
+
+const timeout = 1 * time.Minute
+
+// Command runs the claims in a file through the same steps the agent would
+// before pushing them, ending with a server-side dry-run in the remote
+// cluster, and prints the remote claims the remote cluster would store.
+type Command struct {
+	ClusterConfig *rest.Config
+
+	// File is the path of the file with the claims, or - for stdin.
+	File string
+
+	// NamespaceMapping maps local namespaces to the remote namespaces their
+	// claims are synced to.
+	NamespaceMapping map[string]string
+
+	// CanaryNamespace is the namespace in the remote cluster the dry-run is
+	// made in. The remote namespace of each claim is used if it's empty.
+	CanaryNamespace string
+
+	// MaxClaimSize is the largest serialized claim, in bytes, that is pushed
+	// to the remote cluster.
+	MaxClaimSize int
+}
+
+// Run validates all claims in the file, printing the result of each to out.
+// An error is returned if any claim would not be accepted.
+func (c *Command) Run(out io.Writer) error {
+	in := os.Stdin
+	if c.File != "-" {
+		f, err := os.Open(c.File)
+		if err != nil {
+			return errors.Wrap(err, "cannot open claim file")
+		}
+		defer f.Close() // nolint:errcheck
+		in = f
+	}
+	kube, err := client.New(c.ClusterConfig, client.Options{})
+	if err != nil {
+		return errors.Wrap(err, "cannot create cluster remote client")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	failed := 0
+	d := yaml.NewYAMLOrJSONDecoder(in, 4096)
+	for {
+		obj := map[string]interface{}{}
+		if err := d.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrap(err, "cannot decode claim file")
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		local := claim.New()
+		local.Object = obj
+		id := fmt.Sprintf("%s %s/%s", local.GetKind(), local.GetNamespace(), local.GetName())
+		remote, err := c.validate(ctx, kube, local)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "# %s is rejected: %s\n", id, err) // nolint:errcheck
+			continue
+		}
+		b, err := json.MarshalIndent(remote.Object, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "cannot print remote claim")
+		}
+		fmt.Fprintf(out, "# %s is accepted as:\n%s\n", id, b) // nolint:errcheck
+	}
+	if failed > 0 {
+		return errors.Errorf("%d claims would be rejected", failed)
+	}
+	return nil
+}
+
+func (c *Command) validate(ctx context.Context, kube client.Client, local *claim.Unstructured) (*claim.Unstructured, error) {
+	remote, err := agentclaim.Render(ctx, agentclaim.NewDefaultConfigurator(), agentclaim.NamespaceMap(c.NamespaceMapping), local)
+	if err != nil {
+		return nil, err
+	}
+	size, err := agentclaim.ObjectSize(remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot measure the serialized size of claim")
+	}
+	if c.MaxClaimSize > 0 && size > c.MaxClaimSize {
+		return nil, errors.Errorf("serialized claim is %d bytes, which exceeds the limit of %d bytes", size, c.MaxClaimSize)
+	}
+	if c.CanaryNamespace != "" {
+		remote.SetNamespace(c.CanaryNamespace)
+	}
+
+	// The claim may already be in the remote cluster, in which case the
+	// dry-run is an update of it.
+	err = kube.Create(ctx, remote.GetUnstructured(), client.DryRunAll)
+	if err == nil {
+		return remote, nil
+	}
+	if !kerrors.IsAlreadyExists(err) {
+		return nil, errors.Wrap(err, "remote cluster: cannot create claim with dry-run")
+	}
+	current := claim.New(claim.WithGroupVersionKind(remote.GetObjectKind().GroupVersionKind()))
+	if err := kube.Get(ctx, types.NamespacedName{Namespace: remote.GetNamespace(), Name: remote.GetName()}, current.GetUnstructured()); err != nil {
+		return nil, errors.Wrap(err, "remote cluster: cannot get claim")
+	}
+	remote.SetResourceVersion(current.GetResourceVersion())
+	if err := kube.Update(ctx, remote.GetUnstructured(), client.DryRunAll); err != nil {
+		return nil, errors.Wrap(err, "remote cluster: cannot update claim with dry-run")
+	}
+	return remote, nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package alerts generates Prometheus alerting rules from the conditions and
+// the metrics of the agent.
+package alerts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// Severities of the alerts.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// Reasons of the AgentSynced condition that are alerted on, by what they mean
+// for the claim. Reasons that are not listed here don't need attention.
+var (
+	// BlockedReasons hold the sync of a claim until something outside of the
+	// agent changes.
+	BlockedReasons = []v1alpha1.ConditionReason{
+		resource.ReasonAgentSyncObjectTooLarge,
+		resource.ReasonAgentSyncCanaryFailed,
+		resource.ReasonAgentSyncRolloutPaused,
+		resource.ReasonAgentSyncLocked,
+		resource.ReasonAgentSyncFenced,
+		resource.ReasonAgentSyncNoNamespace,
+		resource.ReasonAgentSyncWaiting,
+		resource.ReasonAgentSyncPending,
+		resource.ReasonAgentSyncStale,
+		resource.ReasonAgentSyncLoop,
+		resource.ReasonAgentSyncRemoteExists,
+		resource.ReasonAgentSyncDisconnected,
+		resource.ReasonAgentSyncMissingLabels,
+		resource.ReasonAgentSyncSchemaInvalid,
+		resource.ReasonAgentSyncQuotaExceeded,
+		resource.ReasonAgentSyncRejected,
+	}
+
+	// FailedReasons mean the sync of a claim failed and is retried.
+	FailedReasons = []v1alpha1.ConditionReason{resource.ReasonAgentSyncError}
+
+	// DriftReasons mean the remote claim was changed out-of-band.
+	DriftReasons = []v1alpha1.ConditionReason{resource.ReasonAgentSyncRemoteReplaced, resource.ReasonAgentSyncRemoteDeleted, resource.ReasonAgentSyncDrifted}
+
+	// UntrustedReasons mean the remote cluster could not be verified.
+	UntrustedReasons = []v1alpha1.ConditionReason{resource.ReasonAgentSyncUntrusted}
+)
+
+// A PrometheusRule of the Prometheus Operator. Only the fields that are
+// generated are declared, so that the operator isn't a dependency.
+type PrometheusRule struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Metadata   Metadata `json:"metadata"`
+	Spec       RuleSpec `json:"spec"`
+}
+
+// Metadata of a PrometheusRule.
+type Metadata struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// RuleSpec is the spec of a PrometheusRule.
+type RuleSpec struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+// A RuleGroup is a group of rules that are evaluated together.
+type RuleGroup struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// A Rule is an alerting rule.
+type Rule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Options of the generated rules.
+type Options struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+
+	// BlockedFor is how long claims may be blocked or failing before it's
+	// alerted on.
+	BlockedFor time.Duration
+
+	// MaxPropagationLag is the propagation lag of the 90th percentile of
+	// changes that is alerted on.
+	MaxPropagationLag time.Duration
+}
+
+// Rules returns the alerting rules of the agent.
+func Rules(o Options) PrometheusRule {
+	return PrometheusRule{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       "PrometheusRule",
+		Metadata:   Metadata{Name: o.Name, Namespace: o.Namespace, Labels: o.Labels},
+		Spec: RuleSpec{Groups: []RuleGroup{{
+			Name: "crossplane-agent",
+			Rules: []Rule{
+				{
+					Alert:  "CrossplaneAgentRemoteUnreachable",
+					Expr:   "max(crossplane_agent_remote_connected) == 0",
+					For:    "5m",
+					Labels: map[string]string{"severity": SeverityCritical},
+					Annotations: map[string]string{
+						"summary": "The remote cluster has been unreachable for 5 minutes; claims are not synced.",
+					},
+				},
+				{
+					Alert:  "CrossplaneAgentRemoteConnectionFailing",
+					Expr:   "max by (stage) (crossplane_agent_remote_probe_failing) == 1",
+					For:    "5m",
+					Labels: map[string]string{"severity": SeverityCritical},
+					Annotations: map[string]string{
+						"summary": "The connection to the remote cluster has been failing at the {{ $labels.stage }} stage for 5 minutes; DNS and TLS point to the network or certificates, Authentication to the credentials and Authorization to the RBAC of the agent.",
+					},
+				},
+				{
+					Alert:  "CrossplaneAgentRemoteUntrusted",
+					Expr:   byReason(UntrustedReasons),
+					Labels: map[string]string{"severity": SeverityCritical},
+					Annotations: map[string]string{
+						"summary": "The remote cluster presents a certificate that doesn't match the pinned ones.",
+					},
+				},
+				{
+					Alert:  "CrossplaneAgentSyncBlocked",
+					Expr:   byReason(BlockedReasons),
+					For:    duration(o.BlockedFor),
+					Labels: map[string]string{"severity": SeverityWarning},
+					Annotations: map[string]string{
+						"summary": "{{ $value }} claims of {{ $labels.gvk }} are not synced: {{ $labels.reason }}.",
+					},
+				},
+				{
+					Alert:  "CrossplaneAgentSyncFailing",
+					Expr:   byReason(FailedReasons),
+					For:    duration(o.BlockedFor),
+					Labels: map[string]string{"severity": SeverityWarning},
+					Annotations: map[string]string{
+						"summary": "{{ $value }} claims of {{ $labels.gvk }} keep failing to sync.",
+					},
+				},
+				{
+					Alert:  "CrossplaneAgentDriftDetected",
+					Expr:   byReason(DriftReasons),
+					Labels: map[string]string{"severity": SeverityWarning},
+					Annotations: map[string]string{
+						"summary": "{{ $value }} remote claims of {{ $labels.gvk }} were changed out-of-band.",
+					},
+				},
+				{
+					Alert:  "CrossplaneAgentPropagationLagHigh",
+					Expr:   fmt.Sprintf("max by (gvk) (crossplane_agent_claim_propagation_lag_seconds{quantile=\"0.9\"}) > %g", o.MaxPropagationLag.Seconds()),
+					For:    "15m",
+					Labels: map[string]string{"severity": SeverityWarning},
+					Annotations: map[string]string{
+						"summary": "Changes to claims of {{ $labels.gvk }} take {{ $value }}s to reach the remote cluster.",
+					},
+				},
+			},
+		}}},
+	}
+}
+
+func byReason(reasons []v1alpha1.ConditionReason) string {
+	r := make([]string, len(reasons))
+	for i := range reasons {
+		r[i] = string(reasons[i])
+	}
+	return fmt.Sprintf("sum by (gvk, reason) (crossplane_agent_claim_sync_reason_claims{reason=~\"%s\"}) > 0", strings.Join(r, "|"))
+}
+
+func duration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
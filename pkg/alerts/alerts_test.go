@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestRules(t *testing.T) {
+	r := Rules(Options{Name: "cool-rules", BlockedFor: 30 * time.Minute, MaxPropagationLag: 5 * time.Minute})
+
+	var exprs []string
+	for _, g := range r.Spec.Groups {
+		for _, rule := range g.Rules {
+			exprs = append(exprs, rule.Expr)
+		}
+	}
+	all := strings.Join(exprs, "\n")
+
+	// Every reason a claim can be stuck with should be alerted on.
+	for _, reason := range []v1alpha1.ConditionReason{
+		resource.ReasonAgentSyncError,
+		resource.ReasonAgentSyncObjectTooLarge,
+		resource.ReasonAgentSyncCanaryFailed,
+		resource.ReasonAgentSyncRolloutPaused,
+		resource.ReasonAgentSyncRemoteReplaced,
+		resource.ReasonAgentSyncLocked,
+		resource.ReasonAgentSyncFenced,
+		resource.ReasonAgentSyncNoNamespace,
+		resource.ReasonAgentSyncWaiting,
+		resource.ReasonAgentSyncPending,
+		resource.ReasonAgentSyncUntrusted,
+		resource.ReasonAgentSyncStale,
+		resource.ReasonAgentSyncLoop,
+		resource.ReasonAgentSyncRemoteDeleted,
+		resource.ReasonAgentSyncRemoteExists,
+		resource.ReasonAgentSyncDisconnected,
+		resource.ReasonAgentSyncDrifted,
+		resource.ReasonAgentSyncMissingLabels,
+		resource.ReasonAgentSyncSchemaInvalid,
+		resource.ReasonAgentSyncQuotaExceeded,
+		resource.ReasonAgentSyncRejected,
+	} {
+		if !strings.Contains(all, string(reason)) {
+			t.Errorf("Rules(...): no rule alerts on claims with reason %s", reason)
+		}
+	}
+	if strings.Contains(all, string(resource.ReasonAgentSyncSuccess)) {
+		t.Errorf("Rules(...): claims that are synced should not be alerted on")
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backpressure slows down all sync controllers of an agent when a
+// large fraction of reconciles fail, e.g. during an outage of the remote
+// cluster, and restores the normal cadence once they succeed again. While all
+// reconcile slots are taken, reconciles of higher priority go first.
+package backpressure
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/rollout"
+)
+
+const (
+	// DefaultMaxFactor is the largest multiplier that is applied to requeue
+	// intervals unless configured otherwise.
+	DefaultMaxFactor = 10
+
+	window = 5 * time.Minute
+
+	// deferWait is how long a reconcile that's deferred in favour of ones
+	// with higher priority is requeued after.
+	deferWait = 5 * time.Second
+
+	errAcquire = "cannot acquire a reconcile slot"
+)
+
+// A RegulatorOption configures a Regulator.
+type RegulatorOption func(*Regulator)
+
+// WithMaxFactor specifies the largest multiplier that is applied to requeue
+// intervals when all reconciles fail.
+func WithMaxFactor(f float64) RegulatorOption {
+	return func(r *Regulator) {
+		r.maxFactor = f
+	}
+}
+
+// WithMaxConcurrency specifies how many reconciles may run at the same time
+// across all wrapped Reconcilers while the error rate is healthy.
+func WithMaxConcurrency(n int) RegulatorOption {
+	return func(r *Regulator) {
+		r.maxConcurrency = n
+	}
+}
+
+// NewRegulator returns a new *Regulator that starts applying backpressure once
+// the fraction of failed reconciles goes above the supplied threshold.
+func NewRegulator(threshold float64, o ...RegulatorOption) *Regulator {
+	r := &Regulator{
+		ErrorBudget:    rollout.NewErrorBudget(threshold, window),
+		threshold:      threshold,
+		maxFactor:      DefaultMaxFactor,
+		maxConcurrency: math.MaxInt32,
+		wake:           make(chan struct{}),
+		waiting:        map[Priority]int{},
+	}
+	for _, f := range o {
+		f(r)
+	}
+	return r
+}
+
+// A Regulator tracks the error rate of reconciles and derives how much longer
+// the requeue intervals should be and how many reconciles may run at once.
+// It satisfies claim.SyncObserver so that the result of claim syncs, which
+// don't return an error when they fail, is taken into account.
+type Regulator struct {
+	*rollout.ErrorBudget
+
+	threshold      float64
+	maxFactor      float64
+	maxConcurrency int
+
+	mu       sync.Mutex
+	inflight int
+	waiting  map[Priority]int
+	wake     chan struct{}
+}
+
+// ObserveSync records the result of the last sync of the supplied claim. Only
+// claims that failed with an error count as failures; claims that are held on
+// purpose, e.g. while onboarding or pending approval, don't add to the load of
+// the clusters and so shouldn't slow down the others.
+func (r *Regulator) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	r.Record(c.GetCondition(resource.TypeAgentSync).Reason == resource.ReasonAgentSyncError)
+}
+
+// Factor returns the multiplier that should be applied to requeue intervals.
+// It's 1 while the error budget is not exhausted and grows linearly with the
+// error rate up to the maximum factor.
+func (r *Regulator) Factor() float64 {
+	if !r.Exhausted() {
+		return 1
+	}
+	rate, _ := r.ErrorRate()
+	if r.threshold >= 1 {
+		return 1
+	}
+	return 1 + (rate-r.threshold)/(1-r.threshold)*(r.maxFactor-1)
+}
+
+// Limit returns how many reconciles may run at the same time. It shrinks by
+// the same factor the requeue intervals grow, but never below one.
+func (r *Regulator) Limit() int {
+	return int(math.Max(1, math.Floor(float64(r.maxConcurrency)/r.Factor())))
+}
+
+// Acquire blocks until a reconcile of the given priority is allowed to run or
+// the supplied context is done. A slot is given to the waiting reconciles of
+// the highest priority first. Every successful call must be followed by a call
+// to Release.
+func (r *Regulator) Acquire(ctx context.Context, p Priority) error {
+	r.mu.Lock()
+	r.waiting[p]++
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.waiting[p]--
+		r.broadcast()
+		r.mu.Unlock()
+	}()
+
+	for {
+		r.mu.Lock()
+		if r.inflight < r.Limit() && !r.outranked(p) {
+			r.inflight++
+			r.mu.Unlock()
+			return nil
+		}
+		wake := r.wake
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Release a slot that was acquired with Acquire.
+func (r *Regulator) Release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inflight--
+	r.broadcast()
+}
+
+// Outranked returns true if all slots are taken and reconciles of a higher
+// priority than the given one are waiting for a slot.
+func (r *Regulator) Outranked(p Priority) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.inflight >= r.Limit() && r.outranked(p)
+}
+
+// outranked returns true if reconciles of a higher priority than the given one
+// are waiting for a slot. It must be called with the lock held.
+func (r *Regulator) outranked(p Priority) bool {
+	for wp, n := range r.waiting {
+		if wp > p && n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast wakes up everyone waiting for a slot. It must be called with the
+// lock held.
+func (r *Regulator) broadcast() {
+	close(r.wake)
+	r.wake = make(chan struct{})
+}
+
+// A ReconcilerOption configures a Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithErrorRecording makes the Reconciler record every reconcile that returns
+// an error as a failure and every other one as a success. It should be used
+// only with reconcilers that return an error when they fail.
+func WithErrorRecording() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.recordErrors = true
+	}
+}
+
+// WithPriority specifies how the Reconciler should determine the priority of
+// a request.
+func WithPriority(fn PriorityFn) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.priority = fn
+	}
+}
+
+// NewReconciler returns a Reconciler that applies the backpressure derived by
+// the supplied Regulator to the supplied reconcile.Reconciler.
+func NewReconciler(wrapped reconcile.Reconciler, reg *Regulator, o ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{wrapped: wrapped, regulator: reg, priority: NewNormalPriority()}
+	for _, f := range o {
+		f(r)
+	}
+	return r
+}
+
+// A Reconciler limits the number of concurrent reconciles of the wrapped
+// reconcile.Reconciler and lengthens its requeue intervals according to a
+// Regulator that is shared by all controllers.
+type Reconciler struct {
+	wrapped      reconcile.Reconciler
+	regulator    *Regulator
+	priority     PriorityFn
+	recordErrors bool
+}
+
+// Reconcile calls the wrapped reconcile.Reconciler once a slot is available.
+func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	// The wait for a slot is bounded so that a worker of the controller is not
+	// blocked forever. The request will be retried with the usual rate limit.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// A request that would have to wait behind ones of a higher priority is
+	// put back into the queue so that the worker can pick up the next one,
+	// which may be of a higher priority itself.
+	p := r.priority(ctx, req)
+	if r.regulator.Outranked(p) {
+		return reconcile.Result{RequeueAfter: deferWait}, nil
+	}
+	if err := r.regulator.Acquire(ctx, p); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, errAcquire)
+	}
+	defer r.regulator.Release()
+
+	result, err := r.wrapped.Reconcile(req)
+	if r.recordErrors {
+		r.regulator.Record(err != nil)
+	}
+	if f := r.regulator.Factor(); f > 1 && result.RequeueAfter > 0 {
+		result.RequeueAfter = time.Duration(float64(result.RequeueAfter) * f)
+	}
+	return result, err
+}
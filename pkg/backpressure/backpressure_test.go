@@ -0,0 +1,203 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backpressure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+var errBoom = errors.New("boom")
+
+type reconcileFn func(req reconcile.Request) (reconcile.Result, error)
+
+func (fn reconcileFn) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	return fn(req)
+}
+
+func TestReconcile(t *testing.T) {
+	type want struct {
+		result reconcile.Result
+		limit  int
+	}
+	cases := map[string]struct {
+		reason   string
+		failures int
+		want     want
+	}{
+		"Healthy": {
+			reason:   "No backpressure should be applied while reconciles succeed",
+			failures: 0,
+			want:     want{result: reconcile.Result{RequeueAfter: time.Minute}, limit: 10},
+		},
+		"AllFailing": {
+			reason:   "The maximum backpressure should be applied while all reconciles fail",
+			failures: 20,
+			want:     want{result: reconcile.Result{RequeueAfter: 10 * time.Minute}, limit: 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg := NewRegulator(0.5, WithMaxConcurrency(10))
+			fail := tc.failures
+			r := NewReconciler(reconcileFn(func(_ reconcile.Request) (reconcile.Result, error) {
+				if fail > 0 {
+					fail--
+					return reconcile.Result{RequeueAfter: time.Minute}, errBoom
+				}
+				return reconcile.Result{RequeueAfter: time.Minute}, nil
+			}), reg, WithErrorRecording())
+
+			for i := 1; i < tc.failures; i++ {
+				_, _ = r.Reconcile(reconcile.Request{})
+			}
+			got, _ := r.Reconcile(reconcile.Request{})
+			if diff := cmp.Diff(tc.want.result, got); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.limit, reg.Limit()); diff != "" {
+				t.Errorf("\nReason: %s\nreg.Limit(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveSync(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		cond   v1alpha1.Condition
+		want   int
+	}{
+		"Failing": {
+			reason: "Backpressure should be applied while claims fail to sync",
+			cond:   resource.AgentSyncError(errBoom),
+			want:   1,
+		},
+		"Held": {
+			reason: "No backpressure should be applied while claims are held on purpose",
+			cond:   resource.AgentSyncOnboarding("1/20"),
+			want:   10,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg := NewRegulator(0.5, WithMaxConcurrency(10))
+			cl := claim.New()
+			cl.SetConditions(tc.cond)
+			for i := 0; i < 20; i++ {
+				reg.ObserveSync(context.Background(), cl)
+			}
+			if diff := cmp.Diff(tc.want, reg.Limit()); diff != "" {
+				t.Errorf("\nReason: %s\nreg.Limit(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPriority(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		priority Priority
+		waiting  Priority
+		want     reconcile.Result
+	}{
+		"Outranked": {
+			reason:   "A request should be deferred while all slots are taken and ones with a higher priority are waiting",
+			priority: PriorityLow,
+			waiting:  PriorityHigh,
+			want:     reconcile.Result{RequeueAfter: deferWait},
+		},
+		"NotOutranked": {
+			reason:   "A request should not be deferred in favour of ones with the same or a lower priority",
+			priority: PriorityHigh,
+			waiting:  PriorityNormal,
+			want:     reconcile.Result{RequeueAfter: time.Minute},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg := NewRegulator(0.5, WithMaxConcurrency(1))
+
+			// Take the only slot and have a request of the waiting priority
+			// queue up behind it.
+			if err := reg.Acquire(context.Background(), PriorityNormal); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			acquired := make(chan error, 1)
+			go func() { acquired <- reg.Acquire(ctx, tc.waiting) }()
+			for !reg.Outranked(tc.waiting - 1) {
+				time.Sleep(time.Millisecond)
+			}
+
+			r := NewReconciler(reconcileFn(func(_ reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{RequeueAfter: time.Minute}, nil
+			}), reg, WithPriority(func(_ context.Context, _ reconcile.Request) Priority { return tc.priority }))
+
+			got := make(chan reconcile.Result, 1)
+			go func() {
+				res, _ := r.Reconcile(reconcile.Request{})
+				got <- res
+			}()
+			deferred := tc.want.RequeueAfter == deferWait
+			if !deferred {
+				// Once the request queues up for the slot as well, the slot
+				// is released. It's given to the request of the highest
+				// priority and the other one gets it after.
+				for !reg.Outranked(tc.priority - 1) {
+					time.Sleep(time.Millisecond)
+				}
+				reg.Release()
+			}
+			if diff := cmp.Diff(tc.want, <-got); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if deferred {
+				reg.Release()
+			}
+			if err := <-acquired; err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+		})
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	cases := map[string]Priority{
+		"low":     PriorityLow,
+		"High":    PriorityHigh,
+		"normal":  PriorityNormal,
+		"urgent!": PriorityNormal,
+		"":        PriorityNormal,
+	}
+	for in, want := range cases {
+		if diff := cmp.Diff(want, ParsePriority(in)); diff != "" {
+			t.Errorf("ParsePriority(%q): -want, +got:\n%s", in, diff)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backpressure
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// A Priority determines the order in which reconciles get a slot while all of
+// them are taken. Greater is more important.
+type Priority int
+
+// Priorities.
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// ParsePriority returns the Priority with the given name, i.e. low, normal or
+// high. Anything else is normal.
+func ParsePriority(s string) Priority {
+	switch strings.ToLower(s) {
+	case "low":
+		return PriorityLow
+	case "high":
+		return PriorityHigh
+	default:
+		return PriorityNormal
+	}
+}
+
+// A PriorityFn returns the priority of the supplied request.
+type PriorityFn func(ctx context.Context, req reconcile.Request) Priority
+
+// NewNormalPriority returns a PriorityFn that treats every request equally.
+func NewNormalPriority() PriorityFn {
+	return func(_ context.Context, _ reconcile.Request) Priority { return PriorityNormal }
+}
+
+// NewAnnotationPriority returns a PriorityFn that reads the priority of the
+// object of the given kind that a request is for from its priority annotation.
+// Objects without one get the priority of their namespace, which is read from
+// the same annotation on the namespace. Everything else is normal.
+func NewAnnotationPriority(c client.Reader, gvk schema.GroupVersionKind) PriorityFn {
+	return func(ctx context.Context, req reconcile.Request) Priority {
+		obj := &kunstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		if err := c.Get(ctx, req.NamespacedName, obj); err == nil {
+			if v, ok := obj.GetAnnotations()[resource.AnnotationKeyPriority]; ok {
+				return ParsePriority(v)
+			}
+		}
+		if req.Namespace == "" {
+			return PriorityNormal
+		}
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+			return PriorityNormal
+		}
+		return ParsePriority(ns.GetAnnotations()[resource.AnnotationKeyPriority])
+	}
+}
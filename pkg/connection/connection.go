@@ -0,0 +1,316 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package connection probes the connection to the remote cluster in stages so
+// that the one that fails tells whether it's the network, the certificates or
+// the permissions of the agent that are wrong.
+package connection
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the state of the connection
+	// to the remote cluster is published to.
+	ConfigMapName = "crossplane-agent-connection"
+
+	keyStatus  = "status"
+	keyStage   = "stage"
+	keyError   = "error"
+	keyUpdated = "updated"
+
+	// StatusConnected means every stage of the connection succeeded.
+	StatusConnected = "Connected"
+
+	// StatusFailing means one of them failed.
+	StatusFailing = "Failing"
+
+	probeTimeout = 10 * time.Second
+
+	schemeHTTPS = "https"
+	portHTTPS   = "443"
+	portHTTP    = "80"
+
+	errParseHost      = "cannot parse the host of the remote cluster"
+	errTLSConfig      = "cannot build the TLS configuration of the remote cluster"
+	errResolve        = "cannot resolve the host of the remote cluster"
+	errDial           = "cannot connect to the remote cluster"
+	errHandshake      = "cannot complete the TLS handshake with the remote cluster"
+	errReview         = "cannot review the permissions of the agent"
+	errPublish        = "cannot publish connection status"
+	errFmtUnauthentic = "remote cluster does not accept the credentials of the agent: %s"
+	errFmtDenied      = "agent is not allowed to %s"
+)
+
+// A Stage of the connection to the remote cluster.
+type Stage string
+
+// Stages of the connection to the remote cluster, in the order they're
+// probed.
+const (
+	StageDNS            Stage = "DNS"
+	StageTLS            Stage = "TLS"
+	StageAuthentication Stage = "Authentication"
+	StageAuthorization  Stage = "Authorization"
+)
+
+// A Result of a probe. Its Stage is the one that failed, with Err, or empty if
+// none did.
+type Result struct {
+	Stage Stage
+	Err   error
+}
+
+// DefaultPermissions are what the agent needs to be allowed to do in the
+// remote cluster for claims to be synced at all.
+var DefaultPermissions = []authorizationv1.ResourceAttributes{
+	{Verb: "list", Group: "apiextensions.crossplane.io", Resource: "compositeresourcedefinitions"},
+	{Verb: "list", Group: "apiextensions.crossplane.io", Resource: "compositions"},
+}
+
+// A ProberOption configures a Prober.
+type ProberOption func(*Prober)
+
+// WithResolver specifies how the Prober should resolve the host of the remote
+// cluster.
+func WithResolver(fn func(ctx context.Context, host string) ([]string, error)) ProberOption {
+	return func(p *Prober) {
+		p.resolve = fn
+	}
+}
+
+// WithDialer specifies how the Prober should open connections to the remote
+// cluster.
+func WithDialer(fn func(ctx context.Context, network, address string) (net.Conn, error)) ProberOption {
+	return func(p *Prober) {
+		p.dial = fn
+	}
+}
+
+// WithPermissions specifies what the Prober should check that the agent is
+// allowed to do in the remote cluster. DefaultPermissions are checked by
+// default.
+func WithPermissions(attrs ...authorizationv1.ResourceAttributes) ProberOption {
+	return func(p *Prober) {
+		p.permissions = attrs
+	}
+}
+
+// NewProber returns a new *Prober of the remote cluster the supplied config
+// points to. The supplied client is used to review the permissions of the
+// agent and should not be a dry-run one.
+func NewProber(cfg *rest.Config, c client.Client, o ...ProberOption) (*Prober, error) {
+	u, err := url.Parse(cfg.Host)
+	if err != nil || u.Host == "" {
+		// Hosts are allowed to be given without a scheme.
+		u, err = url.Parse(schemeHTTPS + "://" + cfg.Host)
+		if err != nil {
+			return nil, errors.Wrap(err, errParseHost)
+		}
+	}
+	tc, err := rest.TLSConfigFor(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, errTLSConfig)
+	}
+	port := u.Port()
+	if port == "" {
+		port = portHTTPS
+		if u.Scheme != schemeHTTPS {
+			port = portHTTP
+		}
+	}
+	if u.Scheme == schemeHTTPS && tc == nil {
+		tc = &tls.Config{}
+	}
+	if tc != nil && tc.ServerName == "" {
+		tc.ServerName = u.Hostname()
+	}
+	d := &net.Dialer{}
+	p := &Prober{
+		host:        u.Hostname(),
+		port:        port,
+		tls:         tc,
+		client:      c,
+		permissions: DefaultPermissions,
+		resolve:     net.DefaultResolver.LookupHost,
+		dial:        d.DialContext,
+	}
+	for _, f := range o {
+		f(p)
+	}
+	return p, nil
+}
+
+// A Prober probes the connection to the remote cluster one stage at a time:
+// it resolves its host, connects to it and completes the TLS handshake, then
+// checks that the agent is authenticated and allowed to do what it needs to.
+type Prober struct {
+	host        string
+	port        string
+	tls         *tls.Config
+	client      client.Client
+	permissions []authorizationv1.ResourceAttributes
+
+	resolve func(ctx context.Context, host string) ([]string, error)
+	dial    func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Probe the connection to the remote cluster and return the first stage that
+// fails, if any.
+func (p *Prober) Probe(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	if net.ParseIP(p.host) == nil {
+		if _, err := p.resolve(ctx, p.host); err != nil {
+			return Result{Stage: StageDNS, Err: errors.Wrap(err, errResolve)}
+		}
+	}
+	if err := p.connect(ctx); err != nil {
+		return Result{Stage: StageTLS, Err: err}
+	}
+	for _, attrs := range p.permissions {
+		a := attrs
+		r := &authorizationv1.SelfSubjectAccessReview{Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &a}}
+		if err := p.client.Create(ctx, r); err != nil {
+			if kerrors.IsUnauthorized(err) {
+				return Result{Stage: StageAuthentication, Err: errors.Errorf(errFmtUnauthentic, err)}
+			}
+			return Result{Stage: StageAuthorization, Err: errors.Wrap(err, errReview)}
+		}
+		if !r.Status.Allowed {
+			return Result{Stage: StageAuthorization, Err: errors.Errorf(errFmtDenied, describe(a))}
+		}
+	}
+	return Result{}
+}
+
+// connect to the remote cluster, completing the TLS handshake if it's served
+// over TLS.
+func (p *Prober) connect(ctx context.Context) error {
+	conn, err := p.dial(ctx, "tcp", net.JoinHostPort(p.host, p.port))
+	if err != nil {
+		return errors.Wrap(err, errDial)
+	}
+	defer conn.Close() // nolint:errcheck
+	if p.tls == nil {
+		return nil
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	return errors.Wrap(tls.Client(conn, p.tls).Handshake(), errHandshake)
+}
+
+// describe the supplied resource attributes, e.g. list compositions.apiextensions.crossplane.io.
+func describe(a authorizationv1.ResourceAttributes) string {
+	r := a.Resource
+	if a.Group != "" {
+		r += "." + a.Group
+	}
+	s := []string{a.Verb, r}
+	if a.Namespace != "" {
+		s = append(s, "in namespace "+a.Namespace)
+	}
+	return strings.Join(s, " ")
+}
+
+// NewReporter returns a new *Reporter. The supplied metrics may be nil.
+func NewReporter(p *Prober, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, m *metrics.ConnectionProbe, log logging.Logger) *Reporter {
+	return &Reporter{prober: p, client: a, name: nn, period: period, metrics: m, log: log}
+}
+
+// A Reporter periodically probes the connection to the remote cluster and
+// writes the stage that fails, if any, into a ConfigMap.
+type Reporter struct {
+	prober  *Prober
+	client  runtimeresource.Applicator
+	name    types.NamespacedName
+	period  time.Duration
+	metrics *metrics.ConnectionProbe
+	log     logging.Logger
+
+	last Stage
+}
+
+// Start reporting until the supplied channel is closed.
+func (r *Reporter) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.period)
+	defer t.Stop()
+	for {
+		if err := r.Report(context.Background()); err != nil {
+			r.log.Debug("Cannot report connection status", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Report the state of the connection to the remote cluster.
+func (r *Reporter) Report(ctx context.Context) error {
+	res := r.prober.Probe(ctx)
+	if res.Stage != r.last {
+		if res.Stage != "" {
+			r.log.Info("Connection to the remote cluster is failing", "stage", res.Stage, "error", res.Err)
+		} else {
+			r.log.Info("Connection to the remote cluster is healthy again", "failed-stage", r.last)
+		}
+		r.last = res.Stage
+	}
+	if r.metrics != nil {
+		for _, s := range []Stage{StageDNS, StageTLS, StageAuthentication, StageAuthorization} {
+			failing := 0.0
+			if s == res.Stage {
+				failing = 1
+			}
+			r.metrics.Failing.WithLabelValues(string(s)).Set(failing)
+		}
+	}
+	data := map[string]string{
+		keyStatus:  StatusConnected,
+		keyStage:   "",
+		keyError:   "",
+		keyUpdated: time.Now().UTC().Format(time.RFC3339),
+	}
+	if res.Stage != "" {
+		data[keyStatus] = StatusFailing
+		data[keyStage] = string(res.Stage)
+		data[keyError] = res.Err.Error()
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, r.client, r.name, data), errPublish)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connection
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var errBoom = errors.New("boom")
+
+func TestProbe(t *testing.T) {
+	resolved := WithResolver(func(_ context.Context, _ string) ([]string, error) { return []string{"10.0.0.1"}, nil })
+	connected := WithDialer(func(_ context.Context, _, _ string) (net.Conn, error) {
+		c, _ := net.Pipe()
+		return c, nil
+	})
+	review := func(allowed bool) *test.MockClient {
+		return &test.MockClient{MockCreate: func(_ context.Context, obj runtime.Object, _ ...client.CreateOption) error {
+			obj.(*authorizationv1.SelfSubjectAccessReview).Status.Allowed = allowed
+			return nil
+		}}
+	}
+	unauthorized := kerrors.NewUnauthorized("invalid bearer token")
+	perm := authorizationv1.ResourceAttributes{Verb: "list", Group: "apiextensions.crossplane.io", Resource: "compositions"}
+
+	type args struct {
+		host   string
+		client client.Client
+		opts   []ProberOption
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   Result
+	}{
+		"DNSFailed": {
+			reason: "A host that cannot be resolved should fail the DNS stage",
+			args: args{
+				host: "https://hub.example.org",
+				opts: []ProberOption{WithResolver(func(_ context.Context, _ string) ([]string, error) { return nil, errBoom })},
+			},
+			want: Result{Stage: StageDNS, Err: errors.Wrap(errBoom, errResolve)},
+		},
+		"DialFailed": {
+			reason: "A host that cannot be connected to should fail the TLS stage",
+			args: args{
+				host: "https://hub.example.org:6443",
+				opts: []ProberOption{resolved, WithDialer(func(_ context.Context, _, address string) (net.Conn, error) {
+					if address != "hub.example.org:6443" {
+						t.Errorf("Dial(...): unexpected address %s", address)
+					}
+					return nil, errBoom
+				})},
+			},
+			want: Result{Stage: StageTLS, Err: errors.Wrap(errBoom, errDial)},
+		},
+		"Unauthenticated": {
+			reason: "Credentials that are rejected should fail the authentication stage",
+			args: args{
+				host:   "http://10.0.0.1:8080",
+				client: &test.MockClient{MockCreate: test.NewMockCreateFn(unauthorized)},
+				opts:   []ProberOption{connected},
+			},
+			want: Result{Stage: StageAuthentication, Err: errors.Errorf(errFmtUnauthentic, unauthorized)},
+		},
+		"ReviewFailed": {
+			reason: "Permissions that cannot be reviewed should fail the authorization stage",
+			args: args{
+				host:   "http://10.0.0.1:8080",
+				client: &test.MockClient{MockCreate: test.NewMockCreateFn(errBoom)},
+				opts:   []ProberOption{connected},
+			},
+			want: Result{Stage: StageAuthorization, Err: errors.Wrap(errBoom, errReview)},
+		},
+		"Denied": {
+			reason: "Permissions the agent isn't granted should fail the authorization stage",
+			args: args{
+				host:   "http://10.0.0.1:8080",
+				client: review(false),
+				opts:   []ProberOption{connected, WithPermissions(perm)},
+			},
+			want: Result{Stage: StageAuthorization, Err: errors.Errorf(errFmtDenied, "list compositions.apiextensions.crossplane.io")},
+		},
+		"Connected": {
+			reason: "No stage should fail if the agent is connected and allowed to do what it needs to",
+			args: args{
+				host:   "http://10.0.0.1:8080",
+				client: review(true),
+				opts:   []ProberOption{connected},
+			},
+			want: Result{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewProber(&rest.Config{Host: tc.args.host}, tc.args.client, tc.args.opts...)
+			if err != nil {
+				t.Fatalf("NewProber(...): %s", err)
+			}
+			got := p.Probe(context.Background())
+			if diff := cmp.Diff(tc.want, got, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nProbe(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -19,10 +19,12 @@ package apiextensions
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,7 +36,12 @@ import (
 	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1/ccrd"
 
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/remote"
+	"github.com/crossplane/agent/pkg/requeue"
 	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/rollout"
+	"github.com/crossplane/agent/pkg/suspend"
 )
 
 const (
@@ -43,6 +50,8 @@ const (
 	shortWait = 30 * time.Second
 	tinyWait  = 3 * time.Second
 
+	metricsController = "apiextensions"
+
 	localPrefix          = "local cluster: "
 	remotePrefix         = "remote cluster: "
 	errGetCRD            = "cannot get custom resource definition"
@@ -50,6 +59,9 @@ const (
 	errFmtListInstance   = "cannot list %s instances"
 	errFmtDeleteInstance = "cannot delete %s instance"
 	errFmtApplyInstance  = "cannot apply %s instance"
+	errFmtUpdateStatus   = "cannot update status of %s instance"
+	errCheckRollout      = "cannot check whether rollout of updates is paused"
+	errCheckSuspended    = "cannot check whether the agent is suspended"
 )
 
 // ReconcilerOption is used to configure the Reconciler.
@@ -79,6 +91,15 @@ func WithGetItemsFn(f func(l runtime.Object) []runtimeresource.Object) Reconcile
 	}
 }
 
+// WithSanitizer specifies how the Reconciler should copy the remote instances
+// before they're applied in the local cluster. The resource.DefaultSanitizer
+// is used by default.
+func WithSanitizer(s *resource.Sanitizer) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.sanitizer = s
+	}
+}
+
 // WithCRDName specifies the name of the corresponding CRD object that has to be
 // available in the local cluster.
 func WithCRDName(name string) ReconcilerOption {
@@ -101,6 +122,112 @@ func WithRecorder(er event.Recorder) ReconcilerOption {
 	}
 }
 
+// WithApplicator specifies how the Reconciler should apply instances to the
+// local cluster. An Applicator that uses server-side apply keeps the fields
+// that other tooling manages on them, like their annotations, rather than
+// replacing the whole instance.
+func WithApplicator(a runtimeresource.Applicator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.local.Applicator = a
+	}
+}
+
+// WithRolloutGate specifies the Gate that decides whether updates to existing
+// instances may be applied in the local cluster.
+func WithRolloutGate(g rollout.Gate) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.gate = g
+	}
+}
+
+// WithSuspendSwitch specifies the Switch that decides whether the agent is
+// suspended, in which case no instance is applied in or deleted from the
+// local cluster.
+func WithSuspendSwitch(s suspend.Switch) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.suspension = s
+	}
+}
+
+// WithRevisionTracker specifies the RevisionTracker that tells which remote
+// instances changed since they were last synced. All of them are applied, and
+// the local instances are pruned, on every reconcile if it's not specified.
+func WithRevisionTracker(t *RevisionTracker) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.revisions = t
+	}
+}
+
+// WithRemoteAPIReader specifies the client.Reader that is used to read from the
+// remote cluster bypassing any cache before an instance is deleted from the
+// local cluster.
+func WithRemoteAPIReader(cr client.Reader) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.remoteLive = cr
+	}
+}
+
+// WithRemoteListReader specifies the client.Reader that is used to list the
+// instances in the remote cluster when looking for the ones to remove from the
+// local cluster.
+func WithRemoteListReader(cr client.Reader) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.remoteList = cr
+	}
+}
+
+// WithReconnectMonitor specifies the Monitor that tells when the remote cluster
+// is reachable again after an outage so that all instances are reconciled.
+func WithReconnectMonitor(m *remote.Monitor) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.monitor = m
+	}
+}
+
+// WithSyncMetrics specifies the Sync metrics the Reconciler records its syncs
+// and the instances it removes from the local cluster in.
+func WithSyncMetrics(m *metrics.Sync) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.metrics = m
+	}
+}
+
+// WithSyncInterval specifies how long to wait before reconciling an instance
+// again when nothing about it changed.
+func WithSyncInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.syncInterval = d
+	}
+}
+
+// WithErrorRetryInterval specifies how long to wait before retrying an
+// instance that failed to reconcile. The wait starts at base and doubles with
+// each consecutive failure, up to ceiling, with jitter. Since the Reconciler
+// returns the errors it encounters, the wait is applied by the rate limiter of
+// its controller.
+func WithErrorRetryInterval(base, ceiling time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.backoff = requeue.NewBackoff(base, ceiling, requeue.DefaultJitter)
+	}
+}
+
+// WithListOptions restricts the remote instances that are synced to the ones
+// that are listed with the supplied options. Local instances that wouldn't be
+// listed with them are left untouched. Only the label selector is checked
+// when a single instance is reconciled.
+func WithListOptions(o ...client.ListOption) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.listOptions = append(r.listOptions, o...)
+	}
+}
+
+// WithSelector restricts the remote instances that are synced to the ones
+// whose labels match the supplied selector, e.g. environment=staging. Local
+// instances that don't match it are left untouched.
+func WithSelector(s labels.Selector) ReconcilerOption {
+	return WithListOptions(client.MatchingLabelsSelector{Selector: s})
+}
+
 // NewReconciler returns a new *Reconciler object.
 func NewReconciler(mgr manager.Manager, localClient runtimeresource.ClientApplicator, opts ...ReconcilerOption) *Reconciler {
 	r := &Reconciler{
@@ -108,7 +235,14 @@ func NewReconciler(mgr manager.Manager, localClient runtimeresource.ClientApplic
 		log:    logging.NewNopLogger(),
 		remote: mgr.GetClient(),
 		local:  localClient,
+		gate:   rollout.NewOpenGate(),
+
+		sanitizer:    resource.DefaultSanitizer,
+		suspension:   suspend.NewNopSwitch(),
+		syncInterval: longWait,
 	}
+	r.remoteLive = r.remote
+	r.remoteList = r.remote
 
 	for _, f := range opts {
 		f(r)
@@ -121,72 +255,227 @@ func NewReconciler(mgr manager.Manager, localClient runtimeresource.ClientApplic
 // remote cluster to local cluster. It works only with cluster-scoped resources and
 // always overrides the changes made to those Custom Resources in the local cluster.
 type Reconciler struct {
-	remote client.Client
-	local  runtimeresource.ClientApplicator
-	mgr    manager.Manager
+	remote     client.Client
+	remoteLive client.Reader
+	remoteList client.Reader
+	local      runtimeresource.ClientApplicator
+	mgr        manager.Manager
 
 	crdName       types.NamespacedName
 	newObjectList func() runtime.Object
 	getItems      func(l runtime.Object) []runtimeresource.Object
 	newObject     func() runtimeresource.Object
 
+	listOptions []client.ListOption
+	sanitizer   *resource.Sanitizer
+	revisions   *RevisionTracker
+
+	gate       rollout.Gate
+	suspension suspend.Switch
+	monitor    *remote.Monitor
+	metrics    *metrics.Sync
+
+	syncInterval time.Duration
+	backoff      *requeue.Backoff
+
 	log    logging.Logger
 	record event.Recorder
 }
 
 // Reconcile syncs the cluster-scoped instance of the type in remote->local direction.
-func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) { // nolint:gocyclo
-	log := r.log.WithValues("request", req)
+func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	result, err := r.reconcile(req)
+	if err != nil && r.metrics != nil {
+		r.metrics.Failed(metricsController, r.crdName.Name, err)
+	}
+	return result, err
+}
+
+func (r *Reconciler) reconcile(req reconcile.Request) (result reconcile.Result, err error) { // nolint:gocyclo
+	log := r.log.WithValues("request", req, "crd", r.crdName.Name)
 	log.Debug("Reconciling")
 
+	// Every pass is logged with what it did to the local cluster, how long it
+	// took and how it ended. Errors are prefixed with the cluster they come
+	// from.
+	start, operations := time.Now(), []string{}
+	defer func() {
+		op := strings.Join(operations, ",")
+		if op == "" {
+			op = "none"
+		}
+		log.Debug("Reconciled", "operation", op, "duration", time.Since(start), "requeue-after", result.RequeueAfter, "error", err)
+	}()
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	localCRD := &v1beta1.CustomResourceDefinition{}
 	if err := r.local.Get(ctx, r.crdName, localCRD); err != nil {
+		log.Debug("Cannot get custom resource definition", "error", err, "requeue-after", time.Now().Add(shortWait))
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errGetCRD)
 	}
 	if !ccrd.IsEstablished(localCRD.Status) {
+		log.Debug("Custom resource definition is not established yet", "requeue-after", time.Now().Add(tinyWait))
+		operations = append(operations, "skip")
 		return reconcile.Result{RequeueAfter: tinyWait}, nil
 	}
 
 	remoteObject := r.newObject()
 	if err := r.remote.Get(ctx, req.NamespacedName, remoteObject); err != nil {
+		log.Debug("Cannot get instance from remote", "error", err, "requeue-after", time.Now().Add(shortWait))
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, remotePrefix+fmt.Sprintf(errFmtGetInstance, r.crdName.Name))
 	}
-	localObject := resource.SanitizedDeepCopyObject(remoteObject)
-	if err := r.local.Apply(ctx, localObject); err != nil {
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+fmt.Sprintf(errFmtApplyInstance, r.crdName.Name))
+	if !r.selects(remoteObject) {
+		log.Debug("Instance is not selected, leaving it untouched")
+		operations = append(operations, "skip")
+		return reconcile.Result{}, nil
+	}
+
+	// Nothing is applied or pruned while the agent is suspended.
+	suspended, msg, err := r.suspension.Suspended(ctx)
+	if err != nil {
+		log.Debug("Cannot check whether the agent is suspended", "error", err, "requeue-after", time.Now().Add(shortWait))
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errCheckSuspended)
+	}
+	if suspended {
+		log.Debug("Agent is suspended, leaving instance untouched", "reason", msg, "requeue-after", time.Now().Add(shortWait))
+		operations = append(operations, "skip")
+		return reconcile.Result{RequeueAfter: shortWait}, nil
+	}
+
+	// Instances that didn't change since they were last synced are not applied
+	// again.
+	unchanged := r.revisions != nil && r.revisions.Unchanged(remoteObject)
+	if !unchanged {
+		localObject := r.sanitizer.Sanitize(remoteObject)
+
+		// New instances are always created but updates to the existing ones are
+		// held while their rollout is paused, i.e. while claims that depend on
+		// them are failing more than usual.
+		paused, msg, err := r.gate.Paused(ctx)
+		if err != nil {
+			log.Debug("Cannot check whether rollout of updates is paused", "error", err, "requeue-after", time.Now().Add(shortWait))
+			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errCheckRollout)
+		}
+		if paused {
+			current := r.newObject()
+			err := r.local.Get(ctx, req.NamespacedName, current)
+			if runtimeresource.IgnoreNotFound(err) != nil {
+				log.Debug("Cannot get local instance", "error", err, "requeue-after", time.Now().Add(shortWait))
+				return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+fmt.Sprintf(errFmtGetInstance, r.crdName.Name))
+			}
+			if err == nil && rollout.Changed(current, localObject) {
+				log.Info("Holding update", "reason", msg)
+				operations = append(operations, "hold")
+				c, ok := current.(runtimeresource.Conditioned)
+				if !ok {
+					return reconcile.Result{RequeueAfter: longWait}, nil
+				}
+				c.SetConditions(resource.AgentSyncRolloutPaused(msg))
+				return reconcile.Result{RequeueAfter: longWait}, errors.Wrap(r.local.Status().Update(ctx, current), localPrefix+fmt.Sprintf(errFmtUpdateStatus, r.crdName.Name))
+			}
+		}
+
+		if err := r.local.Apply(ctx, localObject); err != nil {
+			// The local object was changed since it was read, so it's read again
+			// right away rather than reported as an error.
+			if resource.IsConflict(err) {
+				log.Debug("Local object changed since it was read", "error", err, "requeue-after", time.Now().Add(tinyWait))
+				return reconcile.Result{RequeueAfter: tinyWait}, nil
+			}
+			log.Debug("Cannot apply instance", "error", err, "requeue-after", time.Now().Add(shortWait))
+			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+fmt.Sprintf(errFmtApplyInstance, r.crdName.Name))
+		}
+		operations = append(operations, "apply")
+		// TODO(muvaf): We need to call status update to bring the status subresource
+		// of the resources.
+		if r.revisions != nil {
+			r.revisions.Synced(remoteObject)
+		}
 	}
-	// TODO(muvaf): We need to call status update to bring the status subresource
-	// of the resources.
 
 	// When an instance in the remote cluster is deleted, it's not guaranteed that
 	// we will get a deletion event for a number of reasons including agent not
 	// being up at that time. Since reconciliation is called only for the existing
 	// resources, we need to delete the resources in the local that do not have
-	// a corresponding resource in the remote cluster.
+	// a corresponding resource in the remote cluster. The ones that were
+	// authored in the local cluster are kept.
+	// The local instances are not listed again unless the remote ones changed
+	// since they were last pruned against them.
+	rl := r.newObjectList()
+	if err := r.remoteList.List(ctx, rl, r.listOptions...); err != nil {
+		log.Debug("Cannot list instances in remote", "error", err, "requeue-after", time.Now().Add(shortWait))
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, remotePrefix+fmt.Sprintf(errFmtListInstance, r.crdName.Name))
+	}
+	remoteItems := r.getItems(rl)
+	if r.revisions != nil && r.revisions.ListUnchanged(remoteItems) {
+		log.Debug("Remote instances are unchanged since they were last pruned against")
+		if r.metrics != nil {
+			r.metrics.Synced.WithLabelValues(metricsController, r.crdName.Name).Inc()
+		}
+		return reconcile.Result{RequeueAfter: r.syncInterval}, nil
+	}
 	removalList := map[string]bool{}
 	ll := r.newObjectList()
-	if err := r.local.List(ctx, ll); err != nil {
+	if err := r.local.List(ctx, ll, r.listOptions...); err != nil {
+		log.Debug("Cannot list local instances", "error", err, "requeue-after", time.Now().Add(shortWait))
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+fmt.Sprintf(errFmtListInstance, r.crdName.Name))
 	}
 	for _, obj := range r.getItems(ll) {
+		if resource.IsLocallyAuthored(obj) {
+			continue
+		}
 		removalList[obj.GetName()] = true
 	}
-	rl := r.newObjectList()
-	if err := r.remote.List(ctx, rl); err != nil {
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, remotePrefix+fmt.Sprintf(errFmtListInstance, r.crdName.Name))
-	}
-	for _, obj := range r.getItems(rl) {
+	for _, obj := range remoteItems {
 		delete(removalList, obj.GetName())
 	}
+	pruned := false
 	for remove := range removalList {
+		// The list above is served from a cache that may not be synced yet, so
+		// we confirm with the api-server that the instance is really gone
+		// before we delete it from the local cluster.
 		obj := r.newObject()
+		err := r.remoteLive.Get(ctx, types.NamespacedName{Name: remove}, obj)
+		if runtimeresource.IgnoreNotFound(err) != nil {
+			log.Debug("Cannot get instance from remote bypassing the cache", "name", remove, "error", err, "requeue-after", time.Now().Add(shortWait))
+			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, remotePrefix+fmt.Sprintf(errFmtGetInstance, r.crdName.Name))
+		}
+		if err == nil {
+			log.Debug("Instance still exists in the remote cluster, skipping its removal", "name", remove)
+			continue
+		}
+		obj = r.newObject()
 		obj.SetName(remove)
 		if err := r.local.Delete(ctx, obj); runtimeresource.IgnoreNotFound(err) != nil {
+			log.Debug("Cannot delete local instance", "name", remove, "error", err, "requeue-after", time.Now().Add(shortWait))
 			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+fmt.Sprintf(errFmtDeleteInstance, r.crdName.Name))
 		}
+		log.Debug("Removed instance that no longer exists in the remote cluster", "name", remove)
+		pruned = true
+		if r.metrics != nil {
+			r.metrics.Deleted.WithLabelValues(metricsController, r.crdName.Name).Inc()
+		}
+		if r.revisions != nil {
+			r.revisions.Forget(remove)
+		}
 	}
-	return reconcile.Result{RequeueAfter: longWait}, nil
+	if pruned {
+		operations = append(operations, "prune")
+	}
+	if r.revisions != nil {
+		r.revisions.Pruned(remoteItems)
+	}
+	if r.metrics != nil {
+		r.metrics.Synced.WithLabelValues(metricsController, r.crdName.Name).Inc()
+	}
+	return reconcile.Result{RequeueAfter: r.syncInterval}, nil
+}
+
+// selects returns true if the supplied instance matches the label selector of
+// the list options.
+func (r *Reconciler) selects(o runtimeresource.Object) bool {
+	lo := (&client.ListOptions{}).ApplyOptions(r.listOptions)
+	return lo.LabelSelector == nil || lo.LabelSelector.Matches(labels.Set(o.GetLabels()))
 }
@@ -20,12 +20,18 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -34,6 +40,9 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/resource"
 )
 
 var (
@@ -67,10 +76,13 @@ func Test_Reconcile(t *testing.T) {
 	type args struct {
 		m     manager.Manager
 		local runtimeresource.ClientApplicator
+		opts  []ReconcilerOption
 	}
 	type want struct {
-		result reconcile.Result
-		err    error
+		result  reconcile.Result
+		err     error
+		synced  float64
+		deleted float64
 	}
 
 	cases := map[string]struct {
@@ -153,12 +165,45 @@ func Test_Reconcile(t *testing.T) {
 				result: reconcile.Result{RequeueAfter: shortWait},
 			},
 		},
+		"ApplicatorReplaced": {
+			reason: "Instances should be applied with the Applicator that the Reconciler is configured with",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+					},
+				},
+				local: runtimeresource.ClientApplicator{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							if o, ok := obj.(*apiextensions.CustomResourceDefinition); ok {
+								established.DeepCopyInto(o)
+							}
+							return nil
+						},
+					},
+					Applicator: runtimeresource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...runtimeresource.ApplyOption) error {
+						return nil
+					}),
+				},
+				opts: []ReconcilerOption{
+					WithApplicator(runtimeresource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...runtimeresource.ApplyOption) error {
+						return errBoom
+					})),
+				},
+			},
+			want: want{
+				err:    errors.Wrap(errBoom, localPrefix+fmt.Sprintf(errFmtApplyInstance, compositionCRDName)),
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
 		"LocalListFailed": {
 			reason: "An error should be returned if local List fails",
 			args: args{
 				m: &fake.Manager{
 					Client: &test.MockClient{
-						MockGet: test.NewMockGetFn(nil),
+						MockGet:  test.NewMockGetFn(nil),
+						MockList: test.NewMockListFn(nil),
 					},
 				},
 				local: runtimeresource.ClientApplicator{
@@ -217,7 +262,12 @@ func Test_Reconcile(t *testing.T) {
 			args: args{
 				m: &fake.Manager{
 					Client: &test.MockClient{
-						MockGet:  test.NewMockGetFn(nil),
+						MockGet: func(_ context.Context, key client.ObjectKey, _ runtime.Object) error {
+							if key.Name == "gone" {
+								return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+							}
+							return nil
+						},
 						MockList: test.NewMockListFn(nil),
 					},
 				},
@@ -231,7 +281,7 @@ func Test_Reconcile(t *testing.T) {
 						},
 						MockUpdate: test.NewMockUpdateFn(nil),
 						MockList: func(_ context.Context, list runtime.Object, _ ...client.ListOption) error {
-							l := &v1alpha1.CompositionList{Items: []v1alpha1.Composition{{}}}
+							l := &v1alpha1.CompositionList{Items: []v1alpha1.Composition{{ObjectMeta: metav1.ObjectMeta{Name: "gone"}}}}
 							l.DeepCopyInto(list.(*v1alpha1.CompositionList))
 							return nil
 						},
@@ -252,7 +302,12 @@ func Test_Reconcile(t *testing.T) {
 			args: args{
 				m: &fake.Manager{
 					Client: &test.MockClient{
-						MockGet: test.NewMockGetFn(nil),
+						MockGet: func(_ context.Context, key client.ObjectKey, _ runtime.Object) error {
+							if key.Name == "two" {
+								return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+							}
+							return nil
+						},
 						MockList: func(_ context.Context, list runtime.Object, _ ...client.ListOption) error {
 							l := &v1alpha1.CompositionList{Items: []v1alpha1.Composition{
 								{
@@ -304,18 +359,174 @@ func Test_Reconcile(t *testing.T) {
 					}),
 				},
 			},
+			want: want{
+				result:  reconcile.Result{RequeueAfter: longWait},
+				synced:  1,
+				deleted: 1,
+			},
+		},
+		"NotSelected": {
+			reason: "Remote instances that don't match the selector should be left untouched",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+					},
+				},
+				local: runtimeresource.ClientApplicator{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							o := obj.(*apiextensions.CustomResourceDefinition)
+							established.DeepCopyInto(o)
+							return nil
+						},
+					},
+				},
+				opts: []ReconcilerOption{WithSelector(labels.SelectorFromSet(labels.Set{"environment": "staging"}))},
+			},
+			want: want{
+				result: reconcile.Result{},
+			},
+		},
+		"LocallyAuthoredKept": {
+			reason: "Local instances that were authored in the local cluster should not be deleted",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, key client.ObjectKey, _ runtime.Object) error {
+							if key.Name == "local" {
+								return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+							}
+							return nil
+						},
+						MockList: test.NewMockListFn(nil),
+					},
+				},
+				local: runtimeresource.ClientApplicator{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							if o, ok := obj.(*apiextensions.CustomResourceDefinition); ok {
+								established.DeepCopyInto(o)
+							}
+							return nil
+						},
+						MockList: func(_ context.Context, list runtime.Object, _ ...client.ListOption) error {
+							l := &v1alpha1.CompositionList{Items: []v1alpha1.Composition{{ObjectMeta: metav1.ObjectMeta{
+								Name:        "local",
+								Annotations: map[string]string{resource.AnnotationKeyLocallyAuthored: "true"},
+							}}}}
+							l.DeepCopyInto(list.(*v1alpha1.CompositionList))
+							return nil
+						},
+						MockDelete: func(_ context.Context, _ runtime.Object, _ ...client.DeleteOption) error {
+							t.Error("an instance that was authored in the local cluster is deleted")
+							return nil
+						},
+					},
+					Applicator: runtimeresource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...runtimeresource.ApplyOption) error {
+						return nil
+					}),
+				},
+			},
 			want: want{
 				result: reconcile.Result{RequeueAfter: longWait},
+				synced: 1,
+			},
+		},
+		"Unchanged": {
+			reason: "Instances that didn't change since they were last synced should neither be applied nor pruned against",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							obj.(*v1alpha1.Composition).SetResourceVersion("42")
+							return nil
+						},
+						MockList: test.NewMockListFn(nil),
+					},
+				},
+				local: runtimeresource.ClientApplicator{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							if o, ok := obj.(*apiextensions.CustomResourceDefinition); ok {
+								established.DeepCopyInto(o)
+							}
+							return nil
+						},
+						MockList: func(_ context.Context, _ runtime.Object, _ ...client.ListOption) error {
+							t.Error("local instances are listed although the remote ones didn't change")
+							return nil
+						},
+					},
+					Applicator: runtimeresource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...runtimeresource.ApplyOption) error {
+						t.Error("an instance that didn't change is applied")
+						return nil
+					}),
+				},
+				opts: []ReconcilerOption{WithRevisionTracker(func() *RevisionTracker {
+					rt := NewRevisionTracker(time.Hour)
+					c := &v1alpha1.Composition{}
+					c.SetResourceVersion("42")
+					rt.Synced(c)
+					rt.Pruned(nil)
+					return rt
+				}())},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+				synced: 1,
+			},
+		},
+		"StaleRemoteCache": {
+			reason: "Instances that are missing from the remote cache but exist in the remote cluster should not be deleted",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet:  test.NewMockGetFn(nil),
+						MockList: test.NewMockListFn(nil),
+					},
+				},
+				local: runtimeresource.ClientApplicator{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							if o, ok := obj.(*apiextensions.CustomResourceDefinition); ok {
+								established.DeepCopyInto(o)
+							}
+							return nil
+						},
+						MockList: func(_ context.Context, list runtime.Object, _ ...client.ListOption) error {
+							l := &v1alpha1.CompositionList{Items: []v1alpha1.Composition{{ObjectMeta: metav1.ObjectMeta{Name: "one"}}}}
+							l.DeepCopyInto(list.(*v1alpha1.CompositionList))
+							return nil
+						},
+						MockDelete: func(_ context.Context, _ runtime.Object, _ ...client.DeleteOption) error {
+							t.Error("an instance that exists in the remote cluster is deleted")
+							return nil
+						},
+					},
+					Applicator: runtimeresource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...runtimeresource.ApplyOption) error {
+						return nil
+					}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+				synced: 1,
 			},
 		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			r := NewReconciler(tc.args.m, tc.args.local,
+			m, err := metrics.NewSync(prometheus.NewRegistry())
+			if err != nil {
+				t.Fatalf("NewSync(...): %s", err)
+			}
+			r := NewReconciler(tc.args.m, tc.args.local, append([]ReconcilerOption{
 				WithGetItemsFn(gi),
 				WithNewInstanceFn(ni),
 				WithNewObjectListFn(nl),
-				WithCRDName(compositionCRDName))
+				WithCRDName(compositionCRDName),
+				WithSyncMetrics(m)}, tc.args.opts...)...)
 			got, err := r.Reconcile(reconcile.Request{})
 
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
@@ -325,6 +536,20 @@ func Test_Reconcile(t *testing.T) {
 			if diff := cmp.Diff(tc.want.result, got); diff != "" {
 				t.Errorf("\nReason: %s\nr.Reconcile(...): -want, +got:\n%s", tc.reason, diff)
 			}
+
+			wantMetrics := map[string]float64{"synced": tc.want.synced, "deleted": tc.want.deleted}
+			gotMetrics := map[string]float64{
+				"synced":  testutil.ToFloat64(m.Synced.WithLabelValues(metricsController, compositionCRDName)),
+				"deleted": testutil.ToFloat64(m.Deleted.WithLabelValues(metricsController, compositionCRDName)),
+			}
+			if tc.want.err != nil {
+				cluster := metrics.Cluster(tc.want.err.Error())
+				wantMetrics["failed in "+cluster] = 1
+				gotMetrics["failed in "+cluster] = testutil.ToFloat64(m.Failures.WithLabelValues(metricsController, compositionCRDName, cluster))
+			}
+			if diff := cmp.Diff(wantMetrics, gotMetrics); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want metrics, +got metrics:\n%s", tc.reason, diff)
+			}
 		})
 	}
 }
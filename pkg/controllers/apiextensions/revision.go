@@ -0,0 +1,132 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiextensions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// NewRevisionTracker returns a new *RevisionTracker. Everything it tracks is
+// forgotten after the supplied resync period so that changes made to the local
+// instances, which are not tracked, are eventually reverted.
+func NewRevisionTracker(resync time.Duration) *RevisionTracker {
+	return &RevisionTracker{
+		resync: resync,
+		now:    time.Now,
+		synced: map[string]revision{},
+	}
+}
+
+type revision struct {
+	version string
+	at      time.Time
+}
+
+// A RevisionTracker keeps track of the resource versions of the remote
+// instances of a kind as of their last sync, and of the list of them as of the
+// last time the local instances were pruned, so that only the instances that
+// changed since then are processed. On hubs with hundreds of definitions, a
+// reconcile of an unchanged instance would otherwise apply it and diff the
+// full lists of both clusters again.
+type RevisionTracker struct {
+	resync time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	synced map[string]revision
+	pruned revision
+}
+
+// Unchanged returns true if the supplied remote instance was synced at its
+// current resource version within the resync period.
+func (t *RevisionTracker) Unchanged(o runtimeresource.Object) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.synced[o.GetName()]
+	return ok && t.fresh(r, o.GetResourceVersion())
+}
+
+// Synced records that the supplied remote instance was synced at its current
+// resource version.
+func (t *RevisionTracker) Synced(o runtimeresource.Object) {
+	if o.GetResourceVersion() == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.synced[o.GetName()] = revision{version: o.GetResourceVersion(), at: t.now()}
+}
+
+// Forget the instance with the supplied name.
+func (t *RevisionTracker) Forget(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.synced, name)
+}
+
+// ListUnchanged returns true if the local instances were pruned against the
+// supplied list of remote instances within the resync period.
+func (t *RevisionTracker) ListUnchanged(list []runtimeresource.Object) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fresh(t.pruned, ListRevision(list))
+}
+
+// Pruned records that the local instances were pruned against the supplied
+// list of remote instances.
+func (t *RevisionTracker) Pruned(list []runtimeresource.Object) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruned = revision{version: ListRevision(list), at: t.now()}
+}
+
+// Reset forgets everything so that all instances are processed again, e.g.
+// after an outage of the remote cluster during which events may have been
+// missed.
+func (t *RevisionTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.synced = map[string]revision{}
+	t.pruned = revision{}
+}
+
+func (t *RevisionTracker) fresh(r revision, version string) bool {
+	return version != "" && r.version == version && t.now().Sub(r.at) < t.resync
+}
+
+// ListRevision returns the revision of the supplied list of instances, which
+// changes whenever any of them is added, removed or changed. Lists that are
+// served from a cache don't carry the resource version of the list, so it's
+// derived from the ones of the instances instead.
+func ListRevision(list []runtimeresource.Object) string {
+	versions := make([]string, len(list))
+	for i, o := range list {
+		versions[i] = o.GetName() + "=" + o.GetResourceVersion()
+	}
+	sort.Strings(versions)
+	h := sha256.New()
+	for _, v := range versions {
+		h.Write([]byte(v + "\n")) // nolint:errcheck
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiextensions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+func TestRevisionTracker(t *testing.T) {
+	composition := func(name, rv string) *v1alpha1.Composition {
+		c := &v1alpha1.Composition{}
+		c.SetName(name)
+		c.SetResourceVersion(rv)
+		return c
+	}
+	now := time.Now()
+	synced := []runtimeresource.Object{composition("a", "1"), composition("b", "2")}
+
+	cases := map[string]struct {
+		reason   string
+		o        runtimeresource.Object
+		list     []runtimeresource.Object
+		elapsed  time.Duration
+		instance bool
+		listed   bool
+	}{
+		"Unchanged": {
+			reason:   "Instances and lists whose resource versions didn't change should be unchanged",
+			o:        composition("a", "1"),
+			list:     []runtimeresource.Object{composition("b", "2"), composition("a", "1")},
+			instance: true,
+			listed:   true,
+		},
+		"Changed": {
+			reason: "Instances and lists whose resource versions changed should be changed",
+			o:      composition("a", "3"),
+			list:   []runtimeresource.Object{composition("a", "3"), composition("b", "2")},
+		},
+		"Removed": {
+			reason:   "Lists that an instance was removed from should be changed",
+			o:        composition("a", "1"),
+			list:     []runtimeresource.Object{composition("a", "1")},
+			instance: true,
+		},
+		"ResyncDue": {
+			reason:  "Nothing should be unchanged once the resync period passed",
+			o:       composition("a", "1"),
+			list:    synced,
+			elapsed: 2 * time.Hour,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rt := NewRevisionTracker(time.Hour)
+			rt.now = func() time.Time { return now }
+			for _, o := range synced {
+				rt.Synced(o)
+			}
+			rt.Pruned(synced)
+			rt.now = func() time.Time { return now.Add(tc.elapsed) }
+
+			if diff := cmp.Diff(tc.instance, rt.Unchanged(tc.o)); diff != "" {
+				t.Errorf("\nReason: %s\nrt.Unchanged(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.listed, rt.ListUnchanged(tc.list)); diff != "" {
+				t.Errorf("\nReason: %s\nrt.ListUnchanged(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
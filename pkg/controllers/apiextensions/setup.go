@@ -17,26 +17,51 @@ limitations under the License.
 package apiextensions
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	kcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/remote"
 )
 
 const (
 	maxConcurrency = 5
 
+	// remoteListTTL is how long the list of remote instances is reused by
+	// every reconcile of the same kind. Instances that are missing from a stale
+	// list are looked up before they're removed, so it only delays removals.
+	remoteListTTL = 30 * time.Second
+
+	// revisionResync is how long instances that didn't change in the remote
+	// cluster are left alone before they're applied, and the local instances
+	// pruned, again.
+	revisionResync = 10 * time.Minute
+
 	xrdCRDName         = "compositeresourcedefinitions.apiextensions.crossplane.io"
 	compositionCRDName = "compositions.apiextensions.crossplane.io"
 )
 
+// controllerOptions returns the options of the controller of the supplied
+// Reconciler, which retries the instances that failed with its backoff.
+func controllerOptions(r *Reconciler) kcontroller.Options {
+	o := kcontroller.Options{MaxConcurrentReconciles: maxConcurrency}
+	if r.backoff != nil {
+		o.RateLimiter = r.backoff
+	}
+	return o
+}
+
 // SetupXRDSync adds a controller that syncs CompositeResourceDefinitions from
 // remote cluster to local cluster.
-func SetupXRDSync(mgr ctrl.Manager, localClient client.Client, log logging.Logger) error {
+func SetupXRDSync(mgr ctrl.Manager, localClient client.Client, log logging.Logger, opts ...ReconcilerOption) error {
 	name := "CompositeResourceDefinitions"
 
 	nl := func() runtime.Object { return &v1alpha1.CompositeResourceDefinitionList{} }
@@ -57,22 +82,33 @@ func SetupXRDSync(mgr ctrl.Manager, localClient client.Client, log logging.Logge
 
 	r := NewReconciler(mgr,
 		ca,
-		WithLogger(log.WithValues("controller", name)),
-		WithCRDName(xrdCRDName),
-		WithNewInstanceFn(ni),
-		WithNewObjectListFn(nl),
-		WithGetItemsFn(gi))
-
-	return ctrl.NewControllerManagedBy(mgr).
+		append([]ReconcilerOption{
+			WithLogger(log.WithValues("controller", name)),
+			WithCRDName(xrdCRDName),
+			WithNewInstanceFn(ni),
+			WithNewObjectListFn(nl),
+			WithGetItemsFn(gi),
+			WithRemoteAPIReader(mgr.GetAPIReader()),
+			WithRemoteListReader(remote.NewTTLListCache(mgr.GetClient(), remoteListTTL)),
+			WithRevisionTracker(NewRevisionTracker(revisionResync)),
+		}, opts...)...)
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		For(&v1alpha1.CompositeResourceDefinition{}).
-		WithOptions(kcontroller.Options{MaxConcurrentReconciles: maxConcurrency}).
-		Complete(r)
+		WithOptions(controllerOptions(r))
+	if r.monitor != nil {
+		b = b.Watches(remote.NewResyncSource(r.monitor, mgr.GetClient(), nl, r.log), &handler.EnqueueRequestForObject{})
+		if r.revisions != nil {
+			r.monitor.OnReconnect(r.revisions.Reset)
+		}
+	}
+	return b.Complete(r)
 }
 
 // SetupCompositionSync adds a controller that syncs Compositions from
 // remote cluster to local cluster.
-func SetupCompositionSync(mgr ctrl.Manager, localClient client.Client, log logging.Logger) error {
+func SetupCompositionSync(mgr ctrl.Manager, localClient client.Client, log logging.Logger, opts ...ReconcilerOption) error {
 	name := "Compositions"
 
 	nl := func() runtime.Object { return &v1alpha1.CompositionList{} }
@@ -93,15 +129,26 @@ func SetupCompositionSync(mgr ctrl.Manager, localClient client.Client, log loggi
 
 	r := NewReconciler(mgr,
 		ca,
-		WithLogger(log.WithValues("controller", name)),
-		WithCRDName(compositionCRDName),
-		WithNewInstanceFn(ni),
-		WithNewObjectListFn(nl),
-		WithGetItemsFn(gi))
-
-	return ctrl.NewControllerManagedBy(mgr).
+		append([]ReconcilerOption{
+			WithLogger(log.WithValues("controller", name)),
+			WithCRDName(compositionCRDName),
+			WithNewInstanceFn(ni),
+			WithNewObjectListFn(nl),
+			WithGetItemsFn(gi),
+			WithRemoteAPIReader(mgr.GetAPIReader()),
+			WithRemoteListReader(remote.NewTTLListCache(mgr.GetClient(), remoteListTTL)),
+			WithRevisionTracker(NewRevisionTracker(revisionResync)),
+		}, opts...)...)
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		For(&v1alpha1.Composition{}).
-		WithOptions(kcontroller.Options{MaxConcurrentReconciles: maxConcurrency}).
-		Complete(r)
+		WithOptions(controllerOptions(r))
+	if r.monitor != nil {
+		b = b.Watches(remote.NewResyncSource(r.monitor, mgr.GetClient(), nl, r.log), &handler.EnqueueRequestForObject{})
+		if r.revisions != nil {
+			r.monitor.OnReconnect(r.revisions.Reset)
+		}
+	}
+	return b.Complete(r)
 }
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errMarshalReview   = "cannot marshal admission review"
+	errNewReview       = "cannot create admission review request"
+	errSendReview      = "cannot send admission review"
+	errDecodeReview    = "cannot decode admission review response"
+	errFmtReviewStatus = "admission webhook responded with status %d"
+)
+
+// An Admission is the verdict of an AdmissionGate on a claim.
+type Admission struct {
+	Allowed bool   `json:"allowed"`
+	Message string `json:"message,omitempty"`
+}
+
+// An AdmissionGate decides whether the remote claim may be pushed to the
+// remote cluster, so that platform teams can enforce their policies, e.g. size
+// limits or the compositions claims may refer to, before it gets there.
+type AdmissionGate interface {
+	Admit(ctx context.Context, remote *claim.Unstructured) (Admission, error)
+}
+
+// AdmitFn is used to construct an AdmissionGate with a bare function.
+type AdmitFn func(ctx context.Context, remote *claim.Unstructured) (Admission, error)
+
+// Admit calls the supplied function.
+func (fn AdmitFn) Admit(ctx context.Context, remote *claim.Unstructured) (Admission, error) {
+	return fn(ctx, remote)
+}
+
+// NewNopAdmissionGate returns an AdmissionGate that admits everything.
+func NewNopAdmissionGate() AdmitFn {
+	return func(_ context.Context, _ *claim.Unstructured) (Admission, error) {
+		return Admission{Allowed: true}, nil
+	}
+}
+
+// An AdmissionReview is what a WebhookAdmissionGate posts to its webhook.
+type AdmissionReview struct {
+	// Object is the remote claim as it'd be pushed, without the metadata
+	// that's specific to a cluster.
+	Object interface{} `json:"object"`
+}
+
+// NewWebhookAdmissionGate returns a new *WebhookAdmissionGate that posts
+// claims to the supplied URL.
+func NewWebhookAdmissionGate(url string, timeout time.Duration) *WebhookAdmissionGate {
+	return &WebhookAdmissionGate{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// A WebhookAdmissionGate posts an AdmissionReview of the claim to a webhook,
+// which responds with an Admission. A webhook that cannot be reached or
+// doesn't respond with an Admission returns an error, so that nothing is
+// pushed without its verdict.
+type WebhookAdmissionGate struct {
+	url    string
+	client *http.Client
+}
+
+// Admit returns the verdict of the webhook on the supplied claim.
+func (g *WebhookAdmissionGate) Admit(ctx context.Context, remote *claim.Unstructured) (Admission, error) {
+	a := Admission{}
+	body, err := json.Marshal(AdmissionReview{Object: resource.SanitizedDeepCopyObject(remote.GetUnstructured())})
+	if err != nil {
+		return a, errors.Wrap(err, errMarshalReview)
+	}
+	req, err := http.NewRequest(http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return a, errors.Wrap(err, errNewReview)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := g.client.Do(req)
+	if err != nil {
+		return a, errors.Wrap(err, errSendReview)
+	}
+	defer rsp.Body.Close() // nolint:errcheck
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return a, errors.Errorf(errFmtReviewStatus, rsp.StatusCode)
+	}
+	return a, errors.Wrap(json.NewDecoder(rsp.Body).Decode(&a), errDecodeReview)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestWebhookAdmissionGate(t *testing.T) {
+	type want struct {
+		admission Admission
+		err       error
+	}
+	cases := map[string]struct {
+		reason  string
+		handler http.HandlerFunc
+		want    want
+	}{
+		"Allowed": {
+			reason: "The verdict of the webhook should be returned",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				review := &AdmissionReview{}
+				if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+					t.Errorf("cannot decode admission review: %s", err)
+				}
+				obj, _ := review.Object.(map[string]interface{})
+				meta, _ := obj["metadata"].(map[string]interface{})
+				_ = json.NewEncoder(w).Encode(Admission{Allowed: meta["uid"] == nil})
+			},
+			want: want{admission: Admission{Allowed: true}},
+		},
+		"Rejected": {
+			reason: "The message of a rejection should be returned",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_ = json.NewEncoder(w).Encode(Admission{Message: "size is limited to small"})
+			},
+			want: want{admission: Admission{Message: "size is limited to small"}},
+		},
+		"Failed": {
+			reason: "A webhook that fails should return an error rather than a verdict",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			want: want{err: errors.Errorf(errFmtReviewStatus, http.StatusInternalServerError)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+			c := claim.New()
+			c.SetName("cool")
+			c.SetUID("cool-uid")
+			got, err := NewWebhookAdmissionGate(srv.URL, time.Second).Admit(context.Background(), c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nAdmit(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.admission, got); diff != "" {
+				t.Errorf("\nReason: %s\nAdmit(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
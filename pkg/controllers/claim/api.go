@@ -22,6 +22,8 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/apimachinery/pkg/util/json"
@@ -44,6 +46,13 @@ func (p PropagateFn) Propagate(ctx context.Context, local, remote *claim.Unstruc
 	return p(ctx, local, remote)
 }
 
+// ObjectSize returns the size of the supplied claim in bytes once it's
+// serialized to JSON, which is how it's sent to the api-server.
+func ObjectSize(c *claim.Unstructured) (int, error) {
+	b, err := json.Marshal(c.GetUnstructured())
+	return len(b), err
+}
+
 // NewPropagatorChain returns a new PropagatorChain.
 func NewPropagatorChain(p ...Propagator) PropagatorChain {
 	return PropagatorChain(p)
@@ -52,7 +61,8 @@ func NewPropagatorChain(p ...Propagator) PropagatorChain {
 // PropagatorChain calls Propagate method of all of its Propagators in order.
 type PropagatorChain []Propagator
 
-// Propagate calls all Propagate functions one by one.
+// Propagate calls all Propagate functions one by one, stopping at the first
+// one that fails.
 func (pp PropagatorChain) Propagate(ctx context.Context, local, remote *claim.Unstructured) error {
 	for _, p := range pp {
 		if err := p.Propagate(ctx, local, remote); err != nil {
@@ -62,6 +72,67 @@ func (pp PropagatorChain) Propagate(ctx context.Context, local, remote *claim.Un
 	return nil
 }
 
+// NewNamedPropagator returns a new NamedPropagator.
+func NewNamedPropagator(name string, p Propagator) NamedPropagator {
+	return NamedPropagator{Name: name, Propagator: p}
+}
+
+// A NamedPropagator is a step of a PropagatorChain whose errors are wrapped
+// with its name, so that the step that failed can be told.
+type NamedPropagator struct {
+	Name       string
+	Propagator Propagator
+}
+
+// Propagate calls the Propagator of the step.
+func (p NamedPropagator) Propagate(ctx context.Context, local, remote *claim.Unstructured) error {
+	return errors.Wrapf(p.Propagator.Propagate(ctx, local, remote), errFmtPropagatorStep, p.Name)
+}
+
+// SyncObserverFn is used to construct a SyncObserver with a bare function.
+type SyncObserverFn func(ctx context.Context, c *claim.Unstructured)
+
+// ObserveSync calls the supplied function.
+func (fn SyncObserverFn) ObserveSync(ctx context.Context, c *claim.Unstructured) {
+	fn(ctx, c)
+}
+
+// SyncObserverChain tells all of its SyncObservers about the claim in order.
+type SyncObserverChain []SyncObserver
+
+// ObserveSync calls ObserveSync of all SyncObservers one by one.
+func (oo SyncObserverChain) ObserveSync(ctx context.Context, c *claim.Unstructured) {
+	for _, o := range oo {
+		o.ObserveSync(ctx, c)
+	}
+}
+
+// ConfiguratorFn is used to construct a Configurator with a bare function.
+type ConfiguratorFn func(ctx context.Context, local, remote *claim.Unstructured) error
+
+// Configure calls the supplied function.
+func (fn ConfiguratorFn) Configure(ctx context.Context, local, remote *claim.Unstructured) error {
+	return fn(ctx, local, remote)
+}
+
+// NewConfiguratorChain returns a new ConfiguratorChain.
+func NewConfiguratorChain(c ...Configurator) ConfiguratorChain {
+	return ConfiguratorChain(c)
+}
+
+// ConfiguratorChain calls Configure method of all of its Configurators in order.
+type ConfiguratorChain []Configurator
+
+// Configure calls all Configure functions one by one.
+func (cc ConfiguratorChain) Configure(ctx context.Context, local, remote *claim.Unstructured) error {
+	for _, c := range cc {
+		if err := c.Configure(ctx, local, remote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // NewDefaultConfigurator returns a new DefaultConfigurator.
 func NewDefaultConfigurator() *DefaultConfigurator {
 	return &DefaultConfigurator{}
@@ -72,10 +143,22 @@ func NewDefaultConfigurator() *DefaultConfigurator {
 type DefaultConfigurator struct{}
 
 // Configure copies spec and user-defined metadata from local object to the remote one.
+// The bookkeeping annotations that the remote object already has are kept, since
+// they may belong to another agent that syncs it on to yet another cluster.
 func (sp *DefaultConfigurator) Configure(_ context.Context, local, remote *claim.Unstructured) error {
 	remote.SetName(local.GetName())
 	remote.SetNamespace(local.GetNamespace())
-	remote.SetAnnotations(local.GetAnnotations())
+	a := resource.WithoutAgentAnnotations(local.GetAnnotations())
+	for k, v := range remote.GetAnnotations() {
+		if !resource.IsAgentAnnotation(k) {
+			continue
+		}
+		if a == nil {
+			a = map[string]string{}
+		}
+		a[k] = v
+	}
+	remote.SetAnnotations(a)
 	remote.SetLabels(local.GetLabels())
 	spec, err := fieldpath.Pave(local.GetUnstructured().UnstructuredContent()).GetValue("spec")
 	if err != nil {
@@ -116,20 +199,119 @@ func (li *LateInitializer) Propagate(ctx context.Context, local, remote *claim.U
 	return errors.Wrap(li.localClient.Update(ctx, local), localPrefix+errUpdateClaim)
 }
 
+// A StatusPolicy decides which status fields of remote claims are propagated
+// to local claims.
+type StatusPolicy string
+
+// Status policies.
+const (
+	// StatusPolicyConditions propagates the conditions only.
+	StatusPolicyConditions StatusPolicy = "Conditions"
+
+	// StatusPolicyConnectionDetails propagates the conditions and the
+	// metadata of the connection details, e.g. when they were last
+	// published.
+	StatusPolicyConnectionDetails StatusPolicy = "ConnectionDetails"
+
+	// StatusPolicyFull propagates the whole status.
+	StatusPolicyFull StatusPolicy = "Full"
+)
+
+// A StatusPolicyTable is the StatusPolicy of every kind of claim whose status
+// isn't propagated in full.
+type StatusPolicyTable map[schema.GroupKind]StatusPolicy
+
+// Lookup returns the StatusPolicy of the supplied kind of claim.
+func (t StatusPolicyTable) Lookup(gk schema.GroupKind) StatusPolicy {
+	if p, ok := t[gk]; ok {
+		return p
+	}
+	return StatusPolicyFull
+}
+
+// A ConditionMapping maps the types of the conditions of remote claims to the
+// types they're propagated to local claims with, so that hubs with their own
+// status taxonomy can surface it under the types spokes look at. Conditions
+// whose type is mapped to an empty type are not propagated, and the ones whose
+// type isn't mapped are propagated as they are.
+type ConditionMapping map[v1alpha1.ConditionType]v1alpha1.ConditionType
+
+// DefaultConditionMapping propagates the Ready and Synced conditions of
+// Crossplane as they are.
+var DefaultConditionMapping = ConditionMapping{
+	v1alpha1.TypeReady:  v1alpha1.TypeReady,
+	v1alpha1.TypeSynced: v1alpha1.TypeSynced,
+}
+
+// Map returns the supplied conditions with their types mapped. Conditions that
+// are mapped to another type come last, so that they win over the ones that
+// kept the same type if both end up with it.
+func (m ConditionMapping) Map(in []v1alpha1.Condition) []v1alpha1.Condition {
+	out := make([]v1alpha1.Condition, 0, len(in))
+	var renamed []v1alpha1.Condition
+	for _, c := range in {
+		t, ok := m[c.Type]
+		switch {
+		case !ok || t == c.Type:
+			out = append(out, c)
+		case t != "":
+			c.Type = t
+			renamed = append(renamed, c)
+		}
+	}
+	return append(out, renamed...)
+}
+
+// A StatusPropagatorOption configures a StatusPropagator.
+type StatusPropagatorOption func(*StatusPropagator)
+
+// WithStatusPolicy specifies which status fields are propagated. The whole
+// status is propagated by default.
+func WithStatusPolicy(p StatusPolicy) StatusPropagatorOption {
+	return func(sp *StatusPropagator) {
+		sp.policy = p
+	}
+}
+
+// WithMappedConditions specifies the types the conditions of remote claims
+// are propagated with. The DefaultConditionMapping is used by default.
+func WithMappedConditions(m ConditionMapping) StatusPropagatorOption {
+	return func(sp *StatusPropagator) {
+		sp.conditions = m
+	}
+}
+
 // NewStatusPropagator returns a new StatusPropagator.
-func NewStatusPropagator() *StatusPropagator {
-	return &StatusPropagator{}
+func NewStatusPropagator(o ...StatusPropagatorOption) *StatusPropagator {
+	sp := &StatusPropagator{policy: StatusPolicyFull, conditions: DefaultConditionMapping}
+	for _, fn := range o {
+		fn(sp)
+	}
+	return sp
 }
 
 // StatusPropagator propagates the status from the second object to the first one.
-type StatusPropagator struct{}
+// The references of the remote claim are propagated by the LateInitializer.
+type StatusPropagator struct {
+	policy     StatusPolicy
+	conditions ConditionMapping
+}
 
-// Propagate copies the status of remote object into local object.
+// Propagate copies the status of remote object into local object, so that
+// local users can see how the claim is provisioned without access to the
+// remote cluster. Conditions are merged so that the ones the agent sets on
+// the local object are kept, and the status the agent records on the local
+// object under status.agent is left alone. Fields that the StatusPolicy
+// withholds are removed from the local object, and the types of the
+// conditions are mapped according to the ConditionMapping.
 func (sp *StatusPropagator) Propagate(ctx context.Context, local, remote *claim.Unstructured) error {
 	status, err := fieldpath.Pave(remote.GetUnstructured().UnstructuredContent()).GetValue("status")
 	if err != nil {
 		return runtimeresource.Ignore(fieldpath.IsNotFound, err)
 	}
+	if err := mergeStatus(local, remote, sp.allowed); err != nil {
+		return err
+	}
 	statusJSON, err := json.Marshal(status)
 	if err != nil {
 		return err
@@ -138,14 +320,73 @@ func (sp *StatusPropagator) Propagate(ctx context.Context, local, remote *claim.
 	if err := json.Unmarshal(statusJSON, conditions); err != nil {
 		return err
 	}
-	local.SetConditions(conditions.Conditions...)
-	// TODO(muvaf): Need to propagate other fields as well.
+	local.SetConditions(sp.conditions.Map(conditions.Conditions)...)
 	return nil
 }
 
+func (sp *StatusPropagator) allowed(field string) bool {
+	switch sp.policy {
+	case StatusPolicyConditions:
+		return false
+	case StatusPolicyConnectionDetails:
+		return field == "connectionDetails"
+	default:
+		return true
+	}
+}
+
+// mergeStatus replaces the allowed status fields of local with the ones of
+// remote, other than its conditions and the status of the agent. The fields
+// that aren't allowed are removed.
+func mergeStatus(local, remote *claim.Unstructured, allowed func(field string) bool) error {
+	rs, _, err := kunstructured.NestedMap(remote.GetUnstructured().Object, "status")
+	if err != nil {
+		return err
+	}
+	ls, _, err := kunstructured.NestedMap(local.GetUnstructured().Object, "status")
+	if err != nil {
+		return err
+	}
+	if ls == nil {
+		ls = map[string]interface{}{}
+	}
+	owned := func(k string) bool { return k == "conditions" || k == "agent" }
+	for k := range ls {
+		if _, ok := rs[k]; (!ok || !allowed(k)) && !owned(k) {
+			delete(ls, k)
+		}
+	}
+	for k, v := range rs {
+		if allowed(k) && !owned(k) {
+			ls[k] = v
+		}
+	}
+	return kunstructured.SetNestedMap(local.GetUnstructured().Object, ls, "status")
+}
+
+// A SecretEnvelope encrypts the data of Secrets before they're propagated to
+// another cluster and decrypts it on the consuming side.
+type SecretEnvelope interface {
+	// Seal encrypts the data of the supplied Secret. The current Secret is
+	// the one that was last sealed for it, or nil if there is none.
+	Seal(ctx context.Context, s, current *v1.Secret) error
+	Open(ctx context.Context, s *v1.Secret) error
+}
+
+// NewNopSecretEnvelope returns a SecretEnvelope that leaves Secrets as they
+// are.
+func NewNopSecretEnvelope() SecretEnvelope {
+	return nopSecretEnvelope{}
+}
+
+type nopSecretEnvelope struct{}
+
+func (nopSecretEnvelope) Seal(_ context.Context, _, _ *v1.Secret) error { return nil }
+func (nopSecretEnvelope) Open(_ context.Context, _ *v1.Secret) error    { return nil }
+
 // NewConnectionSecretPropagator returns a new *ConnectionSecretPropagator.
 func NewConnectionSecretPropagator(local, remote runtimeresource.ClientApplicator) *ConnectionSecretPropagator {
-	return &ConnectionSecretPropagator{localClient: local, remoteClient: remote}
+	return &ConnectionSecretPropagator{localClient: local, remoteClient: remote, envelope: NewNopSecretEnvelope()}
 }
 
 // ConnectionSecretPropagator fetches the connection secret from the remote cluster
@@ -153,6 +394,7 @@ func NewConnectionSecretPropagator(local, remote runtimeresource.ClientApplicato
 type ConnectionSecretPropagator struct {
 	localClient  runtimeresource.ClientApplicator
 	remoteClient runtimeresource.ClientApplicator
+	envelope     SecretEnvelope
 }
 
 // Propagate propagates the connection secret from remote cluster to local cluster.
@@ -174,6 +416,9 @@ func (csp *ConnectionSecretPropagator) Propagate(ctx context.Context, local, rem
 		// TODO(muvaf): Set condition to say waiting for secret.
 		return nil
 	}
+	if err := csp.envelope.Open(ctx, rs); err != nil {
+		return errors.Wrap(err, errOpenSecret)
+	}
 	ls := resource.SanitizedDeepCopyObject(rs)
 	ls.SetName(local.GetWriteConnectionSecretToReference().Name)
 	ls.SetNamespace(local.GetNamespace())
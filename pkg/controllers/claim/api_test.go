@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -158,14 +159,20 @@ func TestLateInitializer(t *testing.T) {
 
 func TestStatusPropagator(t *testing.T) {
 	type args struct {
+		policy StatusPolicy
 		local  *claim.Unstructured
 		remote *claim.Unstructured
 	}
 	type want struct {
-		err error
+		err    error
+		status interface{}
 	}
 	remoteWithStatus := &claim.Unstructured{Unstructured: *remoteClaim.DeepCopy()}
 	remoteWithStatus.SetConditions(v1alpha1.Available())
+	remoteWithFields := &claim.Unstructured{Unstructured: *remoteWithStatus.DeepCopy()}
+	remoteWithFields.Object["status"].(map[string]interface{})["atProvider"] = map[string]interface{}{"endpoint": "cool-endpoint"}
+	localWithStale := &claim.Unstructured{Unstructured: *localClaim.DeepCopy()}
+	localWithStale.Object["status"] = map[string]interface{}{"stale": "field"}
 	cases := map[string]struct {
 		reason string
 		args
@@ -178,23 +185,135 @@ func TestStatusPropagator(t *testing.T) {
 				remote: remoteWithStatus,
 			},
 		},
+		"StatusFields": {
+			reason: "Status fields other than conditions should be pulled, and the ones the remote claim no longer has removed",
+			args: args{
+				local:  localWithStale,
+				remote: remoteWithFields,
+			},
+		},
+		"Withheld": {
+			reason: "Only the conditions should be pulled, and the other status fields removed, if the policy withholds them",
+			args: args{
+				policy: StatusPolicyConditions,
+				local:  &claim.Unstructured{Unstructured: *localWithStale.DeepCopy()},
+				remote: remoteWithFields,
+			},
+			want: want{status: remoteWithStatus.Object["status"]},
+		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			p := NewStatusPropagator()
+			var o []StatusPropagatorOption
+			if tc.args.policy != "" {
+				o = append(o, WithStatusPolicy(tc.args.policy))
+			}
+			p := NewStatusPropagator(o...)
 			err := p.Propagate(context.Background(), tc.args.local, tc.args.remote)
 
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\nReason: %s\np.Propagate(...): -want error, +got error:\n%s", tc.reason, diff)
 			}
 
-			if diff := cmp.Diff(tc.args.local.Object["status"], tc.args.remote.Object["status"]); diff != "" {
+			want := tc.want.status
+			if want == nil {
+				want = tc.args.remote.Object["status"]
+			}
+			if diff := cmp.Diff(want, tc.args.local.Object["status"]); diff != "" {
 				t.Errorf("\nReason: %s\np.Propagate(...): -want, +got:\n%s", tc.reason, diff)
 			}
 		})
 	}
 }
 
+func TestConditionMapping(t *testing.T) {
+	provisioned := v1alpha1.Condition{Type: "Provisioned", Status: corev1.ConditionTrue, Reason: "Provisioned"}
+	cases := map[string]struct {
+		reason string
+		m      ConditionMapping
+		in     []v1alpha1.Condition
+		want   []v1alpha1.Condition
+	}{
+		"Default": {
+			reason: "Conditions should be propagated as they are by default",
+			m:      DefaultConditionMapping,
+			in:     []v1alpha1.Condition{v1alpha1.Available(), v1alpha1.ReconcileSuccess(), provisioned},
+			want:   []v1alpha1.Condition{v1alpha1.Available(), v1alpha1.ReconcileSuccess(), provisioned},
+		},
+		"Renamed": {
+			reason: "Conditions should be propagated with the types they're mapped to, winning over the ones that kept theirs",
+			m:      ConditionMapping{"Provisioned": v1alpha1.TypeReady},
+			in:     []v1alpha1.Condition{provisioned, v1alpha1.Creating()},
+			want: []v1alpha1.Condition{v1alpha1.Creating(), {
+				Type:   v1alpha1.TypeReady,
+				Status: corev1.ConditionTrue,
+				Reason: "Provisioned",
+			}},
+		},
+		"Dropped": {
+			reason: "Conditions whose type is mapped to an empty type should not be propagated",
+			m:      ConditionMapping{v1alpha1.TypeSynced: ""},
+			in:     []v1alpha1.Condition{v1alpha1.Available(), v1alpha1.ReconcileSuccess()},
+			want:   []v1alpha1.Condition{v1alpha1.Available()},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.m.Map(tc.in)
+			if diff := cmp.Diff(tc.want, got, test.EquateConditions()); diff != "" {
+				t.Errorf("\nReason: %s\nm.Map(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPropagatorChain(t *testing.T) {
+	errBoom := errors.New("boom")
+	step := func(name string, err error) Propagator {
+		return NewNamedPropagator(name, PropagateFn(func(_ context.Context, local, _ *claim.Unstructured) error {
+			local.SetAnnotations(map[string]string{"steps": local.GetAnnotations()["steps"] + name})
+			return err
+		}))
+	}
+	type want struct {
+		err   error
+		steps string
+	}
+	cases := map[string]struct {
+		reason string
+		chain  PropagatorChain
+		want   want
+	}{
+		"InOrder": {
+			reason: "All steps should be run in order",
+			chain:  NewPropagatorChain(step("a", nil), step("b", nil), step("c", nil)),
+			want:   want{steps: "abc"},
+		},
+		"StepFailed": {
+			reason: "The error of a failed step should be wrapped with its name and no further steps should be run",
+			chain:  NewPropagatorChain(step("a", nil), step("b", errBoom), step("c", nil)),
+			want:   want{err: errors.Wrapf(errBoom, errFmtPropagatorStep, "b"), steps: "ab"},
+		},
+		"Nested": {
+			reason: "Chains should be composable",
+			chain:  NewPropagatorChain(NewPropagatorChain(step("a", nil), step("b", nil)), step("c", nil)),
+			want:   want{steps: "abc"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			err := tc.chain.Propagate(context.Background(), local, claim.New())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nPropagate(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.steps, local.GetAnnotations()["steps"]); diff != "" {
+				t.Errorf("\nReason: %s\nPropagate(...): -want steps, +got steps:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestConnectionSecretPropagator(t *testing.T) {
 	type args struct {
 		local        *claim.Unstructured
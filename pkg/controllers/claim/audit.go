@@ -0,0 +1,132 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/version"
+)
+
+// CorrelationID returns the ID that ties the writes of the supplied generation
+// of a local claim in the remote cluster to the logs of the agent. It only
+// changes with the generation so that syncs that don't change anything don't
+// cause writes either.
+func CorrelationID(local *claim.Unstructured) string {
+	return fmt.Sprintf("%s.%d", local.GetUID(), local.GetGeneration())
+}
+
+// SetAuditAnnotations marks the supplied remote claim with the cluster and the
+// local claim it's written from, the version of the agent and the correlation
+// ID of the write, so that the writes in the audit log of the remote cluster
+// can be traced back. The cluster is omitted if it's empty.
+func SetAuditAnnotations(remote, local *claim.Unstructured, cluster string) {
+	a := map[string]string{
+		resource.AnnotationKeySourceObject:  fmt.Sprintf("%s/%s", local.GetNamespace(), local.GetName()),
+		resource.AnnotationKeyCorrelationID: CorrelationID(local),
+		resource.AnnotationKeyAgentVersion:  version.Version,
+	}
+	if cluster != "" {
+		a[resource.AnnotationKeySourceCluster] = cluster
+		chain := append(OriginChain(local), cluster)
+		a[resource.AnnotationKeyOriginChain] = strings.Join(chain, ",")
+		if _, ok := remote.GetLabels()[resource.LabelKeyOriginCluster]; !ok {
+			meta.AddLabels(remote, map[string]string{resource.LabelKeyOriginCluster: chain[0]})
+		}
+	}
+	meta.AddAnnotations(remote, a)
+}
+
+// WrittenFor returns true if the supplied remote claim was written by an agent
+// for the supplied local claim, from the supplied cluster unless it's empty.
+func WrittenFor(remote, local *claim.Unstructured, cluster string) bool {
+	a := remote.GetAnnotations()
+	if a[resource.AnnotationKeySourceObject] != fmt.Sprintf("%s/%s", local.GetNamespace(), local.GetName()) {
+		return false
+	}
+	c := a[resource.AnnotationKeySourceCluster]
+	return cluster == "" || c == "" || c == cluster
+}
+
+// OwnedByOtherCluster returns true if the supplied remote claim was written
+// from a cluster other than the supplied one. Two agents whose local claims
+// have the same name would otherwise take turns overwriting, or deleting, the
+// same remote claim. It's always false if the cluster is empty.
+func OwnedByOtherCluster(remote *claim.Unstructured, cluster string) bool {
+	c := remote.GetAnnotations()[resource.AnnotationKeySourceCluster]
+	return cluster != "" && c != "" && c != cluster
+}
+
+// DescribeOwner describes who created the supplied remote claim and when, from
+// the markers that agents and other tools leave on the objects they write.
+func DescribeOwner(remote *claim.Unstructured) string {
+	d := []string{"created " + remote.GetCreationTimestamp().UTC().Format(time.RFC3339)}
+	managers := []string{}
+	seen := map[string]bool{}
+	for _, f := range remote.GetManagedFields() {
+		if f.Manager != "" && !seen[f.Manager] {
+			seen[f.Manager] = true
+			managers = append(managers, f.Manager)
+		}
+	}
+	if len(managers) > 0 {
+		d = append(d, "written by "+strings.Join(managers, ", "))
+	}
+	a := remote.GetAnnotations()
+	if o := a[resource.AnnotationKeySourceObject]; o != "" {
+		d = append(d, "synced from "+o)
+	}
+	if c := a[resource.AnnotationKeySourceCluster]; c != "" {
+		d = append(d, "of cluster "+c)
+	}
+	if m := remote.GetLabels()[resource.LabelKeyManagedBy]; m != "" {
+		d = append(d, "managed by "+m)
+	}
+	for _, ref := range remote.GetOwnerReferences() {
+		d = append(d, fmt.Sprintf("owned by %s %s", ref.Kind, ref.Name))
+	}
+	return strings.Join(d, ", ")
+}
+
+// OriginChain returns the clusters the supplied local claim was synced through
+// before it reached the local cluster, if it was synced from another cluster
+// by an agent whose remote cluster is the local cluster.
+func OriginChain(local *claim.Unstructured) []string {
+	v := local.GetAnnotations()[resource.AnnotationKeyOriginChain]
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// InOriginChain returns true if the supplied local claim was already synced
+// through the supplied cluster, in which case syncing it on would close a
+// loop.
+func InOriginChain(local *claim.Unstructured, cluster string) bool {
+	for _, c := range OriginChain(local) {
+		if c == cluster {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/version"
+)
+
+func TestSetAuditAnnotations(t *testing.T) {
+	cases := map[string]struct {
+		reason     string
+		chain      string
+		cluster    string
+		want       map[string]string
+		wantOrigin string
+	}{
+		"WithCluster": {
+			reason:  "The remote claim should be traceable to the cluster and the local claim it's synced from",
+			cluster: "spoke-1",
+			want: map[string]string{
+				"existing":                          "annotation",
+				resource.AnnotationKeySourceCluster: "spoke-1",
+				resource.AnnotationKeySourceObject:  "team-a/cool-claim",
+				resource.AnnotationKeyCorrelationID: "cool-uid.3",
+				resource.AnnotationKeyAgentVersion:  version.Version,
+				resource.AnnotationKeyOriginChain:   "spoke-1",
+			},
+			wantOrigin: "spoke-1",
+		},
+		"Chained": {
+			reason:  "The cluster should be appended to the chain of clusters the local claim was synced through",
+			chain:   "edge-1",
+			cluster: "spoke-1",
+			want: map[string]string{
+				"existing":                          "annotation",
+				resource.AnnotationKeySourceCluster: "spoke-1",
+				resource.AnnotationKeySourceObject:  "team-a/cool-claim",
+				resource.AnnotationKeyCorrelationID: "cool-uid.3",
+				resource.AnnotationKeyAgentVersion:  version.Version,
+				resource.AnnotationKeyOriginChain:   "edge-1,spoke-1",
+			},
+			wantOrigin: "edge-1",
+		},
+		"WithoutCluster": {
+			reason: "The cluster should be omitted if its name is not known",
+			want: map[string]string{
+				"existing":                          "annotation",
+				resource.AnnotationKeySourceObject:  "team-a/cool-claim",
+				resource.AnnotationKeyCorrelationID: "cool-uid.3",
+				resource.AnnotationKeyAgentVersion:  version.Version,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			local.SetNamespace("team-a")
+			local.SetName("cool-claim")
+			local.SetUID("cool-uid")
+			local.SetGeneration(3)
+			resource.SetAnnotation(local, resource.AnnotationKeyOriginChain, tc.chain)
+
+			remote := claim.New()
+			remote.SetAnnotations(map[string]string{"existing": "annotation"})
+			SetAuditAnnotations(remote, local, tc.cluster)
+			if diff := cmp.Diff(tc.want, remote.GetAnnotations()); diff != "" {
+				t.Errorf("\nReason: %s\nSetAuditAnnotations(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantOrigin, remote.GetLabels()[resource.LabelKeyOriginCluster]); diff != "" {
+				t.Errorf("\nReason: %s\nSetAuditAnnotations(...): origin label: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInOriginChain(t *testing.T) {
+	local := claim.New()
+	local.SetAnnotations(map[string]string{resource.AnnotationKeyOriginChain: "edge-1,spoke-1"})
+
+	cases := map[string]struct {
+		reason  string
+		cluster string
+		want    bool
+	}{
+		"Loop": {
+			reason:  "A claim should not be synced on from a cluster it was already synced through",
+			cluster: "edge-1",
+			want:    true,
+		},
+		"NoLoop": {
+			reason:  "A claim should be synced on from a cluster it was not synced through yet",
+			cluster: "hub-1",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, InOriginChain(local, tc.cluster)); diff != "" {
+				t.Errorf("\nReason: %s\nInOriginChain(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWrittenFor(t *testing.T) {
+	local := claim.New()
+	local.SetNamespace("team-a")
+	local.SetName("cool-claim")
+
+	cases := map[string]struct {
+		reason      string
+		annotations map[string]string
+		cluster     string
+		want        bool
+	}{
+		"Unmarked": {
+			reason: "A remote claim without agent markers was not written by an agent",
+			want:   false,
+		},
+		"OtherObject": {
+			reason:      "A remote claim written for another local claim was not written for this one",
+			annotations: map[string]string{resource.AnnotationKeySourceObject: "team-b/cool-claim"},
+			want:        false,
+		},
+		"OtherCluster": {
+			reason: "A remote claim written from another cluster was not written for this local claim",
+			annotations: map[string]string{
+				resource.AnnotationKeySourceObject:  "team-a/cool-claim",
+				resource.AnnotationKeySourceCluster: "spoke-2",
+			},
+			cluster: "spoke-1",
+			want:    false,
+		},
+		"Written": {
+			reason: "A remote claim written for the local claim from this cluster was written for it",
+			annotations: map[string]string{
+				resource.AnnotationKeySourceObject:  "team-a/cool-claim",
+				resource.AnnotationKeySourceCluster: "spoke-1",
+			},
+			cluster: "spoke-1",
+			want:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			remote := claim.New()
+			remote.SetAnnotations(tc.annotations)
+			if diff := cmp.Diff(tc.want, WrittenFor(remote, local, tc.cluster)); diff != "" {
+				t.Errorf("\nReason: %s\nWrittenFor(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestOwnedByOtherCluster(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		source  string
+		cluster string
+		want    bool
+	}{
+		"OtherCluster": {
+			reason:  "A remote claim written from another cluster is owned by it",
+			source:  "spoke-2",
+			cluster: "spoke-1",
+			want:    true,
+		},
+		"ThisCluster": {
+			reason:  "A remote claim written from this cluster is not owned by another one",
+			source:  "spoke-1",
+			cluster: "spoke-1",
+		},
+		"Unmarked": {
+			reason:  "A remote claim without a source cluster is not owned by another cluster",
+			cluster: "spoke-1",
+		},
+		"UnknownCluster": {
+			reason: "Ownership cannot be told if the name of this cluster is not known",
+			source: "spoke-2",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			remote := claim.New()
+			resource.SetAnnotation(remote, resource.AnnotationKeySourceCluster, tc.source)
+			if diff := cmp.Diff(tc.want, OwnedByOtherCluster(remote, tc.cluster)); diff != "" {
+				t.Errorf("\nReason: %s\nOwnedByOtherCluster(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDescribeOwner(t *testing.T) {
+	remote := claim.New()
+	remote.SetCreationTimestamp(metav1.NewTime(time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)))
+	remote.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl"}, {Manager: "kubectl"}, {Manager: "argocd"}})
+	remote.SetLabels(map[string]string{resource.LabelKeyManagedBy: "helm"})
+	remote.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Application", Name: "databases"}})
+
+	want := "created 2020-09-01T10:00:00Z, written by kubectl, argocd, managed by helm, owned by Application databases"
+	if diff := cmp.Diff(want, DescribeOwner(remote)); diff != "" {
+		t.Errorf("\nReason: %s\nDescribeOwner(...): -want, +got:\n%s", "The description should include every owner marker of the remote claim", diff)
+	}
+}
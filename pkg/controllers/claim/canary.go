@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errCanaryCreate = "cannot create canary claim in staging namespace"
+)
+
+// CanaryValidator validates the remote claim in a staging area before it's
+// pushed to its actual namespace.
+type CanaryValidator interface {
+	Validate(ctx context.Context, remote *claim.Unstructured) error
+}
+
+// CanaryValidateFn is used to construct a CanaryValidator with a bare function.
+type CanaryValidateFn func(ctx context.Context, remote *claim.Unstructured) error
+
+// Validate calls the supplied function.
+func (fn CanaryValidateFn) Validate(ctx context.Context, remote *claim.Unstructured) error {
+	return fn(ctx, remote)
+}
+
+// NewNopCanaryValidator returns a CanaryValidator that accepts everything.
+func NewNopCanaryValidator() CanaryValidateFn {
+	return func(_ context.Context, _ *claim.Unstructured) error { return nil }
+}
+
+// NewDryRunCanaryValidator returns a new *DryRunCanaryValidator.
+func NewDryRunCanaryValidator(c client.Client, namespace string) *DryRunCanaryValidator {
+	return &DryRunCanaryValidator{client: c, namespace: namespace}
+}
+
+// DryRunCanaryValidator creates a copy of the remote claim in the staging
+// namespace of the remote cluster using server-side dry-run so that all the
+// admission steps of the remote cluster run against it without anything
+// being persisted.
+type DryRunCanaryValidator struct {
+	client    client.Client
+	namespace string
+}
+
+// Validate returns an error if the remote cluster rejects the canary copy of
+// the supplied claim.
+func (v *DryRunCanaryValidator) Validate(ctx context.Context, remote *claim.Unstructured) error {
+	canary := resource.SanitizedDeepCopyObject(remote.GetUnstructured())
+	canary.SetNamespace(v.namespace)
+	return errors.Wrap(v.client.Create(ctx, canary, client.DryRunAll), remotePrefix+errCanaryCreate)
+}
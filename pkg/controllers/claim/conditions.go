@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+type observedReason struct {
+	gvk    string
+	reason string
+	seen   time.Time
+}
+
+// ObservationExpiry returns how long a claim that's synced at the supplied
+// interval may go without being observed before the observers that keep track
+// of claims forget it. Like the LeaseDuration, it outlives the gap between two
+// passes with room to spare for passes that run late.
+func ObservationExpiry(syncInterval time.Duration) time.Duration {
+	return LeaseDuration(syncInterval)
+}
+
+// NewConditionRecorder returns a new *ConditionRecorder that stops counting
+// claims that it wasn't told about within the given expiry.
+func NewConditionRecorder(m *metrics.Conditions, expiry time.Duration) *ConditionRecorder {
+	return &ConditionRecorder{metrics: m, expiry: expiry, now: time.Now, claims: map[types.UID]observedReason{}}
+}
+
+// A ConditionRecorder counts the claims by the reason of their AgentSynced
+// condition, so that claims whose sync is blocked or failing can be alerted
+// on. It should be shared by all claim Reconcilers.
+type ConditionRecorder struct {
+	metrics *metrics.Conditions
+	expiry  time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	claims map[types.UID]observedReason
+}
+
+// ObserveSync records the reason of the AgentSynced condition of the supplied
+// claim. Claims are forgotten once the agent lets go of them.
+func (r *ConditionRecorder) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.forget(c.GetUID())
+	if meta.WasDeleted(c) && !meta.FinalizerExists(c, Finalizer) {
+		return
+	}
+	now := observedReason{
+		gvk:    c.GetObjectKind().GroupVersionKind().String(),
+		reason: string(c.GetCondition(resource.TypeAgentSync).Reason),
+		seen:   r.now(),
+	}
+	r.claims[c.GetUID()] = now
+	r.metrics.Claims.WithLabelValues(now.gvk, now.reason).Inc()
+}
+
+// Start forgetting the claims that weren't observed within the expiry, e.g.
+// because they were deleted or stopped being selected before the agent got to
+// observe them again, until the supplied channel is closed.
+func (r *ConditionRecorder) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.expiry)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+			r.Expire()
+		}
+	}
+}
+
+// Expire forgets the claims that weren't observed within the expiry.
+func (r *ConditionRecorder) Expire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cutoff := r.now().Add(-r.expiry)
+	for uid, s := range r.claims {
+		if s.seen.Before(cutoff) {
+			r.forget(uid)
+		}
+	}
+}
+
+// forget the claim with the supplied UID, if it's counted. The lock must be
+// held by the caller.
+func (r *ConditionRecorder) forget(uid types.UID) {
+	was, ok := r.claims[uid]
+	if !ok {
+		return
+	}
+	r.metrics.Claims.WithLabelValues(was.gvk, was.reason).Dec()
+	delete(r.claims, uid)
+}
+
+// metricsController is the controller label of the syncs of claims, and of
+// passthrough resources, in the Sync metrics.
+const metricsController = "claim"
+
+// NewSyncCounter returns a new *SyncCounter.
+func NewSyncCounter(m *metrics.Sync) *SyncCounter {
+	return &SyncCounter{metrics: m}
+}
+
+// A SyncCounter counts the syncs of claims that succeeded, and the ones that
+// failed by the cluster that caused them to fail.
+type SyncCounter struct {
+	metrics *metrics.Sync
+}
+
+// ObserveSync counts the sync of the supplied claim by the reason of its
+// AgentSynced condition. Syncs that are blocked are not counted.
+func (sc *SyncCounter) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	gvk := c.GetObjectKind().GroupVersionKind().String()
+	cond := c.GetCondition(resource.TypeAgentSync)
+	switch cond.Reason {
+	case resource.ReasonAgentSyncSuccess:
+		sc.metrics.Synced.WithLabelValues(metricsController, gvk).Inc()
+	case resource.ReasonAgentSyncError:
+		sc.metrics.Failures.WithLabelValues(metricsController, gvk, metrics.Cluster(cond.Message)).Inc()
+	}
+}
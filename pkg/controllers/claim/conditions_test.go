@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestConditionRecorder(t *testing.T) {
+	m, err := metrics.NewConditions(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewConditions(...): %s", err)
+	}
+	r := NewConditionRecorder(m, 10*time.Minute)
+	start := time.Now()
+	r.now = func() time.Time { return start }
+	cl := claim.New()
+	cl.SetUID("cool-uid")
+	cl.SetFinalizers([]string{Finalizer})
+	gvk := cl.GetObjectKind().GroupVersionKind().String()
+	count := func(reason string) float64 {
+		return testutil.ToFloat64(m.Claims.WithLabelValues(gvk, reason))
+	}
+
+	cl.SetConditions(resource.AgentSyncError(errors.New("boom")))
+	r.ObserveSync(context.Background(), cl)
+	cl.SetConditions(resource.AgentSyncSuccess())
+	r.ObserveSync(context.Background(), cl)
+	if diff := cmp.Diff([]float64{0, 1}, []float64{count("Error"), count("Success")}); diff != "" {
+		t.Errorf("ObserveSync(...): a claim should only be counted by its latest reason: -want, +got:\n%s", diff)
+	}
+
+	now := metav1.Now()
+	cl.SetDeletionTimestamp(&now)
+	cl.SetFinalizers(nil)
+	r.ObserveSync(context.Background(), cl)
+	if diff := cmp.Diff(float64(0), count("Success")); diff != "" {
+		t.Errorf("ObserveSync(...): a claim should not be counted once the agent lets go of it: -want, +got:\n%s", diff)
+	}
+
+	// A claim that's never observed again, e.g. because it was deleted before
+	// the agent saw its deletion, is forgotten once it expires.
+	gone := claim.New()
+	gone.SetUID("gone-uid")
+	gone.SetConditions(resource.AgentSyncError(errors.New("boom")))
+	r.ObserveSync(context.Background(), gone)
+	r.now = func() time.Time { return start.Add(11 * time.Minute) }
+	cl.SetDeletionTimestamp(nil)
+	cl.SetFinalizers([]string{Finalizer})
+	cl.SetConditions(resource.AgentSyncSuccess())
+	r.ObserveSync(context.Background(), cl)
+	r.Expire()
+	if diff := cmp.Diff([]float64{0, 1}, []float64{count("Error"), count("Success")}); diff != "" {
+		t.Errorf("ObserveSync(...): a claim should not be counted once it expires: -want, +got:\n%s", diff)
+	}
+}
+
+func TestSyncCounter(t *testing.T) {
+	m, err := metrics.NewSync(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewSync(...): %s", err)
+	}
+	sc := NewSyncCounter(m)
+	cl := claim.New()
+	gvk := cl.GetObjectKind().GroupVersionKind().String()
+
+	for _, c := range []v1alpha1.Condition{
+		resource.AgentSyncSuccess(),
+		resource.AgentSyncError(errors.Wrap(errors.New("boom"), remotePrefix+errGetRequirement)),
+		resource.AgentSyncError(errors.Wrap(errors.New("boom"), localPrefix+errAddFinalizer)),
+		resource.AgentSyncPendingApproval(),
+	} {
+		cl.SetConditions(c)
+		sc.ObserveSync(context.Background(), cl)
+	}
+	got := []float64{
+		testutil.ToFloat64(m.Synced.WithLabelValues(metricsController, gvk)),
+		testutil.ToFloat64(m.Failures.WithLabelValues(metricsController, gvk, metrics.ClusterRemote)),
+		testutil.ToFloat64(m.Failures.WithLabelValues(metricsController, gvk, metrics.ClusterLocal)),
+	}
+	if diff := cmp.Diff([]float64{1, 1, 1}, got); diff != "" {
+		t.Errorf("ObserveSync(...): syncs should be counted by their result, and failures by the cluster that caused them: -want, +got:\n%s", diff)
+	}
+}
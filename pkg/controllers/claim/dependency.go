@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errGetDependency       = "cannot get dependency"
+	errFmtDependencyAbsent = "%s %s does not exist in the remote cluster"
+	errFmtDependencyGone   = "%s %s is being deleted in the remote cluster"
+)
+
+type waitingOnDependency struct{ error }
+
+// IsWaitingOnDependency returns true if the supplied error indicates that an
+// object the claim depends on doesn't exist in the remote cluster yet.
+func IsWaitingOnDependency(err error) bool {
+	_, ok := errors.Cause(err).(waitingOnDependency)
+	return ok
+}
+
+// A DependencyResolver makes sure that everything the remote claim refers to
+// exists in the remote cluster before the claim is pushed.
+type DependencyResolver interface {
+	Resolve(ctx context.Context, local, remote *claim.Unstructured) error
+}
+
+// DependencyResolveFn is used to construct a DependencyResolver with a bare
+// function.
+type DependencyResolveFn func(ctx context.Context, local, remote *claim.Unstructured) error
+
+// Resolve calls the supplied function.
+func (fn DependencyResolveFn) Resolve(ctx context.Context, local, remote *claim.Unstructured) error {
+	return fn(ctx, local, remote)
+}
+
+// NewNopDependencyResolver returns a DependencyResolver that does nothing.
+func NewNopDependencyResolver() DependencyResolveFn {
+	return func(_ context.Context, _, _ *claim.Unstructured) error { return nil }
+}
+
+// DependencyResolverChain calls Resolve method of all of its
+// DependencyResolvers in order.
+type DependencyResolverChain []DependencyResolver
+
+// Resolve calls all Resolve functions one by one.
+func (dd DependencyResolverChain) Resolve(ctx context.Context, local, remote *claim.Unstructured) error {
+	for _, d := range dd {
+		if err := d.Resolve(ctx, local, remote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewAPIDependencyResolver returns a new *APIDependencyResolver.
+func NewAPIDependencyResolver(c client.Reader) *APIDependencyResolver {
+	return &APIDependencyResolver{client: c}
+}
+
+// An APIDependencyResolver looks up the dependencies of a claim in the remote
+// cluster. They are, in order, the namespace of the remote claim, the input
+// Secrets and ConfigMaps, and the objects listed in the depends-on annotation.
+type APIDependencyResolver struct {
+	client client.Reader
+}
+
+// Resolve returns an error that satisfies IsWaitingOnDependency if any of the
+// dependencies of the claim is missing or being deleted.
+func (d *APIDependencyResolver) Resolve(ctx context.Context, local, remote *claim.Unstructured) error {
+	ns := remote.GetNamespace()
+	if err := d.exists(ctx, "Namespace", types.NamespacedName{Name: ns}, &corev1.Namespace{}); err != nil {
+		return err
+	}
+	for _, ref := range resource.ParseInputReferences(local.GetAnnotations()[resource.AnnotationKeyInputSecrets]) {
+		if err := d.exists(ctx, "Secret", types.NamespacedName{Namespace: ns, Name: ref.Name}, &corev1.Secret{}); err != nil {
+			return err
+		}
+	}
+	for _, ref := range resource.ParseInputReferences(local.GetAnnotations()[resource.AnnotationKeyInputConfigMaps]) {
+		if err := d.exists(ctx, "ConfigMap", types.NamespacedName{Namespace: ns, Name: ref.Name}, &corev1.ConfigMap{}); err != nil {
+			return err
+		}
+	}
+	for _, ref := range resource.ParseDependencyReferences(local.GetAnnotations()[resource.AnnotationKeyDependsOn]) {
+		u := &kunstructured.Unstructured{}
+		u.SetGroupVersionKind(ref.GroupVersionKind)
+		// The namespace is ignored for cluster-scoped kinds.
+		if err := d.exists(ctx, ref.Kind, types.NamespacedName{Namespace: ns, Name: ref.Name}, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *APIDependencyResolver) exists(ctx context.Context, kind string, nn types.NamespacedName, obj runtimeresource.Object) error {
+	name := nn.String()
+	if nn.Namespace == "" {
+		name = nn.Name
+	}
+	err := d.client.Get(ctx, nn, obj)
+	if kerrors.IsNotFound(err) {
+		return waitingOnDependency{errors.Errorf(errFmtDependencyAbsent, kind, name)}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "%s %s %s", errGetDependency, kind, name)
+	}
+	if meta.WasDeleted(obj) {
+		return waitingOnDependency{errors.Errorf(errFmtDependencyGone, kind, name)}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestAPIDependencyResolver(t *testing.T) {
+	errBoom := errors.New("boom")
+	type want struct {
+		waiting bool
+		err     bool
+	}
+	cases := map[string]struct {
+		reason string
+		get    test.MockGetFn
+		want   want
+	}{
+		"AllExist": {
+			reason: "A claim should not wait if all of its dependencies exist",
+			get:    test.NewMockGetFn(nil),
+			want:   want{},
+		},
+		"ProviderConfigMissing": {
+			reason: "A claim should wait on an object in its depends-on annotation that doesn't exist",
+			get: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+				if u, ok := obj.(*kunstructured.Unstructured); ok && u.GetKind() == "ProviderConfig" {
+					return kerrors.NewNotFound(schema.GroupResource{}, "")
+				}
+				return nil
+			},
+			want: want{waiting: true, err: true},
+		},
+		"GetError": {
+			reason: "Errors other than NotFound should not be reported as waiting",
+			get:    test.NewMockGetFn(errBoom),
+			want:   want{err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			local.SetNamespace("cool")
+			local.SetAnnotations(map[string]string{
+				resource.AnnotationKeyInputSecrets: "creds",
+				resource.AnnotationKeyDependsOn:    "aws.crossplane.io/v1beta1/ProviderConfig/default",
+			})
+			remote := claim.New()
+			remote.SetNamespace("cool")
+			err := NewAPIDependencyResolver(&test.MockClient{MockGet: tc.get}).Resolve(context.Background(), local, remote)
+			got := want{waiting: IsWaitingOnDependency(err), err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nResolve(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+// maxDriftFields is the number of drifted fields that are described in a
+// Drift, so that the condition it ends up in stays readable.
+const maxDriftFields = 10
+
+// A FieldDrift is a field of the spec of a remote claim whose value is not the
+// desired one.
+type FieldDrift struct {
+	// Path of the field, e.g. spec.parameters.storageGB.
+	Path string
+
+	// Desired value of the field.
+	Desired interface{}
+
+	// Observed value of the field, if it's set.
+	Observed interface{}
+
+	// Unset is true if the field is not set in the remote claim.
+	Unset bool
+}
+
+// String describes the field drift.
+func (f FieldDrift) String() string {
+	got := "<unset>"
+	if !f.Unset {
+		got = describe(f.Observed)
+	}
+	return fmt.Sprintf("%s: want %s, got %s", f.Path, describe(f.Desired), got)
+}
+
+// A Drift is the list of the fields of the spec of a remote claim whose values
+// are not the desired ones, sorted by their paths.
+type Drift []FieldDrift
+
+// String describes the drift in a single line. Only the first few fields are
+// described.
+func (d Drift) String() string {
+	s := make([]string, 0, len(d))
+	for i, f := range d {
+		if i == maxDriftFields {
+			s = append(s, fmt.Sprintf("and %d more", len(d)-maxDriftFields))
+			break
+		}
+		s = append(s, f.String())
+	}
+	return strings.Join(s, "; ")
+}
+
+// SpecDrift returns the fields of the spec of the observed remote claim whose
+// values differ from the ones in the spec of the desired remote claim. Fields
+// that only the observed claim has are not considered drift, since they're
+// defaulted or late-initialized in the remote cluster. Lists are compared as a
+// whole.
+func SpecDrift(desired, observed *claim.Unstructured) Drift {
+	d := Drift{}
+	o, ok := observed.Object["spec"]
+	d.add("spec", desired.Object["spec"], o, ok)
+	return d
+}
+
+func (d *Drift) add(path string, desired, observed interface{}, set bool) {
+	if desired == nil {
+		return
+	}
+	if !set {
+		*d = append(*d, FieldDrift{Path: path, Desired: desired, Unset: true})
+		return
+	}
+	dm, dok := desired.(map[string]interface{})
+	om, ook := observed.(map[string]interface{})
+	if !dok || !ook {
+		if !equal(desired, observed) {
+			*d = append(*d, FieldDrift{Path: path, Desired: desired, Observed: observed})
+		}
+		return
+	}
+	keys := make([]string, 0, len(dm))
+	for k := range dm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := om[k]
+		d.add(child(path, k), dm[k], v, ok)
+	}
+}
+
+func child(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%s]", path, key)
+	}
+	return path + "." + key
+}
+
+// equal compares the supplied values by their JSON, after comparing them
+// deeply, so that a number that was decoded as an integer in one of them and
+// as a float in the other is still considered equal.
+func equal(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+func describe(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
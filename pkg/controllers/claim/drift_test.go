@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+func TestSpecDrift(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		desired  map[string]interface{}
+		observed map[string]interface{}
+		want     Drift
+	}{
+		"NoDrift": {
+			reason:   "Fields that are only set in the remote claim should not be considered drift",
+			desired:  map[string]interface{}{"spec": map[string]interface{}{"size": "large"}},
+			observed: map[string]interface{}{"spec": map[string]interface{}{"size": "large", "compositionRef": map[string]interface{}{"name": "cool"}}},
+			want:     Drift{},
+		},
+		"Numbers": {
+			reason:   "Numbers that are decoded differently should be considered equal",
+			desired:  map[string]interface{}{"spec": map[string]interface{}{"storageGB": int64(20)}},
+			observed: map[string]interface{}{"spec": map[string]interface{}{"storageGB": float64(20)}},
+			want:     Drift{},
+		},
+		"Changed": {
+			reason:   "Fields whose values were changed should be reported with their paths",
+			desired:  map[string]interface{}{"spec": map[string]interface{}{"parameters": map[string]interface{}{"size": "large", "zones": []interface{}{"a", "b"}}}},
+			observed: map[string]interface{}{"spec": map[string]interface{}{"parameters": map[string]interface{}{"size": "small", "zones": []interface{}{"a"}}}},
+			want: Drift{
+				{Path: "spec.parameters.size", Desired: "large", Observed: "small"},
+				{Path: "spec.parameters.zones", Desired: []interface{}{"a", "b"}, Observed: []interface{}{"a"}},
+			},
+		},
+		"Removed": {
+			reason:   "Fields that were removed should be reported as unset",
+			desired:  map[string]interface{}{"spec": map[string]interface{}{"labels": map[string]interface{}{"example.org/team": "a"}}},
+			observed: map[string]interface{}{"spec": map[string]interface{}{"labels": map[string]interface{}{}}},
+			want: Drift{
+				{Path: "spec.labels[example.org/team]", Desired: "a", Unset: true},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			desired := claim.New()
+			desired.Object = tc.desired
+			observed := claim.New()
+			observed.Object = tc.observed
+			got := SpecDrift(desired, observed)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nSpecDrift(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDriftString(t *testing.T) {
+	d := Drift{
+		{Path: "spec.size", Desired: "large", Observed: "small"},
+		{Path: "spec.storageGB", Desired: int64(20), Unset: true},
+	}
+	want := `spec.size: want "large", got "small"; spec.storageGB: want 20, got <unset>`
+	if diff := cmp.Diff(want, d.String()); diff != "" {
+		t.Errorf("\nReason: %s\nd.String(): -want, +got:\n%s", "Every drifted field should be described", diff)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errFmtFanOutGet    = "cannot get claim from fan-out remote %s"
+	errFmtFanOutDelete = "cannot delete claim from fan-out remote %s"
+	errFmtFanOutApply  = "cannot apply claim to fan-out remote %s"
+	errFmtNotReady     = "claim is not ready in fan-out remotes %s"
+)
+
+// fieldPathRemotes is where the state of the claim in each fan-out remote is
+// recorded in the status of the local claim.
+var fieldPathRemotes = []string{"status", "agent", "remotes"}
+
+// Fields of the state of the claim in a fan-out remote.
+const (
+	fieldSynced  = "synced"
+	fieldReady   = "ready"
+	fieldMessage = "message"
+)
+
+// NewFanOut returns a new *FanOut that propagates claims to the supplied
+// remote clusters, keyed by their names.
+func NewFanOut(remotes map[string]client.Client, m NamespaceMapper) *FanOut {
+	f := &FanOut{remotes: map[string]runtimeresource.ClientApplicator{}, mapper: m, configurator: NewDefaultConfigurator()}
+	for name, c := range remotes {
+		uc := unstructured.NewClient(c)
+		f.remotes[name] = runtimeresource.ClientApplicator{Client: uc, Applicator: runtimeresource.NewAPIPatchingApplicator(uc)}
+	}
+	return f
+}
+
+// A FanOut propagates claims to remote clusters other than the one they're
+// synced with, e.g. to provision the same infrastructure in several regions.
+// Claims opt in with the AnnotationKeyFanOut annotation. The state of the claim
+// in each of them is recorded in the status of the local claim, which is only
+// ready once it's ready in all of them.
+type FanOut struct {
+	remotes      map[string]runtimeresource.ClientApplicator
+	mapper       NamespaceMapper
+	configurator Configurator
+}
+
+// Targets returns the names of the remote clusters the supplied claim is
+// fanned out to, sorted.
+func (f *FanOut) Targets(local *claim.Unstructured) []string {
+	v := strings.TrimSpace(local.GetAnnotations()[resource.AnnotationKeyFanOut])
+	var targets []string
+	for name := range f.remotes {
+		if v == resource.FanOutAll {
+			targets = append(targets, name)
+			continue
+		}
+		for _, t := range strings.Split(v, ",") {
+			if strings.TrimSpace(t) == name {
+				targets = append(targets, name)
+				break
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Propagate pushes the local claim to every remote cluster it's fanned out to
+// and removes it from the ones it no longer is. Failures are recorded in the
+// status of the local claim rather than returned, so that they don't hold
+// the sync with the primary remote cluster.
+func (f *FanOut) Propagate(ctx context.Context, local, _ *claim.Unstructured) error {
+	recorded, _, _ := kunstructured.NestedMap(local.GetUnstructured().Object, fieldPathRemotes...)
+	targets := f.Targets(local)
+	remotes := map[string]interface{}{}
+	var notReady []string
+	for _, name := range targets {
+		state := f.push(ctx, name, local)
+		remotes[name] = state
+		if state[fieldReady] != string(corev1.ConditionTrue) {
+			notReady = append(notReady, name)
+		}
+	}
+
+	// Copies in remote clusters the claim is no longer fanned out to are
+	// deleted. They're kept in the status until they're gone.
+	for name := range recorded {
+		if _, ok := remotes[name]; ok {
+			continue
+		}
+		if _, ok := f.remotes[name]; !ok {
+			continue
+		}
+		gone, err := f.delete(ctx, name, local)
+		if gone {
+			continue
+		}
+		state := map[string]interface{}{fieldSynced: string(corev1.ConditionFalse), fieldReady: string(corev1.ConditionFalse), fieldMessage: "deleting"}
+		if err != nil {
+			state[fieldMessage] = err.Error()
+		}
+		remotes[name] = state
+	}
+
+	if len(remotes) == 0 {
+		kunstructured.RemoveNestedField(local.GetUnstructured().Object, fieldPathRemotes...)
+		return nil
+	}
+	if err := kunstructured.SetNestedMap(local.GetUnstructured().Object, remotes, fieldPathRemotes...); err != nil {
+		return err
+	}
+	if len(notReady) > 0 && local.GetCondition(v1alpha1.TypeReady).Status == corev1.ConditionTrue {
+		local.SetConditions(v1alpha1.Unavailable().WithMessage(fmt.Sprintf(errFmtNotReady, strings.Join(notReady, ", "))))
+	}
+	return nil
+}
+
+// push applies the local claim to the named remote cluster and returns the
+// state of the claim there.
+func (f *FanOut) push(ctx context.Context, name string, local *claim.Unstructured) map[string]interface{} {
+	state := map[string]interface{}{fieldSynced: string(corev1.ConditionFalse), fieldReady: string(corev1.ConditionUnknown)}
+	rc := claim.New(claim.WithGroupVersionKind(local.GetObjectKind().GroupVersionKind()))
+	if err := f.configurator.Configure(ctx, local, rc); err != nil {
+		state[fieldMessage] = errors.Wrapf(err, errFmtFanOutApply, name).Error()
+		return state
+	}
+	rc.SetNamespace(f.mapper.RemoteNamespace(local.GetNamespace()))
+
+	// The references that were late-initialized from the primary remote
+	// cluster point to objects that only exist there.
+	kunstructured.RemoveNestedField(rc.GetUnstructured().Object, "spec", "resourceRef")
+	kunstructured.RemoveNestedField(rc.GetUnstructured().Object, "spec", "compositionRef")
+
+	if err := f.remotes[name].Apply(ctx, rc); err != nil {
+		state[fieldMessage] = errors.Wrapf(err, errFmtFanOutApply, name).Error()
+		return state
+	}
+	state[fieldSynced] = string(corev1.ConditionTrue)
+	ready := rc.GetCondition(v1alpha1.TypeReady)
+	state[fieldReady] = string(ready.Status)
+	if ready.Status == "" {
+		state[fieldReady] = string(corev1.ConditionUnknown)
+	}
+	if ready.Message != "" {
+		state[fieldMessage] = ready.Message
+	}
+	return state
+}
+
+// delete deletes the copy of the local claim in the named remote cluster and
+// returns true once it's gone.
+func (f *FanOut) delete(ctx context.Context, name string, local *claim.Unstructured) (bool, error) {
+	rc := claim.New(claim.WithGroupVersionKind(local.GetObjectKind().GroupVersionKind()))
+	nn := types.NamespacedName{Namespace: f.mapper.RemoteNamespace(local.GetNamespace()), Name: local.GetName()}
+	if err := f.remotes[name].Get(ctx, nn, rc); err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Wrapf(err, errFmtFanOutGet, name)
+	}
+	if rc.GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+	return false, errors.Wrapf(runtimeresource.IgnoreNotFound(f.remotes[name].Delete(ctx, rc)), errFmtFanOutDelete, name)
+}
+
+// Delete deletes the copies of the local claim in all remote clusters it may
+// have been fanned out to and returns true once they're all gone.
+func (f *FanOut) Delete(ctx context.Context, local *claim.Unstructured) (bool, error) {
+	names := make([]string, 0, len(f.remotes))
+	for name := range f.remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	all := true
+	for _, name := range names {
+		gone, err := f.delete(ctx, name, local)
+		if err != nil {
+			return false, err
+		}
+		all = all && gone
+	}
+	return all, nil
+}
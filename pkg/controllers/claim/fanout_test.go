@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestFanOut(t *testing.T) {
+	notFound := test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, ""))
+	remotes := map[string]client.Client{
+		"eu": &test.MockClient{MockGet: notFound, MockCreate: test.NewMockCreateFn(nil)},
+		"us": &test.MockClient{MockGet: notFound, MockCreate: test.NewMockCreateFn(kerrors.NewForbidden(schema.GroupResource{}, "", nil))},
+	}
+	newClaim := func(fanOut string) *claim.Unstructured {
+		c := claim.New()
+		c.SetName("cool-claim")
+		c.SetNamespace("default")
+		if fanOut != "" {
+			c.SetAnnotations(map[string]string{resource.AnnotationKeyFanOut: fanOut})
+		}
+		c.SetConditions(v1alpha1.Available())
+		return c
+	}
+
+	type want struct {
+		targets []string
+		ready   corev1.ConditionStatus
+		synced  map[string]string
+	}
+	cases := map[string]struct {
+		reason string
+		local  *claim.Unstructured
+		want   want
+	}{
+		"NotFannedOut": {
+			reason: "A claim without the fan-out annotation should not be propagated anywhere else",
+			local:  newClaim(""),
+			want:   want{ready: corev1.ConditionTrue},
+		},
+		"Named": {
+			reason: "A claim should only be propagated to the remote clusters it names, and not be ready until it's ready in all of them",
+			local:  newClaim("eu, ap"),
+			want: want{
+				targets: []string{"eu"},
+				ready:   corev1.ConditionFalse,
+				synced:  map[string]string{"eu": string(corev1.ConditionTrue)},
+			},
+		},
+		"All": {
+			reason: "A claim should be propagated to all remote clusters and failures should be recorded per remote cluster",
+			local:  newClaim(resource.FanOutAll),
+			want: want{
+				targets: []string{"eu", "us"},
+				ready:   corev1.ConditionFalse,
+				synced:  map[string]string{"eu": string(corev1.ConditionTrue), "us": string(corev1.ConditionFalse)},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewFanOut(remotes, NamespaceMap(nil))
+			if diff := cmp.Diff(tc.want.targets, f.Targets(tc.local)); diff != "" {
+				t.Errorf("\nReason: %s\nTargets(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if err := f.Propagate(context.Background(), tc.local, nil); err != nil {
+				t.Fatalf("\nReason: %s\nPropagate(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.ready, tc.local.GetCondition(v1alpha1.TypeReady).Status); diff != "" {
+				t.Errorf("\nReason: %s\nPropagate(...): -want ready, +got ready:\n%s", tc.reason, diff)
+			}
+			var synced map[string]string
+			for name := range remotes {
+				s, ok, _ := kunstructured.NestedString(tc.local.Object, append(fieldPathRemotes, name, fieldSynced)...)
+				if !ok {
+					continue
+				}
+				if synced == nil {
+					synced = map[string]string{}
+				}
+				synced[name] = s
+			}
+			if diff := cmp.Diff(tc.want.synced, synced); diff != "" {
+				t.Errorf("\nReason: %s\nPropagate(...): -want synced, +got synced:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// FencingLeaseName is the name of the Lease in the remote cluster whose
+	// transitions count the fencing tokens handed out to the agents of a
+	// cluster.
+	FencingLeaseName = "crossplane-agent-fencing"
+
+	errGetFencingLease    = "cannot get fencing lease"
+	errCreateFencingLease = "cannot create fencing lease"
+	errUpdateFencingLease = "cannot update fencing lease"
+)
+
+// A FencingTokenSource hands out the fencing token of an agent.
+type FencingTokenSource interface {
+	// Token returns the fencing token of the agent, or zero if it doesn't
+	// have one yet.
+	Token() int64
+}
+
+// FencingLeaseNameOf returns the name of the fencing Lease of the agents of
+// the supplied cluster.
+func FencingLeaseNameOf(cluster string) string {
+	if cluster == "" {
+		return FencingLeaseName
+	}
+	return FencingLeaseName + "-" + cluster
+}
+
+// NewLeaseFencingToken returns a *LeaseFencingToken that counts the tokens it
+// hands out in the Lease with the supplied name in the remote cluster.
+func NewLeaseFencingToken(c client.Client, nn types.NamespacedName, log logging.Logger) *LeaseFencingToken {
+	return &LeaseFencingToken{client: c, name: nn, log: log}
+}
+
+// A LeaseFencingToken is a FencingTokenSource whose tokens are the transitions
+// of a Lease in the remote cluster, which every agent that starts syncing
+// increments. An agent that starts syncing later than another therefore gets
+// a greater token, whichever clusters they run in and however their clocks
+// drift.
+type LeaseFencingToken struct {
+	client client.Client
+	name   types.NamespacedName
+	log    logging.Logger
+
+	token int64
+}
+
+// Token returns the fencing token, or zero until it's acquired.
+func (f *LeaseFencingToken) Token() int64 {
+	return atomic.LoadInt64(&f.token)
+}
+
+// Start acquires a new fencing token and keeps it until the supplied channel
+// is closed. When leader election is enabled it's started only once the agent
+// becomes the leader, so that the new leader gets a greater token than the
+// ones that led before it.
+func (f *LeaseFencingToken) Start(stop <-chan struct{}) error {
+	for {
+		err := f.acquire(context.Background())
+		if err == nil {
+			break
+		}
+		f.log.Debug("Cannot acquire fencing token", "error", err, "requeue-after", time.Now().Add(tinyWait))
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(tinyWait):
+		}
+	}
+	f.log.Info("Acquired fencing token", "fencing-token", f.Token())
+	<-stop
+	return nil
+}
+
+func (f *LeaseFencingToken) acquire(ctx context.Context) error {
+	l := &coordinationv1.Lease{}
+	err := f.client.Get(ctx, f.name, l)
+	if kerrors.IsNotFound(err) {
+		first := int32(1)
+		l = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: f.name.Namespace, Name: f.name.Name},
+			Spec:       coordinationv1.LeaseSpec{LeaseTransitions: &first},
+		}
+		if err := f.client.Create(ctx, l); err != nil {
+			return errors.Wrap(err, errCreateFencingLease)
+		}
+		atomic.StoreInt64(&f.token, int64(first))
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, errGetFencingLease)
+	}
+	next := int32(1)
+	if l.Spec.LeaseTransitions != nil {
+		next = *l.Spec.LeaseTransitions + 1
+	}
+	l.Spec.LeaseTransitions = &next
+	// The Lease is updated with the resource version it was read at, so two
+	// agents that start at the same time can't get the same token.
+	if err := f.client.Update(ctx, l); err != nil {
+		return errors.Wrap(err, errUpdateFencingLease)
+	}
+	atomic.StoreInt64(&f.token, int64(next))
+	return nil
+}
+
+// FencingTokenOf returns the fencing token of the agent that last wrote the
+// supplied remote claim, or zero if it doesn't have one.
+func FencingTokenOf(remote *claim.Unstructured) int64 {
+	t, err := strconv.ParseInt(remote.GetAnnotations()[resource.AnnotationKeyFencingToken], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return t
+}
+
+// SetFencingToken marks the supplied remote claim as written by the agent that
+// has the supplied fencing token.
+func SetFencingToken(remote *claim.Unstructured, token int64) {
+	meta.AddAnnotations(remote, map[string]string{resource.AnnotationKeyFencingToken: strconv.FormatInt(token, 10)})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestLeaseFencingTokenAcquire(t *testing.T) {
+	errBoom := errors.New("boom")
+	transitions := func(n int32) *int32 { return &n }
+
+	type want struct {
+		token int64
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		client client.Client
+		want   want
+	}{
+		"FirstAgent": {
+			reason: "The first agent to start syncing should create the Lease and get the first token",
+			client: &test.MockClient{
+				MockGet:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, FencingLeaseName)),
+				MockCreate: test.NewMockCreateFn(nil),
+			},
+			want: want{token: 1},
+		},
+		"NextAgent": {
+			reason: "An agent that starts syncing after others should get a token greater than any of theirs",
+			client: &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					obj.(*coordinationv1.Lease).Spec.LeaseTransitions = transitions(41)
+					return nil
+				},
+				MockUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+					if got := *obj.(*coordinationv1.Lease).Spec.LeaseTransitions; got != 42 {
+						t.Errorf("LeaseTransitions: want 42, got %d", got)
+					}
+					return nil
+				},
+			},
+			want: want{token: 42},
+		},
+		"Conflict": {
+			reason: "An agent that loses the race for a token to another should not get one",
+			client: &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					obj.(*coordinationv1.Lease).Spec.LeaseTransitions = transitions(41)
+					return nil
+				},
+				MockUpdate: test.NewMockUpdateFn(errBoom),
+			},
+			want: want{err: errors.Wrap(errBoom, errUpdateFencingLease)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewLeaseFencingToken(tc.client, types.NamespacedName{Namespace: "cool-namespace", Name: FencingLeaseName}, logging.NewNopLogger())
+			err := f.acquire(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nf.acquire(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.token, f.Token()); diff != "" {
+				t.Errorf("\nReason: %s\nf.Token(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const errListByIdempotencyKey = "cannot list remote claims by idempotency key"
+
+// IdempotencyKey returns the key that identifies the remote claims created for
+// the supplied local claim. When the local claim is itself synced from another
+// cluster it's the key of the claim it's synced from, so that it stays the
+// same however many agents a claim is synced through.
+func IdempotencyKey(local *claim.Unstructured) string {
+	if k := local.GetLabels()[resource.LabelKeyIdempotencyKey]; k != "" {
+		return k
+	}
+	return string(local.GetUID())
+}
+
+// SetIdempotencyKey marks the supplied remote claim with the idempotency key of
+// the supplied local claim.
+func SetIdempotencyKey(remote, local *claim.Unstructured) {
+	meta.AddLabels(remote, map[string]string{resource.LabelKeyIdempotencyKey: IdempotencyKey(local)})
+}
+
+// FindCreated returns the remote claim in the supplied namespace that was
+// created for the supplied local claim according to its idempotency key, or
+// nil if there's none. The supplied remote claim is only used for its kind.
+func FindCreated(ctx context.Context, r client.Reader, namespace string, local, remote *claim.Unstructured) (*claim.Unstructured, error) {
+	l := &kunstructured.UnstructuredList{}
+	gvk := remote.GetObjectKind().GroupVersionKind()
+	l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, l, client.InNamespace(namespace), client.MatchingLabels{resource.LabelKeyIdempotencyKey: IdempotencyKey(local)}); err != nil {
+		return nil, errors.Wrap(err, errListByIdempotencyKey)
+	}
+	if len(l.Items) == 0 {
+		return nil, nil
+	}
+	return &claim.Unstructured{Unstructured: l.Items[0]}, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		labels map[string]string
+		want   string
+	}{
+		"Local": {
+			reason: "The key of a claim created in the local cluster should be its UID",
+			want:   "cool-uid",
+		},
+		"Chained": {
+			reason: "The key of a claim synced from another cluster should be the one of the claim it's synced from",
+			labels: map[string]string{resource.LabelKeyIdempotencyKey: "origin-uid"},
+			want:   "origin-uid",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			local.SetUID("cool-uid")
+			local.SetLabels(tc.labels)
+			remote := claim.New()
+			SetIdempotencyKey(remote, local)
+			if diff := cmp.Diff(tc.want, remote.GetLabels()[resource.LabelKeyIdempotencyKey]); diff != "" {
+				t.Errorf("\nReason: %s\nSetIdempotencyKey(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFindCreated(t *testing.T) {
+	kind := schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "CoolClaim"}
+
+	type want struct {
+		name string
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		items  []kunstructured.Unstructured
+		err    error
+		want   want
+	}{
+		"ListFailed": {
+			reason: "An error should be returned if the remote claims cannot be listed",
+			err:    errBoom,
+			want:   want{err: errors.Wrap(errBoom, errListByIdempotencyKey)},
+		},
+		"NotCreated": {
+			reason: "Nothing should be returned if no remote claim carries the key",
+		},
+		"Created": {
+			reason: "The remote claim that carries the key should be returned whatever its name",
+			items: func() []kunstructured.Unstructured {
+				u := kunstructured.Unstructured{}
+				u.SetName("prefixed-cool-claim")
+				return []kunstructured.Unstructured{u}
+			}(),
+			want: want{name: "prefixed-cool-claim"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			local.SetUID("cool-uid")
+			r := &test.MockClient{
+				MockList: func(_ context.Context, obj runtime.Object, opts ...client.ListOption) error {
+					l := obj.(*kunstructured.UnstructuredList)
+					if diff := cmp.Diff(kind.GroupVersion().WithKind("CoolClaimList"), l.GroupVersionKind()); diff != "" {
+						t.Errorf("\nReason: %s\nList(...): -want kind, +got kind:\n%s", tc.reason, diff)
+					}
+					lo := (&client.ListOptions{}).ApplyOptions(opts)
+					if diff := cmp.Diff("team-a", lo.Namespace); diff != "" {
+						t.Errorf("\nReason: %s\nList(...): -want namespace, +got namespace:\n%s", tc.reason, diff)
+					}
+					if diff := cmp.Diff(resource.LabelKeyIdempotencyKey+"=cool-uid", lo.LabelSelector.String()); diff != "" {
+						t.Errorf("\nReason: %s\nList(...): -want selector, +got selector:\n%s", tc.reason, diff)
+					}
+					l.Items = tc.items
+					return tc.err
+				},
+			}
+
+			got, err := FindCreated(context.Background(), r, "team-a", local, claim.New(claim.WithGroupVersionKind(kind)))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nFindCreated(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			name := ""
+			if got != nil {
+				name = got.GetName()
+			}
+			if diff := cmp.Diff(tc.want.name, name); diff != "" {
+				t.Errorf("\nReason: %s\nFindCreated(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
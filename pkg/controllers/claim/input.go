@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errGetInputSecret      = "cannot get input secret"
+	errFmtInputAbsent      = "%s %s does not exist in the local cluster"
+	errApplyInputSecret    = "cannot apply input secret"
+	errSealInputSecret     = "cannot encrypt input secret"
+	errGetRemoteInput      = "cannot get input secret"
+	errGetInputConfigMap   = "cannot get input configmap"
+	errApplyInputConfigMap = "cannot apply input configmap"
+)
+
+// An InputSyncerOption configures an InputSyncer.
+type InputSyncerOption func(*InputSyncer)
+
+// WithInputSecretEnvelope specifies how the data of input Secrets is encrypted
+// before they're pushed to the remote cluster.
+func WithInputSecretEnvelope(e SecretEnvelope) InputSyncerOption {
+	return func(s *InputSyncer) {
+		s.envelope = e
+	}
+}
+
+// WithInputOwnerLabels specifies the labels that are added to the inputs that
+// are pushed to the remote cluster, so that the agent can list them where it's
+// only granted access to the objects that carry them.
+func WithInputOwnerLabels(l map[string]string) InputSyncerOption {
+	return func(s *InputSyncer) {
+		s.ownerLabels = l
+	}
+}
+
+// NewInputSyncer returns a new *InputSyncer.
+func NewInputSyncer(local client.Reader, remote client.Client, opts ...InputSyncerOption) *InputSyncer {
+	s := &InputSyncer{local: local, remoteReader: remote, remote: runtimeresource.NewAPIUpdatingApplicator(remote), envelope: NewNopSecretEnvelope()}
+	for _, f := range opts {
+		f(s)
+	}
+	return s
+}
+
+// InputSyncer syncs the Secrets and ConfigMaps that a claim references in its
+// input annotations to the namespace of the remote claim so that they can be
+// consumed in the remote cluster. It resolves them as dependencies of the claim
+// so that they're pushed once the remote namespace is ensured and before the
+// claim itself.
+type InputSyncer struct {
+	local        client.Reader
+	remoteReader client.Reader
+	remote       runtimeresource.Applicator
+	envelope     SecretEnvelope
+	ownerLabels  map[string]string
+}
+
+// Resolve syncs the inputs of the local claim to the remote cluster. It returns
+// an error that satisfies IsWaitingOnDependency if an input doesn't exist in
+// the local cluster.
+func (s *InputSyncer) Resolve(ctx context.Context, local, remote *claim.Unstructured) error {
+	for _, ref := range resource.ParseInputReferences(local.GetAnnotations()[resource.AnnotationKeyInputSecrets]) {
+		ls := &corev1.Secret{}
+		err := s.local.Get(ctx, types.NamespacedName{Namespace: local.GetNamespace(), Name: ref.Name}, ls)
+		if kerrors.IsNotFound(err) {
+			return waitingOnDependency{errors.Errorf(errFmtInputAbsent, "Secret", ref.Name)}
+		}
+		if err != nil {
+			return errors.Wrap(err, localPrefix+errGetInputSecret)
+		}
+		rs, _ := resource.SanitizedDeepCopyObject(ls).(*corev1.Secret)
+		rs.SetNamespace(remote.GetNamespace())
+		rs.SetAnnotations(nil)
+		meta.AddLabels(rs, map[string]string{resource.LabelKeyManagedBy: resource.LabelValueManagedBy})
+		meta.AddLabels(rs, s.ownerLabels)
+		for k := range rs.Data {
+			if !ref.Allows(k) {
+				delete(rs.Data, k)
+			}
+		}
+		// The Secret that's already in the remote cluster is sealed again
+		// only if the data it was sealed from changed.
+		current := &corev1.Secret{}
+		if err := s.remoteReader.Get(ctx, types.NamespacedName{Namespace: rs.GetNamespace(), Name: rs.GetName()}, current); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return errors.Wrap(err, remotePrefix+errGetRemoteInput)
+			}
+			current = nil
+		}
+		if err := s.envelope.Seal(ctx, rs, current); err != nil {
+			return errors.Wrap(err, errSealInputSecret)
+		}
+		if err := s.remote.Apply(ctx, rs); err != nil {
+			return errors.Wrap(err, remotePrefix+errApplyInputSecret)
+		}
+	}
+	for _, ref := range resource.ParseInputReferences(local.GetAnnotations()[resource.AnnotationKeyInputConfigMaps]) {
+		lc := &corev1.ConfigMap{}
+		err := s.local.Get(ctx, types.NamespacedName{Namespace: local.GetNamespace(), Name: ref.Name}, lc)
+		if kerrors.IsNotFound(err) {
+			return waitingOnDependency{errors.Errorf(errFmtInputAbsent, "ConfigMap", ref.Name)}
+		}
+		if err != nil {
+			return errors.Wrap(err, localPrefix+errGetInputConfigMap)
+		}
+		rc, _ := resource.SanitizedDeepCopyObject(lc).(*corev1.ConfigMap)
+		rc.SetNamespace(remote.GetNamespace())
+		rc.SetAnnotations(nil)
+		meta.AddLabels(rc, map[string]string{resource.LabelKeyManagedBy: resource.LabelValueManagedBy})
+		meta.AddLabels(rc, s.ownerLabels)
+		for k := range rc.Data {
+			if !ref.Allows(k) {
+				delete(rc.Data, k)
+			}
+		}
+		for k := range rc.BinaryData {
+			if !ref.Allows(k) {
+				delete(rc.BinaryData, k)
+			}
+		}
+		if err := s.remote.Apply(ctx, rc); err != nil {
+			return errors.Wrap(err, remotePrefix+errApplyInputConfigMap)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Variables that the values of the default labels of a LabelPolicy may refer
+// to.
+const (
+	LabelVariableCluster   = "$(cluster)"
+	LabelVariableNamespace = "$(namespace)"
+)
+
+// A LabelPolicy determines the labels that claims must have when they're
+// pushed to the remote cluster, e.g. to satisfy the admission policies of the
+// remote cluster.
+type LabelPolicy struct {
+	// Required are the keys of the labels that remote claims must have.
+	Required []string
+
+	// Defaults are added to the remote claims that don't have them. Their
+	// values may refer to the name of the local cluster as $(cluster) and to
+	// the namespace of the local claim as $(namespace).
+	Defaults map[string]string
+}
+
+// Enabled returns true if the policy requires or adds any labels.
+func (p LabelPolicy) Enabled() bool {
+	return len(p.Required) > 0 || len(p.Defaults) > 0
+}
+
+// Apply adds the default labels that the supplied remote claim doesn't have,
+// and returns the sorted keys of the required labels that it still doesn't
+// have.
+func (p LabelPolicy) Apply(local, remote metav1.Object, cluster string) []string {
+	l := remote.GetLabels()
+	for k, v := range p.Defaults {
+		if _, ok := l[k]; ok {
+			continue
+		}
+		if l == nil {
+			l = map[string]string{}
+		}
+		l[k] = strings.NewReplacer(LabelVariableCluster, cluster, LabelVariableNamespace, local.GetNamespace()).Replace(v)
+	}
+	remote.SetLabels(l)
+
+	var missing []string
+	for _, k := range p.Required {
+		if _, ok := l[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+func TestLabelPolicyApply(t *testing.T) {
+	type want struct {
+		labels  map[string]string
+		missing []string
+	}
+	cases := map[string]struct {
+		reason string
+		policy LabelPolicy
+		labels map[string]string
+		want   want
+	}{
+		"Disabled": {
+			reason: "Nothing should be added or required by an empty policy",
+			labels: map[string]string{"cool": "very"},
+			want:   want{labels: map[string]string{"cool": "very"}},
+		},
+		"Defaulted": {
+			reason: "Default labels should be added with their variables expanded",
+			policy: LabelPolicy{
+				Required: []string{"cluster", "team"},
+				Defaults: map[string]string{"cluster": LabelVariableCluster, "team": "team-" + LabelVariableNamespace},
+			},
+			want: want{labels: map[string]string{"cluster": "local", "team": "team-coolns"}},
+		},
+		"NotOverridden": {
+			reason: "Labels that the claim already has should not be overridden by the defaults",
+			policy: LabelPolicy{Defaults: map[string]string{"team": "platform"}},
+			labels: map[string]string{"team": "data"},
+			want:   want{labels: map[string]string{"team": "data"}},
+		},
+		"Missing": {
+			reason: "Required labels that are neither set nor defaulted should be returned sorted",
+			policy: LabelPolicy{Required: []string{"team", "cost-center", "cool"}},
+			labels: map[string]string{"cool": "very"},
+			want: want{
+				labels:  map[string]string{"cool": "very"},
+				missing: []string{"cost-center", "team"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			local.SetNamespace("coolns")
+			remote := claim.New()
+			remote.SetLabels(tc.labels)
+			missing := tc.policy.Apply(local, remote, "local")
+			if diff := cmp.Diff(tc.want.missing, missing); diff != "" {
+				t.Errorf("\nReason: %s\np.Apply(...): -want missing, +got missing:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.labels, remote.GetLabels()); diff != "" {
+				t.Errorf("\nReason: %s\np.Apply(...): -want labels, +got labels:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+// Fields of the local claim status that the propagation lag is recorded in.
+const (
+	fieldPathPushedGeneration = "status.agent.pushedGeneration"
+	fieldPathPropagationLag   = "status.agent.propagationLagSeconds"
+)
+
+type generation struct {
+	generation int64
+	seen       time.Time
+	pushed     bool
+}
+
+// NewLagTracker returns a new *LagTracker.
+func NewLagTracker() *LagTracker {
+	return &LagTracker{now: time.Now, seen: map[types.UID]generation{}}
+}
+
+// A LagTracker remembers when each generation of a claim was first seen so
+// that the time it took to push it to the remote cluster can be measured. The
+// first generation of a claim is seen when the claim is created. Generations
+// that were seen before the agent started are measured from its start.
+type LagTracker struct {
+	now func() time.Time
+
+	mu   sync.Mutex
+	seen map[types.UID]generation
+}
+
+// Observe records the generation of the supplied object if it wasn't seen
+// before. Objects without a generation are ignored.
+func (t *LagTracker) Observe(o metav1.Object) {
+	if o.GetGeneration() == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if g, ok := t.seen[o.GetUID()]; ok && g.generation == o.GetGeneration() {
+		return
+	}
+	g := generation{generation: o.GetGeneration(), seen: t.now()}
+	if g.generation == 1 && !o.GetCreationTimestamp().IsZero() {
+		g.seen = o.GetCreationTimestamp().Time
+	}
+	t.seen[o.GetUID()] = g
+}
+
+// Pushed records that the given generation of the supplied object was pushed
+// and returns how long it took since it was seen, or false if it was pushed
+// before or not seen at all. The current generation of the object is
+// considered pushed as well, since any change made to it after the given one
+// was made by the agent with what it pulled from the remote cluster.
+func (t *LagTracker) Pushed(o metav1.Object, pushed int64) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	g, ok := t.seen[o.GetUID()]
+	t.seen[o.GetUID()] = generation{generation: o.GetGeneration(), seen: t.now(), pushed: true}
+	if !ok || g.pushed || g.generation != pushed {
+		return 0, false
+	}
+	return t.now().Sub(g.seen), true
+}
+
+// Forget the supplied object.
+func (t *LagTracker) Forget(o metav1.Object) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.seen, o.GetUID())
+}
+
+// PushedGeneration returns the generation of the supplied claim that was last
+// recorded as pushed to the remote cluster, or zero if none was.
+func PushedGeneration(c *claim.Unstructured) int64 {
+	v, err := fieldpath.Pave(c.GetUnstructured().UnstructuredContent()).GetValue(fieldPathPushedGeneration)
+	if err != nil {
+		return 0
+	}
+	switch g := v.(type) {
+	case int64:
+		return g
+	case float64:
+		return int64(g)
+	default:
+		return 0
+	}
+}
+
+// SetPropagationLag records the generation of the supplied object that was
+// pushed to the remote cluster, and how long it took, in its status.
+func SetPropagationLag(c *claim.Unstructured, pushed int64, lag time.Duration) error {
+	p := fieldpath.Pave(c.GetUnstructured().UnstructuredContent())
+	if err := p.SetValue(fieldPathPushedGeneration, pushed); err != nil {
+		return err
+	}
+	return p.SetValue(fieldPathPropagationLag, lag.Round(time.Millisecond).Seconds())
+}
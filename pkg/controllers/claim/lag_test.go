@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLagTracker(t *testing.T) {
+	created := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	obj := func(gen int64) *metav1.ObjectMeta {
+		return &metav1.ObjectMeta{UID: "cool-uid", Generation: gen, CreationTimestamp: metav1.NewTime(created)}
+	}
+	type want struct {
+		lag time.Duration
+		ok  bool
+	}
+	cases := map[string]struct {
+		reason  string
+		observe []*metav1.ObjectMeta
+		pushed  *metav1.ObjectMeta
+		gen     int64
+		want    want
+	}{
+		"Created": {
+			reason:  "The first generation should be measured from the creation of the object",
+			observe: []*metav1.ObjectMeta{obj(1)},
+			pushed:  obj(1),
+			gen:     1,
+			want:    want{lag: time.Minute, ok: true},
+		},
+		"Updated": {
+			reason:  "Later generations should be measured from when they were first seen",
+			observe: []*metav1.ObjectMeta{obj(2), obj(2)},
+			pushed:  obj(2),
+			gen:     2,
+			want:    want{lag: 0, ok: true},
+		},
+		"NotSeen": {
+			reason: "A generation that wasn't seen cannot be measured",
+			pushed: obj(2),
+			gen:    2,
+			want:   want{},
+		},
+		"NoGeneration": {
+			reason:  "Objects without a generation should not be measured",
+			observe: []*metav1.ObjectMeta{obj(0)},
+			pushed:  obj(0),
+			gen:     0,
+			want:    want{},
+		},
+		"Superseded": {
+			reason:  "A generation should not be measured if a newer one was seen in the meantime",
+			observe: []*metav1.ObjectMeta{obj(2), obj(3)},
+			pushed:  obj(3),
+			gen:     2,
+			want:    want{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			lt := NewLagTracker()
+			lt.now = func() time.Time { return created.Add(time.Minute) }
+			for _, o := range tc.observe {
+				lt.Observe(o)
+			}
+			lag, ok := lt.Pushed(tc.pushed, tc.gen)
+			if diff := cmp.Diff(tc.want, want{lag: lag, ok: ok}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nPushed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if _, ok := lt.Pushed(tc.pushed, tc.gen); ok {
+				t.Errorf("\nReason: %s\nPushed(...): a generation should be measured only once", tc.reason)
+			}
+		})
+	}
+}
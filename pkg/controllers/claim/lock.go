@@ -0,0 +1,162 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LeaseName is the name of the Lease in each remote namespace that is held
+	// by the agent syncing claims to that namespace.
+	LeaseName = "crossplane-agent"
+
+	errGetLease    = "cannot get namespace lease"
+	errCreateLease = "cannot create namespace lease"
+	errUpdateLease = "cannot update namespace lease"
+)
+
+// A NamespaceLocker makes sure that claims in a remote namespace are synced by
+// only one agent.
+type NamespaceLocker interface {
+	// Lock returns true if the namespace is locked by this agent. Otherwise,
+	// it returns the identity of the agent that holds the lock.
+	Lock(ctx context.Context, namespace string) (bool, string, error)
+}
+
+// NamespaceLockFn is used to construct a NamespaceLocker with a bare function.
+type NamespaceLockFn func(ctx context.Context, namespace string) (bool, string, error)
+
+// Lock calls the supplied function.
+func (fn NamespaceLockFn) Lock(ctx context.Context, namespace string) (bool, string, error) {
+	return fn(ctx, namespace)
+}
+
+// NewNopNamespaceLocker returns a NamespaceLocker that always succeeds.
+func NewNopNamespaceLocker() NamespaceLockFn {
+	return func(_ context.Context, _ string) (bool, string, error) { return true, "", nil }
+}
+
+// LeaseDuration returns how long an agent whose claims are synced at the
+// supplied interval holds the Lease of a namespace for. The Lease is renewed
+// only when a claim in its namespace is reconciled, so it has to outlive the
+// gap between two passes, with room to spare for passes that run late. The
+// default sync interval is used if the supplied one is zero.
+func LeaseDuration(syncInterval time.Duration) time.Duration {
+	if syncInterval <= 0 {
+		syncInterval = longWait
+	}
+	return 3 * syncInterval
+}
+
+// NewLeaseLocker returns a new *LeaseLocker.
+func NewLeaseLocker(c client.Client, identity string, duration time.Duration) *LeaseLocker {
+	return &LeaseLocker{
+		client:   c,
+		identity: identity,
+		duration: duration,
+		now:      time.Now,
+		renewed:  map[string]time.Time{},
+	}
+}
+
+// A LeaseLocker locks remote namespaces by holding a Lease in them. A Lease
+// that isn't renewed within its duration can be taken over by another agent.
+type LeaseLocker struct {
+	client   client.Client
+	identity string
+	duration time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	renewed map[string]time.Time
+}
+
+// Lock acquires or renews the Lease in the supplied namespace.
+func (l *LeaseLocker) Lock(ctx context.Context, namespace string) (bool, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The Lease is renewed a few times within its duration rather than in
+	// every reconcile of every claim in the namespace.
+	now := l.now()
+	if t, ok := l.renewed[namespace]; ok && now.Sub(t) < l.duration/3 {
+		return true, "", nil
+	}
+
+	lease := &coordinationv1.Lease{}
+	err := l.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: LeaseName}, lease)
+	if kerrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: LeaseName}}
+		l.hold(lease, now)
+		if err := l.client.Create(ctx, lease); err != nil {
+			return false, "", errors.Wrap(err, errCreateLease)
+		}
+		l.renewed[namespace] = now
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", errors.Wrap(err, errGetLease)
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	if holder != l.identity && holder != "" && !expired(lease, now) {
+		delete(l.renewed, namespace)
+		return false, holder, nil
+	}
+	l.hold(lease, now)
+	if err := l.client.Update(ctx, lease); err != nil {
+		return false, "", errors.Wrap(err, errUpdateLease)
+	}
+	l.renewed[namespace] = now
+	return true, "", nil
+}
+
+func (l *LeaseLocker) hold(lease *coordinationv1.Lease, now time.Time) {
+	seconds := int32(l.duration / time.Second)
+	t := metav1.NewMicroTime(now)
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.identity {
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.HolderIdentity = &l.identity
+		lease.Spec.AcquireTime = &t
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &t
+}
+
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second).Before(now)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestLeaseLockerLock(t *testing.T) {
+	syncInterval := 5 * time.Minute
+	renewed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type want struct {
+		held   bool
+		holder string
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		at     time.Time
+		want   want
+	}{
+		"HeldAcrossSyncGap": {
+			reason: "A Lease should still be held by its agent one sync interval after it was renewed, when the next pass of its claims is due",
+			at:     renewed.Add(syncInterval),
+			want:   want{held: false, holder: "cool-cluster"},
+		},
+		"Expired": {
+			reason: "A Lease that wasn't renewed within its duration should be taken over",
+			at:     renewed.Add(LeaseDuration(syncInterval) + time.Second),
+			want:   want{held: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					// The Lease was last renewed by the other agent, which
+					// holds it for the duration derived from the sync
+					// interval they share.
+					holder, rt := "cool-cluster", metav1.NewMicroTime(renewed)
+					seconds := int32(LeaseDuration(syncInterval) / time.Second)
+					obj.(*coordinationv1.Lease).Spec = coordinationv1.LeaseSpec{HolderIdentity: &holder, RenewTime: &rt, LeaseDurationSeconds: &seconds}
+					return nil
+				},
+				MockUpdate: test.NewMockUpdateFn(nil),
+			}
+			l := NewLeaseLocker(c, "other-cluster", LeaseDuration(syncInterval))
+			l.now = func() time.Time { return tc.at }
+
+			held, holder, err := l.Lock(context.Background(), "cool-namespace")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nl.Lock(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.held, held); diff != "" {
+				t.Errorf("\nReason: %s\nl.Lock(...): -want held, +got held:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.holder, holder); diff != "" {
+				t.Errorf("\nReason: %s\nl.Lock(...): -want holder, +got holder:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
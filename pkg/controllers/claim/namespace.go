@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+const (
+	errGetNamespace        = "cannot get remote namespace"
+	errCreateNamespace     = "cannot create remote namespace"
+	errFmtNsTerminating    = "remote namespace %s is being deleted"
+	errFmtNsMissing        = "remote namespace %s does not exist"
+	errFmtNsNotReplaceable = "remote namespace %s is being deleted and will be created again once it's gone"
+)
+
+// A NamespacePolicy determines what happens when the remote namespace a claim
+// is synced to doesn't exist.
+type NamespacePolicy string
+
+// Namespace policies.
+const (
+	// NamespacePolicyFail stops syncing claims to the namespace until it's
+	// created by someone else.
+	NamespacePolicyFail NamespacePolicy = "fail"
+
+	// NamespacePolicyRecreate creates the namespace.
+	NamespacePolicyRecreate NamespacePolicy = "recreate"
+)
+
+type namespaceUnavailable struct{ error }
+
+// IsNamespaceUnavailable returns true if the supplied error indicates that the
+// remote namespace cannot accept claims for the time being.
+func IsNamespaceUnavailable(err error) bool {
+	_, ok := errors.Cause(err).(namespaceUnavailable)
+	return ok
+}
+
+// IsNamespaceTerminatingError returns true if the supplied error is returned by
+// an api-server that refuses to create objects in a namespace that is being
+// deleted.
+func IsNamespaceTerminatingError(err error) bool {
+	return kerrors.HasStatusCause(errors.Cause(err), corev1.NamespaceTerminatingCause)
+}
+
+// A NamespaceEnsurer makes sure that the remote namespace exists and accepts
+// new objects.
+type NamespaceEnsurer interface {
+	Ensure(ctx context.Context, namespace string) error
+}
+
+// NamespaceEnsureFn is used to construct a NamespaceEnsurer with a bare
+// function.
+type NamespaceEnsureFn func(ctx context.Context, namespace string) error
+
+// Ensure calls the supplied function.
+func (fn NamespaceEnsureFn) Ensure(ctx context.Context, namespace string) error {
+	return fn(ctx, namespace)
+}
+
+// NewNopNamespaceEnsurer returns a NamespaceEnsurer that does nothing.
+func NewNopNamespaceEnsurer() NamespaceEnsureFn {
+	return func(_ context.Context, _ string) error { return nil }
+}
+
+// NewAPINamespaceEnsurer returns a new *APINamespaceEnsurer.
+func NewAPINamespaceEnsurer(c client.Client, p NamespacePolicy) *APINamespaceEnsurer {
+	return &APINamespaceEnsurer{client: c, policy: p}
+}
+
+// An APINamespaceEnsurer checks the remote namespace through the api-server
+// and creates it if the policy allows.
+type APINamespaceEnsurer struct {
+	client client.Client
+	policy NamespacePolicy
+}
+
+// Ensure returns an error that satisfies IsNamespaceUnavailable if the remote
+// namespace is being deleted, or is missing and cannot be created.
+func (e *APINamespaceEnsurer) Ensure(ctx context.Context, namespace string) error {
+	ns := &corev1.Namespace{}
+	err := e.client.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+	switch {
+	case kerrors.IsNotFound(err) && e.policy == NamespacePolicyRecreate:
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		return errors.Wrap(runtimeresource.Ignore(kerrors.IsAlreadyExists, e.client.Create(ctx, ns)), errCreateNamespace)
+	case kerrors.IsNotFound(err):
+		return namespaceUnavailable{errors.Errorf(errFmtNsMissing, namespace)}
+	case err != nil:
+		return errors.Wrap(err, errGetNamespace)
+	case meta.WasDeleted(ns) || ns.Status.Phase == corev1.NamespaceTerminating:
+		if e.policy == NamespacePolicyRecreate {
+			return namespaceUnavailable{errors.Errorf(errFmtNsNotReplaceable, namespace)}
+		}
+		return namespaceUnavailable{errors.Errorf(errFmtNsTerminating, namespace)}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestAPINamespaceEnsurer(t *testing.T) {
+	type want struct {
+		unavailable bool
+		created     bool
+	}
+	cases := map[string]struct {
+		reason string
+		policy NamespacePolicy
+		get    test.MockGetFn
+		want   want
+	}{
+		"Exists": {
+			reason: "A namespace that exists should be available",
+			policy: NamespacePolicyFail,
+			get:    test.NewMockGetFn(nil),
+			want:   want{},
+		},
+		"Terminating": {
+			reason: "A namespace that is being deleted should be unavailable regardless of the policy",
+			policy: NamespacePolicyRecreate,
+			get: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+				obj.(*corev1.Namespace).Status.Phase = corev1.NamespaceTerminating
+				return nil
+			},
+			want: want{unavailable: true},
+		},
+		"MissingFail": {
+			reason: "A missing namespace should be unavailable if it cannot be created",
+			policy: NamespacePolicyFail,
+			get:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+			want:   want{unavailable: true},
+		},
+		"MissingRecreate": {
+			reason: "A missing namespace should be created if the policy allows",
+			policy: NamespacePolicyRecreate,
+			get:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+			want:   want{created: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			created := false
+			c := &test.MockClient{
+				MockGet: tc.get,
+				MockCreate: func(_ context.Context, _ runtime.Object, _ ...client.CreateOption) error {
+					created = true
+					return nil
+				},
+			}
+			err := NewAPINamespaceEnsurer(c, tc.policy).Ensure(context.Background(), "cool")
+			got := want{unavailable: IsNamespaceUnavailable(err), created: created}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nEnsure(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
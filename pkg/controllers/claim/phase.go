@@ -0,0 +1,131 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// A Phase of a reconcile whose duration is recorded.
+type Phase string
+
+// Phases of a reconcile. The gets and the push tell a slow remote api-server
+// apart from a slow local one.
+const (
+	PhaseLocalGet     Phase = "local_get"
+	PhaseRemoteGet    Phase = "remote_get"
+	PhasePush         Phase = "push"
+	PhasePropagate    Phase = "propagate"
+	PhaseStatusUpdate Phase = "status_update"
+)
+
+// Phases are reported in this order.
+var phases = []Phase{PhaseLocalGet, PhaseRemoteGet, PhasePush, PhasePropagate, PhaseStatusUpdate}
+
+// PhaseTimings are the time spent in each phase of a single reconcile. A phase
+// that runs more than once, like a status update that's retried, is summed.
+type PhaseTimings struct {
+	mu sync.Mutex
+	d  map[Phase]time.Duration
+}
+
+// Start timing the supplied phase. The returned function stops it.
+func (t *PhaseTimings) Start(p Phase) func() {
+	if t == nil {
+		return func() {}
+	}
+	started := time.Now()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.d == nil {
+			t.d = map[Phase]time.Duration{}
+		}
+		t.d[p] += time.Since(started)
+	}
+}
+
+// Timings returns the phases that ran and how long they took.
+func (t *PhaseTimings) Timings() map[Phase]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[Phase]time.Duration, len(t.d))
+	for p, d := range t.d {
+		out[p] = d
+	}
+	return out
+}
+
+// KeysAndValues returns the phases that ran and how long they took in the
+// form that's logged.
+func (t *PhaseTimings) KeysAndValues() []interface{} {
+	d := t.Timings()
+	kv := make([]interface{}, 0, 2*len(d))
+	for _, p := range phases {
+		if v, ok := d[p]; ok {
+			kv = append(kv, string(p), v.String())
+		}
+	}
+	return kv
+}
+
+type phaseTimingsKey struct{}
+
+// withPhaseTimings returns a context that the phases of a reconcile are timed
+// in, and the PhaseTimings they're recorded to.
+func withPhaseTimings(ctx context.Context) (context.Context, *PhaseTimings) {
+	t := &PhaseTimings{}
+	return context.WithValue(ctx, phaseTimingsKey{}, t), t
+}
+
+func phaseTimingsFrom(ctx context.Context) *PhaseTimings {
+	t, _ := ctx.Value(phaseTimingsKey{}).(*PhaseTimings)
+	return t
+}
+
+// newTimedClient returns a client.Client whose status writes are timed in the
+// PhaseTimings of the context they're made with, if any.
+func newTimedClient(c client.Client) client.Client {
+	return &timedClient{Client: c}
+}
+
+type timedClient struct {
+	client.Client
+}
+
+func (c *timedClient) Status() client.StatusWriter {
+	return &timedStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type timedStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *timedStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	defer phaseTimingsFrom(ctx).Start(PhaseStatusUpdate)()
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func (w *timedStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	defer phaseTimingsFrom(ctx).Start(PhaseStatusUpdate)()
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestPhaseTimings(t *testing.T) {
+	ctx, timings := withPhaseTimings(context.Background())
+	timings.Start(PhaseRemoteGet)()
+	timings.Start(PhaseLocalGet)()
+
+	c := newTimedClient(&test.MockClient{
+		MockStatusUpdate: func(_ context.Context, _ runtime.Object, _ ...client.UpdateOption) error { return nil },
+	})
+	for i := 0; i < 2; i++ {
+		if err := c.Status().Update(ctx, claim.New()); err != nil {
+			t.Fatalf("Status().Update(...): %s", err)
+		}
+	}
+
+	var got []interface{}
+	for i, kv := range timings.KeysAndValues() {
+		if i%2 == 0 {
+			got = append(got, kv)
+		}
+	}
+	want := []interface{}{string(PhaseLocalGet), string(PhaseRemoteGet), string(PhaseStatusUpdate)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("KeysAndValues(...): phases should be reported once each, in order: -want, +got:\n%s", diff)
+	}
+
+	// Status writes made outside of a reconcile aren't timed.
+	if err := c.Status().Update(context.Background(), claim.New()); err != nil {
+		t.Errorf("Status().Update(...): %s", err)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// DefaultMaxPushedSpecSize is the largest last pushed spec, in bytes, that is
+// recorded on a local claim as it is. Larger ones are recorded as a digest.
+const DefaultMaxPushedSpecSize = 16 * 1024
+
+const (
+	prefixDigest = "sha256:"
+
+	errMarshalSpec     = "cannot marshal spec"
+	errUnmarshalSpec   = "cannot unmarshal last pushed spec"
+	errFmtOnlyDigested = "last pushed spec was too large to be recorded, its digest is %s"
+)
+
+// SetLastPushedSpec records the spec of the supplied remote claim on the
+// supplied local claim as the last one that was pushed. A spec whose JSON is
+// larger than the supplied number of bytes is recorded as its SHA-256 digest
+// so that the local claim doesn't grow past the size limit of api-server.
+func SetLastPushedSpec(local, pushed *claim.Unstructured, limit int) error {
+	v, err := pushedSpec(pushed, limit)
+	if err != nil {
+		return err
+	}
+	resource.SetAnnotation(local, resource.AnnotationKeyLastPushedSpec, v)
+	return nil
+}
+
+// PushedSpecUnchanged returns true if the spec of the supplied remote claim is
+// the one that was last recorded as pushed for the supplied local claim, i.e.
+// the local claim wasn't changed since it was last pushed.
+func PushedSpecUnchanged(local, pushed *claim.Unstructured, limit int) bool {
+	recorded, ok := local.GetAnnotations()[resource.AnnotationKeyLastPushedSpec]
+	if !ok {
+		return false
+	}
+	v, err := pushedSpec(pushed, limit)
+	return err == nil && v == recorded
+}
+
+func pushedSpec(pushed *claim.Unstructured, limit int) (string, error) {
+	b, err := json.Marshal(pushed.Object["spec"])
+	if err != nil {
+		return "", errors.Wrap(err, errMarshalSpec)
+	}
+	if len(b) > limit {
+		sum := sha256.Sum256(b)
+		return prefixDigest + hex.EncodeToString(sum[:]), nil
+	}
+	return string(b), nil
+}
+
+// LastPushedSpec returns the spec that was last pushed for the supplied local
+// claim, and false if none was recorded. An error is returned if only its
+// digest was recorded.
+func LastPushedSpec(local metav1.Object) (map[string]interface{}, bool, error) {
+	v, ok := local.GetAnnotations()[resource.AnnotationKeyLastPushedSpec]
+	if !ok {
+		return nil, false, nil
+	}
+	if strings.HasPrefix(v, prefixDigest) {
+		return nil, true, errors.Errorf(errFmtOnlyDigested, v)
+	}
+	spec := map[string]interface{}{}
+	return spec, true, errors.Wrap(json.Unmarshal([]byte(v), &spec), errUnmarshalSpec)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+func TestLastPushedSpec(t *testing.T) {
+	spec := map[string]interface{}{"engine": "mysql", "storageGB": float64(20)}
+	type want struct {
+		spec  map[string]interface{}
+		found bool
+		err   bool
+	}
+	cases := map[string]struct {
+		reason string
+		set    bool
+		limit  int
+		want   want
+	}{
+		"NotRecorded": {
+			reason: "Nothing should be found if no spec was recorded",
+		},
+		"Recorded": {
+			reason: "The recorded spec should be returned as it was pushed",
+			set:    true,
+			limit:  DefaultMaxPushedSpecSize,
+			want:   want{spec: spec, found: true},
+		},
+		"TooLarge": {
+			reason: "Only the digest of a spec that is too large should be recorded",
+			set:    true,
+			limit:  8,
+			want:   want{found: true, err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			local := claim.New()
+			if tc.set {
+				pushed := claim.New()
+				pushed.Object["spec"] = spec
+				if err := SetLastPushedSpec(local, pushed, tc.limit); err != nil {
+					t.Fatalf("SetLastPushedSpec(...): %s", err)
+				}
+			}
+			got, found, err := LastPushedSpec(local)
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("\nReason: %s\nLastPushedSpec(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.found, found); diff != "" {
+				t.Errorf("\nReason: %s\nLastPushedSpec(...): -want found, +got found:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.spec, got); diff != "" {
+				t.Errorf("\nReason: %s\nLastPushedSpec(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+const errListQuotas = "cannot list resource quotas"
+
+// A QuotaChecker tells, before a remote claim is created, which quotas of its
+// remote namespace creating it would exceed.
+type QuotaChecker interface {
+	WouldExceed(ctx context.Context, remote *claim.Unstructured) ([]string, error)
+}
+
+// QuotaCheckFn is used to construct a QuotaChecker with a bare function.
+type QuotaCheckFn func(ctx context.Context, remote *claim.Unstructured) ([]string, error)
+
+// WouldExceed calls the supplied function.
+func (fn QuotaCheckFn) WouldExceed(ctx context.Context, remote *claim.Unstructured) ([]string, error) {
+	return fn(ctx, remote)
+}
+
+// NewNopQuotaChecker returns a QuotaChecker that never finds a quota that
+// would be exceeded.
+func NewNopQuotaChecker() QuotaCheckFn {
+	return func(_ context.Context, _ *claim.Unstructured) ([]string, error) { return nil, nil }
+}
+
+// NewAPIQuotaChecker returns a new *APIQuotaChecker that finds the CRD of
+// claims through the supplied local reader and reads the quotas through the
+// supplied remote one.
+func NewAPIQuotaChecker(local, remote client.Reader) *APIQuotaChecker {
+	return &APIQuotaChecker{local: local, remote: remote}
+}
+
+// An APIQuotaChecker checks the object count quotas of the kind of a claim,
+// i.e. count/<plural>.<group>, in its remote namespace. Quotas that are scoped
+// are not checked since whether they apply to the claim cannot be told.
+type APIQuotaChecker struct {
+	local  client.Reader
+	remote client.Reader
+}
+
+// WouldExceed returns the quotas of the namespace of the supplied remote claim
+// that creating it would exceed, along with their usage.
+func (q *APIQuotaChecker) WouldExceed(ctx context.Context, remote *claim.Unstructured) ([]string, error) {
+	gvk := remote.GetObjectKind().GroupVersionKind()
+	crd, err := findCRD(ctx, q.local, gvk.GroupKind())
+	if err != nil {
+		return nil, err
+	}
+	l := &corev1.ResourceQuotaList{}
+	if err := q.remote.List(ctx, l, client.InNamespace(remote.GetNamespace())); err != nil {
+		return nil, errors.Wrap(err, remotePrefix+errListQuotas)
+	}
+	name := corev1.ResourceName(fmt.Sprintf("count/%s.%s", crd.Spec.Names.Plural, crd.Spec.Group))
+	var exceeded []string
+	for _, rq := range l.Items {
+		if len(rq.Spec.Scopes) > 0 || rq.Spec.ScopeSelector != nil {
+			continue
+		}
+		hard, ok := rq.Status.Hard[name]
+		if !ok {
+			continue
+		}
+		used := rq.Status.Used[name]
+		if used.Value()+1 > hard.Value() {
+			exceeded = append(exceeded, fmt.Sprintf("%s: %s, used %d of %d", rq.GetName(), name, used.Value(), hard.Value()))
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestAPIQuotaChecker(t *testing.T) {
+	errBoom := errors.New("boom")
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	local := &test.MockClient{MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+		crd := v1beta1.CustomResourceDefinition{}
+		crd.SetName("mysqlinstances.example.org")
+		crd.Spec.Group = gvk.Group
+		crd.Spec.Names.Kind = gvk.Kind
+		crd.Spec.Names.Plural = "mysqlinstances"
+		obj.(*v1beta1.CustomResourceDefinitionList).Items = []v1beta1.CustomResourceDefinition{crd}
+		return nil
+	}}
+	count := corev1.ResourceName("count/mysqlinstances.example.org")
+	quota := func(name string, used, hard int64, scoped bool) corev1.ResourceQuota {
+		q := corev1.ResourceQuota{}
+		q.SetName(name)
+		q.Status.Hard = corev1.ResourceList{count: *kresource.NewQuantity(hard, kresource.DecimalSI)}
+		q.Status.Used = corev1.ResourceList{count: *kresource.NewQuantity(used, kresource.DecimalSI)}
+		if scoped {
+			q.Spec.Scopes = []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}
+		}
+		return q
+	}
+	type want struct {
+		exceeded []string
+		err      error
+	}
+	cases := map[string]struct {
+		reason string
+		quotas []corev1.ResourceQuota
+		err    error
+		want   want
+	}{
+		"WithinQuota": {
+			reason: "No quota should be reported if the claim fits in all of them",
+			quotas: []corev1.ResourceQuota{quota("claims", 9, 10, false)},
+		},
+		"WouldExceed": {
+			reason: "Quotas whose object count of the kind of the claim is used up should be reported",
+			quotas: []corev1.ResourceQuota{quota("claims", 10, 10, false), quota("others", 1, 10, false)},
+			want:   want{exceeded: []string{"claims: count/mysqlinstances.example.org, used 10 of 10"}},
+		},
+		"Scoped": {
+			reason: "Scoped quotas should not be checked",
+			quotas: []corev1.ResourceQuota{quota("scoped", 10, 10, true)},
+		},
+		"ListError": {
+			reason: "Errors listing the quotas should be returned",
+			err:    errBoom,
+			want:   want{err: errors.Wrap(errBoom, remotePrefix+errListQuotas)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			remote := &test.MockClient{MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+				obj.(*corev1.ResourceQuotaList).Items = tc.quotas
+				return tc.err
+			}}
+			c := claim.New(claim.WithGroupVersionKind(gvk))
+			c.SetNamespace("cool")
+			got, err := NewAPIQuotaChecker(local, remote).WouldExceed(context.Background(), c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nWouldExceed(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.exceeded, got); diff != "" {
+				t.Errorf("\nReason: %s\nWouldExceed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -18,11 +18,16 @@ package claim
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -35,7 +40,13 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured"
 	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
 
+	"github.com/crossplane/agent/pkg/metrics"
+	"github.com/crossplane/agent/pkg/onboarding"
+	agentremote "github.com/crossplane/agent/pkg/remote"
+	"github.com/crossplane/agent/pkg/requeue"
 	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/suspend"
+	"github.com/crossplane/agent/pkg/warning"
 )
 
 const (
@@ -44,8 +55,6 @@ const (
 	shortWait = 30 * time.Second
 	tinyWait  = 5 * time.Second
 
-	finalizer = "agent.crossplane.io/sync"
-
 	localPrefix  = "local cluster: "
 	remotePrefix = "remote cluster: "
 
@@ -54,14 +63,59 @@ const (
 	errApplyClaim        = "cannot apply claim"
 	errPush              = "cannot run push propagator"
 	errPull              = "cannot run pull propagator"
+	errFmtPropagatorStep = "cannot run %s propagator"
 	errUpdateClaim       = "cannot update claim"
 	errStatusUpdateClaim = "cannot update status of claim"
 	errRemoveFinalizer   = "cannot remove finalizer"
 	errAddFinalizer      = "cannot add finalizer"
 	errGetSecret         = "cannot get secret"
 	errApplySecret       = "cannot apply secret"
+	errOpenSecret        = "cannot decrypt connection secret"
+	errCheckStaleness    = "cannot check staleness of definitions"
+	errMeasureSize       = "cannot measure the serialized size of claim"
+	errFmtTooLarge       = "serialized claim is %d bytes, which exceeds the limit of %d bytes"
+	errFmtRegressed      = "resource version of remote claim went back from %s to %s"
+	errFmtReplaced       = "remote claim was replaced out-of-band, its uid changed from %s to %s"
+	errFmtRemoteExists   = "remote claim with uid %s already exists and was not created by the agent for this claim (%s)"
+	errFmtDrifted        = "remote claim was changed out-of-band, pushing the desired spec again: %s"
+	errFmtMissingLabels  = "claim is missing the labels required by the remote cluster: %s"
+	errValidateSchema    = "cannot validate claim against the schema of the remote cluster"
+	errFmtSchemaInvalid  = "claim doesn't conform to the schema of the remote cluster: %s"
+	errCheckQuota        = "cannot check the quotas of the remote namespace"
+	errAdmit             = "cannot get the verdict of the admission gate"
+	errFmtRejected       = "claim is rejected by the admission gate: %s"
+	errFmtQuotaExceeded  = "creating the claim would exceed the quotas of the remote namespace: %s"
+	errDeleteReplaced    = "cannot delete replaced remote claim"
+	errLiveGet           = "cannot get claim bypassing the cache"
+	errCheckSuspended    = "cannot check whether the agent is suspended"
+	errWaitSync          = "cannot wait for a sync slot"
+	errLockNamespace     = "cannot lock namespace"
+	errFmtLocked         = "remote namespace is synced by another agent: %s"
+	errFmtFenced         = "remote claim was written by a newer agent with fencing token %d, this agent has %d"
+	errEnsureNamespace   = "cannot ensure remote namespace"
+	errResolveDependency = "cannot resolve dependencies"
+	errDeleteExpired     = "cannot delete expired claim"
+	errFmtExpiring       = "claim expires at %s"
+	errReleaseRemote     = "cannot remove finalizer of claim"
+	errAckDeletion       = "cannot acknowledge deletion of remote claim"
+	errFmtRemoteDeleted  = "remote claim with uid %s is being deleted out-of-band"
+	msgFmtSynced         = "Synced generation %d to the remote cluster"
 )
 
+// Finalizer is added to local claims to hold their deletion until their remote
+// claims are deleted.
+const Finalizer = "agent.crossplane.io/sync"
+
+// RemoteFinalizer is added to remote claims, if enabled, to hold their
+// deletion by anyone other than the Reconciler until it's observed and
+// acknowledged on the local claim.
+const RemoteFinalizer = "agent.crossplane.io/remote"
+
+// DefaultMaxObjectSize is the largest serialized claim, in bytes, that will be
+// pushed to the remote cluster unless configured otherwise. It matches the
+// default request size limit of etcd.
+const DefaultMaxObjectSize = 1536 * 1024
+
 // Event reasons.
 const (
 	reasonCannotGetFromRemote   event.Reason = "CannotGetFromRemote"
@@ -71,6 +125,48 @@ const (
 	reasonCannotApply           event.Reason = "CannotApply"
 	reasonCannotPropagate       event.Reason = "CannotPropagate"
 	reasonCannotDelete          event.Reason = "CannotDelete"
+	reasonObjectTooLarge        event.Reason = "ObjectTooLarge"
+	reasonCanaryFailed          event.Reason = "CanaryFailed"
+	reasonRemoteRestored        event.Reason = "RemoteRestored"
+	reasonRemoteReplaced        event.Reason = "RemoteReplaced"
+	reasonRemoteExists          event.Reason = "RemoteExists"
+	reasonNamespaceLocked       event.Reason = "NamespaceLocked"
+	reasonFenced                event.Reason = "Fenced"
+	reasonNamespaceUnavailable  event.Reason = "RemoteNamespaceUnavailable"
+	reasonWaitingOnDependency   event.Reason = "WaitingOnDependency"
+	reasonExpiring              event.Reason = "Expiring"
+	reasonExpired               event.Reason = "Expired"
+	reasonRelocated             event.Reason = "Relocated"
+	reasonAPIWarning            event.Reason = "APIWarning"
+	reasonRemoteDeleted         event.Reason = "RemoteDeleted"
+	reasonDeletionRequested     event.Reason = "RemoteDeletionRequested"
+	reasonConflict              event.Reason = "Conflict"
+	reasonSynced                event.Reason = "Synced"
+	reasonCreatedDespiteError   event.Reason = "CreatedDespiteError"
+	reasonRemoteDrifted         event.Reason = "RemoteDrifted"
+	reasonMissingLabels         event.Reason = "MissingRequiredLabels"
+	reasonSchemaInvalid         event.Reason = "SchemaInvalid"
+	reasonQuotaWouldExceed      event.Reason = "QuotaWouldExceed"
+	reasonClaimRejected         event.Reason = "ClaimRejected"
+)
+
+// A UIDPolicy determines what the Reconciler does when the remote claim is
+// deleted and created again by someone other than the Reconciler, i.e. when
+// its UID is different than the one that was recorded.
+type UIDPolicy string
+
+// UID policies.
+const (
+	// UIDPolicyAdopt syncs to the new remote claim as if it was the old one.
+	UIDPolicyAdopt UIDPolicy = "adopt"
+
+	// UIDPolicyAlarm stops syncing the claim until the recorded UID is
+	// removed from the local claim by the user.
+	UIDPolicyAlarm UIDPolicy = "alarm"
+
+	// UIDPolicyRecreate deletes the new remote claim so that it's created
+	// again from the local claim.
+	UIDPolicyRecreate UIDPolicy = "recreate"
 )
 
 // WithLogger specifies how the Reconciler should log messages.
@@ -94,6 +190,14 @@ func WithFinalizer(f runtimeresource.Finalizer) ReconcilerOption {
 	}
 }
 
+// WithConfigurator specifies how the Reconciler should configure the remote
+// claim before it's pushed.
+func WithConfigurator(c Configurator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.Configurator = c
+	}
+}
+
 // WithPropagator specifies how the Reconciler should propagate values and objects
 // between clusters.
 func WithPropagator(p Propagator) ReconcilerOption {
@@ -102,6 +206,388 @@ func WithPropagator(p Propagator) ReconcilerOption {
 	}
 }
 
+// WithAdditionalPropagators specifies Propagators that are run, in order,
+// after the ones of the Reconciler, including the one that's set with
+// WithPropagator. They're usually NamedPropagators.
+func WithAdditionalPropagators(p ...Propagator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.propagators = append(r.propagators, p...)
+	}
+}
+
+// WithStatusPropagator specifies how the Reconciler should pull the status of
+// remote claims onto local claims. It's ignored if the whole chain of
+// Propagators is replaced by WithPropagator.
+func WithStatusPropagator(p Propagator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.status = p
+	}
+}
+
+// WithConditionMapping specifies the types the conditions of remote claims are
+// pulled onto local claims with. It's ignored if the StatusPropagator is
+// replaced by WithStatusPropagator.
+func WithConditionMapping(m ConditionMapping) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.conditionMapping = m
+	}
+}
+
+// WithStatusPolicies specifies which status fields of remote claims are
+// pulled onto local claims, by kind. It's ignored if the StatusPropagator is
+// replaced by WithStatusPropagator.
+func WithStatusPolicies(t StatusPolicyTable) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.statusPolicies = t
+	}
+}
+
+// WithSyncRecorder specifies the SyncRecorder that records the claims every
+// sync reads and pushes. Syncs are not recorded by default.
+func WithSyncRecorder(sr SyncRecorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.syncRecorder = sr
+	}
+}
+
+// WithRemoteFinalizer makes the Reconciler add RemoteFinalizer to the remote
+// claims it pushes. A remote claim that is deleted by someone else is then
+// held until its final status is pulled onto the local claim, after which it's
+// let go and created again.
+func WithRemoteFinalizer() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.remoteFinalizer = true
+	}
+}
+
+// WithLastPushedSpec makes the Reconciler record the spec it last pushed on
+// local claims, or its digest if it's larger than the supplied number of bytes.
+func WithLastPushedSpec(limit int) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.pushedSpecSize = limit
+	}
+}
+
+// WithDriftDetection makes the Reconciler compare the spec it pushes with the
+// one the remote claim has, and report the fields that were changed out-of-band
+// before pushing the desired spec again. The last pushed spec is recorded, as
+// WithLastPushedSpec does, since it's what tells a change made to the remote
+// claim apart from one made to the local claim.
+func WithDriftDetection() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.detectDrift = true
+		if r.pushedSpecSize == 0 {
+			r.pushedSpecSize = DefaultMaxPushedSpecSize
+		}
+	}
+}
+
+// WithMaxObjectSize specifies the largest serialized claim, in bytes, that the
+// Reconciler will push to the remote cluster. Zero disables the check.
+func WithMaxObjectSize(bytes int) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.maxObjectSize = bytes
+	}
+}
+
+// WithCanaryValidator specifies how the Reconciler should validate the remote
+// claim before it's pushed to the remote cluster.
+func WithCanaryValidator(v CanaryValidator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.canary = v
+	}
+}
+
+// WithSchemaValidator specifies how the Reconciler should validate the remote
+// claim against the schema of the remote cluster before it's pushed. Claims
+// are not validated by default.
+func WithSchemaValidator(v SchemaValidator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.schema = v
+	}
+}
+
+// WithAdmissionGate specifies the AdmissionGate that decides whether a claim
+// may be pushed to the remote cluster. Everything is admitted by default.
+func WithAdmissionGate(g AdmissionGate) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.admission = g
+	}
+}
+
+// WithQuotaChecker specifies how the Reconciler should tell whether creating a
+// remote claim would exceed the quotas of its remote namespace, in which case
+// it's not created. Quotas are not checked by default.
+func WithQuotaChecker(q QuotaChecker) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.quota = q
+	}
+}
+
+// WithSuspendSwitch specifies the Switch that decides whether the agent is
+// suspended, in which case nothing is pushed to or deleted from the remote
+// cluster. The agent is never suspended by default.
+func WithSuspendSwitch(s suspend.Switch) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.suspension = s
+	}
+}
+
+// WithSyncObserver adds a SyncObserver that will be told about the claim at the
+// end of every reconcile.
+func WithSyncObserver(o SyncObserver) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.observers = append(r.observers, o)
+	}
+}
+
+// WithResyncTrigger specifies the ResyncTrigger the Reconciler should use to
+// start a full verification resync when it detects that the remote cluster was
+// restored from a backup. It should be shared by all claim Reconcilers.
+func WithResyncTrigger(t *ResyncTrigger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.resync = t
+	}
+}
+
+// WithResyncRequest specifies how the Reconciler tells whether a full resync of
+// a claim was requested by a user.
+func WithResyncRequest(fn ResyncRequestFn) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.resyncRequest = fn
+	}
+}
+
+// WithClusterName specifies the name of the local cluster that remote claims
+// are marked as synced from.
+func WithClusterName(name string) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.clusterName = name
+	}
+}
+
+// WithOwnerLabels specifies the labels that are added to remote claims, so
+// that the agent can list them in remote clusters where it's only granted
+// access to the objects that carry them.
+func WithOwnerLabels(l map[string]string) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.ownerLabels = l
+	}
+}
+
+// WithLabelPolicy specifies the labels that remote claims must have, and the
+// ones that are added to them if they don't. Claims that don't have the
+// required labels are not pushed.
+func WithLabelPolicy(p LabelPolicy) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.labelPolicy = p
+	}
+}
+
+// WithRemoteUIDPolicy specifies what the Reconciler should do when the remote
+// claim is replaced out-of-band.
+func WithRemoteUIDPolicy(p UIDPolicy) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.uidPolicy = p
+	}
+}
+
+// WithLiveReader specifies the client.Reader that the Reconciler uses to read
+// the local claim bypassing any cache before it deletes the remote claim.
+func WithLiveReader(lr client.Reader) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.live = lr
+	}
+}
+
+// WithNamespaceLocker specifies how the Reconciler should make sure that it's
+// the only one syncing claims to a remote namespace.
+func WithNamespaceLocker(l NamespaceLocker) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.locker = l
+	}
+}
+
+// WithFencingToken specifies the source of the fencing token the Reconciler
+// marks remote claims with. The Reconciler doesn't write to remote claims that
+// are marked with a greater token, nor to any until it has a token.
+func WithFencingToken(s FencingTokenSource) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.fencing = s
+	}
+}
+
+// WithNamespaceEnsurer specifies how the Reconciler should make sure that the
+// remote namespace can accept the claim before creating it.
+func WithNamespaceEnsurer(e NamespaceEnsurer) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.namespaces = e
+	}
+}
+
+// WithApprovalRequired makes the Reconciler wait for claims to be approved
+// before they're pushed to the remote cluster for the first time.
+func WithApprovalRequired() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.requireApproval = true
+	}
+}
+
+// WithNamespaceMapper specifies which remote namespace the claims in a local
+// namespace should be synced to.
+func WithNamespaceMapper(m NamespaceMapper) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.mapper = m
+	}
+}
+
+// WithNameMapper specifies the name of the remote claim a local claim should
+// be synced to. Claims are synced to remote claims with the same name by
+// default. Copies of claims in the remote clusters they're fanned out to keep
+// the name of the local claim.
+func WithNameMapper(m NameMapper) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.names = m
+	}
+}
+
+// WithSyncThrottle specifies the SyncThrottle that limits how many claims
+// start syncing against the remote cluster per second. It should be shared by
+// all claim Reconcilers. A *StatusScheduler can be used as one.
+func WithSyncThrottle(t SyncThrottle) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.throttle = t
+	}
+}
+
+// WithDependencyResolver specifies how the Reconciler should make sure that
+// everything the claim refers to exists in the remote cluster before it's
+// pushed.
+func WithDependencyResolver(d DependencyResolver) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.dependencies = d
+	}
+}
+
+// WithPropagationMetrics specifies the metrics the Reconciler should export the
+// propagation lag of claims, and the time spent in each phase of a sync, to.
+func WithPropagationMetrics(m *metrics.Propagation) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.metrics = m
+	}
+}
+
+// WithStatusScheduler specifies the StatusScheduler that staggers the status
+// writes of the Reconciler. It should be shared by all claim Reconcilers.
+func WithStatusScheduler(s *StatusScheduler) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.scheduler = s
+	}
+}
+
+// WithVersionTable specifies the VersionTable that tells the version, and the
+// group, claims are read and written at in the remote cluster when it's
+// different than the one served in the local cluster.
+func WithVersionTable(t VersionTable) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.versions = t
+	}
+}
+
+// WithSecretEnvelope specifies how the connection secrets of remote claims that
+// were sealed in the remote cluster are decrypted before they're applied in
+// the local cluster.
+func WithSecretEnvelope(e SecretEnvelope) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.envelope = e
+	}
+}
+
+// A DefinitionsGate decides whether the definitions claims are pushed against
+// are too stale for new claims to be pushed.
+type DefinitionsGate interface {
+	// Stale returns true and the reason if new claims should be held.
+	Stale(ctx context.Context) (bool, string, error)
+}
+
+// WithDefinitionsGate specifies a DefinitionsGate that holds new claims while
+// the definitions are stale.
+func WithDefinitionsGate(g DefinitionsGate) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.definitions = g
+	}
+}
+
+// WithFanOut specifies the remote clusters, other than the one the Reconciler
+// syncs with, that claims may be propagated to.
+func WithFanOut(f *FanOut) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.fanOut = f
+	}
+}
+
+// WithSyncInterval specifies how long to wait before syncing a claim again when
+// nothing about it changed, or when it's blocked on something the Reconciler
+// can't fix, like a pending approval.
+func WithSyncInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.syncInterval = d
+	}
+}
+
+// WithErrorRetryInterval specifies how long to wait before retrying a claim
+// that failed to sync. The wait starts at base and doubles with each
+// consecutive failure of the claim, up to ceiling, with jitter so that claims
+// that failed together aren't all retried together.
+func WithErrorRetryInterval(base, ceiling time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.backoff = requeue.NewBackoff(base, ceiling, requeue.DefaultJitter)
+	}
+}
+
+// WithConnectivity specifies how the Reconciler tells whether the agent is
+// disconnected from the remote cluster, in which case claims only report that
+// they're disconnected, without anything being written to the remote cluster,
+// until it's reachable again.
+func WithConnectivity(c Connectivity) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.connectivity = c
+	}
+}
+
+// WithClaimSelector specifies which local claims are synced to the remote
+// cluster. Claims that are not selected are left alone, unless they were
+// synced before, in which case they keep being synced until they're deleted so
+// that their remote claims aren't orphaned.
+func WithClaimSelector(s *ClaimSelector) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.selector = s
+	}
+}
+
+// WithRemoteRouting makes the Reconciler sync only the local claims that are
+// routed to the remote cluster with the supplied name among the supplied
+// additional remote clusters, per RemoteOf. An empty name is the default
+// remote cluster. Claims that are routed elsewhere are left alone, once their
+// remote claim in the remote cluster of the Reconciler, if any, is deleted.
+// Claims that are labelled with a remote cluster that's not supplied are held
+// by the Reconciler of the default one.
+func WithRemoteRouting(name string, remotes []string) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.remoteName = name
+		r.remotes = remotes
+	}
+}
+
+// WithOnboardingThrottle specifies an OnboardingThrottle that spreads the
+// first sync of the claims that existed before the agent was started over
+// time.
+func WithOnboardingThrottle(t OnboardingThrottle) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.onboarding = t
+	}
+}
+
 // ReconcilerOption is used to configure *Reconciler.
 type ReconcilerOption func(*Reconciler)
 
@@ -118,25 +604,75 @@ func NewReconciler(mgr manager.Manager, remoteClient client.Client, gvk schema.G
 		Client:     rc,
 		Applicator: runtimeresource.NewAPIPatchingApplicator(rc),
 	}
+	csp := NewConnectionSecretPropagator(lca, rca)
 	r := &Reconciler{
-		mgr:          mgr,
-		local:        lca,
-		remote:       rca,
-		newInstance:  ni,
-		log:          logging.NewNopLogger(),
-		finalizer:    runtimeresource.NewAPIFinalizer(lc, finalizer),
-		Configurator: NewDefaultConfigurator(),
-		Propagator: NewPropagatorChain(
-			NewLateInitializer(lc),
-			NewStatusPropagator(),
-			NewConnectionSecretPropagator(lca, rca),
-		),
-		record: event.NewNopRecorder(),
+		mgr:           mgr,
+		gvk:           gvk,
+		local:         lca,
+		remote:        rca,
+		newInstance:   ni,
+		log:           logging.NewNopLogger(),
+		finalizer:     runtimeresource.NewAPIFinalizer(lc, Finalizer),
+		Configurator:  NewDefaultConfigurator(),
+		record:        event.NewNopRecorder(),
+		maxObjectSize: DefaultMaxObjectSize,
+		canary:        NewNopCanaryValidator(),
+		schema:        NewNopSchemaValidator(),
+		quota:         NewNopQuotaChecker(),
+		admission:     NewNopAdmissionGate(),
+		suspension:    suspend.NewNopSwitch(),
+		resync:        NewResyncTrigger(),
+		uidPolicy:     UIDPolicyAlarm,
+		live:          lc,
+		locker:        NewNopNamespaceLocker(),
+		namespaces:    NewNopNamespaceEnsurer(),
+		dependencies:  NewNopDependencyResolver(),
+		mapper:        NamespaceMap(nil),
+		names:         SameName{},
+		throttle:      NewNopSyncThrottle(),
+		lag:           NewLagTracker(),
+		syncInterval:  longWait,
+		backoff:       requeue.NewBackoff(shortWait, shortWait, 0),
 	}
+	r.newRemoteInstance = ni
+	r.resyncRequest = NewClaimResyncRequest()
 
 	for _, f := range opts {
 		f(r)
 	}
+	if r.status == nil {
+		o := []StatusPropagatorOption{WithStatusPolicy(r.statusPolicies.Lookup(gvk.GroupKind()))}
+		if r.conditionMapping != nil {
+			o = append(o, WithMappedConditions(r.conditionMapping))
+		}
+		r.status = NewStatusPropagator(o...)
+	}
+	if r.Propagator == nil {
+		r.Propagator = NewPropagatorChain(
+			NewNamedPropagator("late initialization", NewLateInitializer(lc)),
+			NewNamedPropagator("status", r.status),
+			NewNamedPropagator("connection secret", csp),
+			NewNamedPropagator("resolved defaults", NewResolvedDefaultsPropagator()),
+		)
+	}
+	if r.envelope != nil {
+		csp.envelope = r.envelope
+	}
+	if r.fanOut != nil {
+		r.Propagator = NewPropagatorChain(r.Propagator, NewNamedPropagator("fan out", r.fanOut))
+	}
+	if len(r.propagators) > 0 {
+		r.Propagator = NewPropagatorChain(append([]Propagator{r.Propagator}, r.propagators...)...)
+	}
+	r.local.Client = newTimedClient(r.local.Client)
+	if r.scheduler != nil {
+		r.local.Client = NewStaggeredClient(r.local.Client, r.scheduler)
+	}
+	if c, ok := r.versions.Lookup(gvk); ok {
+		rgvk := c.RemoteGroupVersionKind(gvk)
+		r.conversion = &c
+		r.newRemoteInstance = func() *claim.Unstructured { return claim.New(claim.WithGroupVersionKind(rgvk)) }
+	}
 	return r
 }
 
@@ -150,14 +686,84 @@ type Propagator interface {
 	Propagate(ctx context.Context, local, remote *claim.Unstructured) error
 }
 
+// Connectivity tells whether the agent is disconnected from the remote cluster,
+// i.e. it has been unreachable for a while, since when, and when it's probed
+// next.
+type Connectivity interface {
+	Disconnected() (bool, time.Time, time.Time)
+}
+
+// An OnboardingThrottle tells whether a claim that was never synced may be
+// synced now, and otherwise how long it should wait, and how far the
+// onboarding of the claims that existed before the agent was started got.
+type OnboardingThrottle interface {
+	Admit(o metav1.Object) (bool, time.Duration)
+	Progress() onboarding.Progress
+}
+
+// SyncObserver is told about the local claim at the end of every reconcile, at
+// which point its AgentSynced condition reflects the result of the sync.
+type SyncObserver interface {
+	ObserveSync(ctx context.Context, c *claim.Unstructured)
+}
+
 // Reconciler syncs the given claim instance from local cluster to remote
 // cluster and fetches its connection secret to local cluster if it's available.
 type Reconciler struct {
 	mgr    ctrl.Manager
 	local  runtimeresource.ClientApplicator
 	remote runtimeresource.ClientApplicator
+	live   client.Reader
+
+	gvk               schema.GroupVersionKind
+	newInstance       func() *claim.Unstructured
+	newRemoteInstance func() *claim.Unstructured
 
-	newInstance func() *claim.Unstructured
+	maxObjectSize int
+	canary        CanaryValidator
+	schema        SchemaValidator
+	quota         QuotaChecker
+	admission     AdmissionGate
+	suspension    suspend.Switch
+	observers     SyncObserverChain
+	resync        *ResyncTrigger
+	resyncRequest ResyncRequestFn
+	uidPolicy     UIDPolicy
+	locker        NamespaceLocker
+	fencing       FencingTokenSource
+	clusterName   string
+	ownerLabels   map[string]string
+	labelPolicy   LabelPolicy
+	namespaces    NamespaceEnsurer
+	dependencies  DependencyResolver
+	mapper        NamespaceMapper
+	names         NameMapper
+	throttle      SyncThrottle
+	lag           *LagTracker
+	metrics       *metrics.Propagation
+	scheduler     *StatusScheduler
+	versions      VersionTable
+	conversion    *VersionConversion
+	envelope      SecretEnvelope
+	definitions   DefinitionsGate
+	fanOut        *FanOut
+	status        Propagator
+	propagators   []Propagator
+	syncRecorder  SyncRecorder
+	syncInterval  time.Duration
+	backoff       *requeue.Backoff
+	connectivity  Connectivity
+	selector      *ClaimSelector
+	onboarding    OnboardingThrottle
+	remoteName    string
+	remotes       []string
+
+	statusPolicies   StatusPolicyTable
+	conditionMapping ConditionMapping
+	requireApproval  bool
+	remoteFinalizer  bool
+	pushedSpecSize   int
+	detectDrift      bool
 
 	finalizer runtimeresource.Finalizer
 	Configurator
@@ -169,105 +775,903 @@ type Reconciler struct {
 
 // Reconcile watches the given type and does necessary sync operations.
 func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) { // nolint:gocyclo
-	log := r.log.WithValues("request", req)
+	log := r.log.WithValues("request", req, "gvk", r.gvk.String())
 	log.Debug("Reconciling")
+	retry := r.backoff.Wait(req)
+
+	// Every path that retries after the backoff, rather than after a fixed
+	// wait, counts as a failure so that the wait keeps growing while the
+	// claim can't be synced, whatever the reason.
+	backingOff := false
+	backOff := func() reconcile.Result {
+		backingOff = true
+		return reconcile.Result{RequeueAfter: retry}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	// The time spent in each phase tells a slow remote api-server apart from
+	// a slow local one.
+	ctx, timings := withPhaseTimings(ctx)
+	defer func() { r.observePhases(log, timings) }()
+
 	// The reconciliation is triggered for the local claim instance, so, if it
 	// cannot be fetched for any reason, then that's a problem.
 	localClaim := r.newInstance()
-	if err := r.local.Get(ctx, req.NamespacedName, localClaim); err != nil {
+	stop := timings.Start(PhaseLocalGet)
+	err := r.local.Get(ctx, req.NamespacedName, localClaim)
+	stop()
+	if err != nil {
 		if kerrors.IsNotFound(err) {
+			r.backoff.Forget(req)
 			return reconcile.Result{Requeue: false}, nil
 		}
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errGetRequirement)
+		return backOff(), errors.Wrap(err, localPrefix+errGetRequirement)
+	}
+
+	// Claims that are routed to another remote cluster are synced by the
+	// Reconciler of that remote cluster, once this one handed them over.
+	if len(r.remotes) > 0 {
+		if ok, result, err := r.route(ctx, log, req, localClaim); !ok {
+			r.backoff.Forget(req)
+			return result, err
+		}
+	}
+
+	// Claims that are not selected are not synced, nor is anything recorded on
+	// them, unless they were synced before they stopped being selected.
+	if !r.selector.Selects(localClaim) && !meta.FinalizerExists(localClaim, Finalizer) {
+		log.Debug("Claim is not selected for sync")
+		r.backoff.Forget(req)
+		return reconcile.Result{Requeue: false}, nil
+	}
+
+	// The propagation lag of a change is measured from the first time its
+	// generation is seen.
+	generation := localClaim.GetGeneration()
+	log = log.WithValues("correlation-id", CorrelationID(localClaim))
+	if !meta.WasDeleted(localClaim) {
+		r.lag.Observe(localClaim)
+	}
+
+	// Every path below leaves the result of this pass in the AgentSynced
+	// condition of the local claim.
+	defer r.observers.ObserveSync(ctx, localClaim)
+
+	// Claims that keep failing to sync are retried less and less often, so
+	// that they don't add to the load of an api-server that's struggling.
+	defer func() {
+		if backingOff || localClaim.GetCondition(resource.TypeAgentSync).Reason == resource.ReasonAgentSyncError {
+			r.backoff.Failed(req)
+			return
+		}
+		r.backoff.Forget(req)
+	}()
+
+	// Warnings that either api-server returns, e.g. about a deprecated API or
+	// from an admission webhook, are surfaced on the claim they're about.
+	ctx, warnings := warning.NewContext(ctx)
+	defer func() {
+		for _, w := range warnings.Warnings() {
+			r.record.Event(localClaim, event.Warning(reasonAPIWarning, errors.New(w)))
+		}
+	}()
+
+	// Nothing is read from or written to a remote cluster that has been
+	// unreachable for a while. Claims report that they're disconnected, and
+	// when the remote cluster is checked next, until it's reachable again.
+	if r.connectivity != nil {
+		if disconnected, since, next := r.connectivity.Disconnected(); disconnected {
+			log.Debug("Remote cluster is disconnected", "since", since, "next-probe", next, "requeue-after", time.Now().Add(r.syncInterval))
+			localClaim.SetConditions(resource.AgentSyncDisconnected(since, next))
+			return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	// Claims that were never synced wait for their turn while the ones that
+	// existed before the agent was started are onboarded. The condition is
+	// only written when a claim starts waiting so that the waiting claims
+	// don't flood the local cluster with status updates instead.
+	if r.onboarding != nil && !meta.WasDeleted(localClaim) && !meta.FinalizerExists(localClaim, Finalizer) {
+		if ok, wait := r.onboarding.Admit(localClaim); !ok {
+			log.Debug("Waiting to be onboarded", "requeue-after", time.Now().Add(wait))
+			if localClaim.GetCondition(resource.TypeAgentSync).Reason == resource.ReasonAgentSyncOnboarding {
+				return reconcile.Result{RequeueAfter: wait}, nil
+			}
+			localClaim.SetConditions(resource.AgentSyncOnboarding(r.onboarding.Progress().String()))
+			return reconcile.Result{RequeueAfter: wait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	// Claims wait for their turn before they touch the remote cluster. One
+	// that couldn't get a turn in time is retried later without writing its
+	// status, which would only add to the load.
+	if err := r.throttle.Wait(ctx); err != nil {
+		log.Debug(errWaitSync, "error", err, "requeue-after", time.Now().Add(shortWait))
+		return reconcile.Result{RequeueAfter: shortWait}, nil
 	}
 
 	// We fetch the remote claim instance that corresponds to this one and ignore
 	// the NotFound error since this pass could be the first one where the remote
 	// instance will be created.
-	remoteClaim := r.newInstance()
-	err := r.remote.Get(ctx, req.NamespacedName, remoteClaim)
+	rnn := types.NamespacedName{Namespace: r.mapper.RemoteNamespace(req.Namespace), Name: r.names.RemoteName(req.NamespacedName)}
+	log = log.WithValues("remote-claim", rnn)
+	remoteClaim := r.newRemoteInstance()
+	stop = timings.Start(PhaseRemoteGet)
+	err = r.remote.Get(ctx, rnn, remoteClaim)
+	stop()
 	if runtimeresource.IgnoreNotFound(err) != nil {
-		log.Debug("Cannot get resource from remote", "error", err, "requeue-after", time.Now().Add(shortWait))
-		r.record.Event(localClaim, event.Warning(reasonCannotGetFromRemote, err))
-		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errGetRequirement)))
+		if IsRestoreError(err) {
+			r.resync.Trigger()
+		}
+		log.Debug("Cannot get resource from remote", "error", err, "requeue-after", time.Now().Add(retry))
+
+		// Transient errors are recorded in the condition of every claim
+		// during an outage of the remote cluster, there is no need to record
+		// an event for each of them as well.
+		if !resource.IsTransient(err) {
+			r.record.Event(localClaim, event.Warning(reasonCannotGetFromRemote, err))
+		}
+		cond := resource.AgentSyncError(errors.Wrap(err, remotePrefix+errGetRequirement))
+		if agentremote.IsPinMismatch(err) {
+			cond = resource.AgentSyncRemoteUntrusted(errors.Wrap(err, remotePrefix+errGetRequirement))
+		}
+		localClaim.SetConditions(cond)
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// Nothing is pushed to or deleted from the remote cluster while the agent
+	// is suspended, but the status of the remote claim is still pulled so
+	// that users keep seeing how their claims are doing.
+	suspended, msg, serr := r.suspension.Suspended(ctx)
+	if serr != nil {
+		log.Debug("Cannot check whether the agent is suspended", "error", serr, "requeue-after", time.Now().Add(retry))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(serr, localPrefix+errCheckSuspended)))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	if suspended {
+		log.Debug("Agent is suspended", "reason", msg, "requeue-after", time.Now().Add(shortWait))
+		if meta.WasCreated(remoteClaim) {
+			if perr := r.pullStatus(ctx, localClaim, remoteClaim); perr != nil {
+				log.Debug("Cannot pull status of remote claim", "error", perr)
+			}
+		}
+		localClaim.SetConditions(resource.AgentSyncSuspended(msg))
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
 	}
 
+	// A claim whose remote namespace changed since it was last synced, because
+	// the mapping changed or the namespace was renamed, is relocated rather
+	// than orphaned in its previous namespace: it's created in the new one and
+	// the previous copy is deleted once that succeeds.
+	var previous *claim.Unstructured
+	if ns := localClaim.GetAnnotations()[resource.AnnotationKeyRemoteNamespace]; ns != "" && ns != rnn.Namespace {
+		p, err := r.getPrevious(ctx, ns, rnn.Name)
+		if err != nil {
+			log.Debug("Cannot get previous remote claim", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(err))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		previous = p
+	}
+
+	// The resource version of an object never goes back unless the etcd of the
+	// remote cluster was restored from a backup. In that case, none of the
+	// remote claims can be trusted, so all of them are verified. Resource
+	// versions of different objects can't be compared, so the one that was
+	// last seen is forgotten if the remote claim isn't the one it was seen on,
+	// e.g. because the claim was relocated, or its remote claim was recreated
+	// or is in another remote cluster.
+	last := localClaim.GetAnnotations()[resource.AnnotationKeyLastRemoteResourceVersion]
+	if previous != nil || localClaim.GetAnnotations()[resource.AnnotationKeyRemoteUID] != string(remoteClaim.GetUID()) {
+		last = ""
+		resource.SetAnnotation(localClaim, resource.AnnotationKeyLastRemoteResourceVersion, "")
+	}
+	if err == nil && ResourceVersionRegressed(last, remoteClaim.GetResourceVersion()) {
+		log.Info("Remote cluster may have been restored from a backup, starting full resync", "last-resource-version", last, "resource-version", remoteClaim.GetResourceVersion())
+		r.record.Event(localClaim, event.Warning(reasonRemoteRestored, errors.Errorf(errFmtRegressed, last, remoteClaim.GetResourceVersion())))
+		r.resync.Trigger()
+	}
+	epoch := r.resync.Epoch()
+	verify := localClaim.GetAnnotations()[resource.AnnotationKeyResyncEpoch] != epoch
+
+	// Users may request a full resync of a claim, or of all claims in a
+	// namespace, as an escape hatch.
+	handled := localClaim.GetAnnotations()[resource.AnnotationKeyResyncHandled]
+	requested, rerr := r.resyncRequest(ctx, localClaim)
+	if rerr != nil {
+		log.Debug("Cannot tell whether a resync was requested", "error", rerr)
+		requested = handled
+	}
+	if requested != "" && requested != handled {
+		verify = true
+	}
+
+	// An agent that was partitioned away and came back while a newer one took
+	// over must not write over what the newer one has written, and nothing is
+	// written until the agent knows how new it is.
+	var token int64
+	if r.fencing != nil {
+		token = r.fencing.Token()
+		if token == 0 {
+			log.Debug("Waiting for a fencing token", "requeue-after", time.Now().Add(tinyWait))
+			return reconcile.Result{RequeueAfter: tinyWait}, nil
+		}
+	}
+	if theirs := FencingTokenOf(remoteClaim); token != 0 && theirs > token {
+		log.Debug("Remote claim was written by a newer agent", "fencing-token", theirs, "requeue-after", time.Now().Add(r.syncInterval))
+		r.record.Event(localClaim, event.Warning(reasonFenced, errors.Errorf(errFmtFenced, theirs, token)))
+		localClaim.SetConditions(resource.AgentSyncFenced(theirs, token))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// Two agents that sync to the same remote namespace would keep overwriting
+	// each other's claims, so the one that doesn't hold the lock backs off.
+	// Removing the finalizer of a claim whose remote is gone is always safe.
+	if !meta.WasDeleted(localClaim) || !kerrors.IsNotFound(err) {
+		held, holder, err := r.locker.Lock(ctx, rnn.Namespace)
+		if err != nil {
+			log.Debug("Cannot lock remote namespace", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errLockNamespace)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		if !held {
+			log.Debug("Remote namespace is locked by another agent", "holder", holder, "requeue-after", time.Now().Add(r.syncInterval))
+			r.record.Event(localClaim, event.Warning(reasonNamespaceLocked, errors.Errorf(errFmtLocked, holder)))
+			localClaim.SetConditions(resource.AgentSyncLocked(holder))
+			return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	// Claims with a TTL are deleted once they expire, which deletes their
+	// remote claims as well. Their owners are warned beforehand.
+	if !meta.WasDeleted(localClaim) {
+		expiry, ok, err := ExpiryOf(localClaim)
+		switch {
+		case err != nil:
+			r.record.Event(localClaim, event.Warning(reasonExpiring, err))
+		case ok && !time.Now().Before(expiry):
+			log.Info("Deleting expired claim", "expired-at", expiry)
+			r.record.Event(localClaim, event.Normal(reasonExpired, "Deleting claim because its TTL has passed"))
+			if err := r.local.Delete(ctx, localClaim); runtimeresource.IgnoreNotFound(err) != nil {
+				localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, localPrefix+errDeleteExpired)))
+				return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+			}
+			return reconcile.Result{RequeueAfter: tinyWait}, nil
+		case ok && time.Until(expiry) < ExpiryWarning(localClaim, expiry):
+			r.record.Event(localClaim, event.Warning(reasonExpiring, errors.Errorf(errFmtExpiring, expiry.UTC().Format(time.RFC3339))))
+		}
+	}
+
 	// If local claim instance is deleted, we need to clean up the remote instance
 	// before allowing it to disappear from api-server.
 	if meta.WasDeleted(localClaim) {
 
+		// A copy that was left in the previous remote namespace by a relocation
+		// that didn't complete is cleaned up as well.
+		if previous != nil {
+			if err := r.deleteRemote(ctx, previous); runtimeresource.IgnoreNotFound(err) != nil {
+				log.Debug("Cannot delete previous remote claim", "error", err, "requeue-after", time.Now().Add(retry))
+				r.record.Event(localClaim, event.Warning(reasonCannotDelete, err))
+				localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errDeletePrevious)))
+				return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+			}
+		}
+
 		// If the remote instance is already gone, then there is nothing else we
 		// need to clean up. The connection secret we created will be deleted by
 		// api-server once local instance is gone since we added our owner ref
 		// to it.
 		if kerrors.IsNotFound(err) {
+			// Copies that were fanned out to other remote clusters are
+			// deleted before the claim is let go.
+			if r.fanOut != nil {
+				gone, err := r.fanOut.Delete(ctx, localClaim)
+				if err != nil {
+					log.Debug("Cannot delete fanned out claims", "error", err, "requeue-after", time.Now().Add(retry))
+					r.record.Event(localClaim, event.Warning(reasonCannotDelete, err))
+					localClaim.SetConditions(resource.AgentSyncError(err))
+					return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+				}
+				if !gone {
+					localClaim.SetConditions(resource.AgentSyncSuccess().WithMessage("Deletion of fanned out claims is successfully requested"))
+					return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+				}
+			}
 			if err := r.finalizer.RemoveFinalizer(ctx, localClaim); err != nil {
-				log.Debug("Cannot remove finalizer", "error", err, "requeue-after", time.Now().Add(shortWait))
+				log.Debug("Cannot remove finalizer", "error", err, "requeue-after", time.Now().Add(retry))
 				r.record.Event(localClaim, event.Warning(reasonCannotRemoveFinalizer, err))
 				localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, localPrefix+errRemoveFinalizer)))
-				return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+				return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
 			}
+			r.lag.Forget(localClaim)
 			return reconcile.Result{}, nil
 		}
 
+		// A stale cache could tell us that the local claim is deleted while it's
+		// not, so we confirm that with the api-server before deleting the
+		// remote claim, which cannot be undone.
+		live := r.newInstance()
+		if err := r.live.Get(ctx, req.NamespacedName, live.GetUnstructured()); err != nil {
+			if kerrors.IsNotFound(err) {
+				return reconcile.Result{Requeue: false}, nil
+			}
+			return backOff(), errors.Wrap(err, localPrefix+errLiveGet)
+		}
+		if !meta.WasDeleted(live) {
+			log.Debug("Local claim is not deleted according to the api-server", "requeue-after", time.Now().Add(tinyWait))
+			return reconcile.Result{RequeueAfter: tinyWait}, nil
+		}
+
+		// A remote claim that's owned by another cluster is not ours to delete,
+		// even though it has the name of the local claim.
+		if meta.WasCreated(remoteClaim) && OwnedByOtherCluster(remoteClaim, r.clusterName) {
+			uid := string(remoteClaim.GetUID())
+			owner := DescribeOwner(remoteClaim)
+			log.Debug("Remote claim is owned by another cluster, refusing to delete it", "uid", uid, "owner", owner, "requeue-after", time.Now().Add(r.syncInterval))
+			r.record.Event(localClaim, event.Warning(reasonRemoteExists, errors.Errorf(errFmtRemoteExists, uid, owner)))
+			localClaim.SetConditions(resource.AgentSyncRemoteExists(uid, owner))
+			return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+
 		// Start the deletion of remote instance and if it's already gone, that's
-		// not an error since that's what we'd like to achieve.
-		if err := r.remote.Delete(ctx, remoteClaim); runtimeresource.IgnoreNotFound(err) != nil {
-			log.Debug("Cannot delete local object", "error", err, "requeue-after", time.Now().Add(shortWait))
+		// not an error since that's what we'd like to achieve. The precondition
+		// makes sure we delete only the instance we've just seen.
+		var do []client.DeleteOption
+		if uid := remoteClaim.GetUID(); uid != "" {
+			do = append(do, client.Preconditions{UID: &uid})
+		}
+		if err := r.deleteRemote(ctx, remoteClaim, do...); runtimeresource.IgnoreNotFound(err) != nil {
+			log.Debug("Cannot delete local object", "error", err, "requeue-after", time.Now().Add(retry))
 			r.record.Event(localClaim, event.Warning(reasonCannotDelete, err))
 			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errDeleteClaim)))
-			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
 		}
 
+		log.Debug("Requested deletion of remote claim", "operation", "delete")
+
 		// We have requested the deletion of the remote instance but that doesn't
 		// meant it's gone. So, we'll requeue and remove the finalizer only if we
 		// confirm that remote instance no longer exists.
+		r.record.Event(localClaim, event.Normal(reasonDeletionRequested, "Requested deletion of the remote claim"))
 		localClaim.SetConditions(resource.AgentSyncSuccess().WithMessage("Deletion is successfully requested"))
 		return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
 	}
 
+	// A remote claim that is deleted by someone else is not pushed to while
+	// it's going away. If we hold it, it's let go only after its final state
+	// is pulled onto the local claim.
+	if meta.WasDeleted(remoteClaim) && (r.remoteFinalizer || meta.FinalizerExists(remoteClaim, RemoteFinalizer)) {
+		return r.remoteDeleted(ctx, log, retry, localClaim, remoteClaim)
+	}
+
+	// Claims that were synced to the local cluster by another agent are not
+	// synced back to a cluster they were already synced through.
+	if r.clusterName != "" && InOriginChain(localClaim, r.clusterName) {
+		log.Debug("Claim would be synced in a loop", "origin-chain", OriginChain(localClaim), "requeue-after", time.Now().Add(r.syncInterval))
+		localClaim.SetConditions(resource.AgentSyncLoop(append(OriginChain(localClaim), r.clusterName)))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// Claims that require approval are held until they're approved. Once the
+	// remote claim exists, later changes are pushed without approval.
+	if r.requireApproval && !meta.WasCreated(remoteClaim) && !resource.IsApproved(localClaim) {
+		log.Debug("Claim is pending approval", "requeue-after", time.Now().Add(r.syncInterval))
+		localClaim.SetConditions(resource.AgentSyncPendingApproval())
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// New claims are not provisioned against definitions that haven't been
+	// refreshed from the remote cluster for a while, since they may have
+	// changed in the meantime. Claims that already exist keep syncing.
+	if r.definitions != nil && !meta.WasCreated(remoteClaim) {
+		stale, msg, err := r.definitions.Stale(ctx)
+		if err != nil {
+			log.Debug("Cannot check definition staleness", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errCheckStaleness)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		if stale {
+			log.Debug("Definitions are stale", "reason", msg, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncDefinitionsStale(msg))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
 	// At this point, we will begin the operations that will need some cleanup in
 	// case of deletion, such as creation of remote correspondent. So, we add to a
 	// finalizer to local claim instance to block its deletion until this controller
 	// takes care of the cleanup.
 	if err := r.finalizer.AddFinalizer(ctx, localClaim); err != nil {
-		log.Debug("Cannot add finalizer", "error", err, "requeue-after", time.Now().Add(shortWait))
+		log.Debug("Cannot add finalizer", "error", err, "requeue-after", time.Now().Add(retry))
 		r.record.Event(localClaim, event.Warning(reasonCannotAddFinalizer, err))
 		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, localPrefix+errAddFinalizer)))
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// A remote claim whose UID is different than the one we synced to was
+	// deleted and created again by someone else. Silently merging our state
+	// into it could hide the fact that whatever it represents was replaced.
+	recorded := localClaim.GetAnnotations()[resource.AnnotationKeyRemoteUID]
+	if meta.WasCreated(remoteClaim) && recorded != "" && recorded != string(remoteClaim.GetUID()) {
+		ruid := remoteClaim.GetUID()
+		uid := string(ruid)
+		switch r.uidPolicy {
+		case UIDPolicyAdopt:
+			log.Info("Adopting remote claim that was replaced out-of-band", "was", recorded, "is", uid)
+			r.record.Event(localClaim, event.Normal(reasonRemoteReplaced, "Adopting remote claim that was replaced out-of-band", "uid", uid))
+		case UIDPolicyRecreate:
+			log.Info("Deleting remote claim that was replaced out-of-band", "was", recorded, "is", uid)
+			r.record.Event(localClaim, event.Warning(reasonRemoteReplaced, errors.Errorf(errFmtReplaced, recorded, uid)))
+			if err := r.deleteRemote(ctx, remoteClaim, client.Preconditions{UID: &ruid}); runtimeresource.IgnoreNotFound(err) != nil {
+				localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errDeleteReplaced)))
+				return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+			}
+			localClaim.SetConditions(resource.AgentSyncRemoteReplaced(recorded, uid))
+			return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		default:
+			log.Debug("Remote claim was replaced out-of-band", "was", recorded, "is", uid, "requeue-after", time.Now().Add(r.syncInterval))
+			r.record.Event(localClaim, event.Warning(reasonRemoteReplaced, errors.Errorf(errFmtReplaced, recorded, uid)))
+			localClaim.SetConditions(resource.AgentSyncRemoteReplaced(recorded, uid))
+			return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	// A remote claim that's there before the local claim was ever synced, and
+	// that wasn't written for it by an agent, belongs to someone else. Merging
+	// the local claim into it would take it over without anyone noticing, so
+	// it's left alone until it's adopted by recording its UID. One that was
+	// last written from another cluster is never updated, since the agent of
+	// that cluster would overwrite it right back.
+	if meta.WasCreated(remoteClaim) && (recorded == "" && !WrittenFor(remoteClaim, localClaim, r.clusterName) || OwnedByOtherCluster(remoteClaim, r.clusterName)) {
+		uid := string(remoteClaim.GetUID())
+		owner := DescribeOwner(remoteClaim)
+		log.Debug("Remote claim was not created by the agent", "uid", uid, "owner", owner, "requeue-after", time.Now().Add(r.syncInterval))
+		r.record.Event(localClaim, event.Warning(reasonRemoteExists, errors.Errorf(errFmtRemoteExists, uid, owner)))
+		localClaim.SetConditions(resource.AgentSyncRemoteExists(uid, owner))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	var observed *claim.Unstructured
+	if r.syncRecorder != nil || r.detectDrift {
+		observed = &claim.Unstructured{Unstructured: *remoteClaim.GetUnstructured().DeepCopy()}
 	}
 
 	// At this point, we are getting remote instance ready for Apply operation
 	// by configuring its fields.
 	if err := r.Configure(ctx, localClaim, remoteClaim); err != nil {
-		log.Debug("Cannot run configurator", "error", err, "requeue-after", time.Now().Add(shortWait))
+		log.Debug("Cannot run configurator", "error", err, "requeue-after", time.Now().Add(retry))
 		r.record.Event(localClaim, event.Warning(reasonCannotConfigure, err))
 		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errPush)))
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// The remote cluster may serve the claim at another version while the
+	// platform is being upgraded.
+	if r.conversion != nil {
+		if err := r.conversion.ToRemote(remoteClaim); err != nil {
+			log.Debug("Cannot convert claim to remote version", "error", err, "requeue-after", time.Now().Add(retry))
+			r.record.Event(localClaim, event.Warning(reasonCannotConfigure, err))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errPush)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	remoteClaim.SetNamespace(rnn.Namespace)
+	remoteClaim.SetName(rnn.Name)
+	if previous != nil && !meta.WasCreated(remoteClaim) {
+		if err := r.preserveExternalName(ctx, previous, remoteClaim); err != nil {
+			log.Debug("Cannot preserve external name", "error", err, "requeue-after", time.Now().Add(retry))
+			r.record.Event(localClaim, event.Warning(reasonCannotConfigure, err))
+			localClaim.SetConditions(resource.AgentSyncError(err))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	if token != 0 {
+		SetFencingToken(remoteClaim, token)
+	}
+	SetAuditAnnotations(remoteClaim, localClaim, r.clusterName)
+	SetIdempotencyKey(remoteClaim, localClaim)
+	meta.AddLabels(remoteClaim, r.ownerLabels)
+
+	// The admission policies of the remote cluster may reject claims without
+	// some labels. We tell the user which ones are missing rather than having
+	// them dig the reason out of a rejected push.
+	if missing := r.labelPolicy.Apply(localClaim, remoteClaim, r.clusterName); len(missing) > 0 {
+		log.Debug("Claim is missing required labels", "labels", missing, "requeue-after", time.Now().Add(r.syncInterval))
+		r.record.Event(localClaim, event.Warning(reasonMissingLabels, errors.Errorf(errFmtMissingLabels, strings.Join(missing, ", "))))
+		localClaim.SetConditions(resource.AgentSyncMissingLabels(missing))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	if r.remoteFinalizer {
+		meta.AddFinalizer(remoteClaim, RemoteFinalizer)
+	}
+	if r.syncRecorder != nil {
+		if err := r.syncRecorder.RecordSync(ctx, localClaim, observed, remoteClaim); err != nil {
+			log.Debug("Cannot record sync", "error", err)
+		}
+	}
+
+	// A remote claim whose spec isn't the one we'd push, while the local claim
+	// wasn't changed since it was last pushed, was changed out-of-band. It's
+	// reported here, and the desired spec is pushed again below.
+	var drift Drift
+	if r.detectDrift && meta.WasCreated(observed) && PushedSpecUnchanged(localClaim, remoteClaim, r.pushedSpecSize) {
+		if drift = SpecDrift(remoteClaim, observed); len(drift) > 0 {
+			log.Info("Remote claim was changed out-of-band", "drift", drift.String())
+			r.record.Event(localClaim, event.Warning(reasonRemoteDrifted, errors.Errorf(errFmtDrifted, drift)))
+		}
+	}
+
+	// The spec we push is recorded so that it can be compared with what the
+	// remote cluster ends up with, which is persisted only if the push
+	// succeeds.
+	if r.pushedSpecSize > 0 {
+		if err := SetLastPushedSpec(localClaim, remoteClaim, r.pushedSpecSize); err != nil {
+			log.Debug("Cannot record last pushed spec", "error", err)
+		}
+	}
+
+	// The remote api-server rejects objects that are too large with an opaque
+	// error on every retry, so we refuse to push them and tell the user how
+	// large the claim is instead. Retrying sooner than the next sync wouldn't
+	// make any difference until the claim is changed.
+	size, err := ObjectSize(remoteClaim)
+	if err != nil {
+		log.Debug("Cannot measure claim size", "error", err, "requeue-after", time.Now().Add(retry))
+		r.record.Event(localClaim, event.Warning(reasonCannotConfigure, err))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errMeasureSize)))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	if r.maxObjectSize > 0 && size > r.maxObjectSize {
+		log.Debug("Claim is too large to be pushed", "size", size, "limit", r.maxObjectSize, "requeue-after", time.Now().Add(r.syncInterval))
+		r.record.Event(localClaim, event.Warning(reasonObjectTooLarge, errors.Errorf(errFmtTooLarge, size, r.maxObjectSize)))
+		localClaim.SetConditions(resource.AgentSyncObjectTooLarge(size, r.maxObjectSize))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// Claims that were created while the local CRD lagged behind the remote
+	// one are reported with the fields that don't conform to the schema of
+	// the remote cluster rather than with the error of its api-server.
+	invalid, err := r.schema.Validate(ctx, remoteClaim)
+	if err != nil {
+		log.Debug("Cannot validate claim against the schema of the remote cluster", "error", err, "requeue-after", time.Now().Add(retry))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errValidateSchema)))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	if len(invalid) > 0 {
+		log.Debug("Claim doesn't conform to the schema of the remote cluster", "errors", invalid.ToAggregate().Error(), "requeue-after", time.Now().Add(r.syncInterval))
+		r.record.Event(localClaim, event.Warning(reasonSchemaInvalid, errors.Errorf(errFmtSchemaInvalid, invalid.ToAggregate())))
+		localClaim.SetConditions(resource.AgentSyncSchemaInvalid(invalid.ToAggregate()))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// The policies of the platform are enforced on the claim as it'd be
+	// pushed. A rejected claim isn't retried until it, or the policies,
+	// change.
+	admission, err := r.admission.Admit(ctx, remoteClaim)
+	if err != nil {
+		log.Debug("Cannot get the verdict of the admission gate", "error", err, "requeue-after", time.Now().Add(retry))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errAdmit)))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	if !admission.Allowed {
+		log.Debug("Claim is rejected by the admission gate", "message", admission.Message, "requeue-after", time.Now().Add(r.syncInterval))
+		r.record.Event(localClaim, event.Warning(reasonClaimRejected, errors.Errorf(errFmtRejected, admission.Message)))
+		localClaim.SetConditions(resource.AgentSyncRejected(admission.Message))
+		return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// A canary copy of the claim is validated before the real one is touched
+	// so that a bad change doesn't reach the actual namespace.
+	if err := r.canary.Validate(ctx, remoteClaim); err != nil {
+		log.Debug("Canary validation failed", "error", err, "requeue-after", time.Now().Add(retry))
+		r.record.Event(localClaim, event.Warning(reasonCanaryFailed, err))
+		localClaim.SetConditions(resource.AgentSyncCanaryFailed(err))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// Creating a claim in a namespace that is being deleted or doesn't exist
+	// is rejected every time, so we hold off until the namespace is usable.
+	if !meta.WasCreated(remoteClaim) {
+		if err := r.namespaces.Ensure(ctx, remoteClaim.GetNamespace()); err != nil {
+			return r.namespaceUnavailable(ctx, log, retry, localClaim, err)
+		}
+	}
+
+	// A create that would exceed the quotas of the remote namespace is
+	// rejected with an opaque Forbidden error, so we tell the user which
+	// quotas it'd exceed instead. Retrying sooner than the next sync wouldn't
+	// make any difference until the quotas or their usage change.
+	if !meta.WasCreated(remoteClaim) {
+		exceeded, err := r.quota.WouldExceed(ctx, remoteClaim)
+		if err != nil {
+			log.Debug("Cannot check quotas", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errCheckQuota)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		if len(exceeded) > 0 {
+			log.Debug("Creating the claim would exceed quotas", "quotas", exceeded, "requeue-after", time.Now().Add(r.syncInterval))
+			r.record.Event(localClaim, event.Warning(reasonQuotaWouldExceed, errors.Errorf(errFmtQuotaExceeded, strings.Join(exceeded, "; "))))
+			localClaim.SetConditions(resource.AgentSyncQuotaWouldExceed(exceeded))
+			return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	// Everything the claim refers to needs to be in the remote cluster before
+	// the claim itself, otherwise it'd fail there instead of waiting here.
+	// Dependencies that are synced by the agent are pushed at this point.
+	if err := r.dependencies.Resolve(ctx, localClaim, remoteClaim); err != nil {
+		if !IsWaitingOnDependency(err) {
+			log.Debug("Cannot resolve dependencies", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errResolveDependency)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		log.Debug("Waiting on dependency", "error", err, "requeue-after", time.Now().Add(retry))
+		r.record.Event(localClaim, event.Warning(reasonWaitingOnDependency, err))
+		localClaim.SetConditions(resource.AgentSyncWaitingOnDependency(err))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
 	}
 
 	// We create/update the final form of the instance in the remote cluster.
-	if err := r.remote.Apply(ctx, remoteClaim); err != nil {
-		log.Debug("Cannot call Apply", "error", err, "requeue-after", time.Now().Add(shortWait))
+	// Apply merges our desired state into the remote one, which would keep the
+	// stale fields of a restored remote claim, so it's overwritten instead
+	// during a verification.
+	creating := !meta.WasCreated(remoteClaim)
+	apply, op := func() error { return r.remote.Apply(ctx, remoteClaim) }, "apply"
+	if verify && meta.WasCreated(remoteClaim) {
+		log.Debug("Verifying remote claim", "resync-epoch", epoch)
+		apply, op = func() error { return r.remote.Update(ctx, remoteClaim) }, "update"
+	}
+	if creating {
+		op = "create"
+	}
+	stop = timings.Start(PhasePush)
+	err = apply()
+	stop()
+	if err != nil {
+		if IsRestoreError(err) {
+			r.resync.Trigger()
+		}
+		log.Debug("Cannot call Apply", "operation", op, "error", err, "requeue-after", time.Now().Add(retry))
+		if kerrors.IsRequestEntityTooLargeError(errors.Cause(err)) {
+			r.record.Event(localClaim, event.Warning(reasonObjectTooLarge, err))
+			localClaim.SetConditions(resource.AgentSyncObjectTooLarge(size, 0))
+			return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		if IsNamespaceTerminatingError(err) {
+			return r.namespaceUnavailable(ctx, log, retry, localClaim, namespaceUnavailable{errors.Errorf(errFmtNsTerminating, remoteClaim.GetNamespace())})
+		}
+
+		// The remote claim was changed since we read it, so we read it again
+		// right away rather than report an error.
+		if resource.IsConflict(err) {
+			r.record.Event(localClaim, event.Normal(reasonConflict, "Remote claim was changed since it was read, syncing it again"))
+			return reconcile.Result{RequeueAfter: tinyWait}, nil
+		}
+
+		// The remote claim was created by someone else since we read it, so
+		// we read it again to tell who it belongs to.
+		if kerrors.IsAlreadyExists(errors.Cause(err)) {
+			return reconcile.Result{RequeueAfter: tinyWait}, nil
+		}
+
+		// A create that failed in a way that leaves its outcome unknown, e.g.
+		// because it timed out, may have gone through regardless. The remote
+		// claim is looked up by its idempotency key rather than its name, and
+		// synced again right away if it was created, instead of being created
+		// a second time.
+		if creating && resource.IsTransient(err) {
+			created, ferr := FindCreated(ctx, r.remote, rnn.Namespace, localClaim, remoteClaim)
+			if ferr != nil {
+				log.Debug("Cannot tell whether the remote claim was created", "error", ferr)
+			}
+			if created != nil {
+				log.Info("Remote claim was created despite the error", "error", err, "uid", created.GetUID(), "name", created.GetName())
+				r.record.Event(localClaim, event.Normal(reasonCreatedDespiteError, "Remote claim was created despite the error, syncing it again", "uid", string(created.GetUID())))
+				return reconcile.Result{RequeueAfter: tinyWait}, nil
+			}
+		}
+
+		// Retrying a claim that's denied by the policies of the remote cluster
+		// won't succeed until either of them changes.
+		wait := retry
+		if resource.IsAdmissionDenied(err) {
+			wait = r.syncInterval
+		}
 		r.record.Event(localClaim, event.Warning(reasonCannotApply, err))
 		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errApplyClaim)))
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		return reconcile.Result{RequeueAfter: wait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	log.Debug("Pushed claim to remote cluster", "operation", op, "duration", timings.Timings()[PhasePush])
+
+	// The previous copy of a relocated claim is deleted only after the claim
+	// exists in its new remote namespace.
+	if previous != nil {
+		puid := previous.GetUID()
+		if err := r.deleteRemote(ctx, previous, client.Preconditions{UID: &puid}); runtimeresource.IgnoreNotFound(err) != nil {
+			log.Debug("Cannot delete previous remote claim", "error", err, "requeue-after", time.Now().Add(retry))
+			r.record.Event(localClaim, event.Warning(reasonCannotDelete, err))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errDeletePrevious)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		log.Info("Relocated remote claim", "from", previous.GetNamespace(), "to", rnn.Namespace)
+		r.record.Event(localClaim, event.Normal(reasonRelocated, fmt.Sprintf(errFmtRelocated, previous.GetNamespace(), rnn.Namespace)))
+	}
+
+	// We record what we've seen so that a restore of the remote cluster can be
+	// detected in the next passes. These are persisted on their own, rather
+	// than by whichever Propagator happens to write the claim.
+	seen := trackedAnnotations(localClaim)
+	resource.SetAnnotation(localClaim, resource.AnnotationKeyLastRemoteResourceVersion, remoteClaim.GetResourceVersion())
+	resource.SetAnnotation(localClaim, resource.AnnotationKeyResyncEpoch, epoch)
+	resource.SetAnnotation(localClaim, resource.AnnotationKeyResyncHandled, requested)
+	resource.SetAnnotation(localClaim, resource.AnnotationKeyRemoteUID, string(remoteClaim.GetUID()))
+	resource.SetAnnotation(localClaim, resource.AnnotationKeyRemoteNamespace, rnn.Namespace)
+	if len(r.remotes) > 0 {
+		meta.AddAnnotations(localClaim, map[string]string{resource.AnnotationKeyRemoteCluster: r.remoteName})
+	}
+	if !reflect.DeepEqual(seen, trackedAnnotations(localClaim)) {
+		if err := persistTrackedAnnotations(ctx, r.local, localClaim); err != nil {
+			log.Debug("Cannot record what was seen of remote claim", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, localPrefix+errUpdateClaim)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
 	}
 
 	// At this point, we have the remote instance in the remote cluster and the
 	// variable "remote" is updated. So, we will propagate new information from
 	// "remote" to "local"
-	if err := r.Propagate(ctx, localClaim, remoteClaim); err != nil {
-		log.Debug("Cannot run propagator", "error", err, "requeue-after", time.Now().Add(shortWait))
+	pulled := remoteClaim
+	if r.conversion != nil {
+		if pulled, err = r.conversion.ToLocal(remoteClaim); err != nil {
+			log.Debug("Cannot convert claim to local version", "error", err, "requeue-after", time.Now().Add(retry))
+			r.record.Event(localClaim, event.Warning(reasonCannotPropagate, err))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errPull)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+	// A successful sync is reported when the claim is synced for the first
+	// time, again after it wasn't, or when a new generation of it is pushed,
+	// rather than on every pass. The status is read before it's overwritten.
+	announce := localClaim.GetCondition(resource.TypeAgentSync).Reason != resource.ReasonAgentSyncSuccess || PushedGeneration(localClaim) != generation
+
+	stop = timings.Start(PhasePropagate)
+	err = r.Propagate(ctx, localClaim, pulled)
+	stop()
+	if err != nil {
+		log.Debug("Cannot run propagator", "error", err, "requeue-after", time.Now().Add(retry))
 		r.record.Event(localClaim, event.Warning(reasonCannotPropagate, err))
 		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errPull)))
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// How long it took for this generation of the claim to reach the remote
+	// cluster is recorded the first time it's pushed.
+	if lag, ok := r.lag.Pushed(localClaim, generation); ok {
+		if err := SetPropagationLag(localClaim, generation, lag); err != nil {
+			log.Debug("Cannot record propagation lag", "error", err)
+		}
+		if r.metrics != nil {
+			r.metrics.LagSeconds.WithLabelValues(r.gvk.String()).Observe(lag.Seconds())
+		}
+	}
+	// The drift is kept in the status until the next pass finds the remote
+	// claim as it was pushed, which is sooner than usual.
+	if len(drift) > 0 {
+		localClaim.SetConditions(resource.AgentSyncDrifted(drift.String()))
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), localPrefix+errStatusUpdateClaim)
+	}
+	if announce {
+		r.record.Event(localClaim, event.Normal(reasonSynced, fmt.Sprintf(msgFmtSynced, generation)))
 	}
 	localClaim.SetConditions(resource.AgentSyncSuccess())
-	return reconcile.Result{RequeueAfter: longWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), localPrefix+errStatusUpdateClaim)
+	return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), localPrefix+errStatusUpdateClaim)
+}
+
+func (r *Reconciler) observePhases(log logging.Logger, t *PhaseTimings) {
+	log.Debug("Reconcile timings", t.KeysAndValues()...)
+	if r.metrics == nil {
+		return
+	}
+	for p, d := range t.Timings() {
+		r.metrics.PhaseSeconds.WithLabelValues(r.gvk.String(), string(p)).Observe(d.Seconds())
+	}
+}
+
+func (r *Reconciler) namespaceUnavailable(ctx context.Context, log logging.Logger, retry time.Duration, localClaim *claim.Unstructured, err error) (reconcile.Result, error) {
+	if !IsNamespaceUnavailable(err) {
+		log.Debug("Cannot ensure remote namespace", "error", err, "requeue-after", time.Now().Add(retry))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errEnsureNamespace)))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	log.Debug("Remote namespace is unavailable", "error", err, "requeue-after", time.Now().Add(r.syncInterval))
+	r.record.Event(localClaim, event.Warning(reasonNamespaceUnavailable, err))
+	localClaim.SetConditions(resource.AgentSyncNamespaceUnavailable(err))
+	return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+}
+
+// pullStatus pulls the status of the supplied remote claim onto the supplied
+// local claim, converting it to the local version first if needed.
+func (r *Reconciler) pullStatus(ctx context.Context, localClaim, remoteClaim *claim.Unstructured) error {
+	pulled := remoteClaim
+	if r.conversion != nil {
+		var err error
+		if pulled, err = r.conversion.ToLocal(remoteClaim); err != nil {
+			return errors.Wrap(err, errPull)
+		}
+	}
+	return errors.Wrap(r.status.Propagate(ctx, localClaim, pulled), errPull)
+}
+
+// deleteRemote deletes the supplied remote claim, removing RemoteFinalizer from
+// it first so that its deletion isn't held for the Reconciler itself.
+func (r *Reconciler) deleteRemote(ctx context.Context, o *claim.Unstructured, do ...client.DeleteOption) error {
+	if meta.FinalizerExists(o, RemoteFinalizer) {
+		meta.RemoveFinalizer(o, RemoteFinalizer)
+		if err := r.remote.Update(ctx, o); err != nil {
+			return errors.Wrap(err, errReleaseRemote)
+		}
+	}
+	return r.remote.Delete(ctx, o, do...)
+}
+
+// remoteDeleted handles a remote claim that is being deleted by someone other
+// than the Reconciler in two phases. The first pass pulls its final status
+// onto the local claim and records that its deletion was acknowledged. A later
+// pass removes RemoteFinalizer so that the deletion completes, after which the
+// remote claim is created again from the local claim.
+func (r *Reconciler) remoteDeleted(ctx context.Context, log logging.Logger, retry time.Duration, localClaim, remoteClaim *claim.Unstructured) (reconcile.Result, error) {
+	uid := string(remoteClaim.GetUID())
+	log = log.WithValues("remote-uid", uid)
+	if !meta.FinalizerExists(remoteClaim, RemoteFinalizer) {
+		log.Debug("Remote claim is being deleted", "requeue-after", time.Now().Add(tinyWait))
+		localClaim.SetConditions(resource.AgentSyncRemoteDeleted(uid))
+		return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	if localClaim.GetAnnotations()[resource.AnnotationKeyRemoteDeletionAcknowledged] != uid {
+		log.Info("Remote claim is being deleted out-of-band, acknowledging")
+		r.record.Event(localClaim, event.Warning(reasonRemoteDeleted, errors.Errorf(errFmtRemoteDeleted, uid)))
+
+		// The annotation is persisted before the status is propagated since
+		// an update of the claim discards changes to its status.
+		resource.SetAnnotation(localClaim, resource.AnnotationKeyRemoteDeletionAcknowledged, uid)
+		if err := r.local.Update(ctx, localClaim); err != nil {
+			log.Debug("Cannot acknowledge remote deletion", "error", err, "requeue-after", time.Now().Add(retry))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, localPrefix+errAckDeletion)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		pulled := remoteClaim
+		if r.conversion != nil {
+			var err error
+			if pulled, err = r.conversion.ToLocal(remoteClaim); err != nil {
+				log.Debug("Cannot convert claim to local version", "error", err, "requeue-after", time.Now().Add(retry))
+				localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errPull)))
+				return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+			}
+		}
+		if err := r.status.Propagate(ctx, localClaim, pulled); err != nil {
+			log.Debug("Cannot propagate status", "error", err, "requeue-after", time.Now().Add(retry))
+			r.record.Event(localClaim, event.Warning(reasonCannotPropagate, err))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errPull)))
+			return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+		localClaim.SetConditions(resource.AgentSyncRemoteDeleted(uid))
+		return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	log.Info("Letting go of remote claim that is deleted out-of-band")
+	meta.RemoveFinalizer(remoteClaim, RemoteFinalizer)
+	if err := r.remote.Update(ctx, remoteClaim); runtimeresource.IgnoreNotFound(err) != nil {
+		log.Debug("Cannot remove finalizer of remote claim", "error", err, "requeue-after", time.Now().Add(retry))
+		r.record.Event(localClaim, event.Warning(reasonCannotRemoveFinalizer, err))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errReleaseRemote)))
+		return backOff(), errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+	localClaim.SetConditions(resource.AgentSyncRemoteDeleted(uid))
+	return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
 }
@@ -18,7 +18,10 @@ package claim
 
 import (
 	"context"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
@@ -27,22 +30,30 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 
+	"github.com/crossplane/agent/pkg/onboarding"
+	agentremote "github.com/crossplane/agent/pkg/remote"
 	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/suspend"
 )
 
 var (
-	errBoom = errors.New("boom")
-	now     = metav1.Now()
-	gvk     = schema.GroupVersionKind{}
+	errBoom      = errors.New("boom")
+	errUntrusted = &url.Error{Op: "Get", URL: "https://remote", Err: &agentremote.PinMismatchError{}}
+	now          = metav1.Now()
+	gvk          = schema.GroupVersionKind{}
 )
 
 func TestReconcile(t *testing.T) {
@@ -80,6 +91,29 @@ func TestReconcile(t *testing.T) {
 				},
 			},
 		},
+		"NotSelected": {
+			reason: "Nothing should be synced or recorded for a claim that is not selected",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				opts:   []ReconcilerOption{WithClaimSelector(NewClaimSelector(nil, "team-a"))},
+			},
+		},
+		"RoutedElsewhere": {
+			reason: "Nothing should be synced or recorded for a claim that is routed to another remote cluster",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+						obj.(*unstructured.Unstructured).SetLabels(map[string]string{resource.LabelKeyRemote: "west"})
+						return nil
+					}},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				opts:   []ReconcilerOption{WithRemoteRouting("", []string{"west"})},
+			},
+		},
 		"RemoteGetFailed": {
 			reason: "An error should be returned if remote claim cannot be retrieved",
 			args: args{
@@ -103,6 +137,62 @@ func TestReconcile(t *testing.T) {
 				result: reconcile.Result{RequeueAfter: shortWait},
 			},
 		},
+		"RemoteUntrusted": {
+			reason: "A remote cluster whose certificate doesn't match the pins should be reported as untrusted",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							want := claim.New(claim.WithGroupVersionKind(gvk))
+							want.SetConditions(resource.AgentSyncRemoteUntrusted(errors.Wrap(errUntrusted, remotePrefix+errGetRequirement)))
+							if diff := cmp.Diff(want.GetUnstructured(), obj, test.EquateConditions()); diff != "" {
+								reason := "A remote cluster whose certificate doesn't match the pins should be reported as untrusted"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(errUntrusted)},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
+		"PreviousRemoteGetFailed": {
+			reason: "An error should be returned if the claim in its previous remote namespace cannot be retrieved",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							l := claim.New(claim.WithGroupVersionKind(gvk))
+							l.SetAnnotations(map[string]string{resource.AnnotationKeyRemoteNamespace: "old"})
+							l.DeepCopyInto(obj.(*unstructured.Unstructured))
+							return nil
+						},
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							want := errors.Wrap(errBoom, remotePrefix+errGetPrevious).Error()
+							if diff := cmp.Diff(want, got.GetCondition(resource.TypeAgentSync).Message); diff != "" {
+								reason := "An error should be returned if the claim in its previous remote namespace cannot be retrieved"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, _ runtime.Object) error {
+					if key.Namespace == "old" {
+						return errBoom
+					}
+					return kerrors.NewNotFound(schema.GroupResource{}, "")
+				}},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
 		"RemoteNotFoundAndDeleted": {
 			reason: "No error should be returned if deletion is requested and the remote claim is gone",
 			args: args{
@@ -287,6 +377,525 @@ func TestReconcile(t *testing.T) {
 				result: reconcile.Result{RequeueAfter: shortWait},
 			},
 		},
+		"ObjectTooLarge": {
+			reason: "The claim should not be pushed if it is larger than the configured limit",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							l := claim.New(claim.WithGroupVersionKind(gvk))
+							l.Object["spec"] = map[string]interface{}{"size": "large"}
+							l.DeepCopyInto(obj.(*unstructured.Unstructured))
+							return nil
+						},
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncObjectTooLarge, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "The claim should not be pushed if it is larger than the configured limit"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+				opts: []ReconcilerOption{
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+					WithMaxObjectSize(1),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"RemoteReplaced": {
+			reason: "The claim should not be pushed if the remote claim was replaced out-of-band",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							l := claim.New(claim.WithGroupVersionKind(gvk))
+							l.SetAnnotations(map[string]string{resource.AnnotationKeyRemoteUID: "old"})
+							l.DeepCopyInto(obj.(*unstructured.Unstructured))
+							return nil
+						},
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncRemoteReplaced, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "The claim should not be pushed if the remote claim was replaced out-of-band"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					r := claim.New(claim.WithGroupVersionKind(gvk))
+					r.SetUID("new")
+					r.SetCreationTimestamp(now)
+					r.DeepCopyInto(obj.(*unstructured.Unstructured))
+					return nil
+				}},
+				opts: []ReconcilerOption{
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"Disconnected": {
+			reason: "Nothing should be read from or written to a remote cluster the agent is disconnected from",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncDisconnected, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "Nothing should be read from or written to a remote cluster the agent is disconnected from"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				opts: []ReconcilerOption{
+					WithConnectivity(connectivityFn(func() (bool, time.Time, time.Time) {
+						return true, now.Time, now.Add(time.Minute)
+					})),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"Onboarding": {
+			reason: "A claim that existed before the agent was started should wait for its turn to be synced for the first time",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncOnboarding, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "A claim that existed before the agent was started should wait for its turn to be synced for the first time"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				opts:   []ReconcilerOption{WithOnboardingThrottle(onboardingWait(30 * time.Second))},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: 30 * time.Second},
+			},
+		},
+		"RemoteExists": {
+			reason: "The claim should not be pushed into a remote claim that was not created by the agent for it",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncRemoteExists, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "The claim should not be pushed into a remote claim that was not created by the agent for it"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					r := claim.New(claim.WithGroupVersionKind(gvk))
+					r.SetUID("theirs")
+					r.SetCreationTimestamp(now)
+					r.DeepCopyInto(obj.(*unstructured.Unstructured))
+					return nil
+				}},
+				opts: []ReconcilerOption{
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"RemoteDeletedAcknowledged": {
+			reason: "The deletion of a remote claim that is held by the agent should be acknowledged on the local claim first",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := obj.(*unstructured.Unstructured).GetAnnotations()[resource.AnnotationKeyRemoteDeletionAcknowledged]
+							if diff := cmp.Diff("cool-uid", got); diff != "" {
+								reason := "The deletion of a remote claim that is held by the agent should be acknowledged on the local claim first"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncRemoteDeleted, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "The deletion of a remote claim that is held by the agent should be acknowledged on the local claim first"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					r := claim.New(claim.WithGroupVersionKind(gvk))
+					r.SetUID("cool-uid")
+					r.SetDeletionTimestamp(&now)
+					r.SetFinalizers([]string{RemoteFinalizer})
+					r.DeepCopyInto(obj.(*unstructured.Unstructured))
+					return nil
+				}},
+				opts: []ReconcilerOption{WithRemoteFinalizer()},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: tinyWait},
+			},
+		},
+		"RemoteDeletedReleased": {
+			reason: "A remote claim whose deletion was acknowledged should be let go",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							l := claim.New(claim.WithGroupVersionKind(gvk))
+							l.SetAnnotations(map[string]string{resource.AnnotationKeyRemoteDeletionAcknowledged: "cool-uid"})
+							l.DeepCopyInto(obj.(*unstructured.Unstructured))
+							return nil
+						},
+						MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+					},
+				},
+				remote: &test.MockClient{
+					MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+						r := claim.New(claim.WithGroupVersionKind(gvk))
+						r.SetUID("cool-uid")
+						r.SetDeletionTimestamp(&now)
+						r.SetFinalizers([]string{RemoteFinalizer})
+						r.DeepCopyInto(obj.(*unstructured.Unstructured))
+						return nil
+					},
+					MockUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+						if meta.FinalizerExists(obj.(*unstructured.Unstructured), RemoteFinalizer) {
+							reason := "A remote claim whose deletion was acknowledged should be let go"
+							t.Errorf("\nReason: %s\nfinalizer %s should be removed", reason, RemoteFinalizer)
+						}
+						return nil
+					},
+				},
+				opts: []ReconcilerOption{WithRemoteFinalizer()},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: tinyWait},
+			},
+		},
+		"PendingApproval": {
+			reason: "The claim should not be pushed for the first time until it's approved",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncPending, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "The claim should not be pushed for the first time until it's approved"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, ""))},
+				opts:   []ReconcilerOption{WithApprovalRequired()},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"RemoteDrifted": {
+			reason: "A remote claim whose spec was changed out-of-band should be reported and pushed again",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+							l := claim.New(claim.WithGroupVersionKind(gvk))
+							l.SetAnnotations(map[string]string{
+								resource.AnnotationKeyRemoteUID:      "uid",
+								resource.AnnotationKeyLastPushedSpec: `{"size":"large"}`,
+							})
+							l.Object["spec"] = map[string]interface{}{"size": "large"}
+							l.DeepCopyInto(obj.(*unstructured.Unstructured))
+							return nil
+						},
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncDrifted, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "A remote claim whose spec was changed out-of-band should be reported and pushed again"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{
+					MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+						r := claim.New(claim.WithGroupVersionKind(gvk))
+						r.SetUID("uid")
+						r.SetCreationTimestamp(now)
+						r.Object["spec"] = map[string]interface{}{"size": "small"}
+						r.DeepCopyInto(obj.(*unstructured.Unstructured))
+						return nil
+					},
+					MockPatch: test.NewMockPatchFn(nil),
+				},
+				opts: []ReconcilerOption{
+					WithDriftDetection(),
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+					WithPropagator(PropagateFn(func(_ context.Context, _, _ *claim.Unstructured) error {
+						return nil
+					})),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
+		"MissingRequiredLabels": {
+			reason: "A claim that doesn't have the labels the remote cluster requires should not be pushed",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncMissingLabels, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "A claim that doesn't have the labels the remote cluster requires should not be pushed"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+				opts: []ReconcilerOption{
+					WithLabelPolicy(LabelPolicy{Required: []string{"team"}}),
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"SchemaInvalid": {
+			reason: "A claim that doesn't conform to the schema of the remote cluster should not be pushed",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncSchemaInvalid, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "A claim that doesn't conform to the schema of the remote cluster should not be pushed"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+				opts: []ReconcilerOption{
+					WithSchemaValidator(SchemaValidateFn(func(_ context.Context, _ *claim.Unstructured) (field.ErrorList, error) {
+						return field.ErrorList{field.Required(field.NewPath("spec", "parameters", "size"), "")}, nil
+					})),
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"Rejected": {
+			reason: "A claim that's rejected by the admission gate should not be pushed",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncRejected, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "Claims should report that they were rejected rather than an error"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{
+					MockGet: test.NewMockGetFn(nil),
+					MockPatch: func(_ context.Context, _ runtime.Object, _ client.Patch, _ ...client.PatchOption) error {
+						t.Errorf("\nReason: %s\nthe remote claim should not be pushed", "A claim that's rejected by the admission gate should not be pushed")
+						return nil
+					},
+				},
+				opts: []ReconcilerOption{
+					WithAdmissionGate(AdmitFn(func(_ context.Context, _ *claim.Unstructured) (Admission, error) {
+						return Admission{Message: "size is limited to small"}, nil
+					})),
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"QuotaWouldExceed": {
+			reason: "A claim whose creation would exceed the quotas of the remote namespace should not be pushed",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncQuotaExceeded, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "Claims should report the quotas their creation would exceed"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{
+					MockGet: test.NewMockGetFn(nil),
+					MockPatch: func(_ context.Context, _ runtime.Object, _ client.Patch, _ ...client.PatchOption) error {
+						t.Errorf("\nReason: %s\nthe remote claim should not be created", "A claim whose creation would exceed the quotas of the remote namespace should not be pushed")
+						return nil
+					},
+				},
+				opts: []ReconcilerOption{
+					WithQuotaChecker(QuotaCheckFn(func(_ context.Context, _ *claim.Unstructured) ([]string, error) {
+						return []string{"claims: count/databases.example.org, used 10 of 10"}, nil
+					})),
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"Suspended": {
+			reason: "Nothing should be pushed to the remote cluster while the agent is suspended",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+							got := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+							if diff := cmp.Diff(resource.ReasonAgentSyncSuspended, got.GetCondition(resource.TypeAgentSync).Reason); diff != "" {
+								reason := "Claims should report that the agent is suspended"
+								t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+							}
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{
+					MockGet: test.NewMockGetFn(nil),
+					MockPatch: func(_ context.Context, _ runtime.Object, _ client.Patch, _ ...client.PatchOption) error {
+						t.Errorf("\nReason: %s\nthe remote claim should not be patched", "Nothing should be pushed to the remote cluster while the agent is suspended")
+						return nil
+					},
+				},
+				opts: []ReconcilerOption{
+					WithSuspendSwitch(suspend.SwitchFn(func(_ context.Context) (bool, string, error) {
+						return true, "agent is suspended", nil
+					})),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
+		"Throttled": {
+			reason: "Claims that cannot get a sync slot should be requeued without touching the remote cluster or their status",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, _ runtime.Object, _ ...client.UpdateOption) error {
+							t.Errorf("\nReason: %s\nStatus of a throttled claim should not be updated", "Claims that cannot get a sync slot should be requeued without touching the remote cluster or their status")
+							return nil
+						},
+					},
+				},
+				remote: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, _ runtime.Object) error {
+					t.Errorf("\nReason: %s\nRemote claim of a throttled claim should not be fetched", "Claims that cannot get a sync slot should be requeued without touching the remote cluster or their status")
+					return nil
+				}},
+				opts: []ReconcilerOption{
+					WithSyncThrottle(SyncThrottleFn(func(_ context.Context) error { return context.DeadlineExceeded })),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
+		"NameMapped": {
+			reason: "Claims should be synced to the remote claim with the name the NameMapper maps them to",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet:          test.NewMockGetFn(nil),
+						MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+					},
+				},
+				remote: &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+					if diff := cmp.Diff("tenant-a-cool", key.Name); diff != "" {
+						reason := "Claims should be synced to the remote claim with the name the NameMapper maps them to"
+						t.Errorf("\nReason: %s\n-want, +got:\n%s", reason, diff)
+					}
+					return nil
+				}},
+				opts: []ReconcilerOption{
+					WithNameMapper(NameMapperFn(func(_ types.NamespacedName) string { return "tenant-a-cool" })),
+					WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+						return nil
+					}}),
+					WithPropagator(PropagateFn(func(_ context.Context, _, _ *claim.Unstructured) error {
+						return nil
+					})),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
 		"Successful": {
 			reason: "No error should be returned if everything goes well.",
 			args: args{
@@ -334,3 +943,232 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileRemoteRestored(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		remoteUID types.UID
+		want      bool
+	}{
+		"Regressed": {
+			reason:    "A remote claim whose resource version went back should be reported as restored",
+			remoteUID: "cool-uid",
+			want:      true,
+		},
+		"OtherRemoteClaim": {
+			reason:    "The resource version of a remote claim other than the one it was last seen on should not be compared",
+			remoteUID: "other-uid",
+			want:      false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := false
+			m := &fake.Manager{
+				Client: &test.MockClient{
+					MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+						l := claim.New(claim.WithGroupVersionKind(gvk))
+						l.SetAnnotations(map[string]string{
+							resource.AnnotationKeyRemoteUID:                 "cool-uid",
+							resource.AnnotationKeyLastRemoteResourceVersion: "100",
+						})
+						l.DeepCopyInto(obj.(*unstructured.Unstructured))
+						return nil
+					},
+					MockPatch:        test.NewMockPatchFn(nil),
+					MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+				},
+			}
+			remote := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					obj.(*unstructured.Unstructured).SetUID(tc.remoteUID)
+					obj.(*unstructured.Unstructured).SetResourceVersion("5")
+					return nil
+				},
+				MockPatch: test.NewMockPatchFn(nil),
+			}
+			r := NewReconciler(m, remote, gvk,
+				WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+					return nil
+				}}),
+				WithPropagator(PropagateFn(func(_ context.Context, _, _ *claim.Unstructured) error {
+					return nil
+				})),
+				WithRecorder(recorderFn(func(_ runtime.Object, e event.Event) {
+					if e.Reason == reasonRemoteRestored {
+						got = true
+					}
+				})),
+			)
+			_, _ = r.Reconcile(reconcile.Request{})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want restored, +got restored:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReconcileTrackingAnnotations(t *testing.T) {
+	var got []string
+	m := &fake.Manager{
+		Client: &test.MockClient{
+			MockGet: test.NewMockGetFn(nil),
+			MockPatch: func(_ context.Context, _ runtime.Object, p client.Patch, _ ...client.PatchOption) error {
+				data, _ := p.Data(nil)
+				got = append(got, string(data))
+				return nil
+			},
+			MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+		},
+	}
+	remote := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+			obj.(*unstructured.Unstructured).SetResourceVersion("42")
+			return nil
+		},
+		MockPatch: test.NewMockPatchFn(nil),
+	}
+	r := NewReconciler(m, remote, gvk,
+		WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+			return nil
+		}}),
+		WithPropagator(PropagateFn(func(_ context.Context, _, _ *claim.Unstructured) error {
+			return nil
+		})),
+	)
+	if _, err := r.Reconcile(reconcile.Request{}); err != nil {
+		t.Fatalf("r.Reconcile(...): %s", err)
+	}
+	if len(got) != 1 || !strings.Contains(got[0], `"`+resource.AnnotationKeyLastRemoteResourceVersion+`":"42"`) {
+		t.Errorf("\nReason: %s\nr.Reconcile(...): got patches %v", "What was seen of the remote claim should be recorded even if the Propagator doesn't write the local claim", got)
+	}
+}
+
+type recorderFn func(obj runtime.Object, e event.Event)
+
+func (fn recorderFn) Event(obj runtime.Object, e event.Event) { fn(obj, e) }
+
+func (fn recorderFn) WithAnnotations(_ ...string) event.Recorder { return fn }
+
+func TestReconcileSyncedEvent(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		local  func() *claim.Unstructured
+		want   []event.Reason
+	}{
+		"Recovered": {
+			reason: "A claim that is synced after a failure should be reported as synced",
+			local: func() *claim.Unstructured {
+				l := claim.New(claim.WithGroupVersionKind(gvk))
+				l.SetConditions(resource.AgentSyncError(errBoom))
+				return l
+			},
+			want: []event.Reason{reasonSynced},
+		},
+		"NewGeneration": {
+			reason: "A claim whose new generation is pushed should be reported as synced",
+			local: func() *claim.Unstructured {
+				l := claim.New(claim.WithGroupVersionKind(gvk))
+				l.SetGeneration(2)
+				l.SetConditions(resource.AgentSyncSuccess())
+				_ = SetPropagationLag(l, 1, 0)
+				return l
+			},
+			want: []event.Reason{reasonSynced},
+		},
+		"Unchanged": {
+			reason: "A claim that was already synced should not be reported again",
+			local: func() *claim.Unstructured {
+				l := claim.New(claim.WithGroupVersionKind(gvk))
+				l.SetGeneration(2)
+				l.SetConditions(resource.AgentSyncSuccess())
+				_ = SetPropagationLag(l, 2, 0)
+				return l
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []event.Reason
+			m := &fake.Manager{
+				Client: &test.MockClient{
+					MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+						tc.local().DeepCopyInto(obj.(*unstructured.Unstructured))
+						return nil
+					},
+					MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+				},
+			}
+			r := NewReconciler(m, &test.MockClient{MockGet: test.NewMockGetFn(nil)}, gvk,
+				WithFinalizer(runtimeresource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ runtimeresource.Object) error {
+					return nil
+				}}),
+				WithPropagator(PropagateFn(func(_ context.Context, _, _ *claim.Unstructured) error {
+					return nil
+				})),
+				WithRecorder(recorderFn(func(_ runtime.Object, e event.Event) {
+					got = append(got, e.Reason)
+				})),
+			)
+			if _, err := r.Reconcile(reconcile.Request{}); err != nil {
+				t.Fatalf("r.Reconcile(...): %s", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want events, +got events:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReconcileErrorBackoff(t *testing.T) {
+	m := &fake.Manager{
+		Client: &test.MockClient{
+			MockGet:          test.NewMockGetFn(nil),
+			MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+		},
+	}
+	cases := map[string]struct {
+		reason string
+		err    error
+	}{
+		"Error": {
+			reason: "The wait before retrying a claim should double with each consecutive failure.",
+			err:    errBoom,
+		},
+		"Held": {
+			reason: "The wait before retrying a claim that's held, e.g. because the remote cluster is untrusted, should double too.",
+			err:    errUntrusted,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewReconciler(m, &test.MockClient{MockGet: test.NewMockGetFn(tc.err)}, gvk, WithErrorRetryInterval(10*time.Second, time.Minute))
+			for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+				got, err := r.Reconcile(reconcile.Request{})
+				if err != nil {
+					t.Fatalf("r.Reconcile(...): %s", err)
+				}
+				// Up to a fifth of the wait is added to it as jitter.
+				if got.RequeueAfter < want || got.RequeueAfter > want+want/5 {
+					t.Errorf("\nReason: %s\nr.Reconcile(...): got requeue after %s, want about %s", tc.reason, got.RequeueAfter, want)
+				}
+			}
+		})
+	}
+}
+
+type connectivityFn func() (bool, time.Time, time.Time)
+
+func (fn connectivityFn) Disconnected() (bool, time.Time, time.Time) {
+	return fn()
+}
+
+type onboardingWait time.Duration
+
+func (w onboardingWait) Admit(_ metav1.Object) (bool, time.Duration) {
+	return w == 0, time.Duration(w)
+}
+
+func (w onboardingWait) Progress() onboarding.Progress {
+	return onboarding.Progress{Synced: 1, Total: 2, ETA: time.Duration(w)}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+// A SyncRecorder records what a sync read from both clusters and what it
+// pushed to the remote cluster, e.g. so that the syncs can be replayed against
+// another version of the agent offline.
+type SyncRecorder interface {
+	// RecordSync records the local claim, the remote claim as it was
+	// observed, which wasn't created yet if it doesn't exist, and the remote
+	// claim that is pushed.
+	RecordSync(ctx context.Context, local, observed, pushed *claim.Unstructured) error
+}
+
+// A SyncRenderer renders the remote claims that syncs push the same way the
+// Reconciler does, without reading from or writing to either cluster.
+type SyncRenderer struct {
+	Configurator Configurator
+	Mapper       NamespaceMapper
+	Versions     VersionTable
+	ClusterName  string
+	OwnerLabels  map[string]string
+
+	// RemoteFinalizer adds RemoteFinalizer to the rendered claims.
+	RemoteFinalizer bool
+}
+
+// Render returns the remote claim that a sync of the supplied local claim
+// pushes when the supplied remote claim is observed, which is an empty claim
+// of the remote kind if it doesn't exist yet. The observed claim is left as it
+// is.
+func (s SyncRenderer) Render(ctx context.Context, local, observed *claim.Unstructured) (*claim.Unstructured, error) {
+	remote := &claim.Unstructured{Unstructured: *observed.GetUnstructured().DeepCopy()}
+	if err := s.Configurator.Configure(ctx, local, remote); err != nil {
+		return nil, errors.Wrap(err, errPush)
+	}
+	if c, ok := s.Versions.Lookup(local.GetObjectKind().GroupVersionKind()); ok {
+		if err := c.ToRemote(remote); err != nil {
+			return nil, errors.Wrap(err, errPush)
+		}
+	}
+	remote.SetNamespace(s.Mapper.RemoteNamespace(local.GetNamespace()))
+	SetAuditAnnotations(remote, local, s.ClusterName)
+	SetIdempotencyKey(remote, local)
+	meta.AddLabels(remote, s.OwnerLabels)
+	if s.RemoteFinalizer {
+		meta.AddFinalizer(remote, RemoteFinalizer)
+	}
+	return remote, nil
+}
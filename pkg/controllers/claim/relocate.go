@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+const (
+	errGetPrevious    = "cannot get claim in its previous remote namespace"
+	errGetComposite   = "cannot get composite resource of claim in its previous remote namespace"
+	errDeletePrevious = "cannot delete claim in its previous remote namespace"
+	errFmtRelocated   = "claim is relocated from remote namespace %s to %s"
+)
+
+// A NamespaceMapper decides which remote namespace the claims in a local
+// namespace are synced to.
+type NamespaceMapper interface {
+	RemoteNamespace(local string) string
+}
+
+// A NamespaceMap maps local namespaces to remote ones. Namespaces that are not
+// in the map are synced to the remote namespace with the same name.
+type NamespaceMap map[string]string
+
+// RemoteNamespace returns the remote namespace of the supplied local one.
+func (m NamespaceMap) RemoteNamespace(local string) string {
+	if remote, ok := m[local]; ok {
+		return remote
+	}
+	return local
+}
+
+// NamespaceMapperFn is used to construct a NamespaceMapper with a bare
+// function.
+type NamespaceMapperFn func(local string) string
+
+// RemoteNamespace calls the supplied function.
+func (fn NamespaceMapperFn) RemoteNamespace(local string) string {
+	return fn(local)
+}
+
+// A NameMapper decides the name of the remote claim a local claim is synced
+// to. The name shouldn't change once the claim is synced, since the remote
+// claim under its previous name would be orphaned.
+type NameMapper interface {
+	RemoteName(local types.NamespacedName) string
+}
+
+// NameMapperFn is used to construct a NameMapper with a bare function.
+type NameMapperFn func(local types.NamespacedName) string
+
+// RemoteName calls the supplied function.
+func (fn NameMapperFn) RemoteName(local types.NamespacedName) string {
+	return fn(local)
+}
+
+// SameName is a NameMapper that syncs claims to remote claims with the same
+// name.
+type SameName struct{}
+
+// RemoteName returns the name of the supplied local claim.
+func (SameName) RemoteName(local types.NamespacedName) string {
+	return local.Name
+}
+
+// getPrevious returns the remote claim in the supplied namespace that was
+// synced before the mapping of its namespace changed, or nil if it's gone.
+func (r *Reconciler) getPrevious(ctx context.Context, namespace, name string) (*claim.Unstructured, error) {
+	previous := r.newRemoteInstance()
+	err := r.remote.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, previous)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return previous, errors.Wrap(err, remotePrefix+errGetPrevious)
+}
+
+// preserveExternalName carries the external name of the previous remote claim,
+// or of its composite resource, over to the relocated one so that the
+// composite resource that's created for it adopts the same external resources.
+func (r *Reconciler) preserveExternalName(ctx context.Context, previous, relocated *claim.Unstructured) error {
+	if n := meta.GetExternalName(previous); n != "" {
+		meta.SetExternalName(relocated, n)
+		return nil
+	}
+	ref := previous.GetResourceReference()
+	if ref == nil {
+		return nil
+	}
+	cp := &kunstructured.Unstructured{}
+	cp.SetAPIVersion(ref.APIVersion)
+	cp.SetKind(ref.Kind)
+	if err := r.remote.Get(ctx, types.NamespacedName{Name: ref.Name}, cp); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, remotePrefix+errGetComposite)
+	}
+	if n := meta.GetExternalName(cp); n != "" {
+		meta.SetExternalName(relocated, n)
+	}
+	return nil
+}
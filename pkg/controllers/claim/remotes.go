@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errHandOver         = "cannot hand claim over to another remote cluster"
+	errForgetHandedOver = "cannot forget remote claim that was handed over"
+)
+
+// A Remote is a remote cluster that claims are synced to in addition to the
+// default one, sharing the local cache with it. Only the local claims whose
+// LabelKeyRemote label names it are synced to it.
+type Remote struct {
+	// Name is the value of LabelKeyRemote that claims are synced to the
+	// remote cluster with.
+	Name string
+
+	// Client reads from and writes to the remote cluster.
+	Client client.Client
+
+	// Options configure the claim Reconcilers of the remote cluster in
+	// addition to the ones of the default remote cluster.
+	Options []ReconcilerOption
+}
+
+// RemoteNames returns the names of the supplied Remotes.
+func RemoteNames(rs []Remote) []string {
+	names := make([]string, len(rs))
+	for i, rm := range rs {
+		names[i] = rm.Name
+	}
+	return names
+}
+
+// RemoteControllerName returns the name of the controller that syncs claims
+// to the supplied remote cluster in place of the controller with the supplied
+// name, which syncs them to the default one.
+func RemoteControllerName(name, remote string) string {
+	if remote == "" {
+		return name
+	}
+	return name + "/" + remote
+}
+
+// KnownRemote returns true if the supplied name is either empty, i.e. names
+// the default remote cluster, or the name of one of the supplied ones.
+func KnownRemote(name string, remotes []string) bool {
+	if name == "" {
+		return true
+	}
+	for _, rm := range remotes {
+		if rm == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteOf returns which of the supplied remote clusters the supplied claim is
+// labelled to be synced to, or an empty string if it's the default one, or if
+// it's labelled with the name of a remote cluster that's not supplied.
+func RemoteOf(o metav1.Object, remotes []string) string {
+	name := o.GetLabels()[resource.LabelKeyRemote]
+	for _, rm := range remotes {
+		if rm == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// route returns true if the Reconciler should sync the supplied claim. A claim
+// is synced to the remote cluster it's labelled with, but only once the
+// Reconciler of the remote cluster it was synced to before, if any, has
+// handed it over. Claims that are labelled with, or were synced to, a remote
+// cluster that's not configured are held rather than synced to the default
+// one. Otherwise the result and the error of the pass are returned.
+func (r *Reconciler) route(ctx context.Context, log logging.Logger, req reconcile.Request, localClaim *claim.Unstructured) (bool, reconcile.Result, error) {
+	target := localClaim.GetLabels()[resource.LabelKeyRemote]
+	synced := localClaim.GetAnnotations()[resource.AnnotationKeyRemoteUID] != ""
+	was, recorded := localClaim.GetAnnotations()[resource.AnnotationKeyRemoteCluster]
+	if !recorded {
+		// Claims synced before the remote cluster was recorded were synced
+		// to the one they're labelled with.
+		was = RemoteOf(localClaim, r.remotes)
+	}
+
+	switch {
+	case synced && was == r.remoteName && (target == r.remoteName || meta.WasDeleted(localClaim)):
+		return true, reconcile.Result{}, nil
+	case synced && was == r.remoteName && !KnownRemote(target, r.remotes):
+		return false, r.unknownRemote(ctx, log, localClaim, target)
+	case synced && was == r.remoteName:
+		res, err := r.handOver(ctx, log, req, localClaim)
+		return false, res, err
+	case synced && target == r.remoteName && !KnownRemote(was, r.remotes):
+		return false, r.unknownRemote(ctx, log, localClaim, was)
+	case synced:
+		log.Debug("Claim is synced to another remote cluster", "remote", was)
+		return false, reconcile.Result{Requeue: false}, nil
+	case target == r.remoteName:
+		return true, reconcile.Result{}, nil
+	case r.remoteName == "" && !KnownRemote(target, r.remotes):
+		return false, r.unknownRemote(ctx, log, localClaim, target)
+	}
+	log.Debug("Claim is routed to another remote cluster", "remote", target)
+	return false, reconcile.Result{Requeue: false}, nil
+}
+
+// unknownRemote holds a claim that's labelled with, or was synced to, the
+// supplied remote cluster, which is not configured.
+func (r *Reconciler) unknownRemote(ctx context.Context, log logging.Logger, localClaim *claim.Unstructured, name string) (reconcile.Result, error) {
+	log.Debug("Claim is held because its remote cluster is not configured", "remote", name, "requeue-after", time.Now().Add(r.syncInterval))
+	localClaim.SetConditions(resource.AgentSyncUnknownRemote(name))
+	return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+}
+
+// handOver a claim that's now labelled with another remote cluster to the
+// Reconciler of that remote cluster. The remote claim it was synced to is
+// deleted from the remote cluster of the Reconciler, then what was seen of it
+// is forgotten so that the claim is synced as a new one from there on.
+func (r *Reconciler) handOver(ctx context.Context, log logging.Logger, req reconcile.Request, localClaim *claim.Unstructured) (reconcile.Result, error) {
+	log = log.WithValues("remote", localClaim.GetLabels()[resource.LabelKeyRemote])
+	ns := localClaim.GetAnnotations()[resource.AnnotationKeyRemoteNamespace]
+	if ns == "" {
+		ns = r.mapper.RemoteNamespace(req.Namespace)
+	}
+	rc, err := r.getPrevious(ctx, ns, r.names.RemoteName(req.NamespacedName))
+	if err != nil {
+		log.Debug("Cannot get remote claim to hand over", "error", err, "requeue-after", time.Now().Add(shortWait))
+		localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, errHandOver)))
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+	}
+
+	// Only the remote claim that the claim was synced to is deleted, never
+	// one that took its place.
+	if rc != nil && string(rc.GetUID()) == localClaim.GetAnnotations()[resource.AnnotationKeyRemoteUID] {
+		uid := rc.GetUID()
+		if err := r.deleteRemote(ctx, rc, client.Preconditions{UID: &uid}); runtimeresource.IgnoreNotFound(err) != nil {
+			log.Debug("Cannot delete remote claim that's handed over", "error", err, "requeue-after", time.Now().Add(shortWait))
+			r.record.Event(localClaim, event.Warning(reasonCannotDelete, err))
+			localClaim.SetConditions(resource.AgentSyncError(errors.Wrap(err, remotePrefix+errHandOver)))
+			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(r.local.Status().Update(ctx, localClaim), errStatusUpdateClaim)
+		}
+	}
+
+	for _, k := range trackingAnnotations {
+		resource.SetAnnotation(localClaim, k, "")
+	}
+	if err := persistTrackedAnnotations(ctx, r.local, localClaim); err != nil {
+		log.Debug(errForgetHandedOver, "error", err, "requeue-after", time.Now().Add(shortWait))
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errForgetHandedOver)
+	}
+	log.Info("Handed claim over to another remote cluster")
+	return reconcile.Result{Requeue: false}, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestRemoteOf(t *testing.T) {
+	type args struct {
+		o       metav1.Object
+		remotes []string
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"Unlabelled": {
+			reason: "A claim without the remote label should be synced to the default remote cluster",
+			args: args{
+				o:       &metav1.ObjectMeta{},
+				remotes: []string{"west"},
+			},
+			want: "",
+		},
+		"Labelled": {
+			reason: "A claim labelled with a known remote cluster should be synced to it",
+			args: args{
+				o:       &metav1.ObjectMeta{Labels: map[string]string{resource.LabelKeyRemote: "west"}},
+				remotes: []string{"east", "west"},
+			},
+			want: "west",
+		},
+		"UnknownRemote": {
+			reason: "A claim labelled with an unknown remote cluster should not be routed to any of the supplied ones",
+			args: args{
+				o:       &metav1.ObjectMeta{Labels: map[string]string{resource.LabelKeyRemote: "north"}},
+				remotes: []string{"east", "west"},
+			},
+			want: "",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := RemoteOf(tc.args.o, tc.args.remotes)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nRemoteOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	type args struct {
+		remote      string
+		labels      map[string]string
+		annotations map[string]string
+	}
+	type want struct {
+		sync    bool
+		reason  v1alpha1.ConditionReason
+		deleted bool
+		forgot  bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Default": {
+			reason: "A claim without the remote label should be synced to the default remote cluster",
+			args:   args{},
+			want:   want{sync: true},
+		},
+		"UnknownRemote": {
+			reason: "A claim labelled with an unknown remote cluster should be held rather than synced to the default one",
+			args: args{
+				labels: map[string]string{resource.LabelKeyRemote: "north"},
+			},
+			want: want{reason: resource.ReasonAgentSyncUnknownRemote},
+		},
+		"RoutedElsewhere": {
+			reason: "A claim labelled with another remote cluster should be left alone",
+			args: args{
+				labels: map[string]string{resource.LabelKeyRemote: "west"},
+			},
+			want: want{},
+		},
+		"HandOver": {
+			reason: "A claim that's relabelled after it was synced should have its remote claim deleted and be forgotten",
+			args: args{
+				labels:      map[string]string{resource.LabelKeyRemote: "west"},
+				annotations: map[string]string{resource.AnnotationKeyRemoteUID: "cool-uid", resource.AnnotationKeyRemoteCluster: ""},
+			},
+			want: want{deleted: true, forgot: true},
+		},
+		"WaitForHandOver": {
+			reason: "A claim that's relabelled should not be synced until it's handed over",
+			args: args{
+				remote:      "west",
+				labels:      map[string]string{resource.LabelKeyRemote: "west"},
+				annotations: map[string]string{resource.AnnotationKeyRemoteUID: "cool-uid", resource.AnnotationKeyRemoteCluster: ""},
+			},
+			want: want{},
+		},
+		"Synced": {
+			reason: "A claim that was synced to the remote cluster it's labelled with should be synced",
+			args: args{
+				remote:      "west",
+				labels:      map[string]string{resource.LabelKeyRemote: "west"},
+				annotations: map[string]string{resource.AnnotationKeyRemoteUID: "cool-uid", resource.AnnotationKeyRemoteCluster: "west"},
+			},
+			want: want{sync: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			m := &fake.Manager{
+				Client: &test.MockClient{
+					MockPatch: func(_ context.Context, _ runtime.Object, _ client.Patch, _ ...client.PatchOption) error {
+						got.forgot = true
+						return nil
+					},
+					MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+						c := &claim.Unstructured{Unstructured: *obj.(*unstructured.Unstructured)}
+						got.reason = c.GetCondition(resource.TypeAgentSync).Reason
+						return nil
+					},
+				},
+			}
+			remote := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					obj.(*unstructured.Unstructured).SetUID("cool-uid")
+					return nil
+				},
+				MockDelete: func(_ context.Context, _ runtime.Object, _ ...client.DeleteOption) error {
+					got.deleted = true
+					return nil
+				},
+			}
+			r := NewReconciler(m, remote, gvk, WithRemoteRouting(tc.args.remote, []string{"west"}))
+			cl := claim.New(claim.WithGroupVersionKind(gvk))
+			cl.SetLabels(tc.args.labels)
+			cl.SetAnnotations(tc.args.annotations)
+
+			got.sync, _, _ = r.route(context.Background(), logging.NewNopLogger(), reconcile.Request{}, cl)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nr.route(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+// Render returns the remote claim that would be pushed for the supplied local
+// claim when it doesn't exist in the remote cluster yet. Only what the supplied
+// Configurator copies from the local claim is kept, and the remote claim is
+// put in the namespace the supplied NamespaceMapper maps its namespace to.
+func Render(ctx context.Context, c Configurator, m NamespaceMapper, local *claim.Unstructured) (*claim.Unstructured, error) {
+	remote := claim.New(claim.WithGroupVersionKind(local.GetObjectKind().GroupVersionKind()))
+	if err := c.Configure(ctx, local, remote); err != nil {
+		return nil, errors.Wrap(err, errPush)
+	}
+	remote.SetNamespace(m.RemoteNamespace(local.GetNamespace()))
+	return remote, nil
+}
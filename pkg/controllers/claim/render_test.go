@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestRender(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	local := func() *claim.Unstructured {
+		c := claim.New(claim.WithGroupVersionKind(gvk))
+		c.SetName("cool-claim")
+		c.SetNamespace("team-a")
+		c.SetUID("cool-uid")
+		c.SetResourceVersion("42")
+		c.SetLabels(map[string]string{"cool": "label"})
+		c.Object["spec"] = map[string]interface{}{"storageGB": int64(20)}
+		return c
+	}
+	type want struct {
+		remote *claim.Unstructured
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		c      Configurator
+		m      NamespaceMapper
+		want   want
+	}{
+		"Rendered": {
+			reason: "The remote claim should only have what is configured from the local claim, in the mapped namespace",
+			c:      NewDefaultConfigurator(),
+			m:      NamespaceMap{"team-a": "prod-team-a"},
+			want: want{
+				remote: func() *claim.Unstructured {
+					c := claim.New(claim.WithGroupVersionKind(gvk))
+					c.SetName("cool-claim")
+					c.SetNamespace("prod-team-a")
+					c.SetLabels(map[string]string{"cool": "label"})
+					c.Object["spec"] = map[string]interface{}{"storageGB": int64(20)}
+					return c
+				}(),
+			},
+		},
+		"ConfigureFailed": {
+			reason: "An error should be returned if the remote claim cannot be configured",
+			c: ConfiguratorFn(func(_ context.Context, _, _ *claim.Unstructured) error {
+				return errBoom
+			}),
+			m: NamespaceMap(nil),
+			want: want{
+				err: errors.Wrap(errBoom, errPush),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Render(context.Background(), tc.c, tc.m, local())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nRender(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.remote, got); diff != "" {
+				t.Errorf("\nReason: %s\nRender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+// fieldPathResolved is where the fields that the remote cluster filled in are
+// recorded in the status of the local claim.
+var fieldPathResolved = []string{"status", "agent", "resolved"}
+
+// NewResolvedDefaultsPropagator returns a new *ResolvedDefaultsPropagator.
+func NewResolvedDefaultsPropagator() *ResolvedDefaultsPropagator {
+	return &ResolvedDefaultsPropagator{}
+}
+
+// ResolvedDefaultsPropagator records the fields of the spec of the remote claim
+// that are not in the spec of the local claim, i.e. the ones filled in by
+// admission webhooks or defaulting in the remote cluster, in the status of the
+// local claim so that users can see what their claim resolved to. They're
+// never copied to the spec of the local claim.
+type ResolvedDefaultsPropagator struct{}
+
+// Propagate records the resolved fields of the remote claim in the local one.
+func (p *ResolvedDefaultsPropagator) Propagate(_ context.Context, local, remote *claim.Unstructured) error {
+	rs, _, err := kunstructured.NestedMap(remote.GetUnstructured().Object, "spec")
+	if err != nil {
+		return err
+	}
+	ls, _, err := kunstructured.NestedMap(local.GetUnstructured().Object, "spec")
+	if err != nil {
+		return err
+	}
+	resolved := ResolvedFields(ls, rs)
+	if len(resolved) == 0 {
+		kunstructured.RemoveNestedField(local.GetUnstructured().Object, fieldPathResolved...)
+		return nil
+	}
+	return kunstructured.SetNestedMap(local.GetUnstructured().Object, resolved, fieldPathResolved...)
+}
+
+// ResolvedFields returns the fields of the remote object that are not in the
+// local object, keeping their structure. Fields that are in both are not
+// compared, the ones in the local object win.
+func ResolvedFields(local, remote map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, rv := range remote {
+		lv, ok := local[k]
+		if !ok {
+			out[k] = runtime.DeepCopyJSONValue(rv)
+			continue
+		}
+		lm, lok := lv.(map[string]interface{})
+		rm, rok := rv.(map[string]interface{})
+		if !lok || !rok {
+			continue
+		}
+		if sub := ResolvedFields(lm, rm); len(sub) > 0 {
+			out[k] = sub
+		}
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+func TestResolvedDefaultsPropagator(t *testing.T) {
+	withSpec := func(spec map[string]interface{}) *claim.Unstructured {
+		c := claim.New()
+		c.Object["spec"] = spec
+		return c
+	}
+	cases := map[string]struct {
+		reason string
+		local  *claim.Unstructured
+		remote *claim.Unstructured
+		want   map[string]interface{}
+	}{
+		"Resolved": {
+			reason: "Fields that only the remote claim has should be recorded, keeping their structure",
+			local: withSpec(map[string]interface{}{
+				"parameters": map[string]interface{}{"size": "large"},
+			}),
+			remote: withSpec(map[string]interface{}{
+				"parameters":          map[string]interface{}{"size": "small", "region": "eu-west-1"},
+				"compositionSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"provider": "aws"}},
+			}),
+			want: map[string]interface{}{
+				"status": map[string]interface{}{"agent": map[string]interface{}{"resolved": map[string]interface{}{
+					"parameters":          map[string]interface{}{"region": "eu-west-1"},
+					"compositionSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"provider": "aws"}},
+				}}},
+			},
+		},
+		"NothingResolved": {
+			reason: "Previously recorded fields should be removed once the remote claim has nothing the local one doesn't",
+			local: func() *claim.Unstructured {
+				c := withSpec(map[string]interface{}{"size": "large"})
+				c.Object["status"] = map[string]interface{}{"agent": map[string]interface{}{"resolved": map[string]interface{}{"region": "eu-west-1"}}}
+				return c
+			}(),
+			remote: withSpec(map[string]interface{}{"size": "small"}),
+			want:   map[string]interface{}{"status": map[string]interface{}{"agent": map[string]interface{}{}}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec := tc.local.Object["spec"]
+			if err := NewResolvedDefaultsPropagator().Propagate(context.Background(), tc.local, tc.remote); err != nil {
+				t.Fatalf("\nReason: %s\nPropagate(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(spec, tc.local.Object["spec"]); diff != "" {
+				t.Errorf("\nReason: %s\nPropagate(...): the spec should not be changed: -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want["status"], tc.local.Object["status"]); diff != "" {
+				t.Errorf("\nReason: %s\nPropagate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const errGetNamespace = "cannot get namespace"
+
+// trackingAnnotations are the annotations the Reconciler records on a local
+// claim to tell, in the next passes, what it has seen of its remote claim.
+var trackingAnnotations = []string{
+	resource.AnnotationKeyLastRemoteResourceVersion,
+	resource.AnnotationKeyResyncEpoch,
+	resource.AnnotationKeyResyncHandled,
+	resource.AnnotationKeyRemoteUID,
+	resource.AnnotationKeyRemoteNamespace,
+	resource.AnnotationKeyRemoteCluster,
+}
+
+// trackedAnnotations returns the tracking annotations of the supplied claim.
+func trackedAnnotations(c *claim.Unstructured) map[string]string {
+	out := map[string]string{}
+	for _, k := range trackingAnnotations {
+		if v, ok := c.GetAnnotations()[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// persistTrackedAnnotations writes the tracking annotations of the supplied
+// claim, and nothing else, to the supplied client. The supplied claim gets the
+// new resource version so that it can still be written afterwards, but is
+// otherwise left as is since it may carry changes that aren't written yet.
+func persistTrackedAnnotations(ctx context.Context, c client.Client, cl *claim.Unstructured) error {
+	a := map[string]interface{}{}
+	for _, k := range trackingAnnotations {
+		a[k] = nil
+		if v, ok := cl.GetAnnotations()[k]; ok {
+			a[k] = v
+		}
+	}
+	p, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": a}})
+	if err != nil {
+		return err
+	}
+	written := cl.DeepCopy()
+	if err := c.Patch(ctx, written, client.RawPatch(types.MergePatchType, p)); err != nil {
+		return err
+	}
+	cl.SetResourceVersion(written.GetResourceVersion())
+	return nil
+}
+
+// ResourceVersionRegressed returns true if the current resource version of an
+// object is older than the last one that was observed, which happens only if
+// the etcd of its api-server was restored from a backup. Resource versions are
+// opaque, so only the ones that are integers, as etcd produces, are compared.
+func ResourceVersionRegressed(last, current string) bool {
+	l, err := strconv.ParseUint(last, 10, 64)
+	if err != nil {
+		return false
+	}
+	c, err := strconv.ParseUint(current, 10, 64)
+	if err != nil {
+		return false
+	}
+	return c < l
+}
+
+// IsRestoreError returns true if the supplied error is returned by an
+// api-server whose state is older than the resource version that was sent.
+func IsRestoreError(err error) bool {
+	err = errors.Cause(err)
+	return kerrors.IsResourceExpired(err) || kerrors.IsGone(err)
+}
+
+// NewResyncTrigger returns a new *ResyncTrigger.
+func NewResyncTrigger() *ResyncTrigger {
+	return &ResyncTrigger{now: time.Now}
+}
+
+// A ResyncTrigger starts a new epoch every time it's triggered. Claims that
+// haven't been synced in the current epoch go through a full verification,
+// i.e. their remote state is overwritten rather than merged.
+type ResyncTrigger struct {
+	now func() time.Time
+
+	mu    sync.RWMutex
+	epoch string
+}
+
+// Trigger a full verification resync of all claims.
+func (t *ResyncTrigger) Trigger() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.epoch = strconv.FormatInt(t.now().UnixNano(), 10)
+}
+
+// Epoch returns the current epoch. It's empty until the first trigger.
+func (t *ResyncTrigger) Epoch() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.epoch
+}
+
+// A ResyncRequestFn returns the latest full resync that was requested for the
+// supplied claim, or an empty string if none was.
+type ResyncRequestFn func(ctx context.Context, c *claim.Unstructured) (string, error)
+
+// NewClaimResyncRequest returns a ResyncRequestFn that reads the request from
+// the resync annotation of the claim.
+func NewClaimResyncRequest() ResyncRequestFn {
+	return func(_ context.Context, c *claim.Unstructured) (string, error) {
+		return c.GetAnnotations()[resource.AnnotationKeyResync], nil
+	}
+}
+
+// NewNamespaceResyncRequest returns a ResyncRequestFn that reads the request
+// from the resync annotation of either the claim or its namespace, whichever
+// is the latest. Values are compared as strings, so that timestamps in RFC3339
+// form in UTC are compared in time order.
+func NewNamespaceResyncRequest(c client.Reader) ResyncRequestFn {
+	return func(ctx context.Context, cl *claim.Unstructured) (string, error) {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: cl.GetNamespace()}, ns); err != nil {
+			return "", errors.Wrap(err, errGetNamespace)
+		}
+		req := cl.GetAnnotations()[resource.AnnotationKeyResync]
+		if v := ns.GetAnnotations()[resource.AnnotationKeyResync]; v > req {
+			req = v
+		}
+		return req, nil
+	}
+}
+
+// EnqueueRequestsForResyncedNamespace returns an EventHandler that enqueues
+// all claims of the supplied kind in a namespace that has the resync
+// annotation whenever the namespace changes.
+func EnqueueRequestsForResyncedNamespace(c client.Reader, gvk schema.GroupVersionKind) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+		if _, ok := o.Meta.GetAnnotations()[resource.AnnotationKeyResync]; !ok {
+			return nil
+		}
+		l := &kunstructured.UnstructuredList{}
+		l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := c.List(context.Background(), l, client.InNamespace(o.Meta.GetName())); err != nil {
+			return nil
+		}
+		reqs := make([]reconcile.Request, len(l.Items))
+		for i, item := range l.Items {
+			reqs[i] = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()}}
+		}
+		return reqs
+	})}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestResourceVersionRegressed(t *testing.T) {
+	type args struct {
+		last    string
+		current string
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   bool
+	}{
+		"NeverSeen": {
+			reason: "There is no regression if no resource version was observed before",
+			args:   args{current: "10"},
+			want:   false,
+		},
+		"Newer": {
+			reason: "There is no regression if the resource version went forward",
+			args:   args{last: "10", current: "12"},
+			want:   false,
+		},
+		"Older": {
+			reason: "There is a regression if the resource version went back",
+			args:   args{last: "10", current: "9"},
+			want:   true,
+		},
+		"Opaque": {
+			reason: "Resource versions that are not integers cannot be compared",
+			args:   args{last: "b", current: "a"},
+			want:   false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ResourceVersionRegressed(tc.args.last, tc.args.current)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nResourceVersionRegressed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNamespaceResyncRequest(t *testing.T) {
+	type want struct {
+		req string
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		claim  string
+		ns     string
+		getErr error
+		want   want
+	}{
+		"None": {
+			reason: "No resync should be requested if neither the claim nor its namespace is annotated",
+			want:   want{},
+		},
+		"Claim": {
+			reason: "The request on the claim should be returned if it's later than the one on its namespace",
+			claim:  "2020-10-11T00:00:00Z",
+			ns:     "2020-10-10T00:00:00Z",
+			want:   want{req: "2020-10-11T00:00:00Z"},
+		},
+		"Namespace": {
+			reason: "The request on the namespace should be returned if it's later than the one on the claim",
+			claim:  "2020-10-10T00:00:00Z",
+			ns:     "2020-10-12T00:00:00Z",
+			want:   want{req: "2020-10-12T00:00:00Z"},
+		},
+		"GetNamespaceFailed": {
+			reason: "An error should be returned if the namespace cannot be read",
+			getErr: errBoom,
+			want:   want{err: errors.Wrap(errBoom, errGetNamespace)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+				if tc.ns != "" {
+					obj.(*corev1.Namespace).SetAnnotations(map[string]string{resource.AnnotationKeyResync: tc.ns})
+				}
+				return tc.getErr
+			}}
+			cl := claim.New()
+			cl.SetNamespace("default")
+			if tc.claim != "" {
+				cl.SetAnnotations(map[string]string{resource.AnnotationKeyResync: tc.claim})
+			}
+			req, err := NewNamespaceResyncRequest(c)(context.Background(), cl)
+			if diff := cmp.Diff(tc.want, want{req: req, err: err}, test.EquateErrors(), cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nNewNamespaceResyncRequest(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+const (
+	errListCRDs        = "cannot list custom resource definitions"
+	errGetSchemaCRD    = "cannot get custom resource definition"
+	errConvertSchema   = "cannot convert schema of custom resource definition"
+	errSchemaValidator = "cannot build schema validator"
+	errFmtNoCRD        = "no custom resource definition of %s"
+)
+
+// A SchemaValidator validates the remote claim against the schema the remote
+// cluster serves it with before it's pushed, and returns the fields that
+// don't conform to it.
+type SchemaValidator interface {
+	Validate(ctx context.Context, remote *claim.Unstructured) (field.ErrorList, error)
+}
+
+// SchemaValidateFn is used to construct a SchemaValidator with a bare
+// function.
+type SchemaValidateFn func(ctx context.Context, remote *claim.Unstructured) (field.ErrorList, error)
+
+// Validate calls the supplied function.
+func (fn SchemaValidateFn) Validate(ctx context.Context, remote *claim.Unstructured) (field.ErrorList, error) {
+	return fn(ctx, remote)
+}
+
+// NewNopSchemaValidator returns a SchemaValidator that accepts everything.
+func NewNopSchemaValidator() SchemaValidateFn {
+	return func(_ context.Context, _ *claim.Unstructured) (field.ErrorList, error) { return nil, nil }
+}
+
+// NewAPISchemaValidator returns a new *APISchemaValidator that finds the CRD
+// of claims through the supplied local reader and reads its schema through
+// the supplied remote one.
+func NewAPISchemaValidator(local, remote client.Reader) *APISchemaValidator {
+	return &APISchemaValidator{local: local, remote: remote, validators: map[string]schemaValidator{}}
+}
+
+// An APISchemaValidator validates remote claims against the structural schema
+// of their CRD in the remote cluster, the same way its api-server would, so
+// that claims that were created while the local CRD lagged behind the remote
+// one are reported with the exact fields that don't conform to it. Validators
+// are built once per version of the CRD.
+type APISchemaValidator struct {
+	local  client.Reader
+	remote client.Reader
+
+	mu         sync.Mutex
+	validators map[string]schemaValidator
+}
+
+// A schemaValidator validates objects against the schema of a version of a
+// CRD as of the supplied resource version of the CRD.
+type schemaValidator struct {
+	resourceVersion string
+	validate        func(obj interface{}) field.ErrorList
+}
+
+// Validate the supplied remote claim against the schema of its version in the
+// remote cluster. Its status is not validated since it's not pushed.
+func (v *APISchemaValidator) Validate(ctx context.Context, remote *claim.Unstructured) (field.ErrorList, error) {
+	gvk := remote.GetObjectKind().GroupVersionKind()
+	name, err := v.crdName(ctx, gvk.GroupKind())
+	if err != nil {
+		return nil, err
+	}
+	crd := &v1beta1.CustomResourceDefinition{}
+	if err := v.remote.Get(ctx, types.NamespacedName{Name: name}, crd); err != nil {
+		return nil, errors.Wrap(err, remotePrefix+errGetSchemaCRD)
+	}
+	validate, err := v.validator(crd, gvk.Version)
+	if err != nil || validate == nil {
+		return nil, err
+	}
+	obj := remote.GetUnstructured().DeepCopy().UnstructuredContent()
+	delete(obj, "status")
+	return validate(obj), nil
+}
+
+// crdName returns the name of the CRD of the supplied kind, which is the same
+// in both clusters.
+func (v *APISchemaValidator) crdName(ctx context.Context, gk schema.GroupKind) (string, error) {
+	crd, err := findCRD(ctx, v.local, gk)
+	if err != nil {
+		return "", err
+	}
+	return crd.GetName(), nil
+}
+
+// findCRD returns the CRD of the supplied kind.
+func findCRD(ctx context.Context, c client.Reader, gk schema.GroupKind) (*v1beta1.CustomResourceDefinition, error) {
+	l := &v1beta1.CustomResourceDefinitionList{}
+	if err := c.List(ctx, l); err != nil {
+		return nil, errors.Wrap(err, localPrefix+errListCRDs)
+	}
+	for i := range l.Items {
+		if l.Items[i].Spec.Group == gk.Group && l.Items[i].Spec.Names.Kind == gk.Kind {
+			return &l.Items[i], nil
+		}
+	}
+	return nil, errors.Errorf(errFmtNoCRD, gk)
+}
+
+// validator returns the function that validates objects of the supplied
+// version against the schema of the supplied CRD, or nil if it has none.
+func (v *APISchemaValidator) validator(crd *v1beta1.CustomResourceDefinition, version string) (func(interface{}) field.ErrorList, error) {
+	s := crd.Spec.Validation
+	for _, ver := range crd.Spec.Versions {
+		if ver.Name == version && ver.Schema != nil {
+			s = ver.Schema
+		}
+	}
+	if s == nil {
+		return nil, nil
+	}
+
+	key := crd.GetName() + "/" + version
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if sv, ok := v.validators[key]; ok && sv.resourceVersion == crd.GetResourceVersion() {
+		return sv.validate, nil
+	}
+	in := &apiextensions.CustomResourceValidation{}
+	if err := v1beta1.Convert_v1beta1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(s, in, nil); err != nil {
+		return nil, errors.Wrap(err, errConvertSchema)
+	}
+	sv, _, err := validation.NewSchemaValidator(in)
+	if err != nil {
+		return nil, errors.Wrap(err, errSchemaValidator)
+	}
+	fn := func(obj interface{}) field.ErrorList { return validation.ValidateCustomResource(nil, obj, sv) }
+	v.validators[key] = schemaValidator{resourceVersion: crd.GetResourceVersion(), validate: fn}
+	return fn, nil
+}
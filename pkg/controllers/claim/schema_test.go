@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestAPISchemaValidator(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	local := &test.MockClient{MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+		crd := v1beta1.CustomResourceDefinition{}
+		crd.SetName("mysqlinstances.example.org")
+		crd.Spec.Group = gvk.Group
+		crd.Spec.Names.Kind = gvk.Kind
+		obj.(*v1beta1.CustomResourceDefinitionList).Items = []v1beta1.CustomResourceDefinition{crd}
+		return nil
+	}}
+	remote := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+		if key.Name != "mysqlinstances.example.org" {
+			t.Errorf("Get(...): unexpected CRD %s", key.Name)
+		}
+		crd := obj.(*v1beta1.CustomResourceDefinition)
+		crd.SetName(key.Name)
+		crd.Spec.Versions = []v1beta1.CustomResourceDefinitionVersion{{
+			Name:   gvk.Version,
+			Served: true,
+			Schema: &v1beta1.CustomResourceValidation{OpenAPIV3Schema: &v1beta1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]v1beta1.JSONSchemaProps{
+					"spec": {
+						Type:     "object",
+						Required: []string{"size"},
+						Properties: map[string]v1beta1.JSONSchemaProps{
+							"size": {Type: "integer"},
+						},
+					},
+				},
+			}},
+		}}
+		return nil
+	}}
+	newClaim := func(k schema.GroupVersionKind, spec map[string]interface{}) *claim.Unstructured {
+		c := claim.New(claim.WithGroupVersionKind(k))
+		c.Object["spec"] = spec
+		c.Object["status"] = map[string]interface{}{"size": "unchecked"}
+		return c
+	}
+
+	type want struct {
+		fields []string
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		claim  *claim.Unstructured
+		want   want
+	}{
+		"Valid": {
+			reason: "A claim that conforms to the schema should be valid",
+			claim:  newClaim(gvk, map[string]interface{}{"size": int64(2)}),
+			want:   want{fields: []string{}},
+		},
+		"Invalid": {
+			reason: "The fields of a claim that don't conform to the schema should be returned",
+			claim:  newClaim(gvk, map[string]interface{}{"size": "big"}),
+			want:   want{fields: []string{"spec.size"}},
+		},
+		"Missing": {
+			reason: "The required fields a claim doesn't have should be returned",
+			claim:  newClaim(gvk, map[string]interface{}{}),
+			want:   want{fields: []string{"spec.size"}},
+		},
+		"NoCRD": {
+			reason: "An error should be returned if the CRD of the claim is unknown",
+			claim:  newClaim(schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "Bucket"}, nil),
+			want:   want{fields: []string{}, err: errors.Errorf(errFmtNoCRD, schema.GroupKind{Group: "example.org", Kind: "Bucket"})},
+		},
+	}
+	v := NewAPISchemaValidator(local, remote)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := v.Validate(context.Background(), tc.claim)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nValidate(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			fields := []string{}
+			for _, e := range got {
+				fields = append(fields, e.Field)
+			}
+			if diff := cmp.Diff(tc.want.fields, fields); diff != "" {
+				t.Errorf("\nReason: %s\nValidate(...): -want fields, +got fields:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
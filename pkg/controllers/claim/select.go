@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+// A ClaimSelector selects the local claims that are synced to the remote
+// cluster.
+type ClaimSelector struct {
+	labels     labels.Selector
+	namespaces map[string]bool
+}
+
+// NewClaimSelector returns a ClaimSelector that selects the claims that match
+// the supplied label selector and that are in one of the supplied namespaces.
+// A nil label selector matches all claims, and so do no namespaces.
+func NewClaimSelector(l labels.Selector, namespaces ...string) *ClaimSelector {
+	s := &ClaimSelector{labels: l}
+	if len(namespaces) > 0 {
+		s.namespaces = make(map[string]bool, len(namespaces))
+		for _, ns := range namespaces {
+			s.namespaces[ns] = true
+		}
+	}
+	return s
+}
+
+// Selects returns true if the supplied claim should be synced. A nil
+// ClaimSelector selects all claims.
+func (s *ClaimSelector) Selects(o metav1.Object) bool {
+	if s == nil {
+		return true
+	}
+	if s.namespaces != nil && !s.namespaces[o.GetNamespace()] {
+		return false
+	}
+	return s.labels == nil || s.labels.Matches(labels.Set(o.GetLabels()))
+}
+
+// ObserveSelected returns a SyncObserver that tells the supplied SyncObserver
+// only about the claims the ClaimSelector selects, leaving out the ones that
+// are still reconciled only to let go of them.
+func (s *ClaimSelector) ObserveSelected(o SyncObserver) SyncObserver {
+	return SyncObserverFn(func(ctx context.Context, c *claim.Unstructured) {
+		if s.Selects(c) {
+			o.ObserveSync(ctx, c)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestClaimSelectorSelects(t *testing.T) {
+	team := labels.SelectorFromSet(labels.Set{"team": "a"})
+
+	cases := map[string]struct {
+		reason string
+		s      *ClaimSelector
+		o      metav1.Object
+		want   bool
+	}{
+		"NilSelector": {
+			reason: "A nil selector should select every claim",
+			o:      &metav1.ObjectMeta{Namespace: "ns"},
+			want:   true,
+		},
+		"EmptySelector": {
+			reason: "A selector without labels or namespaces should select every claim",
+			s:      NewClaimSelector(nil),
+			o:      &metav1.ObjectMeta{Namespace: "ns"},
+			want:   true,
+		},
+		"LabelsMatch": {
+			reason: "A claim whose labels match should be selected",
+			s:      NewClaimSelector(team),
+			o:      &metav1.ObjectMeta{Namespace: "ns", Labels: map[string]string{"team": "a"}},
+			want:   true,
+		},
+		"LabelsDoNotMatch": {
+			reason: "A claim whose labels don't match should not be selected",
+			s:      NewClaimSelector(team),
+			o:      &metav1.ObjectMeta{Namespace: "ns", Labels: map[string]string{"team": "b"}},
+			want:   false,
+		},
+		"NamespaceAllowed": {
+			reason: "A claim in an allowed namespace should be selected",
+			s:      NewClaimSelector(nil, "ns", "other"),
+			o:      &metav1.ObjectMeta{Namespace: "ns"},
+			want:   true,
+		},
+		"NamespaceNotAllowed": {
+			reason: "A claim outside the allowed namespaces should not be selected",
+			s:      NewClaimSelector(team, "other"),
+			o:      &metav1.ObjectMeta{Namespace: "ns", Labels: map[string]string{"team": "a"}},
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.s.Selects(tc.o)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nSelects(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
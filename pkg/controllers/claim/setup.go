@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+// PassthroughControllerName returns the name of the controller that syncs the
+// passthrough resources of the given kind.
+func PassthroughControllerName(gvk schema.GroupVersionKind) string {
+	return "passthrough/" + strings.ToLower(gvk.GroupKind().String())
+}
+
+// SetupPassthrough adds a controller that syncs the namespaced custom
+// resources of the given kind, which are not claims of any
+// CompositeResourceDefinition, to the remote cluster the same way claims are
+// synced. Their CRD has to exist in both clusters.
+func SetupPassthrough(mgr ctrl.Manager, remoteClient client.Client, gvk schema.GroupVersionKind, log logging.Logger, opts ...ReconcilerOption) error {
+	return setupPassthrough(mgr, PassthroughControllerName(gvk), remoteClient, gvk, log, opts...)
+}
+
+// SetupRemotePassthrough adds a controller that syncs the passthrough
+// resources of the given kind that are routed to the supplied additional
+// remote cluster, like SetupPassthrough does for the default one. The supplied
+// names are of all additional remote clusters.
+func SetupRemotePassthrough(mgr ctrl.Manager, rm Remote, remotes []string, gvk schema.GroupVersionKind, log logging.Logger, opts ...ReconcilerOption) error {
+	o := append(append(append([]ReconcilerOption{}, opts...), rm.Options...), WithRemoteRouting(rm.Name, remotes))
+	return setupPassthrough(mgr, RemoteControllerName(PassthroughControllerName(gvk), rm.Name), rm.Client, gvk, log.WithValues("remote", rm.Name), o...)
+}
+
+func setupPassthrough(mgr ctrl.Manager, name string, remoteClient client.Client, gvk schema.GroupVersionKind, log logging.Logger, opts ...ReconcilerOption) error {
+	o := append([]ReconcilerOption{
+		WithLogger(log.WithValues("controller", name)),
+		WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+	}, opts...)
+	r := NewReconciler(mgr, remoteClient, gvk, o...)
+
+	u := &kunstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		For(u).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, EnqueueRequestsForResyncedNamespace(mgr.GetClient(), gvk)).
+		Complete(r)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const errWaitStatusUpdate = "cannot wait for a status update slot"
+
+// NewStatusScheduler returns a new *StatusScheduler that lets the given number
+// of status writes through per second on average, and up to burst of them at
+// once.
+func NewStatusScheduler(qps float64, burst int) *StatusScheduler {
+	if burst < 1 {
+		burst = 1
+	}
+	return &StatusScheduler{interval: time.Duration(float64(time.Second) / qps), burst: burst, now: time.Now}
+}
+
+// A StatusScheduler staggers writes to the status of local claims. When many
+// claims change their condition at once, e.g. when the remote cluster becomes
+// reachable again, their writes are spread out over time instead of hitting
+// the local api-server all at once. It should be shared by all claim
+// Reconcilers.
+type StatusScheduler struct {
+	interval time.Duration
+	burst    int
+	now      func() time.Time
+
+	mu sync.Mutex
+	// next is when the next write would be let through if there was no
+	// burst allowance.
+	next time.Time
+}
+
+// reserve a slot and return how long to wait for it.
+func (s *StatusScheduler) reserve() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.next.Before(now) {
+		s.next = now
+	}
+	wait := s.next.Sub(now) - time.Duration(s.burst-1)*s.interval
+	s.next = s.next.Add(s.interval)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Wait blocks until a write is allowed or the supplied context is done.
+func (s *StatusScheduler) Wait(ctx context.Context) error {
+	wait := s.reserve()
+	if wait == 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// NewStaggeredClient returns a client.Client whose status writes are
+// staggered by the supplied StatusScheduler.
+func NewStaggeredClient(c client.Client, s *StatusScheduler) client.Client {
+	return &staggeredClient{Client: c, scheduler: s}
+}
+
+type staggeredClient struct {
+	client.Client
+	scheduler *StatusScheduler
+}
+
+func (c *staggeredClient) Status() client.StatusWriter {
+	return &staggeredStatusWriter{StatusWriter: c.Client.Status(), scheduler: c.scheduler}
+}
+
+type staggeredStatusWriter struct {
+	client.StatusWriter
+	scheduler *StatusScheduler
+}
+
+func (w *staggeredStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if err := w.scheduler.Wait(ctx); err != nil {
+		return errors.Wrap(err, errWaitStatusUpdate)
+	}
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func (w *staggeredStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := w.scheduler.Wait(ctx); err != nil {
+		return errors.Wrap(err, errWaitStatusUpdate)
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
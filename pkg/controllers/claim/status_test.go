@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatusScheduler(t *testing.T) {
+	start := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		reason string
+		qps    float64
+		burst  int
+		at     []time.Duration
+		want   []time.Duration
+	}{
+		"Burst": {
+			reason: "Writes up to the burst should not wait, later ones should be spread out",
+			qps:    1,
+			burst:  3,
+			at:     []time.Duration{0, 0, 0, 0, 0},
+			want:   []time.Duration{0, 0, 0, time.Second, 2 * time.Second},
+		},
+		"Recovered": {
+			reason: "The burst allowance should recover while there are no writes",
+			qps:    2,
+			burst:  2,
+			at:     []time.Duration{0, 0, 0, 2 * time.Second, 2 * time.Second},
+			want:   []time.Duration{0, 0, 500 * time.Millisecond, 0, 0},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := NewStatusScheduler(tc.qps, tc.burst)
+			got := make([]time.Duration, len(tc.at))
+			for i, at := range tc.at {
+				s.now = func() time.Time { return start.Add(at) }
+				got[i] = s.reserve()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nreserve(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
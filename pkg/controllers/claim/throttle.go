@@ -0,0 +1,43 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"context"
+)
+
+// A SyncThrottle limits how many claims start syncing against the remote
+// cluster per second, so that the remote api-server isn't flooded when many
+// claims are synced at once, e.g. after the agent restarts.
+type SyncThrottle interface {
+	// Wait blocks until a claim may start syncing, or returns an error if
+	// the supplied context is done before that.
+	Wait(ctx context.Context) error
+}
+
+// SyncThrottleFn is used to construct a SyncThrottle with a bare function.
+type SyncThrottleFn func(ctx context.Context) error
+
+// Wait calls the supplied function.
+func (fn SyncThrottleFn) Wait(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// NewNopSyncThrottle returns a SyncThrottle that never waits.
+func NewNopSyncThrottle() SyncThrottleFn {
+	return func(_ context.Context) error { return nil }
+}
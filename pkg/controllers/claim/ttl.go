@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errFmtParseTTL = "cannot parse the %s annotation"
+
+	// maxExpiryWarning is how long before its expiry a claim starts getting
+	// warning events at most. Claims with a shorter TTL are warned about in the
+	// second half of it.
+	maxExpiryWarning = 24 * time.Hour
+)
+
+// ExpiryOf returns the time the supplied object expires at according to its
+// TTL annotation, and false if it doesn't have one.
+func ExpiryOf(o metav1.Object) (time.Time, bool, error) {
+	v, ok := o.GetAnnotations()[resource.AnnotationKeyTTL]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		return time.Time{}, false, errors.Wrapf(err, errFmtParseTTL, resource.AnnotationKeyTTL)
+	}
+	return o.GetCreationTimestamp().Add(ttl), true, nil
+}
+
+// ExpiryWarning returns how long before its expiry an object with the supplied
+// TTL should be warned about.
+func ExpiryWarning(o metav1.Object, expiry time.Time) time.Duration {
+	if half := expiry.Sub(o.GetCreationTimestamp().Time) / 2; half < maxExpiryWarning {
+		return half
+	}
+	return maxExpiryWarning
+}
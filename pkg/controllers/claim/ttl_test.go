@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestExpiryOf(t *testing.T) {
+	created := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	type want struct {
+		expiry time.Time
+		ok     bool
+		err    bool
+	}
+	cases := map[string]struct {
+		reason      string
+		annotations map[string]string
+		want        want
+	}{
+		"NoTTL": {
+			reason: "Objects without a TTL never expire",
+			want:   want{},
+		},
+		"TTL": {
+			reason: "Objects expire once they're older than their TTL",
+			annotations: map[string]string{
+				resource.AnnotationKeyTTL: "72h",
+			},
+			want: want{expiry: created.Add(72 * time.Hour), ok: true},
+		},
+		"InvalidTTL": {
+			reason: "An error should be returned if the TTL cannot be parsed",
+			annotations: map[string]string{
+				resource.AnnotationKeyTTL: "three days",
+			},
+			want: want{err: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created), Annotations: tc.annotations}
+			expiry, ok, err := ExpiryOf(o)
+			got := want{expiry: expiry, ok: ok, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nExpiryOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+const (
+	errFmtReadField  = "cannot read field %s"
+	errFmtWriteField = "cannot write field %s"
+)
+
+// A VersionConversion converts claims of a kind between the version that is
+// served in the local cluster and the one that is served in the remote
+// cluster, e.g. while they differ during a rolling upgrade of the platform.
+// Claims may be relocated to another group and kind in the remote cluster as
+// well, e.g. while the old group is deprecated during a platform refactor.
+type VersionConversion struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+
+	// Local is the version claims are read and written at in the local
+	// cluster.
+	Local string `json:"localVersion"`
+
+	// Remote is the version claims are read and written at in the remote
+	// cluster.
+	Remote string `json:"remoteVersion"`
+
+	// RemoteGroup and RemoteKind are the group and the kind claims are read
+	// and written as in the remote cluster, if they're different than the
+	// local ones.
+	RemoteGroup string `json:"remoteGroup,omitempty"`
+	RemoteKind  string `json:"remoteKind,omitempty"`
+
+	// Fields maps the paths of fields that were moved between the versions,
+	// from their path in the local version to their path in the remote
+	// version. Paths are dot-separated names of object fields, like
+	// spec.parameters.size.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// A VersionTable is a set of VersionConversions.
+type VersionTable []VersionConversion
+
+// Lookup returns the VersionConversion of claims whose local version is
+// the supplied one, and false if there is none.
+func (t VersionTable) Lookup(local schema.GroupVersionKind) (VersionConversion, bool) {
+	for _, c := range t {
+		if c.Group == local.Group && c.Kind == local.Kind && c.Local == local.Version {
+			return c, true
+		}
+	}
+	return VersionConversion{}, false
+}
+
+// RemoteGroupVersionKind returns the kind that claims of the supplied local
+// kind are converted to.
+func (c VersionConversion) RemoteGroupVersionKind(local schema.GroupVersionKind) schema.GroupVersionKind {
+	gvk := local
+	gvk.Version = c.Remote
+	if c.RemoteGroup != "" {
+		gvk.Group = c.RemoteGroup
+	}
+	if c.RemoteKind != "" {
+		gvk.Kind = c.RemoteKind
+	}
+	return gvk
+}
+
+// ToRemote converts the supplied claim, which was configured from a local
+// claim, to the remote version in place.
+func (c VersionConversion) ToRemote(o *claim.Unstructured) error {
+	o.SetGroupVersionKind(c.RemoteGroupVersionKind(o.GetObjectKind().GroupVersionKind()))
+	for from, to := range c.Fields {
+		if err := move(o.GetUnstructured(), from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToLocal returns a copy of the supplied remote claim that is converted to the
+// local version.
+func (c VersionConversion) ToLocal(o *claim.Unstructured) (*claim.Unstructured, error) {
+	out := &claim.Unstructured{Unstructured: *o.GetUnstructured().DeepCopy()}
+	out.SetGroupVersionKind(schema.GroupVersionKind{Group: c.Group, Version: c.Local, Kind: c.Kind})
+	for to, from := range c.Fields {
+		if err := move(out.GetUnstructured(), from, to); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func move(u *kunstructured.Unstructured, from, to string) error {
+	v, found, err := kunstructured.NestedFieldCopy(u.Object, strings.Split(from, ".")...)
+	if err != nil {
+		return errors.Wrapf(err, errFmtReadField, from)
+	}
+	if !found {
+		return nil
+	}
+	kunstructured.RemoveNestedField(u.Object, strings.Split(from, ".")...)
+	return errors.Wrapf(kunstructured.SetNestedField(u.Object, v, strings.Split(to, ".")...), errFmtWriteField, to)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+)
+
+func TestVersionConversion(t *testing.T) {
+	c := VersionConversion{
+		Group:  "example.org",
+		Kind:   "MySQLInstance",
+		Local:  "v1alpha1",
+		Remote: "v1beta1",
+		Fields: map[string]string{"spec.storageGB": "spec.parameters.storage"},
+	}
+	newClaim := func(version string, spec map[string]interface{}) *claim.Unstructured {
+		cl := claim.New(claim.WithGroupVersionKind(schema.GroupVersionKind{Group: "example.org", Version: version, Kind: "MySQLInstance"}))
+		cl.SetName("cool-claim")
+		cl.Object["spec"] = spec
+		return cl
+	}
+	local := func() *claim.Unstructured {
+		return newClaim("v1alpha1", map[string]interface{}{"storageGB": int64(20), "engine": "mysql"})
+	}
+	remote := func() *claim.Unstructured {
+		return newClaim("v1beta1", map[string]interface{}{"parameters": map[string]interface{}{"storage": int64(20)}, "engine": "mysql"})
+	}
+
+	t.Run("ToRemote", func(t *testing.T) {
+		got := local()
+		if err := c.ToRemote(got); err != nil {
+			t.Fatalf("ToRemote(...): %s", err)
+		}
+		if diff := cmp.Diff(remote(), got); diff != "" {
+			t.Errorf("ToRemote(...): -want, +got:\n%s", diff)
+		}
+	})
+	t.Run("ToLocal", func(t *testing.T) {
+		in := remote()
+		got, err := c.ToLocal(in)
+		if err != nil {
+			t.Fatalf("ToLocal(...): %s", err)
+		}
+		if diff := cmp.Diff(local(), got); diff != "" {
+			t.Errorf("ToLocal(...): -want, +got:\n%s", diff)
+		}
+		if diff := cmp.Diff(remote(), in); diff != "" {
+			t.Errorf("ToLocal(...): the remote claim should not be changed: -want, +got:\n%s", diff)
+		}
+	})
+	t.Run("Lookup", func(t *testing.T) {
+		tbl := VersionTable{c}
+		if _, ok := tbl.Lookup(schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}); !ok {
+			t.Errorf("Lookup(...): the conversion of the local version should be found")
+		}
+		if _, ok := tbl.Lookup(schema.GroupVersionKind{Group: "example.org", Version: "v1beta1", Kind: "MySQLInstance"}); ok {
+			t.Errorf("Lookup(...): no conversion should be found for another version")
+		}
+	})
+	t.Run("Relocated", func(t *testing.T) {
+		c := VersionConversion{Group: "example.org", Kind: "MySQLInstance", Local: "v1alpha1", Remote: "v1", RemoteGroup: "database.example.org", RemoteKind: "MySQL"}
+		got := local()
+		if err := c.ToRemote(got); err != nil {
+			t.Fatalf("ToRemote(...): %s", err)
+		}
+		want := schema.GroupVersionKind{Group: "database.example.org", Version: "v1", Kind: "MySQL"}
+		if diff := cmp.Diff(want, got.GetObjectKind().GroupVersionKind()); diff != "" {
+			t.Errorf("ToRemote(...): -want, +got:\n%s", diff)
+		}
+		back, err := c.ToLocal(got)
+		if err != nil {
+			t.Fatalf("ToLocal(...): %s", err)
+		}
+		if diff := cmp.Diff(local(), back); diff != "" {
+			t.Errorf("ToLocal(...): -want, +got:\n%s", diff)
+		}
+	})
+}
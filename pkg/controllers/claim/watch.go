@@ -0,0 +1,151 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"strings"
+	"sync"
+
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// NewRemoteWatch returns a new *RemoteWatch of the remote claims of the
+// supplied kind.
+func NewRemoteWatch(i cache.Informers, gvk schema.GroupVersionKind, log logging.Logger) *RemoteWatch {
+	return &RemoteWatch{informers: i, gvk: gvk, log: log}
+}
+
+// A RemoteWatch enqueues the local claims of a kind whenever their remote
+// claims change, so that changes made in the remote cluster, like their
+// status, are pulled within seconds rather than on the next poll.
+//
+// The controller of the kind is started with watches of the local cluster
+// only, so the RemoteWatch learns the queue of the controller from the events
+// of those watches through the handlers that Handler returns. A controller
+// that's restarted is picked up the same way.
+type RemoteWatch struct {
+	informers cache.Informers
+	gvk       schema.GroupVersionKind
+	log       logging.Logger
+
+	once  sync.Once
+	mu    sync.RWMutex
+	queue workqueue.RateLimitingInterface
+}
+
+// Handler returns a handler.EventHandler that handles events like the
+// supplied one, and binds the RemoteWatch to the queue they're handled with.
+func (w *RemoteWatch) Handler(h handler.EventHandler) handler.EventHandler {
+	return &bindingHandler{EventHandler: h, watch: w}
+}
+
+// Start watching the remote claims. It returns right away, and is a no-op if
+// the watch was already started.
+func (w *RemoteWatch) Start() {
+	w.once.Do(func() {
+		// Getting the informer blocks until it's synced, which may take a
+		// while if the remote cluster is unreachable.
+		go func() {
+			u := &kunstructured.Unstructured{}
+			u.SetGroupVersionKind(w.gvk)
+			i, err := w.informers.GetInformer(u)
+			if err != nil {
+				w.log.Info("Cannot watch remote claims", "kind", w.gvk.String(), "error", err)
+				return
+			}
+			i.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+				AddFunc:    w.enqueue,
+				UpdateFunc: func(_, o interface{}) { w.enqueue(o) },
+				DeleteFunc: w.enqueue,
+			})
+		}()
+	})
+}
+
+func (w *RemoteWatch) bind(q workqueue.RateLimitingInterface) {
+	w.mu.Lock()
+	w.queue = q
+	w.mu.Unlock()
+}
+
+func (w *RemoteWatch) enqueue(o interface{}) {
+	if d, ok := o.(toolscache.DeletedFinalStateUnknown); ok {
+		o = d.Obj
+	}
+	u, ok := o.(*kunstructured.Unstructured)
+	if !ok {
+		return
+	}
+	nn, ok := SourceObject(u)
+	if !ok {
+		return
+	}
+	w.mu.RLock()
+	q := w.queue
+	w.mu.RUnlock()
+	if q == nil {
+		return
+	}
+	q.Add(reconcile.Request{NamespacedName: nn})
+}
+
+// SourceObject returns the local claim the supplied remote claim was synced
+// from, and false if it wasn't synced by an agent.
+func SourceObject(remote *kunstructured.Unstructured) (types.NamespacedName, bool) {
+	parts := strings.SplitN(remote.GetAnnotations()[resource.AnnotationKeySourceObject], "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
+
+type bindingHandler struct {
+	handler.EventHandler
+	watch *RemoteWatch
+}
+
+func (h *bindingHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.watch.bind(q)
+	h.EventHandler.Create(e, q)
+}
+
+func (h *bindingHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.watch.bind(q)
+	h.EventHandler.Update(e, q)
+}
+
+func (h *bindingHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.watch.bind(q)
+	h.EventHandler.Delete(e, q)
+}
+
+func (h *bindingHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.watch.bind(q)
+	h.EventHandler.Generic(e, q)
+}
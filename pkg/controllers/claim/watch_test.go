@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claim
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestRemoteWatch(t *testing.T) {
+	remote := func(source string) *kunstructured.Unstructured {
+		u := &kunstructured.Unstructured{}
+		u.SetNamespace("remote-ns")
+		u.SetName("cool-claim")
+		if source != "" {
+			u.SetAnnotations(map[string]string{resource.AnnotationKeySourceObject: source})
+		}
+		return u
+	}
+
+	cases := map[string]struct {
+		reason string
+		bound  bool
+		remote *kunstructured.Unstructured
+		want   []reconcile.Request
+	}{
+		"Synced": {
+			reason: "The local claim a remote claim was synced from should be enqueued",
+			bound:  true,
+			remote: remote("local-ns/cool-claim"),
+			want:   []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "local-ns", Name: "cool-claim"}}},
+		},
+		"NotSynced": {
+			reason: "Remote claims that were not synced by an agent should be ignored",
+			bound:  true,
+			remote: remote(""),
+		},
+		"NotBound": {
+			reason: "Nothing should be enqueued before the queue of the controller is known",
+			remote: remote("local-ns/cool-claim"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := NewRemoteWatch(nil, schema.GroupVersionKind{}, logging.NewNopLogger())
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			if tc.bound {
+				w.Handler(&handler.Funcs{}).Generic(event.GenericEvent{}, q)
+			}
+			w.enqueue(tc.remote)
+
+			var got []reconcile.Request
+			for q.Len() > 0 {
+				i, _ := q.Get()
+				got = append(got, i.(reconcile.Request))
+				q.Done(i)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nenqueue(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controllers wires all controllers of the agent so that it can be
+// embedded in other operators.
+package controllers
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/controllers/apiextensions"
+	"github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/controllers/crd"
+	"github.com/crossplane/agent/pkg/controllers/xrd"
+)
+
+const (
+	errSetupClaims      = "cannot setup claim controllers"
+	errSetupDefinitions = "cannot setup definition controllers"
+	errFmtPassthrough   = "cannot setup passthrough controller of %s"
+)
+
+// Config declares which controllers are set up and how they're configured.
+type Config struct {
+	// Log is used by all controllers. Nothing is logged if it's nil.
+	Log logging.Logger
+
+	// Claims configures the controllers that sync claims from the local
+	// cluster to the remote cluster. They're not set up if it's nil.
+	Claims *ClaimsConfig
+
+	// Definitions configures the controllers that sync definitions from the
+	// remote cluster to the local cluster. They're not set up if it's nil.
+	Definitions *DefinitionsConfig
+}
+
+// ClaimsConfig configures the controllers that sync claims. A claim
+// controller is started for every CompositeResourceDefinition that offers a
+// claim. It pushes the claims to the remote cluster and pulls their status and
+// connection secrets back.
+type ClaimsConfig struct {
+	// Options configure the Reconciler of every claim controller.
+	Options []claim.ReconcilerOption
+
+	// XRDOptions configure the Reconciler that starts the claim controllers.
+	XRDOptions []xrd.ReconcilerOption
+
+	// Passthrough are the kinds of namespaced custom resources that are not
+	// claims but are synced the same way, using Options.
+	Passthrough []schema.GroupVersionKind
+
+	// Remotes are the remote clusters claims are synced to in addition to
+	// the default one, sharing the cache of the local cluster with it. Claims
+	// and passthrough resources are routed to them by their
+	// resource.LabelKeyRemote label.
+	Remotes []claim.Remote
+}
+
+// DefinitionsConfig configures the controllers that sync the CRDs,
+// CompositeResourceDefinitions and Compositions of the remote cluster into the
+// local cluster.
+type DefinitionsConfig struct {
+	// RemoteManager is the manager that watches the remote cluster, which the
+	// controllers are added to.
+	RemoteManager manager.Manager
+
+	// CRDOptions configure the Reconciler of CRDs.
+	CRDOptions []crd.ReconcilerOption
+
+	// Options configure the Reconcilers of CompositeResourceDefinitions and
+	// Compositions.
+	Options []apiextensions.ReconcilerOption
+
+	// CompositionOptions configure the Reconciler of Compositions in addition
+	// to Options.
+	CompositionOptions []apiextensions.ReconcilerOption
+}
+
+// SetupWithManager adds the controllers declared in the supplied Config to
+// the supplied manager, which watches the local cluster. The supplied client
+// is used to read from and write to the remote cluster.
+func SetupWithManager(mgr manager.Manager, remote client.Client, c Config) error {
+	log := c.Log
+	if log == nil {
+		log = logging.NewNopLogger()
+	}
+	if c.Claims != nil {
+		xo := append(append([]xrd.ReconcilerOption{}, c.Claims.XRDOptions...), xrd.WithClaimReconcilerOptions(c.Claims.Options...), xrd.WithRemotes(c.Claims.Remotes...))
+		if err := xrd.Setup(mgr, remote, log, xo...); err != nil {
+			return errors.Wrap(err, errSetupClaims)
+		}
+		names := claim.RemoteNames(c.Claims.Remotes)
+		for _, gvk := range c.Claims.Passthrough {
+			o := c.Claims.Options
+			if len(names) > 0 {
+				o = append(append([]claim.ReconcilerOption{}, o...), claim.WithRemoteRouting("", names))
+			}
+			if err := claim.SetupPassthrough(mgr, remote, gvk, log, o...); err != nil {
+				return errors.Wrapf(err, errFmtPassthrough, gvk)
+			}
+			for _, rm := range c.Claims.Remotes {
+				if err := claim.SetupRemotePassthrough(mgr, rm, names, gvk, log, c.Claims.Options...); err != nil {
+					return errors.Wrapf(err, errFmtPassthrough, gvk)
+				}
+			}
+		}
+	}
+	if c.Definitions != nil {
+		if err := SetupDefinitions(c.Definitions.RemoteManager, mgr.GetClient(), log, *c.Definitions); err != nil {
+			return errors.Wrap(err, errSetupDefinitions)
+		}
+	}
+	return nil
+}
+
+// SetupDefinitions adds the controllers that sync definitions to the supplied
+// manager, which watches the remote cluster. The supplied client is used to
+// write to the local cluster. The manager in the supplied config is ignored.
+func SetupDefinitions(mgr manager.Manager, local client.Client, log logging.Logger, c DefinitionsConfig) error {
+	if err := crd.Setup(mgr, local, log, c.CRDOptions...); err != nil {
+		return err
+	}
+	if err := apiextensions.SetupXRDSync(mgr, local, log, c.Options...); err != nil {
+		return err
+	}
+	return apiextensions.SetupCompositionSync(mgr, local, log, append(append([]apiextensions.ReconcilerOption{}, c.Options...), c.CompositionOptions...)...)
+}
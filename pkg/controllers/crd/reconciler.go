@@ -22,10 +22,12 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	kcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -33,7 +35,9 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 
+	agentremote "github.com/crossplane/agent/pkg/remote"
 	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/rollout"
 )
 
 const (
@@ -47,6 +51,7 @@ const (
 	remote      = "remote cluster: "
 	errGetCRD   = "cannot get custom resource definition"
 	errApplyCRD = "cannot apply custom resource definition"
+	errRollout  = "cannot check whether rollout of updates is paused"
 )
 
 // NOTE(muvaf): CRDs could also be synced with apiextensions.Reconciler which syncs
@@ -61,27 +66,50 @@ const (
 
 // Setup adds a controller that watches CustomResourceDefinitions in the remote
 // cluster and replicates them in the local cluster.
-func Setup(mgr manager.Manager, localClient client.Client, logger logging.Logger) error {
+func Setup(mgr manager.Manager, localClient client.Client, logger logging.Logger, opts ...ReconcilerOption) error {
 	name := "CustomResourceDefinitions"
 	ca := runtimeresource.ClientApplicator{
 		Client:     localClient,
 		Applicator: runtimeresource.NewAPIUpdatingApplicator(localClient),
 	}
-	r := NewReconciler(mgr, ca, logger)
-	return ctrl.NewControllerManagedBy(mgr).
+	r := NewReconciler(mgr, ca, logger, opts...)
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		For(&v1beta1.CustomResourceDefinition{}).
 		WithOptions(kcontroller.Options{MaxConcurrentReconciles: maxConcurrency}).
 		WithEventFilter(resource.NewNameFilter([]types.NamespacedName{
 			{Name: "compositeresourcedefinitions.apiextensions.crossplane.io"},
 			{Name: "compositions.apiextensions.crossplane.io"},
-		})).
-		Complete(r)
+		}))
+	if r.monitor != nil {
+		nl := func() runtime.Object { return &v1beta1.CustomResourceDefinitionList{} }
+		b = b.Watches(agentremote.NewResyncSource(r.monitor, mgr.GetClient(), nl, r.log), &handler.EnqueueRequestForObject{})
+	}
+	return b.Complete(r)
+}
+
+// ReconcilerOption is used to configure the Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithRolloutGate specifies the Gate that decides whether updates to existing
+// CRDs may be applied in the local cluster.
+func WithRolloutGate(g rollout.Gate) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.gate = g
+	}
+}
+
+// WithReconnectMonitor specifies the Monitor that tells when the remote cluster
+// is reachable again after an outage so that all CRDs are reconciled.
+func WithReconnectMonitor(m *agentremote.Monitor) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.monitor = m
+	}
 }
 
 // NewReconciler returns a new *Reconciler.
-func NewReconciler(mgr manager.Manager, localClientApplicator runtimeresource.ClientApplicator, logger logging.Logger) *Reconciler {
-	return &Reconciler{
+func NewReconciler(mgr manager.Manager, localClientApplicator runtimeresource.ClientApplicator, logger logging.Logger, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
 		mgr:    mgr,
 		local:  localClientApplicator,
 		remote: mgr.GetClient(),
@@ -91,16 +119,23 @@ func NewReconciler(mgr manager.Manager, localClientApplicator runtimeresource.Cl
 		// for now until we figure out how we can construct an event recorder with
 		// just kubeconfig.
 		record: event.NewNopRecorder(),
+		gate:   rollout.NewOpenGate(),
+	}
+	for _, f := range opts {
+		f(r)
 	}
+	return r
 }
 
 // Reconciler syncs CRDs in the remote cluster to the local cluster, overrides
 // the existing ones in the local cluster. It's advised to use this together with
 // an EventFilter to filter only the CRDs you'd like to be synced.
 type Reconciler struct {
-	mgr    ctrl.Manager
-	local  runtimeresource.ClientApplicator
-	remote client.Client
+	mgr     ctrl.Manager
+	local   runtimeresource.ClientApplicator
+	remote  client.Client
+	gate    rollout.Gate
+	monitor *agentremote.Monitor
 
 	log    logging.Logger
 	record event.Recorder
@@ -118,7 +153,27 @@ func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error)
 	if err := r.remote.Get(ctx, req.NamespacedName, remoteCRD); err != nil {
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, remote+errGetCRD)
 	}
+	localCRD := resource.SanitizedDeepCopyObject(remoteCRD)
+
+	// Updates to CRDs that already exist are held while their rollout is
+	// paused so that a bad schema doesn't break more claims.
+	paused, msg, err := r.gate.Paused(ctx)
+	if err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, local+errRollout)
+	}
+	if paused {
+		current := &v1beta1.CustomResourceDefinition{}
+		err := r.local.Get(ctx, req.NamespacedName, current)
+		if runtimeresource.IgnoreNotFound(err) != nil {
+			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, local+errGetCRD)
+		}
+		if err == nil && rollout.Changed(current, localCRD) {
+			log.Info("Holding update", "reason", msg)
+			return reconcile.Result{RequeueAfter: longWait}, nil
+		}
+	}
+
 	// TODO(muvaf): Set condition on local CRD to tell when is the last time
 	// it's been synced.
-	return reconcile.Result{RequeueAfter: longWait}, errors.Wrap(r.local.Apply(ctx, resource.SanitizedDeepCopyObject(remoteCRD)), local+errApplyCRD)
+	return reconcile.Result{RequeueAfter: longWait}, errors.Wrap(r.local.Apply(ctx, localCRD), local+errApplyCRD)
 }
@@ -0,0 +1,514 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package decommission tears down the claims of a local namespace that is
+// being deleted in a defined order, rather than leaving it to whichever claim
+// happens to be reconciled while the namespace terminates, and reports how it
+// went.
+package decommission
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/suspend"
+)
+
+const (
+	timeout   = 2 * time.Minute
+	longWait  = 1 * time.Minute
+	shortWait = 30 * time.Second
+	tinyWait  = 3 * time.Second
+
+	// Finalizer holds a namespace that contains synced claims until the
+	// report of its decommissioning is written.
+	Finalizer = "agent.crossplane.io/decommission"
+
+	// ConfigMapPrefix is the prefix of the name of the ConfigMap the report
+	// of a namespace is published to.
+	ConfigMapPrefix = "crossplane-agent-decommission-"
+
+	keyNamespace = "namespace"
+	keyPhase     = "phase"
+	keyClaims    = "claims"
+	keyStarted   = "started"
+	keyFinished  = "finished"
+
+	localPrefix         = "local cluster: "
+	remotePrefix        = "remote cluster: "
+	errGetNamespace     = "cannot get namespace"
+	errListKinds        = "cannot list claim kinds"
+	errFmtListClaims    = "cannot list claims of %s"
+	errAddFinalizer     = "cannot add finalizer to namespace"
+	errRemoveFinalizer  = "cannot remove finalizer from namespace"
+	errFmtDeleteClaim   = "cannot delete claim %s"
+	errFmtGetRemote     = "cannot get remote claim of %s"
+	errFmtDeleteRemote  = "cannot delete remote claim of %s"
+	errFmtReleaseRemote = "cannot release remote claim of %s"
+	errFmtReleaseClaim  = "cannot release claim %s"
+	errPublish          = "cannot publish decommissioning report"
+	errCheckSuspended   = "cannot check whether the agent is suspended"
+	errFmtLock          = "cannot lock remote namespace of %s"
+
+	reasonDecommissioning event.Reason = "Decommissioning"
+	reasonDecommissioned  event.Reason = "Decommissioned"
+)
+
+// Phases of the decommissioning of a namespace.
+const (
+	PhaseWaitingForRemote = "WaitingForRemoteCleanup"
+	PhaseCompleted        = "Completed"
+)
+
+// States of a claim in the report.
+const (
+	stateRemoteDeleting = "remote claim deleting"
+	stateLeftToClaim    = "left to claim controller"
+	stateReleased       = "released"
+)
+
+// A KindsFn returns the kinds of the claims in the local cluster.
+type KindsFn func(ctx context.Context) ([]schema.GroupVersionKind, error)
+
+// Setup adds a controller that decommissions local namespaces that are being
+// deleted.
+func Setup(mgr manager.Manager, remoteClient client.Client, log logging.Logger, kinds KindsFn, opts ...ReconcilerOption) error {
+	name := "NamespaceDecommissioning"
+	o := append([]ReconcilerOption{
+		WithLogger(log.WithValues("controller", name)),
+		WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+	}, opts...)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		For(&corev1.Namespace{}).
+		Complete(NewReconciler(mgr, remoteClient, kinds, o...))
+}
+
+// ReconcilerOption is used to configure the Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithLogger specifies how the Reconciler should log messages.
+func WithLogger(log logging.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.log = log
+	}
+}
+
+// WithRecorder specifies how the Reconciler should record Kubernetes events.
+func WithRecorder(er event.Recorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.record = er
+	}
+}
+
+// WithNamespaceMapper specifies the remote namespaces the claims of local
+// namespaces are synced to, for claims that didn't record theirs.
+func WithNamespaceMapper(m agentclaim.NamespaceMapper) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.mapper = m
+	}
+}
+
+// WithVersionTable specifies the kinds whose claims are synced to another
+// version in the remote cluster.
+func WithVersionTable(t agentclaim.VersionTable) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.versions = t
+	}
+}
+
+// WithReportNamespace specifies the namespace the reports are published to.
+// They're only recorded as events if it's empty.
+func WithReportNamespace(ns string) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.reportNamespace = ns
+	}
+}
+
+// WithSuspendSwitch specifies the Switch that decides whether the agent is
+// suspended, in which case namespaces that are being deleted are held rather
+// than decommissioned.
+func WithSuspendSwitch(s suspend.Switch) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.suspension = s
+	}
+}
+
+// WithClusterName specifies the name of the cluster the agent runs in. Remote
+// claims that were written from another cluster are left alone.
+func WithClusterName(name string) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.clusterName = name
+	}
+}
+
+// WithNamespaceLocker specifies the NamespaceLocker that makes sure remote
+// claims are only deleted by the agent that syncs their namespace.
+func WithNamespaceLocker(l agentclaim.NamespaceLocker) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.locker = l
+	}
+}
+
+// WithFencingToken specifies the source of the fencing token of the agent.
+// Remote claims that were written by an agent with a greater token, or any
+// until the agent has a token, are left alone.
+func WithFencingToken(s agentclaim.FencingTokenSource) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.fencing = s
+	}
+}
+
+// NewReconciler returns a new *Reconciler.
+func NewReconciler(mgr manager.Manager, remoteClient client.Client, kinds KindsFn, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		local:     mgr.GetClient(),
+		remote:    remoteClient,
+		kinds:     kinds,
+		finalizer: runtimeresource.NewAPIFinalizer(mgr.GetClient(), Finalizer),
+		mapper:    agentclaim.NamespaceMap(nil),
+		locker:    agentclaim.NewNopNamespaceLocker(),
+		reports:   map[types.UID]*Report{},
+		log:       logging.NewNopLogger(),
+		record:    event.NewNopRecorder(),
+
+		suspension: suspend.NewNopSwitch(),
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+// A Reconciler decommissions a local namespace that is being deleted in four
+// steps for each of the claims in it that were synced: it deletes the claim
+// and propagates the deletion to its remote claim, waits for the remote claim
+// to be cleaned up, and releases the local claim. Once all of them are
+// released it reports what it did. Namespaces that contain synced claims are
+// held until then.
+type Reconciler struct {
+	local     client.Client
+	remote    client.Client
+	kinds     KindsFn
+	finalizer runtimeresource.Finalizer
+	mapper    agentclaim.NamespaceMapper
+	versions  agentclaim.VersionTable
+
+	clusterName string
+	locker      agentclaim.NamespaceLocker
+	fencing     agentclaim.FencingTokenSource
+
+	reportNamespace string
+	suspension      suspend.Switch
+
+	mu      sync.Mutex
+	reports map[types.UID]*Report
+
+	log    logging.Logger
+	record event.Recorder
+}
+
+// A Report of the decommissioning of a namespace.
+type Report struct {
+	Namespace string
+	Started   time.Time
+	Finished  time.Time
+
+	// Claims are the states of the claims, keyed by their kind and name.
+	Claims map[string]string
+}
+
+// Phase returns the phase the decommissioning is in.
+func (rp *Report) Phase() string {
+	if rp.Finished.IsZero() {
+		return PhaseWaitingForRemote
+	}
+	return PhaseCompleted
+}
+
+// Data returns the report in the form it's published.
+func (rp *Report) Data() map[string]string {
+	lines := make([]string, 0, len(rp.Claims))
+	for c, s := range rp.Claims {
+		lines = append(lines, fmt.Sprintf("%s: %s", c, s))
+	}
+	sort.Strings(lines)
+	d := map[string]string{
+		keyNamespace: rp.Namespace,
+		keyPhase:     rp.Phase(),
+		keyClaims:    strings.Join(lines, "\n"),
+		keyStarted:   rp.Started.UTC().Format(time.RFC3339),
+	}
+	if !rp.Finished.IsZero() {
+		d[keyFinished] = rp.Finished.UTC().Format(time.RFC3339)
+	}
+	return d
+}
+
+// Reconcile holds a namespace that contains synced claims, and decommissions
+// it once it's deleted.
+func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) { // nolint:gocyclo
+	log := r.log.WithValues("request", req)
+	log.Debug("Reconciling")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ns := &corev1.Namespace{}
+	if err := r.local.Get(ctx, req.NamespacedName, ns); err != nil {
+		if kerrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errGetNamespace)
+	}
+	claims, err := r.synced(ctx, ns.GetName())
+	if err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, err
+	}
+
+	// A finalizer can't be added to a namespace that's already being deleted,
+	// so it's added as soon as the namespace contains a synced claim.
+	if !meta.WasDeleted(ns) {
+		if len(claims) > 0 && !meta.FinalizerExists(ns, Finalizer) {
+			if err := r.finalizer.AddFinalizer(ctx, ns); err != nil {
+				return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errAddFinalizer)
+			}
+		}
+		return reconcile.Result{RequeueAfter: longWait}, nil
+	}
+
+	// Namespaces that are deleted while the agent is suspended are held until
+	// it's resumed.
+	suspended, msg, err := r.suspension.Suspended(ctx)
+	if err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errCheckSuspended)
+	}
+	if suspended {
+		log.Debug("Agent is suspended, holding namespace", "reason", msg, "requeue-after", time.Now().Add(shortWait))
+		return reconcile.Result{RequeueAfter: shortWait}, nil
+	}
+
+	rp := r.report(ns)
+	if len(rp.Claims) == 0 && len(claims) > 0 {
+		log.Info("Decommissioning namespace", "claims", len(claims))
+		r.record.Event(ns, event.Normal(reasonDecommissioning, fmt.Sprintf("Decommissioning %d synced claims", len(claims))))
+	}
+
+	pending := 0
+	for _, c := range claims {
+		id := fmt.Sprintf("%s/%s", c.GetKind(), c.GetName())
+		state, err := r.decommission(ctx, c)
+		if err != nil {
+			log.Debug("Cannot decommission claim", "claim", id, "error", err, "requeue-after", time.Now().Add(shortWait))
+			return reconcile.Result{RequeueAfter: shortWait}, err
+		}
+		rp.Claims[id] = state
+		if state != stateReleased {
+			pending++
+		}
+	}
+
+	if pending > 0 {
+		log.Debug("Waiting for remote claims to be cleaned up", "pending", pending, "requeue-after", time.Now().Add(tinyWait))
+		return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.publish(ctx, rp), localPrefix+errPublish)
+	}
+
+	rp.Finished = time.Now()
+	if err := r.publish(ctx, rp); err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errPublish)
+	}
+	if len(rp.Claims) > 0 {
+		log.Info("Decommissioned namespace", "claims", len(rp.Claims), "duration", rp.Finished.Sub(rp.Started).String())
+		r.record.Event(ns, event.Normal(reasonDecommissioned, fmt.Sprintf("Decommissioned %d synced claims in %s", len(rp.Claims), rp.Finished.Sub(rp.Started).Round(time.Second))))
+	}
+	r.forget(ns)
+	if err := r.finalizer.RemoveFinalizer(ctx, ns); err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errRemoveFinalizer)
+	}
+	return reconcile.Result{}, nil
+}
+
+// decommission takes the supplied claim one step further and returns the state
+// it's in.
+func (r *Reconciler) decommission(ctx context.Context, c *kunstructured.Unstructured) (string, error) {
+	id := fmt.Sprintf("%s %s", c.GetKind(), c.GetName())
+
+	// Deleting a namespace deletes the claims in it eventually, but they're
+	// deleted first so that nothing is pushed for them in the meantime.
+	if !meta.WasDeleted(c) {
+		if err := r.local.Delete(ctx, c); runtimeresource.IgnoreNotFound(err) != nil {
+			return "", errors.Wrapf(err, localPrefix+errFmtDeleteClaim, id)
+		}
+	}
+
+	rc := &kunstructured.Unstructured{}
+	rc.SetGroupVersionKind(r.remoteKind(c.GroupVersionKind()))
+	err := r.remote.Get(ctx, types.NamespacedName{Namespace: r.remoteNamespace(c), Name: c.GetName()}, rc)
+	if runtimeresource.IgnoreNotFound(err) != nil {
+		return "", errors.Wrapf(err, remotePrefix+errFmtGetRemote, id)
+	}
+
+	// The deletion is propagated to the remote claim that was synced from
+	// this one, and to nothing else.
+	if err == nil {
+		owned, err := r.owned(ctx, c, rc)
+		if err != nil {
+			return "", errors.Wrapf(err, remotePrefix+errFmtLock, id)
+		}
+		if !owned {
+			return stateLeftToClaim, nil
+		}
+		uid := rc.GetUID()
+		if meta.FinalizerExists(rc, agentclaim.RemoteFinalizer) {
+			meta.RemoveFinalizer(rc, agentclaim.RemoteFinalizer)
+			if err := r.remote.Update(ctx, rc); err != nil {
+				return "", errors.Wrapf(err, remotePrefix+errFmtReleaseRemote, id)
+			}
+		}
+		if !meta.WasDeleted(rc) {
+			if err := r.remote.Delete(ctx, rc, client.Preconditions{UID: &uid}); runtimeresource.IgnoreNotFound(err) != nil {
+				return "", errors.Wrapf(err, remotePrefix+errFmtDeleteRemote, id)
+			}
+		}
+		return stateRemoteDeleting, nil
+	}
+
+	// Copies of the claim that were fanned out to other remote clusters are
+	// cleaned up by the claim controller, which knows about them.
+	if _, ok := c.GetAnnotations()[resource.AnnotationKeyFanOut]; ok {
+		return stateLeftToClaim, nil
+	}
+	meta.RemoveFinalizer(c, agentclaim.Finalizer)
+	if err := r.local.Update(ctx, c); runtimeresource.IgnoreNotFound(err) != nil {
+		return "", errors.Wrapf(err, localPrefix+errFmtReleaseClaim, id)
+	}
+	return stateReleased, nil
+}
+
+// owned returns true if the supplied remote claim was synced from the supplied
+// claim by this agent, and this agent may still write to it. It applies the
+// same checks the claim controller applies before it writes.
+func (r *Reconciler) owned(ctx context.Context, c, rc *kunstructured.Unstructured) (bool, error) {
+	local, remote := &claim.Unstructured{Unstructured: *c}, &claim.Unstructured{Unstructured: *rc}
+	recorded := c.GetAnnotations()[resource.AnnotationKeyRemoteUID]
+	if recorded != "" && recorded != string(rc.GetUID()) {
+		return false, nil
+	}
+	if recorded == "" && !agentclaim.WrittenFor(remote, local, r.clusterName) || agentclaim.OwnedByOtherCluster(remote, r.clusterName) {
+		return false, nil
+	}
+	if r.fencing != nil {
+		token := r.fencing.Token()
+		if token == 0 || agentclaim.FencingTokenOf(remote) > token {
+			return false, nil
+		}
+	}
+	held, _, err := r.locker.Lock(ctx, rc.GetNamespace())
+	return held, err
+}
+
+// synced returns the claims in the supplied namespace that were synced, i.e.
+// that hold the finalizer of the claim controllers, ordered by kind and name.
+func (r *Reconciler) synced(ctx context.Context, namespace string) ([]*kunstructured.Unstructured, error) {
+	kinds, err := r.kinds(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, localPrefix+errListKinds)
+	}
+	var claims []*kunstructured.Unstructured
+	for _, gvk := range kinds {
+		l := &kunstructured.UnstructuredList{}
+		l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.local.List(ctx, l, client.InNamespace(namespace)); err != nil {
+			return nil, errors.Wrapf(err, localPrefix+errFmtListClaims, gvk)
+		}
+		for i := range l.Items {
+			if meta.FinalizerExists(&l.Items[i], agentclaim.Finalizer) {
+				claims = append(claims, &l.Items[i])
+			}
+		}
+	}
+	sort.SliceStable(claims, func(i, j int) bool {
+		if claims[i].GetKind() != claims[j].GetKind() {
+			return claims[i].GetKind() < claims[j].GetKind()
+		}
+		return claims[i].GetName() < claims[j].GetName()
+	})
+	return claims, nil
+}
+
+func (r *Reconciler) remoteNamespace(c *kunstructured.Unstructured) string {
+	if ns := c.GetAnnotations()[resource.AnnotationKeyRemoteNamespace]; ns != "" {
+		return ns
+	}
+	return r.mapper.RemoteNamespace(c.GetNamespace())
+}
+
+func (r *Reconciler) remoteKind(gvk schema.GroupVersionKind) schema.GroupVersionKind {
+	if c, ok := r.versions.Lookup(gvk); ok {
+		return c.RemoteGroupVersionKind(gvk)
+	}
+	return gvk
+}
+
+// report returns the report of the supplied namespace, which is kept until the
+// namespace is decommissioned.
+func (r *Reconciler) report(ns *corev1.Namespace) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rp, ok := r.reports[ns.GetUID()]
+	if !ok {
+		rp = &Report{Namespace: ns.GetName(), Started: ns.GetDeletionTimestamp().Time, Claims: map[string]string{}}
+		r.reports[ns.GetUID()] = rp
+	}
+	return rp
+}
+
+func (r *Reconciler) forget(ns *corev1.Namespace) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reports, ns.GetUID())
+}
+
+func (r *Reconciler) publish(ctx context.Context, rp *Report) error {
+	if r.reportNamespace == "" || len(rp.Claims) == 0 {
+		return nil
+	}
+	nn := types.NamespacedName{Namespace: r.reportNamespace, Name: ConfigMapPrefix + rp.Namespace}
+	return resource.PublishConfigMap(ctx, runtimeresource.NewAPIPatchingApplicator(r.local), nn, rp.Data())
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decommission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+var (
+	errBoom = errors.New("boom")
+	gvk     = schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "CoolClaim"}
+	kinds   = func(_ context.Context) ([]schema.GroupVersionKind, error) { return []schema.GroupVersionKind{gvk}, nil }
+)
+
+func namespace(deleted bool) func(context.Context, client.ObjectKey, runtime.Object) error {
+	return func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+		ns := obj.(*corev1.Namespace)
+		ns.SetName(key.Name)
+		ns.SetUID("cool-uid")
+		if deleted {
+			now := metav1.Now()
+			ns.SetDeletionTimestamp(&now)
+			ns.SetFinalizers([]string{Finalizer})
+		}
+		return nil
+	}
+}
+
+func syncedClaims(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+	u := kunstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace("team-a")
+	u.SetName("cool-claim")
+	u.SetFinalizers([]string{agentclaim.Finalizer})
+	obj.(*kunstructured.UnstructuredList).Items = []kunstructured.Unstructured{u}
+	return nil
+}
+
+// remoteClaim returns the remote claim of the synced claim, as written from
+// the supplied cluster.
+func remoteClaim(cluster string) func(context.Context, client.ObjectKey, runtime.Object) error {
+	return func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+		u := obj.(*kunstructured.Unstructured)
+		u.SetNamespace(key.Namespace)
+		u.SetName(key.Name)
+		u.SetAnnotations(map[string]string{
+			resource.AnnotationKeySourceObject:  "team-a/cool-claim",
+			resource.AnnotationKeySourceCluster: cluster,
+		})
+		return nil
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	type args struct {
+		local  client.Client
+		remote client.Client
+		opts   []ReconcilerOption
+	}
+	type want struct {
+		result reconcile.Result
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"GetNamespaceFailed": {
+			reason: "An error should be returned if the namespace cannot be retrieved",
+			args: args{
+				local: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: shortWait},
+				err:    errors.Wrap(errBoom, localPrefix+errGetNamespace),
+			},
+		},
+		"Held": {
+			reason: "A namespace that contains synced claims should be held until it's decommissioned",
+			args: args{
+				local: &test.MockClient{
+					MockGet:  namespace(false),
+					MockList: syncedClaims,
+					MockUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+						if !meta.FinalizerExists(obj.(metav1.Object), Finalizer) {
+							t.Errorf("\nReason: %s\nUpdate(...): namespace without finalizer", "A namespace that contains synced claims should be held until it's decommissioned")
+						}
+						return nil
+					},
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: longWait},
+			},
+		},
+		"PropagatingDeletion": {
+			reason: "The deletion of a claim should be propagated to its remote claim before the claim is released",
+			args: args{
+				local: &test.MockClient{
+					MockGet:    namespace(true),
+					MockList:   syncedClaims,
+					MockDelete: test.NewMockDeleteFn(nil),
+				},
+				remote: &test.MockClient{
+					MockGet: remoteClaim("cool-cluster"),
+					MockDelete: func(_ context.Context, obj runtime.Object, _ ...client.DeleteOption) error {
+						if diff := cmp.Diff("cool-claim", obj.(metav1.Object).GetName()); diff != "" {
+							t.Errorf("\nReason: %s\nDelete(...): -want, +got:\n%s", "The remote claim of the deleted claim should be deleted", diff)
+						}
+						return nil
+					},
+				},
+				opts: []ReconcilerOption{WithClusterName("cool-cluster")},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: tinyWait},
+			},
+		},
+		"WrittenFromOtherCluster": {
+			reason: "A remote claim that was written from another cluster should be left alone",
+			args: args{
+				local: &test.MockClient{
+					MockGet:    namespace(true),
+					MockList:   syncedClaims,
+					MockDelete: test.NewMockDeleteFn(nil),
+				},
+				remote: &test.MockClient{
+					MockGet: remoteClaim("other-cluster"),
+				},
+				opts: []ReconcilerOption{WithClusterName("cool-cluster")},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: tinyWait},
+			},
+		},
+		"NamespaceLockedByOtherAgent": {
+			reason: "A remote claim in a namespace that is synced by another agent should be left alone",
+			args: args{
+				local: &test.MockClient{
+					MockGet:    namespace(true),
+					MockList:   syncedClaims,
+					MockDelete: test.NewMockDeleteFn(nil),
+				},
+				remote: &test.MockClient{
+					MockGet: remoteClaim("cool-cluster"),
+				},
+				opts: []ReconcilerOption{
+					WithClusterName("cool-cluster"),
+					WithNamespaceLocker(agentclaim.NamespaceLockFn(func(_ context.Context, _ string) (bool, string, error) {
+						return false, "other-cluster", nil
+					})),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: tinyWait},
+			},
+		},
+		"Decommissioned": {
+			reason: "A claim whose remote claim is gone should be released, and the namespace once all of them are",
+			args: args{
+				local: &test.MockClient{
+					MockGet:    namespace(true),
+					MockList:   syncedClaims,
+					MockDelete: test.NewMockDeleteFn(nil),
+					MockUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+						o := obj.(metav1.Object)
+						if len(o.GetFinalizers()) != 0 {
+							t.Errorf("\nReason: %s\nUpdate(...): %s still has finalizers %v", "Claims and namespace should be released", o.GetName(), o.GetFinalizers())
+						}
+						return nil
+					},
+				},
+				remote: &test.MockClient{
+					MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+				},
+			},
+			want: want{
+				result: reconcile.Result{},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewReconciler(&fake.Manager{Client: tc.args.local}, tc.args.remote, kinds, tc.args.opts...)
+			got, err := r.Reconcile(reconcile.Request{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.result, got); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReportData(t *testing.T) {
+	started := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	rp := &Report{
+		Namespace: "team-a",
+		Started:   started,
+		Finished:  started.Add(time.Minute),
+		Claims:    map[string]string{"CoolClaim/b": stateReleased, "CoolClaim/a": stateReleased},
+	}
+	want := map[string]string{
+		keyNamespace: "team-a",
+		keyPhase:     PhaseCompleted,
+		keyClaims:    "CoolClaim/a: released\nCoolClaim/b: released",
+		keyStarted:   "2020-10-10T00:00:00Z",
+		keyFinished:  "2020-10-10T00:01:00Z",
+	}
+	if diff := cmp.Diff(want, rp.Data()); diff != "" {
+		t.Errorf("\nReason: %s\nData(): -want, +got:\n%s", "The report should list the claims in order", diff)
+	}
+}
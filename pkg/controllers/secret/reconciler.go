@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secret propagates the connection secrets that are written for
+// claims in the remote cluster to the local namespaces of their claims as
+// soon as they change.
+package secret
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	timeout   = 2 * time.Minute
+	shortWait = 30 * time.Second
+
+	maxConcurrency = 5
+
+	local          = "local cluster: "
+	remote         = "remote cluster: "
+	errGetSecret   = "cannot get connection secret"
+	errGetClaim    = "cannot get claim"
+	errOpenSecret  = "cannot decrypt connection secret"
+	errApplySecret = "cannot apply connection secret"
+)
+
+// Setup adds a controller that watches the connection secrets of claims in
+// the remote cluster and propagates them to the local cluster.
+func Setup(mgr manager.Manager, localClient client.Client, logger logging.Logger, opts ...ReconcilerOption) error {
+	name := "ConnectionSecrets"
+	lc := unstructured.NewClient(localClient)
+	ca := runtimeresource.ClientApplicator{
+		Client:     lc,
+		Applicator: runtimeresource.NewAPIPatchingApplicator(lc),
+	}
+	r := NewReconciler(mgr, ca, logger.WithValues("controller", name), opts...)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		For(&corev1.Secret{}).
+		WithOptions(kcontroller.Options{MaxConcurrentReconciles: maxConcurrency}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o metav1.Object, _ runtime.Object) bool {
+			return metav1.GetControllerOf(o) != nil
+		})).
+		Complete(r)
+}
+
+// ReconcilerOption is used to configure the Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithSecretEnvelope specifies how the connection secrets are decrypted before
+// they're applied in the local cluster.
+func WithSecretEnvelope(e agentclaim.SecretEnvelope) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.envelope = e
+	}
+}
+
+// NewReconciler returns a new *Reconciler.
+func NewReconciler(mgr manager.Manager, localClientApplicator runtimeresource.ClientApplicator, logger logging.Logger, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		local:    localClientApplicator,
+		remote:   unstructured.NewClient(mgr.GetClient()),
+		envelope: agentclaim.NewNopSecretEnvelope(),
+		log:      logger,
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+// Reconciler propagates the connection secrets of claims in the remote cluster
+// to the local claims they were synced from. The claim controllers propagate
+// them as well, but only as often as they poll the remote claims.
+type Reconciler struct {
+	local    runtimeresource.ClientApplicator
+	remote   client.Client
+	envelope agentclaim.SecretEnvelope
+
+	log logging.Logger
+}
+
+// Reconcile applies the connection secret in the remote cluster to the
+// namespace of the local claim, if it's the connection secret of a remote
+// claim that this agent synced.
+func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	log := r.log.WithValues("request", req)
+	log.Debug("Reconciling")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// The local copy of a connection secret that is deleted is owned by the
+	// local claim, which removes it.
+	rs := &corev1.Secret{}
+	if err := r.remote.Get(ctx, req.NamespacedName, rs); err != nil {
+		return result(err), errors.Wrap(runtimeresource.IgnoreNotFound(err), remote+errGetSecret)
+	}
+	ref := metav1.GetControllerOf(rs)
+	if ref == nil {
+		return reconcile.Result{}, nil
+	}
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	rc := claim.New(claim.WithGroupVersionKind(gvk))
+	if err := r.remote.Get(ctx, types.NamespacedName{Namespace: rs.GetNamespace(), Name: ref.Name}, rc); err != nil {
+		return result(err), errors.Wrap(runtimeresource.IgnoreNotFound(err), remote+errGetClaim)
+	}
+	if rc.GetUID() != ref.UID || rc.GetWriteConnectionSecretToReference() == nil || rc.GetWriteConnectionSecretToReference().Name != rs.GetName() {
+		return reconcile.Result{}, nil
+	}
+	nn, ok := agentclaim.SourceObject(rc.GetUnstructured())
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+
+	// Claims of the same name may be synced to the same remote namespace from
+	// other clusters too. Only the local claim that recorded the UID of the
+	// remote claim in its last sync gets its connection secret.
+	lc := claim.New(claim.WithGroupVersionKind(gvk))
+	if err := r.local.Get(ctx, nn, lc); err != nil {
+		return result(err), errors.Wrap(runtimeresource.IgnoreNotFound(err), local+errGetClaim)
+	}
+	if lc.GetAnnotations()[resource.AnnotationKeyRemoteUID] != string(rc.GetUID()) || lc.GetWriteConnectionSecretToReference() == nil {
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.envelope.Open(ctx, rs); err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, errOpenSecret)
+	}
+	ls := resource.SanitizedDeepCopyObject(rs)
+	ls.SetName(lc.GetWriteConnectionSecretToReference().Name)
+	ls.SetNamespace(lc.GetNamespace())
+	meta.AddOwnerReference(ls, meta.AsController(meta.ReferenceTo(lc, lc.GroupVersionKind())))
+	if err := r.local.Apply(ctx, ls); err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, local+errApplySecret)
+	}
+	log.Debug("Propagated connection secret", "claim", nn.String())
+	return reconcile.Result{}, nil
+}
+
+// result returns the result of a reconcile that failed to get an object. It's
+// not requeued if the object doesn't exist.
+func result(err error) reconcile.Result {
+	if kerrors.IsNotFound(err) {
+		return reconcile.Result{}
+	}
+	return reconcile.Result{RequeueAfter: shortWait}
+}
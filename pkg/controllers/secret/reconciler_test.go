@@ -0,0 +1,168 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+var errBoom = errors.New("boom")
+
+func TestReconcile(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	newClaim := func(ns, uid string, a map[string]string) *claim.Unstructured {
+		c := claim.New(claim.WithGroupVersionKind(gvk))
+		c.SetNamespace(ns)
+		c.SetName("cool-claim")
+		c.SetUID(types.UID(uid))
+		c.SetAnnotations(a)
+		c.SetWriteConnectionSecretToReference(&runtimev1alpha1.LocalSecretReference{Name: ns + "-secret"})
+		return c
+	}
+	remoteClaim := newClaim("remote-ns", "remote-uid", map[string]string{resource.AnnotationKeySourceObject: "local-ns/cool-claim"})
+	remoteSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "remote-ns", Name: "remote-ns-secret", ResourceVersion: "3"},
+		Data:       map[string][]byte{"password": []byte("cool")},
+	}
+	meta.AddOwnerReference(remoteSecret, meta.AsController(meta.ReferenceTo(remoteClaim, gvk)))
+
+	remote := func(secret *corev1.Secret, c *claim.Unstructured) *fake.Manager {
+		return &fake.Manager{Client: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+			switch o := obj.(type) {
+			case *corev1.Secret:
+				if secret == nil {
+					return kerrors.NewNotFound(schema.GroupResource{}, "")
+				}
+				secret.DeepCopyInto(o)
+			case *kunstructured.Unstructured:
+				c.GetUnstructured().DeepCopyInto(o)
+			}
+			return nil
+		}}}
+	}
+	localGet := func(c *claim.Unstructured) test.MockGetFn {
+		return func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+			if c == nil {
+				return kerrors.NewNotFound(schema.GroupResource{}, "")
+			}
+			c.GetUnstructured().DeepCopyInto(obj.(*claim.Unstructured).GetUnstructured())
+			return nil
+		}
+	}
+	synced := newClaim("local-ns", "local-uid", map[string]string{resource.AnnotationKeyRemoteUID: "remote-uid"})
+	unsynced := newClaim("local-ns", "local-uid", map[string]string{resource.AnnotationKeyRemoteUID: "other-uid"})
+
+	propagated := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "local-ns", Name: "local-ns-secret"},
+		Data:       map[string][]byte{"password": []byte("cool")},
+	}
+	meta.AddOwnerReference(propagated, meta.AsController(meta.ReferenceTo(synced, gvk)))
+
+	type want struct {
+		result  reconcile.Result
+		err     error
+		applied runtime.Object
+	}
+	cases := map[string]struct {
+		reason string
+		remote *fake.Manager
+		local  *claim.Unstructured
+		apply  error
+		want   want
+	}{
+		"SecretNotFound": {
+			reason: "A connection secret that no longer exists should be ignored",
+			remote: remote(nil, remoteClaim),
+		},
+		"NotOwned": {
+			reason: "A secret that isn't controlled by anything should be ignored",
+			remote: remote(&corev1.Secret{}, remoteClaim),
+		},
+		"LocalClaimNotFound": {
+			reason: "A connection secret whose local claim doesn't exist should be ignored",
+			remote: remote(remoteSecret, remoteClaim),
+		},
+		"SyncedFromAnotherCluster": {
+			reason: "A connection secret of a remote claim that the local claim isn't synced to should not be propagated",
+			remote: remote(remoteSecret, remoteClaim),
+			local:  unsynced,
+		},
+		"Propagated": {
+			reason: "The connection secret should be applied in the namespace of the local claim",
+			remote: remote(remoteSecret, remoteClaim),
+			local:  synced,
+			want:   want{applied: propagated},
+		},
+		"ApplyFailed": {
+			reason: "Errors applying the connection secret should be returned",
+			remote: remote(remoteSecret, remoteClaim),
+			local:  synced,
+			apply:  errBoom,
+			want: want{
+				result:  reconcile.Result{RequeueAfter: shortWait},
+				err:     errors.Wrap(errBoom, local+errApplySecret),
+				applied: propagated,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var applied runtime.Object
+			ca := runtimeresource.ClientApplicator{
+				Client: &test.MockClient{MockGet: localGet(tc.local)},
+				Applicator: runtimeresource.ApplyFn(func(_ context.Context, o runtime.Object, _ ...runtimeresource.ApplyOption) error {
+					applied = o
+					return tc.apply
+				}),
+			}
+			r := NewReconciler(tc.remote, ca, logging.NewNopLogger())
+			got, err := r.Reconcile(reconcile.Request{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.result, got); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied); diff != "" {
+				t.Errorf("\nReason: %s\nr.Reconcile(...): -want applied, +got applied:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -18,10 +18,12 @@ package xrd
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pkg/errors"
 
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
@@ -67,10 +69,63 @@ type APIRemoteCRDFetcher struct {
 
 // Fetch returns the sanitized form of the claim CRD of given CompositeResourceDefinition
 // by fetching it from remote cluster and stripping out cluster-specific metadata.
+// All of its versions are kept, the documentation of the
+// CompositeResourceDefinition is mirrored into it and the fields Agent records
+// in the status of claims are added to it.
 func (r *APIRemoteCRDFetcher) Fetch(ctx context.Context, xrd v1alpha1.CompositeResourceDefinition) (*v1beta1.CustomResourceDefinition, error) {
 	remote := &v1beta1.CustomResourceDefinition{}
 	if err := r.client.Get(ctx, GetClaimCRDName(xrd), remote); err != nil {
 		return nil, errors.Wrap(err, errGetCRD)
 	}
-	return resource.SanitizedDeepCopyObject(remote).(*v1beta1.CustomResourceDefinition), nil
+	crd := resource.SanitizedDeepCopyObject(remote).(*v1beta1.CustomResourceDefinition)
+	NormalizeVersions(crd)
+	MirrorDocumentation(xrd, crd)
+	AddAgentStatus(crd)
+	return crd, nil
+}
+
+// NewNopDefinitionChecker returns a DefinitionCheckFn that always reports the
+// definitions as ready.
+func NewNopDefinitionChecker() DefinitionCheckFn {
+	return func(_ context.Context, _ v1alpha1.CompositeResourceDefinition) (bool, string, error) {
+		return true, "", nil
+	}
+}
+
+// DefinitionCheckFn is used to provide a single function instead of a full
+// object to satisfy DefinitionChecker interface.
+type DefinitionCheckFn func(ctx context.Context, xrd v1alpha1.CompositeResourceDefinition) (bool, string, error)
+
+// Ready calls DefinitionCheckFn it belongs to.
+func (fn DefinitionCheckFn) Ready(ctx context.Context, xrd v1alpha1.CompositeResourceDefinition) (bool, string, error) {
+	return fn(ctx, xrd)
+}
+
+// NewAPICompositionChecker returns a new *APICompositionChecker.
+func NewAPICompositionChecker(c client.Reader) *APICompositionChecker {
+	return &APICompositionChecker{client: c}
+}
+
+// APICompositionChecker reports the definitions of a CompositeResourceDefinition
+// as ready once at least one Composition of its composite type is synced to
+// the local cluster.
+type APICompositionChecker struct {
+	client client.Reader
+}
+
+// Ready returns true if a Composition of the composite type of the given
+// CompositeResourceDefinition exists in the local cluster, and the reason it's
+// not ready otherwise.
+func (c *APICompositionChecker) Ready(ctx context.Context, xrd v1alpha1.CompositeResourceDefinition) (bool, string, error) {
+	l := &v1alpha1.CompositionList{}
+	if err := c.client.List(ctx, l); err != nil {
+		return false, "", errors.Wrap(err, errListCompositions)
+	}
+	for _, comp := range l.Items {
+		gvk := schema.FromAPIVersionAndKind(comp.Spec.CompositeTypeRef.APIVersion, comp.Spec.CompositeTypeRef.Kind)
+		if gvk.Group == xrd.Spec.CRDSpecTemplate.Group && gvk.Kind == xrd.Spec.CRDSpecTemplate.Names.Kind {
+			return true, "", nil
+		}
+	}
+	return false, fmt.Sprintf(errFmtNoComposition, xrd.Spec.CRDSpecTemplate.Names.Kind, xrd.Spec.CRDSpecTemplate.Group), nil
 }
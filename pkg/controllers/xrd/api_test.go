@@ -98,3 +98,58 @@ func TestFetch(t *testing.T) {
 		})
 	}
 }
+
+func TestAPICompositionChecker(t *testing.T) {
+	xrd := v1alpha1.CompositeResourceDefinition{
+		Spec: v1alpha1.CompositeResourceDefinitionSpec{
+			CRDSpecTemplate: v1alpha1.CRDSpecTemplate{
+				Group: "example.org",
+				Names: apiextensions.CustomResourceDefinitionNames{Kind: "XDatabase"},
+			},
+		},
+	}
+	list := func(ref v1alpha1.TypeReference) test.MockListFn {
+		return func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+			l := &v1alpha1.CompositionList{Items: []v1alpha1.Composition{{Spec: v1alpha1.CompositionSpec{CompositeTypeRef: ref}}}}
+			l.DeepCopyInto(obj.(*v1alpha1.CompositionList))
+			return nil
+		}
+	}
+	type want struct {
+		ready  bool
+		reason string
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		kube   client.Reader
+		want   want
+	}{
+		"ListFailed": {
+			reason: "The error should be returned if Compositions cannot be listed",
+			kube:   &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			want:   want{err: errors.Wrap(errBoom, errListCompositions)},
+		},
+		"NoComposition": {
+			reason: "The definitions should not be ready if there is no Composition of the composite type",
+			kube:   &test.MockClient{MockList: list(v1alpha1.TypeReference{APIVersion: "example.org/v1alpha1", Kind: "XCache"})},
+			want:   want{reason: "no composition of XDatabase.example.org is synced yet"},
+		},
+		"Composition": {
+			reason: "The definitions should be ready if there is a Composition of the composite type",
+			kube:   &test.MockClient{MockList: list(v1alpha1.TypeReference{APIVersion: "example.org/v1alpha1", Kind: "XDatabase"})},
+			want:   want{ready: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ready, reason, err := NewAPICompositionChecker(tc.kube).Ready(context.Background(), xrd)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nReady(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(want{ready: tc.want.ready, reason: tc.want.reason}, want{ready: ready, reason: reason}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nReady(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xrd
+
+import (
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+// AnnotationKeyDocsPrefix is the prefix of the annotations on XRDs that
+// document the types they define, such as "docs.crossplane.io/url" or
+// "docs.crossplane.io/example". They are copied to the local claim CRD.
+const AnnotationKeyDocsPrefix = "docs.crossplane.io/"
+
+// The fields of a schema that document it.
+var docFields = []string{"description", "example", "externalDocs"}
+
+// MirrorDocumentation copies the documentation of the supplied XRD to its claim
+// CRD so that `kubectl explain` and documentation tools in the local cluster
+// show it. The documentation annotations of the XRD are added to the CRD, and
+// the descriptions, examples and external documentation links in the schema
+// template of the XRD fill the ones that are missing in the schema of the CRD.
+// Nothing that's already documented in the CRD is overwritten.
+func MirrorDocumentation(xrd v1alpha1.CompositeResourceDefinition, crd *v1beta1.CustomResourceDefinition) {
+	for k, v := range xrd.GetAnnotations() {
+		if !strings.HasPrefix(k, AnnotationKeyDocsPrefix) {
+			continue
+		}
+		a := crd.GetAnnotations()
+		if a == nil {
+			a = map[string]string{}
+		}
+		a[k] = v
+		crd.SetAnnotations(a)
+	}
+
+	x, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&xrd)
+	if err != nil {
+		return
+	}
+	from, err := fieldpath.Pave(x).GetValue("spec.crdSpecTemplate.validation.openAPIV3Schema")
+	if err != nil {
+		return
+	}
+	c, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+	if err != nil {
+		return
+	}
+	to, err := fieldpath.Pave(c).GetValue("spec.validation.openAPIV3Schema")
+	if err != nil {
+		return
+	}
+	fm, fok := from.(map[string]interface{})
+	tm, tok := to.(map[string]interface{})
+	if !fok || !tok {
+		return
+	}
+	mirrorSchemaDocs(fm, tm)
+	out := &v1beta1.CustomResourceDefinition{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(c, out); err != nil {
+		return
+	}
+	*crd = *out
+}
+
+func mirrorSchemaDocs(from, to map[string]interface{}) {
+	for _, f := range docFields {
+		if _, ok := to[f]; ok {
+			continue
+		}
+		if v, ok := from[f]; ok {
+			to[f] = v
+		}
+	}
+	if fi, ok := from["items"].(map[string]interface{}); ok {
+		if ti, ok := to["items"].(map[string]interface{}); ok {
+			mirrorSchemaDocs(fi, ti)
+		}
+	}
+	fp, _ := from["properties"].(map[string]interface{})
+	tp, _ := to["properties"].(map[string]interface{})
+	for name, fv := range fp {
+		fs, fok := fv.(map[string]interface{})
+		ts, tok := tp[name].(map[string]interface{})
+		if fok && tok {
+			mirrorSchemaDocs(fs, ts)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xrd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+func TestMirrorDocumentation(t *testing.T) {
+	xrd := v1alpha1.CompositeResourceDefinition{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				AnnotationKeyDocsPrefix + "url": "https://example.org/docs",
+				"unrelated":                     "value",
+			},
+		},
+		"spec": map[string]interface{}{
+			"crdSpecTemplate": map[string]interface{}{
+				"validation": map[string]interface{}{
+					"openAPIV3Schema": map[string]interface{}{
+						"description": "A cool database.",
+						"properties": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"properties": map[string]interface{}{
+									"size": map[string]interface{}{
+										"description": "Size of the database in GB.",
+										"example":     int64(10),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, &xrd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crd := &apiextensions.CustomResourceDefinition{
+		Spec: apiextensions.CustomResourceDefinitionSpec{
+			Validation: &apiextensions.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensions.JSONSchemaProps{
+					Description: "Documented locally.",
+					Properties: map[string]apiextensions.JSONSchemaProps{
+						"spec": {Properties: map[string]apiextensions.JSONSchemaProps{
+							"size": {Type: "integer"},
+						}},
+					},
+				},
+			},
+		},
+	}
+	want := &apiextensions.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationKeyDocsPrefix + "url": "https://example.org/docs"},
+		},
+		Spec: apiextensions.CustomResourceDefinitionSpec{
+			Validation: &apiextensions.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensions.JSONSchemaProps{
+					Description: "Documented locally.",
+					Properties: map[string]apiextensions.JSONSchemaProps{
+						"spec": {Properties: map[string]apiextensions.JSONSchemaProps{
+							"size": {
+								Type:        "integer",
+								Description: "Size of the database in GB.",
+								Example:     &apiextensions.JSON{Raw: []byte("10")},
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+	MirrorDocumentation(xrd, crd)
+	if diff := cmp.Diff(want, crd); diff != "" {
+		t.Errorf("\nReason: %s\nMirrorDocumentation(...): -want, +got:\n%s", "Missing documentation should be mirrored without overwriting the existing one", diff)
+	}
+}
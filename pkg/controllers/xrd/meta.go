@@ -17,36 +17,110 @@ limitations under the License.
 package xrd
 
 import (
-	"fmt"
-
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/resource"
 )
 
 // GetClaimCRDName returns the name of the claim CRD that's created as result of
 // given CompositeResourceDefinition.
 func GetClaimCRDName(xrd v1alpha1.CompositeResourceDefinition) types.NamespacedName {
-	if xrd.Spec.ClaimNames == nil {
-		return types.NamespacedName{}
-	}
-	return types.NamespacedName{Name: fmt.Sprintf("%s.%s", xrd.Spec.ClaimNames.Plural, xrd.Spec.CRDSpecTemplate.Group)}
+	return types.NamespacedName{Name: resource.ClaimCRDName(xrd)}
 }
 
-// GroupVersionKindOf returns the served GroupVersionKind of given CRD.
+// GroupVersionKindOf returns the GroupVersionKind of given CRD that claims are
+// synced with, which is its storage version if it's served and its first served
+// version otherwise.
 func GroupVersionKindOf(crd v1beta1.CustomResourceDefinition) schema.GroupVersionKind {
-	servedVersion := crd.Spec.Version
-	for _, v := range crd.Spec.Versions {
-		if v.Served {
-			servedVersion = v.Name
-			break
+	return resource.ClaimGroupVersionKind(crd)
+}
+
+// NormalizeVersions makes sure that all versions of the given CRD are listed in
+// its versions array and that exactly one of them is the storage version, so
+// that local clients of every version the remote cluster serves keep working
+// as the remote cluster is upgraded.
+func NormalizeVersions(crd *v1beta1.CustomResourceDefinition) {
+	if len(crd.Spec.Versions) == 0 {
+		if crd.Spec.Version == "" {
+			return
+		}
+		crd.Spec.Versions = []v1beta1.CustomResourceDefinitionVersion{{Name: crd.Spec.Version, Served: true, Storage: true}}
+		return
+	}
+	storage := -1
+	for i, v := range crd.Spec.Versions {
+		if v.Storage && storage == -1 {
+			storage = i
+		}
+		crd.Spec.Versions[i].Storage = false
+	}
+	if storage == -1 {
+		storage = 0
+		for i, v := range crd.Spec.Versions {
+			if v.Name == crd.Spec.Version {
+				storage = i
+				break
+			}
+		}
+	}
+	crd.Spec.Versions[storage].Storage = true
+
+	// The top-level version has to match the first one in the array.
+	crd.Spec.Version = crd.Spec.Versions[0].Name
+}
+
+var preserveUnknownFields = true
+
+// AgentStatusProps are the fields Agent records in the status of local claims.
+var AgentStatusProps = v1beta1.JSONSchemaProps{
+	Type:        "object",
+	Description: "Agent describes how the claim is synced to the remote cluster.",
+	Properties: map[string]v1beta1.JSONSchemaProps{
+		"pushedGeneration": {
+			Type:        "integer",
+			Description: "PushedGeneration is the last generation of the claim that was pushed to the remote cluster.",
+		},
+		"propagationLagSeconds": {
+			Type:        "number",
+			Description: "PropagationLagSeconds is how long it took for the last pushed generation to reach the remote cluster.",
+		},
+		"resolved": {
+			Type:                   "object",
+			Description:            "Resolved is the fields of the spec that the remote cluster filled in, like defaulted parameters or the selected composition.",
+			XPreserveUnknownFields: &preserveUnknownFields,
+		},
+		"remotes": {
+			Type:                   "object",
+			Description:            "Remotes is the state of the claim in each remote cluster it's fanned out to.",
+			XPreserveUnknownFields: &preserveUnknownFields,
+		},
+	},
+}
+
+// AddAgentStatus adds the fields Agent records in the status of local claims
+// to every schema of the given CRD that describes the status, so that they're
+// not pruned.
+func AddAgentStatus(crd *v1beta1.CustomResourceDefinition) {
+	add := func(v *v1beta1.CustomResourceValidation) {
+		if v == nil || v.OpenAPIV3Schema == nil {
+			return
+		}
+		status, ok := v.OpenAPIV3Schema.Properties["status"]
+		if !ok {
+			return
+		}
+		if status.Properties == nil {
+			status.Properties = map[string]v1beta1.JSONSchemaProps{}
 		}
+		status.Properties["agent"] = *AgentStatusProps.DeepCopy()
+		v.OpenAPIV3Schema.Properties["status"] = status
 	}
-	return schema.GroupVersionKind{
-		Group:   crd.Spec.Group,
-		Kind:    crd.Spec.Names.Kind,
-		Version: servedVersion,
+	add(crd.Spec.Validation)
+	for i := range crd.Spec.Versions {
+		add(crd.Spec.Versions[i].Schema)
 	}
 }
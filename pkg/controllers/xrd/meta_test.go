@@ -0,0 +1,183 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xrd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGroupVersionKindOf(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		spec   apiextensions.CustomResourceDefinitionSpec
+		want   string
+	}{
+		"SingleVersion": {
+			reason: "The only version of a CRD should be used",
+			spec:   apiextensions.CustomResourceDefinitionSpec{Version: "v1alpha1"},
+			want:   "v1alpha1",
+		},
+		"StorageVersion": {
+			reason: "The storage version should be preferred if it's served",
+			spec: apiextensions.CustomResourceDefinitionSpec{
+				Version: "v1alpha1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Served: true},
+					{Name: "v1beta1", Served: true, Storage: true},
+				},
+			},
+			want: "v1beta1",
+		},
+		"StorageVersionNotServed": {
+			reason: "The first served version should be used if the storage version is not served",
+			spec: apiextensions.CustomResourceDefinitionSpec{
+				Version: "v1alpha1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Storage: true},
+					{Name: "v1beta1", Served: true},
+				},
+			},
+			want: "v1beta1",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := GroupVersionKindOf(apiextensions.CustomResourceDefinition{Spec: tc.spec})
+			if diff := cmp.Diff(schema.GroupVersionKind{Version: tc.want}, got); diff != "" {
+				t.Errorf("\nReason: %s\nGroupVersionKindOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNormalizeVersions(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		spec   apiextensions.CustomResourceDefinitionSpec
+		want   apiextensions.CustomResourceDefinitionSpec
+	}{
+		"NoVersions": {
+			reason: "A CRD without a version should not be changed",
+		},
+		"TopLevelVersion": {
+			reason: "The top-level version should be listed as the served storage version",
+			spec:   apiextensions.CustomResourceDefinitionSpec{Version: "v1alpha1"},
+			want: apiextensions.CustomResourceDefinitionSpec{
+				Version:  "v1alpha1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{{Name: "v1alpha1", Served: true, Storage: true}},
+			},
+		},
+		"MultipleStorageVersions": {
+			reason: "Only the first storage version should be kept as such and all versions should be kept",
+			spec: apiextensions.CustomResourceDefinitionSpec{
+				Version: "v1alpha1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1beta1", Served: true, Storage: true},
+					{Name: "v1alpha1", Served: true, Storage: true},
+				},
+			},
+			want: apiextensions.CustomResourceDefinitionSpec{
+				Version: "v1beta1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1beta1", Served: true, Storage: true},
+					{Name: "v1alpha1", Served: true},
+				},
+			},
+		},
+		"NoStorageVersion": {
+			reason: "The top-level version should be the storage version if none is marked",
+			spec: apiextensions.CustomResourceDefinitionSpec{
+				Version: "v1beta1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Served: true},
+					{Name: "v1beta1", Served: true},
+				},
+			},
+			want: apiextensions.CustomResourceDefinitionSpec{
+				Version: "v1alpha1",
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Served: true},
+					{Name: "v1beta1", Served: true, Storage: true},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := &apiextensions.CustomResourceDefinition{Spec: tc.spec}
+			NormalizeVersions(crd)
+			if diff := cmp.Diff(tc.want, crd.Spec); diff != "" {
+				t.Errorf("\nReason: %s\nNormalizeVersions(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAddAgentStatus(t *testing.T) {
+	withStatus := func(props map[string]apiextensions.JSONSchemaProps) *apiextensions.CustomResourceValidation {
+		return &apiextensions.CustomResourceValidation{OpenAPIV3Schema: &apiextensions.JSONSchemaProps{
+			Type:       "object",
+			Properties: map[string]apiextensions.JSONSchemaProps{"status": {Type: "object", Properties: props}},
+		}}
+	}
+	cases := map[string]struct {
+		reason string
+		spec   apiextensions.CustomResourceDefinitionSpec
+		want   apiextensions.CustomResourceDefinitionSpec
+	}{
+		"NoSchema": {
+			reason: "A CRD without a schema should not be changed",
+		},
+		"NoStatus": {
+			reason: "A schema that doesn't describe the status should not be changed",
+			spec: apiextensions.CustomResourceDefinitionSpec{
+				Validation: &apiextensions.CustomResourceValidation{OpenAPIV3Schema: &apiextensions.JSONSchemaProps{Type: "object"}},
+			},
+			want: apiextensions.CustomResourceDefinitionSpec{
+				Validation: &apiextensions.CustomResourceValidation{OpenAPIV3Schema: &apiextensions.JSONSchemaProps{Type: "object"}},
+			},
+		},
+		"Status": {
+			reason: "The agent status should be added to every schema that describes the status",
+			spec: apiextensions.CustomResourceDefinitionSpec{
+				Validation: withStatus(map[string]apiextensions.JSONSchemaProps{"bindingPhase": {Type: "string"}}),
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Schema: withStatus(nil)},
+				},
+			},
+			want: apiextensions.CustomResourceDefinitionSpec{
+				Validation: withStatus(map[string]apiextensions.JSONSchemaProps{"bindingPhase": {Type: "string"}, "agent": AgentStatusProps}),
+				Versions: []apiextensions.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Schema: withStatus(map[string]apiextensions.JSONSchemaProps{"agent": AgentStatusProps})},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := &apiextensions.CustomResourceDefinition{Spec: tc.spec}
+			AddAgentStatus(crd)
+			if diff := cmp.Diff(tc.want, crd.Spec); diff != "" {
+				t.Errorf("\nReason: %s\nAddAgentStatus(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
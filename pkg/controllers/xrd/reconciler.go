@@ -18,16 +18,20 @@ package xrd
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/crossplane/agent/pkg/resource"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	kmeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	kcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -44,7 +48,10 @@ import (
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1/ccrd"
 	coreclaim "github.com/crossplane/crossplane/pkg/controller/apiextensions/claim"
 
+	"github.com/crossplane/agent/pkg/backpressure"
 	"github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/conversion"
+	"github.com/crossplane/agent/pkg/requeue"
 )
 
 const (
@@ -53,6 +60,10 @@ const (
 	shortWait = 30 * time.Second
 	tinyWait  = 3 * time.Second
 
+	// maxClaimConcurrency is the number of syncs a claim controller may run
+	// at once when they're limited by a Regulator.
+	maxClaimConcurrency = 5
+
 	finalizer = "agent.crossplane.io/claim-crd-controller"
 
 	localPrefix        = "local cluster: "
@@ -68,23 +79,53 @@ const (
 	errDeleteCR        = "cannot delete custom resources of claim type"
 	errDeleteCRD       = "cannot delete crd of claim type"
 	errAddFinalizerXRD = "cannot add finalizer to xrd"
+	errConversion      = "cannot configure conversion of custom resource definition"
+	errCheckDefinition = "cannot check whether the definitions of claim type are ready"
+
+	errListCompositions = "cannot list compositions"
+	errFmtNoComposition = "no composition of %s.%s is synced yet"
 )
 
 // Setup adds a controller that will reconcile CompositeResourceDefinitions that
 // offer resource claim in the local cluster and create CRDs & controllers that
 // will reconcile those new types.
-func Setup(mgr manager.Manager, remoteClient client.Client, logger logging.Logger) error {
+func Setup(mgr manager.Manager, remoteClient client.Client, logger logging.Logger, opts ...ReconcilerOption) error {
 	name := "ClaimCustomResourceDefinitions"
-	r := NewReconciler(mgr, remoteClient,
+	r := NewReconciler(mgr, remoteClient, append([]ReconcilerOption{
 		WithCRDFetcher(NewAPIRemoteCRDFetcher(remoteClient)),
+		WithDefinitionChecker(NewAPICompositionChecker(mgr.GetClient())),
 		WithLogger(logger),
-		WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
-	return ctrl.NewControllerManagedBy(mgr).
+		WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		WithClaimReconcilerOptions(claim.WithLiveReader(mgr.GetAPIReader())),
+	}, opts...)...)
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		For(&v1alpha1.CompositeResourceDefinition{}).
 		WithEventFilter(resource.NewXRDWithClaim()).
-		Owns(&v1beta1.CustomResourceDefinition{}).
-		Complete(r)
+		Owns(&v1beta1.CustomResourceDefinition{})
+	if r.backoff != nil {
+		b = b.WithOptions(kcontroller.Options{RateLimiter: r.backoff})
+	}
+	return b.Complete(r)
+}
+
+// WithSyncInterval specifies how long to wait before reconciling a
+// CompositeResourceDefinition again when nothing about it changed.
+func WithSyncInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.syncInterval = d
+	}
+}
+
+// WithErrorRetryInterval specifies how long to wait before retrying a
+// CompositeResourceDefinition that failed to reconcile. The wait starts at base
+// and doubles with each consecutive failure, up to ceiling, with jitter. Since
+// the Reconciler returns the errors it encounters, the wait is applied by the
+// rate limiter of its controller.
+func WithErrorRetryInterval(base, ceiling time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.backoff = requeue.NewBackoff(base, ceiling, requeue.DefaultJitter)
+	}
 }
 
 // WithControllerEngine specifies how the Reconciler should start and stop controllers.
@@ -116,6 +157,59 @@ func WithLocalApplicator(a runtimeresource.Applicator) ReconcilerOption {
 	}
 }
 
+// WithClaimReconcilerOptions specifies the options that will be supplied to the
+// claim Reconcilers started by the Reconciler.
+func WithClaimReconcilerOptions(opts ...claim.ReconcilerOption) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.claimOpts = append(r.claimOpts, opts...)
+	}
+}
+
+// WithRemotes specifies the remote clusters, other than the one the Reconciler
+// is created with, that a claim controller is started for along with the one
+// of the default remote cluster. The claims of every type are routed to one of
+// them by their resource.LabelKeyRemote label.
+func WithRemotes(rs ...claim.Remote) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.remotes = append(r.remotes, rs...)
+	}
+}
+
+// WithRegulator specifies the Regulator that applies backpressure to the claim
+// Reconcilers started by the Reconciler. The same Regulator should be shared by
+// all of them so that the backpressure is applied to all claim types at once.
+func WithRegulator(reg *backpressure.Regulator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.regulator = reg
+	}
+}
+
+// WithConversionConfigurator specifies how the Reconciler should configure the
+// conversion of claim CRDs that are converted by a webhook in the remote
+// cluster.
+func WithConversionConfigurator(c *conversion.Configurator) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.conversion = c
+	}
+}
+
+// WithDefinitionChecker specifies how the Reconciler should check that the
+// definitions claims depend on are present before starting their controller.
+func WithDefinitionChecker(c DefinitionChecker) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.definitions = c
+	}
+}
+
+// WithRemoteInformers specifies the informers of the remote cluster that the
+// claim controllers started by the Reconciler should watch remote claims with,
+// in addition to polling them.
+func WithRemoteInformers(i cache.Informers) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.remoteInformers = i
+	}
+}
+
 // WithLogger specifies how the Reconciler should log messages.
 func WithLogger(log logging.Logger) ReconcilerOption {
 	return func(r *Reconciler) {
@@ -141,12 +235,16 @@ func NewReconciler(mgr manager.Manager, remoteClient client.Client, opts ...Reco
 			Client:     mgr.GetClient(),
 			Applicator: runtimeresource.NewAPIUpdatingApplicator(mgr.GetClient()),
 		},
-		remote:    remoteClient,
-		engine:    controller.NewEngine(mgr),
-		crd:       NewNopFetcher(),
-		finalizer: runtimeresource.NewAPIFinalizer(mgr.GetClient(), finalizer),
-		log:       logging.NewNopLogger(),
-		record:    event.NewNopRecorder(),
+		remote:      remoteClient,
+		engine:      controller.NewEngine(mgr),
+		crd:         NewNopFetcher(),
+		conversion:  conversion.NewConfigurator(conversion.PolicyNone, nil, nil),
+		definitions: NewNopDefinitionChecker(),
+		finalizer:   runtimeresource.NewAPIFinalizer(mgr.GetClient(), finalizer),
+		log:         logging.NewNopLogger(),
+		record:      event.NewNopRecorder(),
+
+		syncInterval: longWait,
 	}
 	for _, f := range opts {
 		f(r)
@@ -166,6 +264,13 @@ type CRDFetcher interface {
 	Fetch(ctx context.Context, ip v1alpha1.CompositeResourceDefinition) (*v1beta1.CustomResourceDefinition, error)
 }
 
+// A DefinitionChecker checks whether the definitions that the claims of a
+// CompositeResourceDefinition depend on, other than their CRD, are present in
+// the local cluster.
+type DefinitionChecker interface {
+	Ready(ctx context.Context, xrd v1alpha1.CompositeResourceDefinition) (bool, string, error)
+}
+
 // Reconciler watches the CompositeResourceDefinition with resource claim offerings
 // in the cluster and creates a CRD for each of them with spec that is fetched
 // via supplied CRDFetcher. Then it creates a controller for each new type that
@@ -175,9 +280,22 @@ type Reconciler struct {
 	local  runtimeresource.ClientApplicator
 	remote client.Client
 
-	crd       CRDFetcher
-	engine    ControllerEngine
-	finalizer runtimeresource.Finalizer
+	crd         CRDFetcher
+	conversion  *conversion.Configurator
+	definitions DefinitionChecker
+	engine      ControllerEngine
+	finalizer   runtimeresource.Finalizer
+
+	claimOpts []claim.ReconcilerOption
+	remotes   []claim.Remote
+	regulator *backpressure.Regulator
+
+	syncInterval time.Duration
+	backoff      *requeue.Backoff
+
+	remoteInformers cache.Informers
+	watchesMu       sync.Mutex
+	watches         map[string]*claim.RemoteWatch
 
 	log    logging.Logger
 	record event.Recorder
@@ -187,10 +305,17 @@ type Reconciler struct {
 
 // Reconcile reconciles CompositeResourceDefinition and does the necessary operations
 // to bootstrap reconciliation of that new type defined by CompositeResourceDefinition.
-func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) { // nolint:gocyclo
+func (r *Reconciler) Reconcile(req reconcile.Request) (result reconcile.Result, err error) { // nolint:gocyclo
 	log := r.log.WithValues("request", req)
 	log.Debug("Reconciling")
 
+	// Every pass is logged with how long it took and how it ended. Errors are
+	// prefixed with the cluster they come from.
+	start := time.Now()
+	defer func() {
+		log.Debug("Reconciled", "duration", time.Since(start), "requeue-after", result.RequeueAfter, "error", err)
+	}()
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -225,7 +350,7 @@ func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error)
 			// It's likely that we've already stopped this controller on a
 			// previous reconcile, but we try again just in case. This is a
 			// no-op if the controller was already stopped.
-			r.engine.Stop(coreclaim.ControllerName(xrd.GetName()))
+			r.stopControllers(xrd.GetName())
 
 			if err := r.finalizer.RemoveFinalizer(ctx, xrd); err != nil {
 				return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errRemoveFinalizer)
@@ -260,12 +385,14 @@ func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error)
 
 		// The controller should be stopped before the deletion of CRD so that
 		// it doesn't crash.
-		r.engine.Stop(coreclaim.ControllerName(xrd.GetName()))
+		r.stopControllers(xrd.GetName())
 
 		if err := r.local.Delete(ctx, localCRD); runtimeresource.IgnoreNotFound(err) != nil {
 			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errDeleteCRD)
 		}
 
+		log.Debug("Deleted CRD of claim type", "operation", "delete", "crd", localCRD.GetName())
+
 		// We should be requeued implicitly because we're watching the
 		// CustomResourceDefinition that we just deleted, but we requeue after
 		// a tiny wait just in case the CRD isn't gone after the first requeue.
@@ -281,12 +408,19 @@ func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error)
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errAddFinalizerXRD)
 	}
 
+	// The conversion webhook of the remote CRD isn't reachable from the local
+	// api-server as it's declared, so it's either stripped or proxied.
+	if err := r.conversion.Configure(localCRD, xrd); err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, errConversion)
+	}
+
 	// We'll create or update the CRD of the claim type in local cluster to make
 	// it available to users.
 	meta.AddOwnerReference(localCRD, meta.AsController(meta.ReferenceTo(xrd, v1alpha1.CompositeResourceDefinitionGroupVersionKind)))
 	if err := r.local.Apply(ctx, localCRD, runtimeresource.MustBeControllableBy(xrd.GetUID())); err != nil {
 		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errApplyCRD)
 	}
+	log.Debug("Applied CRD of claim type", "operation", "apply", "crd", localCRD.GetName())
 
 	// It takes a little while for Kubernetes API Server to establish the new API
 	// endpoints for the CRD. We'd like to make sure it's ready before starting
@@ -295,31 +429,115 @@ func (r *Reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error)
 		return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, xrd), localPrefix+errUpdateStatus)
 	}
 
+	// Claims that are synced before the Compositions of their type would fail
+	// in the remote cluster, and their controller would report misleading
+	// errors until the Compositions arrive, so it's started only once they're
+	// present.
+	ready, reason, err := r.definitions.Ready(ctx, *xrd)
+	if err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errCheckDefinition)
+	}
+	if !ready {
+		log.Debug("Waiting for definitions of claim type", "reason", reason, "requeue-after", time.Now().Add(tinyWait))
+		return reconcile.Result{RequeueAfter: tinyWait}, errors.Wrap(r.local.Status().Update(ctx, xrd), localPrefix+errUpdateStatus)
+	}
+
+	// A controller is started for the type for every remote cluster, each
+	// syncing the claims that are routed to it.
+	gvk := GroupVersionKindOf(*localCRD)
+	if err := r.startController(coreclaim.ControllerName(xrd.GetName()), "", r.remote, gvk, nil, log); err != nil {
+		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errStartController)
+	}
+	for _, rm := range r.remotes {
+		if err := r.startController(coreclaim.ControllerName(xrd.GetName()), rm.Name, rm.Client, gvk, rm.Options, log); err != nil {
+			return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errStartController)
+		}
+	}
+
+	// The reconciliation is completed successfully.
+	xrd.Status.SetConditions(runtimev1alpha1.ReconcileSuccess())
+	return reconcile.Result{RequeueAfter: r.syncInterval}, errors.Wrap(r.local.Status().Update(ctx, xrd), localPrefix+errUpdateStatus)
+}
+
+// startController starts the controller with the supplied name that syncs the
+// claims of the supplied kind that are routed to the supplied remote cluster.
+func (r *Reconciler) startController(name, remote string, remoteClient client.Client, gvk schema.GroupVersionKind, opts []claim.ReconcilerOption, log logging.Logger) error {
+	name = claim.RemoteControllerName(name, remote)
+
 	// The new controller for the type is configured with a reconciler and other
 	// parameters that the reconciler requires.
-	o := kcontroller.Options{Reconciler: claim.NewReconciler(r.mgr,
-		r.remote,
-		GroupVersionKindOf(*localCRD),
-		claim.WithLogger(log.WithValues("controller", coreclaim.ControllerName(xrd.GetName()))),
-		claim.WithRecorder(r.record.WithAnnotations("controller", coreclaim.ControllerName(xrd.GetName()))),
-	)}
+	l, rec := log.WithValues("controller", name), r.record.WithAnnotations("controller", name)
+	if remote != "" {
+		l, rec = l.WithValues("remote", remote), rec.WithAnnotations("remote", remote)
+	}
+	l.Debug("Starting claim controller", "gvk", gvk.String())
+	co := append([]claim.ReconcilerOption{claim.WithLogger(l), claim.WithRecorder(rec)}, r.claimOpts...)
+	co = append(co, opts...)
+	if len(r.remotes) > 0 {
+		co = append(co, claim.WithRemoteRouting(remote, claim.RemoteNames(r.remotes)))
+	}
+	cr := claim.NewReconciler(r.mgr, remoteClient, gvk, co...)
+	o := kcontroller.Options{Reconciler: cr}
+	if r.regulator != nil {
+		// The Regulator limits the syncs across all claim types, so every
+		// controller may run several at once to let the ones with a higher
+		// priority go ahead.
+		o.Reconciler = backpressure.NewReconciler(cr, r.regulator, backpressure.WithPriority(backpressure.NewAnnotationPriority(r.mgr.GetClient(), gvk)))
+		o.MaxConcurrentReconciles = maxClaimConcurrency
+	}
 
 	// Since we don't have strongly typed structs for the claims, we set the GVK
 	// of Unstructured object so that controller-runtime is able to get events
 	// of them via its unstructured client.
 	rq := &kunstructured.Unstructured{}
-	rq.SetGroupVersionKind(GroupVersionKindOf(*localCRD))
+	rq.SetGroupVersionKind(gvk)
+
+	// Only the remote claims of the default remote cluster are watched, the
+	// ones of the others are polled.
+	var claims, namespaces handler.EventHandler = &handler.EnqueueRequestForObject{}, claim.EnqueueRequestsForResyncedNamespace(r.mgr.GetClient(), gvk)
+	if remote == "" {
+		if w := r.remoteWatch(name, gvk); w != nil {
+			claims, namespaces = w.Handler(claims), w.Handler(namespaces)
+		}
+	}
 
 	// We're all set for starting the controller. This assumes that ControllerEngine
 	// Start call is idempotent, hence we don't check whether it was already started
 	// or not.
-	if err := r.engine.Start(coreclaim.ControllerName(xrd.GetName()), o,
-		controller.For(rq, &handler.EnqueueRequestForObject{}),
-	); err != nil {
-		return reconcile.Result{RequeueAfter: shortWait}, errors.Wrap(err, localPrefix+errStartController)
+	return r.engine.Start(name, o,
+		controller.For(rq, claims),
+		controller.For(&corev1.Namespace{}, namespaces),
+	)
+}
+
+// stopControllers stops the controllers of the claims of the supplied
+// CompositeResourceDefinition for all remote clusters. Stopping a controller
+// that was already stopped is a no-op.
+func (r *Reconciler) stopControllers(xrd string) {
+	r.engine.Stop(coreclaim.ControllerName(xrd))
+	for _, rm := range r.remotes {
+		r.engine.Stop(claim.RemoteControllerName(coreclaim.ControllerName(xrd), rm.Name))
 	}
+}
 
-	// The reconciliation is completed successfully.
-	xrd.Status.SetConditions(runtimev1alpha1.ReconcileSuccess())
-	return reconcile.Result{RequeueAfter: longWait}, errors.Wrap(r.local.Status().Update(ctx, xrd), localPrefix+errUpdateStatus)
+// remoteWatch returns the started RemoteWatch of the claims of the supplied
+// controller, or nil if remote claims aren't watched. A controller that's
+// restarted keeps its RemoteWatch since informers can't be removed from the
+// cache of the remote cluster.
+func (r *Reconciler) remoteWatch(name string, gvk schema.GroupVersionKind) *claim.RemoteWatch {
+	if r.remoteInformers == nil {
+		return nil
+	}
+	r.watchesMu.Lock()
+	defer r.watchesMu.Unlock()
+	if r.watches == nil {
+		r.watches = map[string]*claim.RemoteWatch{}
+	}
+	w, ok := r.watches[name]
+	if !ok {
+		w = claim.NewRemoteWatch(r.remoteInformers, gvk, r.log.WithValues("controller", name))
+		r.watches[name] = w
+	}
+	w.Start()
+	return w
 }
@@ -36,6 +36,9 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+	coreclaim "github.com/crossplane/crossplane/pkg/controller/apiextensions/claim"
+
+	"github.com/crossplane/agent/pkg/controllers/claim"
 )
 
 var (
@@ -484,6 +487,83 @@ func TestReconcile(t *testing.T) {
 				result: reconcile.Result{RequeueAfter: tinyWait},
 			},
 		},
+		"CheckDefinitionsFailed": {
+			reason: "The error should be returned if the definitions of the claim type cannot be checked",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+					},
+				},
+				opts: []ReconcilerOption{
+					WithLocalApplicator(resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+						return nil
+					})),
+					WithFinalizer(resource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ resource.Object) error {
+						return nil
+					}}),
+					WithCRDFetcher(FetchFn(func(_ context.Context, _ v1alpha1.CompositeResourceDefinition) (*apiextensions.CustomResourceDefinition, error) {
+						return &apiextensions.CustomResourceDefinition{
+							Status: apiextensions.CustomResourceDefinitionStatus{
+								Conditions: []apiextensions.CustomResourceDefinitionCondition{
+									{
+										Type:   apiextensions.Established,
+										Status: apiextensions.ConditionTrue,
+									},
+								},
+							},
+						}, nil
+					})),
+					WithDefinitionChecker(DefinitionCheckFn(func(_ context.Context, _ v1alpha1.CompositeResourceDefinition) (bool, string, error) {
+						return false, "", errBoom
+					})),
+				},
+			},
+			want: want{
+				err:    errors.Wrap(errBoom, localPrefix+errCheckDefinition),
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
+		"DefinitionsNotReady": {
+			reason: "The controller should not be started until the definitions of the claim type are ready",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet:          test.NewMockGetFn(nil),
+						MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+					},
+				},
+				opts: []ReconcilerOption{
+					WithLocalApplicator(resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+						return nil
+					})),
+					WithFinalizer(resource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ resource.Object) error {
+						return nil
+					}}),
+					WithCRDFetcher(FetchFn(func(_ context.Context, _ v1alpha1.CompositeResourceDefinition) (*apiextensions.CustomResourceDefinition, error) {
+						return &apiextensions.CustomResourceDefinition{
+							Status: apiextensions.CustomResourceDefinitionStatus{
+								Conditions: []apiextensions.CustomResourceDefinitionCondition{
+									{
+										Type:   apiextensions.Established,
+										Status: apiextensions.ConditionTrue,
+									},
+								},
+							},
+						}, nil
+					})),
+					WithDefinitionChecker(DefinitionCheckFn(func(_ context.Context, _ v1alpha1.CompositeResourceDefinition) (bool, string, error) {
+						return false, "no composition", nil
+					})),
+					WithControllerEngine(&MockEngine{MockStart: func(_ string, _ kcontroller.Options, _ ...controller.Watch) error {
+						return errBoom
+					}}),
+				},
+			},
+			want: want{
+				result: reconcile.Result{RequeueAfter: tinyWait},
+			},
+		},
 		"StartControllerFailed": {
 			reason: "The error should be returned if engine cannot start the controller",
 			args: args{
@@ -521,6 +601,47 @@ func TestReconcile(t *testing.T) {
 				result: reconcile.Result{RequeueAfter: shortWait},
 			},
 		},
+		"StartRemoteControllerFailed": {
+			reason: "The error should be returned if engine cannot start the controller of an additional remote cluster",
+			args: args{
+				m: &fake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+					},
+				},
+				opts: []ReconcilerOption{
+					WithLocalApplicator(resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+						return nil
+					})),
+					WithFinalizer(resource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ resource.Object) error {
+						return nil
+					}}),
+					WithCRDFetcher(FetchFn(func(_ context.Context, _ v1alpha1.CompositeResourceDefinition) (*apiextensions.CustomResourceDefinition, error) {
+						return &apiextensions.CustomResourceDefinition{
+							Status: apiextensions.CustomResourceDefinitionStatus{
+								Conditions: []apiextensions.CustomResourceDefinitionCondition{
+									{
+										Type:   apiextensions.Established,
+										Status: apiextensions.ConditionTrue,
+									},
+								},
+							},
+						}, nil
+					})),
+					WithRemotes(claim.Remote{Name: "west", Client: &test.MockClient{}}),
+					WithControllerEngine(&MockEngine{MockStart: func(name string, _ kcontroller.Options, _ ...controller.Watch) error {
+						if name != claim.RemoteControllerName(coreclaim.ControllerName(""), "west") {
+							return nil
+						}
+						return errBoom
+					}}),
+				},
+			},
+			want: want{
+				err:    errors.Wrap(errBoom, localPrefix+errStartController),
+				result: reconcile.Result{RequeueAfter: shortWait},
+			},
+		},
 		"Successful": {
 			reason: "No error should be returned if all calls go well",
 			args: args{
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conversion decides how the local copies of CRDs whose versions are
+// converted by a webhook in the remote cluster are converted, since the
+// webhook is not reachable from the local api-server as it's declared.
+package conversion
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// AnnotationKeyPolicy can be added to the CRDs, or to the XRDs that define
+// them, in the remote cluster to choose the Policy of a single CRD.
+const AnnotationKeyPolicy = resource.AnnotationKeyPrefix + "conversion-policy"
+
+// PathPrefix is the path under which the Proxy serves conversion requests. It's
+// followed by the name of the CRD.
+const PathPrefix = "/convert/"
+
+const (
+	errFmtUnknownPolicy = "unknown conversion policy %q"
+	errNoProxy          = "conversion cannot be proxied because the conversion proxy is not configured"
+	errNoService        = "conversion cannot be proxied to a webhook that is not a service in the remote cluster"
+	errReadReview       = "cannot read conversion review"
+	errProxyReview      = "cannot proxy conversion review to the remote cluster"
+)
+
+// A Policy determines how the local copy of a CRD that is converted by a
+// webhook in the remote cluster is converted.
+type Policy string
+
+// Conversion policies.
+const (
+	// PolicyNone strips the webhook so that the local api-server converts
+	// between versions by changing only the apiVersion. If the schemas of the
+	// versions differ, only the storage version is served so that nothing is
+	// converted.
+	PolicyNone Policy = "none"
+
+	// PolicyProxy routes the conversion to a Proxy run by the agent, which
+	// forwards it to the webhook in the remote cluster.
+	PolicyProxy Policy = "proxy"
+)
+
+// PolicyOf returns the Policy that's chosen in the annotations of the supplied
+// objects, the first one that has it winning, or the supplied default.
+func PolicyOf(def Policy, objs ...metav1.Object) Policy {
+	for _, o := range objs {
+		if p, ok := o.GetAnnotations()[AnnotationKeyPolicy]; ok {
+			return Policy(p)
+		}
+	}
+	return def
+}
+
+// A ProxyConfig tells how the local api-server reaches the Proxy.
+type ProxyConfig struct {
+	// Service the Proxy is exposed with in the local cluster.
+	Service v1beta1.ServiceReference
+
+	// CABundle that the serving certificate of the Proxy is signed with.
+	CABundle []byte
+}
+
+// NewConfigurator returns a new *Configurator. The Proxy and its configuration
+// may be nil if conversions are never proxied.
+func NewConfigurator(def Policy, p *Proxy, pc *ProxyConfig) *Configurator {
+	return &Configurator{def: def, proxy: p, config: pc}
+}
+
+// A Configurator configures the conversion of the local copy of a CRD.
+type Configurator struct {
+	def    Policy
+	proxy  *Proxy
+	config *ProxyConfig
+}
+
+// Configure the conversion of the supplied local copy of a CRD according to
+// the Policy chosen by the supplied objects, which are usually the CRD and the
+// XRD that defines it.
+func (c *Configurator) Configure(crd *v1beta1.CustomResourceDefinition, objs ...metav1.Object) error {
+	conv := crd.Spec.Conversion
+	if conv == nil || conv.Strategy != v1beta1.WebhookConverter || conv.WebhookClientConfig == nil {
+		return nil
+	}
+	switch p := PolicyOf(c.def, append(objs, crd)...); p {
+	case PolicyNone:
+		StripConversion(crd)
+		return nil
+	case PolicyProxy:
+		if c.proxy == nil || c.config == nil {
+			return errors.New(errNoProxy)
+		}
+		svc := conv.WebhookClientConfig.Service
+		if svc == nil {
+			return errors.New(errNoService)
+		}
+		c.proxy.Route(crd.GetName(), *svc)
+		path := PathPrefix + crd.GetName()
+		local := c.config.Service
+		local.Path = &path
+		conv.WebhookClientConfig = &v1beta1.WebhookClientConfig{Service: &local, CABundle: c.config.CABundle}
+		return nil
+	default:
+		return errors.Errorf(errFmtUnknownPolicy, p)
+	}
+}
+
+// StripConversion makes the supplied CRD convert between its versions without
+// a webhook. Only its storage version is served if its versions have different
+// schemas, since the local api-server cannot convert between them.
+func StripConversion(crd *v1beta1.CustomResourceDefinition) {
+	crd.Spec.Conversion = &v1beta1.CustomResourceConversion{Strategy: v1beta1.NoneConverter}
+	for i := range crd.Spec.Versions {
+		if !reflect.DeepEqual(crd.Spec.Versions[i].Schema, crd.Spec.Versions[0].Schema) {
+			for j := range crd.Spec.Versions {
+				crd.Spec.Versions[j].Served = crd.Spec.Versions[j].Storage
+			}
+			return
+		}
+	}
+}
+
+// NewProxy returns a new *Proxy that forwards conversion reviews to webhooks in
+// the remote cluster through the service proxy of its api-server.
+func NewProxy(remote rest.Interface, addr, certFile, keyFile string, log logging.Logger) *Proxy {
+	return &Proxy{remote: remote, addr: addr, certFile: certFile, keyFile: keyFile, log: log, routes: map[string]v1beta1.ServiceReference{}}
+}
+
+// A Proxy serves the conversion reviews of the local api-server.
+type Proxy struct {
+	remote            rest.Interface
+	addr              string
+	certFile, keyFile string
+	log               logging.Logger
+
+	mu     sync.RWMutex
+	routes map[string]v1beta1.ServiceReference
+}
+
+// Route the conversion reviews of the CRD with the supplied name to the
+// supplied webhook service in the remote cluster.
+func (p *Proxy) Route(crd string, svc v1beta1.ServiceReference) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes[crd] = svc
+}
+
+// ServeHTTP forwards the conversion review in the request to the webhook of its
+// CRD in the remote cluster and writes back its response.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p.mu.RLock()
+	svc, ok := p.routes[strings.TrimPrefix(req.URL.Path, PathPrefix)]
+	p.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, errors.Wrap(err, errReadReview).Error(), http.StatusBadRequest)
+		return
+	}
+	port := int32(443)
+	if svc.Port != nil {
+		port = *svc.Port
+	}
+	path := ""
+	if svc.Path != nil {
+		path = *svc.Path
+	}
+	abs := fmt.Sprintf("/api/v1/namespaces/%s/services/https:%s:%d/proxy%s", svc.Namespace, svc.Name, port, path)
+	out, err := p.remote.Post().AbsPath(abs).SetHeader("Content-Type", "application/json").Body(body).Do(req.Context()).Raw()
+	if err != nil {
+		p.log.Debug("Cannot proxy conversion review", "error", err, "service", svc.Namespace+"/"+svc.Name)
+		http.Error(w, errors.Wrap(err, errProxyReview).Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// Start serving until the supplied channel is closed.
+func (p *Proxy) Start(stop <-chan struct{}) error {
+	srv := &http.Server{Addr: p.addr, Handler: p}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+	if err := srv.ListenAndServeTLS(p.certFile, p.keyFile); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
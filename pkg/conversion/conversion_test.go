@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func webhookCRD(versions ...v1beta1.CustomResourceDefinitionVersion) *v1beta1.CustomResourceDefinition {
+	path := "/convert"
+	return &v1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "databases.example.org"},
+		Spec: v1beta1.CustomResourceDefinitionSpec{
+			Versions: versions,
+			Conversion: &v1beta1.CustomResourceConversion{
+				Strategy: v1beta1.WebhookConverter,
+				WebhookClientConfig: &v1beta1.WebhookClientConfig{
+					Service: &v1beta1.ServiceReference{Namespace: "crossplane-system", Name: "webhook", Path: &path},
+				},
+			},
+		},
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	path := PathPrefix + "databases.example.org"
+	differ := []v1beta1.CustomResourceDefinitionVersion{
+		{Name: "v1alpha1", Served: true, Schema: &v1beta1.CustomResourceValidation{OpenAPIV3Schema: &v1beta1.JSONSchemaProps{Type: "object"}}},
+		{Name: "v1beta1", Served: true, Storage: true},
+	}
+	type want struct {
+		crd *v1beta1.CustomResourceDefinition
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		c      *Configurator
+		crd    *v1beta1.CustomResourceDefinition
+		want   want
+	}{
+		"None": {
+			reason: "The webhook should be stripped and only the storage version served if the schemas of the versions differ",
+			c:      NewConfigurator(PolicyNone, nil, nil),
+			crd:    webhookCRD(differ...),
+			want: want{crd: &v1beta1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "databases.example.org"},
+				Spec: v1beta1.CustomResourceDefinitionSpec{
+					Versions: []v1beta1.CustomResourceDefinitionVersion{
+						{Name: "v1alpha1", Served: false, Schema: differ[0].Schema},
+						{Name: "v1beta1", Served: true, Storage: true},
+					},
+					Conversion: &v1beta1.CustomResourceConversion{Strategy: v1beta1.NoneConverter},
+				},
+			}},
+		},
+		"Proxy": {
+			reason: "The webhook should be routed to the local proxy",
+			c: NewConfigurator(PolicyProxy, NewProxy(nil, "", "", "", logging.NewNopLogger()), &ProxyConfig{
+				Service:  v1beta1.ServiceReference{Namespace: "agent", Name: "proxy"},
+				CABundle: []byte("ca"),
+			}),
+			crd: webhookCRD(),
+			want: want{crd: &v1beta1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "databases.example.org"},
+				Spec: v1beta1.CustomResourceDefinitionSpec{
+					Conversion: &v1beta1.CustomResourceConversion{
+						Strategy: v1beta1.WebhookConverter,
+						WebhookClientConfig: &v1beta1.WebhookClientConfig{
+							Service:  &v1beta1.ServiceReference{Namespace: "agent", Name: "proxy", Path: &path},
+							CABundle: []byte("ca"),
+						},
+					},
+				},
+			}},
+		},
+		"ProxyNotConfigured": {
+			reason: "An error should be returned if the CRD chooses to be proxied but there is no proxy",
+			c:      NewConfigurator(PolicyNone, nil, nil),
+			crd: func() *v1beta1.CustomResourceDefinition {
+				crd := webhookCRD()
+				crd.SetAnnotations(map[string]string{AnnotationKeyPolicy: string(PolicyProxy)})
+				return crd
+			}(),
+			want: want{
+				crd: func() *v1beta1.CustomResourceDefinition {
+					crd := webhookCRD()
+					crd.SetAnnotations(map[string]string{AnnotationKeyPolicy: string(PolicyProxy)})
+					return crd
+				}(),
+				err: errors.New(errNoProxy),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.c.Configure(tc.crd)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nConfigure(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.crd, tc.crd); diff != "" {
+				t.Errorf("\nReason: %s\nConfigure(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
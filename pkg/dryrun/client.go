@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dryrun reports the writes the agent would make to a cluster instead
+// of making them.
+package dryrun
+
+import (
+	"context"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// Metadata that every write changes, and that would only add noise to the
+// reported diffs.
+var volatileMetadata = []string{"resourceVersion", "managedFields", "generation", "uid", "creationTimestamp", "selfLink"}
+
+// NewClient returns a client.Client that reads through the supplied client but
+// only reports the writes it's asked to make. Every write is logged together
+// with the diff it would make to the supplied cluster, and is sent as a
+// server-side dry-run so that it's still validated, and so that the object
+// that's written to is updated as if the write was made.
+func NewClient(c client.Client, cluster string, log logging.Logger) client.Client {
+	return &Client{Client: c, log: log.WithValues("cluster", cluster, "dry-run", true)}
+}
+
+// NewClientFunc returns a manager.NewClientFunc that wraps the clients the
+// manager would create by default with a Client.
+func NewClientFunc(cluster string, log logging.Logger) manager.NewClientFunc {
+	return func(ca cache.Cache, cfg *rest.Config, o client.Options) (client.Client, error) {
+		c, err := manager.DefaultNewClient(ca, cfg, o)
+		if err != nil {
+			return nil, err
+		}
+		return NewClient(c, cluster, log), nil
+	}
+}
+
+// A Client reports writes rather than making them.
+type Client struct {
+	client.Client
+
+	log logging.Logger
+}
+
+// Create reports the object that would be created.
+func (c *Client) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...)
+	c.report("create", nil, obj, err)
+	return err
+}
+
+// Update reports the diff the update would make.
+func (c *Client) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	current := c.current(ctx, obj)
+	err := c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...)
+	c.report("update", current, obj, err)
+	return err
+}
+
+// Patch reports the diff the patch would make.
+func (c *Client) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	current := c.current(ctx, obj)
+	err := c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+	c.report("patch", current, obj, err)
+	return err
+}
+
+// Delete reports the object that would be deleted.
+func (c *Client) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...)
+	c.report("delete", obj, nil, err)
+	return err
+}
+
+// DeleteAllOf reports the kind of the objects that would be deleted.
+func (c *Client) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	err := c.Client.DeleteAllOf(ctx, obj, append(opts, client.DryRunAll)...)
+	c.log.Info("Would delete all matching objects", "kind", obj.GetObjectKind().GroupVersionKind().String(), "error", err)
+	return err
+}
+
+// Status returns a StatusWriter that reports the status writes it's asked to
+// make.
+func (c *Client) Status() client.StatusWriter {
+	return &StatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+// current returns the object the supplied one would replace, or nil if it
+// cannot be read.
+func (c *Client) current(ctx context.Context, obj runtime.Object) runtime.Object {
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return nil
+	}
+	current := obj.DeepCopyObject()
+	if err := c.Client.Get(ctx, key, current); err != nil {
+		return nil
+	}
+	return current
+}
+
+func (c *Client) report(verb string, from, to runtime.Object, err error) {
+	o := to
+	if o == nil {
+		o = from
+	}
+	key, _ := client.ObjectKeyFromObject(o)
+	log := c.log.WithValues("verb", verb, "kind", o.GetObjectKind().GroupVersionKind().String(), "object", key.String())
+	if err != nil {
+		log.Info("Would fail to write object", "error", err)
+		return
+	}
+	diff := Diff(from, to)
+	if diff == "" {
+		log.Debug("Would write object without changing it")
+		return
+	}
+	log.Info("Would write object", "diff", diff)
+}
+
+// Diff returns the diff between the supplied objects, leaving out the metadata
+// that changes with every write. The data of Secrets is redacted, so only the
+// keys that would be added or removed are reported. Either of them may be nil.
+func Diff(from, to runtime.Object) string {
+	return cmp.Diff(withoutVolatile(resource.RedactObject(from)), withoutVolatile(resource.RedactObject(to)))
+}
+
+func withoutVolatile(o runtime.Object) map[string]interface{} {
+	if o == nil {
+		return nil
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil
+	}
+	if m, ok := u["metadata"].(map[string]interface{}); ok {
+		for _, k := range volatileMetadata {
+			delete(m, k)
+		}
+	}
+	return u
+}
+
+// A StatusWriter reports status writes rather than making them.
+type StatusWriter struct {
+	client.StatusWriter
+
+	client *Client
+}
+
+// Update reports the diff the update of the status would make.
+func (w *StatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	current := w.client.current(ctx, obj)
+	err := w.StatusWriter.Update(ctx, obj, append(opts, client.DryRunAll)...)
+	w.client.report("update status", current, obj, err)
+	return err
+}
+
+// Patch reports the diff the patch of the status would make.
+func (w *StatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	current := w.client.current(ctx, obj)
+	err := w.StatusWriter.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+	w.client.report("patch status", current, obj, err)
+	return err
+}
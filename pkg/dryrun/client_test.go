@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestClient(t *testing.T) {
+	dryRun := []string{metav1.DryRunAll}
+	var got []string
+	record := func(verb string, opts []string) {
+		if cmp.Equal(opts, dryRun) {
+			got = append(got, verb)
+		}
+	}
+
+	c := NewClient(&test.MockClient{
+		MockGet: test.NewMockGetFn(nil),
+		MockCreate: func(_ context.Context, _ runtime.Object, opts ...client.CreateOption) error {
+			record("create", (&client.CreateOptions{}).ApplyOptions(opts).DryRun)
+			return nil
+		},
+		MockUpdate: func(_ context.Context, _ runtime.Object, opts ...client.UpdateOption) error {
+			record("update", (&client.UpdateOptions{}).ApplyOptions(opts).DryRun)
+			return nil
+		},
+		MockPatch: func(_ context.Context, _ runtime.Object, _ client.Patch, opts ...client.PatchOption) error {
+			record("patch", (&client.PatchOptions{}).ApplyOptions(opts).DryRun)
+			return nil
+		},
+		MockDelete: func(_ context.Context, _ runtime.Object, opts ...client.DeleteOption) error {
+			record("delete", (&client.DeleteOptions{}).ApplyOptions(opts).DryRun)
+			return nil
+		},
+		MockStatusUpdate: func(_ context.Context, _ runtime.Object, opts ...client.UpdateOption) error {
+			record("update status", (&client.UpdateOptions{}).ApplyOptions(opts).DryRun)
+			return nil
+		},
+	}, "remote", logging.NewNopLogger())
+
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm"}}
+	_ = c.Create(ctx, cm)
+	_ = c.Update(ctx, cm)
+	_ = c.Patch(ctx, cm, client.MergeFrom(cm))
+	_ = c.Delete(ctx, cm)
+	_ = c.Status().Update(ctx, cm)
+
+	want := []string{"create", "update", "patch", "delete", "update status"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nReason: %s\nDry-run writes: -want, +got:\n%s", "Every write should be sent as a server-side dry-run", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type args struct {
+		from runtime.Object
+		to   runtime.Object
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   bool
+	}{
+		"VolatileMetadataOnly": {
+			reason: "Metadata that changes with every write should not be reported",
+			args: args{
+				from: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", ResourceVersion: "1", Generation: 1}, Data: map[string]string{"k": "v"}},
+				to:   &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", ResourceVersion: "2", Generation: 2}, Data: map[string]string{"k": "v"}},
+			},
+			want: false,
+		},
+		"DataChanged": {
+			reason: "Changes to the content of an object should be reported",
+			args: args{
+				from: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}, Data: map[string]string{"k": "v"}},
+				to:   &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}, Data: map[string]string{"k": "w"}},
+			},
+			want: true,
+		},
+		"SecretDataChanged": {
+			reason: "Changes to the data of a Secret should not be reported, since its values are redacted",
+			args: args{
+				from: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s"}, Data: map[string][]byte{"k": []byte("v")}},
+				to:   &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s"}, Data: map[string][]byte{"k": []byte("w")}},
+			},
+			want: false,
+		},
+		"Created": {
+			reason: "Objects that would be created should be reported in full",
+			args: args{
+				to: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}},
+			},
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Diff(tc.args.from, tc.args.to) != ""
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nDiff(...) != \"\": -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDiffSecret(t *testing.T) {
+	secretValue := "cool-password"
+	from := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s"}, Data: map[string][]byte{"password": []byte(secretValue)}}
+	to := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s"}, StringData: map[string]string{"token": secretValue}}
+	got := Diff(nil, from) + Diff(from, to)
+	for _, v := range []string{secretValue, base64.StdEncoding.EncodeToString([]byte(secretValue))} {
+		if strings.Contains(got, v) {
+			t.Errorf("\nReason: %s\nDiff(...): secret data found in diff:\n%s", "The data of Secrets should never be reported", got)
+		}
+	}
+	if !strings.Contains(got, "token") {
+		t.Errorf("\nReason: %s\nDiff(...): key missing from diff:\n%s", "The keys of Secrets that would be added should be reported", got)
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package envelope encrypts the data of Secrets that are propagated across
+// clusters with a data key of their own, which is in turn encrypted by a
+// KeyWrapper like a KMS or an age key.
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"os/exec"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	errNewCipher     = "cannot create cipher"
+	errGenerateKey   = "cannot generate data key"
+	errGenerateNonce = "cannot generate nonce"
+	errWrapKey       = "cannot wrap data key"
+	errUnwrapKey     = "cannot unwrap data key"
+	errDecodeKey     = "cannot decode data key"
+	errFmtDecrypt    = "cannot decrypt value of key %s"
+	errShortValue    = "encrypted value is too short"
+	errNoCommand     = "no command is configured"
+	errFmtRunCommand = "cannot run %s: %s: %s"
+	errNotSealed     = "secret is not sealed"
+)
+
+// dataKeySize is the size of the AES-256 data keys.
+const dataKeySize = 32
+
+// A KeyWrapper encrypts and decrypts the data keys of Secrets.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// NewAESKeyWrapper returns a KeyWrapper that encrypts data keys with the
+// supplied AES key using GCM. The key has to be 16, 24 or 32 bytes long.
+func NewAESKeyWrapper(key []byte) (*AESKeyWrapper, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESKeyWrapper{aead: aead}, nil
+}
+
+// An AESKeyWrapper encrypts data keys with a static key that's shared by the
+// sealing and the consuming side.
+type AESKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// WrapKey encrypts the supplied data key.
+func (w *AESKeyWrapper) WrapKey(_ context.Context, key []byte) ([]byte, error) {
+	return seal(w.aead, key, nil)
+}
+
+// UnwrapKey decrypts the supplied data key.
+func (w *AESKeyWrapper) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	return open(w.aead, wrapped, nil)
+}
+
+// A CommandKeyWrapper encrypts and decrypts data keys by running a command
+// that reads the key from its stdin and writes the result to its stdout, like
+// age with a recipient and an identity, or the CLI of a KMS.
+type CommandKeyWrapper struct {
+	// Wrap is the command and arguments that encrypt a data key.
+	Wrap []string
+
+	// Unwrap is the command and arguments that decrypt a data key.
+	Unwrap []string
+}
+
+// WrapKey runs the Wrap command.
+func (w *CommandKeyWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	return run(ctx, w.Wrap, key)
+}
+
+// UnwrapKey runs the Unwrap command.
+func (w *CommandKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return run(ctx, w.Unwrap, wrapped)
+}
+
+func run(ctx context.Context, command []string, in []byte) ([]byte, error) {
+	if len(command) == 0 {
+		return nil, errors.New(errNoCommand)
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...) // nolint:gosec
+	cmd.Stdin = bytes.NewReader(in)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Errorf(errFmtRunCommand, command[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+// An Option configures an Envelope.
+type Option func(*Envelope)
+
+// WithRequireSealed makes the Envelope fail to open Secrets that were not
+// sealed, rather than leave them as they are.
+func WithRequireSealed() Option {
+	return func(e *Envelope) {
+		e.requireSealed = true
+	}
+}
+
+// New returns a new *Envelope.
+func New(w KeyWrapper, opts ...Option) *Envelope {
+	e := &Envelope{wrapper: w}
+	// The digests of Secrets are keyed so that they don't give away what
+	// guessable values are. A key that's lost when the agent restarts only
+	// means that every Secret is sealed once more.
+	e.digestKey = make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, e.digestKey); err != nil {
+		e.digestKey = nil
+	}
+	for _, f := range opts {
+		f(e)
+	}
+	return e
+}
+
+// An Envelope seals the data of Secrets before they're propagated to another
+// cluster and opens them once they're received. Every Secret is encrypted
+// with a data key of its own using AES-256-GCM, and the data key is kept in
+// the AnnotationKeyEnvelopeKey annotation of the Secret once it's wrapped.
+type Envelope struct {
+	wrapper       KeyWrapper
+	digestKey     []byte
+	requireSealed bool
+}
+
+// Seal encrypts the data of the supplied Secret in place. The supplied current
+// Secret is the one that was last sealed for it, if any. Its data is reused
+// if the data of the supplied Secret didn't change since, so that a Secret is
+// only sealed, and its data key only wrapped, when it changes.
+func (e *Envelope) Seal(ctx context.Context, s, current *corev1.Secret) error {
+	digest := e.digest(s.Data)
+	if current != nil && digest != "" {
+		a := current.GetAnnotations()
+		if a[resource.AnnotationKeyEnvelopeKey] != "" && hmac.Equal([]byte(a[resource.AnnotationKeyEnvelopeDigest]), []byte(digest)) {
+			s.Data = make(map[string][]byte, len(current.Data))
+			for k, v := range current.Data {
+				s.Data[k] = v
+			}
+			meta.AddAnnotations(s, map[string]string{
+				resource.AnnotationKeyEnvelopeKey:    a[resource.AnnotationKeyEnvelopeKey],
+				resource.AnnotationKeyEnvelopeDigest: digest,
+			})
+			return nil
+		}
+	}
+
+	key := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return errors.Wrap(err, errGenerateKey)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Data {
+		// The name of the key is authenticated so that values can't be
+		// swapped between keys.
+		if s.Data[k], err = seal(aead, v, []byte(k)); err != nil {
+			return err
+		}
+	}
+	wrapped, err := e.wrapper.WrapKey(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, errWrapKey)
+	}
+	a := map[string]string{resource.AnnotationKeyEnvelopeKey: base64.StdEncoding.EncodeToString(wrapped)}
+	if digest != "" {
+		a[resource.AnnotationKeyEnvelopeDigest] = digest
+	}
+	meta.AddAnnotations(s, a)
+	return nil
+}
+
+// Open decrypts the data of the supplied Secret in place and removes its
+// envelope key. Secrets that were not sealed are left as they are, unless the
+// Envelope requires them to be sealed.
+func (e *Envelope) Open(ctx context.Context, s *corev1.Secret) error {
+	v, ok := s.GetAnnotations()[resource.AnnotationKeyEnvelopeKey]
+	if !ok {
+		if e.requireSealed {
+			return errors.New(errNotSealed)
+		}
+		return nil
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return errors.Wrap(err, errDecodeKey)
+	}
+	key, err := e.wrapper.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return errors.Wrap(err, errUnwrapKey)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Data {
+		if s.Data[k], err = open(aead, v, []byte(k)); err != nil {
+			return errors.Wrapf(err, errFmtDecrypt, k)
+		}
+	}
+	a := s.GetAnnotations()
+	delete(a, resource.AnnotationKeyEnvelopeKey)
+	delete(a, resource.AnnotationKeyEnvelopeDigest)
+	s.SetAnnotations(a)
+	return nil
+}
+
+// digest returns the keyed digest of the supplied plaintext data, or an empty
+// string if the Envelope has no key to digest it with.
+func (e *Envelope) digest(data map[string][]byte) string {
+	if len(e.digestKey) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := hmac.New(sha256.New, e.digestKey)
+	l := make([]byte, 8)
+	for _, k := range keys {
+		// Lengths are written ahead of keys and values so that no two
+		// different Secrets are written the same.
+		for _, b := range [][]byte{[]byte(k), data[k]} {
+			binary.BigEndian.PutUint64(l, uint64(len(b)))
+			_, _ = h.Write(l)
+			_, _ = h.Write(b)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewCipher)
+	}
+	aead, err := cipher.NewGCM(b)
+	return aead, errors.Wrap(err, errNewCipher)
+}
+
+// seal returns the nonce followed by the ciphertext.
+func seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, errGenerateNonce)
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func open(aead cipher.AEAD, in, ad []byte) ([]byte, error) {
+	if len(in) < aead.NonceSize() {
+		return nil, errors.New(errShortValue)
+	}
+	return aead.Open(nil, in[:aead.NonceSize()], in[aead.NonceSize():], ad)
+}
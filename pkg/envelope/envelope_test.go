@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestEnvelope(t *testing.T) {
+	w, err := NewAESKeyWrapper([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewAESKeyWrapper(...): %s", err)
+	}
+	e := New(w)
+	secret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cool-secret", Annotations: map[string]string{"cool": "annotation"}},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("hunter2")},
+		}
+	}
+
+	cases := map[string]struct {
+		reason  string
+		tamper  func(s *corev1.Secret)
+		want    *corev1.Secret
+		wantErr bool
+	}{
+		"RoundTrip": {
+			reason: "A sealed Secret should be opened to its original data and annotations",
+			tamper: func(_ *corev1.Secret) {},
+			want:   secret(),
+		},
+		"Swapped": {
+			reason: "Values that are swapped between keys should not be decrypted",
+			tamper: func(s *corev1.Secret) {
+				s.Data["username"], s.Data["password"] = s.Data["password"], s.Data["username"]
+			},
+			wantErr: true,
+		},
+		"NotSealed": {
+			reason: "A Secret that was not sealed should be left as it is",
+			tamper: func(s *corev1.Secret) { *s = *secret() },
+			want:   secret(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := secret()
+			if err := e.Seal(context.Background(), s, nil); err != nil {
+				t.Fatalf("Seal(...): %s", err)
+			}
+			if _, ok := s.GetAnnotations()[resource.AnnotationKeyEnvelopeKey]; !ok {
+				t.Fatalf("Seal(...): the envelope key annotation should be added")
+			}
+			if cmp.Equal(secret().Data, s.Data) {
+				t.Fatalf("Seal(...): the data should be encrypted")
+			}
+			tc.tamper(s)
+			err := e.Open(context.Background(), s)
+			if diff := cmp.Diff(tc.wantErr, err != nil); diff != "" {
+				t.Fatalf("\nReason: %s\nOpen(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, s); diff != "" {
+				t.Errorf("\nReason: %s\nOpen(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEnvelopeSealUnchanged(t *testing.T) {
+	w, err := NewAESKeyWrapper([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewAESKeyWrapper(...): %s", err)
+	}
+	e := New(w)
+	secret := func(password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cool-secret"},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte(password)},
+		}
+	}
+	current := secret("hunter2")
+	if err := e.Seal(context.Background(), current, nil); err != nil {
+		t.Fatalf("Seal(...): %s", err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      *corev1.Secret
+		want   bool
+	}{
+		"Unchanged": {
+			reason: "A Secret whose data didn't change since it was last sealed should not be sealed again",
+			s:      secret("hunter2"),
+			want:   true,
+		},
+		"Changed": {
+			reason: "A Secret whose data changed since it was last sealed should be sealed again",
+			s:      secret("hunter3"),
+			want:   false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := e.Seal(context.Background(), tc.s, current); err != nil {
+				t.Fatalf("Seal(...): %s", err)
+			}
+			if diff := cmp.Diff(tc.want, cmp.Equal(current, tc.s)); diff != "" {
+				t.Errorf("\nReason: %s\nSeal(...): -want reused, +got reused:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEnvelopeRequireSealed(t *testing.T) {
+	w, err := NewAESKeyWrapper([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewAESKeyWrapper(...): %s", err)
+	}
+	s := &corev1.Secret{Data: map[string][]byte{"password": []byte("hunter2")}}
+	if err := New(w, WithRequireSealed()).Open(context.Background(), s); err == nil {
+		t.Errorf("\nReason: %s\nOpen(...): want error, got nil", "A Secret that was not sealed should not be opened if sealed Secrets are required")
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fault injects latency, errors and conflicts into the requests made
+// to api-servers so that the resilience of the reconcilers can be tested in
+// end-to-end suites. It's not meant to be used in production.
+package fault
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+const (
+	errFmtParseEntry = "cannot parse fault %q, it should be in key=value form"
+	errFmtUnknownKey = "unknown fault %q"
+	errFmtParseValue = "cannot parse value of fault %q"
+	errFmtRate       = "rate of fault %q should be between 0 and 1"
+
+	msgInjected = "fault injected by crossplane-agent"
+)
+
+// Keys of a Spec in its string form.
+const (
+	keyLatency   = "latency"
+	keyErrors    = "errors"
+	keyConflicts = "conflicts"
+)
+
+// A Spec is the faults that are injected into requests.
+type Spec struct {
+	// Latency is added to every request.
+	Latency time.Duration
+
+	// ErrorRate is the fraction of requests that fail with an internal
+	// error without reaching the api-server.
+	ErrorRate float64
+
+	// ConflictRate is the fraction of the requests that write objects that
+	// fail with a conflict without reaching the api-server.
+	ConflictRate float64
+}
+
+// ParseSpec parses a Spec in the form of comma-separated key=value pairs,
+// e.g. "latency=200ms,errors=0.1,conflicts=0.05".
+func ParseSpec(s string) (Spec, error) {
+	spec := Spec{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			return Spec{}, errors.Errorf(errFmtParseEntry, entry)
+		}
+		k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		var err error
+		switch k {
+		case keyLatency:
+			spec.Latency, err = time.ParseDuration(v)
+		case keyErrors:
+			spec.ErrorRate, err = parseRate(k, v)
+		case keyConflicts:
+			spec.ConflictRate, err = parseRate(k, v)
+		default:
+			return Spec{}, errors.Errorf(errFmtUnknownKey, k)
+		}
+		if err != nil {
+			return Spec{}, errors.Wrapf(err, errFmtParseValue, k)
+		}
+	}
+	return spec, nil
+}
+
+func parseRate(k, v string) (float64, error) {
+	r, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if r < 0 || r > 1 {
+		return 0, errors.Errorf(errFmtRate, k)
+	}
+	return r, nil
+}
+
+// Empty returns true if no faults are injected.
+func (s Spec) Empty() bool {
+	return s == Spec{}
+}
+
+// NewTransportWrapper returns a function that wraps a http.RoundTripper so
+// that the faults of the supplied Spec are injected into its requests. It can
+// be used to wrap the transport of a rest.Config.
+func NewTransportWrapper(s Spec, log logging.Logger) func(http.RoundTripper) http.RoundTripper {
+	rnd := &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))} // nolint:gosec
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &transport{wrapped: rt, spec: s, rand: rnd, log: log}
+	}
+}
+
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+type transport struct {
+	wrapped http.RoundTripper
+	spec    Spec
+	rand    interface{ Float64() float64 }
+	log     logging.Logger
+}
+
+// RoundTrip injects the faults and executes the request unless it's failed.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.spec.Latency > 0 {
+		select {
+		case <-time.After(t.spec.Latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if t.spec.ErrorRate > 0 && t.rand.Float64() < t.spec.ErrorRate {
+		t.log.Debug("Injecting error", "host", req.URL.Host, "method", req.Method, "path", req.URL.Path)
+		return response(req, kerrors.NewInternalError(errors.New(msgInjected)))
+	}
+	if t.spec.ConflictRate > 0 && isWrite(req.Method) && t.rand.Float64() < t.spec.ConflictRate {
+		t.log.Debug("Injecting conflict", "host", req.URL.Host, "method", req.Method, "path", req.URL.Path)
+		return response(req, kerrors.NewConflict(schema.GroupResource{}, "", errors.New(msgInjected)))
+	}
+	return t.wrapped.RoundTrip(req)
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// response returns the response the api-server would return with the supplied
+// error.
+func response(req *http.Request, err *kerrors.StatusError) (*http.Response, error) {
+	status := err.ErrStatus
+	status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	b, jerr := json.Marshal(status)
+	if jerr != nil {
+		return nil, jerr
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(int(status.Code)) + " " + http.StatusText(int(status.Code)),
+		StatusCode:    int(status.Code),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestParseSpec(t *testing.T) {
+	type want struct {
+		spec Spec
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   want
+	}{
+		"Empty": {
+			reason: "No faults should be injected if none are given",
+		},
+		"All": {
+			reason: "All faults should be parsed",
+			s:      "latency=200ms, errors=0.1,conflicts=0.05",
+			want:   want{spec: Spec{Latency: 200 * time.Millisecond, ErrorRate: 0.1, ConflictRate: 0.05}},
+		},
+		"Unknown": {
+			reason: "An error should be returned for an unknown fault",
+			s:      "timeouts=0.1",
+			want:   want{err: errors.Errorf(errFmtUnknownKey, "timeouts")},
+		},
+		"OutOfRange": {
+			reason: "An error should be returned for a rate that is not a fraction",
+			s:      "errors=10",
+			want:   want{err: errors.Wrapf(errors.Errorf(errFmtRate, "errors"), errFmtParseValue, "errors")},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseSpec(tc.s)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nParseSpec(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.spec, got); diff != "" {
+				t.Errorf("\nReason: %s\nParseSpec(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cases := map[string]struct {
+		reason string
+		spec   Spec
+		method string
+		want   int
+	}{
+		"None": {
+			reason: "Requests should reach the api-server if no faults are injected",
+			method: http.MethodGet,
+			want:   http.StatusOK,
+		},
+		"Error": {
+			reason: "Requests should fail with an internal error at the error rate",
+			spec:   Spec{ErrorRate: 1},
+			method: http.MethodGet,
+			want:   http.StatusInternalServerError,
+		},
+		"ConflictOnRead": {
+			reason: "Conflicts should not be injected into reads",
+			spec:   Spec{ConflictRate: 1},
+			method: http.MethodGet,
+			want:   http.StatusOK,
+		},
+		"ConflictOnWrite": {
+			reason: "Writes should fail with a conflict at the conflict rate",
+			spec:   Spec{ConflictRate: 1},
+			method: http.MethodPut,
+			want:   http.StatusConflict,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &http.Client{Transport: NewTransportWrapper(tc.spec, logging.NewNopLogger())(http.DefaultTransport)}
+			req, _ := http.NewRequest(tc.method, srv.URL, nil)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("Do(...): %s", err)
+			}
+			resp.Body.Close()
+			if diff := cmp.Diff(tc.want, resp.StatusCode); diff != "" {
+				t.Errorf("\nReason: %s\nRoundTrip(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
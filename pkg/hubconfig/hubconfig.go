@@ -0,0 +1,241 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hubconfig reads the settings of agents that platform teams manage
+// centrally from ConfigMaps in the remote cluster, so that a fleet of agents
+// can be reconfigured without redeploying each of them.
+//
+// Settings are read from the ConfigMap that all agents share and from the one
+// named after the cluster of each agent, whose values take precedence. The
+// settings that are given to an agent locally take precedence over both. The
+// effective configuration is reported in a ConfigMap in the local cluster.
+package hubconfig
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap in the remote cluster that
+	// holds the settings of all agents.
+	ConfigMapName = "crossplane-agent-config"
+
+	// ClusterConfigMapPrefix is the prefix of the name of the ConfigMap in the
+	// remote cluster that holds the settings of the agent of a single cluster.
+	// It's followed by the name of the cluster.
+	ClusterConfigMapPrefix = ConfigMapName + "-"
+
+	// EffectiveConfigMapName is the name of the ConfigMap in the local cluster
+	// the effective configuration of the agent is reported in.
+	EffectiveConfigMapName = "crossplane-agent-effective-config"
+
+	// KeySourceSuffix is appended to the key of a setting in the effective
+	// configuration to report where its value comes from.
+	KeySourceSuffix = ".source"
+
+	keyUpdated = "updated"
+
+	errGetConfigMap = "cannot get hub configuration"
+	errPublish      = "cannot publish effective configuration"
+	errFmtChanged   = "hub configuration changed, restarting to apply it: %s"
+)
+
+// A Source of the value of a setting.
+type Source string
+
+// Sources of the values of settings.
+const (
+	SourceDefault Source = "default"
+	SourceLocal   Source = "local"
+	SourceHub     Source = "hub"
+)
+
+// ClusterConfigMapName returns the name of the ConfigMap that holds the
+// settings of the agent of the supplied cluster.
+func ClusterConfigMapName(cluster string) string {
+	return ClusterConfigMapPrefix + cluster
+}
+
+// Fetch returns the settings of the agent of the supplied cluster in the
+// supplied namespace of the remote cluster. Settings of the cluster override
+// the ones of all agents. Either ConfigMap may not exist, and only the one of
+// all agents is read if the cluster has no name.
+func Fetch(ctx context.Context, r client.Reader, namespace, cluster string) (map[string]string, error) {
+	names := []string{ConfigMapName}
+	if cluster != "" {
+		names = append(names, ClusterConfigMapName(cluster))
+	}
+	settings := map[string]string{}
+	for _, name := range names {
+		cm := &corev1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm)
+		if runtimeresource.IgnoreNotFound(err) != nil {
+			return nil, errors.Wrap(err, errGetConfigMap)
+		}
+		for k, v := range cm.Data {
+			settings[k] = v
+		}
+	}
+	return settings, nil
+}
+
+// A Setting is the effective value of a setting and where it comes from.
+type Setting struct {
+	Value  string
+	Source Source
+}
+
+// Effective is the effective configuration of an agent by the names of its
+// settings.
+type Effective map[string]Setting
+
+// Resolve returns the effective configuration of the supplied settings, given
+// their current values and whether they were set locally. Settings that were
+// set locally keep their values; the rest take the ones from the hub, if any.
+// The sorted names of the settings from the hub that are not known are
+// returned too.
+func Resolve(current map[string]string, local map[string]bool, hub map[string]string) (Effective, []string) {
+	e := make(Effective, len(current))
+	for name, v := range current {
+		s := Setting{Value: v, Source: SourceDefault}
+		hv, ok := hub[name]
+		switch {
+		case local[name]:
+			s.Source = SourceLocal
+		case ok:
+			s = Setting{Value: hv, Source: SourceHub}
+		}
+		e[name] = s
+	}
+	var unknown []string
+	for name := range hub {
+		if _, ok := current[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return e, unknown
+}
+
+// Changed returns the sorted names of the settings whose values in the
+// supplied settings of the hub are not the effective ones. Settings that were
+// set locally never change.
+func (e Effective) Changed(hub map[string]string) []string {
+	var changed []string
+	for name, s := range e {
+		hv, ok := hub[name]
+		switch {
+		case s.Source == SourceLocal:
+		case s.Source == SourceHub && (!ok || hv != s.Value), s.Source == SourceDefault && ok:
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Data returns the effective configuration as the data of a ConfigMap. The
+// source of each setting is reported under its name with KeySourceSuffix.
+func (e Effective) Data() map[string]string {
+	data := make(map[string]string, 2*len(e))
+	for name, s := range e {
+		data[name] = s.Value
+		data[name+KeySourceSuffix] = string(s.Source)
+	}
+	return data
+}
+
+// NewWatcher returns a new *Watcher of the settings of the agent of the
+// supplied cluster in the supplied namespace of the remote cluster. The
+// effective configuration is published to the supplied ConfigMap of the local
+// cluster.
+func NewWatcher(remote client.Reader, local client.Client, namespace, cluster string, e Effective, nn types.NamespacedName, period time.Duration, log logging.Logger) *Watcher {
+	return &Watcher{
+		remote:    remote,
+		client:    runtimeresource.NewAPIPatchingApplicator(local),
+		namespace: namespace,
+		cluster:   cluster,
+		effective: e,
+		name:      nn,
+		period:    period,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// A Watcher periodically publishes the effective configuration of the agent
+// and checks whether its settings changed in the hub. Settings are applied
+// only when the agent starts, so the Watcher stops with an error, which stops
+// the agent so that it's restarted, once they do.
+type Watcher struct {
+	remote    client.Reader
+	client    runtimeresource.Applicator
+	namespace string
+	cluster   string
+	effective Effective
+	name      types.NamespacedName
+	period    time.Duration
+	log       logging.Logger
+	now       func() time.Time
+}
+
+// Start watching until the supplied channel is closed or the settings change.
+func (w *Watcher) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(w.period)
+	defer t.Stop()
+	for {
+		changed, err := w.Watch(context.Background())
+		if err != nil {
+			w.log.Debug("Cannot watch hub configuration", "error", err)
+		}
+		if len(changed) > 0 {
+			w.log.Info("Hub configuration changed", "settings", changed)
+			return errors.Errorf(errFmtChanged, strings.Join(changed, ", "))
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Watch publishes the effective configuration and returns the names of the
+// settings that changed in the hub since the agent started.
+func (w *Watcher) Watch(ctx context.Context) ([]string, error) {
+	data := w.effective.Data()
+	data[keyUpdated] = w.now().UTC().Format(time.RFC3339)
+	published := errors.Wrap(resource.PublishConfigMap(ctx, w.client, w.name, data), errPublish)
+
+	hub, err := Fetch(ctx, w.remote, w.namespace, w.cluster)
+	if err != nil {
+		return nil, err
+	}
+	return w.effective.Changed(hub), published
+}
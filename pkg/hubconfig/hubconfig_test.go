@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hubconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestFetch(t *testing.T) {
+	errBoom := errors.New("boom")
+	configMaps := func(cms map[string]map[string]string) test.MockGetFn {
+		return func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+			data, ok := cms[key.Name]
+			if !ok {
+				return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+			}
+			obj.(*corev1.ConfigMap).Data = data
+			return nil
+		}
+	}
+
+	type want struct {
+		settings map[string]string
+		err      error
+	}
+	cases := map[string]struct {
+		reason  string
+		get     test.MockGetFn
+		cluster string
+		want    want
+	}{
+		"NotFound": {
+			reason: "No settings should be returned if there are no ConfigMaps",
+			get:    configMaps(nil),
+			want:   want{settings: map[string]string{}},
+		},
+		"GetFailed": {
+			reason: "Errors getting a ConfigMap should be returned",
+			get:    test.NewMockGetFn(errBoom),
+			want:   want{err: errors.Wrap(errBoom, errGetConfigMap)},
+		},
+		"ClusterOverrides": {
+			reason:  "Settings of the cluster should override the ones of all agents",
+			cluster: "cool",
+			get: configMaps(map[string]map[string]string{
+				ConfigMapName:                {"sync-interval": "1m", "watch-remote": "true"},
+				ClusterConfigMapName("cool"): {"sync-interval": "5m"},
+			}),
+			want: want{settings: map[string]string{"sync-interval": "5m", "watch-remote": "true"}},
+		},
+		"NoClusterName": {
+			reason: "Only the settings of all agents should be read if the cluster has no name",
+			get: configMaps(map[string]map[string]string{
+				ConfigMapName:            {"sync-interval": "1m"},
+				ClusterConfigMapName(""): {"sync-interval": "5m"},
+			}),
+			want: want{settings: map[string]string{"sync-interval": "1m"}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Fetch(context.Background(), &test.MockClient{MockGet: tc.get}, "crossplane-system", tc.cluster)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nFetch(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.settings, got); diff != "" {
+				t.Errorf("\nReason: %s\nFetch(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	current := map[string]string{"sync-interval": "1m", "claim-selector": "team=a", "watch-remote": "false"}
+	local := map[string]bool{"claim-selector": true}
+	hub := map[string]string{"sync-interval": "5m", "claim-selector": "team=b", "cool-setting": "true"}
+
+	e, unknown := Resolve(current, local, hub)
+	want := Effective{
+		"sync-interval":  {Value: "5m", Source: SourceHub},
+		"claim-selector": {Value: "team=a", Source: SourceLocal},
+		"watch-remote":   {Value: "false", Source: SourceDefault},
+	}
+	if diff := cmp.Diff(want, e); diff != "" {
+		t.Errorf("\nReason: %s\nResolve(...): -want, +got:\n%s", "Settings set locally should take precedence over the ones in the hub", diff)
+	}
+	if diff := cmp.Diff([]string{"cool-setting"}, unknown); diff != "" {
+		t.Errorf("\nReason: %s\nResolve(...): -want unknown, +got unknown:\n%s", "Settings in the hub that are not known should be returned", diff)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	e := Effective{
+		"sync-interval":  {Value: "5m", Source: SourceHub},
+		"claim-selector": {Value: "team=a", Source: SourceLocal},
+		"watch-remote":   {Value: "false", Source: SourceDefault},
+	}
+	cases := map[string]struct {
+		reason string
+		hub    map[string]string
+		want   []string
+	}{
+		"Unchanged": {
+			reason: "Nothing should change if the hub has the settings that were applied",
+			hub:    map[string]string{"sync-interval": "5m"},
+		},
+		"LocalIgnored": {
+			reason: "Settings that were set locally should never change",
+			hub:    map[string]string{"sync-interval": "5m", "claim-selector": "team=b"},
+		},
+		"Changed": {
+			reason: "Settings that were changed, added or removed in the hub should be returned",
+			hub:    map[string]string{"watch-remote": "true"},
+			want:   []string{"sync-interval", "watch-remote"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, e.Changed(tc.hub)); diff != "" {
+				t.Errorf("\nReason: %s\ne.Changed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package idle reports claims that have been ready but untouched for a while so
+// that the resources they represent can be considered for cleanup.
+package idle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the report is published to.
+	ConfigMapName = "crossplane-agent-idle-claims"
+
+	keyIdle    = "idle"
+	keyCount   = "count"
+	keyUpdated = "updated"
+
+	errPublish = "cannot publish idle claim report"
+)
+
+// A Claim that has been idle.
+type Claim struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	// Since is the last time the claim showed a sign of being used.
+	Since time.Time
+}
+
+// String returns the claim in the form it's reported.
+func (c Claim) String() string {
+	return fmt.Sprintf("%s %s/%s since %s", c.Kind, c.Namespace, c.Name, c.Since.UTC().Format(time.RFC3339))
+}
+
+type entry struct {
+	Claim
+	seen time.Time
+}
+
+// LastActivity returns the last time the supplied claim showed a sign of being
+// used, and false if it's not ready. Whether its connection secret is read is
+// unknowable, so the last transition of its Ready condition and the touch
+// annotation, which consumers can update, are the only signals.
+func LastActivity(c *claim.Unstructured) (time.Time, bool) {
+	ready := c.GetCondition(v1alpha1.TypeReady)
+	if ready.Status != corev1.ConditionTrue {
+		return time.Time{}, false
+	}
+	last := ready.LastTransitionTime.Time
+	if t, err := time.Parse(time.RFC3339, c.GetAnnotations()[resource.AnnotationKeyLastTouched]); err == nil && t.After(last) {
+		last = t
+	}
+	return last, true
+}
+
+// NewTracker returns a new *Tracker that reports claims that were idle for
+// longer than the given threshold. Claims it hasn't been told about within the
+// given expiry are forgotten.
+func NewTracker(threshold, expiry time.Duration) *Tracker {
+	return &Tracker{threshold: threshold, expiry: expiry, now: time.Now, claims: map[types.UID]entry{}}
+}
+
+// A Tracker keeps the last activity of every ready claim it's told about.
+type Tracker struct {
+	threshold time.Duration
+	expiry    time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	claims map[types.UID]entry
+}
+
+// ObserveSync records the last activity of the supplied claim.
+func (t *Tracker) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, ready := LastActivity(c)
+	if meta.WasDeleted(c) || !ready {
+		delete(t.claims, c.GetUID())
+		return
+	}
+	t.claims[c.GetUID()] = entry{
+		Claim: Claim{
+			Kind:      c.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: c.GetNamespace(),
+			Name:      c.GetName(),
+			Since:     last,
+		},
+		seen: t.now(),
+	}
+}
+
+// Idle returns the claims that have been idle for longer than the threshold,
+// the ones that have been idle the longest first.
+func (t *Tracker) Idle() []Claim {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	var idle []Claim
+	for uid, e := range t.claims {
+		if e.seen.Before(now.Add(-t.expiry)) {
+			delete(t.claims, uid)
+			continue
+		}
+		if now.Sub(e.Since) > t.threshold {
+			idle = append(idle, e.Claim)
+		}
+	}
+	sort.Slice(idle, func(i, j int) bool { return idle[i].Since.Before(idle[j].Since) })
+	return idle
+}
+
+// NewReporter returns a new *Reporter.
+func NewReporter(t *Tracker, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, log logging.Logger) *Reporter {
+	return &Reporter{tracker: t, client: a, name: nn, period: period, log: log}
+}
+
+// A Reporter periodically writes the claims that have been idle into a
+// ConfigMap.
+type Reporter struct {
+	tracker *Tracker
+	client  runtimeresource.Applicator
+	name    types.NamespacedName
+	period  time.Duration
+	log     logging.Logger
+}
+
+// Start reporting until the supplied channel is closed.
+func (r *Reporter) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.period)
+	defer t.Stop()
+	for {
+		if err := r.Report(context.Background()); err != nil {
+			r.log.Debug("Cannot report idle claims", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Report the claims that have been idle.
+func (r *Reporter) Report(ctx context.Context) error {
+	idle := r.tracker.Idle()
+	lines := make([]string, len(idle))
+	for i, c := range idle {
+		lines[i] = c.String()
+	}
+	if len(idle) > 0 {
+		r.log.Info("Found idle claims", "count", len(idle))
+	}
+	data := map[string]string{
+		keyIdle:    strings.Join(lines, "\n"),
+		keyCount:   fmt.Sprint(len(idle)),
+		keyUpdated: time.Now().UTC().Format(time.RFC3339),
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, r.client, r.name, data), errPublish)
+}
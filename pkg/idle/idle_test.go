@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestTracker(t *testing.T) {
+	// A claim with the supplied name and Ready condition that was last
+	// touched at the supplied time, if any.
+	type observed struct {
+		name    string
+		ready   v1alpha1.Condition
+		touched string
+	}
+	now := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	ready := func(at time.Time) v1alpha1.Condition {
+		return v1alpha1.Condition{Type: v1alpha1.TypeReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(at)}
+	}
+	cases := map[string]struct {
+		reason string
+		claims []observed
+		want   []string
+	}{
+		"Idle": {
+			reason: "Claims that have been ready for longer than the threshold should be reported",
+			claims: []observed{
+				{"old", ready(now.Add(-72 * time.Hour)), ""},
+				{"older", ready(now.Add(-96 * time.Hour)), ""},
+				{"new", ready(now.Add(-time.Hour)), ""},
+			},
+			want: []string{"older", "old"},
+		},
+		"Touched": {
+			reason: "Claims that were touched recently should not be reported",
+			claims: []observed{
+				{"touched", ready(now.Add(-72 * time.Hour)), now.Add(-time.Hour).Format(time.RFC3339)},
+			},
+		},
+		"NotReady": {
+			reason: "Claims that are not ready should not be reported",
+			claims: []observed{
+				{"unready", v1alpha1.Condition{Type: v1alpha1.TypeReady, Status: corev1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-72 * time.Hour))}, ""},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr := NewTracker(48*time.Hour, 10*time.Minute)
+			tr.now = func() time.Time { return now }
+			for _, o := range tc.claims {
+				c := claim.New()
+				c.SetName(o.name)
+				c.SetUID(types.UID(o.name))
+				c.SetConditions(o.ready)
+				if o.touched != "" {
+					c.SetAnnotations(map[string]string{resource.AnnotationKeyLastTouched: o.touched})
+				}
+				tr.ObserveSync(context.Background(), c)
+			}
+			var got []string
+			for _, c := range tr.Idle() {
+				got = append(got, c.Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nIdle(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
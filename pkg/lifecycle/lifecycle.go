@@ -0,0 +1,287 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle emits CloudEvents when claims go through the transitions
+// of their lifecycle so that automation can react to them without polling.
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	specVersion = "1.0"
+
+	errMarshal    = "cannot marshal event data"
+	errNewRequest = "cannot create request"
+	errSend       = "cannot send event"
+	errFmtStatus  = "sink responded with status %d"
+)
+
+// A Type of lifecycle event.
+type Type string
+
+// Lifecycle event types.
+const (
+	// TypePropagated is emitted when a generation of a claim is pushed to the
+	// remote cluster.
+	TypePropagated Type = "io.crossplane.agent.claim.propagated"
+
+	// TypeReady is emitted when a claim becomes ready in the remote cluster.
+	TypeReady Type = "io.crossplane.agent.claim.ready"
+
+	// TypeDrifted is emitted when the remote claim is found to be replaced or
+	// changed out-of-band.
+	TypeDrifted Type = "io.crossplane.agent.claim.drifted"
+
+	// TypeDeleted is emitted when a claim is deleted from the remote cluster
+	// and let go in the local cluster.
+	TypeDeleted Type = "io.crossplane.agent.claim.deleted"
+)
+
+// Data is the payload of a lifecycle event.
+type Data struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid"`
+	Generation int64     `json:"generation"`
+}
+
+// An Event in the lifecycle of a claim.
+type Event struct {
+	ID      string
+	Type    Type
+	Source  string
+	Subject string
+	Time    time.Time
+	Data    Data
+}
+
+// A Sink receives events.
+type Sink interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// NewHTTPSink returns a new *HTTPSink that posts events to the supplied URL.
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// An HTTPSink posts events in the binary content mode of the HTTP binding of
+// CloudEvents.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// Send the supplied event to the sink.
+func (s *HTTPSink) Send(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e.Data)
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, errNewRequest)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("ce-specversion", specVersion)
+	req.Header.Set("ce-id", e.ID)
+	req.Header.Set("ce-type", string(e.Type))
+	req.Header.Set("ce-source", e.Source)
+	req.Header.Set("ce-subject", e.Subject)
+	req.Header.Set("ce-time", e.Time.UTC().Format(time.RFC3339Nano))
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, errSend)
+	}
+	defer rsp.Body.Close() // nolint:errcheck
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return errors.Errorf(errFmtStatus, rsp.StatusCode)
+	}
+	return nil
+}
+
+type state struct {
+	propagated int64
+	ready      bool
+	drifted    bool
+}
+
+// EmitterOption configures an Emitter.
+type EmitterOption func(*Emitter)
+
+// WithLogger specifies how the Emitter should log messages.
+func WithLogger(l logging.Logger) EmitterOption {
+	return func(e *Emitter) {
+		e.log = l
+	}
+}
+
+// WithQueueSize specifies how many events may wait to be sent before new ones
+// are dropped.
+func WithQueueSize(n int) EmitterOption {
+	return func(e *Emitter) {
+		e.queue = make(chan Event, n)
+	}
+}
+
+// NewEmitter returns a new *Emitter that sends events about the claims it's
+// told about to the supplied Sink. The supplied source identifies the agent in
+// the events.
+func NewEmitter(s Sink, source string, o ...EmitterOption) *Emitter {
+	e := &Emitter{
+		sink:    s,
+		source:  source,
+		log:     logging.NewNopLogger(),
+		now:     time.Now,
+		queue:   make(chan Event, 1000),
+		claims:  map[types.UID]state{},
+		started: time.Now(),
+	}
+	for _, fn := range o {
+		fn(e)
+	}
+	return e
+}
+
+// An Emitter derives the lifecycle transitions of claims from the state they
+// are in after every sync and sends an event for each of them. Events are sent
+// in the background, so a slow sink doesn't hold up the syncs. The claims it
+// sees for the first time are not reported unless they were created after it,
+// so that a restart doesn't replay the transitions of all existing claims.
+type Emitter struct {
+	sink   Sink
+	source string
+	log    logging.Logger
+	now    func() time.Time
+	queue  chan Event
+
+	mu      sync.Mutex
+	claims  map[types.UID]state
+	started time.Time
+}
+
+// ObserveSync emits events for the transitions the supplied claim went through
+// since it was last observed.
+func (e *Emitter) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	uid := c.GetUID()
+	if meta.WasDeleted(c) {
+		if _, ok := e.claims[uid]; ok && !meta.FinalizerExists(c, agentclaim.Finalizer) {
+			delete(e.claims, uid)
+			e.emit(c, TypeDeleted)
+		}
+		return
+	}
+
+	now := observe(c)
+	was, ok := e.claims[uid]
+	if now.propagated < was.propagated {
+		// A failed sync doesn't take back what was pushed before.
+		now.propagated = was.propagated
+	}
+	e.claims[uid] = now
+	if !ok && c.GetCreationTimestamp().Time.Before(e.started) {
+		return
+	}
+	if now.propagated > was.propagated {
+		e.emit(c, TypePropagated)
+	}
+	if now.ready && !was.ready {
+		e.emit(c, TypeReady)
+	}
+	if now.drifted && !was.drifted {
+		e.emit(c, TypeDrifted)
+	}
+}
+
+// Start sending events until the supplied channel is closed.
+func (e *Emitter) Start(stop <-chan struct{}) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		case ev := <-e.queue:
+			if err := e.sink.Send(context.Background(), ev); err != nil {
+				e.log.Debug("Cannot send lifecycle event", "error", err, "type", ev.Type, "subject", ev.Subject)
+			}
+		}
+	}
+}
+
+func (e *Emitter) emit(c *claim.Unstructured, t Type) {
+	now := e.now()
+	gvk := c.GetObjectKind().GroupVersionKind()
+	ev := Event{
+		ID:      fmt.Sprintf("%s.%d", c.GetUID(), now.UnixNano()),
+		Type:    t,
+		Source:  e.source,
+		Subject: fmt.Sprintf("%s/%s", c.GetNamespace(), c.GetName()),
+		Time:    now,
+		Data: Data{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  c.GetNamespace(),
+			Name:       c.GetName(),
+			UID:        c.GetUID(),
+			Generation: c.GetGeneration(),
+		},
+	}
+	select {
+	case e.queue <- ev:
+	default:
+		e.log.Info("Dropping lifecycle event, too many are waiting to be sent", "type", t, "subject", ev.Subject)
+	}
+}
+
+func drifted(r v1alpha1.ConditionReason) bool {
+	return r == resource.ReasonAgentSyncRemoteReplaced || r == resource.ReasonAgentSyncDrifted
+}
+
+func observe(c *claim.Unstructured) state {
+	s := state{
+		ready:   c.GetCondition(v1alpha1.TypeReady).Status == corev1.ConditionTrue,
+		drifted: drifted(c.GetCondition(resource.TypeAgentSync).Reason),
+	}
+	if c.GetCondition(resource.TypeAgentSync).Reason == resource.ReasonAgentSyncSuccess {
+		s.propagated = c.GetGeneration()
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestEmitter(t *testing.T) {
+	started := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	newClaim := func(created time.Time, gen int64, c ...v1alpha1.Condition) *claim.Unstructured {
+		cl := claim.New()
+		cl.SetName("cool-claim")
+		cl.SetNamespace("default")
+		cl.SetUID("cool-uid")
+		cl.SetCreationTimestamp(metav1.NewTime(created))
+		cl.SetGeneration(gen)
+		cl.SetFinalizers([]string{agentclaim.Finalizer})
+		cl.SetConditions(c...)
+		return cl
+	}
+	deleted := func(c *claim.Unstructured, finalizers ...string) *claim.Unstructured {
+		now := metav1.NewTime(started)
+		c.SetDeletionTimestamp(&now)
+		c.SetFinalizers(finalizers)
+		return c
+	}
+	ready := v1alpha1.Condition{Type: v1alpha1.TypeReady, Status: corev1.ConditionTrue}
+	synced := resource.AgentSyncSuccess()
+	replaced := resource.AgentSyncRemoteReplaced("old-uid", "new-uid")
+	failed := resource.AgentSyncError(errors.New("boom"))
+	after := started.Add(time.Minute)
+
+	cases := map[string]struct {
+		reason string
+		claims []*claim.Unstructured
+		want   []Type
+	}{
+		"New": {
+			reason: "A claim that is created, pushed and becomes ready should go through all of these transitions",
+			claims: []*claim.Unstructured{
+				newClaim(after, 1),
+				newClaim(after, 1, synced),
+				newClaim(after, 1, synced, ready),
+			},
+			want: []Type{TypePropagated, TypeReady},
+		},
+		"Existing": {
+			reason: "The transitions of claims that existed before the emitter was started should not be replayed",
+			claims: []*claim.Unstructured{
+				newClaim(started.Add(-time.Hour), 1, synced, ready),
+				newClaim(started.Add(-time.Hour), 1, synced, ready),
+			},
+		},
+		"Updated": {
+			reason: "A new generation should be reported once it's pushed, even after a failed sync",
+			claims: []*claim.Unstructured{
+				newClaim(started.Add(-time.Hour), 1, synced, ready),
+				newClaim(started.Add(-time.Hour), 2, failed, ready),
+				newClaim(started.Add(-time.Hour), 2, synced, ready),
+				newClaim(started.Add(-time.Hour), 2, failed, ready),
+				newClaim(started.Add(-time.Hour), 2, synced, ready),
+			},
+			want: []Type{TypePropagated},
+		},
+		"Drifted": {
+			reason: "A remote claim that is replaced out-of-band should be reported once",
+			claims: []*claim.Unstructured{
+				newClaim(after, 1, synced),
+				newClaim(after, 1, replaced),
+				newClaim(after, 1, replaced),
+			},
+			want: []Type{TypePropagated, TypeDrifted},
+		},
+		"Deleted": {
+			reason: "A claim should be reported as deleted once the agent lets go of it",
+			claims: []*claim.Unstructured{
+				newClaim(started.Add(-time.Hour), 1, synced),
+				deleted(newClaim(started.Add(-time.Hour), 1, synced), agentclaim.Finalizer),
+				deleted(newClaim(started.Add(-time.Hour), 1, synced)),
+				deleted(newClaim(started.Add(-time.Hour), 1, synced)),
+			},
+			want: []Type{TypeDeleted},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := NewEmitter(nil, "cool-agent")
+			e.started = started
+			for _, c := range tc.claims {
+				e.ObserveSync(context.Background(), c)
+			}
+			close(e.queue)
+			var got []Type
+			for ev := range e.queue {
+				got = append(got, ev.Type)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nObserveSync(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	e := Event{ID: "cool-id", Type: TypeReady, Source: "cool-agent", Subject: "default/cool-claim", Time: time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)}
+	if err := NewHTTPSink(srv.URL, time.Second).Send(context.Background(), e); err != nil {
+		t.Fatalf("Send(...): %s", err)
+	}
+	want := map[string]string{
+		"Ce-Specversion": specVersion,
+		"Ce-Id":          "cool-id",
+		"Ce-Type":        string(TypeReady),
+		"Ce-Source":      "cool-agent",
+		"Ce-Subject":     "default/cool-claim",
+		"Ce-Time":        "2020-10-10T00:00:00Z",
+		"Content-Type":   "application/json",
+	}
+	for k, v := range want {
+		if diff := cmp.Diff(v, got.Get(k)); diff != "" {
+			t.Errorf("Send(...): header %s: -want, +got:\n%s", k, diff)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the Prometheus metrics that Agent exposes in
+// addition to the ones controller-runtime exposes.
+package metrics
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "crossplane_agent"
+
+	errRegister = "cannot register metrics"
+)
+
+// Connectivity metrics describe the connection to the remote cluster.
+type Connectivity struct {
+	Connected           prometheus.Gauge
+	Disconnects         prometheus.Counter
+	DisconnectedSeconds prometheus.Histogram
+}
+
+// NewConnectivity returns Connectivity metrics that are registered with the
+// supplied prometheus.Registerer.
+func NewConnectivity(reg prometheus.Registerer) (*Connectivity, error) {
+	m := &Connectivity{
+		Connected: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "connected",
+			Help:      "Whether the remote cluster is reachable.",
+		}),
+		Disconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "disconnects_total",
+			Help:      "Number of times the remote cluster became unreachable.",
+		}),
+		DisconnectedSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "disconnected_seconds",
+			Help:      "How long the remote cluster was unreachable for.",
+			Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 3600},
+		}),
+	}
+	for _, c := range []prometheus.Collector{m.Connected, m.Disconnects, m.DisconnectedSeconds} {
+		if err := reg.Register(c); err != nil {
+			return nil, errors.Wrap(err, errRegister)
+		}
+	}
+	return m, nil
+}
+
+// ConnectionProbe metrics describe which stage of the connection to the
+// remote cluster fails.
+type ConnectionProbe struct {
+	Failing *prometheus.GaugeVec
+}
+
+// NewConnectionProbe returns ConnectionProbe metrics that are registered with
+// the supplied prometheus.Registerer.
+func NewConnectionProbe(reg prometheus.Registerer) (*ConnectionProbe, error) {
+	m := &ConnectionProbe{
+		Failing: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "probe_failing",
+			Help:      "Whether the last probe of the connection to the remote cluster failed, by the stage it failed at.",
+		}, []string{"stage"}),
+	}
+	if err := reg.Register(m.Failing); err != nil {
+		return nil, errors.Wrap(err, errRegister)
+	}
+	return m, nil
+}
+
+// Propagation metrics describe how long it takes for changes to local claims
+// to reach the remote cluster, and where the time of each sync is spent.
+type Propagation struct {
+	LagSeconds   *prometheus.SummaryVec
+	PhaseSeconds *prometheus.HistogramVec
+}
+
+// NewPropagation returns Propagation metrics that are registered with the
+// supplied prometheus.Registerer.
+func NewPropagation(reg prometheus.Registerer) (*Propagation, error) {
+	m := &Propagation{
+		LagSeconds: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  namespace,
+			Subsystem:  "claim",
+			Name:       "propagation_lag_seconds",
+			Help:       "Time between a change to the spec of a local claim and its push to the remote cluster.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, []string{"gvk"}),
+		PhaseSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "claim",
+			Name:      "reconcile_phase_seconds",
+			Help:      "Time spent in each phase of a claim sync, e.g. reading from the local or the remote cluster.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"gvk", "phase"}),
+	}
+	for _, c := range []prometheus.Collector{m.LagSeconds, m.PhaseSeconds} {
+		if err := reg.Register(c); err != nil {
+			return nil, errors.Wrap(err, errRegister)
+		}
+	}
+	return m, nil
+}
+
+// Warmup metrics describe the progress of filling the caches of the kinds the
+// agent syncs after it's started.
+type Warmup struct {
+	Kinds      prometheus.Gauge
+	ReadyKinds prometheus.Gauge
+}
+
+// NewWarmup returns Warmup metrics that are registered with the supplied
+// prometheus.Registerer.
+func NewWarmup(reg prometheus.Registerer) (*Warmup, error) {
+	m := &Warmup{
+		Kinds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "warmup_kinds",
+			Help:      "Number of kinds whose caches are warmed up when the agent is started.",
+		}),
+		ReadyKinds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "warmup_ready_kinds",
+			Help:      "Number of kinds whose caches are warm.",
+		}),
+	}
+	for _, c := range []prometheus.Collector{m.Kinds, m.ReadyKinds} {
+		if err := reg.Register(c); err != nil {
+			return nil, errors.Wrap(err, errRegister)
+		}
+	}
+	return m, nil
+}
+
+// Conditions metrics describe the results of the last syncs of claims.
+type Conditions struct {
+	Claims *prometheus.GaugeVec
+}
+
+// NewConditions returns Conditions metrics that are registered with the
+// supplied prometheus.Registerer.
+func NewConditions(reg prometheus.Registerer) (*Conditions, error) {
+	m := &Conditions{
+		Claims: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "claim",
+			Name:      "sync_reason_claims",
+			Help:      "Number of claims whose AgentSynced condition has the given reason.",
+		}, []string{"gvk", "reason"}),
+	}
+	if err := reg.Register(m.Claims); err != nil {
+		return nil, errors.Wrap(err, errRegister)
+	}
+	return m, nil
+}
+
+// Sync metrics describe the results of the syncs of the controllers.
+type Sync struct {
+	Synced   *prometheus.CounterVec
+	Failures *prometheus.CounterVec
+	Deleted  *prometheus.CounterVec
+}
+
+// NewSync returns Sync metrics that are registered with the supplied
+// prometheus.Registerer.
+func NewSync(reg prometheus.Registerer) (*Sync, error) {
+	m := &Sync{
+		Synced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sync",
+			Name:      "synced_total",
+			Help:      "Number of objects that were synced successfully.",
+		}, []string{"controller", "kind"}),
+		Failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sync",
+			Name:      "failures_total",
+			Help:      "Number of syncs that failed, by the cluster that caused the failure.",
+		}, []string{"controller", "kind", "cluster"}),
+		Deleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sync",
+			Name:      "garbage_collected_total",
+			Help:      "Number of local objects that were deleted because they no longer exist in the remote cluster.",
+		}, []string{"controller", "kind"}),
+	}
+	for _, c := range []prometheus.Collector{m.Synced, m.Failures, m.Deleted} {
+		if err := reg.Register(c); err != nil {
+			return nil, errors.Wrap(err, errRegister)
+		}
+	}
+	return m, nil
+}
+
+// The clusters that a failed sync is attributed to. Errors are attributed by
+// the prefix that the controllers add to them.
+const (
+	ClusterLocal   = "local"
+	ClusterRemote  = "remote"
+	ClusterUnknown = "unknown"
+
+	prefixLocal  = "local cluster: "
+	prefixRemote = "remote cluster: "
+)
+
+// Cluster returns the cluster that the supplied error message is attributed
+// to.
+func Cluster(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, prefixLocal):
+		return ClusterLocal
+	case strings.HasPrefix(msg, prefixRemote):
+		return ClusterRemote
+	default:
+		return ClusterUnknown
+	}
+}
+
+// Failed records a failed sync of an object of the supplied kind by the
+// supplied controller, attributed by the supplied error.
+func (m *Sync) Failed(controller, kind string, err error) {
+	m.Failures.WithLabelValues(controller, kind, Cluster(err.Error())).Inc()
+}
+
+// RemoteAPI metrics describe the requests made to the api-server of the remote
+// cluster.
+type RemoteAPI struct {
+	RequestSeconds *prometheus.HistogramVec
+}
+
+// NewRemoteAPI returns RemoteAPI metrics that are registered with the supplied
+// prometheus.Registerer.
+func NewRemoteAPI(reg prometheus.Registerer) (*RemoteAPI, error) {
+	m := &RemoteAPI{
+		RequestSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "request_seconds",
+			Help:      "Time it took for the api-server of the remote cluster to respond to requests, by method and status code.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"method", "code"}),
+	}
+	if err := reg.Register(m.RequestSeconds); err != nil {
+		return nil, errors.Wrap(err, errRegister)
+	}
+	return m, nil
+}
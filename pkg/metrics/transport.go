@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NewTransportWrapper returns a function that wraps a http.RoundTripper so
+// that the time it takes for each request to be responded to is observed in
+// the supplied RemoteAPI metrics. It can be used to wrap the transport of a
+// rest.Config. Requests that fail without a response are observed with code
+// 0.
+func NewTransportWrapper(m *RemoteAPI) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &transport{wrapped: rt, metrics: m}
+	}
+}
+
+type transport struct {
+	wrapped http.RoundTripper
+	metrics *RemoteAPI
+}
+
+// RoundTrip executes the request and observes how long it took.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.wrapped.RoundTrip(req)
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	t.metrics.RequestSeconds.WithLabelValues(req.Method, strconv.Itoa(code)).Observe(time.Since(start).Seconds())
+	return resp, err
+}
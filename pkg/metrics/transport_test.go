@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type roundTripFn func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFn) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestTransport(t *testing.T) {
+	m, err := NewRemoteAPI(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewRemoteAPI(...): %s", err)
+	}
+	wrap := NewTransportWrapper(m)
+	ok := wrap(roundTripFn(func(_ *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+	failed := wrap(roundTripFn(func(_ *http.Request) (*http.Response, error) {
+		return nil, errors.New("boom")
+	}))
+
+	get, _ := http.NewRequest(http.MethodGet, "https://example.org", nil)
+	put, _ := http.NewRequest(http.MethodPut, "https://example.org", nil)
+	for _, rt := range []http.RoundTripper{ok, ok} {
+		if _, err := rt.RoundTrip(get); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := failed.RoundTrip(put); err == nil {
+		t.Fatal("RoundTrip(...): the error of the wrapped transport should be returned")
+	}
+
+	count := func(method, code string) uint64 {
+		d := &dto.Metric{}
+		if err := m.RequestSeconds.WithLabelValues(method, code).(prometheus.Metric).Write(d); err != nil {
+			t.Fatal(err)
+		}
+		return d.GetHistogram().GetSampleCount()
+	}
+	want := map[string]uint64{"GET 200": 2, "PUT 0": 1}
+	got := map[string]uint64{"GET 200": count(http.MethodGet, "200"), "PUT 0": count(http.MethodPut, "0")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nReason: %s\nRoundTrip(...): -want, +got:\n%s", "Every request should be observed by its method and status code", diff)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package onboarding spreads the first sync of the claims that existed before
+// the agent was started over time, so that pointing the agent at a cluster
+// with many claims doesn't flood the remote cluster with creates, and reports
+// the progress of their onboarding.
+package onboarding
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the progress is published to.
+	ConfigMapName = "crossplane-agent-onboarding"
+
+	keySynced  = "synced"
+	keyTotal   = "total"
+	keyETA     = "eta"
+	keyUpdated = "updated"
+
+	errPublish = "cannot publish onboarding progress"
+)
+
+// Progress of the onboarding of the claims that existed before the agent was
+// started.
+type Progress struct {
+	// Synced is how many of them were let through to be synced.
+	Synced int
+
+	// Total is how many of them are known. Claims are only known once they're
+	// reconciled, so it may grow shortly after the agent is started.
+	Total int
+
+	// ETA is roughly how long it'll take to let the rest of them through.
+	ETA time.Duration
+}
+
+// Done returns true if all known claims were let through.
+func (p Progress) Done() bool {
+	return p.Synced == p.Total
+}
+
+// String returns the progress in the form it's reported.
+func (p Progress) String() string {
+	return fmt.Sprintf("%d/%d synced, ETA %s", p.Synced, p.Total, p.ETA)
+}
+
+// NewThrottle returns a *Throttle that lets at most batch claims that existed
+// before the supplied time be synced for the first time per interval. At least
+// one claim is let through per interval.
+func NewThrottle(started time.Time, batch int, interval time.Duration) *Throttle {
+	if batch < 1 {
+		batch = 1
+	}
+	return &Throttle{
+		started:  started,
+		batch:    batch,
+		interval: interval,
+		now:      time.Now,
+		pending:  map[types.UID]time.Time{},
+		synced:   map[types.UID]bool{},
+	}
+}
+
+// A Throttle lets the claims that existed before the agent was started be
+// synced for the first time in batches.
+type Throttle struct {
+	started  time.Time
+	batch    int
+	interval time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	window   time.Time
+	admitted int
+	pending  map[types.UID]time.Time
+	synced   map[types.UID]bool
+}
+
+// Admit returns true if the supplied claim, which has never been synced, may
+// be synced now, and otherwise how long to wait before asking again. Claims
+// that were created after the agent was started are always admitted.
+func (t *Throttle) Admit(o metav1.Object) (bool, time.Duration) {
+	if o.GetCreationTimestamp().After(t.started) {
+		return true, 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	uid := o.GetUID()
+	if t.synced[uid] {
+		return true, 0
+	}
+	now := t.now()
+	if now.Sub(t.window) >= t.interval {
+		t.window = now
+		t.admitted = 0
+	}
+	if t.admitted < t.batch {
+		t.admitted++
+		t.synced[uid] = true
+		delete(t.pending, uid)
+		return true, 0
+	}
+	t.pending[uid] = now
+	return false, t.window.Add(t.interval).Sub(now)
+}
+
+// Progress returns the progress of the onboarding. Claims that haven't asked to
+// be admitted for a while, because they were deleted or stopped being synced,
+// are forgotten.
+func (t *Throttle) Progress() Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for uid, seen := range t.pending {
+		if now.Sub(seen) > 2*t.interval {
+			delete(t.pending, uid)
+		}
+	}
+	batches := (len(t.pending) + t.batch - 1) / t.batch
+	return Progress{
+		Synced: len(t.synced),
+		Total:  len(t.synced) + len(t.pending),
+		ETA:    time.Duration(batches) * t.interval,
+	}
+}
+
+// NewReporter returns a new *Reporter.
+func NewReporter(t *Throttle, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, log logging.Logger) *Reporter {
+	return &Reporter{throttle: t, client: a, name: nn, period: period, log: log}
+}
+
+// A Reporter periodically logs the progress of the onboarding and writes it
+// into a ConfigMap.
+type Reporter struct {
+	throttle *Throttle
+	client   runtimeresource.Applicator
+	name     types.NamespacedName
+	period   time.Duration
+	log      logging.Logger
+
+	done bool
+}
+
+// Start reporting until the supplied channel is closed.
+func (r *Reporter) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.period)
+	defer t.Stop()
+	for {
+		if err := r.Report(context.Background()); err != nil {
+			r.log.Debug("Cannot report onboarding progress", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Report the progress of the onboarding. It's only logged until all known
+// claims were let through.
+func (r *Reporter) Report(ctx context.Context) error {
+	p := r.throttle.Progress()
+	switch {
+	case !p.Done():
+		r.log.Info("Onboarding pre-existing claims", "synced", p.Synced, "total", p.Total, "eta", p.ETA.String())
+	case !r.done && p.Total > 0:
+		r.log.Info("Onboarded pre-existing claims", "total", p.Total)
+	}
+	r.done = p.Done()
+	data := map[string]string{
+		keySynced:  fmt.Sprint(p.Synced),
+		keyTotal:   fmt.Sprint(p.Total),
+		keyETA:     p.ETA.String(),
+		keyUpdated: time.Now().UTC().Format(time.RFC3339),
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, r.client, r.name, data), errPublish)
+}
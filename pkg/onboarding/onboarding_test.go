@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboarding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestThrottle(t *testing.T) {
+	started := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	now := started
+	claim := func(uid string, created time.Time) metav1.Object {
+		return &metav1.ObjectMeta{UID: types.UID(uid), CreationTimestamp: metav1.NewTime(created)}
+	}
+	type admission struct {
+		Admitted bool
+		Wait     time.Duration
+	}
+	admit := func(th *Throttle, o metav1.Object) admission {
+		ok, wait := th.Admit(o)
+		return admission{Admitted: ok, Wait: wait}
+	}
+
+	th := NewThrottle(started, 2, time.Minute)
+	th.now = func() time.Time { return now }
+	old := started.Add(-time.Hour)
+
+	got := []admission{
+		admit(th, claim("a", old)),
+		admit(th, claim("b", old)),
+		admit(th, claim("c", old)),
+		admit(th, claim("d", old)),
+		admit(th, claim("e", old)),
+		admit(th, claim("a", old)),
+		admit(th, claim("new", started.Add(time.Second))),
+	}
+	want := []admission{
+		{Admitted: true},
+		{Admitted: true},
+		{Wait: time.Minute},
+		{Wait: time.Minute},
+		{Wait: time.Minute},
+		{Admitted: true},
+		{Admitted: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nReason: %s\nAdmit(...): -want, +got:\n%s", "Only a batch of pre-existing claims should be admitted per interval", diff)
+	}
+	if diff := cmp.Diff(Progress{Synced: 2, Total: 5, ETA: 2 * time.Minute}, th.Progress()); diff != "" {
+		t.Errorf("\nReason: %s\nProgress(): -want, +got:\n%s", "Progress should count the admitted and the pending pre-existing claims", diff)
+	}
+
+	now = now.Add(time.Minute)
+	got = []admission{
+		admit(th, claim("c", old)),
+		admit(th, claim("d", old)),
+	}
+	want = []admission{{Admitted: true}, {Admitted: true}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nReason: %s\nAdmit(...): -want, +got:\n%s", "The next batch should be admitted once the interval passed", diff)
+	}
+
+	now = now.Add(3 * time.Minute)
+	if diff := cmp.Diff(Progress{Synced: 4, Total: 4}, th.Progress()); diff != "" {
+		t.Errorf("\nReason: %s\nProgress(): -want, +got:\n%s", "Claims that stopped asking to be admitted should be forgotten", diff)
+	}
+}
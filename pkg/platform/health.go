@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package platform reports the health of the definitions in the remote
+// cluster that the claims of the local cluster depend on.
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the health of the platform is
+	// published to.
+	ConfigMapName = "crossplane-agent-platform-health"
+
+	keyStatus   = "status"
+	keyProblems = "problems"
+	keyUpdated  = "updated"
+
+	// StatusHealthy means all definitions the local cluster depends on are
+	// healthy in the remote cluster.
+	StatusHealthy = "Healthy"
+
+	// StatusDegraded means some of them are not.
+	StatusDegraded = "Degraded"
+
+	errListLocalXRDs    = "cannot list local composite resource definitions"
+	errListCompositions = "cannot list remote compositions"
+	errPublish          = "cannot publish platform health"
+)
+
+// Conditions of CompositeResourceDefinitions in the remote cluster that tell
+// whether they're usable. The reason they're not, e.g. that the remote
+// Crossplane is of the wrong version, is reported together with them.
+const (
+	typeEstablished runtimev1alpha1.ConditionType = "Established"
+	typeOffered     runtimev1alpha1.ConditionType = "Offered"
+)
+
+// NewChecker returns a new *Checker.
+func NewChecker(local, remote client.Reader) *Checker {
+	return &Checker{local: local, remote: remote}
+}
+
+// A Checker finds the problems of the CompositeResourceDefinitions, and their
+// Compositions, in the remote cluster that are synced to the local cluster.
+type Checker struct {
+	local  client.Reader
+	remote client.Reader
+}
+
+// Problems returns a line for every problem that is found, sorted.
+func (c *Checker) Problems(ctx context.Context) ([]string, error) {
+	l := &v1alpha1.CompositeResourceDefinitionList{}
+	if err := c.local.List(ctx, l); err != nil {
+		return nil, errors.Wrap(err, errListLocalXRDs)
+	}
+	comps := &v1alpha1.CompositionList{}
+	if err := c.remote.List(ctx, comps); err != nil {
+		return nil, errors.Wrap(err, errListCompositions)
+	}
+	composed := map[schema.GroupKind]bool{}
+	for _, comp := range comps.Items {
+		gvk := schema.FromAPIVersionAndKind(comp.Spec.CompositeTypeRef.APIVersion, comp.Spec.CompositeTypeRef.Kind)
+		composed[gvk.GroupKind()] = true
+	}
+
+	var problems []string
+	for _, local := range l.Items {
+		xrd := &v1alpha1.CompositeResourceDefinition{}
+		if err := c.remote.Get(ctx, types.NamespacedName{Name: local.GetName()}, xrd); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: cannot be read from the remote cluster: %s", local.GetName(), errors.Cause(err)))
+			continue
+		}
+		watched := []runtimev1alpha1.ConditionType{typeEstablished}
+		if xrd.Spec.ClaimNames != nil {
+			watched = append(watched, typeOffered)
+		}
+		for _, t := range watched {
+			cond := xrd.Status.GetCondition(t)
+			if cond.Status == corev1.ConditionTrue {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s is %s: %s %s", xrd.GetName(), t, cond.Status, cond.Reason, cond.Message))
+		}
+		gk := schema.GroupKind{Group: xrd.Spec.CRDSpecTemplate.Group, Kind: xrd.Spec.CRDSpecTemplate.Names.Kind}
+		if !composed[gk] {
+			problems = append(problems, fmt.Sprintf("%s: there is no composition of %s", xrd.GetName(), gk))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// NewReporter returns a new *Reporter.
+func NewReporter(c *Checker, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, log logging.Logger) *Reporter {
+	return &Reporter{checker: c, client: a, name: nn, period: period, log: log}
+}
+
+// A Reporter periodically writes the health of the platform into a ConfigMap.
+type Reporter struct {
+	checker *Checker
+	client  runtimeresource.Applicator
+	name    types.NamespacedName
+	period  time.Duration
+	log     logging.Logger
+}
+
+// Start reporting until the supplied channel is closed.
+func (r *Reporter) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.period)
+	defer t.Stop()
+	for {
+		if err := r.Report(context.Background()); err != nil {
+			r.log.Debug("Cannot report platform health", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Report the health of the platform.
+func (r *Reporter) Report(ctx context.Context) error {
+	problems, err := r.checker.Problems(ctx)
+	if err != nil {
+		problems = []string{err.Error()}
+	}
+	status := StatusHealthy
+	if len(problems) > 0 {
+		status = StatusDegraded
+		r.log.Info("Platform is degraded", "problems", len(problems))
+	}
+	data := map[string]string{
+		keyStatus:   status,
+		keyProblems: strings.Join(problems, "\n"),
+		keyUpdated:  time.Now().UTC().Format(time.RFC3339),
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, r.client, r.name, data), errPublish)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+var errBoom = errors.New("boom")
+
+func TestProblems(t *testing.T) {
+	xrd := func(established, offered corev1.ConditionStatus) v1alpha1.CompositeResourceDefinition {
+		x := v1alpha1.CompositeResourceDefinition{}
+		x.SetName("xdatabases.example.org")
+		x.Spec.CRDSpecTemplate.Group = "example.org"
+		x.Spec.CRDSpecTemplate.Names.Kind = "XDatabase"
+		x.Spec.ClaimNames = &v1beta1.CustomResourceDefinitionNames{Kind: "Database", Plural: "databases"}
+		x.Status.SetConditions(
+			runtimev1alpha1.Condition{Type: typeEstablished, Status: established},
+			runtimev1alpha1.Condition{Type: typeOffered, Status: offered, Reason: "WrongCrossplaneVersion"},
+		)
+		return x
+	}
+	local := &test.MockClient{MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+		obj.(*v1alpha1.CompositeResourceDefinitionList).Items = []v1alpha1.CompositeResourceDefinition{xrd(corev1.ConditionTrue, corev1.ConditionTrue)}
+		return nil
+	}}
+	remote := func(x v1alpha1.CompositeResourceDefinition, kind string) *test.MockClient {
+		return &test.MockClient{
+			MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+				x.DeepCopyInto(obj.(*v1alpha1.CompositeResourceDefinition))
+				return nil
+			},
+			MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+				c := v1alpha1.Composition{}
+				c.Spec.CompositeTypeRef = v1alpha1.TypeReference{APIVersion: "example.org/v1alpha1", Kind: kind}
+				obj.(*v1alpha1.CompositionList).Items = []v1alpha1.Composition{c}
+				return nil
+			},
+		}
+	}
+	type want struct {
+		problems []string
+		err      error
+	}
+	cases := map[string]struct {
+		reason string
+		local  client.Reader
+		remote client.Reader
+		want   want
+	}{
+		"Healthy": {
+			reason: "There should be no problems if the definitions are usable and composed",
+			local:  local,
+			remote: remote(xrd(corev1.ConditionTrue, corev1.ConditionTrue), "XDatabase"),
+			want:   want{},
+		},
+		"NotOffered": {
+			reason: "A definition whose claim is not offered should be reported with the reason",
+			local:  local,
+			remote: remote(xrd(corev1.ConditionTrue, corev1.ConditionFalse), "XDatabase"),
+			want: want{problems: []string{
+				"xdatabases.example.org: Offered is False: WrongCrossplaneVersion ",
+			}},
+		},
+		"NotComposed": {
+			reason: "A definition without a composition should be reported",
+			local:  local,
+			remote: remote(xrd(corev1.ConditionTrue, corev1.ConditionTrue), "XCache"),
+			want: want{problems: []string{
+				"xdatabases.example.org: there is no composition of XDatabase.example.org",
+			}},
+		},
+		"ListLocalFailed": {
+			reason: "An error should be returned if the local definitions cannot be listed",
+			local:  &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			want:   want{err: errors.Wrap(errBoom, errListLocalXRDs)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewChecker(tc.local, tc.remote).Problems(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nProblems(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.problems, got); diff != "" {
+				t.Errorf("\nReason: %s\nProblems(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
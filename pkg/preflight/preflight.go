@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package preflight checks the configuration of the agent, and whether both
+// clusters can be used with it, before anything is started so that all that
+// is wrong is reported at once.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Discovery is the part of the discovery client that the checks use.
+type Discovery interface {
+	ServerVersion() (*version.Info, error)
+	ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error)
+}
+
+// A Cluster that is checked.
+type Cluster struct {
+	Discovery Discovery
+	Client    client.Reader
+
+	// Kinds have to be served by the cluster.
+	Kinds []schema.GroupVersionKind
+}
+
+// Config is the configuration of the agent that is checked.
+type Config struct {
+	Local  Cluster
+	Remote Cluster
+
+	// Namespace is the namespace in the local cluster where the agent keeps
+	// its bookkeeping objects.
+	Namespace string
+
+	// ClusterName identifies the local cluster in the remote cluster.
+	ClusterName string
+
+	// NamespaceMapping maps local namespaces to remote namespaces.
+	NamespaceMapping map[string]string
+
+	// OwnerLabels are added to the objects pushed to the remote cluster and
+	// select the ones listed there.
+	OwnerLabels map[string]string
+}
+
+// The CompositeResourceDefinitions the remote cluster has to serve, and the
+// agent has to be able to list, for claims to be synced at all.
+var xrdList = schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1alpha1", Kind: "CompositeResourceDefinitionList"}
+
+// Check returns every problem that is found with the supplied Config, sorted.
+func Check(ctx context.Context, c Config) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if c.ClusterName != "" {
+		for _, msg := range validation.IsDNS1123Label(c.ClusterName) {
+			add("cluster name %q: %s", c.ClusterName, msg)
+		}
+	}
+	for l, r := range c.NamespaceMapping {
+		for _, ns := range []string{l, r} {
+			for _, msg := range validation.IsDNS1123Label(ns) {
+				add("namespace mapping %s=%s: namespace %q: %s", l, r, ns, msg)
+			}
+		}
+	}
+	for k, v := range c.OwnerLabels {
+		for _, msg := range validation.IsQualifiedName(k) {
+			add("remote owner label %s=%s: key: %s", k, v, msg)
+		}
+		for _, msg := range validation.IsValidLabelValue(v) {
+			add("remote owner label %s=%s: value: %s", k, v, msg)
+		}
+	}
+
+	if checkCluster(c.Local, "local", add) {
+		if err := c.Local.Client.Get(ctx, types.NamespacedName{Name: c.Namespace}, &corev1.Namespace{}); err != nil {
+			add("local cluster: cannot get agent namespace %s: %s", c.Namespace, errors.Cause(err))
+		}
+	}
+	if checkCluster(c.Remote, "remote", add) {
+		l := &kunstructured.UnstructuredList{}
+		l.SetGroupVersionKind(xrdList)
+		if err := c.Remote.Client.List(ctx, l, client.Limit(1)); err != nil {
+			add("remote cluster: cannot list composite resource definitions: %s", errors.Cause(err))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// checkCluster reports the problems of the supplied Cluster, and returns
+// whether it's reachable at all.
+func checkCluster(c Cluster, name string, add func(format string, args ...interface{})) bool {
+	if _, err := c.Discovery.ServerVersion(); err != nil {
+		add("%s cluster: cannot be reached: %s", name, err)
+		return false
+	}
+	// The kinds of a group version that can't be discovered are nil, so
+	// that the same problem isn't reported for every one of them.
+	served := map[schema.GroupVersion]map[string]bool{}
+	for _, gvk := range c.Kinds {
+		gv := gvk.GroupVersion()
+		kinds, ok := served[gv]
+		if !ok {
+			kinds = discover(c.Discovery, gv, name, add)
+			served[gv] = kinds
+		}
+		if kinds != nil && !kinds[gvk.Kind] {
+			add("%s cluster: %s is not served", name, gvk)
+		}
+	}
+	return true
+}
+
+func discover(d Discovery, gv schema.GroupVersion, name string, add func(format string, args ...interface{})) map[string]bool {
+	rl, err := d.ServerResourcesForGroupVersion(gv.String())
+	if err != nil {
+		add("%s cluster: cannot discover the kinds of %s: %s", name, gv, err)
+		return nil
+	}
+	kinds := map[string]bool{}
+	for _, r := range rl.APIResources {
+		// Subresources, like status, are listed with the kind of their
+		// resource too.
+		if !strings.Contains(r.Name, "/") {
+			kinds[r.Kind] = true
+		}
+	}
+	return kinds
+}
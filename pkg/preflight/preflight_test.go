@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/version"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var errBoom = errors.New("boom")
+
+type mockDiscovery struct {
+	err       error
+	resources map[string]*metav1.APIResourceList
+}
+
+func (d *mockDiscovery) ServerVersion() (*version.Info, error) {
+	return &version.Info{}, d.err
+}
+
+func (d *mockDiscovery) ServerResourcesForGroupVersion(gv string) (*metav1.APIResourceList, error) {
+	rl, ok := d.resources[gv]
+	if !ok {
+		return nil, errBoom
+	}
+	return rl, nil
+}
+
+func TestCheck(t *testing.T) {
+	served := &mockDiscovery{resources: map[string]*metav1.APIResourceList{
+		"example.org/v1alpha1": {APIResources: []metav1.APIResource{
+			{Name: "mysqlinstances", Kind: "MySQLInstance"},
+			{Name: "mysqlinstances/status", Kind: "MySQLInstance"},
+			{Name: "buckets/status", Kind: "Bucket"},
+		}},
+	}}
+	ok := &test.MockClient{MockGet: test.NewMockGetFn(nil), MockList: test.NewMockListFn(nil)}
+	mysql := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	bucket := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Bucket"}
+	cache := schema.GroupVersionKind{Group: "cache.example.org", Version: "v1alpha1", Kind: "Redis"}
+
+	cases := map[string]struct {
+		reason string
+		c      Config
+		want   []string
+	}{
+		"Valid": {
+			reason: "No problems should be reported if the configuration is valid and both clusters serve everything",
+			c: Config{
+				Local:            Cluster{Discovery: served, Client: ok, Kinds: []schema.GroupVersionKind{mysql}},
+				Remote:           Cluster{Discovery: served, Client: ok, Kinds: []schema.GroupVersionKind{mysql}},
+				Namespace:        "crossplane-system",
+				ClusterName:      "cool-cluster",
+				NamespaceMapping: map[string]string{"team-a": "prod-team-a"},
+				OwnerLabels:      map[string]string{"example.org/owner": "cool-cluster"},
+			},
+		},
+		"Invalid": {
+			reason: "Every problem should be reported at once",
+			c: Config{
+				Local:            Cluster{Discovery: served, Client: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)}, Kinds: []schema.GroupVersionKind{bucket, cache, cache}},
+				Remote:           Cluster{Discovery: &mockDiscovery{err: errBoom}, Kinds: []schema.GroupVersionKind{mysql}},
+				Namespace:        "crossplane-system",
+				ClusterName:      "Cool_Cluster",
+				NamespaceMapping: map[string]string{"team-a": "Team-A"},
+				OwnerLabels:      map[string]string{"example.org/owner": "cool cluster"},
+			},
+			want: []string{
+				`cluster name "Cool_Cluster": ` + validation.IsDNS1123Label("Cool_Cluster")[0],
+				"local cluster: cannot discover the kinds of cache.example.org/v1alpha1: boom",
+				"local cluster: cannot get agent namespace crossplane-system: boom",
+				"local cluster: example.org/v1alpha1, Kind=Bucket is not served",
+				`namespace mapping team-a=Team-A: namespace "Team-A": ` + validation.IsDNS1123Label("Team-A")[0],
+				"remote cluster: cannot be reached: boom",
+				"remote owner label example.org/owner=cool cluster: value: " + validation.IsValidLabelValue("cool cluster")[0],
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Check(context.Background(), tc.c)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nCheck(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
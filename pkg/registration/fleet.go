@@ -0,0 +1,150 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registration
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// FleetName is the name of the ConfigMap the status of the fleet is
+	// published to. It doesn't start with NamePrefix so that it cannot clash
+	// with the registration object of a cluster.
+	FleetName = "crossplane-fleet"
+
+	// Keys of the fleet status in the data of its ConfigMap.
+	KeyClusters           = "clusters"
+	KeyUnhealthyClusters  = "unhealthyClusters"
+	KeyAgentVersions      = "agentVersions"
+	KeyKubernetesVersions = "kubernetesVersions"
+	KeyVersionSkew        = "versionSkew"
+
+	errListRegistrations = "cannot list registrations"
+	errPublishFleet      = "cannot publish fleet status"
+)
+
+// A Fleet is the aggregated state of all agents that publish snapshots.
+type Fleet struct {
+	Clusters           int
+	Claims             int
+	ClaimsFailing      int
+	UnhealthyClusters  []string
+	AgentVersions      map[string]int
+	KubernetesVersions map[string]int
+}
+
+// Data returns the fleet status in the form it's published.
+func (f Fleet) Data() map[string]string {
+	return map[string]string{
+		KeyClusters:           strconv.Itoa(f.Clusters),
+		KeyClaims:             strconv.Itoa(f.Claims),
+		KeyClaimsFailing:      strconv.Itoa(f.ClaimsFailing),
+		KeyUnhealthyClusters:  strings.Join(f.UnhealthyClusters, ","),
+		KeyAgentVersions:      FormatCounts(f.AgentVersions),
+		KeyKubernetesVersions: FormatCounts(f.KubernetesVersions),
+		KeyVersionSkew:        strconv.FormatBool(len(f.AgentVersions) > 1),
+		KeyUpdated:            time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Aggregate the supplied registration objects into a Fleet. Clusters whose
+// last snapshot is older than maxAge are considered unhealthy and only their
+// registration is counted.
+func Aggregate(regs []corev1.ConfigMap, maxAge time.Duration, now time.Time) Fleet {
+	f := Fleet{AgentVersions: map[string]int{}, KubernetesVersions: map[string]int{}}
+	for _, cm := range regs {
+		cluster, ok := cm.Data[KeyCluster]
+		if !ok {
+			continue
+		}
+		f.Clusters++
+		updated, err := time.Parse(time.RFC3339, cm.Data[KeyUpdated])
+		if err != nil || now.Sub(updated) > maxAge {
+			f.UnhealthyClusters = append(f.UnhealthyClusters, cluster)
+			continue
+		}
+		claims, _ := strconv.Atoi(cm.Data[KeyClaims])
+		failing, _ := strconv.Atoi(cm.Data[KeyClaimsFailing])
+		f.Claims += claims
+		f.ClaimsFailing += failing
+		if v := cm.Data[KeyAgentVersion]; v != "" {
+			f.AgentVersions[v]++
+		}
+		if v := cm.Data[KeyKubernetesVersion]; v != "" {
+			f.KubernetesVersions[v]++
+		}
+	}
+	sort.Strings(f.UnhealthyClusters)
+	return f
+}
+
+// NewAggregator returns a new *Aggregator.
+func NewAggregator(r client.Reader, a runtimeresource.Applicator, namespace string, maxAge, period time.Duration, log logging.Logger) *Aggregator {
+	return &Aggregator{reader: r, client: a, namespace: namespace, maxAge: maxAge, period: period, log: log}
+}
+
+// An Aggregator periodically aggregates the registration objects of all
+// agents in a namespace of the remote cluster into the status of the fleet.
+type Aggregator struct {
+	reader    client.Reader
+	client    runtimeresource.Applicator
+	namespace string
+	maxAge    time.Duration
+	period    time.Duration
+	log       logging.Logger
+}
+
+// Start aggregating until the supplied channel is closed.
+func (a *Aggregator) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(a.period)
+	defer t.Stop()
+	for {
+		if err := a.Aggregate(context.Background()); err != nil {
+			a.log.Debug("Cannot aggregate fleet status", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Aggregate the registration objects and publish the status of the fleet.
+func (a *Aggregator) Aggregate(ctx context.Context) error {
+	l := &corev1.ConfigMapList{}
+	if err := a.reader.List(ctx, l, client.InNamespace(a.namespace), client.MatchingLabels{resource.LabelKeyManagedBy: resource.LabelValueManagedBy}); err != nil {
+		return errors.Wrap(err, errListRegistrations)
+	}
+	f := Aggregate(l.Items, a.maxAge, time.Now())
+	nn := types.NamespacedName{Namespace: a.namespace, Name: FleetName}
+	return errors.Wrap(resource.PublishConfigMap(ctx, a.client, nn, f.Data()), errPublishFleet)
+}
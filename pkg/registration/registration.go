@@ -0,0 +1,246 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registration publishes a condensed snapshot of the state of an agent
+// into its registration object in the remote cluster, so that a fleet of
+// agents can be watched from the remote cluster without scraping each of them.
+package registration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// NamePrefix is the prefix of the name of the registration object of an
+	// agent in the remote cluster. It's followed by the name of the cluster.
+	NamePrefix = "crossplane-agent-"
+
+	// Keys of the snapshot in the data of the registration object.
+	KeyCluster           = "cluster"
+	KeyAgentVersion      = "agentVersion"
+	KeyKubernetesVersion = "kubernetesVersion"
+	KeyClaims            = "claims"
+	KeyClaimsFailing     = "claimsFailing"
+	KeyClaimsByKind      = "claimsByKind"
+	KeyUpdated           = "updated"
+
+	errPublish = "cannot publish snapshot"
+)
+
+// Name returns the name of the registration object of the given cluster.
+func Name(cluster string) string {
+	return NamePrefix + cluster
+}
+
+type claimState struct {
+	kind    string
+	failed  bool
+	reason  string
+	message string
+	seen    time.Time
+}
+
+// NewClaimTracker returns a new *ClaimTracker that forgets claims that it
+// hasn't been told about within the given period.
+func NewClaimTracker(expiry time.Duration) *ClaimTracker {
+	return &ClaimTracker{expiry: expiry, now: time.Now, claims: map[types.UID]claimState{}, synced: map[string]time.Time{}}
+}
+
+// A ClaimTracker keeps the last sync result of every claim it's told about,
+// and the last time a claim of each kind was synced successfully.
+type ClaimTracker struct {
+	expiry time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	claims map[types.UID]claimState
+	synced map[string]time.Time
+}
+
+// ObserveSync records the result of the last sync of the supplied claim.
+func (t *ClaimTracker) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if meta.WasDeleted(c) {
+		delete(t.claims, c.GetUID())
+		return
+	}
+	cond := c.GetCondition(resource.TypeAgentSync)
+	s := claimState{
+		kind:    c.GetObjectKind().GroupVersionKind().Kind,
+		failed:  cond.Status != corev1.ConditionTrue,
+		reason:  string(cond.Reason),
+		message: cond.Message,
+		seen:    t.now(),
+	}
+	if !s.failed {
+		t.synced[s.kind] = s.seen
+	}
+	t.claims[c.GetUID()] = s
+}
+
+// forget the claims that weren't observed within the expiry. The lock must be
+// held by the caller.
+func (t *ClaimTracker) forget() {
+	cutoff := t.now().Add(-t.expiry)
+	for uid, s := range t.claims {
+		if s.seen.Before(cutoff) {
+			delete(t.claims, uid)
+		}
+	}
+}
+
+// Counts returns the number of claims, the number of claims whose last sync
+// failed and the number of claims of each kind.
+func (t *ClaimTracker) Counts() (total, failing int, byKind map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forget()
+	byKind = map[string]int{}
+	for _, s := range t.claims {
+		total++
+		byKind[s.kind]++
+		if s.failed {
+			failing++
+		}
+	}
+	return total, failing, byKind
+}
+
+// A Summary of the sync state of the claims a ClaimTracker was told about.
+type Summary struct {
+	// LastSynced is the last time a claim of each kind was synced
+	// successfully.
+	LastSynced map[string]time.Time
+
+	// Errors are the number of claims of each kind that are not synced, by
+	// the reason of their AgentSynced condition, along with the message of
+	// one of them.
+	Errors []string
+}
+
+// Summary returns the sync state of the claims the ClaimTracker was told
+// about.
+func (t *ClaimTracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forget()
+	type failure struct {
+		count   int
+		message string
+	}
+	failures := map[string]*failure{}
+	for _, s := range t.claims {
+		if !s.failed {
+			continue
+		}
+		key := fmt.Sprintf("%s %s", s.kind, s.reason)
+		if failures[key] == nil {
+			failures[key] = &failure{message: s.message}
+		}
+		failures[key].count++
+	}
+	sum := Summary{LastSynced: make(map[string]time.Time, len(t.synced))}
+	for kind, at := range t.synced {
+		sum.LastSynced[kind] = at
+	}
+	for key, f := range failures {
+		line := fmt.Sprintf("%s: %d", key, f.count)
+		if f.message != "" {
+			line += ": " + f.message
+		}
+		sum.Errors = append(sum.Errors, line)
+	}
+	sort.Strings(sum.Errors)
+	return sum
+}
+
+// NewPublisher returns a new *Publisher.
+func NewPublisher(t *ClaimTracker, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, log logging.Logger) *Publisher {
+	return &Publisher{tracker: t, client: a, name: nn, period: period, log: log, static: map[string]string{}}
+}
+
+// A Publisher periodically writes a snapshot of the state of the agent into its
+// registration object.
+type Publisher struct {
+	tracker *ClaimTracker
+	client  runtimeresource.Applicator
+	name    types.NamespacedName
+	period  time.Duration
+	log     logging.Logger
+
+	// static data, like versions, that's published with every snapshot.
+	static map[string]string
+}
+
+// Set data that doesn't change over the lifetime of the agent, like versions.
+// It must be called before the Publisher is started.
+func (p *Publisher) Set(key, value string) {
+	p.static[key] = value
+}
+
+// Start publishing until the supplied channel is closed.
+func (p *Publisher) Start(stop <-chan struct{}) error {
+	return resource.PublishEvery(stop, p.period, p.Publish, p.log)
+}
+
+// Publish the current snapshot.
+func (p *Publisher) Publish(ctx context.Context) error {
+	return errors.Wrap(resource.PublishConfigMap(ctx, p.client, p.name, p.Snapshot()), errPublish)
+}
+
+// Snapshot returns the current state of the agent.
+func (p *Publisher) Snapshot() map[string]string {
+	total, failing, byKind := p.tracker.Counts()
+	data := map[string]string{
+		KeyClaims:        strconv.Itoa(total),
+		KeyClaimsFailing: strconv.Itoa(failing),
+		KeyClaimsByKind:  FormatCounts(byKind),
+		KeyUpdated:       time.Now().UTC().Format(time.RFC3339),
+	}
+	for k, v := range p.static {
+		data[k] = v
+	}
+	return data
+}
+
+// FormatCounts formats the supplied counts as a sorted, comma-separated list of
+// key=count pairs.
+func FormatCounts(counts map[string]int) string {
+	pairs := make([]string, 0, len(counts))
+	for k, n := range counts {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", k, n))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
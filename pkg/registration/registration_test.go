@@ -0,0 +1,196 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// A claim of the supplied kind with the supplied AgentSynced condition that
+// is observed by a ClaimTracker.
+type observed struct {
+	kind    string
+	uid     types.UID
+	synced  v1alpha1.Condition
+	deleted bool
+}
+
+func TestClaimTracker(t *testing.T) {
+	errBoom := errors.New("boom")
+	type want struct {
+		total   int
+		failing int
+		byKind  string
+	}
+	now := time.Now()
+	cases := map[string]struct {
+		reason string
+		claims []observed
+		after  time.Duration
+		want   want
+	}{
+		"Counts": {
+			reason: "Claims should be counted by their kind and last sync result",
+			claims: []observed{
+				{kind: "Database", uid: "a", synced: resource.AgentSyncSuccess()},
+				{kind: "Database", uid: "b", synced: resource.AgentSyncError(errBoom)},
+				{kind: "Bucket", uid: "c", synced: resource.AgentSyncSuccess()},
+				{kind: "Database", uid: "b", synced: resource.AgentSyncSuccess()},
+			},
+			want: want{total: 3, byKind: "Bucket=1,Database=2"},
+		},
+		"Deleted": {
+			reason: "Deleted claims should not be counted",
+			claims: []observed{
+				{kind: "Database", uid: "a", synced: resource.AgentSyncError(errBoom)},
+				{kind: "Database", uid: "a", synced: resource.AgentSyncError(errBoom), deleted: true},
+			},
+			want: want{},
+		},
+		"Expired": {
+			reason: "Claims that weren't synced for a while should be forgotten",
+			claims: []observed{{kind: "Database", uid: "a", synced: resource.AgentSyncSuccess()}},
+			after:  time.Hour,
+			want:   want{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr := NewClaimTracker(10 * time.Minute)
+			tr.now = func() time.Time { return now }
+			for _, o := range tc.claims {
+				c := claim.New(claim.WithGroupVersionKind(schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: o.kind}))
+				c.SetUID(o.uid)
+				c.SetConditions(o.synced)
+				if o.deleted {
+					c.SetDeletionTimestamp(&metav1.Time{Time: now})
+				}
+				tr.ObserveSync(context.Background(), c)
+			}
+			tr.now = func() time.Time { return now.Add(tc.after) }
+			total, failing, byKind := tr.Counts()
+			got := want{total: total, failing: failing, byKind: FormatCounts(byKind)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nCounts(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClaimTrackerSummary(t *testing.T) {
+	errBoom := errors.New("boom")
+	now := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		reason string
+		claims []observed
+		want   Summary
+	}{
+		"Synced": {
+			reason: "The last successful sync of every kind should be reported without errors",
+			claims: []observed{
+				{kind: "Database", uid: "a", synced: resource.AgentSyncSuccess()},
+				{kind: "Bucket", uid: "b", synced: resource.AgentSyncSuccess()},
+			},
+			want: Summary{LastSynced: map[string]time.Time{"Database": now, "Bucket": now}},
+		},
+		"Errors": {
+			reason: "Claims that are not synced should be counted by kind and reason",
+			claims: []observed{
+				{kind: "Database", uid: "a", synced: resource.AgentSyncError(errBoom)},
+				{kind: "Database", uid: "b", synced: resource.AgentSyncError(errBoom)},
+				{kind: "Database", uid: "c", synced: resource.AgentSyncSuccess()},
+				{kind: "Bucket", uid: "d", synced: resource.AgentSyncPendingApproval()},
+			},
+			want: Summary{
+				LastSynced: map[string]time.Time{"Database": now},
+				Errors: []string{
+					"Bucket PendingApproval: 1: waiting for the " + resource.AnnotationKeyApproved + " annotation to be set to true",
+					"Database Error: 2: boom",
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr := NewClaimTracker(10 * time.Minute)
+			tr.now = func() time.Time { return now }
+			for _, o := range tc.claims {
+				c := claim.New(claim.WithGroupVersionKind(schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: o.kind}))
+				c.SetUID(o.uid)
+				c.SetConditions(o.synced)
+				if o.deleted {
+					c.SetDeletionTimestamp(&metav1.Time{Time: now})
+				}
+				tr.ObserveSync(context.Background(), c)
+			}
+			if diff := cmp.Diff(tc.want, tr.Summary()); diff != "" {
+				t.Errorf("\nReason: %s\nSummary(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	now := time.Now()
+	fresh := now.Add(-time.Minute).UTC().Format(time.RFC3339)
+	stale := now.Add(-time.Hour).UTC().Format(time.RFC3339)
+	cases := map[string]struct {
+		reason string
+		regs   []corev1.ConfigMap
+		want   Fleet
+	}{
+		"Fleet": {
+			reason: "Snapshots of healthy clusters should be summed up and stale ones reported as unhealthy",
+			regs: []corev1.ConfigMap{
+				{Data: map[string]string{KeyCluster: "a", KeyUpdated: fresh, KeyClaims: "3", KeyClaimsFailing: "1", KeyAgentVersion: "v0.1.0"}},
+				{Data: map[string]string{KeyCluster: "b", KeyUpdated: fresh, KeyClaims: "2", KeyAgentVersion: "v0.2.0", KeyKubernetesVersion: "v1.18.6"}},
+				{Data: map[string]string{KeyCluster: "c", KeyUpdated: stale, KeyClaims: "5", KeyAgentVersion: "v0.1.0"}},
+				{Data: map[string]string{KeyClusters: "3"}},
+			},
+			want: Fleet{
+				Clusters:           3,
+				Claims:             5,
+				ClaimsFailing:      1,
+				UnhealthyClusters:  []string{"c"},
+				AgentVersions:      map[string]int{"v0.1.0": 1, "v0.2.0": 1},
+				KubernetesVersions: map[string]int{"v1.18.6": 1},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Aggregate(tc.regs, 5*time.Minute, now)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nAggregate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
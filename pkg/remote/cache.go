@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewTTLListCache returns a new *TTLListCache that keeps the results of List
+// calls made through the given client.Reader for the given duration.
+func NewTTLListCache(r client.Reader, ttl time.Duration) *TTLListCache {
+	return &TTLListCache{
+		Reader:  r,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]listEntry{},
+	}
+}
+
+type listEntry struct {
+	list    runtime.Object
+	expires time.Time
+}
+
+// A TTLListCache serves List calls from the result of an earlier call with the
+// same list type and options until it expires. It's meant for kinds that
+// change rarely but are listed on every reconcile, like Compositions and
+// CompositeResourceDefinitions. Get calls are passed to the wrapped reader.
+type TTLListCache struct {
+	client.Reader
+
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]listEntry
+}
+
+// List fills the given list from the cache if a result younger than the TTL
+// exists, and lists through the wrapped reader otherwise.
+func (c *TTLListCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if c.ttl <= 0 {
+		return c.Reader.List(ctx, list, opts...)
+	}
+	key := listKey(list, opts...)
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return copyInto(list, e.list)
+	}
+	if err := c.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.entries[key] = listEntry{list: list.DeepCopyObject(), expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return nil
+}
+
+// Invalidate drops all cached results so that the next List calls are served
+// by the wrapped reader.
+func (c *TTLListCache) Invalidate() {
+	c.mu.Lock()
+	c.entries = map[string]listEntry{}
+	c.mu.Unlock()
+}
+
+func listKey(list runtime.Object, opts ...client.ListOption) string {
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+	return fmt.Sprintf("%T/%s/%s/%v/%v", list, list.GetObjectKind().GroupVersionKind(), lo.Namespace, lo.LabelSelector, lo.FieldSelector)
+}
+
+func copyInto(dst, src runtime.Object) error {
+	dv := reflect.ValueOf(dst)
+	sv := reflect.ValueOf(src.DeepCopyObject())
+	if dv.Kind() != reflect.Ptr || dv.Type() != sv.Type() {
+		return errors.Errorf("cannot copy cached %T into %T", src, dst)
+	}
+	dv.Elem().Set(sv.Elem())
+	return nil
+}
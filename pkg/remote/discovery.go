@@ -0,0 +1,212 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+const (
+	errGetSecret         = "cannot get kubeconfig secret"
+	errFmtNoKey          = "kubeconfig secret has no %s key"
+	errFmtNoFile         = "kubeconfig refers to file %s that is not in its secret"
+	errParseKubeconfig   = "cannot parse kubeconfig"
+	errFmtUnknownSource  = "unknown kubeconfig source %s"
+	errBuildClientConfig = "cannot build client config from kubeconfig"
+	errConfigChanged     = "discovered kubeconfig of the remote cluster changed, restarting to use it"
+)
+
+// A KubeconfigSource is where the kubeconfig of the remote cluster is read
+// from.
+type KubeconfigSource string
+
+// Kubeconfig sources.
+const (
+	// KubeconfigSourceFile reads the kubeconfig from a file that's given.
+	KubeconfigSourceFile KubeconfigSource = "file"
+
+	// KubeconfigSourceOCM reads the kubeconfig of the hub from the Secret the
+	// Open Cluster Management klusterlet keeps it in.
+	KubeconfigSourceOCM KubeconfigSource = "ocm"
+
+	// KubeconfigSourceCAPI reads the kubeconfig from the Secret Cluster API
+	// keeps it in for a Cluster.
+	KubeconfigSourceCAPI KubeconfigSource = "capi"
+)
+
+// DefaultOCMHubKubeconfigSecret is the Secret the klusterlet of Open Cluster
+// Management keeps the kubeconfig of the hub in.
+var DefaultOCMHubKubeconfigSecret = types.NamespacedName{Namespace: "open-cluster-management-agent", Name: "hub-kubeconfig-secret"}
+
+const (
+	keyOCMKubeconfig  = "kubeconfig"
+	keyCAPIKubeconfig = "value"
+	suffixCAPISecret  = "-kubeconfig"
+)
+
+// DiscoverConfig returns the config of the remote cluster that is read from the
+// supplied source in the local cluster. The supplied name is the Secret of
+// the klusterlet for KubeconfigSourceOCM, and the Cluster for
+// KubeconfigSourceCAPI.
+func DiscoverConfig(ctx context.Context, local client.Reader, src KubeconfigSource, nn types.NamespacedName) (*rest.Config, error) {
+	switch src {
+	case KubeconfigSourceOCM:
+		s := &corev1.Secret{}
+		if err := local.Get(ctx, nn, s); err != nil {
+			return nil, errors.Wrap(err, errGetSecret)
+		}
+		return ConfigFromSecret(s, keyOCMKubeconfig)
+	case KubeconfigSourceCAPI:
+		s := &corev1.Secret{}
+		if err := local.Get(ctx, types.NamespacedName{Namespace: nn.Namespace, Name: nn.Name + suffixCAPISecret}, s); err != nil {
+			return nil, errors.Wrap(err, errGetSecret)
+		}
+		return ConfigFromSecret(s, keyCAPIKubeconfig)
+	default:
+		return nil, errors.Errorf(errFmtUnknownSource, src)
+	}
+}
+
+// ConfigFromSecret returns the config in the kubeconfig that is under the
+// supplied key of the supplied Secret. The files that the kubeconfig refers
+// to, like the client certificate the klusterlet keeps next to it, are read
+// from the keys of the Secret with the same name.
+func ConfigFromSecret(s *corev1.Secret, key string) (*rest.Config, error) {
+	kc, ok := s.Data[key]
+	if !ok {
+		return nil, errors.Errorf(errFmtNoKey, key)
+	}
+	cfg, err := clientcmd.Load(kc)
+	if err != nil {
+		return nil, errors.Wrap(err, errParseKubeconfig)
+	}
+	file := func(path string) ([]byte, error) {
+		b, ok := s.Data[filepath.Base(path)]
+		if !ok {
+			return nil, errors.Errorf(errFmtNoFile, path)
+		}
+		return b, nil
+	}
+	for _, c := range cfg.Clusters {
+		if c.CertificateAuthority == "" {
+			continue
+		}
+		if c.CertificateAuthorityData, err = file(c.CertificateAuthority); err != nil {
+			return nil, err
+		}
+		c.CertificateAuthority = ""
+	}
+	for _, a := range cfg.AuthInfos {
+		if a.ClientCertificate != "" {
+			if a.ClientCertificateData, err = file(a.ClientCertificate); err != nil {
+				return nil, err
+			}
+			a.ClientCertificate = ""
+		}
+		if a.ClientKey != "" {
+			if a.ClientKeyData, err = file(a.ClientKey); err != nil {
+				return nil, err
+			}
+			a.ClientKey = ""
+		}
+	}
+	rc, err := clientcmd.NewDefaultClientConfig(*cfg, &clientcmd.ConfigOverrides{}).ClientConfig()
+	return rc, errors.Wrap(err, errBuildClientConfig)
+}
+
+// NewKubeconfigWatcher returns a new *KubeconfigWatcher of the kubeconfig that
+// the supplied config was discovered from.
+func NewKubeconfigWatcher(local client.Reader, src KubeconfigSource, nn types.NamespacedName, cfg *rest.Config, period time.Duration, log logging.Logger) *KubeconfigWatcher {
+	return &KubeconfigWatcher{
+		local:  local,
+		source: src,
+		name:   nn,
+		config: cfg,
+		period: period,
+		log:    log,
+	}
+}
+
+// A KubeconfigWatcher periodically discovers the kubeconfig of the remote
+// cluster again, since the klusterlet of Open Cluster Management rotates its
+// client certificate and Cluster API may rotate its credentials. The clients
+// of the agent are built from the kubeconfig only when it starts, so the
+// KubeconfigWatcher stops with an error, which stops the agent so that it's
+// restarted, once it changes.
+type KubeconfigWatcher struct {
+	local  client.Reader
+	source KubeconfigSource
+	name   types.NamespacedName
+	config *rest.Config
+	period time.Duration
+	log    logging.Logger
+}
+
+// NeedLeaderElection returns false since the replicas that aren't the leader
+// talk to the remote cluster too, and have to be able to once they take over.
+func (w *KubeconfigWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start watching until the supplied channel is closed or the kubeconfig
+// changes.
+func (w *KubeconfigWatcher) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(w.period)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+		changed, err := w.Changed(context.Background())
+		if err != nil {
+			w.log.Debug("Cannot discover kubeconfig of the remote cluster", "error", err)
+			continue
+		}
+		if changed {
+			w.log.Info("Discovered kubeconfig of the remote cluster changed", "source", w.source, "ref", w.name.String())
+			return errors.New(errConfigChanged)
+		}
+	}
+}
+
+// Changed returns true if the kubeconfig discovered now points to another
+// cluster, or authenticates differently, than the one the agent started with.
+func (w *KubeconfigWatcher) Changed(ctx context.Context) (bool, error) {
+	cfg, err := DiscoverConfig(ctx, w.local, w.source, w.name)
+	if err != nil {
+		return false, err
+	}
+	return cfg.Host != w.config.Host ||
+		cfg.BearerToken != w.config.BearerToken ||
+		cfg.Username != w.config.Username ||
+		cfg.Password != w.config.Password ||
+		!reflect.DeepEqual(cfg.TLSClientConfig, w.config.TLSClientConfig), nil
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package remote keeps track of the connection to the remote cluster.
+package remote
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	kmeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/metrics"
+)
+
+const (
+	probeTimeout = 10 * time.Second
+
+	errNewDiscovery = "cannot create discovery client"
+	errList         = "cannot list objects to resync"
+
+	errFmtUnreachable = "remote cluster has been unreachable for %s"
+)
+
+// A MonitorOption configures a Monitor.
+type MonitorOption func(*Monitor)
+
+// WithLogger specifies how the Monitor should log messages.
+func WithLogger(l logging.Logger) MonitorOption {
+	return func(m *Monitor) {
+		m.log = l
+	}
+}
+
+// WithMetrics specifies the metrics the Monitor should report to.
+func WithMetrics(c *metrics.Connectivity) MonitorOption {
+	return func(m *Monitor) {
+		m.metrics = c
+	}
+}
+
+// WithProbe specifies the function the Monitor calls to check whether the
+// remote cluster is reachable.
+func WithProbe(fn func() error) MonitorOption {
+	return func(m *Monitor) {
+		m.probe = fn
+	}
+}
+
+// WithProbeFailureHandler specifies a function the Monitor calls every time
+// the remote cluster cannot be reached, e.g. to drop connections that may be
+// dead.
+func WithProbeFailureHandler(fn func()) MonitorOption {
+	return func(m *Monitor) {
+		m.onFailure = fn
+	}
+}
+
+// WithDisconnectedAfter specifies how long the remote cluster may be
+// unreachable before the Monitor reports that the agent is disconnected from
+// it. The agent is never reported as disconnected if it's zero.
+func WithDisconnectedAfter(d time.Duration) MonitorOption {
+	return func(m *Monitor) {
+		m.disconnectedAfter = d
+	}
+}
+
+// NewMonitor returns a new *Monitor that probes the api-server the supplied
+// config points to once in every period.
+func NewMonitor(cfg *rest.Config, period time.Duration, o ...MonitorOption) (*Monitor, error) {
+	c := rest.CopyConfig(cfg)
+	c.Timeout = probeTimeout
+	d, err := discovery.NewDiscoveryClientForConfig(c)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewDiscovery)
+	}
+	m := &Monitor{
+		period:    period,
+		log:       logging.NewNopLogger(),
+		connected: true,
+		probe: func() error {
+			_, err := d.ServerVersion()
+			return err
+		},
+	}
+	for _, f := range o {
+		f(m)
+	}
+	return m, nil
+}
+
+// A Monitor periodically checks whether the remote cluster is reachable and
+// notifies its subscribers when it becomes reachable after an outage.
+//
+// Informers re-establish their watches on their own, re-listing if the
+// resource version they were at is gone, but anything that failed while the
+// remote cluster was unreachable is retried only with backoff. Subscribers
+// of the Monitor use the reconnect to reconcile everything right away.
+type Monitor struct {
+	period  time.Duration
+	probe   func() error
+	log     logging.Logger
+	metrics *metrics.Connectivity
+
+	disconnectedAfter time.Duration
+
+	// onFailure is called without the lock held since it may take a while.
+	onFailure func()
+
+	mu           sync.Mutex
+	connected    bool
+	disconnected time.Time
+	probed       time.Time
+	subscribers  []func()
+	recoveries   []func()
+}
+
+// OnReconnect registers a function that is called every time the remote
+// cluster becomes reachable after an outage.
+func (m *Monitor) OnReconnect(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// OnRecover registers a function that is called every time the remote cluster
+// becomes reachable after the agent was disconnected from it.
+func (m *Monitor) OnRecover(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recoveries = append(m.recoveries, fn)
+}
+
+// Disconnected returns true if the remote cluster has been unreachable for
+// long enough that the agent is disconnected from it, together with when it
+// became unreachable and when it's probed next.
+func (m *Monitor) Disconnected() (bool, time.Time, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connected || m.disconnectedAfter == 0 || time.Since(m.disconnected) < m.disconnectedAfter {
+		return false, time.Time{}, time.Time{}
+	}
+	return true, m.disconnected, m.probed.Add(m.period)
+}
+
+// Unreachable returns how long the remote cluster has been unreachable, or
+// zero if it's reachable.
+func (m *Monitor) Unreachable() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connected {
+		return 0
+	}
+	return time.Since(m.disconnected)
+}
+
+// ReadyCheck returns a readiness check that fails while the remote cluster
+// has been unreachable for longer than the supplied threshold. It never fails
+// if the threshold is zero.
+func (m *Monitor) ReadyCheck(threshold time.Duration) healthz.Checker {
+	return func(_ *http.Request) error {
+		if d := m.Unreachable(); threshold > 0 && d > threshold {
+			return errors.Errorf(errFmtUnreachable, d.Round(time.Second))
+		}
+		return nil
+	}
+}
+
+// NeedLeaderElection returns false since the replicas that aren't the leader
+// probe the remote cluster too, so that they don't report themselves ready
+// to take over while it's unreachable from them.
+func (m *Monitor) NeedLeaderElection() bool {
+	return false
+}
+
+// Start probing until the supplied channel is closed.
+func (m *Monitor) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(m.period)
+	defer t.Stop()
+	for {
+		err := m.probe()
+		if err != nil && m.onFailure != nil {
+			m.onFailure()
+		}
+		m.observe(err)
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Monitor) observe(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probed = time.Now()
+
+	switch {
+	case err != nil && m.connected:
+		m.log.Info("Remote cluster is unreachable", "error", err)
+		m.connected = false
+		m.disconnected = time.Now()
+		if m.metrics != nil {
+			m.metrics.Connected.Set(0)
+			m.metrics.Disconnects.Inc()
+		}
+	case err == nil && !m.connected:
+		d := time.Since(m.disconnected)
+		m.log.Info("Remote cluster is reachable again", "disconnected-for", d.String())
+		m.connected = true
+		if m.metrics != nil {
+			m.metrics.Connected.Set(1)
+			m.metrics.DisconnectedSeconds.Observe(d.Seconds())
+		}
+		for _, fn := range m.subscribers {
+			fn()
+		}
+		if m.disconnectedAfter > 0 && d >= m.disconnectedAfter {
+			for _, fn := range m.recoveries {
+				fn()
+			}
+		}
+	case err == nil && m.metrics != nil:
+		m.metrics.Connected.Set(1)
+	}
+}
+
+// NewResyncSource returns a source.Source that emits a generic event for every
+// object that the supplied reader lists every time the Monitor notices that
+// the remote cluster is reachable again.
+func NewResyncSource(m *Monitor, r client.Reader, newList func() runtime.Object, log logging.Logger) source.Source {
+	return source.Func(func(h handler.EventHandler, q workqueue.RateLimitingInterface, pp ...predicate.Predicate) error {
+		m.OnReconnect(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+			defer cancel()
+			l := newList()
+			if err := r.List(ctx, l); err != nil {
+				log.Debug(errList, "error", err)
+				return
+			}
+			items, err := kmeta.ExtractList(l)
+			if err != nil {
+				log.Debug(errList, "error", err)
+				return
+			}
+			for _, o := range items {
+				a, err := kmeta.Accessor(o)
+				if err != nil {
+					continue
+				}
+				evt := event.GenericEvent{Meta: a, Object: o}
+				if !allow(evt, pp) {
+					continue
+				}
+				h.Generic(evt, q)
+			}
+			log.Debug("Resynced after reconnect", "count", len(items))
+		})
+		return nil
+	})
+}
+
+func allow(evt event.GenericEvent, pp []predicate.Predicate) bool {
+	for _, p := range pp {
+		if !p.Generic(evt) {
+			return false
+		}
+	}
+	return true
+}
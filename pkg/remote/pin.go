@@ -0,0 +1,180 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errNoCA          = "pinned CA bundle has no certificates"
+	errFmtParseCert  = "cannot parse certificate presented by the remote cluster: %s"
+	errFmtUntrusted  = "certificate presented by the remote cluster is not trusted: %s"
+	errNoPinnedSPKI  = "no certificate presented by the remote cluster matches the pinned SPKI hashes"
+	errNotTLS        = "connection to the remote cluster does not use TLS but its certificate is pinned"
+	errNotTransport  = "transport of the remote cluster cannot be pinned"
+	prefixSPKISHA256 = "sha256/"
+)
+
+// TLSPins are what the certificate of the remote cluster is verified against,
+// in addition to and independent of what its kubeconfig says, so that a
+// kubeconfig that was tampered with cannot point the agent to another
+// api-server.
+type TLSPins struct {
+	// CABundle is PEM encoded certificates one of which has to sign the
+	// certificate of the remote cluster.
+	CABundle []byte
+
+	// SPKIHashes are base64 encoded SHA-256 hashes of the SubjectPublicKeyInfo
+	// of certificates, optionally prefixed with sha256/, one of which has to
+	// be in a verified chain of the certificate of the remote cluster. The
+	// chains are built from the pinned CA bundle if there is one, and from the
+	// CA of the kubeconfig or the system roots otherwise.
+	SPKIHashes []string
+}
+
+// Empty returns true if nothing is pinned.
+func (p TLSPins) Empty() bool {
+	return len(p.CABundle) == 0 && len(p.SPKIHashes) == 0
+}
+
+// Validate returns an error if the pins cannot be used.
+func (p TLSPins) Validate() error {
+	_, err := p.verifier()
+	return err
+}
+
+// A PinMismatchError is returned when the remote cluster presents a
+// certificate that doesn't match the pins.
+type PinMismatchError struct {
+	msg string
+}
+
+func (e *PinMismatchError) Error() string {
+	return e.msg
+}
+
+// IsPinMismatch returns true if the supplied error, or an error it wraps, is a
+// *PinMismatchError.
+func IsPinMismatch(err error) bool {
+	var pe *PinMismatchError
+	return stderrors.As(err, &pe)
+}
+
+// verifier returns a function that verifies the certificates presented in a
+// TLS handshake against the pins. The SPKI hashes are only matched against the
+// chains the certificate of the remote cluster was verified with, since any
+// certificate can be presented alongside it.
+func (p TLSPins) verifier() (func(raw [][]byte, verified [][]*x509.Certificate) error, error) {
+	var roots *x509.CertPool
+	if len(p.CABundle) > 0 {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(p.CABundle) {
+			return nil, errors.New(errNoCA)
+		}
+	}
+	hashes := map[string]bool{}
+	for _, h := range p.SPKIHashes {
+		hashes[strings.TrimPrefix(h, prefixSPKISHA256)] = true
+	}
+	return func(raw [][]byte, verified [][]*x509.Certificate) error {
+		certs := make([]*x509.Certificate, len(raw))
+		for i, r := range raw {
+			c, err := x509.ParseCertificate(r)
+			if err != nil {
+				return &PinMismatchError{msg: errors.Errorf(errFmtParseCert, err).Error()}
+			}
+			certs[i] = c
+		}
+		if len(certs) == 0 {
+			return &PinMismatchError{msg: errNoPinnedSPKI}
+		}
+		// The handshake has already verified the certificate against the CA of
+		// the kubeconfig, unless it skipped verification. It's verified again
+		// against the pinned CA bundle if there is one, and against the system
+		// roots if there's nothing else.
+		chains := verified
+		if roots != nil || len(chains) == 0 {
+			o := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+			for _, c := range certs[1:] {
+				o.Intermediates.AddCert(c)
+			}
+			var err error
+			if chains, err = certs[0].Verify(o); err != nil {
+				return &PinMismatchError{msg: errors.Errorf(errFmtUntrusted, err).Error()}
+			}
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+		for _, chain := range chains {
+			for _, c := range chain {
+				sum := sha256.Sum256(c.RawSubjectPublicKeyInfo)
+				if hashes[base64.StdEncoding.EncodeToString(sum[:])] {
+					return nil
+				}
+			}
+		}
+		return &PinMismatchError{msg: errNoPinnedSPKI}
+	}, nil
+}
+
+// pin makes the supplied transport verify the certificate of the remote
+// cluster against the pins. It fails closed: requests that cannot be verified
+// are not sent.
+func (p TLSPins) pin(rt http.RoundTripper) http.RoundTripper {
+	ht, ok := rt.(*http.Transport)
+	if !ok {
+		return failingRoundTripper{err: &PinMismatchError{msg: errNotTransport}}
+	}
+	verify, err := p.verifier()
+	if err != nil {
+		return failingRoundTripper{err: err}
+	}
+	if ht.TLSClientConfig == nil {
+		ht.TLSClientConfig = &tls.Config{} // nolint:gosec
+	}
+	ht.TLSClientConfig.VerifyPeerCertificate = verify
+	return httpsOnlyRoundTripper{RoundTripper: ht}
+}
+
+type failingRoundTripper struct {
+	err error
+}
+
+func (f failingRoundTripper) RoundTrip(_ *http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+type httpsOnlyRoundTripper struct {
+	http.RoundTripper
+}
+
+func (h httpsOnlyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, &PinMismatchError{msg: errNotTLS}
+	}
+	return h.RoundTripper.RoundTrip(req)
+}
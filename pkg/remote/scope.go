@@ -0,0 +1,56 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewScopedClient returns a new *ScopedClient that restricts the List calls
+// made through the supplied client to the objects matched by the supplied
+// selector.
+func NewScopedClient(c client.Client, sel labels.Selector) *ScopedClient {
+	return &ScopedClient{Client: c, selector: sel}
+}
+
+// A ScopedClient restricts List calls to the objects that carry the ownership
+// labels of the agent, so that it works against remote clusters where it's
+// only granted access to the subset of objects it labels. Other calls address
+// objects by name and are passed to the wrapped client as they are.
+type ScopedClient struct {
+	client.Client
+
+	selector labels.Selector
+}
+
+// List lists the objects that are matched by both the selector of the client
+// and the one in the supplied options, if any.
+func (c *ScopedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+	sel := c.selector
+	if lo.LabelSelector != nil {
+		reqs, _ := lo.LabelSelector.Requirements()
+		sel = sel.Add(reqs...)
+	}
+	lo.LabelSelector = sel
+	return c.Client.List(ctx, list, lo)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// TransportOptions tune the connections to the remote cluster. Zero values
+// keep the client-go defaults.
+type TransportOptions struct {
+	// DialTimeout is how long establishing a connection may take.
+	DialTimeout time.Duration
+
+	// KeepAlive is how often TCP keep-alive probes are sent on idle
+	// connections.
+	KeepAlive time.Duration
+
+	// MaxIdleConns is how many idle connections are kept open.
+	MaxIdleConns int
+
+	// IdleConnTimeout is how long an idle connection is kept open.
+	IdleConnTimeout time.Duration
+
+	// Pins are what the certificate of the remote cluster is verified
+	// against in addition to its kubeconfig.
+	Pins TLSPins
+
+	// QPS is how many requests per second are sent to the remote cluster on
+	// average by each client of the config, and Burst how many of them may
+	// be sent at once.
+	QPS   float32
+	Burst int
+}
+
+// ConfigureTransport applies the supplied options to the transport of the
+// given config. It must be called before the config is wrapped otherwise. The
+// returned *Transport can be used to drop the connections made with it.
+func ConfigureTransport(cfg *rest.Config, o TransportOptions) *Transport {
+	t := &Transport{transports: map[*http.Transport]struct{}{}}
+	if o.QPS > 0 {
+		cfg.QPS = o.QPS
+	}
+	if o.Burst > 0 {
+		cfg.Burst = o.Burst
+	}
+	if o.DialTimeout > 0 || o.KeepAlive > 0 {
+		d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if o.DialTimeout > 0 {
+			d.Timeout = o.DialTimeout
+		}
+		if o.KeepAlive > 0 {
+			d.KeepAlive = o.KeepAlive
+		}
+		cfg.Dial = d.DialContext
+	}
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		ht, ok := rt.(*http.Transport)
+		if !ok {
+			if !o.Pins.Empty() {
+				return o.Pins.pin(rt)
+			}
+			return rt
+		}
+		if o.MaxIdleConns > 0 {
+			ht.MaxIdleConns = o.MaxIdleConns
+			ht.MaxIdleConnsPerHost = o.MaxIdleConns
+		}
+		if o.IdleConnTimeout > 0 {
+			ht.IdleConnTimeout = o.IdleConnTimeout
+		}
+		t.add(ht)
+		if !o.Pins.Empty() {
+			return o.Pins.pin(ht)
+		}
+		return rt
+	})
+	return t
+}
+
+// A Transport keeps track of the HTTP transports that clients of a config
+// use.
+type Transport struct {
+	mu         sync.Mutex
+	transports map[*http.Transport]struct{}
+}
+
+func (t *Transport) add(ht *http.Transport) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.transports[ht] = struct{}{}
+}
+
+// CloseIdleConnections closes the idle connections of all transports so that
+// the next requests dial new ones. HTTP/2 connections are reused for all
+// requests to the api-server, so a connection that was silently dropped by
+// the network would otherwise be used until the operating system gives up on
+// it, which takes much longer than a health check.
+func (t *Transport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ht := range t.transports {
+		ht.CloseIdleConnections()
+	}
+}
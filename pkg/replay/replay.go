@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replay records the claims that syncs read and push, and replays them
+// offline so that a new version of the agent can be validated against the
+// syncs of a production cluster without touching either cluster.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+	"github.com/crossplane/agent/pkg/trim"
+)
+
+const (
+	errWriteRecord = "cannot write sync record"
+	errReadRecord  = "cannot read sync record"
+)
+
+// A Record is what a sync of a claim read from both clusters and pushed to the
+// remote cluster.
+type Record struct {
+	Time     time.Time                   `json:"time"`
+	Local    *kunstructured.Unstructured `json:"local"`
+	Observed *kunstructured.Unstructured `json:"observed"`
+	Pushed   *kunstructured.Unstructured `json:"pushed"`
+}
+
+// Sanitize returns a copy of the supplied object without the fields that
+// aren't used by syncs, or differ on every one of them, like the managed
+// fields and the fencing token of the agent.
+func Sanitize(u *kunstructured.Unstructured) *kunstructured.Unstructured {
+	out := u.DeepCopy()
+	trim.Options{ManagedFields: true, LastApplied: true}.Object(out.Object)
+	resource.SetAnnotation(out, resource.AnnotationKeyFencingToken, "")
+	return out
+}
+
+// NewRecorder returns a new *Recorder that writes to the supplied writer.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// A Recorder writes sanitized Records of syncs, one JSON object per line. It's
+// safe to use from all claim controllers at once.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// RecordSync writes a Record of the supplied claims.
+func (r *Recorder) RecordSync(_ context.Context, local, observed, pushed *claim.Unstructured) error {
+	rec := Record{
+		Time:     time.Now().UTC(),
+		Local:    Sanitize(local.GetUnstructured()),
+		Observed: Sanitize(observed.GetUnstructured()),
+		Pushed:   Sanitize(pushed.GetUnstructured()),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Wrap(r.enc.Encode(rec), errWriteRecord)
+}
+
+// A Renderer renders the remote claim a sync pushes.
+type Renderer interface {
+	Render(ctx context.Context, local, observed *claim.Unstructured) (*claim.Unstructured, error)
+}
+
+// A Summary of a replay.
+type Summary struct {
+	// Replayed is the number of Records that were replayed.
+	Replayed int
+
+	// Changed is the number of them that would push a different remote
+	// claim, or fail, with the Renderer they were replayed with.
+	Changed int
+}
+
+// Replay renders the remote claim of every Record read from the supplied
+// reader again with the supplied Renderer, and writes the ones that differ
+// from the recorded ones to the supplied writer.
+func Replay(ctx context.Context, in io.Reader, r Renderer, out io.Writer) (Summary, error) {
+	s := Summary{}
+	d := json.NewDecoder(in)
+	for {
+		rec := Record{}
+		if err := d.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return s, nil
+			}
+			return s, errors.Wrap(err, errReadRecord)
+		}
+		if rec.Local == nil || rec.Observed == nil || rec.Pushed == nil {
+			continue
+		}
+		s.Replayed++
+		id := fmt.Sprintf("%s %s/%s at %s", rec.Local.GetKind(), rec.Local.GetNamespace(), rec.Local.GetName(), rec.Time.Format(time.RFC3339))
+		got, err := r.Render(ctx, &claim.Unstructured{Unstructured: *rec.Local}, &claim.Unstructured{Unstructured: *rec.Observed})
+		if err != nil {
+			s.Changed++
+			fmt.Fprintf(out, "# %s fails: %s\n", id, err) // nolint:errcheck
+			continue
+		}
+		if diff := cmp.Diff(rec.Pushed.Object, Sanitize(got.GetUnstructured()).Object); diff != "" {
+			s.Changed++
+			fmt.Fprintf(out, "# %s pushes a different remote claim: -recorded, +replayed:\n%s\n", id, diff) // nolint:errcheck
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	agentclaim "github.com/crossplane/agent/pkg/controllers/claim"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestReplay(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	local := claim.New(claim.WithGroupVersionKind(gvk))
+	local.SetName("cool-claim")
+	local.SetNamespace("team-a")
+	local.SetUID("cool-uid")
+	local.Object["spec"] = map[string]interface{}{"storageGB": int64(20)}
+	observed := claim.New(claim.WithGroupVersionKind(gvk))
+
+	recorded := agentclaim.SyncRenderer{Configurator: agentclaim.NewDefaultConfigurator(), Mapper: agentclaim.NamespaceMap(nil), ClusterName: "cool-cluster"}
+	pushed, err := recorded.Render(context.Background(), local, observed)
+	if err != nil {
+		t.Fatalf("Render(...): %s", err)
+	}
+	resource.SetAnnotation(pushed, resource.AnnotationKeyFencingToken, "42")
+	buf := &bytes.Buffer{}
+	if err := NewRecorder(buf).RecordSync(context.Background(), local, observed, pushed); err != nil {
+		t.Fatalf("RecordSync(...): %s", err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		r      Renderer
+		want   Summary
+	}{
+		"Unchanged": {
+			reason: "A sync that pushes the same remote claim, other than its fencing token, should not be reported",
+			r:      recorded,
+			want:   Summary{Replayed: 1},
+		},
+		"Changed": {
+			reason: "A sync that pushes the remote claim to another namespace should be reported",
+			r:      agentclaim.SyncRenderer{Configurator: agentclaim.NewDefaultConfigurator(), Mapper: agentclaim.NamespaceMap{"team-a": "prod-team-a"}, ClusterName: "cool-cluster"},
+			want:   Summary{Replayed: 1, Changed: 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Replay(context.Background(), bytes.NewReader(buf.Bytes()), tc.r, ioutil.Discard)
+			if err != nil {
+				t.Fatalf("\nReason: %s\nReplay(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nReplay(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package requeue decides when objects that failed to sync are retried.
+package requeue
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultJitter is the largest fraction of a wait that's added to it at
+// random, so that objects that failed together aren't all retried together.
+const DefaultJitter = 0.2
+
+// NewBackoff returns a Backoff that waits base after the first failure of an
+// object and doubles the wait with each consecutive failure, up to ceiling. Up
+// to jitter times the wait is added to it at random.
+func NewBackoff(base, ceiling time.Duration, jitter float64) *Backoff {
+	if ceiling < base {
+		ceiling = base
+	}
+	return &Backoff{base: base, ceiling: ceiling, jitter: jitter, failures: map[interface{}]int{}}
+}
+
+// A Backoff tells how long to wait before retrying an object, given how many
+// times in a row it failed. It satisfies workqueue.RateLimiter, so controllers
+// whose reconcilers return errors can be configured with it too.
+type Backoff struct {
+	base    time.Duration
+	ceiling time.Duration
+	jitter  float64
+
+	mu       sync.Mutex
+	failures map[interface{}]int
+}
+
+// Wait returns how long to wait before retrying the supplied object if it
+// fails now.
+func (b *Backoff) Wait(item interface{}) time.Duration {
+	b.mu.Lock()
+	n := b.failures[item]
+	b.mu.Unlock()
+
+	d := b.base
+	for i := 0; i < n && d < b.ceiling; i++ {
+		d *= 2
+	}
+	if d > b.ceiling {
+		d = b.ceiling
+	}
+	if b.jitter > 0 {
+		d = wait.Jitter(d, b.jitter)
+	}
+	return d
+}
+
+// Failed records that the supplied object failed.
+func (b *Backoff) Failed(item interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[item]++
+}
+
+// When records that the supplied object failed and returns how long to wait
+// before retrying it.
+func (b *Backoff) When(item interface{}) time.Duration {
+	d := b.Wait(item)
+	b.Failed(item)
+	return d
+}
+
+// Forget forgets the failures of the supplied object, e.g. because it
+// succeeded.
+func (b *Backoff) Forget(item interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, item)
+}
+
+// NumRequeues returns how many times in a row the supplied object failed.
+func (b *Backoff) NumRequeues(item interface{}) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures[item]
+}
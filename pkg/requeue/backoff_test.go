@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requeue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBackoffWait(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		base     time.Duration
+		ceiling  time.Duration
+		failures int
+		want     time.Duration
+	}{
+		"FirstFailure": {
+			reason:   "The base wait should be used for an object that has not failed before.",
+			base:     10 * time.Second,
+			ceiling:  time.Minute,
+			failures: 0,
+			want:     10 * time.Second,
+		},
+		"ConsecutiveFailures": {
+			reason:   "The wait should double with each consecutive failure.",
+			base:     10 * time.Second,
+			ceiling:  time.Minute,
+			failures: 2,
+			want:     40 * time.Second,
+		},
+		"Ceiling": {
+			reason:   "The wait should never exceed the ceiling.",
+			base:     10 * time.Second,
+			ceiling:  time.Minute,
+			failures: 100,
+			want:     time.Minute,
+		},
+		"CeilingBelowBase": {
+			reason:   "The base wait should be used if the ceiling is lower than it.",
+			base:     10 * time.Second,
+			ceiling:  time.Second,
+			failures: 3,
+			want:     10 * time.Second,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := NewBackoff(tc.base, tc.ceiling, 0)
+			for i := 0; i < tc.failures; i++ {
+				b.Failed("o")
+			}
+			if diff := cmp.Diff(tc.want, b.Wait("o")); diff != "" {
+				t.Errorf("\nReason: %s\nb.Wait(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestBackoffForget(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute, 0)
+	b.When("o")
+	b.When("o")
+	b.Forget("o")
+	if diff := cmp.Diff(time.Second, b.When("o")); diff != "" {
+		t.Errorf("\nReason: %s\nb.When(...): -want, +got:\n%s\n", "The base wait should be used again once an object's failures are forgotten.", diff)
+	}
+	if diff := cmp.Diff(1, b.NumRequeues("o")); diff != "" {
+		t.Errorf("\nReason: %s\nb.NumRequeues(...): -want, +got:\n%s\n", "Failures should be counted again after they're forgotten.", diff)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := NewBackoff(10*time.Second, time.Minute, DefaultJitter)
+	for i := 0; i < 100; i++ {
+		got := b.Wait("o")
+		if got < 10*time.Second || got > 12*time.Second {
+			t.Fatalf("\nReason: %s\nb.Wait(...): got %s, want between 10s and 12s\n", "Up to the jitter fraction of the wait should be added to it.", got)
+		}
+	}
+}
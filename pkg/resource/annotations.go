@@ -0,0 +1,222 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationKeyPrefix is the prefix of the annotations Agent uses to keep track
+// of the objects it syncs. They are not propagated to the remote cluster.
+const AnnotationKeyPrefix = "agent.crossplane.io/"
+
+// Annotations that Agent adds to local claims.
+const (
+	// AnnotationKeyLastRemoteResourceVersion is the resource version of the
+	// remote claim as of the last successful sync.
+	AnnotationKeyLastRemoteResourceVersion = AnnotationKeyPrefix + "last-remote-resource-version"
+
+	// AnnotationKeyResyncEpoch is the epoch of the full verification resync
+	// that the claim last went through.
+	AnnotationKeyResyncEpoch = AnnotationKeyPrefix + "resync-epoch"
+
+	// AnnotationKeyRemoteUID is the UID of the remote claim that the local
+	// claim is synced to.
+	AnnotationKeyRemoteUID = AnnotationKeyPrefix + "remote-uid"
+
+	// AnnotationKeyRemoteNamespace is the remote namespace that the local
+	// claim is synced to. A claim is relocated when it no longer matches.
+	AnnotationKeyRemoteNamespace = AnnotationKeyPrefix + "remote-namespace"
+
+	// AnnotationKeyRemoteCluster is the name of the remote cluster, per
+	// LabelKeyRemote, that the local claim is synced to. It's empty for
+	// claims that are synced to the default remote cluster.
+	AnnotationKeyRemoteCluster = AnnotationKeyPrefix + "remote-cluster"
+
+	// AnnotationKeyResyncHandled is the value of the resync annotation that
+	// the claim last went through a full resync for.
+	AnnotationKeyResyncHandled = AnnotationKeyPrefix + "resync-handled"
+
+	// AnnotationKeyRemoteDeletionAcknowledged is the UID of the remote claim
+	// whose deletion by someone other than Agent was observed and reconciled
+	// into the local claim.
+	AnnotationKeyRemoteDeletionAcknowledged = AnnotationKeyPrefix + "remote-deletion-acknowledged"
+
+	// AnnotationKeyLastPushedSpec is the JSON of the spec that was last pushed
+	// to the remote cluster, or its digest if it's too large.
+	AnnotationKeyLastPushedSpec = AnnotationKeyPrefix + "last-pushed-spec"
+
+	// AnnotationKeySyncStats is the JSON of the statistics of the syncs of
+	// the claim, e.g. how many times it was pushed and how many times it
+	// failed.
+	AnnotationKeySyncStats = AnnotationKeyPrefix + "sync-stats"
+)
+
+// AnnotationKeyApproved is added to local claims by a human or an external
+// workflow to approve their first push to the remote cluster when approval is
+// required.
+const AnnotationKeyApproved = AnnotationKeyPrefix + "approved"
+
+// IsApproved returns true if the supplied object is approved to be pushed to
+// the remote cluster.
+func IsApproved(o metav1.Object) bool {
+	return o.GetAnnotations()[AnnotationKeyApproved] == "true"
+}
+
+// AnnotationKeyLocallyAuthored is added to objects in the local cluster, like
+// Compositions, by users to tell that they were authored there rather than
+// synced from the remote cluster, so that they're not removed for not existing
+// in the remote cluster.
+const AnnotationKeyLocallyAuthored = AnnotationKeyPrefix + "locally-authored"
+
+// IsLocallyAuthored returns true if the supplied object was authored in the
+// local cluster.
+func IsLocallyAuthored(o metav1.Object) bool {
+	return o.GetAnnotations()[AnnotationKeyLocallyAuthored] == "true"
+}
+
+// AnnotationKeyTTL is added to local claims by users to have them deleted,
+// together with their remote claims, once they're older than the given
+// duration, e.g. "72h".
+const AnnotationKeyTTL = AnnotationKeyPrefix + "ttl"
+
+// AnnotationKeyLastTouched is updated on local claims by their consumers, as an
+// RFC3339 timestamp, to tell that they're still in use.
+const AnnotationKeyLastTouched = AnnotationKeyPrefix + "last-touched"
+
+// AnnotationKeyPriority is added to local claims, or to their namespaces, by
+// users to have them synced ahead of, or after, others while the agent is
+// busy. It's either low, normal or high.
+const AnnotationKeyPriority = AnnotationKeyPrefix + "priority"
+
+// AnnotationKeyResync is added to local claims, or to their namespaces, by
+// users to have them go through a full resync right away. Its value, usually a
+// timestamp, has to change every time one is requested.
+const AnnotationKeyResync = AnnotationKeyPrefix + "resync"
+
+// Annotations that Agent adds to remote claims.
+const (
+	// AnnotationKeyFencingToken is the fencing token of the agent that last
+	// wrote the remote claim. It's the epoch of that agent, counted in the
+	// remote cluster, rather than the time it started, which the tokens that
+	// were written under the former key were.
+	AnnotationKeyFencingToken = AnnotationKeyPrefix + "fencing-epoch"
+
+	// AnnotationKeySourceCluster is the name of the cluster the remote claim
+	// is synced from.
+	AnnotationKeySourceCluster = AnnotationKeyPrefix + "source-cluster"
+
+	// AnnotationKeySourceObject is the namespace/name of the local claim the
+	// remote claim is synced from.
+	AnnotationKeySourceObject = AnnotationKeyPrefix + "source-object"
+
+	// AnnotationKeyCorrelationID ties the last write of the remote claim to
+	// the logs of the agent that made it.
+	AnnotationKeyCorrelationID = AnnotationKeyPrefix + "correlation-id"
+
+	// AnnotationKeyAgentVersion is the version of the agent that last wrote
+	// the remote claim.
+	AnnotationKeyAgentVersion = AnnotationKeyPrefix + "agent-version"
+
+	// AnnotationKeyOriginChain is the comma-separated list of the clusters
+	// the remote claim is synced through, from the one it was created in to
+	// the one it's synced from, when agents are chained so that the remote
+	// cluster of one is the local cluster of another.
+	AnnotationKeyOriginChain = AnnotationKeyPrefix + "origin-chain"
+)
+
+// LabelKeyOriginCluster is added to remote claims so that they can be
+// selected by the cluster they were originally created in, however many
+// agents they're synced through.
+const LabelKeyOriginCluster = AnnotationKeyPrefix + "origin-cluster"
+
+// LabelKeyIdempotencyKey is added to remote claims so that a create whose
+// outcome is unknown, e.g. because it timed out, can be told apart from one
+// that didn't happen, whatever name the remote claim got. Its value is the UID
+// of the claim the remote claim originates from.
+const LabelKeyIdempotencyKey = AnnotationKeyPrefix + "idempotency-key"
+
+// LabelKeyRemote is added to local claims by users to have them synced to one
+// of the additional remote clusters the agent is configured with rather than
+// the default one. Its value is the name of that remote cluster. Changing it
+// once the claim is synced moves the claim: its remote claim is deleted from
+// the remote cluster it was synced to before it's created in the new one.
+const LabelKeyRemote = AnnotationKeyPrefix + "remote"
+
+// IsAgentAnnotation returns true if the supplied annotation key is used by
+// Agent for its own bookkeeping.
+func IsAgentAnnotation(key string) bool {
+	return strings.HasPrefix(key, AnnotationKeyPrefix)
+}
+
+// WithoutAgentAnnotations returns a copy of the supplied annotations without
+// the ones that are used by Agent for its own bookkeeping.
+func WithoutAgentAnnotations(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		if IsAgentAnnotation(k) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// SetAnnotation sets the supplied annotation on the object if the value is not
+// empty and removes it otherwise.
+func SetAnnotation(o metav1.Object, key, value string) {
+	a := o.GetAnnotations()
+	if value == "" {
+		if _, ok := a[key]; !ok {
+			return
+		}
+		delete(a, key)
+		o.SetAnnotations(a)
+		return
+	}
+	if a == nil {
+		a = map[string]string{}
+	}
+	a[key] = value
+	o.SetAnnotations(a)
+}
+
+// AnnotationKeyEnvelopeKey is added to Secrets whose data is encrypted beyond
+// TLS while it's propagated across clusters. Its value is the base64 encoded
+// data key the values are encrypted with, which is itself encrypted with a key
+// that only the sealing and the consuming side have access to.
+const AnnotationKeyEnvelopeKey = AnnotationKeyPrefix + "envelope-key"
+
+// AnnotationKeyEnvelopeDigest is added to sealed Secrets alongside their
+// envelope key. Its value is a keyed digest of the data the Secret was sealed
+// from, which tells whether the data changed without opening the Secret.
+const AnnotationKeyEnvelopeDigest = AnnotationKeyPrefix + "envelope-digest"
+
+// AnnotationKeyFanOut is added to local claims by users to have them
+// propagated to remote clusters other than the one they're synced with. Its
+// value is a comma-separated list of the names of those remote clusters, or
+// FanOutAll for all of them.
+const AnnotationKeyFanOut = AnnotationKeyPrefix + "fan-out"
+
+// FanOutAll is the value of AnnotationKeyFanOut that propagates a claim to all
+// remote clusters.
+const FanOutAll = "*"
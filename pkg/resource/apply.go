@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// DefaultFieldManager is the name the agent applies objects with unless it's
+// configured otherwise.
+const DefaultFieldManager = "crossplane-agent"
+
+const (
+	errApplyOptions    = "apply options are not supported with server-side apply"
+	errGetGVK          = "cannot get the kind of object"
+	errServerSideApply = "cannot apply object"
+)
+
+// NewServerSideApplicator returns a new *ServerSideApplicator that applies
+// objects as the supplied field manager. The supplied scheme is used to find
+// the kind of typed objects.
+func NewServerSideApplicator(c client.Client, s *runtime.Scheme, fieldManager string) *ServerSideApplicator {
+	return &ServerSideApplicator{client: c, scheme: s, manager: fieldManager}
+}
+
+// A ServerSideApplicator applies objects with server-side apply. The api-server
+// merges the fields they have with the ones that are managed by others, like
+// annotations added by other tooling, rather than replacing them.
+type ServerSideApplicator struct {
+	client  client.Client
+	scheme  *runtime.Scheme
+	manager string
+}
+
+// Apply the supplied object, taking over the fields it has from any other field
+// manager. ApplyOptions are not supported since the current object is never
+// read.
+func (a *ServerSideApplicator) Apply(ctx context.Context, o runtime.Object, ao ...resource.ApplyOption) error {
+	if len(ao) > 0 {
+		return errors.New(errApplyOptions)
+	}
+	gvk, err := apiutil.GVKForObject(o, a.scheme)
+	if err != nil {
+		return errors.Wrap(err, errGetGVK)
+	}
+	o.GetObjectKind().SetGroupVersionKind(gvk)
+	return errors.Wrap(a.client.Patch(ctx, o, client.Apply, client.FieldOwner(a.manager), client.ForceOwnership), errServerSideApply)
+}
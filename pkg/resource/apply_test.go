@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestServerSideApplicator(t *testing.T) {
+	errBoom := errors.New("boom")
+	force := true
+	s := runtime.NewScheme()
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatalf("corev1.AddToScheme(...): %s", err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   error
+	}{
+		"Applied": {
+			reason: "Objects should be applied as the field manager with their kind set, taking over the fields they have",
+		},
+		"PatchFailed": {
+			reason: "Errors applying the object should be returned",
+			err:    errBoom,
+			want:   errors.Wrap(errBoom, errServerSideApply),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &test.MockClient{MockPatch: func(_ context.Context, obj runtime.Object, p client.Patch, opts ...client.PatchOption) error {
+				if diff := cmp.Diff(types.ApplyPatchType, p.Type()); diff != "" {
+					t.Errorf("\nReason: %s\nclient.Patch(...): -want patch type, +got patch type:\n%s", tc.reason, diff)
+				}
+				want := &client.PatchOptions{FieldManager: "cool-agent", Force: &force}
+				if diff := cmp.Diff(want, (&client.PatchOptions{}).ApplyOptions(opts)); diff != "" {
+					t.Errorf("\nReason: %s\nclient.Patch(...): -want options, +got options:\n%s", tc.reason, diff)
+				}
+				if diff := cmp.Diff("ConfigMap", obj.GetObjectKind().GroupVersionKind().Kind); diff != "" {
+					t.Errorf("\nReason: %s\nclient.Patch(...): -want kind, +got kind:\n%s", tc.reason, diff)
+				}
+				return tc.err
+			}}
+			err := NewServerSideApplicator(c, s, "cool-agent").Apply(context.Background(), &corev1.ConfigMap{})
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\na.Apply(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
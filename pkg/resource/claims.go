@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+// ClaimCRDName returns the name of the CRD of the claims the supplied
+// CompositeResourceDefinition offers, or an empty string if it doesn't offer
+// a claim.
+func ClaimCRDName(xrd v1alpha1.CompositeResourceDefinition) string {
+	if xrd.Spec.ClaimNames == nil {
+		return ""
+	}
+	return xrd.Spec.ClaimNames.Plural + "." + xrd.Spec.CRDSpecTemplate.Group
+}
+
+// ClaimGroupKind returns the GroupKind of the claims the supplied
+// CompositeResourceDefinition offers, and whether it offers a claim at all.
+func ClaimGroupKind(xrd v1alpha1.CompositeResourceDefinition) (schema.GroupKind, bool) {
+	if xrd.Spec.ClaimNames == nil {
+		return schema.GroupKind{}, false
+	}
+	return schema.GroupKind{Group: xrd.Spec.CRDSpecTemplate.Group, Kind: xrd.Spec.ClaimNames.Kind}, true
+}
+
+// ServedVersions returns the versions the supplied CRD serves, in the order
+// they're listed.
+func ServedVersions(crd v1beta1.CustomResourceDefinition) []string {
+	if len(crd.Spec.Versions) == 0 {
+		if crd.Spec.Version == "" {
+			return nil
+		}
+		return []string{crd.Spec.Version}
+	}
+	served := make([]string, 0, len(crd.Spec.Versions))
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			served = append(served, v.Name)
+		}
+	}
+	return served
+}
+
+// ClaimVersion returns the version of the supplied CRD that claims are synced
+// with, which is its storage version if it's served and its first served
+// version otherwise.
+func ClaimVersion(crd v1beta1.CustomResourceDefinition) string {
+	version := crd.Spec.Version
+	firstServed := true
+	for _, v := range crd.Spec.Versions {
+		if v.Served && v.Storage {
+			return v.Name
+		}
+		if v.Served && firstServed {
+			version = v.Name
+			firstServed = false
+		}
+	}
+	return version
+}
+
+// ClaimGroupVersionKind returns the GroupVersionKind of the supplied CRD that
+// claims are synced with.
+func ClaimGroupVersionKind(crd v1beta1.CustomResourceDefinition) schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   crd.Spec.Group,
+		Kind:    crd.Spec.Names.Kind,
+		Version: ClaimVersion(crd),
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+func TestClaimNames(t *testing.T) {
+	offered := v1alpha1.CompositeResourceDefinition{}
+	offered.Spec.CRDSpecTemplate.Group = "example.org"
+	offered.Spec.CRDSpecTemplate.Names.Kind = "XMySQLInstance"
+	offered.Spec.ClaimNames = &v1beta1.CustomResourceDefinitionNames{Plural: "mysqlinstances", Kind: "MySQLInstance"}
+
+	type want struct {
+		name    string
+		gk      schema.GroupKind
+		offered bool
+	}
+	cases := map[string]struct {
+		reason string
+		xrd    v1alpha1.CompositeResourceDefinition
+		want   want
+	}{
+		"NoClaim": {
+			reason: "A CompositeResourceDefinition that doesn't offer a claim should have no claim CRD",
+			xrd:    v1alpha1.CompositeResourceDefinition{},
+		},
+		"Claim": {
+			reason: "The claim CRD should be named after the plural of the claim and the group of the composite",
+			xrd:    offered,
+			want: want{
+				name:    "mysqlinstances.example.org",
+				gk:      schema.GroupKind{Group: "example.org", Kind: "MySQLInstance"},
+				offered: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want.name, ClaimCRDName(tc.xrd)); diff != "" {
+				t.Errorf("\nReason: %s\nClaimCRDName(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			gk, ok := ClaimGroupKind(tc.xrd)
+			if diff := cmp.Diff(tc.want.gk, gk); diff != "" {
+				t.Errorf("\nReason: %s\nClaimGroupKind(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.offered, ok); diff != "" {
+				t.Errorf("\nReason: %s\nClaimGroupKind(...): -want offered, +got offered:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClaimVersions(t *testing.T) {
+	type want struct {
+		served []string
+		gvk    schema.GroupVersionKind
+	}
+	cases := map[string]struct {
+		reason string
+		spec   v1beta1.CustomResourceDefinitionSpec
+		want   want
+	}{
+		"NoVersion": {
+			reason: "A CRD without a version should serve none",
+		},
+		"SingleVersion": {
+			reason: "The only version of a CRD should be served and used",
+			spec: v1beta1.CustomResourceDefinitionSpec{
+				Group:   "example.org",
+				Names:   v1beta1.CustomResourceDefinitionNames{Kind: "MySQLInstance"},
+				Version: "v1alpha1",
+			},
+			want: want{
+				served: []string{"v1alpha1"},
+				gvk:    schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"},
+			},
+		},
+		"StorageVersion": {
+			reason: "The storage version should be preferred if it's served",
+			spec: v1beta1.CustomResourceDefinitionSpec{
+				Version: "v1alpha1",
+				Versions: []v1beta1.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Served: true},
+					{Name: "v1beta1", Served: true, Storage: true},
+				},
+			},
+			want: want{
+				served: []string{"v1alpha1", "v1beta1"},
+				gvk:    schema.GroupVersionKind{Version: "v1beta1"},
+			},
+		},
+		"StorageVersionNotServed": {
+			reason: "The first served version should be used if the storage version is not served",
+			spec: v1beta1.CustomResourceDefinitionSpec{
+				Version: "v1alpha1",
+				Versions: []v1beta1.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Storage: true},
+					{Name: "v1beta1", Served: true},
+					{Name: "v1", Served: true},
+				},
+			},
+			want: want{
+				served: []string{"v1beta1", "v1"},
+				gvk:    schema.GroupVersionKind{Version: "v1beta1"},
+			},
+		},
+		"NoneServed": {
+			reason: "The top-level version should be used if no version is served",
+			spec: v1beta1.CustomResourceDefinitionSpec{
+				Version:  "v1alpha1",
+				Versions: []v1beta1.CustomResourceDefinitionVersion{{Name: "v1alpha1", Storage: true}},
+			},
+			want: want{
+				served: []string{},
+				gvk:    schema.GroupVersionKind{Version: "v1alpha1"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := v1beta1.CustomResourceDefinition{Spec: tc.spec}
+			if diff := cmp.Diff(tc.want.served, ServedVersions(crd)); diff != "" {
+				t.Errorf("\nReason: %s\nServedVersions(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.gvk.Version, ClaimVersion(crd)); diff != "" {
+				t.Errorf("\nReason: %s\nClaimVersion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.gvk, ClaimGroupVersionKind(crd)); diff != "" {
+				t.Errorf("\nReason: %s\nClaimGroupVersionKind(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
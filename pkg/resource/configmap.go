@@ -0,0 +1,68 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// The label that is added to the objects Agent creates for its own bookkeeping.
+const (
+	LabelKeyManagedBy   = "app.kubernetes.io/managed-by"
+	LabelValueManagedBy = "crossplane-agent"
+)
+
+// PublishConfigMap creates or updates the ConfigMap with the given name so that
+// its data matches the supplied data. Agent uses ConfigMaps to report state
+// that is not specific to a single object.
+func PublishConfigMap(ctx context.Context, a resource.Applicator, nn types.NamespacedName, data map[string]string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nn.Name,
+			Namespace: nn.Namespace,
+			Labels:    map[string]string{LabelKeyManagedBy: LabelValueManagedBy},
+		},
+		Data: data,
+	}
+	return a.Apply(ctx, cm)
+}
+
+// PublishEvery calls the supplied publish function right away and then every
+// period until the supplied channel is closed. Errors are logged and the next
+// period is waited for, since the data is published again anyway.
+func PublishEvery(stop <-chan struct{}, period time.Duration, publish func(ctx context.Context) error, log logging.Logger) error {
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		if err := publish(context.Background()); err != nil {
+			log.Debug("Cannot publish", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
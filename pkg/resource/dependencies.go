@@ -0,0 +1,60 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AnnotationKeyDependsOn is added by users to local claims to list the objects
+// that need to exist in the remote cluster before the claim is pushed, such as
+// the ProviderConfig its composition uses. Its value is a comma-separated list
+// of references in the form of apiVersion/kind/name, e.g.
+// "aws.crossplane.io/v1beta1/ProviderConfig/default". Namespaced objects are
+// looked up in the namespace of the remote claim.
+const AnnotationKeyDependsOn = AnnotationKeyPrefix + "depends-on"
+
+// A DependencyReference is a reference to an object a claim depends on.
+type DependencyReference struct {
+	schema.GroupVersionKind
+	Name string
+}
+
+// ParseDependencyReferences parses the value of the depends-on annotation.
+// Malformed entries are skipped.
+func ParseDependencyReferences(v string) []DependencyReference {
+	var refs []DependencyReference
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		i := strings.LastIndex(entry, "/")
+		if i <= 0 {
+			continue
+		}
+		j := strings.LastIndex(entry[:i], "/")
+		if j <= 0 {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(entry[:j])
+		if err != nil || entry[j+1:i] == "" || entry[i+1:] == "" {
+			continue
+		}
+		refs = append(refs, DependencyReference{GroupVersionKind: gv.WithKind(entry[j+1 : i]), Name: entry[i+1:]})
+	}
+	return refs
+}
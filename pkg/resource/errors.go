@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// IsTransient returns true if the supplied error, or the error it wraps, is
+// likely to go away on its own, e.g. because the api-server is overloaded or
+// unreachable. Retrying soon is expected to succeed.
+func IsTransient(err error) bool {
+	err = errors.Cause(err)
+	if err == nil {
+		return false
+	}
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	if kerrors.IsTimeout(err) || kerrors.IsServerTimeout(err) || kerrors.IsTooManyRequests(err) ||
+		kerrors.IsServiceUnavailable(err) || kerrors.IsInternalError(err) || kerrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	if utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+	ne, ok := err.(net.Error)
+	return ok && (ne.Timeout() || ne.Temporary())
+}
+
+// IsAdmissionDenied returns true if the supplied error, or the error it wraps,
+// is returned by an api-server that refused a request because an admission
+// webhook denied it or it exceeds a quota. Retrying is not expected to
+// succeed until either the request or the policy changes.
+func IsAdmissionDenied(err error) bool {
+	s, ok := errors.Cause(err).(kerrors.APIStatus)
+	if !ok {
+		return false
+	}
+	msg := s.Status().Message
+	if strings.Contains(msg, "admission webhook") && strings.Contains(msg, "denied the request") {
+		return true
+	}
+	return kerrors.IsForbidden(errors.Cause(err)) && strings.Contains(msg, "exceeded quota")
+}
+
+// IsConflict returns true if the supplied error, or the error it wraps, is
+// returned by an api-server that refused to write an object because it was
+// changed since it was read. Retrying right away with a fresh copy is
+// expected to succeed.
+func IsConflict(err error) bool {
+	return kerrors.IsConflict(errors.Cause(err))
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	gr := schema.GroupResource{Group: "example.org", Resource: "databases"}
+	type want struct {
+		transient bool
+		denied    bool
+		conflict  bool
+	}
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   want
+	}{
+		"Nil": {
+			reason: "No error is in no class",
+		},
+		"Unknown": {
+			reason: "An arbitrary error is in no class",
+			err:    errors.New("boom"),
+		},
+		"TooManyRequests": {
+			reason: "Throttling is transient, even when it's wrapped",
+			err:    errors.Wrap(kerrors.NewTooManyRequests("slow down", 1), "cannot apply"),
+			want:   want{transient: true},
+		},
+		"DeadlineExceeded": {
+			reason: "A request that timed out is transient",
+			err:    errors.Wrap(context.DeadlineExceeded, "cannot get"),
+			want:   want{transient: true},
+		},
+		"WebhookDenied": {
+			reason: "A request that an admission webhook denied is an admission denial",
+			err:    kerrors.NewBadRequest(`admission webhook "policy.example.org" denied the request: size is too large`),
+			want:   want{denied: true},
+		},
+		"QuotaExceeded": {
+			reason: "A request that exceeds a quota is an admission denial",
+			err:    errors.Wrap(kerrors.NewForbidden(gr, "db", errors.New("exceeded quota: compute")), "cannot create"),
+			want:   want{denied: true},
+		},
+		"Forbidden": {
+			reason: "A request that's forbidden by RBAC is not an admission denial",
+			err:    kerrors.NewForbidden(gr, "db", errors.New("not allowed")),
+		},
+		"Conflict": {
+			reason: "A write to a stale object is a conflict, even when it's wrapped",
+			err:    errors.Wrap(kerrors.NewConflict(gr, "db", errors.New("changed")), "cannot update"),
+			want:   want{conflict: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{transient: IsTransient(tc.err), denied: IsAdmissionDenied(tc.err), conflict: IsConflict(tc.err)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\n-want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"strings"
+)
+
+// Annotations that users add to local claims to have the Secrets and
+// ConfigMaps they reference synced to the remote cluster. Their values are
+// comma-separated lists of names in the namespace of the claim. A name can be
+// followed by a colon and a semicolon-separated list of the keys to sync,
+// e.g. "db-creds:username;password,params". All keys are synced otherwise.
+const (
+	AnnotationKeyInputSecrets    = AnnotationKeyPrefix + "input-secrets"
+	AnnotationKeyInputConfigMaps = AnnotationKeyPrefix + "input-configmaps"
+)
+
+// An InputReference is a reference to an object whose data is an input of a
+// claim.
+type InputReference struct {
+	Name string
+
+	// Keys of the data to sync. All keys are synced if it's empty.
+	Keys []string
+}
+
+// ParseInputReferences parses the value of an input annotation.
+func ParseInputReferences(v string) []InputReference {
+	var refs []InputReference
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		ref := InputReference{Name: strings.TrimSpace(parts[0])}
+		if len(parts) == 2 {
+			for _, k := range strings.Split(parts[1], ";") {
+				if k = strings.TrimSpace(k); k != "" {
+					ref.Keys = append(ref.Keys, k)
+				}
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// Allows returns true if the supplied key should be synced.
+func (r InputReference) Allows(key string) bool {
+	if len(r.Keys) == 0 {
+		return true
+	}
+	for _, k := range r.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseInputReferences(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		value  string
+		want   []InputReference
+	}{
+		"Empty": {
+			reason: "No references should be returned for an empty value",
+		},
+		"NamesOnly": {
+			reason: "All keys of each named object should be synced",
+			value:  "one, two,",
+			want:   []InputReference{{Name: "one"}, {Name: "two"}},
+		},
+		"WithKeys": {
+			reason: "Only the listed keys should be synced",
+			value:  "db:username;password,params",
+			want:   []InputReference{{Name: "db", Keys: []string{"username", "password"}}, {Name: "params"}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ParseInputReferences(tc.value)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nParseInputReferences(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package resource
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -29,23 +33,38 @@ import (
 const (
 	TypeAgentSync v1alpha1.ConditionType = "AgentSynced"
 
-	ReasonAgentSyncSuccess v1alpha1.ConditionReason = "Success"
-	ReasonAgentSyncError   v1alpha1.ConditionReason = "Error"
+	ReasonAgentSyncSuccess        v1alpha1.ConditionReason = "Success"
+	ReasonAgentSyncError          v1alpha1.ConditionReason = "Error"
+	ReasonAgentSyncObjectTooLarge v1alpha1.ConditionReason = "ObjectTooLarge"
+	ReasonAgentSyncCanaryFailed   v1alpha1.ConditionReason = "CanaryFailed"
+	ReasonAgentSyncRolloutPaused  v1alpha1.ConditionReason = "RolloutPaused"
+	ReasonAgentSyncRemoteReplaced v1alpha1.ConditionReason = "RemoteReplaced"
+	ReasonAgentSyncLocked         v1alpha1.ConditionReason = "NamespaceLocked"
+	ReasonAgentSyncFenced         v1alpha1.ConditionReason = "Fenced"
+	ReasonAgentSyncNoNamespace    v1alpha1.ConditionReason = "RemoteNamespaceUnavailable"
+	ReasonAgentSyncWaiting        v1alpha1.ConditionReason = "WaitingOnDependency"
+	ReasonAgentSyncPending        v1alpha1.ConditionReason = "PendingApproval"
+	ReasonAgentSyncUntrusted      v1alpha1.ConditionReason = "RemoteUntrusted"
+	ReasonAgentSyncStale          v1alpha1.ConditionReason = "DefinitionsStale"
+	ReasonAgentSyncLoop           v1alpha1.ConditionReason = "PropagationLoop"
+	ReasonAgentSyncRemoteDeleted  v1alpha1.ConditionReason = "RemoteDeleted"
+	ReasonAgentSyncRemoteExists   v1alpha1.ConditionReason = "RemoteExists"
+	ReasonAgentSyncDisconnected   v1alpha1.ConditionReason = "Disconnected"
+	ReasonAgentSyncOnboarding     v1alpha1.ConditionReason = "Onboarding"
+	ReasonAgentSyncDrifted        v1alpha1.ConditionReason = "RemoteDrifted"
+	ReasonAgentSyncMissingLabels  v1alpha1.ConditionReason = "MissingRequiredLabels"
+	ReasonAgentSyncSchemaInvalid  v1alpha1.ConditionReason = "SchemaInvalid"
+	ReasonAgentSyncSuspended      v1alpha1.ConditionReason = "Suspended"
+	ReasonAgentSyncQuotaExceeded  v1alpha1.ConditionReason = "QuotaWouldExceed"
+	ReasonAgentSyncRejected       v1alpha1.ConditionReason = "ClaimRejected"
+	ReasonAgentSyncUnknownRemote  v1alpha1.ConditionReason = "UnknownRemote"
 )
 
 // SanitizedDeepCopyObject removes the metadata that can be specific to a cluster.
 // For example, owner references are references to resources in that cluster and
-// would be meaningless in another one.
+// would be meaningless in another one. It uses the DefaultSanitizer.
 func SanitizedDeepCopyObject(in runtime.Object) resource.Object {
-	out, _ := in.DeepCopyObject().(resource.Object)
-	out.SetResourceVersion("")
-	out.SetUID("")
-	out.SetCreationTimestamp(metav1.Time{})
-	out.SetSelfLink("")
-	out.SetOwnerReferences(nil)
-	out.SetManagedFields(nil)
-	out.SetFinalizers(nil)
-	return out
+	return DefaultSanitizer.Sanitize(in)
 }
 
 // AgentSyncSuccess returns a condition indicating that Agent successfully
@@ -70,3 +89,297 @@ func AgentSyncError(err error) v1alpha1.Condition {
 		Message:            err.Error(),
 	}
 }
+
+// AgentSyncObjectTooLarge returns a condition indicating that Agent refused to
+// sync the resource because its serialized form is larger than the limit. A
+// limit that is not positive means the api-server itself rejected the object.
+func AgentSyncObjectTooLarge(size, limit int) v1alpha1.Condition {
+	msg := fmt.Sprintf("serialized object is %d bytes, which exceeds the limit of %d bytes", size, limit)
+	if limit <= 0 {
+		msg = fmt.Sprintf("serialized object is %d bytes, which is larger than the api-server accepts", size)
+	}
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncObjectTooLarge,
+		Message:            msg,
+	}
+}
+
+// AgentSyncCanaryFailed returns a condition indicating that Agent did not sync
+// the resource because its canary in the staging namespace was rejected.
+func AgentSyncCanaryFailed(err error) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncCanaryFailed,
+		Message:            err.Error(),
+	}
+}
+
+// AgentSyncRolloutPaused returns a condition indicating that Agent is holding
+// an update to the resource because the rollout of updates is paused.
+func AgentSyncRolloutPaused(msg string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncRolloutPaused,
+		Message:            msg,
+	}
+}
+
+// AgentSyncRemoteReplaced returns a condition indicating that the remote
+// object was deleted and created again by someone other than Agent.
+func AgentSyncRemoteReplaced(was, is string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncRemoteReplaced,
+		Message: fmt.Sprintf("remote object was replaced out-of-band, its uid changed from %s to %s; "+
+			"remove the %s annotation to adopt it", was, is, AnnotationKeyRemoteUID),
+	}
+}
+
+// AgentSyncRemoteDeleted returns a condition indicating that the remote object
+// is being deleted by someone other than Agent, and that Agent will let it go
+// and create it again.
+func AgentSyncRemoteDeleted(uid string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncRemoteDeleted,
+		Message:            fmt.Sprintf("remote object with uid %s is being deleted out-of-band, it will be created again", uid),
+	}
+}
+
+// AgentSyncDrifted returns a condition indicating that the spec of the remote
+// object was changed out-of-band, how it differed, and that Agent pushed the
+// desired spec again.
+func AgentSyncDrifted(diff string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncDrifted,
+		Message:            fmt.Sprintf("remote object was changed out-of-band, the desired spec was pushed again: %s", diff),
+	}
+}
+
+// AgentSyncRemoteExists returns a condition indicating that the remote object
+// was created by someone other than Agent before the object was first synced,
+// and how it can be adopted.
+func AgentSyncRemoteExists(uid, owner string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncRemoteExists,
+		Message: fmt.Sprintf("remote object with uid %s already exists and was not created by Agent for this object (%s); "+
+			"set the %s annotation to %s to adopt it", uid, owner, AnnotationKeyRemoteUID, uid),
+	}
+}
+
+// AgentSyncDisconnected returns a condition indicating that the remote cluster
+// has been unreachable for so long that nothing is written to it until it's
+// reachable again, and when it's checked next.
+func AgentSyncDisconnected(since, next time.Time) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncDisconnected,
+		Message: fmt.Sprintf("remote cluster has been unreachable since %s, nothing is written to it until it's reachable again; "+
+			"it's probed next at %s", since.UTC().Format(time.RFC3339), next.UTC().Format(time.RFC3339)),
+	}
+}
+
+// AgentSyncOnboarding returns a condition indicating that the object existed
+// before Agent was started and waits for its turn to be synced for the first
+// time, along with the progress of the onboarding.
+func AgentSyncOnboarding(progress string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncOnboarding,
+		Message:            "waiting to be synced for the first time in a throttled batch of pre-existing objects: " + progress,
+	}
+}
+
+// AgentSyncLocked returns a condition indicating that the remote namespace is
+// synced by another agent.
+func AgentSyncLocked(holder string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncLocked,
+		Message:            fmt.Sprintf("remote namespace is synced by another agent: %s", holder),
+	}
+}
+
+// AgentSyncFenced returns a condition indicating that the remote object was
+// written by a newer agent, so this one no longer writes to it.
+func AgentSyncFenced(theirs, ours int64) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncFenced,
+		Message:            fmt.Sprintf("remote object was written by a newer agent with fencing token %d, this agent has %d", theirs, ours),
+	}
+}
+
+// AgentSyncNamespaceUnavailable returns a condition indicating that the remote
+// namespace cannot accept the object.
+func AgentSyncNamespaceUnavailable(err error) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncNoNamespace,
+		Message:            err.Error(),
+	}
+}
+
+// AgentSyncWaitingOnDependency returns a condition indicating that the object
+// is not pushed until something it refers to exists in the remote cluster.
+func AgentSyncWaitingOnDependency(err error) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncWaiting,
+		Message:            err.Error(),
+	}
+}
+
+// AgentSyncUnknownRemote returns a condition indicating that the object is not
+// synced because the remote cluster it's labelled with, or the one it was
+// synced to before, is not one the agent is configured with.
+func AgentSyncUnknownRemote(name string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncUnknownRemote,
+		Message:            fmt.Sprintf("remote cluster %q is not configured; set the %s label to one that is", name, LabelKeyRemote),
+	}
+}
+
+// AgentSyncPendingApproval returns a condition indicating that the object is
+// not pushed until it's approved.
+func AgentSyncPendingApproval() v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncPending,
+		Message:            fmt.Sprintf("waiting for the %s annotation to be set to true", AnnotationKeyApproved),
+	}
+}
+
+// AgentSyncMissingLabels returns a condition indicating that the object is not
+// pushed until it has the labels that the remote cluster requires.
+func AgentSyncMissingLabels(keys []string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncMissingLabels,
+		Message:            fmt.Sprintf("labels required by the remote cluster are missing: %s", strings.Join(keys, ", ")),
+	}
+}
+
+// AgentSyncSchemaInvalid returns a condition indicating that the object is not
+// pushed because it doesn't conform to the schema of the remote cluster.
+func AgentSyncSchemaInvalid(err error) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncSchemaInvalid,
+		Message:            fmt.Sprintf("object doesn't conform to the schema of the remote cluster: %s", err),
+	}
+}
+
+// AgentSyncQuotaWouldExceed returns a condition indicating that the object is
+// not created in the remote cluster because it'd exceed the supplied quotas of
+// its namespace there.
+func AgentSyncQuotaWouldExceed(quotas []string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncQuotaExceeded,
+		Message:            fmt.Sprintf("creating the object would exceed the quotas of its namespace in the remote cluster: %s", strings.Join(quotas, "; ")),
+	}
+}
+
+// AgentSyncRejected returns a condition indicating that the object is not
+// pushed because the policies of the platform reject it.
+func AgentSyncRejected(msg string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncRejected,
+		Message:            fmt.Sprintf("object is rejected by the admission policies of the platform: %s", msg),
+	}
+}
+
+// AgentSyncSuspended returns a condition indicating that Agent doesn't
+// propagate any changes to the resource because it's suspended. Its status is
+// still pulled.
+func AgentSyncSuspended(msg string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncSuspended,
+		Message:            fmt.Sprintf("%s, nothing is pushed to or deleted from the remote cluster until it's resumed", msg),
+	}
+}
+
+// AgentSyncRemoteUntrusted returns a condition indicating that Agent did not
+// sync the resource because the remote cluster presented a certificate that
+// doesn't match the pinned ones.
+func AgentSyncRemoteUntrusted(err error) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncUntrusted,
+		Message:            err.Error(),
+	}
+}
+
+// AgentSyncDefinitionsStale returns a condition indicating that the object is
+// not pushed until the definitions are refreshed from the remote cluster.
+func AgentSyncDefinitionsStale(msg string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncStale,
+		Message:            msg,
+	}
+}
+
+// AgentSyncLoop returns a condition indicating that Agent did not sync the
+// resource because it was already synced through the local cluster, i.e. the
+// agents are chained in a loop.
+func AgentSyncLoop(chain []string) v1alpha1.Condition {
+	return v1alpha1.Condition{
+		Type:               TypeAgentSync,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonAgentSyncLoop,
+		Message:            fmt.Sprintf("claim was already synced through this cluster: %s", strings.Join(chain, " -> ")),
+	}
+}
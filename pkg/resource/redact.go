@@ -0,0 +1,170 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+// RedactedValue replaces the values that are not safe to be logged or diffed.
+const RedactedValue = "<redacted>"
+
+// sensitiveKeys are the logging keys whose values are always redacted
+// regardless of their type. Keys are matched whole, ignoring case and the
+// separators in keySeparators, so that keys that merely contain one of them,
+// like fencing-token, are still logged.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"passwd":        true,
+	"token":         true,
+	"bearertoken":   true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"idtoken":       true,
+	"authtoken":     true,
+	"apitoken":      true,
+	"apikey":        true,
+	"accesskey":     true,
+	"secretkey":     true,
+	"secret":        true,
+	"clientsecret":  true,
+	"kubeconfig":    true,
+	"credential":    true,
+	"credentials":   true,
+	"privatekey":    true,
+	"authorization": true,
+}
+
+// keySeparators are removed from logging keys before they're matched against
+// sensitiveKeys.
+var keySeparators = strings.NewReplacer("-", "", "_", "", ".", "", " ", "")
+
+// RedactData returns the key names of the supplied secret data, each mapped to
+// RedactedValue.
+func RedactData(in map[string][]byte) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k := range in {
+		out[k] = RedactedValue
+	}
+	return out
+}
+
+// RedactObject returns a copy of the supplied object whose values that may
+// hold secret data are replaced with RedactedValue. Only Secrets, typed or
+// unstructured, carry such data; any other object is returned as is.
+func RedactObject(in runtime.Object) runtime.Object {
+	switch o := in.(type) {
+	case *corev1.Secret:
+		out := o.DeepCopy()
+		for k := range out.Data {
+			out.Data[k] = []byte(RedactedValue)
+		}
+		for k := range out.StringData {
+			out.StringData[k] = RedactedValue
+		}
+		return out
+	case *kunstructured.Unstructured:
+		if o.GetKind() != "Secret" {
+			return o
+		}
+		out := o.DeepCopy()
+		for _, f := range []string{"data", "stringData"} {
+			m, ok := out.Object[f].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for k := range m {
+				m[k] = RedactedValue
+			}
+		}
+		return out
+	}
+	return in
+}
+
+// RedactValue returns a form of the supplied logging value that is safe to
+// be written out. Raw bytes are never considered safe.
+func RedactValue(key string, v interface{}) interface{} {
+	switch o := v.(type) {
+	case []byte:
+		return RedactedValue
+	case map[string][]byte:
+		return RedactData(o)
+	case corev1.Secret:
+		return RedactObject(&o)
+	case runtime.Object:
+		return RedactObject(o)
+	case *rest.Config:
+		// rest.Config takes care of masking its own credentials.
+		return o.String()
+	}
+	if isSensitiveKey(key) {
+		return RedactedValue
+	}
+	return v
+}
+
+func isSensitiveKey(key string) bool {
+	return sensitiveKeys[keySeparators.Replace(strings.ToLower(key))]
+}
+
+// RedactKeysAndValues returns a copy of the supplied logging key value pairs
+// with every value passed through RedactValue.
+func RedactKeysAndValues(keysAndValues ...interface{}) []interface{} {
+	out := make([]interface{}, len(keysAndValues))
+	for i := range keysAndValues {
+		if i%2 == 0 {
+			out[i] = keysAndValues[i]
+			continue
+		}
+		k, _ := keysAndValues[i-1].(string)
+		out[i] = RedactValue(k, keysAndValues[i])
+	}
+	return out
+}
+
+// NewRedactingLogger returns a logging.Logger that redacts all values before
+// they reach the supplied logging.Logger.
+func NewRedactingLogger(l logging.Logger) logging.Logger {
+	return redactingLogger{wrapped: l}
+}
+
+type redactingLogger struct {
+	wrapped logging.Logger
+}
+
+func (l redactingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.wrapped.Info(msg, RedactKeysAndValues(keysAndValues...)...)
+}
+
+func (l redactingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.wrapped.Debug(msg, RedactKeysAndValues(keysAndValues...)...)
+}
+
+func (l redactingLogger) WithValues(keysAndValues ...interface{}) logging.Logger {
+	return redactingLogger{wrapped: l.wrapped.WithValues(RedactKeysAndValues(keysAndValues...)...)}
+}
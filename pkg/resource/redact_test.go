@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+const secretValue = "sup3r-s3cr3t"
+
+type recordingLogger struct {
+	lines *[]string
+	kv    []interface{}
+}
+
+func (l recordingLogger) record(msg string, keysAndValues ...interface{}) {
+	*l.lines = append(*l.lines, fmt.Sprintf("%s %+v %+v", msg, l.kv, keysAndValues))
+}
+
+func (l recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues...)
+}
+
+func (l recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues...)
+}
+
+func (l recordingLogger) WithValues(keysAndValues ...interface{}) logging.Logger {
+	return recordingLogger{lines: l.lines, kv: append(l.kv, keysAndValues...)}
+}
+
+func TestRedactingLogger(t *testing.T) {
+	secret := &corev1.Secret{
+		Data:       map[string][]byte{"password": []byte(secretValue)},
+		StringData: map[string]string{"token": secretValue},
+	}
+	u := &kunstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"data":       map[string]interface{}{"password": secretValue},
+	}}
+	cases := map[string]struct {
+		reason        string
+		keysAndValues []interface{}
+	}{
+		"TypedSecret": {
+			reason:        "Data of typed Secrets should not be logged",
+			keysAndValues: []interface{}{"secret", secret, "value", *secret},
+		},
+		"UnstructuredSecret": {
+			reason:        "Data of unstructured Secrets should not be logged",
+			keysAndValues: []interface{}{"object", u},
+		},
+		"RawData": {
+			reason:        "Raw bytes and secret data maps should not be logged",
+			keysAndValues: []interface{}{"bytes", []byte(secretValue), "data", secret.Data},
+		},
+		"SensitiveKey": {
+			reason:        "Values of keys that look sensitive should not be logged",
+			keysAndValues: []interface{}{"bearer-token", secretValue, "kubeconfig", secretValue},
+		},
+		"RESTConfig": {
+			reason:        "Credentials in REST configs should not be logged",
+			keysAndValues: []interface{}{"config", &rest.Config{BearerToken: secretValue}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var lines []string
+			l := NewRedactingLogger(recordingLogger{lines: &lines})
+			l.Info("info", tc.keysAndValues...)
+			l.Debug("debug", tc.keysAndValues...)
+			l.WithValues(tc.keysAndValues...).Info("with values")
+			l.WithValues(tc.keysAndValues...).Debug("with values")
+
+			for _, line := range lines {
+				if strings.Contains(line, secretValue) {
+					t.Errorf("\nReason: %s\nsecret value found in log line: %s", tc.reason, line)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		key    string
+		want   interface{}
+	}{
+		"SensitiveKey": {
+			reason: "Values of keys that are known to be sensitive should be redacted",
+			key:    "Bearer_Token",
+			want:   RedactedValue,
+		},
+		"KeyContainingSensitiveWord": {
+			reason: "Values of keys that merely contain a sensitive word should be logged",
+			key:    "fencing-token",
+			want:   "42",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := RedactValue(tc.key, "42")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nRedactValue(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRedactObject(t *testing.T) {
+	in := &corev1.Secret{Data: map[string][]byte{"password": []byte(secretValue)}}
+	got := RedactObject(in)
+	want := &corev1.Secret{Data: map[string][]byte{"password": []byte(RedactedValue)}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nRedactObject(...): -want, +got:\n%s", diff)
+	}
+	if string(in.Data["password"]) != secretValue {
+		t.Errorf("\nRedactObject(...): the supplied object should not be modified")
+	}
+}
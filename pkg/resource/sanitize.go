@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"reflect"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// DefaultSanitizer removes the metadata that can be specific to a cluster,
+// including finalizers, and keeps the status. It's what
+// SanitizedDeepCopyObject uses.
+var DefaultSanitizer = NewSanitizer(KeepStatus())
+
+// A SanitizerOption configures a Sanitizer.
+type SanitizerOption func(*Sanitizer)
+
+// KeepFinalizers makes the Sanitizer keep the finalizers of objects, e.g. when
+// they're mirrored into a cluster where the same controllers run.
+func KeepFinalizers() SanitizerOption {
+	return func(s *Sanitizer) {
+		s.keepFinalizers = true
+	}
+}
+
+// KeepStatus makes the Sanitizer keep the status of objects.
+func KeepStatus() SanitizerOption {
+	return func(s *Sanitizer) {
+		s.keepStatus = true
+	}
+}
+
+// StripAnnotationsMatching makes the Sanitizer remove the annotations whose
+// keys match any of the supplied expressions, e.g. the ones that admission
+// webhooks of the source cluster inject.
+func StripAnnotationsMatching(re ...*regexp.Regexp) SanitizerOption {
+	return func(s *Sanitizer) {
+		s.annotations = append(s.annotations, re...)
+	}
+}
+
+// StripLabelsMatching makes the Sanitizer remove the labels whose keys match
+// any of the supplied expressions.
+func StripLabelsMatching(re ...*regexp.Regexp) SanitizerOption {
+	return func(s *Sanitizer) {
+		s.labels = append(s.labels, re...)
+	}
+}
+
+// NewSanitizer returns a new *Sanitizer that removes the metadata that can be
+// specific to a cluster, the finalizers and the status of objects, unless it's
+// told to keep them by the supplied options.
+func NewSanitizer(o ...SanitizerOption) *Sanitizer {
+	s := &Sanitizer{}
+	for _, f := range o {
+		f(s)
+	}
+	return s
+}
+
+// A Sanitizer copies objects from one cluster so that they can be written to
+// another. Every controller may compose its own profile of what's removed,
+// depending on the direction the objects are propagated in.
+type Sanitizer struct {
+	keepFinalizers bool
+	keepStatus     bool
+	annotations    []*regexp.Regexp
+	labels         []*regexp.Regexp
+}
+
+// Sanitize returns a sanitized deep copy of the supplied object. Owner
+// references, for example, are references to resources in the source cluster
+// and would be meaningless in another one.
+func (s *Sanitizer) Sanitize(in runtime.Object) resource.Object {
+	out, _ := in.DeepCopyObject().(resource.Object)
+	out.SetResourceVersion("")
+	out.SetUID("")
+	out.SetCreationTimestamp(metav1.Time{})
+	out.SetSelfLink("")
+	out.SetOwnerReferences(nil)
+	out.SetManagedFields(nil)
+	if !s.keepFinalizers {
+		out.SetFinalizers(nil)
+	}
+	if len(s.annotations) > 0 {
+		out.SetAnnotations(strip(out.GetAnnotations(), s.annotations))
+	}
+	if len(s.labels) > 0 {
+		out.SetLabels(strip(out.GetLabels(), s.labels))
+	}
+	if !s.keepStatus {
+		removeStatus(out)
+	}
+	return out
+}
+
+// strip the keys that match any of the supplied expressions from the supplied
+// map, returning nil if none is left.
+func strip(m map[string]string, re []*regexp.Regexp) map[string]string {
+	for k := range m {
+		for _, r := range re {
+			if r.MatchString(k) {
+				delete(m, k)
+				break
+			}
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// removeStatus removes the status of the supplied object, whether it's
+// unstructured or has a Status field.
+func removeStatus(o runtime.Object) {
+	if u, ok := o.(runtime.Unstructured); ok {
+		c := u.UnstructuredContent()
+		delete(c, "status")
+		u.SetUnstructuredContent(c)
+		return
+	}
+	v := reflect.ValueOf(o)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	if f := v.Elem().FieldByName("Status"); f.IsValid() && f.CanSet() {
+		f.Set(reflect.Zero(f.Type()))
+	}
+}
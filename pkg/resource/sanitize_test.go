@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSanitize(t *testing.T) {
+	pod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "cool",
+				UID:             types.UID("cool-uid"),
+				ResourceVersion: "42",
+				Finalizers:      []string{"example.org/cleanup"},
+				OwnerReferences: []metav1.OwnerReference{{Name: "owner"}},
+				Annotations:     map[string]string{"webhook.example.org/injected": "true", "team": "a"},
+				Labels:          map[string]string{"pod-template-hash": "abc", "app": "cool"},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	sanitized := func(mod func(p *corev1.Pod)) *corev1.Pod {
+		p := pod()
+		p.SetUID("")
+		p.SetResourceVersion("")
+		p.SetOwnerReferences(nil)
+		p.SetFinalizers(nil)
+		p.Status = corev1.PodStatus{}
+		if mod != nil {
+			mod(p)
+		}
+		return p
+	}
+	u := &kunstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.org/v1",
+		"kind":       "Database",
+		"metadata":   map[string]interface{}{"name": "cool", "finalizers": []interface{}{"example.org/cleanup"}},
+		"spec":       map[string]interface{}{"size": int64(2)},
+		"status":     map[string]interface{}{"ready": true},
+	}}
+
+	cases := map[string]struct {
+		reason string
+		s      *Sanitizer
+		in     runtime.Object
+		want   runtime.Object
+	}{
+		"Default": {
+			reason: "The DefaultSanitizer should remove the finalizers but keep the status",
+			s:      DefaultSanitizer,
+			in:     pod(),
+			want:   sanitized(func(p *corev1.Pod) { p.Status = pod().Status }),
+		},
+		"Bare": {
+			reason: "A Sanitizer without options should remove the finalizers and the status",
+			s:      NewSanitizer(),
+			in:     pod(),
+			want:   sanitized(nil),
+		},
+		"KeepFinalizers": {
+			reason: "A Sanitizer that keeps finalizers should not remove them",
+			s:      NewSanitizer(KeepFinalizers()),
+			in:     pod(),
+			want:   sanitized(func(p *corev1.Pod) { p.SetFinalizers([]string{"example.org/cleanup"}) }),
+		},
+		"StripMatching": {
+			reason: "Only the annotations and labels that match should be removed",
+			s: NewSanitizer(
+				StripAnnotationsMatching(regexp.MustCompile(`^webhook\.example\.org/`)),
+				StripLabelsMatching(regexp.MustCompile(`^pod-template-hash$`), regexp.MustCompile(`^app$`)),
+			),
+			in: pod(),
+			want: sanitized(func(p *corev1.Pod) {
+				p.SetAnnotations(map[string]string{"team": "a"})
+				p.SetLabels(nil)
+			}),
+		},
+		"Unstructured": {
+			reason: "The status of unstructured objects should be removed too",
+			s:      NewSanitizer(),
+			in:     u,
+			want: &kunstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "example.org/v1",
+				"kind":       "Database",
+				"metadata":   map[string]interface{}{"name": "cool"},
+				"spec":       map[string]interface{}{"size": int64(2)},
+			}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.s.Sanitize(tc.in)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\ns.Sanitize(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rollout pauses the rollout of definition updates from the remote
+// cluster when claims in the local cluster start failing to sync.
+//
+// Claims are reconciled by the agent running in local mode while definitions
+// are synced by the agent running in remote mode, so the two communicate over
+// a ConfigMap in the local cluster: ErrorBudget tracks the sync results of
+// claims, Publisher writes the state of the budget into the ConfigMap and
+// ConfigMapGate reads it back to decide whether updates should be held.
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the state of the error budget
+	// is published to.
+	ConfigMapName = "crossplane-agent-error-budget"
+
+	keyPaused    = "paused"
+	keyErrorRate = "errorRate"
+	keySamples   = "samples"
+	keyUpdated   = "updated"
+
+	// The minimum number of sync results that need to be observed in a window
+	// before the error rate is considered meaningful.
+	minSamples = 10
+
+	errGetConfigMap     = "cannot get error budget configmap"
+	errPublishConfigMap = "cannot publish error budget configmap"
+	errFmtPaused        = "definition updates are paused because %.0f%% of the last %d claim syncs failed"
+)
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// NewErrorBudget returns a new *ErrorBudget that is exhausted when more than
+// the given fraction of claim syncs fail within the given window.
+func NewErrorBudget(threshold float64, window time.Duration) *ErrorBudget {
+	return &ErrorBudget{threshold: threshold, window: window, now: time.Now}
+}
+
+// An ErrorBudget tracks the results of claim syncs within a sliding window.
+type ErrorBudget struct {
+	threshold float64
+	window    time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	outcomes []outcome
+}
+
+// ObserveSync records the result of the last sync of the supplied claim. Only
+// claims that failed with an error count as failures; the ones that are held
+// on purpose, e.g. pending approval or suspended, don't. Claims that are being
+// deleted are not recorded.
+func (b *ErrorBudget) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	if meta.WasDeleted(c) {
+		return
+	}
+	b.Record(c.GetCondition(resource.TypeAgentSync).Reason == resource.ReasonAgentSyncError)
+}
+
+// Record the result of a sync.
+func (b *ErrorBudget) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.outcomes = append(b.trim(), outcome{at: b.now(), failed: failed})
+}
+
+// Reset forgets all recorded results.
+func (b *ErrorBudget) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.outcomes = nil
+}
+
+// ErrorRate returns the fraction of the syncs within the window that failed
+// and the number of syncs it's calculated from.
+func (b *ErrorBudget) ErrorRate() (float64, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.outcomes = b.trim()
+	if len(b.outcomes) == 0 {
+		return 0, 0
+	}
+	failed := 0
+	for _, o := range b.outcomes {
+		if o.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(b.outcomes)), len(b.outcomes)
+}
+
+// Exhausted returns true if the error rate within the window is above the
+// threshold.
+func (b *ErrorBudget) Exhausted() bool {
+	rate, n := b.ErrorRate()
+	return n >= minSamples && rate > b.threshold
+}
+
+// trim returns the outcomes that are still within the window. It must be
+// called with the lock held.
+func (b *ErrorBudget) trim() []outcome {
+	cutoff := b.now().Add(-b.window)
+	for i, o := range b.outcomes {
+		if o.at.After(cutoff) {
+			return b.outcomes[i:]
+		}
+	}
+	return b.outcomes[:0]
+}
+
+// NewPublisher returns a new *Publisher.
+func NewPublisher(b *ErrorBudget, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, log logging.Logger) *Publisher {
+	return &Publisher{budget: b, client: a, name: nn, period: period, log: log}
+}
+
+// A Publisher periodically writes the state of an ErrorBudget into a ConfigMap.
+type Publisher struct {
+	budget *ErrorBudget
+	client runtimeresource.Applicator
+	name   types.NamespacedName
+	period time.Duration
+	log    logging.Logger
+}
+
+// Start publishing until the supplied channel is closed.
+func (p *Publisher) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(p.period)
+	defer t.Stop()
+	for {
+		if err := p.Publish(context.Background()); err != nil {
+			p.log.Debug("Cannot publish error budget", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Publish the current state of the ErrorBudget.
+func (p *Publisher) Publish(ctx context.Context) error {
+	rate, n := p.budget.ErrorRate()
+	data := map[string]string{
+		keyPaused:    strconv.FormatBool(p.budget.Exhausted()),
+		keyErrorRate: strconv.FormatFloat(rate, 'f', 4, 64),
+		keySamples:   strconv.Itoa(n),
+		keyUpdated:   time.Now().UTC().Format(time.RFC3339),
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, p.client, p.name, data), errPublishConfigMap)
+}
+
+// A Gate decides whether updates to definitions may be rolled out.
+type Gate interface {
+	// Paused returns true and the reason if updates should be held.
+	Paused(ctx context.Context) (bool, string, error)
+}
+
+// GateFn is used to construct a Gate with a bare function.
+type GateFn func(ctx context.Context) (bool, string, error)
+
+// Paused calls the supplied function.
+func (fn GateFn) Paused(ctx context.Context) (bool, string, error) {
+	return fn(ctx)
+}
+
+// NewOpenGate returns a Gate that never pauses updates.
+func NewOpenGate() GateFn {
+	return func(_ context.Context) (bool, string, error) { return false, "", nil }
+}
+
+// NewConfigMapGate returns a new *ConfigMapGate. The published state is
+// ignored once it's older than the supplied maximum age so that updates are
+// not held forever by a publisher that's gone.
+func NewConfigMapGate(c client.Reader, nn types.NamespacedName, maxAge time.Duration) *ConfigMapGate {
+	return &ConfigMapGate{client: c, name: nn, maxAge: maxAge}
+}
+
+// A ConfigMapGate pauses updates according to the error budget state that is
+// published by a Publisher.
+type ConfigMapGate struct {
+	client client.Reader
+	name   types.NamespacedName
+	maxAge time.Duration
+}
+
+// Paused returns true if the published error budget is exhausted.
+func (g *ConfigMapGate) Paused(ctx context.Context) (bool, string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := g.client.Get(ctx, g.name, cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", errors.Wrap(err, errGetConfigMap)
+	}
+	if paused, _ := strconv.ParseBool(cm.Data[keyPaused]); !paused {
+		return false, "", nil
+	}
+	if updated, err := time.Parse(time.RFC3339, cm.Data[keyUpdated]); err != nil || time.Since(updated) > g.maxAge {
+		return false, "", nil
+	}
+	rate, _ := strconv.ParseFloat(cm.Data[keyErrorRate], 64)
+	n, _ := strconv.Atoi(cm.Data[keySamples])
+	return true, fmt.Sprintf(errFmtPaused, rate*100, n), nil
+}
+
+// Changed returns true if the supplied objects differ in anything other than
+// their metadata and status, i.e. if applying desired would be an update to
+// the definition current describes.
+func Changed(current, desired runtime.Object) bool {
+	c, err := runtime.DefaultUnstructuredConverter.ToUnstructured(current)
+	if err != nil {
+		return true
+	}
+	d, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return true
+	}
+	for _, f := range []string{"apiVersion", "kind", "metadata", "status"} {
+		delete(c, f)
+		delete(d, f)
+	}
+	return !reflect.DeepEqual(c, d)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestErrorBudget(t *testing.T) {
+	type want struct {
+		rate      float64
+		samples   int
+		exhausted bool
+	}
+	cases := map[string]struct {
+		reason  string
+		results []bool
+		age     time.Duration
+		want    want
+	}{
+		"TooFewSamples": {
+			reason:  "The budget should not be exhausted until enough syncs are observed",
+			results: []bool{true, true, true},
+			want:    want{rate: 1, samples: 3},
+		},
+		"Exhausted": {
+			reason:  "The budget should be exhausted if the error rate is above the threshold",
+			results: []bool{true, true, true, true, true, true, false, false, false, false},
+			want:    want{rate: 0.6, samples: 10, exhausted: true},
+		},
+		"WithinBudget": {
+			reason:  "The budget should not be exhausted if the error rate is below the threshold",
+			results: []bool{true, false, false, false, false, false, false, false, false, false},
+			want:    want{rate: 0.1, samples: 10},
+		},
+		"OutsideWindow": {
+			reason:  "Syncs that are older than the window should not count",
+			results: []bool{true, true, true, true, true, true, true, true, true, true},
+			age:     time.Hour,
+			want:    want{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			b := NewErrorBudget(0.5, 10*time.Minute)
+			b.now = func() time.Time { return now.Add(-tc.age) }
+			for _, failed := range tc.results {
+				b.Record(failed)
+			}
+			b.now = func() time.Time { return now }
+
+			rate, n := b.ErrorRate()
+			got := want{rate: rate, samples: n, exhausted: b.Exhausted()}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nErrorBudget: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestErrorBudgetObserveSync(t *testing.T) {
+	type want struct {
+		rate    float64
+		samples int
+	}
+	cases := map[string]struct {
+		reason  string
+		cond    v1alpha1.Condition
+		deleted bool
+		want    want
+	}{
+		"Success": {
+			reason: "A claim that synced should be recorded as a success",
+			cond:   resource.AgentSyncSuccess(),
+			want:   want{rate: 0, samples: 1},
+		},
+		"Error": {
+			reason: "A claim that failed to sync should be recorded as a failure",
+			cond:   resource.AgentSyncError(errors.New("boom")),
+			want:   want{rate: 1, samples: 1},
+		},
+		"Held": {
+			reason: "A claim that's held on purpose should not be recorded as a failure",
+			cond:   resource.AgentSyncPendingApproval(),
+			want:   want{rate: 0, samples: 1},
+		},
+		"Deleted": {
+			reason:  "A claim that's being deleted should not be recorded",
+			cond:    resource.AgentSyncError(errors.New("boom")),
+			deleted: true,
+			want:    want{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := NewErrorBudget(0.5, 10*time.Minute)
+			cl := claim.New()
+			cl.SetConditions(tc.cond)
+			if tc.deleted {
+				now := metav1.Now()
+				cl.SetDeletionTimestamp(&now)
+			}
+			b.ObserveSync(context.Background(), cl)
+
+			rate, n := b.ErrorRate()
+			got := want{rate: rate, samples: n}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nErrorBudget: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		current *corev1.ConfigMap
+		desired *corev1.ConfigMap
+		want    bool
+	}{
+		"MetadataOnly": {
+			reason:  "Differences in metadata should not count as a change",
+			current: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}, Data: map[string]string{"a": "b"}},
+			desired: &corev1.ConfigMap{Data: map[string]string{"a": "b"}},
+			want:    false,
+		},
+		"Content": {
+			reason:  "Differences in content should count as a change",
+			current: &corev1.ConfigMap{Data: map[string]string{"a": "b"}},
+			desired: &corev1.ConfigMap{Data: map[string]string{"a": "c"}},
+			want:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Changed(tc.current, tc.desired)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nChanged(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
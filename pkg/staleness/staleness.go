@@ -0,0 +1,214 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package staleness keeps track of how long ago the definitions were last
+// refreshed from the remote cluster.
+//
+// Definitions are synced by the agent running in remote mode while claims are
+// reconciled by the agent running in local mode, so the two communicate over
+// a ConfigMap in the local cluster: Recorder writes the time definitions were
+// last refreshed into it and Gate reads it back to decide whether new claims
+// should be held.
+package staleness
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	crds "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the time definitions were
+	// last refreshed is published to.
+	ConfigMapName = "crossplane-agent-definitions"
+
+	keyRefreshed = "refreshed"
+	keyStatus    = "status"
+	keyUpdated   = "updated"
+
+	// StatusFresh means definitions were refreshed within the maximum
+	// staleness.
+	StatusFresh = "Fresh"
+
+	// StatusDegraded means they were not.
+	StatusDegraded = "Degraded"
+
+	errListCRDs         = "cannot list remote custom resource definitions"
+	errListCompositions = "cannot list remote compositions"
+	errGetConfigMap     = "cannot get definitions configmap"
+	errPublish          = "cannot publish definitions configmap"
+	errFmtStale         = "definitions were last refreshed from the remote cluster %s ago, which is longer than the maximum staleness of %s"
+	errNeverRefreshed   = "definitions were never refreshed from the remote cluster"
+)
+
+// NewRecorder returns a new *Recorder.
+func NewRecorder(remote client.Reader, local client.Client, nn types.NamespacedName, period, maxStaleness time.Duration, log logging.Logger) *Recorder {
+	return &Recorder{
+		remote:       remote,
+		local:        local,
+		client:       runtimeresource.NewAPIPatchingApplicator(local),
+		name:         nn,
+		period:       period,
+		maxStaleness: maxStaleness,
+		log:          log,
+		now:          time.Now,
+	}
+}
+
+// A Recorder periodically checks whether definitions can be refreshed from
+// the remote cluster and records when they last were into a ConfigMap.
+type Recorder struct {
+	remote       client.Reader
+	local        client.Reader
+	client       runtimeresource.Applicator
+	name         types.NamespacedName
+	period       time.Duration
+	maxStaleness time.Duration
+	log          logging.Logger
+	now          func() time.Time
+
+	refreshed time.Time
+}
+
+// Start recording until the supplied channel is closed.
+func (r *Recorder) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.period)
+	defer t.Stop()
+	for {
+		if err := r.Record(context.Background()); err != nil {
+			r.log.Debug("Cannot record definition staleness", "error", err)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Record whether definitions can be refreshed right now and publish when they
+// last were.
+func (r *Recorder) Record(ctx context.Context) error {
+	if err := r.refresh(ctx); err != nil {
+		r.log.Debug("Cannot refresh definitions", "error", err)
+	} else {
+		r.refreshed = r.now()
+	}
+
+	// The agent may be restarted while the remote cluster is unreachable, in
+	// which case the time it was last reachable is kept.
+	if r.refreshed.IsZero() {
+		cm := &corev1.ConfigMap{}
+		if err := r.local.Get(ctx, r.name, cm); runtimeresource.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, errGetConfigMap)
+		}
+		r.refreshed, _ = time.Parse(time.RFC3339, cm.Data[keyRefreshed])
+	}
+
+	status := StatusFresh
+	if stale, msg := Stale(r.refreshed, r.now(), r.maxStaleness); stale {
+		status = StatusDegraded
+		r.log.Info("Definitions are stale", "reason", msg)
+	}
+	data := map[string]string{
+		keyStatus:  status,
+		keyUpdated: r.now().UTC().Format(time.RFC3339),
+	}
+	if !r.refreshed.IsZero() {
+		data[keyRefreshed] = r.refreshed.UTC().Format(time.RFC3339)
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, r.client, r.name, data), errPublish)
+}
+
+// refresh reads definitions from the remote cluster, bypassing any cache.
+func (r *Recorder) refresh(ctx context.Context) error {
+	if err := r.remote.List(ctx, &crds.CustomResourceDefinitionList{}, client.Limit(1)); err != nil {
+		return errors.Wrap(err, errListCRDs)
+	}
+	return errors.Wrap(r.remote.List(ctx, &v1alpha1.CompositionList{}, client.Limit(1)), errListCompositions)
+}
+
+// Stale returns true and the reason if definitions that were last refreshed at
+// the supplied time are older than the maximum staleness.
+func Stale(refreshed, now time.Time, maxStaleness time.Duration) (bool, string) {
+	if refreshed.IsZero() {
+		return true, errNeverRefreshed
+	}
+	if age := now.Sub(refreshed); age > maxStaleness {
+		return true, fmt.Sprintf(errFmtStale, age.Round(time.Second), maxStaleness)
+	}
+	return false, ""
+}
+
+// gateTTL is how long a Gate reuses what it last read, since it's asked on
+// every reconcile of every claim.
+const gateTTL = 10 * time.Second
+
+// NewGate returns a new *Gate.
+func NewGate(c client.Reader, nn types.NamespacedName, maxStaleness time.Duration) *Gate {
+	return &Gate{client: c, name: nn, maxStaleness: maxStaleness, now: time.Now}
+}
+
+// A Gate holds new claims while the definitions that are published by a
+// Recorder are stale, so that nothing is provisioned against definitions that
+// may have changed in the meantime.
+type Gate struct {
+	client       client.Reader
+	name         types.NamespacedName
+	maxStaleness time.Duration
+	now          func() time.Time
+
+	mu        sync.Mutex
+	read      time.Time
+	refreshed time.Time
+	published bool
+}
+
+// Stale returns true and the reason if the published definitions are stale.
+// They're never stale if nothing is published.
+func (g *Gate) Stale(ctx context.Context) (bool, string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.now().Sub(g.read) > gateTTL {
+		cm := &corev1.ConfigMap{}
+		err := g.client.Get(ctx, g.name, cm)
+		if runtimeresource.IgnoreNotFound(err) != nil {
+			return false, "", errors.Wrap(err, errGetConfigMap)
+		}
+		g.read = g.now()
+		g.published = !kerrors.IsNotFound(err)
+		g.refreshed, _ = time.Parse(time.RFC3339, cm.Data[keyRefreshed])
+	}
+	if !g.published {
+		return false, "", nil
+	}
+	stale, msg := Stale(g.refreshed, g.now(), g.maxStaleness)
+	return stale, msg, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package staleness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestGate(t *testing.T) {
+	errBoom := errors.New("boom")
+	now := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	published := func(refreshed string) test.MockGetFn {
+		return func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+			cm := obj.(*corev1.ConfigMap)
+			cm.Data = map[string]string{keyRefreshed: refreshed}
+			return nil
+		}
+	}
+
+	type want struct {
+		stale bool
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		get    test.MockGetFn
+		want   want
+	}{
+		"NotPublished": {
+			reason: "Definitions should not be stale if their staleness is not recorded",
+			get:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+		},
+		"GetFailed": {
+			reason: "An error should be returned if the configmap cannot be read",
+			get:    test.NewMockGetFn(errBoom),
+			want:   want{err: errors.Wrap(errBoom, errGetConfigMap)},
+		},
+		"Fresh": {
+			reason: "Definitions that were refreshed within the maximum staleness should not be stale",
+			get:    published(now.Add(-time.Minute).Format(time.RFC3339)),
+		},
+		"Stale": {
+			reason: "Definitions that were refreshed longer ago than the maximum staleness should be stale",
+			get:    published(now.Add(-2 * time.Hour).Format(time.RFC3339)),
+			want:   want{stale: true},
+		},
+		"NeverRefreshed": {
+			reason: "Definitions that were never refreshed should be stale",
+			get:    published(""),
+			want:   want{stale: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewGate(&test.MockClient{MockGet: tc.get}, types.NamespacedName{Name: ConfigMapName}, time.Hour)
+			g.now = func() time.Time { return now }
+			stale, _, err := g.Stale(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nStale(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stale, stale); diff != "" {
+				t.Errorf("\nReason: %s\nStale(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package stats keeps compact statistics of the syncs of every claim in an
+// annotation of the claim, so that they can be collected for troubleshooting.
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+// maxErrorLength is how much of the last error of a claim is recorded.
+const maxErrorLength = 256
+
+const (
+	errParse   = "cannot parse sync statistics"
+	errMarshal = "cannot marshal sync statistics"
+	errPatch   = "cannot record sync statistics"
+)
+
+// Stats of the syncs of a claim.
+type Stats struct {
+	// Syncs is how many times the claim was pushed to the remote cluster.
+	Syncs int64 `json:"syncs"`
+
+	// Failures is how many times the claim started failing to sync, or
+	// failed with a different error than before.
+	Failures int64 `json:"failures"`
+
+	// LastSuccess is when the claim was last pushed, in RFC3339 form.
+	LastSuccess string `json:"lastSuccess,omitempty"`
+
+	// LastFailure is when the claim last failed to sync, in RFC3339 form.
+	LastFailure string `json:"lastFailure,omitempty"`
+
+	// LastError is the error the claim last failed to sync with.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Get returns the statistics recorded on the supplied claim, and false if
+// none were.
+func Get(o metav1.Object) (Stats, bool, error) {
+	s := Stats{}
+	v, ok := o.GetAnnotations()[resource.AnnotationKeySyncStats]
+	if !ok {
+		return s, false, nil
+	}
+	return s, true, errors.Wrap(json.Unmarshal([]byte(v), &s), errParse)
+}
+
+func truncate(msg string) string {
+	if len(msg) > maxErrorLength {
+		return msg[:maxErrorLength]
+	}
+	return msg
+}
+
+type entry struct {
+	gvk   schema.GroupVersionKind
+	nn    types.NamespacedName
+	stats Stats
+
+	// rv is the resource version of the remote claim as of the last push
+	// that was counted.
+	rv      string
+	failing bool
+	dirty   bool
+}
+
+// NewRecorder returns a new *Recorder that writes the statistics it keeps to
+// the claims they belong to every period.
+func NewRecorder(c client.Writer, period time.Duration, log logging.Logger) *Recorder {
+	return &Recorder{client: c, period: period, log: log, now: time.Now, claims: map[types.UID]*entry{}}
+}
+
+// A Recorder keeps the statistics of the syncs of every claim it's told about.
+// A push is told apart from a sync that didn't change anything by the resource
+// version of the remote claim, and a failure from another try of the same one
+// by its error, so that writing the statistics, which syncs the claim again,
+// doesn't change them.
+type Recorder struct {
+	client client.Writer
+	period time.Duration
+	log    logging.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	claims map[types.UID]*entry
+}
+
+// ObserveSync updates the statistics of the supplied claim. The ones recorded
+// on the claim are picked up the first time it's seen.
+func (r *Recorder) ObserveSync(_ context.Context, c *claim.Unstructured) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if meta.WasDeleted(c) {
+		delete(r.claims, c.GetUID())
+		return
+	}
+	cond := c.GetCondition(resource.TypeAgentSync)
+	rv := c.GetAnnotations()[resource.AnnotationKeyLastRemoteResourceVersion]
+	e, ok := r.claims[c.GetUID()]
+	if !ok {
+		s, recorded, err := Get(c)
+		if err != nil {
+			r.log.Debug("Cannot read sync statistics, starting over", "error", err)
+		}
+		e = &entry{
+			gvk:   c.GetObjectKind().GroupVersionKind(),
+			nn:    types.NamespacedName{Namespace: c.GetNamespace(), Name: c.GetName()},
+			stats: s,
+		}
+		if recorded && err == nil {
+			e.rv = rv
+			e.failing = cond.Reason == resource.ReasonAgentSyncError && truncate(cond.Message) == s.LastError
+		}
+		r.claims[c.GetUID()] = e
+	}
+	now := r.now().UTC().Format(time.RFC3339)
+	switch {
+	case cond.Status == corev1.ConditionTrue:
+		e.failing = false
+		if rv != "" && rv != e.rv {
+			e.rv = rv
+			e.stats.Syncs++
+			e.stats.LastSuccess = now
+			e.dirty = true
+		}
+	case cond.Reason == resource.ReasonAgentSyncError:
+		msg := truncate(cond.Message)
+		if !e.failing || msg != e.stats.LastError {
+			e.failing = true
+			e.stats.Failures++
+			e.stats.LastFailure = now
+			e.stats.LastError = msg
+			e.dirty = true
+		}
+	}
+}
+
+// Start writing the statistics until the supplied channel is closed.
+func (r *Recorder) Start(stop <-chan struct{}) error {
+	t := time.NewTicker(r.period)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+		if err := r.Flush(context.Background()); err != nil {
+			r.log.Debug("Cannot record sync statistics", "error", err)
+		}
+	}
+}
+
+// Flush writes the statistics that changed since they were last written to
+// the claims they belong to. The ones that cannot be written are tried again
+// the next time.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	changed := map[types.UID]entry{}
+	for uid, e := range r.claims {
+		if e.dirty {
+			changed[uid] = *e
+			e.dirty = false
+		}
+	}
+	r.mu.Unlock()
+
+	var first error
+	for uid, e := range changed {
+		err := r.write(ctx, e)
+		r.mu.Lock()
+		switch {
+		case kerrors.IsNotFound(errors.Cause(err)):
+			delete(r.claims, uid)
+		case err != nil:
+			if c, ok := r.claims[uid]; ok {
+				c.dirty = true
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		r.mu.Unlock()
+	}
+	return first
+}
+
+func (r *Recorder) write(ctx context.Context, e entry) error {
+	s, err := json.Marshal(e.stats)
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{resource.AnnotationKeySyncStats: string(s)},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(e.gvk)
+	u.SetNamespace(e.nn.Namespace)
+	u.SetName(e.nn.Name)
+	return errors.Wrap(r.client.Patch(ctx, u, client.RawPatch(types.MergePatchType, patch)), errPatch)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+func TestObserveSync(t *testing.T) {
+	// A claim that's observed with the supplied remote resource version,
+	// AgentSynced condition and recorded statistics.
+	type observed struct {
+		rv       string
+		synced   v1alpha1.Condition
+		recorded string
+	}
+	now := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	ts := now.Format(time.RFC3339)
+	success := resource.AgentSyncSuccess()
+	cases := map[string]struct {
+		reason string
+		claims []observed
+		want   Stats
+	}{
+		"Pushed": {
+			reason: "Every new resource version of the remote claim should be counted as a sync",
+			claims: []observed{{"1", success, ""}, {"2", success, ""}},
+			want:   Stats{Syncs: 2, LastSuccess: ts},
+		},
+		"Unchanged": {
+			reason: "A sync that didn't change the remote claim should not be counted",
+			claims: []observed{{"1", success, ""}, {"1", success, ""}},
+			want:   Stats{Syncs: 1, LastSuccess: ts},
+		},
+		"Failed": {
+			reason: "A failure should be counted once until the error changes",
+			claims: []observed{
+				{"", resource.AgentSyncError(errors.New("boom")), ""},
+				{"", resource.AgentSyncError(errors.New("boom")), ""},
+				{"", resource.AgentSyncError(errors.New("bang")), ""},
+			},
+			want: Stats{Failures: 2, LastFailure: ts, LastError: "bang"},
+		},
+		"FailedAgain": {
+			reason: "The same failure should be counted again after a successful sync",
+			claims: []observed{
+				{"", resource.AgentSyncError(errors.New("boom")), ""},
+				{"1", success, ""},
+				{"1", resource.AgentSyncError(errors.New("boom")), ""},
+			},
+			want: Stats{Syncs: 1, Failures: 2, LastSuccess: ts, LastFailure: ts, LastError: "boom"},
+		},
+		"Recorded": {
+			reason: "The statistics recorded on the claim should be picked up without counting what they already count",
+			claims: []observed{
+				{"1", success, `{"syncs":3,"failures":1,"lastError":"boom"}`},
+				{"2", success, ""},
+			},
+			want: Stats{Syncs: 4, Failures: 1, LastSuccess: ts, LastError: "boom"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewRecorder(&test.MockClient{}, time.Minute, logging.NewNopLogger())
+			r.now = func() time.Time { return now }
+			for _, o := range tc.claims {
+				c := claim.New(claim.WithGroupVersionKind(schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "Database"}))
+				c.SetUID("cool")
+				a := map[string]string{resource.AnnotationKeyLastRemoteResourceVersion: o.rv}
+				if o.recorded != "" {
+					a[resource.AnnotationKeySyncStats] = o.recorded
+				}
+				c.SetAnnotations(a)
+				c.SetConditions(o.synced)
+				r.ObserveSync(context.Background(), c)
+			}
+			if diff := cmp.Diff(tc.want, r.claims["cool"].stats); diff != "" {
+				t.Errorf("\nReason: %s\nObserveSync(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFlush(t *testing.T) {
+	errBoom := errors.New("boom")
+	type want struct {
+		err     error
+		patch   string
+		tracked bool
+		dirty   bool
+	}
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   want
+	}{
+		"Written": {
+			reason: "The statistics should be patched into the annotation of the claim",
+			want: want{
+				patch:   `{"metadata":{"annotations":{"` + resource.AnnotationKeySyncStats + `":"{\"syncs\":1,\"failures\":0,\"lastSuccess\":\"2020-10-10T00:00:00Z\"}"}}}`,
+				tracked: true,
+			},
+		},
+		"NotFound": {
+			reason: "The statistics of a claim that no longer exists should be forgotten",
+			err:    kerrors.NewNotFound(schema.GroupResource{}, "cool"),
+		},
+		"Failed": {
+			reason: "The statistics that cannot be written should be tried again",
+			err:    errBoom,
+			want:   want{err: errors.Wrap(errBoom, errPatch), tracked: true, dirty: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var patch string
+			c := &test.MockClient{MockPatch: func(_ context.Context, _ runtime.Object, p client.Patch, _ ...client.PatchOption) error {
+				b, _ := p.Data(nil)
+				patch = string(b)
+				return tc.err
+			}}
+			r := NewRecorder(c, time.Minute, logging.NewNopLogger())
+			r.now = func() time.Time { return time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC) }
+			cl := claim.New(claim.WithGroupVersionKind(schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "Database"}))
+			cl.SetUID("cool")
+			cl.SetAnnotations(map[string]string{resource.AnnotationKeyLastRemoteResourceVersion: "1"})
+			cl.SetConditions(resource.AgentSyncSuccess())
+			r.ObserveSync(context.Background(), cl)
+			err := r.Flush(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nFlush(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if tc.want.patch != "" {
+				if diff := cmp.Diff(tc.want.patch, patch); diff != "" {
+					t.Errorf("\nReason: %s\nFlush(...): -want patch, +got patch:\n%s", tc.reason, diff)
+				}
+			}
+			e, tracked := r.claims[types.UID("cool")]
+			if diff := cmp.Diff(tc.want.tracked, tracked); diff != "" {
+				t.Errorf("\nReason: %s\nFlush(...): -want tracked, +got tracked:\n%s", tc.reason, diff)
+			}
+			if tracked {
+				if diff := cmp.Diff(tc.want.dirty, e.dirty); diff != "" {
+					t.Errorf("\nReason: %s\nFlush(...): -want dirty, +got dirty:\n%s", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package status publishes the overall sync state of the agent, so that its
+// health can be told without inspecting the conditions of every claim.
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/agent/pkg/registration"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap the status is published to.
+	ConfigMapName = "crossplane-agent-status"
+
+	keyHealthy      = "healthy"
+	keyKinds        = "kinds"
+	keyLastSynced   = "lastSynced"
+	keyConnectivity = "connectivity"
+	keyErrors       = "errors"
+	keyUpdated      = "updated"
+
+	errKinds   = "cannot list watched claim kinds"
+	errPublish = "cannot publish agent status"
+)
+
+// Connectivity tells how long the remote cluster has been unreachable.
+type Connectivity interface {
+	Unreachable() time.Duration
+}
+
+// NewReporter returns a new *Reporter.
+func NewReporter(t *registration.ClaimTracker, kinds func(ctx context.Context) ([]schema.GroupVersionKind, error), conn Connectivity, a runtimeresource.Applicator, nn types.NamespacedName, period time.Duration, log logging.Logger) *Reporter {
+	return &Reporter{tracker: t, kinds: kinds, conn: conn, client: a, name: nn, period: period, log: log}
+}
+
+// A Reporter periodically writes the overall sync state of the agent into a
+// ConfigMap.
+type Reporter struct {
+	tracker *registration.ClaimTracker
+	kinds   func(ctx context.Context) ([]schema.GroupVersionKind, error)
+	conn    Connectivity
+	client  runtimeresource.Applicator
+	name    types.NamespacedName
+	period  time.Duration
+	log     logging.Logger
+}
+
+// Start reporting until the supplied channel is closed.
+func (r *Reporter) Start(stop <-chan struct{}) error {
+	return resource.PublishEvery(stop, r.period, r.Report, r.log)
+}
+
+// Report the overall sync state of the agent. The agent is healthy if the
+// remote cluster is reachable and no claim failed to sync.
+func (r *Reporter) Report(ctx context.Context) error {
+	kinds, err := r.kinds(ctx)
+	if err != nil {
+		return errors.Wrap(err, errKinds)
+	}
+	s := r.tracker.Summary()
+	synced := make([]string, 0, len(s.LastSynced))
+	for kind, at := range s.LastSynced {
+		synced = append(synced, fmt.Sprintf("%s: %s", kind, at.UTC().Format(time.RFC3339)))
+	}
+	sort.Strings(synced)
+	connectivity := "Reachable"
+	unreachable := r.conn.Unreachable()
+	if unreachable > 0 {
+		connectivity = fmt.Sprintf("Unreachable for %s", unreachable.Round(time.Second))
+	}
+	data := map[string]string{
+		keyHealthy:      fmt.Sprint(unreachable == 0 && len(s.Errors) == 0),
+		keyKinds:        fmt.Sprint(len(kinds)),
+		keyLastSynced:   strings.Join(synced, "\n"),
+		keyConnectivity: connectivity,
+		keyErrors:       strings.Join(s.Errors, "\n"),
+		keyUpdated:      time.Now().UTC().Format(time.RFC3339),
+	}
+	return errors.Wrap(resource.PublishConfigMap(ctx, r.client, r.name, data), errPublish)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	runtimeresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/claim"
+
+	"github.com/crossplane/agent/pkg/registration"
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+type unreachable time.Duration
+
+func (u unreachable) Unreachable() time.Duration { return time.Duration(u) }
+
+func TestReport(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Database"}
+
+	type want struct {
+		healthy      string
+		connectivity string
+		errors       string
+	}
+
+	cases := map[string]struct {
+		reason string
+		synced bool
+		conn   Connectivity
+		want   want
+	}{
+		"Healthy": {
+			reason: "The agent should be reported healthy if the remote cluster is reachable and every claim is synced",
+			synced: true,
+			conn:   unreachable(0),
+			want:   want{healthy: "true", connectivity: "Reachable"},
+		},
+		"Errors": {
+			reason: "The agent should be reported unhealthy along with the claims that are not synced",
+			conn:   unreachable(0),
+			want:   want{healthy: "false", connectivity: "Reachable", errors: "Database Error: 1: boom"},
+		},
+		"Unreachable": {
+			reason: "The agent should be reported unhealthy if the remote cluster is unreachable",
+			synced: true,
+			conn:   unreachable(90 * time.Second),
+			want:   want{healthy: "false", connectivity: "Unreachable for 1m30s"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := claim.New(claim.WithGroupVersionKind(gvk))
+			c.SetUID(types.UID("cool-uid"))
+			c.SetConditions(resource.AgentSyncError(errors.New("boom")))
+			if tc.synced {
+				c.SetConditions(resource.AgentSyncSuccess())
+			}
+			tr := registration.NewClaimTracker(10 * time.Minute)
+			tr.ObserveSync(context.Background(), c)
+
+			var got want
+			a := runtimeresource.ApplyFn(func(_ context.Context, o runtime.Object, _ ...runtimeresource.ApplyOption) error {
+				data := o.(*corev1.ConfigMap).Data
+				got = want{healthy: data[keyHealthy], connectivity: data[keyConnectivity], errors: data[keyErrors]}
+				return nil
+			})
+			kinds := func(_ context.Context) ([]schema.GroupVersionKind, error) { return []schema.GroupVersionKind{gvk}, nil }
+			r := NewReporter(tr, kinds, tc.conn, a, types.NamespacedName{Namespace: "crossplane-system", Name: ConfigMapName}, time.Minute, logging.NewNopLogger())
+			if err := r.Report(context.Background()); err != nil {
+				t.Fatalf("\nReason: %s\nr.Report(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\nReason: %s\nr.Report(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
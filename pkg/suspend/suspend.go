@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package suspend is the emergency brake of the agent. While it's suspended,
+// nothing is propagated to or pruned from either cluster, but the status of
+// claims, the reports and the probes are kept up to date, unlike when the
+// agent is scaled down to zero.
+//
+// The agent is suspended either by a flag, or by annotating the ConfigMap
+// named ConfigMapName in the namespace of the agent with
+// AnnotationKeySuspended set to "true", which takes effect without a restart.
+package suspend
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/agent/pkg/resource"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap in the local cluster that
+	// the agent is suspended with.
+	ConfigMapName = "crossplane-agent-config"
+
+	// AnnotationKeySuspended is set to "true" on the ConfigMap to suspend
+	// the agent.
+	AnnotationKeySuspended = resource.AnnotationKeyPrefix + "suspended"
+
+	msgFlag         = "agent is suspended by its --suspend flag"
+	msgFmtConfigMap = "agent is suspended by the %s annotation of ConfigMap %s"
+
+	errGetConfigMap = "cannot get agent configmap"
+)
+
+// A Switch decides whether the agent is suspended.
+type Switch interface {
+	// Suspended returns true and the reason if the agent is suspended.
+	Suspended(ctx context.Context) (bool, string, error)
+}
+
+// SwitchFn is used to construct a Switch with a bare function.
+type SwitchFn func(ctx context.Context) (bool, string, error)
+
+// Suspended calls the supplied function.
+func (fn SwitchFn) Suspended(ctx context.Context) (bool, string, error) {
+	return fn(ctx)
+}
+
+// NewNopSwitch returns a Switch that never suspends the agent.
+func NewNopSwitch() SwitchFn {
+	return func(_ context.Context) (bool, string, error) { return false, "", nil }
+}
+
+// NewConfigMapSwitch returns a new *ConfigMapSwitch. The agent is always
+// suspended if the supplied flag is set.
+func NewConfigMapSwitch(c client.Reader, nn types.NamespacedName, flag bool) *ConfigMapSwitch {
+	return &ConfigMapSwitch{client: c, name: nn, flag: flag}
+}
+
+// A ConfigMapSwitch suspends the agent according to an annotation of a
+// ConfigMap, unless it was suspended when it was started.
+type ConfigMapSwitch struct {
+	client client.Reader
+	name   types.NamespacedName
+	flag   bool
+}
+
+// Suspended returns true if the agent was suspended when it was started or
+// the ConfigMap is annotated to suspend it. The agent is not suspended if the
+// ConfigMap doesn't exist.
+func (s *ConfigMapSwitch) Suspended(ctx context.Context) (bool, string, error) {
+	if s.flag {
+		return true, msgFlag, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, s.name, cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", errors.Wrap(err, errGetConfigMap)
+	}
+	if suspended, _ := strconv.ParseBool(cm.GetAnnotations()[AnnotationKeySuspended]); !suspended {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf(msgFmtConfigMap, AnnotationKeySuspended, s.name), nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package suspend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestConfigMapSwitch(t *testing.T) {
+	errBoom := errors.New("boom")
+	nn := types.NamespacedName{Namespace: "crossplane-system", Name: ConfigMapName}
+	withAnnotations := func(a map[string]string) test.MockGetFn {
+		return func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+			obj.(*corev1.ConfigMap).SetAnnotations(a)
+			return nil
+		}
+	}
+
+	type want struct {
+		suspended bool
+		msg       string
+		err       error
+	}
+	cases := map[string]struct {
+		reason string
+		get    test.MockGetFn
+		flag   bool
+		want   want
+	}{
+		"Flag": {
+			reason: "The agent should be suspended if it was started with the flag",
+			get:    test.NewMockGetFn(errBoom),
+			flag:   true,
+			want:   want{suspended: true, msg: msgFlag},
+		},
+		"Annotated": {
+			reason: "The agent should be suspended if the ConfigMap is annotated to suspend it",
+			get:    withAnnotations(map[string]string{AnnotationKeySuspended: "true"}),
+			want:   want{suspended: true, msg: "agent is suspended by the " + AnnotationKeySuspended + " annotation of ConfigMap crossplane-system/" + ConfigMapName},
+		},
+		"Resumed": {
+			reason: "The agent should not be suspended if the annotation is not true",
+			get:    withAnnotations(map[string]string{AnnotationKeySuspended: "false"}),
+		},
+		"NoConfigMap": {
+			reason: "The agent should not be suspended if the ConfigMap doesn't exist",
+			get:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, ConfigMapName)),
+		},
+		"GetError": {
+			reason: "Errors getting the ConfigMap should be returned",
+			get:    test.NewMockGetFn(errBoom),
+			want:   want{err: errors.Wrap(errBoom, errGetConfigMap)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := NewConfigMapSwitch(&test.MockClient{MockGet: tc.get}, nn, tc.flag)
+			suspended, msg, err := s.Suspended(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\ns.Suspended(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.suspended, suspended); diff != "" {
+				t.Errorf("\nReason: %s\ns.Suspended(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.msg, msg); diff != "" {
+				t.Errorf("\nReason: %s\ns.Suspended(...): -want message, +got message:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
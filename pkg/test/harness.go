@@ -0,0 +1,197 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package test provides a harness that runs the Agent controllers against two
+// real control planes, one acting as the local cluster and one as the remote
+// cluster, so that they can be tested end-to-end.
+//
+// The control planes are started with envtest, which requires the etcd and
+// kube-apiserver binaries to be available in the directory that the
+// KUBEBUILDER_ASSETS environment variable points to. Tests that use the
+// harness are built only with the integration tag:
+//
+//	go test -tags integration ./pkg/test/...
+package test
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	crds "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/crossplane/crossplane/apis/apiextensions"
+)
+
+const (
+	errStartLocal   = "cannot start local control plane"
+	errStartRemote  = "cannot start remote control plane"
+	errStopLocal    = "cannot stop local control plane"
+	errStopRemote   = "cannot stop remote control plane"
+	errNewClient    = "cannot create client"
+	errNewManager   = "cannot create manager"
+	errSetup        = "cannot setup controllers"
+	errBuildScheme  = "cannot build scheme"
+	errNotStarted   = "harness is not started"
+	errManagerStart = "cannot start manager"
+)
+
+// A HarnessOption configures a Harness.
+type HarnessOption func(*Harness)
+
+// WithCRDPaths specifies the directories or files that contain the CRDs to be
+// installed in both clusters.
+func WithCRDPaths(paths ...string) HarnessOption {
+	return func(h *Harness) {
+		h.local.CRDDirectoryPaths = append(h.local.CRDDirectoryPaths, paths...)
+		h.remote.CRDDirectoryPaths = append(h.remote.CRDDirectoryPaths, paths...)
+	}
+}
+
+// WithCRDs specifies the CRDs to be installed in both clusters.
+func WithCRDs(c ...*crds.CustomResourceDefinition) HarnessOption {
+	return func(h *Harness) {
+		for _, crd := range c {
+			h.local.CRDs = append(h.local.CRDs, crd.DeepCopy())
+			h.remote.CRDs = append(h.remote.CRDs, crd.DeepCopy())
+		}
+	}
+}
+
+// NewHarness returns a new *Harness.
+func NewHarness(o ...HarnessOption) *Harness {
+	h := &Harness{
+		local:  &envtest.Environment{ErrorIfCRDPathMissing: true},
+		remote: &envtest.Environment{ErrorIfCRDPathMissing: true},
+	}
+	for _, f := range o {
+		f(h)
+	}
+	return h
+}
+
+// A Harness runs a local and a remote control plane.
+type Harness struct {
+	local  *envtest.Environment
+	remote *envtest.Environment
+
+	// LocalConfig and RemoteConfig are the REST configs of the local and the
+	// remote clusters. They are available once the Harness is started.
+	LocalConfig  *rest.Config
+	RemoteConfig *rest.Config
+
+	// LocalClient and RemoteClient are uncached clients of the local and the
+	// remote clusters. They are available once the Harness is started.
+	LocalClient  client.Client
+	RemoteClient client.Client
+
+	// Scheme knows about all the types that Agent works with.
+	Scheme *runtime.Scheme
+}
+
+// Start both control planes and install the CRDs in them.
+func (h *Harness) Start() error {
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		scheme.AddToScheme,
+		crds.AddToScheme,
+		apiextensions.AddToScheme,
+	} {
+		if err := add(s); err != nil {
+			return errors.Wrap(err, errBuildScheme)
+		}
+	}
+	h.Scheme = s
+
+	var err error
+	if h.LocalConfig, err = h.local.Start(); err != nil {
+		return errors.Wrap(err, errStartLocal)
+	}
+	if h.RemoteConfig, err = h.remote.Start(); err != nil {
+		return errors.Wrap(err, errStartRemote)
+	}
+	if h.LocalClient, err = client.New(h.LocalConfig, client.Options{Scheme: s}); err != nil {
+		return errors.Wrap(err, errNewClient)
+	}
+	h.RemoteClient, err = client.New(h.RemoteConfig, client.Options{Scheme: s})
+	return errors.Wrap(err, errNewClient)
+}
+
+// Stop both control planes.
+func (h *Harness) Stop() error {
+	if err := h.local.Stop(); err != nil {
+		return errors.Wrap(err, errStopLocal)
+	}
+	return errors.Wrap(h.remote.Stop(), errStopRemote)
+}
+
+// A SetupFn adds controllers to the supplied manager. The supplied client is
+// a client of the other cluster.
+type SetupFn func(mgr manager.Manager, other client.Client) error
+
+// RunLocal starts a manager of the local cluster with the controllers that are
+// added by the supplied function, i.e. runs the agent in local mode. The
+// manager runs until the returned function is called.
+func (h *Harness) RunLocal(setup SetupFn) (func(), error) {
+	return h.run(h.LocalConfig, h.RemoteClient, setup)
+}
+
+// RunRemote starts a manager of the remote cluster with the controllers that
+// are added by the supplied function, i.e. runs the agent in remote mode. The
+// manager runs until the returned function is called.
+func (h *Harness) RunRemote(setup SetupFn) (func(), error) {
+	return h.run(h.RemoteConfig, h.LocalClient, setup)
+}
+
+func (h *Harness) run(cfg *rest.Config, other client.Client, setup SetupFn) (func(), error) {
+	if cfg == nil {
+		return nil, errors.New(errNotStarted)
+	}
+	mgr, err := manager.New(cfg, manager.Options{Scheme: h.Scheme, MetricsBindAddress: "0"})
+	if err != nil {
+		return nil, errors.Wrap(err, errNewManager)
+	}
+	if err := setup(mgr, other); err != nil {
+		return nil, errors.Wrap(err, errSetup)
+	}
+	stop := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() { errs <- mgr.Start(stop) }()
+
+	// We give the manager a chance to fail fast, e.g. if a watch cannot be
+	// started, before the caller begins to make assertions.
+	select {
+	case err := <-errs:
+		return nil, errors.Wrap(err, errManagerStart)
+	case <-time.After(time.Second):
+	}
+	return func() { close(stop) }, nil
+}
+
+// Eventually calls the supplied function until it returns true, an error, or
+// the timeout expires.
+func Eventually(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (bool, error)) error {
+	return wait.PollImmediate(250*time.Millisecond, timeout, func() (bool, error) {
+		return fn(ctx)
+	})
+}
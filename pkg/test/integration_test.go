@@ -0,0 +1,123 @@
+// +build integration
+
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/agent/pkg/controllers/apiextensions"
+)
+
+// The CRDs of Crossplane are not vendored, so the directory that contains
+// them has to be supplied, e.g. cluster/crds of the Crossplane repository.
+const envCRDPath = "CROSSPLANE_CRD_PATH"
+
+const timeout = 30 * time.Second
+
+func composition(name string) *kunstructured.Unstructured {
+	u := &kunstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"compositeTypeRef": map[string]interface{}{
+				"apiVersion": "example.org/v1alpha1",
+				"kind":       "XExample",
+			},
+			"resources": []interface{}{
+				map[string]interface{}{
+					"base": map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "ConfigMap",
+					},
+				},
+			},
+		},
+	}}
+	u.SetAPIVersion("apiextensions.crossplane.io/v1alpha1")
+	u.SetKind("Composition")
+	u.SetName(name)
+	return u
+}
+
+func TestCompositionSync(t *testing.T) {
+	path := os.Getenv(envCRDPath)
+	if path == "" {
+		t.Skipf("%s is not set", envCRDPath)
+	}
+
+	h := NewHarness(WithCRDPaths(path))
+	if err := h.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := h.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	stop, err := h.RunRemote(func(mgr manager.Manager, local client.Client) error {
+		return apiextensions.SetupCompositionSync(mgr, local, logging.NewNopLogger())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	ctx := context.Background()
+	c := composition("cool-composition")
+	if err := h.RemoteClient.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	exists := func(want bool) func(ctx context.Context) (bool, error) {
+		return func(ctx context.Context) (bool, error) {
+			got := composition("")
+			err := h.LocalClient.Get(ctx, types.NamespacedName{Name: c.GetName()}, got)
+			if kerrors.IsNotFound(err) {
+				return !want, nil
+			}
+			return want, err
+		}
+	}
+
+	if err := Eventually(ctx, timeout, exists(true)); err != nil {
+		t.Errorf("Composition created in the remote cluster should be synced to the local cluster: %v", err)
+	}
+
+	if err := h.RemoteClient.Delete(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pruning happens only when another Composition is reconciled.
+	if err := h.RemoteClient.Create(ctx, composition("another-composition")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Eventually(ctx, timeout, exists(false)); err != nil {
+		t.Errorf("Composition deleted from the remote cluster should be removed from the local cluster: %v", err)
+	}
+}
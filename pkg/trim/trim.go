@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trim removes the fields that the agent never reads from the objects
+// that are listed and watched into its caches, so that they don't take up
+// memory for as long as the objects are cached.
+package trim
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AnnotationKeyLastApplied is the annotation kubectl apply keeps the last
+// applied configuration of an object in.
+const AnnotationKeyLastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Options are the fields that are trimmed.
+type Options struct {
+	// ManagedFields removes metadata.managedFields. They're preserved by the
+	// api-server when an object without them is updated.
+	ManagedFields bool
+
+	// LastApplied removes the last-applied-configuration annotation of
+	// kubectl. Note that it's removed by the api-server as well when an
+	// object that was read from the cache is updated, rather than patched.
+	LastApplied bool
+}
+
+// Empty returns true if nothing is trimmed.
+func (o Options) Empty() bool {
+	return !o.ManagedFields && !o.LastApplied
+}
+
+// Object trims the supplied object, which is in its unstructured form, in
+// place.
+func (o Options) Object(obj map[string]interface{}) {
+	md, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if o.ManagedFields {
+		delete(md, "managedFields")
+	}
+	if o.LastApplied {
+		if a, ok := md["annotations"].(map[string]interface{}); ok {
+			delete(a, AnnotationKeyLastApplied)
+		}
+	}
+}
+
+// List trims the supplied object, and its items if it's a list, in place.
+func (o Options) List(obj map[string]interface{}) {
+	items, ok := obj["items"].([]interface{})
+	if !ok {
+		o.Object(obj)
+		return
+	}
+	for _, i := range items {
+		if m, ok := i.(map[string]interface{}); ok {
+			o.Object(m)
+		}
+	}
+}
+
+// NewTransportWrapper returns a function that wraps a http.RoundTripper so that
+// the JSON objects that are read through it are trimmed. It's meant for the
+// config the caches list and watch with.
+func NewTransportWrapper(o Options) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &transport{wrapped: rt, options: o}
+	}
+}
+
+type transport struct {
+	wrapped http.RoundTripper
+	options Options
+}
+
+// RoundTrip executes the request and trims the objects in its response.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.wrapped.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || resp.StatusCode != http.StatusOK || !isJSON(resp.Header.Get("Content-Type")) {
+		return resp, err
+	}
+	if isWatch(req) {
+		pr, pw := io.Pipe()
+		go t.stream(resp.Body, pw)
+		resp.Body = pr
+		return resp, nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(b, &obj); err == nil {
+		t.options.List(obj)
+		if tb, err := json.Marshal(obj); err == nil {
+			b = tb
+		}
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(b)))
+	return resp, nil
+}
+
+type watchEvent struct {
+	Type   string                 `json:"type"`
+	Object map[string]interface{} `json:"object"`
+}
+
+// stream trims the objects of the watch events that are read from in and
+// writes them to out until in is closed.
+func (t *transport) stream(in io.ReadCloser, out *io.PipeWriter) {
+	defer in.Close() // nolint:errcheck
+	d := json.NewDecoder(in)
+	e := json.NewEncoder(out)
+	for {
+		ev := watchEvent{}
+		if err := d.Decode(&ev); err != nil {
+			out.CloseWithError(err)
+			return
+		}
+		t.options.Object(ev.Object)
+		if err := e.Encode(ev); err != nil {
+			out.CloseWithError(err)
+			return
+		}
+	}
+}
+
+func isWatch(req *http.Request) bool {
+	return req.URL.Query().Get("watch") == "true" || req.URL.Query().Get("watch") == "1" || strings.Contains(req.URL.Path, "/watch/")
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mt == "application/json"
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trim
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type roundTripperFn func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFn) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestTransport(t *testing.T) {
+	obj := `{"metadata":{"name":"cool","managedFields":[{"manager":"kubectl"}],"annotations":{"` + AnnotationKeyLastApplied + `":"{}","keep":"me"}}}`
+	trimmed := `{"metadata":{"annotations":{"keep":"me"},"name":"cool"}}`
+
+	cases := map[string]struct {
+		reason string
+		url    string
+		body   string
+		want   string
+	}{
+		"Get": {
+			reason: "A single object should be trimmed",
+			url:    "https://example.org/api/v1/namespaces/default/secrets/cool",
+			body:   obj,
+			want:   trimmed,
+		},
+		"List": {
+			reason: "The items of a list should be trimmed",
+			url:    "https://example.org/api/v1/secrets",
+			body:   `{"items":[` + obj + `]}`,
+			want:   `{"items":[` + trimmed + `]}`,
+		},
+		"Watch": {
+			reason: "The objects of watch events should be trimmed as they're streamed",
+			url:    "https://example.org/api/v1/secrets?watch=true",
+			body:   `{"type":"ADDED","object":` + obj + "}\n" + `{"type":"MODIFIED","object":` + obj + "}\n",
+			want:   `{"type":"ADDED","object":` + trimmed + "}\n" + `{"type":"MODIFIED","object":` + trimmed + "}\n",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rt := NewTransportWrapper(Options{ManagedFields: true, LastApplied: true})(roundTripperFn(func(_ *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(strings.NewReader(tc.body)),
+				}, nil
+			}))
+			req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip(...): %s", err)
+			}
+			got, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("ReadAll(...): %s", err)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.want), strings.TrimSpace(string(got))); diff != "" {
+				t.Errorf("\nReason: %s\nRoundTrip(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
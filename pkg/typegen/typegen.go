@@ -0,0 +1,392 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package typegen generates Go types, typed clients and informers for claim
+// kinds, so that tooling can use them without unstructured objects.
+package typegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/agent/pkg/controllers/xrd"
+)
+
+const (
+	errNoKinds         = "no claim kinds to generate"
+	errFmtNoSchema     = "claim kind %s has no schema"
+	errFmtGroupVersion = "claim kind %s is not in group version %s"
+	errFormat          = "cannot format generated source"
+)
+
+// A Kind is a claim kind that Go types and a client are generated for.
+type Kind struct {
+	GroupVersionKind schema.GroupVersionKind
+
+	// Schema is the OpenAPI v3 schema of the claims of the kind.
+	Schema v1beta1.JSONSchemaProps
+}
+
+// KindOf returns the claim kind the supplied claim CRD defines, in the version
+// that claims are synced with.
+func KindOf(crd v1beta1.CustomResourceDefinition) (Kind, error) {
+	gvk := xrd.GroupVersionKindOf(crd)
+	v := crd.Spec.Validation
+	for _, ver := range crd.Spec.Versions {
+		if ver.Name == gvk.Version && ver.Schema != nil {
+			v = ver.Schema
+		}
+	}
+	if v == nil || v.OpenAPIV3Schema == nil {
+		return Kind{}, errors.Errorf(errFmtNoSchema, gvk.Kind)
+	}
+	return Kind{GroupVersionKind: gvk, Schema: *v.OpenAPIV3Schema}, nil
+}
+
+// Generate returns the Go source of a package with the supplied name that has a
+// type for each of the supplied kinds, a function that adds them to a scheme,
+// and a typed client and informer for each of them. All kinds must be in the
+// same group version.
+func Generate(pkg string, kinds []Kind) ([]byte, error) {
+	if len(kinds) == 0 {
+		return nil, errors.New(errNoKinds)
+	}
+	gv := kinds[0].GroupVersionKind.GroupVersion()
+	sorted := make([]Kind, len(kinds))
+	copy(sorted, kinds)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GroupVersionKind.Kind < sorted[j].GroupVersionKind.Kind })
+
+	g := &generator{names: map[string]bool{}}
+	for _, k := range sorted {
+		if k.GroupVersionKind.GroupVersion() != gv {
+			return nil, errors.Errorf(errFmtGroupVersion, k.GroupVersionKind.Kind, gv)
+		}
+		for _, n := range []string{"", "List", "Client", "Informer"} {
+			g.names[k.GroupVersionKind.Kind+n] = true
+		}
+	}
+	for _, k := range sorted {
+		g.kind(k)
+	}
+
+	src := &bytes.Buffer{}
+	err := fileTemplate.Execute(src, struct {
+		Package      string
+		GroupVersion schema.GroupVersion
+		IntOrString  bool
+		Kinds        []string
+		Types        []string
+	}{Package: pkg, GroupVersion: gv, IntOrString: g.intOrString, Kinds: kindNames(sorted), Types: g.decls})
+	if err != nil {
+		return nil, err
+	}
+	out, err := format.Source(src.Bytes())
+	return out, errors.Wrap(err, errFormat)
+}
+
+func kindNames(kinds []Kind) []string {
+	n := make([]string, len(kinds))
+	for i, k := range kinds {
+		n[i] = k.GroupVersionKind.Kind
+	}
+	return n
+}
+
+// A generator renders the declarations of the Go types of schemas. Types are
+// declared in the order they're first referenced, parents before children.
+type generator struct {
+	names       map[string]bool
+	decls       []string
+	intOrString bool
+}
+
+func (g *generator) kind(k Kind) {
+	name := k.GroupVersionKind.Kind
+	s := k.Schema
+	if s.Description == "" {
+		s.Description = fmt.Sprintf("A %s is a claim of the %s API.", name, k.GroupVersionKind.GroupVersion())
+	}
+	props := map[string]v1beta1.JSONSchemaProps{}
+	for n, p := range s.Properties {
+		switch n {
+		case "apiVersion", "kind", "metadata":
+			continue
+		}
+		props[n] = p
+	}
+	s.Properties = props
+	g.decl(name, s, true)
+}
+
+// decl declares a struct type for the supplied object schema and returns its
+// name. The fields of the top-level type of a kind are never pointers, and it
+// embeds the type and object metadata.
+func (g *generator) decl(name string, s v1beta1.JSONSchemaProps, top bool) string {
+	if !top {
+		name = g.unique(name)
+	}
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	b := &strings.Builder{}
+	writeComment(b, s.Description, "")
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	if top {
+		b.WriteString("metav1.TypeMeta `json:\",inline\"`\nmetav1.ObjectMeta `json:\"metadata,omitempty\"`\n\n")
+	}
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	fields := make([]string, 0, len(s.Properties))
+	for f := range s.Properties {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		p := s.Properties[f]
+		field := goName(f)
+		t := g.typeOf(name+field, p)
+		tag := f
+		if !required[f] {
+			tag += ",omitempty"
+			if !top && pointable(t) {
+				t = "*" + t
+			}
+		}
+		writeComment(b, p.Description, "\t")
+		fmt.Fprintf(b, "\t%s %s `json:\"%s\"`\n", field, t, tag)
+	}
+	b.WriteString("}\n")
+	g.decls[i] = b.String()
+	return name
+}
+
+func (g *generator) typeOf(name string, s v1beta1.JSONSchemaProps) string {
+	switch {
+	case s.XIntOrString:
+		g.intOrString = true
+		return "intstr.IntOrString"
+	case s.Type == "string":
+		return "string"
+	case s.Type == "integer":
+		return "int64"
+	case s.Type == "number":
+		return "float64"
+	case s.Type == "boolean":
+		return "bool"
+	case s.Type == "array":
+		if s.Items == nil || s.Items.Schema == nil {
+			return "[]interface{}"
+		}
+		return "[]" + g.typeOf(name+"Item", *s.Items.Schema)
+	case len(s.Properties) > 0:
+		return g.decl(name, s, false)
+	case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+		return "map[string]" + g.typeOf(name+"Value", *s.AdditionalProperties.Schema)
+	case s.Type == "object":
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+// unique returns the supplied type name, suffixed with a number if a type of
+// that name was already declared.
+func (g *generator) unique(name string) string {
+	n := name
+	for i := 2; g.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[n] = true
+	return n
+}
+
+// pointable returns true if optional fields of the supplied type must be
+// pointers to tell unset fields from ones set to their zero value.
+func pointable(t string) bool {
+	switch t {
+	case "string", "interface{}", "intstr.IntOrString":
+		return false
+	}
+	return !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[")
+}
+
+// goName returns the exported Go identifier of the supplied JSON field name.
+func goName(json string) string {
+	b := &strings.Builder{}
+	upper := true
+	for _, r := range json {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteRune('X')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "X"
+	}
+	return b.String()
+}
+
+func writeComment(b *strings.Builder, text, indent string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	for _, l := range strings.Split(text, "\n") {
+		fmt.Fprintf(b, "%s// %s\n", indent, strings.TrimRightFunc(l, unicode.IsSpace))
+	}
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by crossplane-agent generate-types. DO NOT EDIT.
+
+// Package {{ .Package }} contains the claims of the {{ .GroupVersion }} API.
+package {{ .Package }}
+
+import (
+	"context"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+{{- if .IntOrString }}
+	"k8s.io/apimachinery/pkg/util/intstr"
+{{- end }}
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// SchemeGroupVersion is the group version of the claims in this package.
+var SchemeGroupVersion = schema.GroupVersion{Group: "{{ .GroupVersion.Group }}", Version: "{{ .GroupVersion.Version }}"}
+
+var (
+	// SchemeBuilder adds the claims in this package to a scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+
+	// AddToScheme adds the claims in this package to the supplied scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func init() {
+	SchemeBuilder.Register({{ range .Kinds }}&{{ . }}{}, &{{ . }}List{}, {{ end }})
+}
+
+// deepCopyJSON copies in to out by round-tripping it through JSON, which is
+// lossless for types generated from OpenAPI schemas.
+func deepCopyJSON(in, out interface{}) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		panic(err)
+	}
+}
+{{ range .Types }}
+{{ . }}
+{{- end }}
+{{ range .Kinds }}
+// A {{ . }}List is a list of {{ . }} claims.
+type {{ . }}List struct {
+	metav1.TypeMeta ` + "`" + `json:",inline"` + "`" + `
+	metav1.ListMeta ` + "`" + `json:"metadata,omitempty"` + "`" + `
+
+	Items []{{ . }} ` + "`" + `json:"items"` + "`" + `
+}
+
+// DeepCopyObject returns a deep copy of the {{ . }}.
+func (in *{{ . }}) DeepCopyObject() runtime.Object {
+	out := &{{ . }}{}
+	deepCopyJSON(in, out)
+	return out
+}
+
+// DeepCopyObject returns a deep copy of the {{ . }}List.
+func (in *{{ . }}List) DeepCopyObject() runtime.Object {
+	out := &{{ . }}List{}
+	deepCopyJSON(in, out)
+	return out
+}
+
+// A {{ . }}Client reads and writes {{ . }} claims.
+type {{ . }}Client struct {
+	kube client.Client
+}
+
+// New{{ . }}Client returns a {{ . }}Client that uses the supplied client, whose
+// scheme must have the claims in this package added to it.
+func New{{ . }}Client(c client.Client) *{{ . }}Client {
+	return &{{ . }}Client{kube: c}
+}
+
+// Get returns the {{ . }} with the supplied namespace and name.
+func (c *{{ . }}Client) Get(ctx context.Context, namespace, name string) (*{{ . }}, error) {
+	o := &{{ . }}{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// List returns the {{ . }} claims in the supplied namespace, or in all
+// namespaces if it's empty.
+func (c *{{ . }}Client) List(ctx context.Context, namespace string, opts ...client.ListOption) (*{{ . }}List, error) {
+	l := &{{ . }}List{}
+	if err := c.kube.List(ctx, l, append([]client.ListOption{client.InNamespace(namespace)}, opts...)...); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Create creates the supplied {{ . }}.
+func (c *{{ . }}Client) Create(ctx context.Context, o *{{ . }}, opts ...client.CreateOption) error {
+	return c.kube.Create(ctx, o, opts...)
+}
+
+// Update updates the supplied {{ . }}.
+func (c *{{ . }}Client) Update(ctx context.Context, o *{{ . }}, opts ...client.UpdateOption) error {
+	return c.kube.Update(ctx, o, opts...)
+}
+
+// Delete deletes the supplied {{ . }}.
+func (c *{{ . }}Client) Delete(ctx context.Context, o *{{ . }}, opts ...client.DeleteOption) error {
+	return c.kube.Delete(ctx, o, opts...)
+}
+
+// {{ . }}Informer returns the informer of {{ . }} claims in the supplied cache.
+func {{ . }}Informer(ctx context.Context, c cache.Informers) (cache.Informer, error) {
+	return c.GetInformer(ctx, &{{ . }}{})
+}
+{{ end }}`))
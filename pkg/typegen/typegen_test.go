@@ -0,0 +1,164 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package typegen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestGenerate(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "database.example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	s := v1beta1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]v1beta1.JSONSchemaProps{
+			"metadata": {Type: "object"},
+			"spec": {
+				Type: "object",
+				Properties: map[string]v1beta1.JSONSchemaProps{
+					"parameters": {
+						Type: "object",
+						Properties: map[string]v1beta1.JSONSchemaProps{
+							"storageGB": {Type: "integer"},
+							"port":      {XIntOrString: true},
+						},
+					},
+					"zones": {
+						Type: "array",
+						Items: &v1beta1.JSONSchemaPropsOrArray{Schema: &v1beta1.JSONSchemaProps{
+							Type:       "object",
+							Properties: map[string]v1beta1.JSONSchemaProps{"name": {Type: "string"}},
+						}},
+					},
+				},
+			},
+			"status": {
+				Type:       "object",
+				Properties: map[string]v1beta1.JSONSchemaProps{"ready": {Type: "boolean"}},
+			},
+			"list": {
+				Type:       "object",
+				Properties: map[string]v1beta1.JSONSchemaProps{"name": {Type: "string"}},
+			},
+		},
+	}
+
+	type want struct {
+		types []string
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		kinds  []Kind
+		want   want
+	}{
+		"NoKinds": {
+			reason: "An error should be returned if there are no kinds to generate.",
+			want:   want{err: errors.New(errNoKinds)},
+		},
+		"MixedGroupVersions": {
+			reason: "An error should be returned if the kinds are not all in the same group version.",
+			kinds: []Kind{
+				{GroupVersionKind: gvk, Schema: s},
+				{GroupVersionKind: schema.GroupVersionKind{Group: "cache.example.org", Version: "v1alpha1", Kind: "Redis"}, Schema: s},
+			},
+			want: want{err: errors.Errorf(errFmtGroupVersion, "Redis", gvk.GroupVersion())},
+		},
+		"Success": {
+			reason: "A type should be declared for the kind, its list and each object in its schema, with names that do not collide, together with a typed client.",
+			kinds:  []Kind{{GroupVersionKind: gvk, Schema: s}},
+			want: want{types: []string{
+				"MySQLInstance",
+				"MySQLInstanceList2",
+				"MySQLInstanceSpec",
+				"MySQLInstanceSpecParameters",
+				"MySQLInstanceSpecZonesItem",
+				"MySQLInstanceStatus",
+				"MySQLInstanceList",
+				"MySQLInstanceClient",
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			src, err := Generate("v1alpha1", tc.kinds)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\nReason: %s\nGenerate(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			f, err := parser.ParseFile(token.NewFileSet(), "generated.go", src, 0)
+			if err != nil {
+				t.Fatalf("\nReason: %s\nGenerate(...): cannot parse generated source: %s\n", tc.reason, err)
+			}
+			var types []string
+			ast.Inspect(f, func(n ast.Node) bool {
+				if ts, ok := n.(*ast.TypeSpec); ok {
+					types = append(types, ts.Name.Name)
+				}
+				return true
+			})
+			if diff := cmp.Diff(tc.want.types, types); diff != "" {
+				t.Errorf("\nReason: %s\nGenerate(...): -want types, +got types:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGoName(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		json   string
+		want   string
+	}{
+		"CamelCase": {
+			reason: "The first letter of camel case names should be capitalized.",
+			json:   "writeConnectionSecretToRef",
+			want:   "WriteConnectionSecretToRef",
+		},
+		"Separators": {
+			reason: "Characters that are not valid in identifiers should be dropped, capitalizing the next letter.",
+			json:   "engine-version_major",
+			want:   "EngineVersionMajor",
+		},
+		"LeadingDigit": {
+			reason: "Names that start with a digit should be prefixed.",
+			json:   "3az",
+			want:   "X3az",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := goName(tc.json)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\ngoName(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package warmup fills the informer caches of the kinds the agent syncs before
+// it reports itself ready, so that the first syncs after a restart don't all
+// wait for their kind to be listed.
+package warmup
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+
+	"github.com/crossplane/agent/pkg/controllers/xrd"
+	"github.com/crossplane/agent/pkg/metrics"
+)
+
+const (
+	errListXRDs    = "cannot list composite resource definitions"
+	errGetClaimCRD = "cannot get claim custom resource definition"
+	errNotWarm     = "caches are not warmed up yet"
+)
+
+// ClaimKinds returns the kinds of the claims that are offered by the
+// CompositeResourceDefinitions in the local cluster, and the supplied
+// passthrough kinds.
+func ClaimKinds(ctx context.Context, c client.Reader, passthrough []schema.GroupVersionKind) ([]schema.GroupVersionKind, error) {
+	l := &v1alpha1.CompositeResourceDefinitionList{}
+	if err := c.List(ctx, l); err != nil {
+		return nil, errors.Wrap(err, errListXRDs)
+	}
+	kinds := append([]schema.GroupVersionKind{}, passthrough...)
+	for _, x := range l.Items {
+		if x.Spec.ClaimNames == nil {
+			continue
+		}
+		crd := &v1beta1.CustomResourceDefinition{}
+		if err := c.Get(ctx, xrd.GetClaimCRDName(x), crd); err != nil {
+			return nil, errors.Wrap(err, errGetClaimCRD)
+		}
+		kinds = append(kinds, xrd.GroupVersionKindOf(*crd))
+	}
+	return kinds, nil
+}
+
+// A WarmerOption configures a Warmer.
+type WarmerOption func(*Warmer)
+
+// WithoutSecrets makes the Warmer warm only the caches of the kinds it's given
+// up. It's meant for the cache of the remote cluster, whose Secrets aren't
+// read through it.
+func WithoutSecrets() WarmerOption {
+	return func(w *Warmer) {
+		w.noSecrets = true
+	}
+}
+
+// NewWarmer returns a new *Warmer that warms the caches of the kinds that the
+// supplied function returns, and of Secrets, up within the supplied timeout.
+func NewWarmer(c cache.Cache, kinds func(ctx context.Context) ([]schema.GroupVersionKind, error), timeout time.Duration, m *metrics.Warmup, log logging.Logger, o ...WarmerOption) *Warmer {
+	w := &Warmer{cache: c, kinds: kinds, timeout: timeout, metrics: m, log: log}
+	for _, fn := range o {
+		fn(w)
+	}
+	return w
+}
+
+// A Warmer fills the informer caches of the kinds the agent syncs when it's
+// started. It's ready once all of them are filled or the timeout passes,
+// whichever comes first, so that a kind that can't be listed doesn't keep the
+// agent from becoming ready forever.
+type Warmer struct {
+	cache   cache.Cache
+	kinds   func(ctx context.Context) ([]schema.GroupVersionKind, error)
+	timeout time.Duration
+	metrics *metrics.Warmup
+	log     logging.Logger
+
+	noSecrets bool
+
+	done int32
+}
+
+// NeedLeaderElection returns false since the caches of the replicas that
+// aren't the leader are filled too, and they should be ready to take over.
+func (w *Warmer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start warming the caches up. It returns once they're warm or the timeout
+// passes.
+func (w *Warmer) Start(stop <-chan struct{}) error {
+	defer atomic.StoreInt32(&w.done, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// A cache that's started alongside the Warmer, rather than before it like
+	// the one of the manager, would let informers through before they sync.
+	w.cache.WaitForCacheSync(ctx.Done())
+
+	kinds, err := w.kinds(ctx)
+	if err != nil {
+		w.log.Info("Cannot determine the kinds to warm the caches of", "error", err)
+	}
+	objs := w.objects(kinds)
+
+	total := len(objs)
+	var ready int32
+	if w.metrics != nil {
+		w.metrics.Kinds.Set(float64(total))
+		w.metrics.ReadyKinds.Set(0)
+	}
+	started := time.Now()
+
+	var wg sync.WaitGroup
+	for _, o := range objs {
+		wg.Add(1)
+		go func(o runtime.Object) {
+			defer wg.Done()
+			// Informers of kinds that aren't cached yet are created, started
+			// and waited on until they're synced.
+			if _, err := w.cache.GetInformer(o); err != nil {
+				w.log.Debug("Cannot warm cache up", "kind", o.GetObjectKind().GroupVersionKind().String(), "error", err)
+				return
+			}
+			w.log.Debug("Warmed cache up", "kind", o.GetObjectKind().GroupVersionKind().String(), "progress", atomic.AddInt32(&ready, 1), "kinds", total)
+			if w.metrics != nil {
+				w.metrics.ReadyKinds.Inc()
+			}
+		}(o)
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		w.log.Info("Caches are warmed up", "kinds", total, "took", time.Since(started).String())
+	case <-ctx.Done():
+		w.log.Info("Stopped waiting for caches to warm up", "ready", atomic.LoadInt32(&ready), "kinds", total)
+	}
+	return nil
+}
+
+// objects returns the objects whose informers are warmed up for the supplied
+// kinds.
+func (w *Warmer) objects(kinds []schema.GroupVersionKind) []runtime.Object {
+	objs := make([]runtime.Object, 0, len(kinds)+1)
+	if !w.noSecrets {
+		objs = append(objs, &corev1.Secret{})
+	}
+	for _, gvk := range kinds {
+		u := &kunstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		objs = append(objs, u)
+	}
+	return objs
+}
+
+// Ready returns an error until the caches are warmed up. It satisfies
+// healthz.Checker.
+func (w *Warmer) Ready(_ *http.Request) error {
+	if atomic.LoadInt32(&w.done) == 0 {
+		return errors.New(errNotWarm)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/crossplane/apis/apiextensions/v1alpha1"
+)
+
+func TestClaimKinds(t *testing.T) {
+	passthrough := schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "Bucket"}
+	c := &test.MockClient{
+		MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+			l := obj.(*v1alpha1.CompositeResourceDefinitionList)
+			offered := v1alpha1.CompositeResourceDefinition{}
+			offered.Spec.CRDSpecTemplate.Group = "example.org"
+			offered.Spec.ClaimNames = &v1beta1.CustomResourceDefinitionNames{Plural: "mysqlinstances", Kind: "MySQLInstance"}
+			l.Items = []v1alpha1.CompositeResourceDefinition{offered, {}}
+			return nil
+		},
+		MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+			if key.Name != "mysqlinstances.example.org" {
+				t.Errorf("Get(...): unexpected claim CRD %s", key.Name)
+			}
+			crd := obj.(*v1beta1.CustomResourceDefinition)
+			crd.Spec.Group = "example.org"
+			crd.Spec.Names.Kind = "MySQLInstance"
+			crd.Spec.Versions = []v1beta1.CustomResourceDefinitionVersion{{Name: "v1alpha1", Served: true, Storage: true}}
+			return nil
+		},
+	}
+
+	got, err := ClaimKinds(context.Background(), c, []schema.GroupVersionKind{passthrough})
+	if err != nil {
+		t.Fatalf("ClaimKinds(...): %s", err)
+	}
+	want := []schema.GroupVersionKind{passthrough, {Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ClaimKinds(...): the passthrough kinds and the kinds of offered claims should be warmed up: -want, +got:\n%s", diff)
+	}
+}
+
+func TestReady(t *testing.T) {
+	w := NewWarmer(nil, nil, 0, nil, nil)
+	if err := w.Ready(nil); err == nil {
+		t.Errorf("Ready(...): should not be ready before the caches are warmed up")
+	}
+	w.done = 1
+	if err := w.Ready(nil); err != nil {
+		t.Errorf("Ready(...): %s", err)
+	}
+}
+
+func TestObjects(t *testing.T) {
+	kind := schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "MySQLInstance"}
+	u := &kunstructured.Unstructured{}
+	u.SetGroupVersionKind(kind)
+
+	cases := map[string]struct {
+		reason string
+		o      []WarmerOption
+		want   []runtime.Object
+	}{
+		"WithSecrets": {
+			reason: "Secrets should be warmed up along with the supplied kinds by default",
+			want:   []runtime.Object{&corev1.Secret{}, u},
+		},
+		"WithoutSecrets": {
+			reason: "Only the supplied kinds should be warmed up if Secrets are left out",
+			o:      []WarmerOption{WithoutSecrets()},
+			want:   []runtime.Object{u},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := NewWarmer(nil, nil, 0, nil, nil, tc.o...).objects([]schema.GroupVersionKind{kind})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nReason: %s\nobjects(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
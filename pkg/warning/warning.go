@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package warning captures the warnings that api-servers return in the Warning
+// headers of their responses, such as the ones about deprecated APIs or from
+// admission webhooks, so that they can be surfaced to operators.
+package warning
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+type sinkKey struct{}
+
+// A Sink collects the warnings returned to the requests that are made with
+// its context.
+type Sink struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+// NewContext returns a context whose requests' warnings are collected in the
+// returned Sink.
+func NewContext(ctx context.Context) (context.Context, *Sink) {
+	s := &Sink{}
+	return context.WithValue(ctx, sinkKey{}, s), s
+}
+
+func (s *Sink) add(w string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.warnings {
+		if existing == w {
+			return
+		}
+	}
+	s.warnings = append(s.warnings, w)
+}
+
+// Warnings returns the distinct warnings collected so far.
+func (s *Sink) Warnings() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.warnings...)
+}
+
+// Parse returns the text of the supplied Warning header value, which is in the
+// form of `299 - "text"`.
+func Parse(h string) string {
+	parts := strings.SplitN(h, " ", 3)
+	if len(parts) != 3 {
+		return h
+	}
+	if text, err := strconv.Unquote(parts[2]); err == nil {
+		return text
+	}
+	return parts[2]
+}
+
+// NewTransportWrapper returns a function that wraps a http.RoundTripper so
+// that the warnings in its responses are logged, once per distinct warning,
+// and added to the Sink of the request's context if there is one. It can be
+// used to wrap the transport of a rest.Config.
+func NewTransportWrapper(log logging.Logger) func(http.RoundTripper) http.RoundTripper {
+	seen := &sync.Map{}
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &transport{wrapped: rt, log: log, seen: seen}
+	}
+}
+
+type transport struct {
+	wrapped http.RoundTripper
+	log     logging.Logger
+	seen    *sync.Map
+}
+
+// RoundTrip executes the request and captures the warnings in its response.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.wrapped.RoundTrip(req)
+	if resp == nil {
+		return resp, err
+	}
+	for _, h := range resp.Header["Warning"] {
+		w := Parse(h)
+		if s, ok := req.Context().Value(sinkKey{}).(*Sink); ok {
+			s.add(w)
+		}
+		if _, loaded := t.seen.LoadOrStore(w, true); !loaded {
+			t.log.Info("API server returned a warning", "host", req.URL.Host, "method", req.Method, "path", req.URL.Path, "warning", w)
+		}
+	}
+	return resp, err
+}
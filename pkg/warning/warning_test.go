@@ -0,0 +1,54 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warning
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+type roundTripFn func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFn) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestTransport(t *testing.T) {
+	rt := NewTransportWrapper(logging.NewNopLogger())(roundTripFn(func(_ *http.Request) (*http.Response, error) {
+		h := http.Header{}
+		h.Add("Warning", `299 - "extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+"`)
+		h.Add("Warning", `299 - "extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+"`)
+		h.Add("Warning", "malformed")
+		return &http.Response{Header: h}, nil
+	}))
+
+	ctx, sink := NewContext(context.Background())
+	req, _ := http.NewRequest(http.MethodGet, "https://example.org", nil)
+	if _, err := rt.RoundTrip(req.WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+", "malformed"}
+	if diff := cmp.Diff(want, sink.Warnings()); diff != "" {
+		t.Errorf("\nReason: %s\nWarnings(): -want, +got:\n%s", "Distinct warnings of requests should be collected in the sink of their context", diff)
+	}
+}